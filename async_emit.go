@@ -0,0 +1,432 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: async_emit.go
+//
+// This file contains the [asyncEmitter], which applies the write operations of
+// the adapters of metrics created with [BasicMetricOpts.Async] on a background
+// goroutine through a bounded queue, so that costly (e.g. network) emits don't
+// block their callers. Other metrics of the group stay synchronous.
+//--------------------------------------------------------------------------------
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultAsyncQueueSize is the number of operations of async metrics a
+	// registry queues by default, see [WithAsyncEmit]
+	DefaultAsyncQueueSize = 1024
+
+	// DefaultAsyncDeadline is how long operations of async metrics wait by
+	// default for room in a full queue before being dropped, see [WithAsyncEmit]
+	DefaultAsyncDeadline = time.Millisecond
+)
+
+// asyncEmitter applies queued adapter operations in order on a goroutine,
+// started on the first operation. It is shared by every group of a registry,
+// and runs until it is closed, see [Registry.Close].
+type asyncEmitter struct {
+	queue    chan func() error
+	deadline time.Duration
+	onError  func(err error) // Called with the errors of operations, if set
+
+	mu      sync.RWMutex // Held for writing while closing, so that nothing is queued
+	closed  bool         // Whether operations are applied synchronously
+	start   sync.Once
+	done    chan struct{} // Closed once the goroutine has exited, or when closed before starting
+	dropped atomic.Uint64 // Number of operations dropped
+}
+
+// newAsyncEmitter returns an emitter queueing up to size operations, each
+// waiting up to deadline for room in a full queue
+func newAsyncEmitter(size int, deadline time.Duration) *asyncEmitter {
+	return &asyncEmitter{
+		queue:    make(chan func() error, max(size, 1)),
+		deadline: deadline,
+		done:     make(chan struct{}),
+	}
+}
+
+// emit queues op, returning [ErrAsyncQueueFull] and counting op as dropped if
+// the queue is still full after the deadline. Once the emitter is closed, op
+// is applied synchronously instead.
+func (e *asyncEmitter) emit(op func() error) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.closed {
+		return op()
+	}
+	e.start.Do(func() { go e.run() })
+
+	select {
+	case e.queue <- op:
+		return nil
+	default:
+	}
+
+	if e.deadline > 0 {
+		timer := time.NewTimer(e.deadline)
+		defer timer.Stop()
+
+		select {
+		case e.queue <- op:
+			return nil
+		case <-timer.C:
+		}
+	}
+
+	e.dropped.Add(1)
+	return ErrAsyncQueueFull
+}
+
+func (e *asyncEmitter) run() {
+	defer close(e.done)
+
+	for op := range e.queue {
+		if err := op(); err != nil && e.onError != nil {
+			e.onError(err)
+		}
+	}
+}
+
+// wait blocks until every operation queued before the call is applied, by
+// queueing a marker behind them. Operations queued meanwhile by other
+// goroutines don't delay it.
+func (e *asyncEmitter) wait() {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.closed {
+		return
+	}
+	e.start.Do(func() { go e.run() })
+
+	applied := make(chan struct{})
+	e.queue <- func() error {
+		close(applied)
+		return nil
+	}
+	<-applied
+}
+
+// close applies the queued operations and stops the goroutine. Operations
+// emitted afterwards are applied synchronously.
+func (e *asyncEmitter) close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return
+	}
+	e.closed = true
+
+	// Nothing was ever queued if the goroutine wasn't started
+	e.start.Do(func() { close(e.done) })
+	close(e.queue)
+	<-e.done
+}
+
+//--------------------------------------------------------------------------------
+// Async Adapter Constructors
+//
+// Each returns the adapter as is, unless the metric is async and the group
+// has an emitter.
+//--------------------------------------------------------------------------------
+
+func (g *group) asyncCounter(opts BasicMetricOpts, adapter CounterAdapter) CounterAdapter {
+	if !opts.Async || g.async == nil {
+		return adapter
+	}
+	return &asyncCounterAdapter{emitter: g.async, internal: adapter}
+}
+
+func (g *group) asyncCounterVec(opts BasicMetricOpts, adapter CounterVecAdapter) CounterVecAdapter {
+	if !opts.Async || g.async == nil {
+		return adapter
+	}
+	return &asyncCounterVecAdapter{emitter: g.async, internal: adapter}
+}
+
+func (g *group) asyncGauge(opts BasicMetricOpts, adapter GaugeAdapter) GaugeAdapter {
+	if !opts.Async || g.async == nil {
+		return adapter
+	}
+	return &asyncGaugeAdapter{emitter: g.async, internal: adapter}
+}
+
+func (g *group) asyncGaugeVec(opts BasicMetricOpts, adapter GaugeVecAdapter) GaugeVecAdapter {
+	if !opts.Async || g.async == nil {
+		return adapter
+	}
+	return &asyncGaugeVecAdapter{emitter: g.async, internal: adapter}
+}
+
+func (g *group) asyncHistogram(opts BasicMetricOpts, adapter HistogramAdapter) HistogramAdapter {
+	if !opts.Async || g.async == nil {
+		return adapter
+	}
+	return &asyncHistogramAdapter{emitter: g.async, internal: adapter}
+}
+
+func (g *group) asyncHistogramVec(opts BasicMetricOpts, adapter HistogramVecAdapter) HistogramVecAdapter {
+	if !opts.Async || g.async == nil {
+		return adapter
+	}
+	return &asyncHistogramVecAdapter{emitter: g.async, internal: adapter}
+}
+
+func (g *group) asyncSummary(opts BasicMetricOpts, adapter SummaryAdapter) SummaryAdapter {
+	if !opts.Async || g.async == nil {
+		return adapter
+	}
+	return &asyncSummaryAdapter{emitter: g.async, internal: adapter}
+}
+
+func (g *group) asyncSummaryVec(opts BasicMetricOpts, adapter SummaryVecAdapter) SummaryVecAdapter {
+	if !opts.Async || g.async == nil {
+		return adapter
+	}
+	return &asyncSummaryVecAdapter{emitter: g.async, internal: adapter}
+}
+
+//--------------------------------------------------------------------------------
+// Async Adapters
+//
+// Writes are queued. Reads (e.g. values and quantiles) and deletions wait for
+// the writes queued before them to be applied, so that callers read their own
+// writes, while series initialization is applied synchronously.
+//--------------------------------------------------------------------------------
+
+type asyncCounterAdapter struct {
+	emitter  *asyncEmitter
+	internal CounterAdapter
+}
+
+func (a *asyncCounterAdapter) Inc() error {
+	return a.emitter.emit(a.internal.Inc)
+}
+
+func (a *asyncCounterAdapter) Add(value float64) error {
+	return a.emitter.emit(func() error { return a.internal.Add(value) })
+}
+
+func (a *asyncCounterAdapter) AddWithExemplar(value float64, exemplar VecLabels) error {
+	return a.emitter.emit(func() error { return addWithExemplar(a.internal, value, exemplar) })
+}
+
+func (a *asyncCounterAdapter) Value() (float64, error) {
+	a.emitter.wait()
+	return adapterValue(a.internal)
+}
+
+type asyncCounterVecAdapter struct {
+	emitter  *asyncEmitter
+	internal CounterVecAdapter
+}
+
+func (a *asyncCounterVecAdapter) Inc(labels VecLabels) error {
+	return a.emitter.emit(func() error { return a.internal.Inc(labels) })
+}
+
+func (a *asyncCounterVecAdapter) Add(value float64, labels VecLabels) error {
+	return a.emitter.emit(func() error { return a.internal.Add(value, labels) })
+}
+
+func (a *asyncCounterVecAdapter) Series() ([]Sample, error) {
+	a.emitter.wait()
+	return adapterSeries(a.internal)
+}
+
+func (a *asyncCounterVecAdapter) InitLabels(labels VecLabels) error {
+	return initVecSeries(a.internal, labels, func(labels VecLabels) error { return a.internal.Add(0, labels) })
+}
+
+func (a *asyncCounterVecAdapter) WithLabelValues(values ...string) (CounterAdapter, error) {
+	internal, err := adapterLabelValues(a.internal, values)
+	if err != nil {
+		return nil, err
+	}
+	return &asyncCounterAdapter{emitter: a.emitter, internal: internal}, nil
+}
+
+// Delete removes the series once the queued writes are applied, so that they
+// don't recreate it
+func (a *asyncCounterVecAdapter) Delete(labels VecLabels) bool {
+	a.emitter.wait()
+	return adapterDelete(a.internal, labels)
+}
+
+func (a *asyncCounterVecAdapter) Reset() {
+	a.emitter.wait()
+	adapterReset(a.internal)
+}
+
+type asyncGaugeAdapter struct {
+	emitter  *asyncEmitter
+	internal GaugeAdapter
+}
+
+func (a *asyncGaugeAdapter) Set(value float64) error {
+	return a.emitter.emit(func() error { return a.internal.Set(value) })
+}
+
+func (a *asyncGaugeAdapter) Inc() error {
+	return a.emitter.emit(a.internal.Inc)
+}
+
+func (a *asyncGaugeAdapter) Dec() error {
+	return a.emitter.emit(a.internal.Dec)
+}
+
+func (a *asyncGaugeAdapter) Add(value float64) error {
+	return a.emitter.emit(func() error { return a.internal.Add(value) })
+}
+
+func (a *asyncGaugeAdapter) Value() (float64, error) {
+	a.emitter.wait()
+	return adapterValue(a.internal)
+}
+
+type asyncGaugeVecAdapter struct {
+	emitter  *asyncEmitter
+	internal GaugeVecAdapter
+}
+
+func (a *asyncGaugeVecAdapter) Set(value float64, labels VecLabels) error {
+	return a.emitter.emit(func() error { return a.internal.Set(value, labels) })
+}
+
+func (a *asyncGaugeVecAdapter) Inc(labels VecLabels) error {
+	return a.emitter.emit(func() error { return a.internal.Inc(labels) })
+}
+
+func (a *asyncGaugeVecAdapter) Dec(labels VecLabels) error {
+	return a.emitter.emit(func() error { return a.internal.Dec(labels) })
+}
+
+func (a *asyncGaugeVecAdapter) Add(value float64, labels VecLabels) error {
+	return a.emitter.emit(func() error { return a.internal.Add(value, labels) })
+}
+
+func (a *asyncGaugeVecAdapter) InitLabels(labels VecLabels) error {
+	return initVecSeries(a.internal, labels, func(labels VecLabels) error { return a.internal.Add(0, labels) })
+}
+
+func (a *asyncGaugeVecAdapter) Delete(labels VecLabels) bool {
+	a.emitter.wait()
+	return adapterDelete(a.internal, labels)
+}
+
+func (a *asyncGaugeVecAdapter) Reset() {
+	a.emitter.wait()
+	adapterReset(a.internal)
+}
+
+type asyncHistogramAdapter struct {
+	emitter  *asyncEmitter
+	internal HistogramAdapter
+}
+
+func (a *asyncHistogramAdapter) Observe(value float64) error {
+	return a.emitter.emit(func() error { return a.internal.Observe(value) })
+}
+
+func (a *asyncHistogramAdapter) ObserveWithExemplar(value float64, exemplar VecLabels) error {
+	return a.emitter.emit(func() error { return observeWithExemplar(a.internal, value, exemplar) })
+}
+
+type asyncHistogramVecAdapter struct {
+	emitter  *asyncEmitter
+	internal HistogramVecAdapter
+}
+
+func (a *asyncHistogramVecAdapter) Observe(value float64, labels VecLabels) error {
+	return a.emitter.emit(func() error { return a.internal.Observe(value, labels) })
+}
+
+func (a *asyncHistogramVecAdapter) InitLabels(labels VecLabels) error {
+	return initVecSeries(a.internal, labels, nil)
+}
+
+func (a *asyncHistogramVecAdapter) Delete(labels VecLabels) bool {
+	a.emitter.wait()
+	return adapterDelete(a.internal, labels)
+}
+
+func (a *asyncHistogramVecAdapter) Reset() {
+	a.emitter.wait()
+	adapterReset(a.internal)
+}
+
+type asyncSummaryAdapter struct {
+	emitter  *asyncEmitter
+	internal SummaryAdapter
+}
+
+func (a *asyncSummaryAdapter) Observe(value float64) error {
+	return a.emitter.emit(func() error { return a.internal.Observe(value) })
+}
+
+func (a *asyncSummaryAdapter) Quantile(q float64) (float64, error) {
+	a.emitter.wait()
+	return a.internal.Quantile(q)
+}
+
+type asyncSummaryVecAdapter struct {
+	emitter  *asyncEmitter
+	internal SummaryVecAdapter
+}
+
+func (a *asyncSummaryVecAdapter) Observe(value float64, labels VecLabels) error {
+	return a.emitter.emit(func() error { return a.internal.Observe(value, labels) })
+}
+
+func (a *asyncSummaryVecAdapter) Quantile(q float64, labels VecLabels) (float64, error) {
+	a.emitter.wait()
+	return a.internal.Quantile(q, labels)
+}
+
+func (a *asyncSummaryVecAdapter) InitLabels(labels VecLabels) error {
+	return initVecSeries(a.internal, labels, nil)
+}
+
+func (a *asyncSummaryVecAdapter) Delete(labels VecLabels) bool {
+	a.emitter.wait()
+	return adapterDelete(a.internal, labels)
+}
+
+func (a *asyncSummaryVecAdapter) Reset() {
+	a.emitter.wait()
+	adapterReset(a.internal)
+}
+
+var (
+	__ctc_asyncCounterAdapter      CounterAdapter      = (*asyncCounterAdapter)(nil)
+	__ctc_asyncCounterVecAdapter   CounterVecAdapter   = (*asyncCounterVecAdapter)(nil)
+	__ctc_asyncGaugeAdapter        GaugeAdapter        = (*asyncGaugeAdapter)(nil)
+	__ctc_asyncGaugeVecAdapter     GaugeVecAdapter     = (*asyncGaugeVecAdapter)(nil)
+	__ctc_asyncHistogramAdapter    HistogramAdapter    = (*asyncHistogramAdapter)(nil)
+	__ctc_asyncHistogramVecAdapter HistogramVecAdapter = (*asyncHistogramVecAdapter)(nil)
+	__ctc_asyncSummaryAdapter      SummaryAdapter      = (*asyncSummaryAdapter)(nil)
+	__ctc_asyncSummaryVecAdapter   SummaryVecAdapter   = (*asyncSummaryVecAdapter)(nil)
+
+	__ctc_asyncCounterValueAdapter ValueAdapter             = (*asyncCounterAdapter)(nil)
+	__ctc_asyncGaugeValueAdapter   ValueAdapter             = (*asyncGaugeAdapter)(nil)
+	__ctc_asyncCounterVecSeries    SeriesAdapter            = (*asyncCounterVecAdapter)(nil)
+	__ctc_asyncCounterVecValues    LabelValuesAdapter       = (*asyncCounterVecAdapter)(nil)
+	__ctc_asyncCounterVecDelete    DeleteAdapter            = (*asyncCounterVecAdapter)(nil)
+	__ctc_asyncGaugeVecDelete      DeleteAdapter            = (*asyncGaugeVecAdapter)(nil)
+	__ctc_asyncHistogramVecDelete  DeleteAdapter            = (*asyncHistogramVecAdapter)(nil)
+	__ctc_asyncSummaryVecDelete    DeleteAdapter            = (*asyncSummaryVecAdapter)(nil)
+	__ctc_asyncCounterExemplar     ExemplarCounterAdapter   = (*asyncCounterAdapter)(nil)
+	__ctc_asyncHistogramExemplar   ExemplarHistogramAdapter = (*asyncHistogramAdapter)(nil)
+
+	__ctc_asyncCounterVecInitAdapter   VecInitAdapter = (*asyncCounterVecAdapter)(nil)
+	__ctc_asyncGaugeVecInitAdapter     VecInitAdapter = (*asyncGaugeVecAdapter)(nil)
+	__ctc_asyncHistogramVecInitAdapter VecInitAdapter = (*asyncHistogramVecAdapter)(nil)
+	__ctc_asyncSummaryVecInitAdapter   VecInitAdapter = (*asyncSummaryVecAdapter)(nil)
+)
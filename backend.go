@@ -27,10 +27,288 @@ type Backend interface {
 	Histogram(opts HistogramOpts) HistogramAdapter
 	HistogramVec(opts HistogramVecOpts) HistogramVecAdapter
 	Summary(opts SummaryOpts) SummaryAdapter
-	SummaryVec(opts SummaryVecOpts) SummaryVecAdapater
+	SummaryVec(opts SummaryVecOpts) SummaryVecAdapter
 	Name() string
 }
 
+// GaugeFuncBackend is an optional [Backend] extension for backends that can
+// read a gauge's value from a function at collection time (e.g. Prometheus'
+// GaugeFunc), instead of having it pushed through a [GaugeAdapter].
+//
+// See [Factory.GaugeFromAtomic] and [Factory.GaugeFunc].
+type GaugeFuncBackend interface {
+	GaugeFunc(opts GaugeOpts, fn func() float64)
+}
+
+// CounterFuncBackend is an optional [Backend] extension for backends that can
+// read a counter's value from a function at collection time (e.g. Prometheus'
+// CounterFunc), instead of having it pushed through a [CounterAdapter].
+//
+// See [Factory.CounterGroup] and [Factory.CounterFunc].
+type CounterFuncBackend interface {
+	CounterFunc(opts CounterOpts, fn func() float64)
+}
+
+// wrapperBackend is implemented by the internal backends wrapping another
+// backend, e.g. the [recoverBackend], which implement [GaugeFuncBackend] and
+// [CounterFuncBackend] by forwarding them to the wrapped backend
+type wrapperBackend interface {
+	wrapped() Backend
+}
+
+// fanOutBackend is implemented by the internal backends fanning out to
+// several backends, e.g. the [mirrorBackend], which implement
+// [GaugeFuncBackend] and [CounterFuncBackend] by forwarding them to each of
+// those backends
+type fanOutBackend interface {
+	backends() []Backend
+}
+
+// gaugeFuncBackend returns backend as a [GaugeFuncBackend], and false if it
+// isn't one, or wraps or fans out to a backend that isn't one
+func gaugeFuncBackend(backend Backend) (GaugeFuncBackend, bool) {
+	funcs, ok := backend.(GaugeFuncBackend)
+	if wrapper, isWrapper := backend.(wrapperBackend); ok && isWrapper {
+		_, ok = gaugeFuncBackend(wrapper.wrapped())
+	}
+	if fanOut, isFanOut := backend.(fanOutBackend); ok && isFanOut {
+		for _, target := range fanOut.backends() {
+			if _, ok = gaugeFuncBackend(target); !ok {
+				break
+			}
+		}
+	}
+	return funcs, ok
+}
+
+// counterFuncBackend returns backend as a [CounterFuncBackend], and false if
+// it isn't one, or wraps or fans out to a backend that isn't one
+func counterFuncBackend(backend Backend) (CounterFuncBackend, bool) {
+	funcs, ok := backend.(CounterFuncBackend)
+	if wrapper, isWrapper := backend.(wrapperBackend); ok && isWrapper {
+		_, ok = counterFuncBackend(wrapper.wrapped())
+	}
+	if fanOut, isFanOut := backend.(fanOutBackend); ok && isFanOut {
+		for _, target := range fanOut.backends() {
+			if _, ok = counterFuncBackend(target); !ok {
+				break
+			}
+		}
+	}
+	return funcs, ok
+}
+
+// UnregisterBackend is an optional [Backend] extension for backends that can
+// remove a previously created metric, e.g. to replace it (see [Summary.Reconfigure]).
+type UnregisterBackend interface {
+	// Unregister removes the metric with the given qualified name (see
+	// [MetricInfo.QualifiedName]), returning false if no such metric exists
+	Unregister(name string) bool
+}
+
+// GatherBackend is an optional [Backend] extension for backends that can
+// report the current values of their metrics, see [Registry.MetricValue].
+type GatherBackend interface {
+	// Gather returns the current value of every metric of the backend
+	Gather() ([]MetricFamily, error)
+}
+
+// CapabilityBackend is an optional [Backend] extension for backends that don't
+// record every kind of metric, e.g. a backend whose summary adapters are noops.
+// Backends that don't implement it are assumed to record every kind.
+//
+// See [Group.BackendSupports].
+type CapabilityBackend interface {
+	// Supports returns true if metrics of the kind are recorded
+	Supports(kind MetricKind) bool
+}
+
+// backendSupports returns true if backend records metrics of the kind, see
+// [CapabilityBackend]. Unknown kinds are not supported.
+func backendSupports(backend Backend, kind MetricKind) bool {
+	switch kind {
+	case MetricKindCounter, MetricKindGauge, MetricKindHistogram, MetricKindSummary:
+	default:
+		return false
+	}
+
+	capable, ok := backend.(CapabilityBackend)
+	return !ok || capable.Supports(kind)
+}
+
+// ValueAdapter is an optional extension of the [CounterAdapter] and
+// [GaugeAdapter] for backends that can read back the current value of a
+// metric, see [Counter.Value] and [Gauge.Value].
+type ValueAdapter interface {
+	// Value returns the current value of the metric
+	Value() (float64, error)
+}
+
+// adapterValue returns the current value of adapter if it is a [ValueAdapter],
+// and [ErrUnsupportedMetric] otherwise
+func adapterValue(adapter any) (float64, error) {
+	reader, ok := adapter.(ValueAdapter)
+	if !ok {
+		return 0, ErrUnsupportedMetric
+	}
+	return reader.Value()
+}
+
+// SeriesAdapter is an optional extension of the [CounterVecAdapter] for
+// backends that can enumerate the series of a metric, see [CounterVec.Collect].
+type SeriesAdapter interface {
+	// Series returns a snapshot of the labels and value of every series, in
+	// no particular order
+	Series() ([]Sample, error)
+}
+
+// adapterSeries returns the series of adapter if it is a [SeriesAdapter],
+// and [ErrUnsupportedMetric] otherwise
+func adapterSeries(adapter any) ([]Sample, error) {
+	enumerator, ok := adapter.(SeriesAdapter)
+	if !ok {
+		return nil, ErrUnsupportedMetric
+	}
+	return enumerator.Series()
+}
+
+// DeleteAdapter is an optional extension of the [CounterVecAdapter],
+// [GaugeVecAdapter], [HistogramVecAdapter], and [SummaryVecAdapter] for
+// backends that can remove series, see [CounterVec.Delete].
+type DeleteAdapter interface {
+	// Delete removes the series of the labels, returning false if there is
+	// no such series
+	Delete(labels VecLabels) bool
+
+	// Reset removes every series
+	Reset()
+}
+
+// adapterDelete removes the series of the labels from adapter if it is a
+// [DeleteAdapter], and returns false otherwise
+func adapterDelete(adapter any, labels VecLabels) bool {
+	deleter, ok := adapter.(DeleteAdapter)
+	if !ok {
+		return false
+	}
+	return deleter.Delete(labels)
+}
+
+// adapterReset removes every series of adapter if it is a [DeleteAdapter]
+func adapterReset(adapter any) {
+	if deleter, ok := adapter.(DeleteAdapter); ok {
+		deleter.Reset()
+	}
+}
+
+// ExemplarCounterAdapter is an optional extension of the [CounterAdapter] for
+// backends that can attach an exemplar, e.g. the trace ID of a request, to
+// the values added to a counter, see [Counter.AddWithExemplar].
+type ExemplarCounterAdapter interface {
+	AddWithExemplar(value float64, exemplar VecLabels) error
+}
+
+// addWithExemplar adds value to adapter with exemplar if it is an
+// [ExemplarCounterAdapter], and without it otherwise
+func addWithExemplar(adapter CounterAdapter, value float64, exemplar VecLabels) error {
+	if exemplarAdder, ok := adapter.(ExemplarCounterAdapter); ok {
+		return exemplarAdder.AddWithExemplar(value, exemplar)
+	}
+	return adapter.Add(value)
+}
+
+// ExemplarHistogramAdapter is an optional extension of the [HistogramAdapter]
+// for backends that can attach an exemplar to an observation, see
+// [Histogram.ObserveWithExemplar].
+type ExemplarHistogramAdapter interface {
+	ObserveWithExemplar(value float64, exemplar VecLabels) error
+}
+
+// observeWithExemplar observes value on adapter with exemplar if it is an
+// [ExemplarHistogramAdapter], and without it otherwise
+func observeWithExemplar(adapter HistogramAdapter, value float64, exemplar VecLabels) error {
+	if exemplarObserver, ok := adapter.(ExemplarHistogramAdapter); ok {
+		return exemplarObserver.ObserveWithExemplar(value, exemplar)
+	}
+	return adapter.Observe(value)
+}
+
+// LabelValuesAdapter is an optional extension of the [CounterVecAdapter] for
+// backends that can bind the series of positional label values to a counter
+// adapter, so that its operations skip resolving the series from a label map,
+// see [CounterVec.WithLabelValues].
+type LabelValuesAdapter interface {
+	// WithLabelValues returns the adapter of the series of values, given in
+	// the declared label order
+	WithLabelValues(values ...string) (CounterAdapter, error)
+}
+
+// adapterLabelValues returns the adapter of the series of values on adapter
+// if it is a [LabelValuesAdapter], and [ErrUnsupportedMetric] otherwise
+func adapterLabelValues(adapter any, values []string) (CounterAdapter, error) {
+	binder, ok := adapter.(LabelValuesAdapter)
+	if !ok {
+		return nil, ErrUnsupportedMetric
+	}
+	return binder.WithLabelValues(values...)
+}
+
+// vecCounterAdapter is the [CounterAdapter] of the series of labels of a
+// [CounterVecAdapter] that isn't a [LabelValuesAdapter]
+type vecCounterAdapter struct {
+	internal CounterVecAdapter
+	labels   VecLabels
+}
+
+func (a *vecCounterAdapter) Inc() error {
+	return a.internal.Inc(a.labels)
+}
+
+func (a *vecCounterAdapter) Add(value float64) error {
+	return a.internal.Add(value, a.labels)
+}
+
+// FlushableBackend is a [Backend] buffering recorded values, e.g. to send them
+// to a remote system in batches, see [NewPeriodicFlushBackend].
+type FlushableBackend interface {
+	Backend
+
+	// Flush sends the buffered values
+	Flush() error
+}
+
+// VecInitAdapter is an optional extension of the label-vectorized adapters
+// for backends that can create the series of a set of labels without
+// recording into it, see [CounterVecOpts.PreInitLabels].
+//
+// Counter and gauge vecs whose adapter doesn't implement it are initialized
+// by adding 0. Histogram and summary vecs have no such fallback, and are
+// not initialized.
+type VecInitAdapter interface {
+	InitLabels(labels VecLabels) error
+}
+
+// initVecSeries creates the series of labels on adapter if it is a
+// [VecInitAdapter], or calls fallback (if any) otherwise
+func initVecSeries(adapter any, labels VecLabels, fallback func(labels VecLabels) error) error {
+	if initializer, ok := adapter.(VecInitAdapter); ok {
+		return initializer.InitLabels(labels)
+	}
+	if fallback != nil {
+		return fallback(labels)
+	}
+	return nil
+}
+
+// preInitVec creates the series of each of labelSets on a vec adapter, see
+// [CounterVecOpts.PreInitLabels]. Like recording, this is best effort, and
+// errors are dropped.
+func preInitVec(adapter any, labelSets []VecLabels, fallback func(labels VecLabels) error) {
+	for _, labels := range labelSets {
+		_ = initVecSeries(adapter, labels, fallback)
+	}
+}
+
 // CounterAdapter defines the interface for counter metrics that concrete
 // backend adapter implementations must satisfy.
 type CounterAdapter interface {
@@ -78,11 +356,18 @@ type SummaryAdapter interface {
 	Quantile(q float64) (float64, error)
 }
 
-type SummaryVecAdapater interface {
+// SummaryVecAdapter defines the interface for label-vectorized summary metrics
+// that concrete backend adapter implementations must satisfy.
+type SummaryVecAdapter interface {
 	Observe(value float64, labels VecLabels) error
 	Quantile(q float64, labels VecLabels) (float64, error)
 }
 
+// SummaryVecAdapater is the original, misspelled name of [SummaryVecAdapter].
+//
+// Deprecated: Use [SummaryVecAdapter].
+type SummaryVecAdapater = SummaryVecAdapter
+
 const (
 	BackendNoneName string = "none"
 )
@@ -38,9 +38,21 @@ package umami
 // - baseCircuitBreakerVec (composes a GaugeVec and CounterVecs)
 // - baseQueue (composes a Gauge, Counters, and a Histogram)
 // - baseQueueVec (composes a GaugeVec, CounterVecs, and a HistogramVec)
+// - baseThroughput (composes a Counter and a Gauge)
+// - baseDistributionGauge (composes a Gauge and a Histogram)
+// - baseMultiResHistogram (composes Histograms)
+// - baseDependency (composes a CounterVec, a HistogramVec, and a GaugeVec)
 //--------------------------------------------------------------------------------
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
 //--------------------------------------------------------------------------------
 // Basic Base Metric Implementations
@@ -60,9 +72,86 @@ import "time"
 // override the [baseMetric.SetLevel] method to propagate level changes,
 // to composed basic metrics.
 type baseMetric struct {
-	level Level
-	name  string
-	help  string
+	level      Level
+	name       string
+	help       string
+	predicate  func(ctx Context) bool
+	tags       []string
+	tagFilter  *tagFilter  // Disabled tags of the creating group, if any
+	mask       Mask        // Categories of the metric, see [Mask]
+	maskFilter *maskFilter // Mask of the creating group, if any
+	slo        *SLOSpec    // See [MetricInfo.SLO]
+
+	// groupDisabled caches whether the level is not enabled by the level of
+	// the creating group (1) or is (0), so that operations of metrics disabled
+	// by their group are rejected with a single atomic load. It is recomputed
+	// eagerly by [group.SetGroupLevel], see [baseMetric.cacheEnabled].
+	//
+	// It is a plain uint32 accessed atomically, rather than an [atomic.Bool],
+	// as composites copy their baseMetric on construction.
+	groupDisabled uint32
+
+	// groupPaused caches whether the level of the creating group is
+	// [LevelDisabled] (1) or not (0). Unlike groupDisabled, it also rejects
+	// the operations of forced contexts, see [Context.WithForce].
+	groupPaused uint32
+}
+
+// enabled returns true if an operation on this metric should be processed
+// under the given context.
+//
+// The level is checked first, against the level of the creating group and
+// then the context (and passes for a forced context, see [Context.WithForce],
+// unless the creating group is paused), then the tags and the mask, and the predicate, if any, is only consulted
+// when all checks pass.
+func (b *baseMetric) enabled(ctx Context) bool {
+	if !ctx.Sampled() || atomic.LoadUint32(&b.groupPaused) == 1 {
+		return false
+	}
+	if (atomic.LoadUint32(&b.groupDisabled) == 1 || !ctx.Enabled(b.level)) && !ctx.Forced() {
+		return false
+	}
+	if len(b.tags) > 0 && b.tagFilter != nil && b.tagFilter.anyDisabled(b.tags) {
+		return false
+	}
+	if !ctx.EnabledMask(b.mask) || (b.maskFilter != nil && !b.maskFilter.allows(b.mask)) {
+		return false
+	}
+	return b.predicate == nil || b.predicate(ctx)
+}
+
+// collected returns true if the metric should be read at collection time,
+// i.e. if it is enabled by the level, the tags and the mask of the creating
+// group, see [group.readGauge]
+func (b *baseMetric) collected() bool {
+	return b.enabled(NewContextAll())
+}
+
+// checkCounterAdd returns an error if value can't be added to a counter,
+// before it reaches a backend that may panic on it
+func (b *baseMetric) checkCounterAdd(value float64) error {
+	switch {
+	case math.IsNaN(value):
+		return fmt.Errorf("%w: %s: %v", ErrInvalidValue, b.name, value)
+	case value < 0:
+		return fmt.Errorf("%w: %s: %v", ErrNegativeCounterAdd, b.name, value)
+	}
+	return nil
+}
+
+// cacheEnabled recomputes whether the level is enabled by groupLevel, the
+// level of the creating group, and whether the group is paused, see
+// [baseMetric.groupDisabled] and [baseMetric.groupPaused]
+func (b *baseMetric) cacheEnabled(groupLevel Level) {
+	var disabled, paused uint32
+	if !b.level.Enabled(groupLevel) {
+		disabled = 1
+	}
+	if groupLevel == LevelDisabled {
+		paused = 1
+	}
+	atomic.StoreUint32(&b.groupDisabled, disabled)
+	atomic.StoreUint32(&b.groupPaused, paused)
 }
 
 func (b *baseMetric) Name() string {
@@ -90,14 +179,26 @@ func (b *baseMetric) Level() Level {
 // It embeds [baseMetric] to inherit common functionality, but overrides
 // the [SetLevel] method to propagate level changes to composed metrics.
 //
+// Operations of inheriting structs check [baseMetric.enabled] before
+// delegating to their components, so that a composite disabled by the context
+// records nothing, even if some of its components would be enabled.
+//
 // Note: Inheriting structs must implement the [CompositeMetric.Components] method to
-// return the composed metrics for level propagation by [baseCompositeMetric.SetLevel].
+// return the composed metrics, and override [baseCompositeMetric.SetLevel] to call
+// [baseCompositeMetric.setLevel] with them, since Go has no virtual dispatch and
+// the embedded SetLevel cannot see the inheriting struct's Components.
 type baseCompositeMetric struct {
 	baseMetric
 }
 
 func (b *baseCompositeMetric) SetLevel(level Level) {
-	for _, component := range b.Components() {
+	b.setLevel(level, b.Components())
+}
+
+// setLevel sets the level of the composite and propagates it to the components
+func (b *baseCompositeMetric) setLevel(level Level, components []Metric) {
+	b.level = level
+	for _, component := range components {
 		component.SetLevel(level)
 	}
 }
@@ -124,116 +225,489 @@ type baseCounter struct {
 }
 
 func (c *baseCounter) Inc(ctx Context) error {
-	if !ctx.Enabled(c.level) {
+	if !c.enabled(ctx) {
 		return nil
 	}
 	return c.adapter.Inc()
 }
 
 func (c *baseCounter) Add(ctx Context, value float64) error {
-	if !ctx.Enabled(c.level) {
+	if !c.enabled(ctx) {
 		return nil
 	}
+	if err := c.checkCounterAdd(value); err != nil {
+		return err
+	}
 	return c.adapter.Add(value)
 }
 
+func (c *baseCounter) IncWithExemplar(ctx Context, exemplar VecLabels) error {
+	return c.AddWithExemplar(ctx, 1, exemplar)
+}
+
+func (c *baseCounter) AddWithExemplar(ctx Context, value float64, exemplar VecLabels) error {
+	if !c.enabled(ctx) {
+		return nil
+	}
+	if err := c.checkCounterAdd(value); err != nil {
+		return err
+	}
+	return addWithExemplar(c.adapter, value, exemplar)
+}
+
+func (c *baseCounter) Value(ctx Context) (float64, error) {
+	if !c.enabled(ctx) {
+		return 0, nil
+	}
+	return adapterValue(c.adapter)
+}
+
+// baseCounterGroup is a [CounterGroup] summing its members.
+//
+// The backend reads the sum at collection time (see [CounterFuncBackend]),
+// or samples it periodically.
+type baseCounterGroup struct {
+	baseMetric
+	members []Counter
+	sampler *sampler // Samples the sum, unless read by the backend
+}
+
+// Stop stops sampling the metric, if it is sampled
+func (c *baseCounterGroup) Stop() {
+	c.sampler.Stop()
+}
+
+func (c *baseCounterGroup) Value(ctx Context) (float64, error) {
+	if !c.enabled(ctx) {
+		return 0, nil
+	}
+	return c.sum()
+}
+
+func (c *baseCounterGroup) Members() []Counter {
+	return c.members
+}
+
+// sum returns the sum of the values of the members, read regardless of
+// their level like at collection time
+func (c *baseCounterGroup) sum() (float64, error) {
+	ctx := NewContextAll().WithForce()
+
+	var sum float64
+	var errs []error
+	for _, member := range c.members {
+		value, err := member.Value(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", member.Name(), err))
+			continue
+		}
+		sum += value
+	}
+	return sum, errors.Join(errs...)
+}
+
+// baseCounterFunc is a [CounterFunc] returning the value of a function.
+//
+// The backend calls the function at collection time (see
+// [CounterFuncBackend]), or samples it periodically.
+type baseCounterFunc struct {
+	baseMetric
+	fn      func() float64
+	sampler *sampler // Samples fn, unless read by the backend
+}
+
+// Stop stops sampling the metric, if it is sampled
+func (c *baseCounterFunc) Stop() {
+	c.sampler.Stop()
+}
+
+func (c *baseCounterFunc) Value(ctx Context) (float64, error) {
+	if !c.enabled(ctx) {
+		return 0, nil
+	}
+	return c.fn(), nil
+}
+
 type baseCounterVec struct {
 	baseMetric
 	adapter CounterVecAdapter
+	labels  []string // Declared label names, see [CounterVec.WithLabelValues]
 }
 
 func (cv *baseCounterVec) Inc(ctx Context, labels VecLabels) error {
-	if !ctx.Enabled(cv.level) {
+	if !cv.enabled(ctx) {
 		return nil
 	}
 	return cv.adapter.Inc(labels)
 }
 
 func (cv *baseCounterVec) Add(ctx Context, value float64, labels VecLabels) error {
-	if !ctx.Enabled(cv.level) {
+	if !cv.enabled(ctx) {
 		return nil
 	}
+	if err := cv.checkCounterAdd(value); err != nil {
+		return err
+	}
 	return cv.adapter.Add(value, labels)
 }
 
+func (cv *baseCounterVec) Collect(ctx Context) ([]Sample, error) {
+	if !cv.enabled(ctx) {
+		return nil, nil
+	}
+	return adapterSeries(cv.adapter)
+}
+
+func (cv *baseCounterVec) WithLabelValues(ctx Context, values ...string) Counter {
+	values = slices.Clone(values)
+	c := &labelValuesCounter{
+		baseMetric: &cv.baseMetric,
+		bind:       bindUntilBound(func() (CounterAdapter, error) { return cv.bindLabelValues(values) }),
+	}
+	if cv.enabled(ctx) {
+		_, _ = c.bind()
+	}
+	return c
+}
+
+// bindLabelValues returns the adapter of the series of values, bound by the
+// backend if it is a [LabelValuesAdapter], see [CounterVec.WithLabelValues]
+func (cv *baseCounterVec) bindLabelValues(values []string) (CounterAdapter, error) {
+	if len(values) != len(cv.labels) {
+		return nil, fmt.Errorf("%w: got %d label values, declared %v", ErrLabelMismatch, len(values), cv.labels)
+	}
+
+	adapter, err := adapterLabelValues(cv.adapter, values)
+	if !errors.Is(err, ErrUnsupportedMetric) {
+		return adapter, err
+	}
+
+	labels := make(VecLabels, len(values))
+	for i, name := range cv.labels {
+		labels[name] = values[i]
+	}
+	return &vecCounterAdapter{internal: cv.adapter, labels: labels}, nil
+}
+
+func (cv *baseCounterVec) Delete(ctx Context, labels VecLabels) bool {
+	if !cv.enabled(ctx) {
+		return false
+	}
+	return adapterDelete(cv.adapter, labels)
+}
+
+func (cv *baseCounterVec) Reset(ctx Context) {
+	if !cv.enabled(ctx) {
+		return
+	}
+	adapterReset(cv.adapter)
+}
+
+// bindUntilBound returns a function calling bind until it succeeds, and
+// returning its adapter from then on. Failed binds aren't cached, as they
+// may succeed later, e.g. once a series over a cardinality limit is deleted.
+func bindUntilBound(bind func() (CounterAdapter, error)) func() (CounterAdapter, error) {
+	var (
+		mu    sync.Mutex
+		bound atomic.Pointer[CounterAdapter]
+	)
+	return func() (CounterAdapter, error) {
+		if adapter := bound.Load(); adapter != nil {
+			return *adapter, nil
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if adapter := bound.Load(); adapter != nil {
+			return *adapter, nil
+		}
+		adapter, err := bind()
+		if err != nil {
+			return nil, err
+		}
+		bound.Store(&adapter)
+		return adapter, nil
+	}
+}
+
+// labelValuesCounter is the [Counter] of a series of a [baseCounterVec], see
+// [CounterVec.WithLabelValues]. It shares the baseMetric of the vec, so that
+// it follows its level.
+type labelValuesCounter struct {
+	*baseMetric
+	bind func() (CounterAdapter, error)
+}
+
+func (c *labelValuesCounter) Inc(ctx Context) error {
+	if !c.enabled(ctx) {
+		return nil
+	}
+	adapter, err := c.bind()
+	if err != nil {
+		return err
+	}
+	return adapter.Inc()
+}
+
+func (c *labelValuesCounter) Add(ctx Context, value float64) error {
+	if !c.enabled(ctx) {
+		return nil
+	}
+	if err := c.checkCounterAdd(value); err != nil {
+		return err
+	}
+	adapter, err := c.bind()
+	if err != nil {
+		return err
+	}
+	return adapter.Add(value)
+}
+
+func (c *labelValuesCounter) IncWithExemplar(ctx Context, exemplar VecLabels) error {
+	return c.AddWithExemplar(ctx, 1, exemplar)
+}
+
+func (c *labelValuesCounter) AddWithExemplar(ctx Context, value float64, exemplar VecLabels) error {
+	if !c.enabled(ctx) {
+		return nil
+	}
+	if err := c.checkCounterAdd(value); err != nil {
+		return err
+	}
+	adapter, err := c.bind()
+	if err != nil {
+		return err
+	}
+	return addWithExemplar(adapter, value, exemplar)
+}
+
+func (c *labelValuesCounter) Value(ctx Context) (float64, error) {
+	if !c.enabled(ctx) {
+		return 0, nil
+	}
+	adapter, err := c.bind()
+	if err != nil {
+		return 0, err
+	}
+	return adapterValue(adapter)
+}
+
+// apply returns value clamped into the bounds, or an error wrapping
+// [ErrOutOfBounds] if the bounds are strict and value is out of range.
+// NaN values are always rejected. A nil bounds allows any value.
+func (b *GaugeBounds) apply(name string, value float64) (float64, error) {
+	switch {
+	case b == nil:
+		return value, nil
+	case math.IsNaN(value):
+		return 0, fmt.Errorf("%w: %s: %v", ErrInvalidValue, name, value)
+	case value >= b.Min && value <= b.Max:
+		return value, nil
+	case b.Strict:
+		return 0, fmt.Errorf("%w: %s: %v is not in [%v, %v]", ErrOutOfBounds, name, value, b.Min, b.Max)
+	default:
+		return min(max(value, b.Min), b.Max), nil
+	}
+}
+
 type baseGauge struct {
 	baseMetric
 	adapter GaugeAdapter
+	bounds  *GaugeBounds // See [GaugeOpts.Bounds]
 }
 
 func (g *baseGauge) Set(ctx Context, value float64) error {
-	if !ctx.Enabled(g.level) {
+	if !g.enabled(ctx) {
 		return nil
 	}
+	value, err := g.bounds.apply(g.name, value)
+	if err != nil {
+		return err
+	}
 	return g.adapter.Set(value)
 }
 
 func (g *baseGauge) Inc(ctx Context) error {
-	if !ctx.Enabled(g.level) {
+	if !g.enabled(ctx) {
 		return nil
 	}
 	return g.adapter.Inc()
 }
 
 func (g *baseGauge) Dec(ctx Context) error {
-	if !ctx.Enabled(g.level) {
+	if !g.enabled(ctx) {
 		return nil
 	}
 	return g.adapter.Dec()
 }
 
 func (g *baseGauge) Add(ctx Context, value float64) error {
-	if !ctx.Enabled(g.level) {
+	if !g.enabled(ctx) {
 		return nil
 	}
 	return g.adapter.Add(value)
 }
 
+func (g *baseGauge) Value(ctx Context) (float64, error) {
+	if !g.enabled(ctx) {
+		return 0, nil
+	}
+	return adapterValue(g.adapter)
+}
+
+// baseAtomicGauge is a [Gauge] reflecting a caller owned [atomic.Int64].
+//
+// Operations write through to the atomic, and the backend reads the atomic
+// at collection time (see [GaugeFuncBackend]), or samples it periodically.
+type baseAtomicGauge struct {
+	baseMetric
+	value   *atomic.Int64
+	bounds  *GaugeBounds // See [GaugeOpts.Bounds]
+	sampler *sampler     // Samples value, unless read by the backend
+}
+
+// Stop stops sampling the value, if it is sampled
+func (g *baseAtomicGauge) Stop() {
+	g.sampler.Stop()
+}
+
+func (g *baseAtomicGauge) Set(ctx Context, value float64) error {
+	if !g.enabled(ctx) {
+		return nil
+	}
+	value, err := g.bounds.apply(g.name, value)
+	if err != nil {
+		return err
+	}
+	g.value.Store(int64(value))
+	return nil
+}
+
+func (g *baseAtomicGauge) Inc(ctx Context) error {
+	return g.Add(ctx, 1)
+}
+
+func (g *baseAtomicGauge) Dec(ctx Context) error {
+	return g.Add(ctx, -1)
+}
+
+func (g *baseAtomicGauge) Add(ctx Context, value float64) error {
+	if !g.enabled(ctx) {
+		return nil
+	}
+	g.value.Add(int64(value))
+	return nil
+}
+
+// Value returns the current value of the caller owned value
+func (g *baseAtomicGauge) Value(ctx Context) (float64, error) {
+	if !g.enabled(ctx) {
+		return 0, nil
+	}
+	return float64(g.value.Load()), nil
+}
+
+// baseGaugeFunc is a [GaugeFunc] returning the value of a function.
+//
+// The backend calls the function at collection time (see [GaugeFuncBackend]),
+// or samples it periodically.
+type baseGaugeFunc struct {
+	baseMetric
+	fn      func() float64
+	sampler *sampler // Samples fn, unless read by the backend
+}
+
+// Stop stops sampling the metric, if it is sampled
+func (g *baseGaugeFunc) Stop() {
+	g.sampler.Stop()
+}
+
+func (g *baseGaugeFunc) Value(ctx Context) (float64, error) {
+	if !g.enabled(ctx) {
+		return 0, nil
+	}
+	return g.fn(), nil
+}
+
 type baseGaugeVec struct {
 	baseMetric
 	adapter GaugeVecAdapter
 }
 
 func (gv *baseGaugeVec) Set(ctx Context, value float64, labels VecLabels) error {
-	if !ctx.Enabled(gv.level) {
+	if !gv.enabled(ctx) {
 		return nil
 	}
 	return gv.adapter.Set(value, labels)
 }
 
 func (gv *baseGaugeVec) Inc(ctx Context, labels VecLabels) error {
-	if !ctx.Enabled(gv.level) {
+	if !gv.enabled(ctx) {
 		return nil
 	}
 	return gv.adapter.Inc(labels)
 }
 
 func (gv *baseGaugeVec) Dec(ctx Context, labels VecLabels) error {
-	if !ctx.Enabled(gv.level) {
+	if !gv.enabled(ctx) {
 		return nil
 	}
 	return gv.adapter.Dec(labels)
 }
 
 func (gv *baseGaugeVec) Add(ctx Context, value float64, labels VecLabels) error {
-	if !ctx.Enabled(gv.level) {
+	if !gv.enabled(ctx) {
 		return nil
 	}
 	return gv.adapter.Add(value, labels)
 }
 
+func (gv *baseGaugeVec) Delete(ctx Context, labels VecLabels) bool {
+	if !gv.enabled(ctx) {
+		return false
+	}
+	return adapterDelete(gv.adapter, labels)
+}
+
+func (gv *baseGaugeVec) Reset(ctx Context) {
+	if !gv.enabled(ctx) {
+		return
+	}
+	adapterReset(gv.adapter)
+}
+
 type baseHistogram struct {
 	baseMetric
 	adapter HistogramAdapter
 }
 
 func (h *baseHistogram) Observe(ctx Context, value float64) error {
-	if !ctx.Enabled(h.level) {
+	if !h.enabled(ctx) {
 		return nil
 	}
 	return h.adapter.Observe(value)
 }
 
+func (h *baseHistogram) ObserveWithExemplar(ctx Context, value float64, exemplar VecLabels) error {
+	if !h.enabled(ctx) {
+		return nil
+	}
+	return observeWithExemplar(h.adapter, value, exemplar)
+}
+
+func (h *baseHistogram) ObserveBytes(ctx Context, n int64) error {
+	return h.Observe(ctx, float64(n))
+}
+
+func (h *baseHistogram) Time(ctx Context, fn func() error) error {
+	if !h.enabled(ctx) {
+		return fn()
+	}
+	start := time.Now()
+	defer func() { _ = h.adapter.Observe(time.Since(start).Seconds()) }()
+	return fn()
+}
+
 // histogram wraps a HistogramBackend and implements early return
 type baseHistogramVec struct {
 	baseMetric
@@ -241,19 +715,42 @@ type baseHistogramVec struct {
 }
 
 func (hv *baseHistogramVec) Observe(ctx Context, value float64, labels VecLabels) error {
-	if !ctx.Enabled(hv.level) {
+	if !hv.enabled(ctx) {
 		return nil
 	}
 	return hv.adapter.Observe(value, labels)
 }
 
+func (hv *baseHistogramVec) Time(ctx Context, fn func() error, labels VecLabels) error {
+	if !hv.enabled(ctx) {
+		return fn()
+	}
+	start := time.Now()
+	defer func() { _ = hv.adapter.Observe(time.Since(start).Seconds(), labels) }()
+	return fn()
+}
+
+func (hv *baseHistogramVec) Delete(ctx Context, labels VecLabels) bool {
+	if !hv.enabled(ctx) {
+		return false
+	}
+	return adapterDelete(hv.adapter, labels)
+}
+
+func (hv *baseHistogramVec) Reset(ctx Context) {
+	if !hv.enabled(ctx) {
+		return
+	}
+	adapterReset(hv.adapter)
+}
+
 type baseSummary struct {
 	baseMetric
 	adapter SummaryAdapter
 }
 
 func (s *baseSummary) Observe(ctx Context, value float64) error {
-	if !ctx.Enabled(s.level) {
+	if !s.enabled(ctx) {
 		return nil
 	}
 
@@ -261,32 +758,51 @@ func (s *baseSummary) Observe(ctx Context, value float64) error {
 }
 
 func (s *baseSummary) Quantile(ctx Context, q float64) (float64, error) {
-	if !ctx.Enabled(s.level) {
+	if !s.enabled(ctx) {
 		return 0, nil
 	}
 
 	return s.adapter.Quantile(q)
 }
 
+// Reconfigure is only supported through the switchable wrapper created by a [Group]
+func (s *baseSummary) Reconfigure(opts SummaryOpts) error {
+	return ErrNotReconfigurable
+}
+
 type baseSummaryVec struct {
 	baseMetric
-	adapter SummaryVecAdapater
+	adapter SummaryVecAdapter
 }
 
 func (sv *baseSummaryVec) Observe(ctx Context, value float64, labels VecLabels) error {
-	if !ctx.Enabled(sv.level) {
+	if !sv.enabled(ctx) {
 		return nil
 	}
 	return sv.adapter.Observe(value, labels)
 }
 
 func (sv *baseSummaryVec) Quantile(ctx Context, q float64, labels VecLabels) (float64, error) {
-	if !ctx.Enabled(sv.level) {
+	if !sv.enabled(ctx) {
 		return 0, nil
 	}
 	return sv.adapter.Quantile(q, labels)
 }
 
+func (sv *baseSummaryVec) Delete(ctx Context, labels VecLabels) bool {
+	if !sv.enabled(ctx) {
+		return false
+	}
+	return adapterDelete(sv.adapter, labels)
+}
+
+func (sv *baseSummaryVec) Reset(ctx Context) {
+	if !sv.enabled(ctx) {
+		return
+	}
+	adapterReset(sv.adapter)
+}
+
 //--------------------------------------------------------------------------------
 // Composite Base Metric Implementations.
 //
@@ -305,25 +821,56 @@ func (sv *baseSummaryVec) Quantile(ctx Context, q float64, labels VecLabels) (fl
 //
 // Required overrides:
 // - Components() to return composed metrics for level propagation
+// - SetLevel(level) to call setLevel(level, Components())
 //--------------------------------------------------------------------------------
 
 type baseTimer struct {
 	baseCompositeMetric
 	histogram Histogram
+	clock     Clock
 }
 
 func (t *baseTimer) Start(ctx Context) func() {
-	start := time.Now()
+	if !t.enabled(ctx) {
+		return func() {}
+	}
+	start := t.clock.Now()
 	return func() {
-		duration := time.Since(start)
+		duration := t.clock.Now().Sub(start)
 		t.histogram.Observe(ctx, duration.Seconds())
 	}
 }
 
+func (t *baseTimer) StartFunc(ctx Context, fn func() error) error {
+	defer t.Start(ctx)()
+	return fn()
+}
+
 func (t *baseTimer) Record(ctx Context, duration time.Duration) error {
+	if !t.enabled(ctx) {
+		return nil
+	}
 	return t.histogram.Observe(ctx, duration.Seconds())
 }
 
+func (t *baseTimer) RecordFromContextStart(ctx Context) error {
+	start, ok := ctx.Start()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoStartTime, t.name)
+	}
+	return t.Record(ctx, t.clock.Now().Sub(start))
+}
+
+func (t *baseTimer) AsObserver(ctx Context) DurationObserver {
+	return func(duration time.Duration) {
+		_ = t.Record(ctx, duration)
+	}
+}
+
+func (t *baseTimer) SetLevel(level Level) {
+	t.setLevel(level, t.Components())
+}
+
 func (t *baseTimer) Components() []Metric {
 	return []Metric{t.histogram}
 }
@@ -331,24 +878,63 @@ func (t *baseTimer) Components() []Metric {
 type baseTimerVec struct {
 	baseCompositeMetric
 	histogramVec HistogramVec
+	clock        Clock
 }
 
 func (tv *baseTimerVec) Start(ctx Context, labels VecLabels) func() {
-	start := time.Now()
+	if !tv.enabled(ctx) {
+		return func() {}
+	}
+	start := tv.clock.Now()
 	return func() {
-		duration := time.Since(start)
+		duration := tv.clock.Now().Sub(start)
 		tv.histogramVec.Observe(ctx, duration.Seconds(), labels)
 	}
 }
 
 func (tv *baseTimerVec) Record(ctx Context, duration time.Duration, labels VecLabels) error {
+	if !tv.enabled(ctx) {
+		return nil
+	}
 	return tv.histogramVec.Observe(ctx, duration.Seconds(), labels)
 }
 
+func (tv *baseTimerVec) RecordFromContextStart(ctx Context, labels VecLabels) error {
+	start, ok := ctx.Start()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoStartTime, tv.name)
+	}
+	return tv.Record(ctx, tv.clock.Now().Sub(start), labels)
+}
+
+func (tv *baseTimerVec) AsObserverVec(ctx Context) DurationObserverVec {
+	return &timerObserverVec{timerVec: tv, ctx: ctx}
+}
+
+func (tv *baseTimerVec) SetLevel(level Level) {
+	tv.setLevel(level, tv.Components())
+}
+
 func (tv *baseTimerVec) Components() []Metric {
 	return []Metric{tv.histogramVec}
 }
 
+func (tv *baseTimerVec) Partition(labels VecLabels) Timer {
+	return &timerPartition{TimerVec: tv, labels: labels}
+}
+
+// timerObserverVec implements [DurationObserverVec] by recording into a [TimerVec]
+type timerObserverVec struct {
+	timerVec TimerVec
+	ctx      Context
+}
+
+func (o *timerObserverVec) With(labels VecLabels) DurationObserver {
+	return func(duration time.Duration) {
+		_ = o.timerVec.Record(o.ctx, duration, labels)
+	}
+}
+
 type baseCache struct {
 	baseCompositeMetric
 	hits   Counter
@@ -357,17 +943,30 @@ type baseCache struct {
 }
 
 func (c *baseCache) Hit(ctx Context) error {
+	if !c.enabled(ctx) {
+		return nil
+	}
 	return c.hits.Inc(ctx)
 }
 
 func (c *baseCache) Miss(ctx Context) error {
+	if !c.enabled(ctx) {
+		return nil
+	}
 	return c.misses.Inc(ctx)
 }
 
 func (c *baseCache) SetSize(ctx Context, bytes int64) error {
+	if !c.enabled(ctx) {
+		return nil
+	}
 	return c.size.Set(ctx, float64(bytes))
 }
 
+func (c *baseCache) SetLevel(level Level) {
+	c.setLevel(level, c.Components())
+}
+
 func (c *baseCache) Components() []Metric {
 	return []Metric{c.hits, c.misses, c.size}
 }
@@ -380,21 +979,38 @@ type baseCacheVec struct {
 }
 
 func (cv *baseCacheVec) Hit(ctx Context, labels VecLabels) error {
+	if !cv.enabled(ctx) {
+		return nil
+	}
 	return cv.hits.Inc(ctx, labels)
 }
 
 func (cv *baseCacheVec) Miss(ctx Context, labels VecLabels) error {
+	if !cv.enabled(ctx) {
+		return nil
+	}
 	return cv.misses.Inc(ctx, labels)
 }
 
 func (cv *baseCacheVec) SetSize(ctx Context, bytes int64, labels VecLabels) error {
+	if !cv.enabled(ctx) {
+		return nil
+	}
 	return cv.size.Set(ctx, float64(bytes), labels)
 }
 
+func (cv *baseCacheVec) SetLevel(level Level) {
+	cv.setLevel(level, cv.Components())
+}
+
 func (cv *baseCacheVec) Components() []Metric {
 	return []Metric{cv.hits, cv.misses, cv.size}
 }
 
+func (cv *baseCacheVec) Partition(labels VecLabels) Cache {
+	return &cachePartition{CacheVec: cv, labels: labels}
+}
+
 type basePool struct {
 	baseCompositeMetric
 	active   Gauge
@@ -404,21 +1020,37 @@ type basePool struct {
 }
 
 func (p *basePool) SetActive(ctx Context, count int) error {
+	if !p.enabled(ctx) {
+		return nil
+	}
 	return p.active.Set(ctx, float64(count))
 }
 
 func (p *basePool) SetIdle(ctx Context, count int) error {
+	if !p.enabled(ctx) {
+		return nil
+	}
 	return p.idle.Set(ctx, float64(count))
 }
 
 func (p *basePool) Acquired(ctx Context) error {
+	if !p.enabled(ctx) {
+		return nil
+	}
 	return p.acquired.Inc(ctx)
 }
 
 func (p *basePool) Released(ctx Context) error {
+	if !p.enabled(ctx) {
+		return nil
+	}
 	return p.released.Inc(ctx)
 }
 
+func (p *basePool) SetLevel(level Level) {
+	p.setLevel(level, p.Components())
+}
+
 func (p *basePool) Components() []Metric {
 	return []Metric{p.active, p.idle, p.acquired, p.released}
 }
@@ -432,25 +1064,45 @@ type basePoolVec struct {
 }
 
 func (pv *basePoolVec) SetActive(ctx Context, count int, labels VecLabels) error {
+	if !pv.enabled(ctx) {
+		return nil
+	}
 	return pv.active.Set(ctx, float64(count), labels)
 }
 
 func (pv *basePoolVec) SetIdle(ctx Context, count int, labels VecLabels) error {
+	if !pv.enabled(ctx) {
+		return nil
+	}
 	return pv.idle.Set(ctx, float64(count), labels)
 }
 
 func (pv *basePoolVec) Acquired(ctx Context, labels VecLabels) error {
+	if !pv.enabled(ctx) {
+		return nil
+	}
 	return pv.acquired.Inc(ctx, labels)
 }
 
 func (pv *basePoolVec) Released(ctx Context, labels VecLabels) error {
+	if !pv.enabled(ctx) {
+		return nil
+	}
 	return pv.released.Inc(ctx, labels)
 }
 
+func (pv *basePoolVec) SetLevel(level Level) {
+	pv.setLevel(level, pv.Components())
+}
+
 func (pv *basePoolVec) Components() []Metric {
 	return []Metric{pv.active, pv.idle, pv.acquired, pv.released}
 }
 
+func (pv *basePoolVec) Partition(labels VecLabels) Pool {
+	return &poolPartition{PoolVec: pv, labels: labels}
+}
+
 type baseCircuitBreaker struct {
 	baseCompositeMetric
 	state     Gauge
@@ -459,6 +1111,9 @@ type baseCircuitBreaker struct {
 }
 
 func (cb *baseCircuitBreaker) SetState(ctx Context, state CircuitBreakerState) error {
+	if !cb.enabled(ctx) {
+		return nil
+	}
 	var value float64
 	switch state {
 	case CircuitBreakerStateClosed, CircuitBreakerStateOpen, CircuitBreakerStateHalfOpen:
@@ -470,13 +1125,23 @@ func (cb *baseCircuitBreaker) SetState(ctx Context, state CircuitBreakerState) e
 }
 
 func (cb *baseCircuitBreaker) Success(ctx Context) error {
+	if !cb.enabled(ctx) {
+		return nil
+	}
 	return cb.successes.Inc(ctx)
 }
 
 func (cb *baseCircuitBreaker) Failure(ctx Context) error {
+	if !cb.enabled(ctx) {
+		return nil
+	}
 	return cb.failures.Inc(ctx)
 }
 
+func (cb *baseCircuitBreaker) SetLevel(level Level) {
+	cb.setLevel(level, cb.Components())
+}
+
 func (cb *baseCircuitBreaker) Components() []Metric {
 	return []Metric{cb.state, cb.successes, cb.failures}
 }
@@ -497,6 +1162,9 @@ type baseCircuitBreakerVec struct {
 }
 
 func (cbv *baseCircuitBreakerVec) SetState(ctx Context, state CircuitBreakerState, labels VecLabels) error {
+	if !cbv.enabled(ctx) {
+		return nil
+	}
 	var value float64
 	switch state {
 	case CircuitBreakerStateClosed, CircuitBreakerStateOpen, CircuitBreakerStateHalfOpen:
@@ -508,17 +1176,31 @@ func (cbv *baseCircuitBreakerVec) SetState(ctx Context, state CircuitBreakerStat
 }
 
 func (cbv *baseCircuitBreakerVec) Success(ctx Context, labels VecLabels) error {
+	if !cbv.enabled(ctx) {
+		return nil
+	}
 	return cbv.successes.Inc(ctx, labels)
 }
 
 func (cbv *baseCircuitBreakerVec) Failure(ctx Context, labels VecLabels) error {
+	if !cbv.enabled(ctx) {
+		return nil
+	}
 	return cbv.failures.Inc(ctx, labels)
 }
 
+func (cbv *baseCircuitBreakerVec) SetLevel(level Level) {
+	cbv.setLevel(level, cbv.Components())
+}
+
 func (cbv *baseCircuitBreakerVec) Components() []Metric {
 	return []Metric{cbv.state, cbv.successes, cbv.failures}
 }
 
+func (cbv *baseCircuitBreakerVec) Partition(labels VecLabels) CircuitBreaker {
+	return &circuitBreakerPartition{CircuitBreakerVec: cbv, labels: labels}
+}
+
 type baseQueue struct {
 	baseCompositeMetric
 	depth    Gauge
@@ -528,21 +1210,72 @@ type baseQueue struct {
 }
 
 func (q *baseQueue) SetDepth(ctx Context, depth int) error {
+	if !q.enabled(ctx) {
+		return nil
+	}
 	return q.depth.Set(ctx, float64(depth))
 }
 
 func (q *baseQueue) Enqueued(ctx Context) error {
+	if !q.enabled(ctx) {
+		return nil
+	}
 	return q.enqueued.Inc(ctx)
 }
 
 func (q *baseQueue) Dequeued(ctx Context) error {
+	if !q.enabled(ctx) {
+		return nil
+	}
 	return q.dequeued.Inc(ctx)
 }
 
-func (q *baseQueue) SetWaitTime(ctx Context, duration time.Duration) error {
+func (q *baseQueue) ObserveWait(ctx Context, duration time.Duration) error {
+	if !q.enabled(ctx) {
+		return nil
+	}
 	return q.waitTime.Observe(ctx, duration.Seconds())
 }
 
+func (q *baseQueue) DequeuedWithWait(ctx Context, waited time.Duration) error {
+	if !q.enabled(ctx) {
+		return nil
+	}
+	return errors.Join(
+		q.dequeued.Inc(ctx),
+		q.waitTime.Observe(ctx, waited.Seconds()),
+	)
+}
+
+func (q *baseQueue) Enqueue(ctx Context) error {
+	if !q.enabled(ctx) {
+		return nil
+	}
+	return errors.Join(
+		q.enqueued.Inc(ctx),
+		q.depth.Inc(ctx),
+	)
+}
+
+func (q *baseQueue) Dequeue(ctx Context, waited time.Duration) error {
+	if !q.enabled(ctx) {
+		return nil
+	}
+	return errors.Join(
+		q.dequeued.Inc(ctx),
+		q.depth.Dec(ctx),
+		q.waitTime.Observe(ctx, waited.Seconds()),
+	)
+}
+
+func (q *baseQueue) SetWaitTime(ctx Context, duration time.Duration) error {
+	return q.ObserveWait(ctx, duration)
+}
+
+func (q *baseQueue) SetLevel(level Level) {
+	q.setLevel(level, q.Components())
+}
+
 func (q *baseQueue) Components() []Metric {
 	return []Metric{q.depth, q.enqueued, q.dequeued, q.waitTime}
 }
@@ -556,39 +1289,297 @@ type baseQueueVec struct {
 }
 
 func (qv *baseQueueVec) SetDepth(ctx Context, depth int, labels VecLabels) error {
+	if !qv.enabled(ctx) {
+		return nil
+	}
 	return qv.depth.Set(ctx, float64(depth), labels)
 }
 
 func (qv *baseQueueVec) Enqueued(ctx Context, labels VecLabels) error {
+	if !qv.enabled(ctx) {
+		return nil
+	}
 	return qv.enqueued.Inc(ctx, labels)
 }
 
 func (qv *baseQueueVec) Dequeued(ctx Context, labels VecLabels) error {
+	if !qv.enabled(ctx) {
+		return nil
+	}
 	return qv.dequeued.Inc(ctx, labels)
 }
 
-func (qv *baseQueueVec) SetWaitTime(ctx Context, duration time.Duration, labels VecLabels) error {
+func (qv *baseQueueVec) ObserveWait(ctx Context, duration time.Duration, labels VecLabels) error {
+	if !qv.enabled(ctx) {
+		return nil
+	}
 	return qv.waitTime.Observe(ctx, duration.Seconds(), labels)
 }
 
+func (qv *baseQueueVec) DequeuedWithWait(ctx Context, waited time.Duration, labels VecLabels) error {
+	if !qv.enabled(ctx) {
+		return nil
+	}
+	return errors.Join(
+		qv.dequeued.Inc(ctx, labels),
+		qv.waitTime.Observe(ctx, waited.Seconds(), labels),
+	)
+}
+
+func (qv *baseQueueVec) Enqueue(ctx Context, labels VecLabels) error {
+	if !qv.enabled(ctx) {
+		return nil
+	}
+	return errors.Join(
+		qv.enqueued.Inc(ctx, labels),
+		qv.depth.Inc(ctx, labels),
+	)
+}
+
+func (qv *baseQueueVec) Dequeue(ctx Context, waited time.Duration, labels VecLabels) error {
+	if !qv.enabled(ctx) {
+		return nil
+	}
+	return errors.Join(
+		qv.dequeued.Inc(ctx, labels),
+		qv.depth.Dec(ctx, labels),
+		qv.waitTime.Observe(ctx, waited.Seconds(), labels),
+	)
+}
+
+func (qv *baseQueueVec) SetWaitTime(ctx Context, duration time.Duration, labels VecLabels) error {
+	return qv.ObserveWait(ctx, duration, labels)
+}
+
+func (qv *baseQueueVec) SetLevel(level Level) {
+	qv.setLevel(level, qv.Components())
+}
+
 func (qv *baseQueueVec) Components() []Metric {
 	return []Metric{qv.depth, qv.enqueued, qv.dequeued, qv.waitTime}
 }
 
+func (qv *baseQueueVec) Partition(labels VecLabels) Queue {
+	return &queuePartition{QueueVec: qv, labels: labels}
+}
+
+// baseThroughput counts events, and sets its rate gauge to the events per
+// second marked since the previous tick of a background ticker.
+//
+// The ticker is owned by the metric, see [Throughput.Start] and [Throughput.Stop].
+type baseThroughput struct {
+	baseCompositeMetric
+	count  Counter
+	rate   Gauge
+	window time.Duration
+	clock  Clock
+
+	pending atomic.Int64 // Events marked since the previous tick
+
+	mu       sync.Mutex
+	lastTick time.Time
+	stop     chan struct{} // Closed to stop the ticker, nil while stopped
+	done     chan struct{} // Closed once the ticker has exited
+}
+
+func (t *baseThroughput) Mark(ctx Context) error {
+	return t.MarkN(ctx, 1)
+}
+
+func (t *baseThroughput) MarkN(ctx Context, n int64) error {
+	if !t.enabled(ctx) {
+		return nil
+	}
+	t.pending.Add(n)
+	return t.count.Add(ctx, float64(n))
+}
+
+func (t *baseThroughput) Start() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stop != nil {
+		return
+	}
+
+	// Events marked while stopped don't belong to the first window
+	t.lastTick = t.clock.Now()
+	t.pending.Store(0)
+
+	t.stop = make(chan struct{})
+	t.done = make(chan struct{})
+	go t.run(t.stop, t.done)
+}
+
+func (t *baseThroughput) Stop() {
+	t.mu.Lock()
+	stop, done := t.stop, t.done
+	t.stop, t.done = nil, nil
+	t.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// running returns true between [baseThroughput.Start] and [baseThroughput.Stop]
+func (t *baseThroughput) running() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stop != nil
+}
+
+func (t *baseThroughput) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(t.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.tick()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// tick sets the rate to the events per second marked since the previous tick.
+//
+// The rate is set under [NewContextAll], as the events were already filtered
+// when marked.
+func (t *baseThroughput) tick() error {
+	t.mu.Lock()
+	now := t.clock.Now()
+	elapsed := now.Sub(t.lastTick)
+	t.lastTick = now
+	t.mu.Unlock()
+
+	events := t.pending.Swap(0)
+	if elapsed <= 0 {
+		return nil
+	}
+	return t.rate.Set(NewContextAll(), float64(events)/elapsed.Seconds())
+}
+
+func (t *baseThroughput) SetLevel(level Level) {
+	t.setLevel(level, t.Components())
+}
+
+func (t *baseThroughput) Components() []Metric {
+	return []Metric{t.count, t.rate}
+}
+
+type baseDistributionGauge struct {
+	baseCompositeMetric
+	gauge     Gauge
+	histogram Histogram
+}
+
+func (d *baseDistributionGauge) Observe(ctx Context, value float64) error {
+	if !d.enabled(ctx) {
+		return nil
+	}
+	return errors.Join(
+		d.gauge.Set(ctx, value),
+		d.histogram.Observe(ctx, value),
+	)
+}
+
+func (d *baseDistributionGauge) SetLevel(level Level) {
+	d.setLevel(level, d.Components())
+}
+
+func (d *baseDistributionGauge) Components() []Metric {
+	return []Metric{d.gauge, d.histogram}
+}
+
+type baseMultiResHistogram struct {
+	baseCompositeMetric
+	histograms []Histogram
+}
+
+func (m *baseMultiResHistogram) Observe(ctx Context, value float64) error {
+	if !m.enabled(ctx) {
+		return nil
+	}
+	errs := make([]error, len(m.histograms))
+	for i, histogram := range m.histograms {
+		errs[i] = histogram.Observe(ctx, value)
+	}
+	return errors.Join(errs...)
+}
+
+func (m *baseMultiResHistogram) SetLevel(level Level) {
+	m.setLevel(level, m.Components())
+}
+
+func (m *baseMultiResHistogram) Components() []Metric {
+	components := make([]Metric, len(m.histograms))
+	for i, histogram := range m.histograms {
+		components[i] = histogram
+	}
+	return components
+}
+
+type baseDependency struct {
+	baseCompositeMetric
+	calls    CounterVec
+	latency  HistogramVec
+	inFlight GaugeVec
+	clock    Clock
+}
+
+func (d *baseDependency) Call(ctx Context, dependency, operation string) func(err error) {
+	if !d.enabled(ctx) {
+		return func(error) {}
+	}
+	labels := VecLabels{DependencyLabel: dependency, OperationLabel: operation}
+	_ = d.inFlight.Inc(ctx, labels)
+	start := d.clock.Now()
+
+	return func(err error) {
+		outcome := OutcomeSuccess
+		if err != nil {
+			outcome = OutcomeFailure
+		}
+
+		_ = d.latency.Observe(ctx, d.clock.Now().Sub(start).Seconds(), labels)
+		_ = d.inFlight.Dec(ctx, labels)
+		_ = d.calls.Inc(ctx, VecLabels{DependencyLabel: dependency, OperationLabel: operation, OutcomeLabel: outcome})
+	}
+}
+
+func (d *baseDependency) SetLevel(level Level) {
+	d.setLevel(level, d.Components())
+}
+
+func (d *baseDependency) Components() []Metric {
+	return []Metric{d.calls, d.latency, d.inFlight}
+}
+
 var (
 	// Common Interface compliance checks
 	__ctc_baseMetric          Metric          = (*baseMetric)(nil)
 	__ctc_baseCompositeMetric CompositeMetric = (*baseCompositeMetric)(nil)
 
 	// Basic metrics compliance checks
-	__ctc_baseCounter      Counter      = (*baseCounter)(nil)
-	__ctc_baseCounterVec   CounterVec   = (*baseCounterVec)(nil)
-	__ctc_baseGauge        Gauge        = (*baseGauge)(nil)
-	__ctc_baseGaugeVec     GaugeVec     = (*baseGaugeVec)(nil)
-	__ctc_baseHistogram    Histogram    = (*baseHistogram)(nil)
-	__ctc_baseHistogramVec HistogramVec = (*baseHistogramVec)(nil)
-	__ctc_baseSummary      Summary      = (*baseSummary)(nil)
-	__ctc_baseSummaryVec   SummaryVec   = (*baseSummaryVec)(nil)
+	__ctc_baseCounter        Counter      = (*baseCounter)(nil)
+	__ctc_baseCounterGroup   CounterGroup = (*baseCounterGroup)(nil)
+	__ctc_baseCounterFunc    CounterFunc  = (*baseCounterFunc)(nil)
+	__ctc_baseCounterVec     CounterVec   = (*baseCounterVec)(nil)
+	__ctc_labelValuesCounter Counter      = (*labelValuesCounter)(nil)
+	__ctc_baseGauge          Gauge        = (*baseGauge)(nil)
+	__ctc_baseAtomicGauge    Gauge        = (*baseAtomicGauge)(nil)
+	__ctc_baseGaugeFunc      GaugeFunc    = (*baseGaugeFunc)(nil)
+	__ctc_baseGaugeVec       GaugeVec     = (*baseGaugeVec)(nil)
+	__ctc_baseHistogram      Histogram    = (*baseHistogram)(nil)
+	__ctc_baseHistogramVec   HistogramVec = (*baseHistogramVec)(nil)
+	__ctc_baseSummary        Summary      = (*baseSummary)(nil)
+	__ctc_baseSummaryVec     SummaryVec   = (*baseSummaryVec)(nil)
 
 	// Composite metrics compliance checks
 	__ctc_baseTimer             Timer             = (*baseTimer)(nil)
@@ -601,4 +1592,10 @@ var (
 	__ctc_baseCircuitBreakerVec CircuitBreakerVec = (*baseCircuitBreakerVec)(nil)
 	__ctc_baseQueue             Queue             = (*baseQueue)(nil)
 	__ctc_baseQueueVec          QueueVec          = (*baseQueueVec)(nil)
+	__ctc_baseThroughput        Throughput        = (*baseThroughput)(nil)
+	__ctc_baseDistributionGauge DistributionGauge = (*baseDistributionGauge)(nil)
+	__ctc_baseMultiResHistogram MultiResHistogram = (*baseMultiResHistogram)(nil)
+	__ctc_baseDependency        Dependency        = (*baseDependency)(nil)
+
+	__ctc_timerObserverVec DurationObserverVec = (*timerObserverVec)(nil)
 )
@@ -0,0 +1,1477 @@
+package umami
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mockHistogramOf returns the mock adapter backing a real histogram created
+// by a group on the mock backend
+func mockHistogramOf(t *testing.T, h Histogram) *mockHistogramAdapter {
+	t.Helper()
+
+	switchable, ok := h.(*switchableHistogram)
+	if !ok {
+		t.Fatalf("Expected a switchable histogram, got %T", h)
+	}
+	base, ok := switchable.impl.(*baseHistogram)
+	if !ok {
+		t.Fatalf("Expected a real histogram, got %T", switchable.impl)
+	}
+	return base.adapter.(*mockHistogramAdapter)
+}
+
+// mockCounterOf returns the mock adapter backing a real counter created
+// by a group on the mock backend
+func mockCounterOf(t *testing.T, c Counter) *mockCounterAdapter {
+	t.Helper()
+
+	switchable, ok := c.(*switchableCounter)
+	if !ok {
+		t.Fatalf("Expected a switchable counter, got %T", c)
+	}
+	base, ok := switchable.impl.(*baseCounter)
+	if !ok {
+		t.Fatalf("Expected a real counter, got %T", switchable.impl)
+	}
+	return base.adapter.(*mockCounterAdapter)
+}
+
+func newTestQueue(group Group) Queue {
+	return group.Queue(
+		QueueOpts{
+			MetricInfo:   MetricInfo{Name: "jobs", Help: "Job queue"},
+			DepthOpts:    GaugeOpts{MetricInfo: MetricInfo{Name: "jobs_depth"}},
+			EnqueuedOpts: CounterOpts{MetricInfo: MetricInfo{Name: "jobs_enqueued"}},
+			DequeuedOpts: CounterOpts{MetricInfo: MetricInfo{Name: "jobs_dequeued"}},
+			WaitTimeOpts: HistogramOpts{MetricInfo: MetricInfo{Name: "jobs_wait_time"}},
+		},
+		LevelCritical,
+	)
+}
+
+func TestQueueObserveWait(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+	queue := newTestQueue(group)
+	ctx := group.Context()
+
+	if err := queue.ObserveWait(ctx, 2*time.Second); err != nil {
+		t.Fatalf("ObserveWait() failed: %v", err)
+	}
+	if err := queue.SetWaitTime(ctx, 500*time.Millisecond); err != nil {
+		t.Fatalf("SetWaitTime() failed: %v", err)
+	}
+
+	base := queue.(*switchableQueue).impl.(*baseQueue)
+	observations := mockHistogramOf(t, base.waitTime).GetObservations()
+	if len(observations) != 2 || observations[0] != 2 || observations[1] != 0.5 {
+		t.Errorf("Expected observations [2 0.5], got %v", observations)
+	}
+}
+
+func TestQueueDequeuedWithWait(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+	queue := newTestQueue(group)
+	ctx := group.Context()
+
+	for range 3 {
+		if err := queue.DequeuedWithWait(ctx, time.Second); err != nil {
+			t.Fatalf("DequeuedWithWait() failed: %v", err)
+		}
+	}
+
+	base := queue.(*switchableQueue).impl.(*baseQueue)
+	if got := mockCounterOf(t, base.dequeued).GetCount(); got != 3 {
+		t.Errorf("Expected 3 dequeues, got %v", got)
+	}
+	if got := mockHistogramOf(t, base.waitTime).GetObservationCount(); got != 3 {
+		t.Errorf("Expected 3 wait observations, got %v", got)
+	}
+}
+
+func TestBasicMetricPredicate(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+
+	var flag atomic.Bool
+	counter := group.Counter(
+		CounterOpts{
+			MetricInfo:      MetricInfo{Name: "flagged", Help: "Flagged"},
+			BasicMetricOpts: BasicMetricOpts{Predicate: func(Context) bool { return flag.Load() }},
+		},
+		LevelCritical,
+	)
+	adapter := mockCounterOf(t, counter)
+	ctx := group.Context()
+
+	_ = counter.Inc(ctx)
+	if got := adapter.GetCount(); got != 0 {
+		t.Errorf("Expected no recording while predicate is false, got %v", got)
+	}
+
+	flag.Store(true)
+	_ = counter.Inc(ctx)
+	if got := adapter.GetCount(); got != 1 {
+		t.Errorf("Expected recording while predicate is true, got %v", got)
+	}
+
+	flag.Store(false)
+	_ = counter.Inc(ctx)
+	if got := adapter.GetCount(); got != 1 {
+		t.Errorf("Expected no recording after predicate is false again, got %v", got)
+	}
+}
+
+func TestBasicMetricPredicateNotCalledWhenLevelDisabled(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+
+	called := false
+	counter := group.Counter(
+		CounterOpts{
+			MetricInfo:      MetricInfo{Name: "verbose", Help: "Verbose"},
+			BasicMetricOpts: BasicMetricOpts{Predicate: func(Context) bool { called = true; return true }},
+		},
+		LevelDebug,
+	)
+
+	_ = counter.Inc(NewContext(LevelCritical))
+	if called {
+		t.Error("Expected predicate to not be consulted when the level check fails")
+	}
+}
+
+// fakeClock is a [Clock] that only advances when told to
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestTimerClock(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+	clock := &fakeClock{now: time.Unix(0, 0)}
+
+	timer := group.Timer(
+		TimerOpts{
+			MetricInfo:    MetricInfo{Name: "op", Help: "Operation"},
+			HistogramOpts: HistogramOpts{MetricInfo: MetricInfo{Name: "op_duration"}},
+			Clock:         clock,
+		},
+		LevelCritical,
+	)
+
+	stop := timer.Start(group.Context())
+	clock.Advance(1500 * time.Millisecond)
+	stop()
+
+	base := timer.(*switchableTimer).impl.(*baseTimer)
+	observations := mockHistogramOf(t, base.histogram).GetObservations()
+	if len(observations) != 1 || observations[0] != 1.5 {
+		t.Errorf("Expected observations [1.5], got %v", observations)
+	}
+}
+
+func TestTimerRecordFromContextStart(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	timer := group.Timer(TimerOpts{
+		MetricInfo:    MetricInfo{Name: "op"},
+		HistogramOpts: HistogramOpts{MetricInfo: MetricInfo{Name: "op_duration"}},
+		Clock:         clock,
+	}, LevelCritical)
+
+	if err := timer.RecordFromContextStart(group.Context()); !errors.Is(err, ErrNoStartTime) {
+		t.Errorf("Expected ErrNoStartTime without a start time, got %v", err)
+	}
+
+	// The start is kept by contexts derived from the started one
+	ctx := group.Context().WithStart(clock.Now()).WithLevel(LevelVerbose)
+	clock.Advance(250 * time.Millisecond)
+	if err := timer.RecordFromContextStart(ctx); err != nil {
+		t.Fatalf("RecordFromContextStart failed: %v", err)
+	}
+
+	base := timer.(*switchableTimer).impl.(*baseTimer)
+	observations := mockHistogramOf(t, base.histogram).GetObservations()
+	if len(observations) != 1 || observations[0] != 0.25 {
+		t.Errorf("Expected observations [0.25], got %v", observations)
+	}
+}
+
+func TestTimerStartRecordsOnPanic(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	timer := group.Timer(TimerOpts{
+		MetricInfo:    MetricInfo{Name: "op"},
+		HistogramOpts: HistogramOpts{MetricInfo: MetricInfo{Name: "op_duration"}},
+		Clock:         clock,
+	}, LevelCritical)
+
+	func() {
+		defer func() { recover() }()
+		defer timer.Start(group.Context())()
+		clock.Advance(time.Second)
+		panic("operation failed")
+	}()
+
+	base := timer.(*switchableTimer).impl.(*baseTimer)
+	observations := mockHistogramOf(t, base.histogram).GetObservations()
+	if len(observations) != 1 || observations[0] != 1 {
+		t.Errorf("Expected the deferred stop to record [1] despite the panic, got %v", observations)
+	}
+}
+
+func TestTimerStartFunc(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	timer := group.Timer(TimerOpts{
+		MetricInfo:    MetricInfo{Name: "op"},
+		HistogramOpts: HistogramOpts{MetricInfo: MetricInfo{Name: "op_duration"}},
+		Clock:         clock,
+	}, LevelCritical)
+	ctx := group.Context()
+
+	errFailed := errors.New("operation failed")
+	err := timer.StartFunc(ctx, func() error {
+		clock.Advance(time.Second)
+		return errFailed
+	})
+	if err != errFailed {
+		t.Errorf("Expected the error of fn, got %v", err)
+	}
+
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != "boom" {
+				t.Errorf("Expected the panic of fn to propagate, got %v", recovered)
+			}
+		}()
+		timer.StartFunc(ctx, func() error {
+			clock.Advance(2 * time.Second)
+			panic("boom")
+		})
+	}()
+
+	base := timer.(*switchableTimer).impl.(*baseTimer)
+	observations := mockHistogramOf(t, base.histogram).GetObservations()
+	if len(observations) != 2 || observations[0] != 1 || observations[1] != 2 {
+		t.Errorf("Expected observations [1 2], got %v", observations)
+	}
+}
+
+func TestTimerAsObserver(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDisabled).NewGroup("test", backend)
+	timer := group.Timer(TimerOpts{
+		MetricInfo:    MetricInfo{Name: "op"},
+		HistogramOpts: HistogramOpts{MetricInfo: MetricInfo{Name: "op_duration"}},
+	}, LevelCritical)
+
+	// Middleware only knows the function type
+	var observe func(time.Duration) = timer.AsObserver(NewContextAll())
+	observe(time.Second)
+
+	// The observer follows the timer once it is converted from a noop
+	group.SetGroupLevel(LevelDebug, LevelOpts{ReplaceNoops: true})
+	observe(2 * time.Second)
+
+	observations := backend.adapter("test_op_duration").(*mockHistogramAdapter).GetObservations()
+	if len(observations) != 1 || observations[0] != 2 {
+		t.Errorf("Expected observations [2], got %v", observations)
+	}
+}
+
+func TestTimerVecAsObserverVec(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug).NewGroup("test", backend)
+	timerVec := group.TimerVec(TimerVecOpts{
+		MetricInfo:       MetricInfo{Name: "request"},
+		HistogramVecOpts: HistogramVecOpts{MetricInfo: MetricInfo{Name: "request_duration"}, Labels: []string{"route"}},
+	}, LevelCritical)
+
+	observers := timerVec.AsObserverVec(group.Context())
+	observers.With(VecLabels{"route": "/a"})(time.Second)
+	observers.With(VecLabels{"route": "/a"})(3 * time.Second)
+	observers.With(VecLabels{"route": "/b"})(500 * time.Millisecond)
+
+	adapter := backend.adapter("test_request_duration").(*mockHistogramVecAdapter)
+	a := adapter.GetObservations(VecLabels{"route": "/a"})
+	b := adapter.GetObservations(VecLabels{"route": "/b"})
+	if !slices.Equal(a, []float64{1, 3}) || !slices.Equal(b, []float64{0.5}) {
+		t.Errorf("Expected observations [1 3] and [0.5], got %v and %v", a, b)
+	}
+}
+
+func TestGaugeFromAtomicConvertedFromNoop(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDisabled).NewGroup("test", backend)
+
+	var inFlight atomic.Int64
+	gauge := group.GaugeFromAtomic(GaugeOpts{MetricInfo: MetricInfo{Name: "in_flight"}}, LevelCritical, &inFlight)
+	if !gauge.(SwitchableMetric).IsNoop() {
+		t.Fatal("Expected a noop gauge on a disabled group")
+	}
+
+	group.SetGroupLevel(LevelDebug, LevelOpts{ReplaceNoops: true})
+
+	adapter, ok := backend.adapter("test_in_flight").(*mockGaugeFuncAdapter)
+	if !ok {
+		t.Fatalf("Expected a gauge func adapter after enabling, got %T", backend.adapter("test_in_flight"))
+	}
+
+	inFlight.Store(7)
+	if got := adapter.GetValue(); got != 7 {
+		t.Errorf("Expected gauge to follow the atomic, got %v", got)
+	}
+
+	_ = gauge.Inc(group.Context())
+	if got := inFlight.Load(); got != 8 {
+		t.Errorf("Expected gauge operations to write through, got %v", got)
+	}
+}
+
+func TestCounterResetOnScrape(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	reg := NewRegistry(LevelImportant)
+	group := reg.NewGroup("test", backend)
+	ctx := group.Context()
+
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "flushed_total"}, ResetOnScrape: true}, LevelCritical)
+	_ = counter.Add(ctx, 4)
+
+	for _, want := range []float64{4, 0} {
+		family, err := reg.MetricValue("test_flushed_total")
+		if err != nil {
+			t.Fatalf("MetricValue failed: %v", err)
+		}
+		if got := family.Samples[0].Value; got != want {
+			t.Errorf("Expected the scraped value %v, got %v", want, got)
+		}
+	}
+
+	_ = counter.Inc(ctx)
+	if got, _ := counter.Value(ctx); got != 1 {
+		t.Errorf("Expected the counter to count from 0 after the scrape, got %v", got)
+	}
+}
+
+func TestCounterGroupSumsMembers(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelImportant).NewGroup("test", backend)
+	ctx := group.Context()
+
+	shards := make([]Counter, 3)
+	for i := range shards {
+		shards[i] = group.Counter(CounterOpts{
+			BasicMetricOpts: BasicMetricOpts{FromComposite: true},
+			MetricInfo:      MetricInfo{Name: fmt.Sprintf("errors_shard_%d", i)},
+		}, LevelCritical)
+	}
+	errorsTotal := group.CounterGroup(CounterOpts{MetricInfo: MetricInfo{Name: "errors_total"}}, LevelCritical, shards...)
+
+	_ = shards[0].Inc(ctx)
+	_ = shards[1].Add(ctx, 2)
+	_ = shards[2].Add(ctx, 4)
+
+	adapter, ok := backend.adapter("test_errors_total").(*mockCounterFuncAdapter)
+	if !ok {
+		t.Fatalf("Expected a counter func adapter, got %T", backend.adapter("test_errors_total"))
+	}
+	if got := adapter.GetCount(); got != 7 {
+		t.Errorf("Expected the reported value to be the sum of the members, got %v", got)
+	}
+	if got, err := errorsTotal.Value(ctx); err != nil || got != 7 {
+		t.Errorf("Expected Value to be the sum of the members, got %v, %v", got, err)
+	}
+
+	// The sum is read at collection time
+	_ = shards[1].Inc(ctx)
+	if got := adapter.GetCount(); got != 8 {
+		t.Errorf("Expected the reported value to follow the members, got %v", got)
+	}
+	if len(errorsTotal.Members()) != 3 {
+		t.Errorf("Expected 3 members, got %d", len(errorsTotal.Members()))
+	}
+}
+
+func TestCounterGroupConvertedFromNoop(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelCritical).NewGroup("test", backend)
+
+	members := []Counter{
+		group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "a_total"}}, LevelCritical),
+		group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "b_total"}}, LevelCritical),
+	}
+	total := group.CounterGroup(CounterOpts{MetricInfo: MetricInfo{Name: "total"}}, LevelDebug, members...)
+	if !total.(SwitchableMetric).IsNoop() {
+		t.Fatal("Expected a noop counter group below the group level")
+	}
+
+	_ = members[0].Add(group.Context(), 3)
+	_ = members[1].Add(group.Context(), 5)
+	group.SetGroupLevel(LevelDebug, LevelOpts{ReplaceNoops: true})
+
+	if got, err := total.Value(NewContextAll()); err != nil || got != 8 {
+		t.Errorf("Expected the converted group to sum its members, got %v, %v", got, err)
+	}
+	if got := backend.adapter("test_total").(*mockCounterFuncAdapter).GetCount(); got != 8 {
+		t.Errorf("Expected the reported value to be the sum of the members, got %v", got)
+	}
+}
+
+func TestGaugeFuncAndCounterFunc(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelImportant).NewGroup("test", backend)
+	ctx := group.Context()
+
+	queued, processed := 2.0, 10.0
+	queueLength := group.GaugeFunc(GaugeOpts{MetricInfo: MetricInfo{Name: "queue_length"}}, func() float64 { return queued }, LevelCritical)
+	processedTotal := group.CounterFunc(CounterOpts{MetricInfo: MetricInfo{Name: "processed_total"}}, func() float64 { return processed }, LevelCritical)
+
+	gaugeAdapter, ok := backend.adapter("test_queue_length").(*mockGaugeFuncAdapter)
+	if !ok {
+		t.Fatalf("Expected a gauge func adapter, got %T", backend.adapter("test_queue_length"))
+	}
+	counterAdapter, ok := backend.adapter("test_processed_total").(*mockCounterFuncAdapter)
+	if !ok {
+		t.Fatalf("Expected a counter func adapter, got %T", backend.adapter("test_processed_total"))
+	}
+
+	// The functions are read at collection time
+	queued, processed = 5, 12
+	if got := gaugeAdapter.GetValue(); got != 5 {
+		t.Errorf("Expected the gauge to follow the function, got %v", got)
+	}
+	if got := counterAdapter.GetCount(); got != 12 {
+		t.Errorf("Expected the counter to follow the function, got %v", got)
+	}
+	if got, err := queueLength.Value(ctx); err != nil || got != 5 {
+		t.Errorf("Expected Value to return the function's value, got %v, %v", got, err)
+	}
+	if got, err := processedTotal.Value(ctx); err != nil || got != 12 {
+		t.Errorf("Expected Value to return the function's value, got %v, %v", got, err)
+	}
+
+	debug := group.GaugeFunc(GaugeOpts{MetricInfo: MetricInfo{Name: "debug_length"}}, func() float64 { return queued }, LevelDebug)
+	if got, err := debug.Value(ctx); err != nil || got != 0 {
+		t.Errorf("Expected a disabled gauge func to return 0, got %v, %v", got, err)
+	}
+	if adapter := backend.adapter("test_debug_length"); adapter != nil {
+		t.Errorf("Expected a disabled gauge func not to be registered, got %T", adapter)
+	}
+}
+
+func TestFuncMetricsReadZeroWhileDisabled(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug).NewGroup("test", backend)
+
+	group.GaugeFunc(GaugeOpts{MetricInfo: MetricInfo{Name: "queue_length"}}, func() float64 { return 5 }, LevelImportant)
+	group.CounterFunc(CounterOpts{MetricInfo: MetricInfo{Name: "processed_total"}}, func() float64 { return 12 }, LevelImportant)
+	gaugeAdapter := backend.adapter("test_queue_length").(*mockGaugeFuncAdapter)
+	counterAdapter := backend.adapter("test_processed_total").(*mockCounterFuncAdapter)
+
+	expect := func(when string, gauge, counter float64) {
+		t.Helper()
+		if got := gaugeAdapter.GetValue(); got != gauge {
+			t.Errorf("Expected the gauge func to read %v %s, got %v", gauge, when, got)
+		}
+		if got := counterAdapter.GetCount(); got != counter {
+			t.Errorf("Expected the counter func to read %v %s, got %v", counter, when, got)
+		}
+	}
+
+	expect("while enabled", 5, 12)
+	group.SetGroupLevel(LevelDisabled, LevelOpts{})
+	expect("while the group is paused", 0, 0)
+	group.SetGroupLevel(LevelDebug, LevelOpts{})
+	expect("once enabled again", 5, 12)
+	group.SetGroupMask(MaskCounter)
+	expect("with a counter mask", 0, 12)
+	group.SetGroupMask(MaskAll)
+	expect("once unmasked", 5, 12)
+}
+
+func TestSampleGaugeSkipsWhileDisabled(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	adapter := backend.Gauge(GaugeOpts{MetricInfo: MetricInfo{Name: "sampled"}}).(*mockGaugeAdapter)
+
+	checked := make(chan struct{}, 1)
+	sampler := startSampler(func(stop <-chan struct{}) {
+		sampleGauge(adapter, func() float64 { return 3 }, func() bool {
+			checked <- struct{}{}
+			return false
+		}, time.Hour, stop)
+	})
+	<-checked
+	sampler.Stop()
+
+	if got := adapter.GetValue(); got != 0 {
+		t.Errorf("Expected no sample while disabled, got %v", got)
+	}
+}
+
+func TestSampleCounterSkipsDecreases(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	adapter := backend.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "sampled_total"}}).(*mockCounterAdapter)
+
+	values := make(chan float64)
+	reads := make(chan struct{})
+	sampler := startSampler(func(stop <-chan struct{}) {
+		sampleCounter(adapter, func() float64 {
+			select {
+			case value := <-values:
+				reads <- struct{}{}
+				return value
+			case <-stop:
+				return 0
+			}
+		}, func() bool { return true }, time.Millisecond, stop)
+	})
+
+	for _, value := range []float64{3, 5, 2, 6} {
+		values <- value
+		<-reads
+	}
+	// Wait for the add of the last sample
+	values <- 6
+	<-reads
+
+	if got := adapter.GetCount(); got != 6 {
+		t.Errorf("Expected the counter to follow the increases of the samples, got %v", got)
+	}
+	sampler.Stop()
+}
+
+func TestSummaryReconfigureKeepsNameAndLevel(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+	summary := group.Summary(SummaryOpts{MetricInfo: MetricInfo{Name: "latency"}}, LevelImportant)
+
+	err := summary.Reconfigure(SummaryOpts{
+		MetricInfo: MetricInfo{Name: "ignored"},
+		Objectives: map[float64]float64{0.99: 0.001},
+	})
+	if err != nil {
+		t.Fatalf("Reconfigure() failed: %v", err)
+	}
+
+	if summary.Name() != "test_latency" {
+		t.Errorf("Expected name to be kept, got %q", summary.Name())
+	}
+	if summary.Level() != LevelImportant {
+		t.Errorf("Expected level to be kept, got %v", summary.Level())
+	}
+	if group.Summary(SummaryOpts{MetricInfo: MetricInfo{Name: "latency"}}, LevelImportant) != summary {
+		t.Error("Expected the tracked reference to be unchanged")
+	}
+
+	opts := summary.(*switchableSummary).switchOpts().(SummaryOpts)
+	if opts.Objectives[0.99] != 0.001 {
+		t.Errorf("Expected new objectives to be stored, got %v", opts.Objectives)
+	}
+}
+
+func TestSummaryReconfigureKeepsDisabled(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug).NewGroup("test", backend)
+	summary := group.Summary(SummaryOpts{MetricInfo: MetricInfo{Name: "latency"}}, LevelImportant)
+
+	group.SetGroupLevel(LevelDisabled, LevelOpts{})
+	if err := summary.Reconfigure(SummaryOpts{Objectives: map[float64]float64{0.99: 0.001}}); err != nil {
+		t.Fatalf("Reconfigure() failed: %v", err)
+	}
+
+	_ = summary.Observe(NewContextAll(), 1)
+	adapter := backend.adapter("test_latency").(*mockSummaryAdapter)
+	if len(adapter.observations) != 0 {
+		t.Errorf("Expected a reconfigured summary of a paused group to stay disabled, got %v", adapter.observations)
+	}
+
+	group.SetGroupLevel(LevelDebug, LevelOpts{})
+	_ = summary.Observe(NewContextAll(), 2)
+	if len(adapter.observations) != 1 {
+		t.Errorf("Expected the summary to record once resumed, got %v", adapter.observations)
+	}
+}
+
+func TestSummaryReconfigureUnsupportedBackend(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", noUnregisterBackend{NewMockBackend()})
+	summary := group.Summary(SummaryOpts{MetricInfo: MetricInfo{Name: "latency"}}, LevelCritical)
+
+	if err := summary.Reconfigure(SummaryOpts{}); !errors.Is(err, ErrUnregisterUnsupported) {
+		t.Errorf("Expected ErrUnregisterUnsupported, got %v", err)
+	}
+}
+
+// noUnregisterBackend hides the [UnregisterBackend] implementation of a backend
+type noUnregisterBackend struct {
+	Backend
+}
+
+func TestCounterGaugeValue(t *testing.T) {
+	group := NewRegistry(LevelImportant).NewGroup("test", NewMockBackend())
+	ctx := group.Context()
+
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests"}}, LevelImportant)
+	_ = counter.Inc(ctx)
+	_ = counter.Add(ctx, 2.5)
+	if got, err := counter.Value(ctx); err != nil || got != 3.5 {
+		t.Errorf("Expected counter value 3.5, got %v (%v)", got, err)
+	}
+
+	gauge := group.Gauge(GaugeOpts{MetricInfo: MetricInfo{Name: "in_flight"}}, LevelImportant)
+	_ = gauge.Set(ctx, 7)
+	_ = gauge.Dec(ctx)
+	if got, err := gauge.Value(ctx); err != nil || got != 6 {
+		t.Errorf("Expected gauge value 6, got %v (%v)", got, err)
+	}
+
+	// Metrics below the group level are noops
+	noop := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "debug_requests"}}, LevelDebug)
+	_ = noop.Inc(ctx)
+	if got, err := noop.Value(ctx); err != nil || got != 0 {
+		t.Errorf("Expected noop value 0, got %v (%v)", got, err)
+	}
+}
+
+func TestCounterGaugeValueUnsupportedBackend(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewValidatingBackend())
+	ctx := group.Context()
+
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests"}}, LevelImportant)
+	if _, err := counter.Value(ctx); !errors.Is(err, ErrUnsupportedMetric) {
+		t.Errorf("Expected ErrUnsupportedMetric, got %v", err)
+	}
+	gauge := group.Gauge(GaugeOpts{MetricInfo: MetricInfo{Name: "in_flight"}}, LevelImportant)
+	if _, err := gauge.Value(ctx); !errors.Is(err, ErrUnsupportedMetric) {
+		t.Errorf("Expected ErrUnsupportedMetric, got %v", err)
+	}
+}
+
+func TestCounterVecCollect(t *testing.T) {
+	group := NewRegistry(LevelImportant).NewGroup("test", NewMockBackend())
+	ctx := group.Context()
+
+	requests := group.CounterVec(CounterVecOpts{MetricInfo: MetricInfo{Name: "requests"}, Labels: []string{"route"}}, LevelImportant)
+	_ = requests.Inc(ctx, VecLabels{"route": "/"})
+	_ = requests.Add(ctx, 2, VecLabels{"route": "/users"})
+	_ = requests.Inc(ctx, VecLabels{"route": "/users"})
+
+	series, err := requests.Collect(ctx)
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	want := []Sample{
+		{Labels: VecLabels{"route": "/"}, Value: 1},
+		{Labels: VecLabels{"route": "/users"}, Value: 3},
+	}
+	if !slices.EqualFunc(series, want, func(a, b Sample) bool { return maps.Equal(a.Labels, b.Labels) && a.Value == b.Value }) {
+		t.Errorf("Expected series %v, got %v", want, series)
+	}
+
+	// Collecting through an unsupported backend fails
+	unsupported := NewRegistry(LevelDebug).NewGroup("test", NewValidatingBackend()).
+		CounterVec(CounterVecOpts{MetricInfo: MetricInfo{Name: "requests"}, Labels: []string{"route"}}, LevelImportant)
+	if _, err := unsupported.Collect(ctx); !errors.Is(err, ErrUnsupportedMetric) {
+		t.Errorf("Expected ErrUnsupportedMetric, got %v", err)
+	}
+}
+
+func TestCounterVecWithLabelValues(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelImportant).NewGroup("test", backend)
+	ctx := group.Context()
+
+	requests := group.CounterVec(CounterVecOpts{MetricInfo: MetricInfo{Name: "requests"}, Labels: []string{"route", "method"}}, LevelImportant)
+	users := requests.WithLabelValues(ctx, "/users", "GET")
+	_ = users.Inc(ctx)
+	_ = users.Add(ctx, 2)
+	_ = requests.Inc(ctx, VecLabels{"route": "/users", "method": "GET"})
+
+	adapter := backend.adapter("test_requests").(*mockCounterVecAdapter)
+	if got := adapter.GetCount(VecLabels{"route": "/users", "method": "GET"}); got != 4 {
+		t.Errorf("Expected the bound series to be 4, got %v", got)
+	}
+	if got := adapter.GetCount(VecLabels{"route": "GET", "method": "/users"}); got != 0 {
+		t.Errorf("Expected values to be bound in the declared label order, got %v", got)
+	}
+
+	// Values not matching the declared labels fail every operation
+	for _, values := range [][]string{{"/users"}, {"/users", "GET", "extra"}} {
+		bad := requests.WithLabelValues(ctx, values...)
+		if err := bad.Inc(ctx); !errors.Is(err, ErrLabelMismatch) {
+			t.Errorf("Expected ErrLabelMismatch for %v, got %v", values, err)
+		}
+		if err := bad.Add(ctx, 1); !errors.Is(err, ErrLabelMismatch) {
+			t.Errorf("Expected ErrLabelMismatch for %v, got %v", values, err)
+		}
+	}
+
+	// A counter bound on a noop vec records once the vec is enabled
+	debug := group.CounterVec(CounterVecOpts{MetricInfo: MetricInfo{Name: "debug_requests"}, Labels: []string{"route"}}, LevelDebug)
+	root := debug.WithLabelValues(ctx, "/")
+	_ = root.Inc(ctx)
+	debug.SetLevel(LevelImportant)
+	_ = root.Inc(ctx)
+
+	adapter = backend.adapter("test_debug_requests").(*mockCounterVecAdapter)
+	if got := adapter.GetCount(VecLabels{"route": "/"}); got != 1 {
+		t.Errorf("Expected only the enabled increment to be recorded, got %v", got)
+	}
+}
+
+func TestVecDelete(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelImportant, WithAsyncEmit(16, time.Second)).NewGroup("test", backend)
+	ctx := group.Context()
+
+	sessions := group.CounterVec(CounterVecOpts{MetricInfo: MetricInfo{Name: "sessions"}, Labels: []string{"client"}}, LevelImportant)
+	for _, client := range []string{"a", "b", "c"} {
+		_ = sessions.Inc(ctx, VecLabels{"client": client})
+	}
+
+	if !sessions.Delete(ctx, VecLabels{"client": "b"}) {
+		t.Error("Expected deleting an existing series to return true")
+	}
+	if sessions.Delete(ctx, VecLabels{"client": "b"}) {
+		t.Error("Expected deleting a missing series to return false")
+	}
+	series, _ := sessions.Collect(ctx)
+	if len(series) != 2 || series[0].Labels["client"] != "a" || series[1].Labels["client"] != "c" {
+		t.Errorf("Expected only the deleted series to be removed, got %v", series)
+	}
+
+	sessions.Reset(ctx)
+	if series, _ := sessions.Collect(ctx); len(series) != 0 {
+		t.Errorf("Expected Reset to remove every series, got %v", series)
+	}
+
+	// Async writes queued before a delete are applied first
+	async := group.GaugeVec(GaugeVecOpts{
+		BasicMetricOpts: BasicMetricOpts{Async: true},
+		MetricInfo:      MetricInfo{Name: "queued"},
+		Labels:          []string{"client"},
+	}, LevelImportant)
+	_ = async.Set(ctx, 3, VecLabels{"client": "a"})
+	if !async.Delete(ctx, VecLabels{"client": "a"}) {
+		t.Error("Expected the queued series to be deleted")
+	}
+
+	debug := group.CounterVec(CounterVecOpts{MetricInfo: MetricInfo{Name: "debug_sessions"}, Labels: []string{"client"}}, LevelDebug)
+	if debug.Delete(ctx, VecLabels{"client": "a"}) {
+		t.Error("Expected a disabled vec to delete nothing")
+	}
+}
+
+func TestVecMaxCardinality(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelImportant).NewGroup("test", backend)
+	ctx := group.Context()
+
+	requests := group.CounterVec(CounterVecOpts{
+		MetricInfo:     MetricInfo{Name: "requests"},
+		Labels:         []string{"user"},
+		PreInitLabels:  []VecLabels{{"user": "a"}},
+		MaxCardinality: 2,
+	}, LevelImportant)
+	adapter := backend.adapter("test_requests").(*mockCounterVecAdapter)
+
+	// Under the limit, new series are created
+	for _, user := range []string{"a", "b", "b"} {
+		if err := requests.Inc(ctx, VecLabels{"user": user}); err != nil {
+			t.Errorf("Expected recording under the limit to succeed, got %v", err)
+		}
+	}
+	if backend.adapter("test_"+DroppedSeriesMetricName) != nil {
+		t.Error("Expected the dropped series counter to be created on the first drop")
+	}
+
+	// Over the limit, new series are dropped and counted, while known series still record
+	if err := requests.Inc(ctx, VecLabels{"user": "c"}); !errors.Is(err, ErrCardinalityExceeded) {
+		t.Errorf("Expected ErrCardinalityExceeded, got %v", err)
+	}
+	bound := requests.WithLabelValues(ctx, "d")
+	if err := bound.Inc(ctx); !errors.Is(err, ErrCardinalityExceeded) {
+		t.Errorf("Expected binding a new series over the limit to fail, got %v", err)
+	}
+	if err := requests.Add(ctx, 2, VecLabels{"user": "a"}); err != nil {
+		t.Errorf("Expected recording a known series over the limit to succeed, got %v", err)
+	}
+	if got := adapter.GetCount(VecLabels{"user": "a"}); got != 3 {
+		t.Errorf("Expected the known series to be 3, got %v", got)
+	}
+	if got := adapter.GetCount(VecLabels{"user": "b"}); got != 2 {
+		t.Errorf("Expected the known series to be 2, got %v", got)
+	}
+	if series, _ := requests.Collect(ctx); len(series) != 2 {
+		t.Errorf("Expected the dropped series not to be created, got %v", series)
+	}
+	dropped := backend.adapter("test_" + DroppedSeriesMetricName).(*mockCounterVecAdapter)
+	if got := dropped.GetCount(VecLabels{"metric": "test_requests"}); got != 3 {
+		t.Errorf("Expected 3 dropped operations, got %v", got)
+	}
+
+	// Deleting a series frees its slot, including for a counter whose bind failed
+	requests.Delete(ctx, VecLabels{"user": "b"})
+	if err := bound.Inc(ctx); err != nil {
+		t.Errorf("Expected a deleted series to free its slot, got %v", err)
+	}
+	if err := requests.Inc(ctx, VecLabels{"user": "c"}); !errors.Is(err, ErrCardinalityExceeded) {
+		t.Errorf("Expected the freed slot to be taken, got %v", err)
+	}
+
+	// Each vec has its own limit
+	latency := group.HistogramVec(HistogramVecOpts{
+		MetricInfo:     MetricInfo{Name: "latency"},
+		Labels:         []string{"user"},
+		MaxCardinality: 1,
+	}, LevelImportant)
+	if err := latency.Observe(ctx, 1, VecLabels{"user": "a"}); err != nil {
+		t.Errorf("Expected recording under the limit to succeed, got %v", err)
+	}
+	if err := latency.Observe(ctx, 1, VecLabels{"user": "b"}); !errors.Is(err, ErrCardinalityExceeded) {
+		t.Errorf("Expected ErrCardinalityExceeded, got %v", err)
+	}
+	if got := dropped.GetCount(VecLabels{"metric": "test_latency"}); got != 1 {
+		t.Errorf("Expected 1 dropped operation, got %v", got)
+	}
+}
+
+func TestVecMaxCardinalityFailedOperation(t *testing.T) {
+	limiter := &cardinalityLimiter{name: "requests", max: 1, labels: []string{"user"}, onDrop: func() {}, series: make(map[string]struct{})}
+	failure := errors.New("backend failure")
+
+	if err := limiter.admit(VecLabels{"user": "a"}, func() error { return failure }); !errors.Is(err, failure) {
+		t.Errorf("Expected the error of the operation, got %v", err)
+	}
+	// The failed operation didn't take the only slot
+	if err := limiter.admit(VecLabels{"user": "b"}, func() error { return nil }); err != nil {
+		t.Errorf("Expected a new series to be admitted, got %v", err)
+	}
+	if err := limiter.admit(VecLabels{"user": "a"}, func() error { return nil }); !errors.Is(err, ErrCardinalityExceeded) {
+		t.Errorf("Expected ErrCardinalityExceeded, got %v", err)
+	}
+}
+
+func TestVecMaxCardinalityReservesDroppedSeriesName(t *testing.T) {
+	assertNameTaken := func(name string, create func()) {
+		t.Helper()
+		defer func() {
+			if err, _ := recover().(error); !errors.Is(err, ErrMetricNameTaken) {
+				t.Errorf("Expected %s to panic with ErrMetricNameTaken, got %v", name, err)
+			}
+		}()
+		create()
+	}
+	limited := CounterVecOpts{MetricInfo: MetricInfo{Name: "requests"}, Labels: []string{"user"}, MaxCardinality: 2}
+	dropped := CounterOpts{MetricInfo: MetricInfo{Name: DroppedSeriesMetricName}}
+
+	reg := NewRegistry(LevelImportant)
+	group := reg.NewGroup("web", NewMockBackend())
+	group.CounterVec(limited, LevelImportant)
+	assertNameTaken("a metric named like the dropped series of a limited vec", func() {
+		group.Counter(dropped, LevelImportant)
+	})
+
+	group = reg.NewGroup("db", NewMockBackend())
+	group.Counter(dropped, LevelImportant)
+	assertNameTaken("a limited vec of a group using the dropped series name", func() {
+		group.CounterVec(limited, LevelImportant)
+	})
+}
+
+func TestGaugeBounds(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug).NewGroup("test", backend)
+	ctx := group.Context()
+
+	clamped := group.Gauge(GaugeOpts{MetricInfo: MetricInfo{Name: "hit_ratio"}, Bounds: RatioBounds(false)}, LevelImportant)
+	strict := group.Gauge(GaugeOpts{MetricInfo: MetricInfo{Name: "miss_ratio"}, Bounds: RatioBounds(true)}, LevelImportant)
+	clampedAdapter := backend.adapter("test_hit_ratio").(*mockGaugeAdapter)
+	strictAdapter := backend.adapter("test_miss_ratio").(*mockGaugeAdapter)
+
+	tests := []struct {
+		name           string
+		value          float64
+		wantClamped    float64
+		wantClampedErr error
+		wantStrictErr  error
+	}{
+		{"in range", 0.25, 0.25, nil, nil},
+		{"below", -0.5, 0, nil, ErrOutOfBounds},
+		{"above", 1.5, 1, nil, ErrOutOfBounds},
+		{"NaN", math.NaN(), 1, ErrInvalidValue, ErrInvalidValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clampedAdapter.Set(1)
+			strictAdapter.Set(0.5)
+
+			err := clamped.Set(ctx, tt.value)
+			if !errors.Is(err, tt.wantClampedErr) || clampedAdapter.GetValue() != tt.wantClamped {
+				t.Errorf("Expected the clamped gauge to hold %v (error %v), got %v (error %v)", tt.wantClamped, tt.wantClampedErr, clampedAdapter.GetValue(), err)
+			}
+
+			err = strict.Set(ctx, tt.value)
+			want := tt.value
+			if tt.wantStrictErr != nil {
+				want = 0.5 // Unchanged
+			}
+			if !errors.Is(err, tt.wantStrictErr) || strictAdapter.GetValue() != want {
+				t.Errorf("Expected the strict gauge to hold %v (error %v), got %v (error %v)", want, tt.wantStrictErr, strictAdapter.GetValue(), err)
+			}
+		})
+	}
+}
+
+func TestQueueEnqueueDequeueTracksDepth(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+	queue := newTestQueue(group)
+	ctx := group.Context()
+
+	for range 5 {
+		if err := queue.Enqueue(ctx); err != nil {
+			t.Fatalf("Enqueue() failed: %v", err)
+		}
+	}
+	for range 2 {
+		if err := queue.Dequeue(ctx, time.Second); err != nil {
+			t.Fatalf("Dequeue() failed: %v", err)
+		}
+	}
+
+	base := queue.(*switchableQueue).impl.(*baseQueue)
+	depth := base.depth.(*switchableGauge).impl.(*baseGauge).adapter.(*mockGaugeAdapter)
+	if got := depth.GetValue(); got != 3 {
+		t.Errorf("Expected depth 3, got %v", got)
+	}
+	if got := mockCounterOf(t, base.enqueued).GetCount(); got != 5 {
+		t.Errorf("Expected 5 enqueues, got %v", got)
+	}
+	if got := mockCounterOf(t, base.dequeued).GetCount(); got != 2 {
+		t.Errorf("Expected 2 dequeues, got %v", got)
+	}
+	if got := mockHistogramOf(t, base.waitTime).GetObservationCount(); got != 2 {
+		t.Errorf("Expected 2 wait observations, got %v", got)
+	}
+}
+
+// throughputOf returns the base implementation of a group created throughput
+func throughputOf(t *testing.T, tp Throughput) *baseThroughput {
+	t.Helper()
+
+	switchable, ok := tp.(*switchableThroughput)
+	if !ok {
+		t.Fatalf("Expected a switchable throughput, got %T", tp)
+	}
+	base, ok := switchable.impl.(*baseThroughput)
+	if !ok {
+		t.Fatalf("Expected a base throughput, got %T", switchable.impl)
+	}
+	return base
+}
+
+func TestThroughputRate(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+	clock := &fakeClock{now: time.Unix(0, 0)}
+
+	throughput := group.Throughput(
+		ThroughputOpts{
+			MetricInfo: MetricInfo{Name: "requests", Help: "Requests"},
+			CountOpts:  CounterOpts{MetricInfo: MetricInfo{Name: "requests_total"}},
+			RateOpts:   GaugeOpts{MetricInfo: MetricInfo{Name: "requests_rate"}},
+			Clock:      clock,
+		},
+		LevelImportant,
+	)
+	ctx := group.Context()
+
+	if err := throughput.MarkN(ctx, 29); err != nil {
+		t.Fatalf("MarkN() failed: %v", err)
+	}
+	if err := throughput.Mark(ctx); err != nil {
+		t.Fatalf("Mark() failed: %v", err)
+	}
+
+	// Tick directly, the ticker was never started
+	base := throughputOf(t, throughput)
+	clock.Advance(10 * time.Second)
+	if err := base.tick(); err != nil {
+		t.Fatalf("tick() failed: %v", err)
+	}
+
+	rate := base.rate.(*switchableGauge).impl.(*baseGauge).adapter.(*mockGaugeAdapter)
+	if got := rate.GetValue(); got != 3 {
+		t.Errorf("Expected rate 3/s, got %v", got)
+	}
+	if got := mockCounterOf(t, base.count).GetCount(); got != 30 {
+		t.Errorf("Expected 30 events, got %v", got)
+	}
+
+	// The next window is empty
+	clock.Advance(10 * time.Second)
+	base.tick()
+	if got := rate.GetValue(); got != 0 {
+		t.Errorf("Expected rate 0/s after an empty window, got %v", got)
+	}
+}
+
+func TestThroughputAutoStartAndStop(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+
+	throughput := group.Throughput(
+		ThroughputOpts{
+			MetricInfo: MetricInfo{Name: "events", Help: "Events"},
+			CountOpts:  CounterOpts{MetricInfo: MetricInfo{Name: "events_total"}},
+			RateOpts:   GaugeOpts{MetricInfo: MetricInfo{Name: "events_rate"}},
+			Window:     5 * time.Millisecond,
+			AutoStart:  true,
+		},
+		LevelImportant,
+	)
+	base := throughputOf(t, throughput)
+	if !base.running() {
+		t.Fatal("Expected AutoStart to start the ticker")
+	}
+
+	throughput.MarkN(group.Context(), 10)
+	deadline := time.Now().Add(time.Second)
+	for base.pending.Load() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the ticker to compute the rate within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	throughput.Stop()
+	throughput.Stop()
+	if base.running() {
+		t.Error("Expected Stop to stop the ticker")
+	}
+
+	// Stop waits for the ticker to exit, so the gauge is safe to read
+	rate := base.rate.(*switchableGauge).impl.(*baseGauge).adapter.(*mockGaugeAdapter)
+	if got := rate.GetValue(); got <= 0 {
+		t.Errorf("Expected a positive rate, got %v", got)
+	}
+}
+
+func TestThroughputConvertedFromNoopKeepsTicker(t *testing.T) {
+	group := NewRegistry(LevelCritical).NewGroup("test", NewMockBackend())
+
+	throughput := group.Throughput(
+		ThroughputOpts{
+			MetricInfo: MetricInfo{Name: "jobs", Help: "Jobs"},
+			CountOpts:  CounterOpts{MetricInfo: MetricInfo{Name: "jobs_total"}},
+			RateOpts:   GaugeOpts{MetricInfo: MetricInfo{Name: "jobs_rate"}},
+			Window:     time.Hour,
+			AutoStart:  true,
+		},
+		LevelDebug,
+	)
+	noop := throughputOf(t, throughput)
+	if !throughput.(*switchableThroughput).IsNoop() || !noop.running() {
+		t.Fatal("Expected a started noop throughput")
+	}
+
+	group.SetGroupLevel(LevelDebug, LevelOpts{ReplaceNoops: true})
+
+	real := throughputOf(t, throughput)
+	if real == noop {
+		t.Fatal("Expected the noop to be replaced")
+	}
+	if noop.running() || !real.running() {
+		t.Error("Expected the ticker to be handed over to the real throughput")
+	}
+
+	throughput.Stop()
+	if real.running() {
+		t.Error("Expected Stop to stop the ticker")
+	}
+}
+
+func TestDistributionGaugeObserve(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+	distribution := group.DistributionGauge(
+		DistributionGaugeOpts{
+			MetricInfo:    MetricInfo{Name: "temperature", Help: "Temperature"},
+			GaugeOpts:     GaugeOpts{MetricInfo: MetricInfo{Name: "temperature_celsius"}},
+			HistogramOpts: HistogramOpts{MetricInfo: MetricInfo{Name: "temperature_celsius_observed"}},
+		},
+		LevelImportant,
+	)
+	ctx := group.Context()
+
+	for _, value := range []float64{21, 23.5, 22} {
+		if err := distribution.Observe(ctx, value); err != nil {
+			t.Fatalf("Observe() failed: %v", err)
+		}
+	}
+
+	base := distribution.(*switchableDistributionGauge).impl.(*baseDistributionGauge)
+	latest := base.gauge.(*switchableGauge).impl.(*baseGauge).adapter.(*mockGaugeAdapter)
+	if got := latest.GetValue(); got != 22 {
+		t.Errorf("Expected the gauge to hold the latest value 22, got %v", got)
+	}
+	observed := mockHistogramOf(t, base.histogram).GetObservations()
+	if !slices.Equal(observed, []float64{21, 23.5, 22}) {
+		t.Errorf("Expected the histogram to accumulate all values, got %v", observed)
+	}
+}
+
+func TestMultiResHistogramObserve(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug).NewGroup("test", backend)
+	multiRes := group.MultiResHistogram(
+		MultiResHistogramOpts{
+			MetricInfo: MetricInfo{Name: "latency", Help: "Latency"},
+			Resolutions: []HistogramOpts{
+				{MetricInfo: MetricInfo{Name: "latency_coarse"}, Buckets: []float64{0.1, 1, 10}},
+				{Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}},
+			},
+		},
+		LevelImportant,
+	)
+	ctx := group.Context()
+
+	for _, value := range []float64{0.02, 0.3} {
+		if err := multiRes.Observe(ctx, value); err != nil {
+			t.Fatalf("Observe() failed: %v", err)
+		}
+	}
+
+	components := multiRes.Components()
+	if len(components) != 2 || components[0].Name() != "test_latency_coarse" || components[1].Name() != "test_latency_res1" {
+		t.Fatalf("Expected distinct resolution names, got %v", components)
+	}
+	for _, name := range []string{"test_latency_coarse", "test_latency_res1"} {
+		observed := backend.adapter(name).(*mockHistogramAdapter).GetObservations()
+		if !slices.Equal(observed, []float64{0.02, 0.3}) {
+			t.Errorf("Expected %s to receive every observation, got %v", name, observed)
+		}
+	}
+	if help := components[1].Help(); help != "Latency (resolution 1)" {
+		t.Errorf("Expected the resolution help to be derived, got %q", help)
+	}
+}
+
+func TestMultiResHistogramDuplicateNamesPanics(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected resolutions with the same name to panic")
+		}
+	}()
+	group.MultiResHistogram(MultiResHistogramOpts{
+		MetricInfo: MetricInfo{Name: "latency"},
+		Resolutions: []HistogramOpts{
+			{MetricInfo: MetricInfo{Name: "latency_res1"}},
+			{}, // Defaults to latency_res1 too
+		},
+	}, LevelImportant)
+}
+
+func TestDependencyCall(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug).NewGroup("test", backend)
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	dependency := group.Dependency(DependencyOpts{MetricInfo: MetricInfo{Name: "db", Help: "Database"}, Clock: clock}, LevelImportant)
+	ctx := group.Context()
+
+	labels := VecLabels{DependencyLabel: "postgres", OperationLabel: "select_user"}
+	inFlight := backend.adapter("test_db_in_flight").(*mockGaugeVecAdapter)
+
+	done := dependency.Call(ctx, "postgres", "select_user")
+	if got := inFlight.GetValue(labels); got != 1 {
+		t.Errorf("Expected 1 call in flight, got %v", got)
+	}
+	clock.Advance(250 * time.Millisecond)
+	done(nil)
+
+	failed := dependency.Call(ctx, "postgres", "select_user")
+	clock.Advance(time.Second)
+	failed(errors.New("connection refused"))
+
+	if got := inFlight.GetValue(labels); got != 0 {
+		t.Errorf("Expected no calls in flight, got %v", got)
+	}
+	latency := backend.adapter("test_db_latency_seconds").(*mockHistogramVecAdapter)
+	if got := latency.GetObservations(labels); !slices.Equal(got, []float64{0.25, 1}) {
+		t.Errorf("Expected the latencies [0.25 1], got %v", got)
+	}
+	calls := backend.adapter("test_db_calls_total").(*mockCounterVecAdapter)
+	for outcome, want := range map[string]float64{OutcomeSuccess: 1, OutcomeFailure: 1} {
+		outcomeLabels := VecLabels{DependencyLabel: "postgres", OperationLabel: "select_user", OutcomeLabel: outcome}
+		if got := calls.GetCount(outcomeLabels); got != want {
+			t.Errorf("Expected %v %s calls, got %v", want, outcome, got)
+		}
+	}
+
+	if help := dependency.Components()[0].Help(); help != "Database (calls)" {
+		t.Errorf("Expected the calls help to be derived, got %q", help)
+	}
+}
+
+func TestDependencyDisabled(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelImportant).NewGroup("test", backend)
+	dependency := group.Dependency(DependencyOpts{MetricInfo: MetricInfo{Name: "db"}}, LevelDebug)
+
+	dependency.Call(group.Context(), "postgres", "select_user")(nil)
+	if !dependency.(SwitchableMetric).IsNoop() || backend.adapter("test_db_calls_total") != nil {
+		t.Error("Expected a disabled dependency to record nothing")
+	}
+}
+
+func TestCompositeLevelGatesComponents(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelVerbose).NewGroup("test", backend)
+	cache := group.Cache(CacheOpts{
+		MetricInfo: MetricInfo{Name: "cache"},
+		HitOpts:    CounterOpts{MetricInfo: MetricInfo{Name: "cache_hits"}},
+		MissOpts:   CounterOpts{MetricInfo: MetricInfo{Name: "cache_misses"}},
+		SizeOpts:   GaugeOpts{MetricInfo: MetricInfo{Name: "cache_size"}},
+	}, LevelVerbose)
+	timer := group.Timer(TimerOpts{
+		MetricInfo:    MetricInfo{Name: "op"},
+		HistogramOpts: HistogramOpts{MetricInfo: MetricInfo{Name: "op_duration"}},
+	}, LevelVerbose)
+	dependency := group.Dependency(DependencyOpts{MetricInfo: MetricInfo{Name: "db"}}, LevelVerbose)
+
+	// Components left enabled at a more important level than their composite
+	for _, composite := range []CompositeMetric{cache, timer, dependency} {
+		for _, component := range composite.Components() {
+			component.SetLevel(LevelCritical)
+		}
+	}
+
+	ctx := NewContext(LevelImportant)
+	_ = cache.Hit(ctx)
+	_ = cache.SetSize(ctx, 64)
+	timer.Start(ctx)()
+	_ = timer.Record(ctx, time.Second)
+	dependency.Call(ctx, "postgres", "select_user")(nil)
+
+	for _, name := range []string{"test_cache_hits", "test_cache_size", "test_op_duration"} {
+		if mockRecorded(backend.adapter(name)) {
+			t.Errorf("Expected %s not to record under a context disabling its composite", name)
+		}
+	}
+	if calls, _ := backend.adapter("test_db_calls_total").(*mockCounterVecAdapter).Series(); len(calls) != 0 {
+		t.Errorf("Expected no dependency calls under a context disabling it, got %v", calls)
+	}
+
+	// The composite records once the context enables its level
+	_ = cache.Hit(NewContextAll())
+	if got := backend.adapter("test_cache_hits").(*mockCounterAdapter).GetCount(); got != 1 {
+		t.Errorf("Expected 1 hit, got %v", got)
+	}
+}
+
+func TestCompositeComponentsOrder(t *testing.T) {
+	info := func(name string) MetricInfo { return MetricInfo{Name: name} }
+	labels := []string{"route"}
+
+	tests := []struct {
+		name   string
+		create func(f Factory) CompositeMetric
+		want   []string
+	}{
+		{"Timer", func(f Factory) CompositeMetric {
+			return f.Timer(TimerOpts{MetricInfo: info("op"), HistogramOpts: HistogramOpts{MetricInfo: info("op_seconds")}}, LevelCritical)
+		}, []string{"op_seconds"}},
+		{"TimerVec", func(f Factory) CompositeMetric {
+			return f.TimerVec(TimerVecOpts{MetricInfo: info("op"), HistogramVecOpts: HistogramVecOpts{MetricInfo: info("op_seconds"), Labels: labels}}, LevelCritical)
+		}, []string{"op_seconds"}},
+		{"Cache", func(f Factory) CompositeMetric {
+			return f.Cache(CacheOpts{
+				MetricInfo: info("cache"),
+				HitOpts:    CounterOpts{MetricInfo: info("hits")},
+				MissOpts:   CounterOpts{MetricInfo: info("misses")},
+				SizeOpts:   GaugeOpts{MetricInfo: info("size")},
+			}, LevelCritical)
+		}, []string{"hits", "misses", "size"}},
+		{"CacheVec", func(f Factory) CompositeMetric {
+			return f.CacheVec(CacheVecOpts{
+				MetricInfo:  info("cache"),
+				HitVecOpts:  CounterVecOpts{MetricInfo: info("hits"), Labels: labels},
+				MissVecOpts: CounterVecOpts{MetricInfo: info("misses"), Labels: labels},
+				SizeVecOpts: GaugeVecOpts{MetricInfo: info("size"), Labels: labels},
+			}, LevelCritical)
+		}, []string{"hits", "misses", "size"}},
+		{"Pool", func(f Factory) CompositeMetric {
+			return f.Pool(PoolOpts{
+				MetricInfo:   info("pool"),
+				ActiveOpts:   GaugeOpts{MetricInfo: info("active")},
+				IdleOpts:     GaugeOpts{MetricInfo: info("idle")},
+				AcquiredOpts: CounterOpts{MetricInfo: info("acquired")},
+				ReleasedOpts: CounterOpts{MetricInfo: info("released")},
+			}, LevelCritical)
+		}, []string{"active", "idle", "acquired", "released"}},
+		{"PoolVec", func(f Factory) CompositeMetric {
+			return f.PoolVec(PoolVecOpts{
+				MetricInfo:      info("pool"),
+				ActiveVecOpts:   GaugeVecOpts{MetricInfo: info("active"), Labels: labels},
+				IdleVecOpts:     GaugeVecOpts{MetricInfo: info("idle"), Labels: labels},
+				AcquiredVecOpts: CounterVecOpts{MetricInfo: info("acquired"), Labels: labels},
+				ReleasedVecOpts: CounterVecOpts{MetricInfo: info("released"), Labels: labels},
+			}, LevelCritical)
+		}, []string{"active", "idle", "acquired", "released"}},
+		{"CircuitBreaker", func(f Factory) CompositeMetric {
+			return f.CircuitBreaker(CircuitBreakerOpts{
+				MetricInfo:  info("breaker"),
+				StateOpts:   GaugeOpts{MetricInfo: info("state")},
+				SuccessOpts: CounterOpts{MetricInfo: info("successes")},
+				FailureOpts: CounterOpts{MetricInfo: info("failures")},
+			}, LevelCritical)
+		}, []string{"state", "successes", "failures"}},
+		{"CircuitBreakerVec", func(f Factory) CompositeMetric {
+			return f.CircuitBreakerVec(CircuitBreakerVecOpts{
+				MetricInfo:     info("breaker"),
+				StateVecOpts:   GaugeVecOpts{MetricInfo: info("state"), Labels: labels},
+				SuccessVecOpts: CounterVecOpts{MetricInfo: info("successes"), Labels: labels},
+				FailureVecOpts: CounterVecOpts{MetricInfo: info("failures"), Labels: labels},
+			}, LevelCritical)
+		}, []string{"state", "successes", "failures"}},
+		{"Queue", func(f Factory) CompositeMetric {
+			return f.Queue(QueueOpts{
+				MetricInfo:   info("queue"),
+				DepthOpts:    GaugeOpts{MetricInfo: info("depth")},
+				EnqueuedOpts: CounterOpts{MetricInfo: info("enqueued")},
+				DequeuedOpts: CounterOpts{MetricInfo: info("dequeued")},
+				WaitTimeOpts: HistogramOpts{MetricInfo: info("wait_seconds")},
+			}, LevelCritical)
+		}, []string{"depth", "enqueued", "dequeued", "wait_seconds"}},
+		{"QueueVec", func(f Factory) CompositeMetric {
+			return f.QueueVec(QueueVecOpts{
+				MetricInfo:      info("queue"),
+				DepthVecOpts:    GaugeVecOpts{MetricInfo: info("depth"), Labels: labels},
+				EnqueuedVecOpts: CounterVecOpts{MetricInfo: info("enqueued"), Labels: labels},
+				DequeuedVecOpts: CounterVecOpts{MetricInfo: info("dequeued"), Labels: labels},
+				WaitTimeVecOpts: HistogramVecOpts{MetricInfo: info("wait_seconds"), Labels: labels},
+			}, LevelCritical)
+		}, []string{"depth", "enqueued", "dequeued", "wait_seconds"}},
+		{"Throughput", func(f Factory) CompositeMetric {
+			return f.Throughput(ThroughputOpts{
+				MetricInfo: info("events"),
+				CountOpts:  CounterOpts{MetricInfo: info("events_total")},
+				RateOpts:   GaugeOpts{MetricInfo: info("events_per_second")},
+			}, LevelCritical)
+		}, []string{"events_total", "events_per_second"}},
+		{"DistributionGauge", func(f Factory) CompositeMetric {
+			return f.DistributionGauge(DistributionGaugeOpts{
+				MetricInfo:    info("temperature"),
+				GaugeOpts:     GaugeOpts{MetricInfo: info("temperature_latest")},
+				HistogramOpts: HistogramOpts{MetricInfo: info("temperature_observed")},
+			}, LevelCritical)
+		}, []string{"temperature_latest", "temperature_observed"}},
+		{"MultiResHistogram", func(f Factory) CompositeMetric {
+			return f.MultiResHistogram(MultiResHistogramOpts{
+				MetricInfo:  info("latency"),
+				Resolutions: []HistogramOpts{{MetricInfo: info("latency_fine")}, {MetricInfo: info("latency_coarse")}},
+			}, LevelCritical)
+		}, []string{"latency_fine", "latency_coarse"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+			composite := tt.create(group)
+			if stoppable, ok := composite.(Stoppable); ok {
+				defer stoppable.Stop()
+			}
+
+			// The order doesn't vary between calls
+			for range 2 {
+				names := make([]string, 0, len(tt.want))
+				for _, component := range composite.Components() {
+					names = append(names, strings.TrimPrefix(component.Name(), "test_"))
+				}
+				if !slices.Equal(names, tt.want) {
+					t.Fatalf("Expected components %v, got %v", tt.want, names)
+				}
+			}
+		})
+	}
+}
+
+func TestExemplarsIgnoredByUnsupportedBackend(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelImportant).NewGroup("test", backend)
+	ctx := group.Context()
+	exemplar := VecLabels{"trace_id": "abc"}
+
+	requests := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests"}}, LevelImportant)
+	_ = requests.IncWithExemplar(ctx, exemplar)
+	_ = requests.AddWithExemplar(ctx, 2, exemplar)
+	if err := requests.AddWithExemplar(ctx, -1, exemplar); !errors.Is(err, ErrNegativeCounterAdd) {
+		t.Errorf("Expected ErrNegativeCounterAdd, got %v", err)
+	}
+	if got := backend.adapter("test_requests").(*mockCounterAdapter).GetCount(); got != 3 {
+		t.Errorf("Expected the counter to be 3, got %v", got)
+	}
+
+	latency := group.Histogram(HistogramOpts{MetricInfo: MetricInfo{Name: "latency"}}, LevelImportant)
+	_ = latency.ObserveWithExemplar(ctx, 0.5, exemplar)
+	if got := backend.adapter("test_latency").(*mockHistogramAdapter).GetObservations(); !slices.Equal(got, []float64{0.5}) {
+		t.Errorf("Expected the observation to be recorded, got %v", got)
+	}
+}
+
+func TestHistogramTime(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug).NewGroup("test", backend)
+	histogram := group.Histogram(HistogramOpts{MetricInfo: MetricInfo{Name: "op_duration"}}, LevelImportant)
+	errFailed := errors.New("failed")
+
+	err := histogram.Time(NewContextAll(), func() error {
+		time.Sleep(20 * time.Millisecond)
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Errorf("Expected the error of fn to be returned, got %v", err)
+	}
+	observations := backend.adapter("test_op_duration").(*mockHistogramAdapter).GetObservations()
+	if len(observations) != 1 || observations[0] < 0.02 || observations[0] > 1 {
+		t.Fatalf("Expected a single observation of about 0.02s, got %v", observations)
+	}
+
+	// Disabled, fn is still called but isn't timed
+	called := false
+	if err := histogram.Time(NewContext(LevelCritical), func() error { called = true; return nil }); err != nil || !called {
+		t.Errorf("Expected fn to be called when disabled, got called=%v err=%v", called, err)
+	}
+	if n := len(backend.adapter("test_op_duration").(*mockHistogramAdapter).GetObservations()); n != 1 {
+		t.Errorf("Expected no observation when disabled, got %d", n)
+	}
+}
+
+func TestHistogramVecTime(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug).NewGroup("test", backend)
+	vec := group.HistogramVec(HistogramVecOpts{MetricInfo: MetricInfo{Name: "op_duration"}, Labels: []string{"route"}}, LevelImportant)
+	errFailed := errors.New("failed")
+
+	err := vec.Time(NewContextAll(), func() error {
+		time.Sleep(20 * time.Millisecond)
+		return errFailed
+	}, VecLabels{"route": "/users"})
+	if !errors.Is(err, errFailed) {
+		t.Errorf("Expected the error of fn to be returned, got %v", err)
+	}
+	observations := backend.adapter("test_op_duration").(*mockHistogramVecAdapter).GetObservations(VecLabels{"route": "/users"})
+	if len(observations) != 1 || observations[0] < 0.02 || observations[0] > 1 {
+		t.Errorf("Expected a single observation of about 0.02s, got %v", observations)
+	}
+}
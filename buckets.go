@@ -0,0 +1,80 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: buckets.go
+//
+// This file contains default histogram bucket sets for common units, and the
+// named presets that can be selected with [HistogramOpts.BucketPreset].
+//--------------------------------------------------------------------------------
+
+// Bucket presets for [HistogramOpts.BucketPreset] and [HistogramVecOpts.BucketPreset]
+const (
+	BucketPresetLatency = "latency" // [LatencyBuckets]
+	BucketPresetSize    = "size"    // [SizeBuckets]
+	BucketPresetRatio   = "ratio"   // [RatioBuckets]
+)
+
+// LatencyBuckets returns histogram buckets suited for request latencies in
+// seconds, from 5ms up to 10s.
+func LatencyBuckets() []float64 {
+	return []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+}
+
+// SizeBuckets returns histogram buckets suited for sizes in bytes.
+// It is the same as [ByteBuckets].
+func SizeBuckets() []float64 {
+	return ByteBuckets()
+}
+
+// RatioBuckets returns histogram buckets suited for ratios in [0, 1],
+// e.g. utilization or hit rates.
+func RatioBuckets() []float64 {
+	return []float64{.05, .1, .25, .5, .75, .9, .95, .99, 1}
+}
+
+// resolveBuckets returns buckets if set, otherwise the buckets of the named
+// preset. An empty or unknown preset resolves to nil, leaving the choice of
+// buckets to the backend.
+func resolveBuckets(buckets []float64, preset string) []float64 {
+	if buckets != nil {
+		return buckets
+	}
+
+	switch preset {
+	case BucketPresetLatency:
+		return LatencyBuckets()
+	case BucketPresetSize:
+		return SizeBuckets()
+	case BucketPresetRatio:
+		return RatioBuckets()
+	default:
+		return nil
+	}
+}
+
+// ByteBuckets returns histogram buckets suited for payload sizes in bytes.
+//
+// The buckets are powers of four, from 64 B up to and including 1 GiB:
+// 64, 256, 1 KiB, 4 KiB, ..., 256 MiB, 1 GiB.
+func ByteBuckets() []float64 {
+	return ExponentialBuckets(64, 4, 13)
+}
+
+// ExponentialBuckets returns count buckets, where the lowest bucket has an
+// upper bound of start, and each following bucket's upper bound is factor
+// times the previous one.
+//
+// It returns nil if count < 1, start <= 0, or factor <= 1.
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	if count < 1 || start <= 0 || factor <= 1 {
+		return nil
+	}
+
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+
+	return buckets
+}
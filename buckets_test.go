@@ -0,0 +1,71 @@
+package umami
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestByteBuckets(t *testing.T) {
+	want := []float64{
+		64, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10,
+		1 << 20, 4 << 20, 16 << 20, 64 << 20, 256 << 20, 1 << 30,
+	}
+	if got := ByteBuckets(); !slices.Equal(got, want) {
+		t.Errorf("ByteBuckets() = %v, want %v", got, want)
+	}
+}
+
+func TestExponentialBucketsInvalid(t *testing.T) {
+	if got := ExponentialBuckets(1, 2, 0); got != nil {
+		t.Errorf("Expected nil for zero count, got %v", got)
+	}
+	if got := ExponentialBuckets(0, 2, 3); got != nil {
+		t.Errorf("Expected nil for zero start, got %v", got)
+	}
+	if got := ExponentialBuckets(1, 1, 3); got != nil {
+		t.Errorf("Expected nil for factor 1, got %v", got)
+	}
+}
+
+func TestBucketPresets(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+
+	tests := []struct {
+		preset string
+		want   []float64
+	}{
+		{BucketPresetLatency, []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}},
+		{BucketPresetSize, ByteBuckets()},
+		{BucketPresetRatio, []float64{.05, .1, .25, .5, .75, .9, .95, .99, 1}},
+		{"unknown", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.preset, func(t *testing.T) {
+			hist := group.Histogram(
+				HistogramOpts{MetricInfo: MetricInfo{Name: "h_" + tt.preset}, BucketPreset: tt.preset},
+				LevelCritical,
+			)
+			opts := hist.(*switchableHistogram).switchOpts().(HistogramOpts)
+			if !slices.Equal(opts.Buckets, tt.want) {
+				t.Errorf("Buckets = %v, want %v", opts.Buckets, tt.want)
+			}
+
+			histVec := group.HistogramVec(
+				HistogramVecOpts{MetricInfo: MetricInfo{Name: "hv_" + tt.preset}, BucketPreset: tt.preset},
+				LevelCritical,
+			)
+			vecOpts := histVec.(*switchableHistogramVec).switchOpts().(HistogramVecOpts)
+			if !slices.Equal(vecOpts.Buckets, tt.want) {
+				t.Errorf("Vec Buckets = %v, want %v", vecOpts.Buckets, tt.want)
+			}
+		})
+	}
+}
+
+func TestBucketPresetExplicitBucketsWin(t *testing.T) {
+	explicit := []float64{1, 2, 3}
+	if got := resolveBuckets(explicit, BucketPresetLatency); !slices.Equal(got, explicit) {
+		t.Errorf("Expected explicit buckets to win over the preset, got %v", got)
+	}
+}
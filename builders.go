@@ -0,0 +1,1011 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: builders.go
+//
+// This file contains fluent builders of the opts of every metric type, as a
+// terser alternative to nested opts literals. Each builder is created from
+// the name of the metric, and Build returns the opts, e.g.
+//
+//	opts := umami.NewCache("sessions").
+//		WithHelp("Session cache").
+//		HitHelp("Session cache hits").
+//		Build()
+//
+//	sessions := group.Cache(opts, umami.LevelImportant)
+//
+// Builders only set fields of the opts, and unset fields keep the defaults
+// of the struct literal. Build returns the opts by value, but the slices and
+// maps set on the builder are shared with the opts, so a builder shouldn't
+// be modified once the opts it built are in use.
+//--------------------------------------------------------------------------------
+
+import "time"
+
+//--------------------------------------------------------------------------------
+// Shared Setters
+//--------------------------------------------------------------------------------
+
+// infoBuilder provides the setters of the [MetricInfo] of the opts built by B
+type infoBuilder[B any] struct {
+	builder *B
+	info    *MetricInfo
+}
+
+// WithHelp sets [MetricInfo.Help]
+func (b infoBuilder[B]) WithHelp(help string) *B {
+	b.info.Help = help
+	return b.builder
+}
+
+// WithUnit sets [MetricInfo.Unit]
+func (b infoBuilder[B]) WithUnit(unit string) *B {
+	b.info.Unit = unit
+	return b.builder
+}
+
+// WithNamespace sets [MetricInfo.Namespace]
+func (b infoBuilder[B]) WithNamespace(namespace string) *B {
+	b.info.Namespace = namespace
+	return b.builder
+}
+
+// WithSubsystem sets [MetricInfo.Subsystem]
+func (b infoBuilder[B]) WithSubsystem(subsystem string) *B {
+	b.info.Subsystem = subsystem
+	return b.builder
+}
+
+// WithConstLabels sets [MetricInfo.ConstLabels]
+func (b infoBuilder[B]) WithConstLabels(labels map[string]string) *B {
+	b.info.ConstLabels = labels
+	return b.builder
+}
+
+// WithTags sets [MetricInfo.Tags]
+func (b infoBuilder[B]) WithTags(tags ...string) *B {
+	b.info.Tags = tags
+	return b.builder
+}
+
+// WithMask sets [MetricInfo.Mask]
+func (b infoBuilder[B]) WithMask(mask Mask) *B {
+	b.info.Mask = mask
+	return b.builder
+}
+
+// WithSLO sets [MetricInfo.SLO]
+func (b infoBuilder[B]) WithSLO(slo *SLOSpec) *B {
+	b.info.SLO = slo
+	return b.builder
+}
+
+// basicBuilder provides the setters of the [MetricInfo] and [BasicMetricOpts]
+// of the opts of a basic metric built by B
+type basicBuilder[B any] struct {
+	infoBuilder[B]
+	basic *BasicMetricOpts
+}
+
+func newBasicBuilder[B any](builder *B, info *MetricInfo, basic *BasicMetricOpts) basicBuilder[B] {
+	return basicBuilder[B]{infoBuilder: infoBuilder[B]{builder: builder, info: info}, basic: basic}
+}
+
+// WithNoPrefix sets [BasicMetricOpts.NoPrefix]
+func (b basicBuilder[B]) WithNoPrefix() *B {
+	b.basic.NoPrefix = true
+	return b.builder
+}
+
+// WithPredicate sets [BasicMetricOpts.Predicate]
+func (b basicBuilder[B]) WithPredicate(predicate func(ctx Context) bool) *B {
+	b.basic.Predicate = predicate
+	return b.builder
+}
+
+// WithAsync sets [BasicMetricOpts.Async]
+func (b basicBuilder[B]) WithAsync() *B {
+	b.basic.Async = true
+	return b.builder
+}
+
+// compositeBuilder provides the setters of the [MetricInfo] and
+// [CompositeMetricOpts] of the opts of a composite metric built by B
+type compositeBuilder[B any] struct {
+	infoBuilder[B]
+	composite *CompositeMetricOpts
+}
+
+func newCompositeBuilder[B any](builder *B, info *MetricInfo, composite *CompositeMetricOpts) compositeBuilder[B] {
+	return compositeBuilder[B]{infoBuilder: infoBuilder[B]{builder: builder, info: info}, composite: composite}
+}
+
+// WithNoPrefix sets [CompositeMetricOpts.NoPrefix]
+func (b compositeBuilder[B]) WithNoPrefix() *B {
+	b.composite.NoPrefix = true
+	return b.builder
+}
+
+// vecBuilder provides the setters of the labels of the opts of a basic
+// label-vectorized metric built by B
+type vecBuilder[B any] struct {
+	builder        *B
+	labels         *[]string
+	preInitLabels  *[]VecLabels
+	maxCardinality *int
+}
+
+// Labels sets the label names
+func (b vecBuilder[B]) Labels(labels ...string) *B {
+	*b.labels = labels
+	return b.builder
+}
+
+// PreInitLabels sets the label sets whose series are created with the metric,
+// see [CounterVecOpts.PreInitLabels]
+func (b vecBuilder[B]) PreInitLabels(labelSets ...VecLabels) *B {
+	*b.preInitLabels = labelSets
+	return b.builder
+}
+
+// MaxCardinality sets the maximum number of series, see
+// [CounterVecOpts.MaxCardinality]
+func (b vecBuilder[B]) MaxCardinality(n int) *B {
+	*b.maxCardinality = n
+	return b.builder
+}
+
+// bucketsBuilder provides the setters of the buckets of the opts of a
+// histogram built by B
+type bucketsBuilder[B any] struct {
+	builder      *B
+	buckets      *[]float64
+	bucketPreset *string
+}
+
+// Buckets sets the bucket upper bounds
+func (b bucketsBuilder[B]) Buckets(buckets ...float64) *B {
+	*b.buckets = buckets
+	return b.builder
+}
+
+// BucketPreset sets the named default bucket set, see [HistogramOpts.BucketPreset]
+func (b bucketsBuilder[B]) BucketPreset(preset string) *B {
+	*b.bucketPreset = preset
+	return b.builder
+}
+
+//--------------------------------------------------------------------------------
+// Basic Builders
+//--------------------------------------------------------------------------------
+
+// CounterBuilder builds [CounterOpts], see [NewCounter]
+type CounterBuilder struct {
+	basicBuilder[CounterBuilder]
+	opts CounterOpts
+}
+
+// NewCounter returns a builder of the [CounterOpts] of the counter name
+func NewCounter(name string) *CounterBuilder {
+	b := &CounterBuilder{opts: CounterOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.basicBuilder = newBasicBuilder(b, &b.opts.MetricInfo, &b.opts.BasicMetricOpts)
+	return b
+}
+
+// ResetOnScrape sets [CounterOpts.ResetOnScrape]
+func (b *CounterBuilder) ResetOnScrape() *CounterBuilder {
+	b.opts.ResetOnScrape = true
+	return b
+}
+
+// Build returns the opts
+func (b *CounterBuilder) Build() CounterOpts {
+	return b.opts
+}
+
+// CounterVecBuilder builds [CounterVecOpts], see [NewCounterVec]
+type CounterVecBuilder struct {
+	basicBuilder[CounterVecBuilder]
+	vecBuilder[CounterVecBuilder]
+	opts CounterVecOpts
+}
+
+// NewCounterVec returns a builder of the [CounterVecOpts] of the counter vec name
+func NewCounterVec(name string) *CounterVecBuilder {
+	b := &CounterVecBuilder{opts: CounterVecOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.basicBuilder = newBasicBuilder(b, &b.opts.MetricInfo, &b.opts.BasicMetricOpts)
+	b.vecBuilder = vecBuilder[CounterVecBuilder]{builder: b, labels: &b.opts.Labels, preInitLabels: &b.opts.PreInitLabels, maxCardinality: &b.opts.MaxCardinality}
+	return b
+}
+
+// Build returns the opts
+func (b *CounterVecBuilder) Build() CounterVecOpts {
+	return b.opts
+}
+
+// GaugeBuilder builds [GaugeOpts], see [NewGauge]
+type GaugeBuilder struct {
+	basicBuilder[GaugeBuilder]
+	opts GaugeOpts
+}
+
+// NewGauge returns a builder of the [GaugeOpts] of the gauge name
+func NewGauge(name string) *GaugeBuilder {
+	b := &GaugeBuilder{opts: GaugeOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.basicBuilder = newBasicBuilder(b, &b.opts.MetricInfo, &b.opts.BasicMetricOpts)
+	return b
+}
+
+// Bounds sets [GaugeOpts.Bounds], e.g. to [RatioBounds]
+func (b *GaugeBuilder) Bounds(bounds *GaugeBounds) *GaugeBuilder {
+	b.opts.Bounds = bounds
+	return b
+}
+
+// Build returns the opts
+func (b *GaugeBuilder) Build() GaugeOpts {
+	return b.opts
+}
+
+// GaugeVecBuilder builds [GaugeVecOpts], see [NewGaugeVec]
+type GaugeVecBuilder struct {
+	basicBuilder[GaugeVecBuilder]
+	vecBuilder[GaugeVecBuilder]
+	opts GaugeVecOpts
+}
+
+// NewGaugeVec returns a builder of the [GaugeVecOpts] of the gauge vec name
+func NewGaugeVec(name string) *GaugeVecBuilder {
+	b := &GaugeVecBuilder{opts: GaugeVecOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.basicBuilder = newBasicBuilder(b, &b.opts.MetricInfo, &b.opts.BasicMetricOpts)
+	b.vecBuilder = vecBuilder[GaugeVecBuilder]{builder: b, labels: &b.opts.Labels, preInitLabels: &b.opts.PreInitLabels, maxCardinality: &b.opts.MaxCardinality}
+	return b
+}
+
+// Build returns the opts
+func (b *GaugeVecBuilder) Build() GaugeVecOpts {
+	return b.opts
+}
+
+// HistogramBuilder builds [HistogramOpts], see [NewHistogram]
+type HistogramBuilder struct {
+	basicBuilder[HistogramBuilder]
+	bucketsBuilder[HistogramBuilder]
+	opts HistogramOpts
+}
+
+// NewHistogram returns a builder of the [HistogramOpts] of the histogram name
+func NewHistogram(name string) *HistogramBuilder {
+	b := &HistogramBuilder{opts: HistogramOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.basicBuilder = newBasicBuilder(b, &b.opts.MetricInfo, &b.opts.BasicMetricOpts)
+	b.bucketsBuilder = bucketsBuilder[HistogramBuilder]{builder: b, buckets: &b.opts.Buckets, bucketPreset: &b.opts.BucketPreset}
+	return b
+}
+
+// NativeHistogram sets [HistogramOpts.NativeHistogramBucketFactor] and
+// [HistogramOpts.NativeHistogramMaxBucketNumber]
+func (b *HistogramBuilder) NativeHistogram(bucketFactor float64, maxBucketNumber uint32) *HistogramBuilder {
+	b.opts.NativeHistogramBucketFactor = bucketFactor
+	b.opts.NativeHistogramMaxBucketNumber = maxBucketNumber
+	return b
+}
+
+// Build returns the opts
+func (b *HistogramBuilder) Build() HistogramOpts {
+	return b.opts
+}
+
+// HistogramVecBuilder builds [HistogramVecOpts], see [NewHistogramVec]
+type HistogramVecBuilder struct {
+	basicBuilder[HistogramVecBuilder]
+	vecBuilder[HistogramVecBuilder]
+	bucketsBuilder[HistogramVecBuilder]
+	opts HistogramVecOpts
+}
+
+// NewHistogramVec returns a builder of the [HistogramVecOpts] of the histogram vec name
+func NewHistogramVec(name string) *HistogramVecBuilder {
+	b := &HistogramVecBuilder{opts: HistogramVecOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.basicBuilder = newBasicBuilder(b, &b.opts.MetricInfo, &b.opts.BasicMetricOpts)
+	b.vecBuilder = vecBuilder[HistogramVecBuilder]{builder: b, labels: &b.opts.Labels, preInitLabels: &b.opts.PreInitLabels, maxCardinality: &b.opts.MaxCardinality}
+	b.bucketsBuilder = bucketsBuilder[HistogramVecBuilder]{builder: b, buckets: &b.opts.Buckets, bucketPreset: &b.opts.BucketPreset}
+	return b
+}
+
+// NativeHistogram sets [HistogramVecOpts.NativeHistogramBucketFactor] and
+// [HistogramVecOpts.NativeHistogramMaxBucketNumber]
+func (b *HistogramVecBuilder) NativeHistogram(bucketFactor float64, maxBucketNumber uint32) *HistogramVecBuilder {
+	b.opts.NativeHistogramBucketFactor = bucketFactor
+	b.opts.NativeHistogramMaxBucketNumber = maxBucketNumber
+	return b
+}
+
+// Build returns the opts
+func (b *HistogramVecBuilder) Build() HistogramVecOpts {
+	return b.opts
+}
+
+// SummaryBuilder builds [SummaryOpts], see [NewSummary]
+type SummaryBuilder struct {
+	basicBuilder[SummaryBuilder]
+	opts SummaryOpts
+}
+
+// NewSummary returns a builder of the [SummaryOpts] of the summary name
+func NewSummary(name string) *SummaryBuilder {
+	b := &SummaryBuilder{opts: SummaryOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.basicBuilder = newBasicBuilder(b, &b.opts.MetricInfo, &b.opts.BasicMetricOpts)
+	return b
+}
+
+// Objectives sets [SummaryOpts.Objectives]
+func (b *SummaryBuilder) Objectives(objectives map[float64]float64) *SummaryBuilder {
+	b.opts.Objectives = objectives
+	return b
+}
+
+// ObjectiveSets sets [SummaryOpts.ObjectiveSets]
+func (b *SummaryBuilder) ObjectiveSets(sets ...map[float64]float64) *SummaryBuilder {
+	b.opts.ObjectiveSets = sets
+	return b
+}
+
+// Build returns the opts
+func (b *SummaryBuilder) Build() SummaryOpts {
+	return b.opts
+}
+
+// SummaryVecBuilder builds [SummaryVecOpts], see [NewSummaryVec]
+type SummaryVecBuilder struct {
+	basicBuilder[SummaryVecBuilder]
+	vecBuilder[SummaryVecBuilder]
+	opts SummaryVecOpts
+}
+
+// NewSummaryVec returns a builder of the [SummaryVecOpts] of the summary vec name
+func NewSummaryVec(name string) *SummaryVecBuilder {
+	b := &SummaryVecBuilder{opts: SummaryVecOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.basicBuilder = newBasicBuilder(b, &b.opts.MetricInfo, &b.opts.BasicMetricOpts)
+	b.vecBuilder = vecBuilder[SummaryVecBuilder]{builder: b, labels: &b.opts.Labels, preInitLabels: &b.opts.PreInitLabels, maxCardinality: &b.opts.MaxCardinality}
+	return b
+}
+
+// Objectives sets [SummaryVecOpts.Objectives]
+func (b *SummaryVecBuilder) Objectives(objectives map[float64]float64) *SummaryVecBuilder {
+	b.opts.Objectives = objectives
+	return b
+}
+
+// ObjectiveSets sets [SummaryVecOpts.ObjectiveSets]
+func (b *SummaryVecBuilder) ObjectiveSets(sets ...map[float64]float64) *SummaryVecBuilder {
+	b.opts.ObjectiveSets = sets
+	return b
+}
+
+// Build returns the opts
+func (b *SummaryVecBuilder) Build() SummaryVecOpts {
+	return b.opts
+}
+
+//--------------------------------------------------------------------------------
+// Composite Builders
+//
+// The setters of a component are prefixed with its role, e.g. HitHelp sets
+// the help of the hits counter of a cache. The Labels of a vec composite are
+// set on every component.
+//--------------------------------------------------------------------------------
+
+// TimerBuilder builds [TimerOpts], see [NewTimer]
+type TimerBuilder struct {
+	compositeBuilder[TimerBuilder]
+	opts TimerOpts
+}
+
+// NewTimer returns a builder of the [TimerOpts] of the timer name
+func NewTimer(name string) *TimerBuilder {
+	b := &TimerBuilder{opts: TimerOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.compositeBuilder = newCompositeBuilder(b, &b.opts.MetricInfo, &b.opts.CompositeMetricOpts)
+	return b
+}
+
+// HistogramHelp sets the help of the histogram
+func (b *TimerBuilder) HistogramHelp(help string) *TimerBuilder {
+	b.opts.HistogramOpts.Help = help
+	return b
+}
+
+// Buckets sets the buckets of the histogram, in seconds
+func (b *TimerBuilder) Buckets(buckets ...float64) *TimerBuilder {
+	b.opts.HistogramOpts.Buckets = buckets
+	return b
+}
+
+// BucketPreset sets the bucket preset of the histogram
+func (b *TimerBuilder) BucketPreset(preset string) *TimerBuilder {
+	b.opts.HistogramOpts.BucketPreset = preset
+	return b
+}
+
+// Clock sets [TimerOpts.Clock]
+func (b *TimerBuilder) Clock(clock Clock) *TimerBuilder {
+	b.opts.Clock = clock
+	return b
+}
+
+// Build returns the opts
+func (b *TimerBuilder) Build() TimerOpts {
+	return b.opts
+}
+
+// TimerVecBuilder builds [TimerVecOpts], see [NewTimerVec]
+type TimerVecBuilder struct {
+	compositeBuilder[TimerVecBuilder]
+	opts TimerVecOpts
+}
+
+// NewTimerVec returns a builder of the [TimerVecOpts] of the timer vec name
+func NewTimerVec(name string) *TimerVecBuilder {
+	b := &TimerVecBuilder{opts: TimerVecOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.compositeBuilder = newCompositeBuilder(b, &b.opts.MetricInfo, &b.opts.CompositeMetricOpts)
+	return b
+}
+
+// Labels sets the label names of the histogram
+func (b *TimerVecBuilder) Labels(labels ...string) *TimerVecBuilder {
+	b.opts.HistogramVecOpts.Labels = labels
+	return b
+}
+
+// HistogramHelp sets the help of the histogram
+func (b *TimerVecBuilder) HistogramHelp(help string) *TimerVecBuilder {
+	b.opts.HistogramVecOpts.Help = help
+	return b
+}
+
+// Buckets sets the buckets of the histogram, in seconds
+func (b *TimerVecBuilder) Buckets(buckets ...float64) *TimerVecBuilder {
+	b.opts.HistogramVecOpts.Buckets = buckets
+	return b
+}
+
+// BucketPreset sets the bucket preset of the histogram
+func (b *TimerVecBuilder) BucketPreset(preset string) *TimerVecBuilder {
+	b.opts.HistogramVecOpts.BucketPreset = preset
+	return b
+}
+
+// Clock sets [TimerVecOpts.Clock]
+func (b *TimerVecBuilder) Clock(clock Clock) *TimerVecBuilder {
+	b.opts.Clock = clock
+	return b
+}
+
+// Build returns the opts
+func (b *TimerVecBuilder) Build() TimerVecOpts {
+	return b.opts
+}
+
+// CacheBuilder builds [CacheOpts], see [NewCache]
+type CacheBuilder struct {
+	compositeBuilder[CacheBuilder]
+	opts CacheOpts
+}
+
+// NewCache returns a builder of the [CacheOpts] of the cache name
+func NewCache(name string) *CacheBuilder {
+	b := &CacheBuilder{opts: CacheOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.compositeBuilder = newCompositeBuilder(b, &b.opts.MetricInfo, &b.opts.CompositeMetricOpts)
+	return b
+}
+
+// HitHelp sets the help of the hits counter
+func (b *CacheBuilder) HitHelp(help string) *CacheBuilder {
+	b.opts.HitOpts.Help = help
+	return b
+}
+
+// MissHelp sets the help of the misses counter
+func (b *CacheBuilder) MissHelp(help string) *CacheBuilder {
+	b.opts.MissOpts.Help = help
+	return b
+}
+
+// SizeHelp sets the help of the size gauge
+func (b *CacheBuilder) SizeHelp(help string) *CacheBuilder {
+	b.opts.SizeOpts.Help = help
+	return b
+}
+
+// Build returns the opts
+func (b *CacheBuilder) Build() CacheOpts {
+	return b.opts
+}
+
+// CacheVecBuilder builds [CacheVecOpts], see [NewCacheVec]
+type CacheVecBuilder struct {
+	compositeBuilder[CacheVecBuilder]
+	opts CacheVecOpts
+}
+
+// NewCacheVec returns a builder of the [CacheVecOpts] of the cache vec name
+func NewCacheVec(name string) *CacheVecBuilder {
+	b := &CacheVecBuilder{opts: CacheVecOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.compositeBuilder = newCompositeBuilder(b, &b.opts.MetricInfo, &b.opts.CompositeMetricOpts)
+	return b
+}
+
+// Labels sets the label names of every component
+func (b *CacheVecBuilder) Labels(labels ...string) *CacheVecBuilder {
+	b.opts.HitVecOpts.Labels = labels
+	b.opts.MissVecOpts.Labels = labels
+	b.opts.SizeVecOpts.Labels = labels
+	return b
+}
+
+// HitHelp sets the help of the hits counter
+func (b *CacheVecBuilder) HitHelp(help string) *CacheVecBuilder {
+	b.opts.HitVecOpts.Help = help
+	return b
+}
+
+// MissHelp sets the help of the misses counter
+func (b *CacheVecBuilder) MissHelp(help string) *CacheVecBuilder {
+	b.opts.MissVecOpts.Help = help
+	return b
+}
+
+// SizeHelp sets the help of the size gauge
+func (b *CacheVecBuilder) SizeHelp(help string) *CacheVecBuilder {
+	b.opts.SizeVecOpts.Help = help
+	return b
+}
+
+// Build returns the opts
+func (b *CacheVecBuilder) Build() CacheVecOpts {
+	return b.opts
+}
+
+// PoolBuilder builds [PoolOpts], see [NewPool]
+type PoolBuilder struct {
+	compositeBuilder[PoolBuilder]
+	opts PoolOpts
+}
+
+// NewPool returns a builder of the [PoolOpts] of the pool name
+func NewPool(name string) *PoolBuilder {
+	b := &PoolBuilder{opts: PoolOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.compositeBuilder = newCompositeBuilder(b, &b.opts.MetricInfo, &b.opts.CompositeMetricOpts)
+	return b
+}
+
+// ActiveHelp sets the help of the active gauge
+func (b *PoolBuilder) ActiveHelp(help string) *PoolBuilder {
+	b.opts.ActiveOpts.Help = help
+	return b
+}
+
+// IdleHelp sets the help of the idle gauge
+func (b *PoolBuilder) IdleHelp(help string) *PoolBuilder {
+	b.opts.IdleOpts.Help = help
+	return b
+}
+
+// AcquiredHelp sets the help of the acquired counter
+func (b *PoolBuilder) AcquiredHelp(help string) *PoolBuilder {
+	b.opts.AcquiredOpts.Help = help
+	return b
+}
+
+// ReleasedHelp sets the help of the released counter
+func (b *PoolBuilder) ReleasedHelp(help string) *PoolBuilder {
+	b.opts.ReleasedOpts.Help = help
+	return b
+}
+
+// Build returns the opts
+func (b *PoolBuilder) Build() PoolOpts {
+	return b.opts
+}
+
+// PoolVecBuilder builds [PoolVecOpts], see [NewPoolVec]
+type PoolVecBuilder struct {
+	compositeBuilder[PoolVecBuilder]
+	opts PoolVecOpts
+}
+
+// NewPoolVec returns a builder of the [PoolVecOpts] of the pool vec name
+func NewPoolVec(name string) *PoolVecBuilder {
+	b := &PoolVecBuilder{opts: PoolVecOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.compositeBuilder = newCompositeBuilder(b, &b.opts.MetricInfo, &b.opts.CompositeMetricOpts)
+	return b
+}
+
+// Labels sets the label names of every component
+func (b *PoolVecBuilder) Labels(labels ...string) *PoolVecBuilder {
+	b.opts.ActiveVecOpts.Labels = labels
+	b.opts.IdleVecOpts.Labels = labels
+	b.opts.AcquiredVecOpts.Labels = labels
+	b.opts.ReleasedVecOpts.Labels = labels
+	return b
+}
+
+// ActiveHelp sets the help of the active gauge
+func (b *PoolVecBuilder) ActiveHelp(help string) *PoolVecBuilder {
+	b.opts.ActiveVecOpts.Help = help
+	return b
+}
+
+// IdleHelp sets the help of the idle gauge
+func (b *PoolVecBuilder) IdleHelp(help string) *PoolVecBuilder {
+	b.opts.IdleVecOpts.Help = help
+	return b
+}
+
+// AcquiredHelp sets the help of the acquired counter
+func (b *PoolVecBuilder) AcquiredHelp(help string) *PoolVecBuilder {
+	b.opts.AcquiredVecOpts.Help = help
+	return b
+}
+
+// ReleasedHelp sets the help of the released counter
+func (b *PoolVecBuilder) ReleasedHelp(help string) *PoolVecBuilder {
+	b.opts.ReleasedVecOpts.Help = help
+	return b
+}
+
+// Build returns the opts
+func (b *PoolVecBuilder) Build() PoolVecOpts {
+	return b.opts
+}
+
+// CircuitBreakerBuilder builds [CircuitBreakerOpts], see [NewCircuitBreaker]
+type CircuitBreakerBuilder struct {
+	compositeBuilder[CircuitBreakerBuilder]
+	opts CircuitBreakerOpts
+}
+
+// NewCircuitBreaker returns a builder of the [CircuitBreakerOpts] of the
+// circuit breaker name
+func NewCircuitBreaker(name string) *CircuitBreakerBuilder {
+	b := &CircuitBreakerBuilder{opts: CircuitBreakerOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.compositeBuilder = newCompositeBuilder(b, &b.opts.MetricInfo, &b.opts.CompositeMetricOpts)
+	return b
+}
+
+// StateHelp sets the help of the state gauge
+func (b *CircuitBreakerBuilder) StateHelp(help string) *CircuitBreakerBuilder {
+	b.opts.StateOpts.Help = help
+	return b
+}
+
+// SuccessHelp sets the help of the successes counter
+func (b *CircuitBreakerBuilder) SuccessHelp(help string) *CircuitBreakerBuilder {
+	b.opts.SuccessOpts.Help = help
+	return b
+}
+
+// FailureHelp sets the help of the failures counter
+func (b *CircuitBreakerBuilder) FailureHelp(help string) *CircuitBreakerBuilder {
+	b.opts.FailureOpts.Help = help
+	return b
+}
+
+// Build returns the opts
+func (b *CircuitBreakerBuilder) Build() CircuitBreakerOpts {
+	return b.opts
+}
+
+// CircuitBreakerVecBuilder builds [CircuitBreakerVecOpts], see [NewCircuitBreakerVec]
+type CircuitBreakerVecBuilder struct {
+	compositeBuilder[CircuitBreakerVecBuilder]
+	opts CircuitBreakerVecOpts
+}
+
+// NewCircuitBreakerVec returns a builder of the [CircuitBreakerVecOpts] of the
+// circuit breaker vec name
+func NewCircuitBreakerVec(name string) *CircuitBreakerVecBuilder {
+	b := &CircuitBreakerVecBuilder{opts: CircuitBreakerVecOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.compositeBuilder = newCompositeBuilder(b, &b.opts.MetricInfo, &b.opts.CompositeMetricOpts)
+	return b
+}
+
+// Labels sets the label names of every component
+func (b *CircuitBreakerVecBuilder) Labels(labels ...string) *CircuitBreakerVecBuilder {
+	b.opts.StateVecOpts.Labels = labels
+	b.opts.SuccessVecOpts.Labels = labels
+	b.opts.FailureVecOpts.Labels = labels
+	return b
+}
+
+// StateHelp sets the help of the state gauge
+func (b *CircuitBreakerVecBuilder) StateHelp(help string) *CircuitBreakerVecBuilder {
+	b.opts.StateVecOpts.Help = help
+	return b
+}
+
+// SuccessHelp sets the help of the successes counter
+func (b *CircuitBreakerVecBuilder) SuccessHelp(help string) *CircuitBreakerVecBuilder {
+	b.opts.SuccessVecOpts.Help = help
+	return b
+}
+
+// FailureHelp sets the help of the failures counter
+func (b *CircuitBreakerVecBuilder) FailureHelp(help string) *CircuitBreakerVecBuilder {
+	b.opts.FailureVecOpts.Help = help
+	return b
+}
+
+// Build returns the opts
+func (b *CircuitBreakerVecBuilder) Build() CircuitBreakerVecOpts {
+	return b.opts
+}
+
+// QueueBuilder builds [QueueOpts], see [NewQueue]
+type QueueBuilder struct {
+	compositeBuilder[QueueBuilder]
+	opts QueueOpts
+}
+
+// NewQueue returns a builder of the [QueueOpts] of the queue name
+func NewQueue(name string) *QueueBuilder {
+	b := &QueueBuilder{opts: QueueOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.compositeBuilder = newCompositeBuilder(b, &b.opts.MetricInfo, &b.opts.CompositeMetricOpts)
+	return b
+}
+
+// DepthHelp sets the help of the depth gauge
+func (b *QueueBuilder) DepthHelp(help string) *QueueBuilder {
+	b.opts.DepthOpts.Help = help
+	return b
+}
+
+// EnqueuedHelp sets the help of the enqueued counter
+func (b *QueueBuilder) EnqueuedHelp(help string) *QueueBuilder {
+	b.opts.EnqueuedOpts.Help = help
+	return b
+}
+
+// DequeuedHelp sets the help of the dequeued counter
+func (b *QueueBuilder) DequeuedHelp(help string) *QueueBuilder {
+	b.opts.DequeuedOpts.Help = help
+	return b
+}
+
+// WaitTimeHelp sets the help of the wait time histogram
+func (b *QueueBuilder) WaitTimeHelp(help string) *QueueBuilder {
+	b.opts.WaitTimeOpts.Help = help
+	return b
+}
+
+// WaitTimeBuckets sets the buckets of the wait time histogram, in seconds
+func (b *QueueBuilder) WaitTimeBuckets(buckets ...float64) *QueueBuilder {
+	b.opts.WaitTimeOpts.Buckets = buckets
+	return b
+}
+
+// Build returns the opts
+func (b *QueueBuilder) Build() QueueOpts {
+	return b.opts
+}
+
+// QueueVecBuilder builds [QueueVecOpts], see [NewQueueVec]
+type QueueVecBuilder struct {
+	compositeBuilder[QueueVecBuilder]
+	opts QueueVecOpts
+}
+
+// NewQueueVec returns a builder of the [QueueVecOpts] of the queue vec name
+func NewQueueVec(name string) *QueueVecBuilder {
+	b := &QueueVecBuilder{opts: QueueVecOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.compositeBuilder = newCompositeBuilder(b, &b.opts.MetricInfo, &b.opts.CompositeMetricOpts)
+	return b
+}
+
+// Labels sets the label names of every component
+func (b *QueueVecBuilder) Labels(labels ...string) *QueueVecBuilder {
+	b.opts.DepthVecOpts.Labels = labels
+	b.opts.EnqueuedVecOpts.Labels = labels
+	b.opts.DequeuedVecOpts.Labels = labels
+	b.opts.WaitTimeVecOpts.Labels = labels
+	return b
+}
+
+// DepthHelp sets the help of the depth gauge
+func (b *QueueVecBuilder) DepthHelp(help string) *QueueVecBuilder {
+	b.opts.DepthVecOpts.Help = help
+	return b
+}
+
+// EnqueuedHelp sets the help of the enqueued counter
+func (b *QueueVecBuilder) EnqueuedHelp(help string) *QueueVecBuilder {
+	b.opts.EnqueuedVecOpts.Help = help
+	return b
+}
+
+// DequeuedHelp sets the help of the dequeued counter
+func (b *QueueVecBuilder) DequeuedHelp(help string) *QueueVecBuilder {
+	b.opts.DequeuedVecOpts.Help = help
+	return b
+}
+
+// WaitTimeHelp sets the help of the wait time histogram
+func (b *QueueVecBuilder) WaitTimeHelp(help string) *QueueVecBuilder {
+	b.opts.WaitTimeVecOpts.Help = help
+	return b
+}
+
+// WaitTimeBuckets sets the buckets of the wait time histogram, in seconds
+func (b *QueueVecBuilder) WaitTimeBuckets(buckets ...float64) *QueueVecBuilder {
+	b.opts.WaitTimeVecOpts.Buckets = buckets
+	return b
+}
+
+// Build returns the opts
+func (b *QueueVecBuilder) Build() QueueVecOpts {
+	return b.opts
+}
+
+// DistributionGaugeBuilder builds [DistributionGaugeOpts], see [NewDistributionGauge]
+type DistributionGaugeBuilder struct {
+	compositeBuilder[DistributionGaugeBuilder]
+	opts DistributionGaugeOpts
+}
+
+// NewDistributionGauge returns a builder of the [DistributionGaugeOpts] of the
+// distribution gauge name
+func NewDistributionGauge(name string) *DistributionGaugeBuilder {
+	b := &DistributionGaugeBuilder{opts: DistributionGaugeOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.compositeBuilder = newCompositeBuilder(b, &b.opts.MetricInfo, &b.opts.CompositeMetricOpts)
+	return b
+}
+
+// GaugeHelp sets the help of the gauge
+func (b *DistributionGaugeBuilder) GaugeHelp(help string) *DistributionGaugeBuilder {
+	b.opts.GaugeOpts.Help = help
+	return b
+}
+
+// HistogramHelp sets the help of the histogram
+func (b *DistributionGaugeBuilder) HistogramHelp(help string) *DistributionGaugeBuilder {
+	b.opts.HistogramOpts.Help = help
+	return b
+}
+
+// Buckets sets the buckets of the histogram
+func (b *DistributionGaugeBuilder) Buckets(buckets ...float64) *DistributionGaugeBuilder {
+	b.opts.HistogramOpts.Buckets = buckets
+	return b
+}
+
+// BucketPreset sets the bucket preset of the histogram
+func (b *DistributionGaugeBuilder) BucketPreset(preset string) *DistributionGaugeBuilder {
+	b.opts.HistogramOpts.BucketPreset = preset
+	return b
+}
+
+// Build returns the opts
+func (b *DistributionGaugeBuilder) Build() DistributionGaugeOpts {
+	return b.opts
+}
+
+// MultiResHistogramBuilder builds [MultiResHistogramOpts], see [NewMultiResHistogram]
+type MultiResHistogramBuilder struct {
+	compositeBuilder[MultiResHistogramBuilder]
+	opts MultiResHistogramOpts
+}
+
+// NewMultiResHistogram returns a builder of the [MultiResHistogramOpts] of the
+// multi-resolution histogram name
+func NewMultiResHistogram(name string) *MultiResHistogramBuilder {
+	b := &MultiResHistogramBuilder{opts: MultiResHistogramOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.compositeBuilder = newCompositeBuilder(b, &b.opts.MetricInfo, &b.opts.CompositeMetricOpts)
+	return b
+}
+
+// Resolution appends a histogram with the buckets to the resolutions, named
+// by default, see [MultiResHistogramOpts.Resolutions]
+func (b *MultiResHistogramBuilder) Resolution(buckets ...float64) *MultiResHistogramBuilder {
+	b.opts.Resolutions = append(b.opts.Resolutions, HistogramOpts{Buckets: buckets})
+	return b
+}
+
+// Build returns the opts
+func (b *MultiResHistogramBuilder) Build() MultiResHistogramOpts {
+	return b.opts
+}
+
+// DependencyBuilder builds [DependencyOpts], see [NewDependency]
+type DependencyBuilder struct {
+	compositeBuilder[DependencyBuilder]
+	opts DependencyOpts
+}
+
+// NewDependency returns a builder of the [DependencyOpts] of the dependency name
+func NewDependency(name string) *DependencyBuilder {
+	b := &DependencyBuilder{opts: DependencyOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.compositeBuilder = newCompositeBuilder(b, &b.opts.MetricInfo, &b.opts.CompositeMetricOpts)
+	return b
+}
+
+// CallsHelp sets the help of the calls counter
+func (b *DependencyBuilder) CallsHelp(help string) *DependencyBuilder {
+	b.opts.CallsVecOpts.Help = help
+	return b
+}
+
+// LatencyHelp sets the help of the latency histogram
+func (b *DependencyBuilder) LatencyHelp(help string) *DependencyBuilder {
+	b.opts.LatencyVecOpts.Help = help
+	return b
+}
+
+// LatencyBuckets sets the buckets of the latency histogram, in seconds
+func (b *DependencyBuilder) LatencyBuckets(buckets ...float64) *DependencyBuilder {
+	b.opts.LatencyVecOpts.Buckets = buckets
+	return b
+}
+
+// InFlightHelp sets the help of the in-flight gauge
+func (b *DependencyBuilder) InFlightHelp(help string) *DependencyBuilder {
+	b.opts.InFlightVecOpts.Help = help
+	return b
+}
+
+// Clock sets [DependencyOpts.Clock]
+func (b *DependencyBuilder) Clock(clock Clock) *DependencyBuilder {
+	b.opts.Clock = clock
+	return b
+}
+
+// Build returns the opts
+func (b *DependencyBuilder) Build() DependencyOpts {
+	return b.opts
+}
+
+// ThroughputBuilder builds [ThroughputOpts], see [NewThroughput]
+type ThroughputBuilder struct {
+	compositeBuilder[ThroughputBuilder]
+	opts ThroughputOpts
+}
+
+// NewThroughput returns a builder of the [ThroughputOpts] of the throughput name
+func NewThroughput(name string) *ThroughputBuilder {
+	b := &ThroughputBuilder{opts: ThroughputOpts{MetricInfo: MetricInfo{Name: name}}}
+	b.compositeBuilder = newCompositeBuilder(b, &b.opts.MetricInfo, &b.opts.CompositeMetricOpts)
+	return b
+}
+
+// CountHelp sets the help of the count counter
+func (b *ThroughputBuilder) CountHelp(help string) *ThroughputBuilder {
+	b.opts.CountOpts.Help = help
+	return b
+}
+
+// RateHelp sets the help of the rate gauge
+func (b *ThroughputBuilder) RateHelp(help string) *ThroughputBuilder {
+	b.opts.RateOpts.Help = help
+	return b
+}
+
+// Window sets [ThroughputOpts.Window]
+func (b *ThroughputBuilder) Window(window time.Duration) *ThroughputBuilder {
+	b.opts.Window = window
+	return b
+}
+
+// AutoStart sets [ThroughputOpts.AutoStart]
+func (b *ThroughputBuilder) AutoStart() *ThroughputBuilder {
+	b.opts.AutoStart = true
+	return b
+}
+
+// Clock sets [ThroughputOpts.Clock]
+func (b *ThroughputBuilder) Clock(clock Clock) *ThroughputBuilder {
+	b.opts.Clock = clock
+	return b
+}
+
+// Build returns the opts
+func (b *ThroughputBuilder) Build() ThroughputOpts {
+	return b.opts
+}
@@ -0,0 +1,162 @@
+package umami
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBuildersMatchLiterals(t *testing.T) {
+	slo := &SLOSpec{Target: 0.99, Threshold: 0.3}
+	clock := &fakeClock{now: time.Unix(0, 0)}
+
+	tests := []struct {
+		name    string
+		built   any
+		literal any
+	}{
+		{
+			"Counter",
+			NewCounter("requests_total").WithHelp("Requests").WithTags("http").WithNoPrefix().WithAsync().ResetOnScrape().Build(),
+			CounterOpts{
+				BasicMetricOpts: BasicMetricOpts{NoPrefix: true, Async: true},
+				MetricInfo:      MetricInfo{Name: "requests_total", Help: "Requests", Tags: []string{"http"}},
+				ResetOnScrape:   true,
+			},
+		},
+		{
+			"CounterVec",
+			NewCounterVec("requests_total").WithNamespace("shop").WithSubsystem("api").Labels("route").PreInitLabels(VecLabels{"route": "/"}).MaxCardinality(100).Build(),
+			CounterVecOpts{
+				MetricInfo:     MetricInfo{Name: "requests_total", Namespace: "shop", Subsystem: "api"},
+				Labels:         []string{"route"},
+				PreInitLabels:  []VecLabels{{"route": "/"}},
+				MaxCardinality: 100,
+			},
+		},
+		{
+			"Gauge",
+			NewGauge("hit_ratio").WithUnit("ratio").WithMask(MaskCustom).Bounds(RatioBounds(true)).Build(),
+			GaugeOpts{
+				MetricInfo: MetricInfo{Name: "hit_ratio", Unit: "ratio", Mask: MaskCustom},
+				Bounds:     RatioBounds(true),
+			},
+		},
+		{
+			"GaugeVec",
+			NewGaugeVec("temperature").WithConstLabels(map[string]string{"site": "a"}).Labels("room").Build(),
+			GaugeVecOpts{
+				MetricInfo: MetricInfo{Name: "temperature", ConstLabels: map[string]string{"site": "a"}},
+				Labels:     []string{"room"},
+			},
+		},
+		{
+			"Histogram",
+			NewHistogram("latency_seconds").WithSLO(slo).Buckets(0.1, 1).NativeHistogram(1.1, 100).Build(),
+			HistogramOpts{
+				MetricInfo:                     MetricInfo{Name: "latency_seconds", SLO: slo},
+				Buckets:                        []float64{0.1, 1},
+				NativeHistogramBucketFactor:    1.1,
+				NativeHistogramMaxBucketNumber: 100,
+			},
+		},
+		{
+			"HistogramVec",
+			NewHistogramVec("latency_seconds").Labels("route").BucketPreset(BucketPresetLatency).Build(),
+			HistogramVecOpts{
+				MetricInfo:   MetricInfo{Name: "latency_seconds"},
+				Labels:       []string{"route"},
+				BucketPreset: BucketPresetLatency,
+			},
+		},
+		{
+			"SummaryVec",
+			NewSummaryVec("size_bytes").Labels("route").Objectives(map[float64]float64{0.5: 0.05}).Build(),
+			SummaryVecOpts{
+				MetricInfo: MetricInfo{Name: "size_bytes"},
+				Labels:     []string{"route"},
+				Objectives: map[float64]float64{0.5: 0.05},
+			},
+		},
+		{
+			"TimerVec",
+			NewTimerVec("request").Labels("route").Buckets(0.1, 1).Clock(clock).Build(),
+			TimerVecOpts{
+				MetricInfo:       MetricInfo{Name: "request"},
+				HistogramVecOpts: HistogramVecOpts{Labels: []string{"route"}, Buckets: []float64{0.1, 1}},
+				Clock:            clock,
+			},
+		},
+		{
+			"Cache",
+			NewCache("sessions").WithHelp("Sessions").WithNoPrefix().HitHelp("Hits").MissHelp("Misses").SizeHelp("Size").Build(),
+			CacheOpts{
+				CompositeMetricOpts: CompositeMetricOpts{NoPrefix: true},
+				MetricInfo:          MetricInfo{Name: "sessions", Help: "Sessions"},
+				HitOpts:             CounterOpts{MetricInfo: MetricInfo{Help: "Hits"}},
+				MissOpts:            CounterOpts{MetricInfo: MetricInfo{Help: "Misses"}},
+				SizeOpts:            GaugeOpts{MetricInfo: MetricInfo{Help: "Size"}},
+			},
+		},
+		{
+			"CacheVec",
+			NewCacheVec("sessions").Labels("region").HitHelp("Hits").Build(),
+			CacheVecOpts{
+				MetricInfo:  MetricInfo{Name: "sessions"},
+				HitVecOpts:  CounterVecOpts{MetricInfo: MetricInfo{Help: "Hits"}, Labels: []string{"region"}},
+				MissVecOpts: CounterVecOpts{Labels: []string{"region"}},
+				SizeVecOpts: GaugeVecOpts{Labels: []string{"region"}},
+			},
+		},
+		{
+			"QueueVec",
+			NewQueueVec("jobs").Labels("queue").WaitTimeBuckets(1, 10).DepthHelp("Depth").Build(),
+			QueueVecOpts{
+				MetricInfo:      MetricInfo{Name: "jobs"},
+				DepthVecOpts:    GaugeVecOpts{MetricInfo: MetricInfo{Help: "Depth"}, Labels: []string{"queue"}},
+				EnqueuedVecOpts: CounterVecOpts{Labels: []string{"queue"}},
+				DequeuedVecOpts: CounterVecOpts{Labels: []string{"queue"}},
+				WaitTimeVecOpts: HistogramVecOpts{Labels: []string{"queue"}, Buckets: []float64{1, 10}},
+			},
+		},
+		{
+			"MultiResHistogram",
+			NewMultiResHistogram("latency").Resolution(1, 10).Resolution(0.1, 0.5, 1).Build(),
+			MultiResHistogramOpts{
+				MetricInfo:  MetricInfo{Name: "latency"},
+				Resolutions: []HistogramOpts{{Buckets: []float64{1, 10}}, {Buckets: []float64{0.1, 0.5, 1}}},
+			},
+		},
+		{
+			"Throughput",
+			NewThroughput("orders").RateHelp("Orders per second").Window(time.Minute).AutoStart().Build(),
+			ThroughputOpts{
+				MetricInfo: MetricInfo{Name: "orders"},
+				RateOpts:   GaugeOpts{MetricInfo: MetricInfo{Help: "Orders per second"}},
+				Window:     time.Minute,
+				AutoStart:  true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.built, tt.literal) {
+				t.Errorf("Expected the builder to build %+v, got %+v", tt.literal, tt.built)
+			}
+		})
+	}
+}
+
+func TestBuilderOptsCreateMetric(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+	ctx := group.Context()
+
+	cache := group.Cache(NewCache("sessions").WithHelp("Sessions").HitHelp("Session hits").Build(), LevelDebug)
+	_ = cache.Hit(ctx)
+
+	hits := cache.Components()[0]
+	if hits.Name() != "test_sessions_hit" || hits.Help() != "Session hits" {
+		t.Errorf("Expected the hits counter to be named and described by the builder, got %q: %q", hits.Name(), hits.Help())
+	}
+}
@@ -0,0 +1,381 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: cardinality.go
+//
+// This file contains the adapters of label-vectorized metrics created with a
+// MaxCardinality (e.g. [CounterVecOpts.MaxCardinality]), which track the
+// series of the metric and drop the operations that would create a series
+// beyond the limit, so that an unbounded label (e.g. a user id) can't create
+// unbounded series on the backend.
+//--------------------------------------------------------------------------------
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DroppedSeriesMetricName is the name, prefixed like the other metrics of the
+// group, of the counter vec of the operations dropped because they would have
+// created a series beyond the MaxCardinality of a vec of the group, see
+// [CounterVecOpts.MaxCardinality]. It is labeled by the name of the vec.
+//
+// It is created on the backend of the group on the first drop. The name is
+// reserved for the registry once the group creates a vec with a
+// MaxCardinality, so groups can't create a metric of the same name.
+const DroppedSeriesMetricName = "dropped_series_total"
+
+// labelSeparator separates the label values of a series signature. It is not
+// valid UTF-8, so it doesn't appear in valid label values.
+const labelSeparator = "\xff"
+
+// reserveDroppedSeries reserves the name of the [DroppedSeriesMetricName]
+// counter vec of the group if maxCardinality limits a vec, panicking with
+// [ErrMetricNameTaken] if the group already uses it
+func (g *group) reserveDroppedSeries(maxCardinality int) {
+	if maxCardinality > 0 && g.names != nil {
+		g.names.claim(g.backendName(DroppedSeriesMetricName, false), reservedOwner)
+	}
+}
+
+// droppedSeries counts an operation of the named vec dropped by its limit in
+// the [DroppedSeriesMetricName] counter vec, created on the first drop. It is
+// called outside of g.mu.
+func (g *group) droppedSeries(name string) {
+	g.droppedOnce.Do(func() {
+		g.dropped = g.backend.CounterVec(CounterVecOpts{
+			MetricInfo: MetricInfo{
+				Name: g.backendName(DroppedSeriesMetricName, false),
+				Help: "Number of metric operations dropped by the cardinality limit of their metric",
+			},
+			Labels: []string{"metric"},
+		})
+	})
+	_ = g.dropped.Inc(VecLabels{"metric": name})
+}
+
+// cardinalityLimiter tracks the series of a vec by the signature of their
+// labels, admitting at most max series
+type cardinalityLimiter struct {
+	name   string   // Qualified backend name of the vec
+	max    int      // Maximum number of series
+	labels []string // Declared label names, ordering the values of signatures
+	onDrop func()   // Called for each dropped operation
+
+	mu     sync.Mutex
+	series map[string]struct{} // Signatures of the known series
+}
+
+// newCardinalityLimiter returns a limiter of the series of the named vec, or
+// nil if max is not positive (i.e. unlimited)
+func (g *group) newCardinalityLimiter(name string, max int, labels []string) *cardinalityLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &cardinalityLimiter{
+		name:   name,
+		max:    max,
+		labels: labels,
+		onDrop: func() { g.droppedSeries(name) },
+		series: make(map[string]struct{}),
+	}
+}
+
+// signature returns the signature of the series of labels, and false if they
+// don't match the declared label names, leaving them to the backend to reject
+func (l *cardinalityLimiter) signature(labels VecLabels) (string, bool) {
+	if len(labels) != len(l.labels) {
+		return "", false
+	}
+	values := make([]string, len(l.labels))
+	for i, name := range l.labels {
+		value, ok := labels[name]
+		if !ok {
+			return "", false
+		}
+		values[i] = value
+	}
+	return strings.Join(values, labelSeparator), true
+}
+
+// admit calls op on the series of labels, tracking it if it is new and op
+// succeeds. If it is new and the limit is reached, op is dropped and an error
+// wrapping [ErrCardinalityExceeded] is returned instead.
+func (l *cardinalityLimiter) admit(labels VecLabels, op func() error) error {
+	key, ok := l.signature(labels)
+	if !ok {
+		return op()
+	}
+	return l.admitKey(key, op)
+}
+
+// admitKey is [cardinalityLimiter.admit] for the signature of a series
+func (l *cardinalityLimiter) admitKey(key string, op func() error) error {
+	l.mu.Lock()
+	if _, known := l.series[key]; known {
+		l.mu.Unlock()
+		return op()
+	}
+	if len(l.series) >= l.max {
+		l.mu.Unlock()
+		l.onDrop()
+		return fmt.Errorf("%w: metric %q has %d series", ErrCardinalityExceeded, l.name, l.max)
+	}
+
+	// Held while op runs on the new series, so that concurrent operations on
+	// new series can't exceed the limit before they are tracked
+	defer l.mu.Unlock()
+	if err := op(); err != nil {
+		return err
+	}
+	l.series[key] = struct{}{}
+	return nil
+}
+
+// forget stops tracking the series of labels, freeing its slot
+func (l *cardinalityLimiter) forget(labels VecLabels) {
+	if key, ok := l.signature(labels); ok {
+		l.mu.Lock()
+		delete(l.series, key)
+		l.mu.Unlock()
+	}
+}
+
+// reset stops tracking every series
+func (l *cardinalityLimiter) reset() {
+	l.mu.Lock()
+	clear(l.series)
+	l.mu.Unlock()
+}
+
+//--------------------------------------------------------------------------------
+// Limited Adapter Constructors
+//
+// Each returns the adapter as is, unless the vec has a MaxCardinality.
+//--------------------------------------------------------------------------------
+
+func (g *group) limitCounterVec(opts CounterVecOpts, adapter CounterVecAdapter) CounterVecAdapter {
+	limiter := g.newCardinalityLimiter(opts.QualifiedName(), opts.MaxCardinality, opts.Labels)
+	if limiter == nil {
+		return adapter
+	}
+	return &limitedCounterVecAdapter{limiter: limiter, internal: adapter}
+}
+
+func (g *group) limitGaugeVec(opts GaugeVecOpts, adapter GaugeVecAdapter) GaugeVecAdapter {
+	limiter := g.newCardinalityLimiter(opts.QualifiedName(), opts.MaxCardinality, opts.Labels)
+	if limiter == nil {
+		return adapter
+	}
+	return &limitedGaugeVecAdapter{limiter: limiter, internal: adapter}
+}
+
+func (g *group) limitHistogramVec(opts HistogramVecOpts, adapter HistogramVecAdapter) HistogramVecAdapter {
+	limiter := g.newCardinalityLimiter(opts.QualifiedName(), opts.MaxCardinality, opts.Labels)
+	if limiter == nil {
+		return adapter
+	}
+	return &limitedHistogramVecAdapter{limiter: limiter, internal: adapter}
+}
+
+func (g *group) limitSummaryVec(opts SummaryVecOpts, adapter SummaryVecAdapter) SummaryVecAdapter {
+	limiter := g.newCardinalityLimiter(opts.QualifiedName(), opts.MaxCardinality, opts.Labels)
+	if limiter == nil {
+		return adapter
+	}
+	return &limitedSummaryVecAdapter{limiter: limiter, internal: adapter}
+}
+
+//--------------------------------------------------------------------------------
+// Limited Adapters
+//
+// Writes and series initialization go through the limiter, while reads
+// (e.g. quantiles) don't create series and are forwarded as is.
+//--------------------------------------------------------------------------------
+
+type limitedCounterVecAdapter struct {
+	limiter  *cardinalityLimiter
+	internal CounterVecAdapter
+}
+
+func (a *limitedCounterVecAdapter) Inc(labels VecLabels) error {
+	return a.limiter.admit(labels, func() error { return a.internal.Inc(labels) })
+}
+
+func (a *limitedCounterVecAdapter) Add(value float64, labels VecLabels) error {
+	return a.limiter.admit(labels, func() error { return a.internal.Add(value, labels) })
+}
+
+func (a *limitedCounterVecAdapter) Series() ([]Sample, error) {
+	return adapterSeries(a.internal)
+}
+
+func (a *limitedCounterVecAdapter) InitLabels(labels VecLabels) error {
+	return a.limiter.admit(labels, func() error {
+		return initVecSeries(a.internal, labels, func(labels VecLabels) error { return a.internal.Add(0, labels) })
+	})
+}
+
+// WithLabelValues binds the series of values if it is known or under the
+// limit. The bound adapter is admitted again on each operation, as the series
+// may have been deleted since.
+func (a *limitedCounterVecAdapter) WithLabelValues(values ...string) (CounterAdapter, error) {
+	if len(values) != len(a.limiter.labels) {
+		return adapterLabelValues(a.internal, values)
+	}
+
+	key := strings.Join(values, labelSeparator)
+	var internal CounterAdapter
+	err := a.limiter.admitKey(key, func() (err error) {
+		internal, err = adapterLabelValues(a.internal, values)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &limitedCounterAdapter{limiter: a.limiter, key: key, internal: internal}, nil
+}
+
+func (a *limitedCounterVecAdapter) Delete(labels VecLabels) bool {
+	a.limiter.forget(labels)
+	return adapterDelete(a.internal, labels)
+}
+
+func (a *limitedCounterVecAdapter) Reset() {
+	a.limiter.reset()
+	adapterReset(a.internal)
+}
+
+// limitedCounterAdapter is the adapter of a series bound by
+// [limitedCounterVecAdapter.WithLabelValues]
+type limitedCounterAdapter struct {
+	limiter  *cardinalityLimiter
+	key      string // Signature of the series
+	internal CounterAdapter
+}
+
+func (a *limitedCounterAdapter) Inc() error {
+	return a.limiter.admitKey(a.key, a.internal.Inc)
+}
+
+func (a *limitedCounterAdapter) Add(value float64) error {
+	return a.limiter.admitKey(a.key, func() error { return a.internal.Add(value) })
+}
+
+func (a *limitedCounterAdapter) AddWithExemplar(value float64, exemplar VecLabels) error {
+	return a.limiter.admitKey(a.key, func() error { return addWithExemplar(a.internal, value, exemplar) })
+}
+
+func (a *limitedCounterAdapter) Value() (float64, error) {
+	return adapterValue(a.internal)
+}
+
+type limitedGaugeVecAdapter struct {
+	limiter  *cardinalityLimiter
+	internal GaugeVecAdapter
+}
+
+func (a *limitedGaugeVecAdapter) Set(value float64, labels VecLabels) error {
+	return a.limiter.admit(labels, func() error { return a.internal.Set(value, labels) })
+}
+
+func (a *limitedGaugeVecAdapter) Inc(labels VecLabels) error {
+	return a.limiter.admit(labels, func() error { return a.internal.Inc(labels) })
+}
+
+func (a *limitedGaugeVecAdapter) Dec(labels VecLabels) error {
+	return a.limiter.admit(labels, func() error { return a.internal.Dec(labels) })
+}
+
+func (a *limitedGaugeVecAdapter) Add(value float64, labels VecLabels) error {
+	return a.limiter.admit(labels, func() error { return a.internal.Add(value, labels) })
+}
+
+func (a *limitedGaugeVecAdapter) InitLabels(labels VecLabels) error {
+	return a.limiter.admit(labels, func() error {
+		return initVecSeries(a.internal, labels, func(labels VecLabels) error { return a.internal.Add(0, labels) })
+	})
+}
+
+func (a *limitedGaugeVecAdapter) Delete(labels VecLabels) bool {
+	a.limiter.forget(labels)
+	return adapterDelete(a.internal, labels)
+}
+
+func (a *limitedGaugeVecAdapter) Reset() {
+	a.limiter.reset()
+	adapterReset(a.internal)
+}
+
+type limitedHistogramVecAdapter struct {
+	limiter  *cardinalityLimiter
+	internal HistogramVecAdapter
+}
+
+func (a *limitedHistogramVecAdapter) Observe(value float64, labels VecLabels) error {
+	return a.limiter.admit(labels, func() error { return a.internal.Observe(value, labels) })
+}
+
+func (a *limitedHistogramVecAdapter) InitLabels(labels VecLabels) error {
+	return a.limiter.admit(labels, func() error { return initVecSeries(a.internal, labels, nil) })
+}
+
+func (a *limitedHistogramVecAdapter) Delete(labels VecLabels) bool {
+	a.limiter.forget(labels)
+	return adapterDelete(a.internal, labels)
+}
+
+func (a *limitedHistogramVecAdapter) Reset() {
+	a.limiter.reset()
+	adapterReset(a.internal)
+}
+
+type limitedSummaryVecAdapter struct {
+	limiter  *cardinalityLimiter
+	internal SummaryVecAdapter
+}
+
+func (a *limitedSummaryVecAdapter) Observe(value float64, labels VecLabels) error {
+	return a.limiter.admit(labels, func() error { return a.internal.Observe(value, labels) })
+}
+
+func (a *limitedSummaryVecAdapter) Quantile(q float64, labels VecLabels) (float64, error) {
+	return a.internal.Quantile(q, labels)
+}
+
+func (a *limitedSummaryVecAdapter) InitLabels(labels VecLabels) error {
+	return a.limiter.admit(labels, func() error { return initVecSeries(a.internal, labels, nil) })
+}
+
+func (a *limitedSummaryVecAdapter) Delete(labels VecLabels) bool {
+	a.limiter.forget(labels)
+	return adapterDelete(a.internal, labels)
+}
+
+func (a *limitedSummaryVecAdapter) Reset() {
+	a.limiter.reset()
+	adapterReset(a.internal)
+}
+
+var (
+	__ctc_limitedCounterVecAdapter   CounterVecAdapter   = (*limitedCounterVecAdapter)(nil)
+	__ctc_limitedCounterAdapter      CounterAdapter      = (*limitedCounterAdapter)(nil)
+	__ctc_limitedGaugeVecAdapter     GaugeVecAdapter     = (*limitedGaugeVecAdapter)(nil)
+	__ctc_limitedHistogramVecAdapter HistogramVecAdapter = (*limitedHistogramVecAdapter)(nil)
+	__ctc_limitedSummaryVecAdapter   SummaryVecAdapter   = (*limitedSummaryVecAdapter)(nil)
+
+	__ctc_limitedCounterVecSeries   SeriesAdapter          = (*limitedCounterVecAdapter)(nil)
+	__ctc_limitedCounterVecValues   LabelValuesAdapter     = (*limitedCounterVecAdapter)(nil)
+	__ctc_limitedCounterVecDelete   DeleteAdapter          = (*limitedCounterVecAdapter)(nil)
+	__ctc_limitedGaugeVecDelete     DeleteAdapter          = (*limitedGaugeVecAdapter)(nil)
+	__ctc_limitedHistogramVecDelete DeleteAdapter          = (*limitedHistogramVecAdapter)(nil)
+	__ctc_limitedSummaryVecDelete   DeleteAdapter          = (*limitedSummaryVecAdapter)(nil)
+	__ctc_limitedCounterExemplar    ExemplarCounterAdapter = (*limitedCounterAdapter)(nil)
+	__ctc_limitedCounterValue       ValueAdapter           = (*limitedCounterAdapter)(nil)
+
+	__ctc_limitedCounterVecInitAdapter   VecInitAdapter = (*limitedCounterVecAdapter)(nil)
+	__ctc_limitedGaugeVecInitAdapter     VecInitAdapter = (*limitedGaugeVecAdapter)(nil)
+	__ctc_limitedHistogramVecInitAdapter VecInitAdapter = (*limitedHistogramVecAdapter)(nil)
+	__ctc_limitedSummaryVecInitAdapter   VecInitAdapter = (*limitedSummaryVecAdapter)(nil)
+)
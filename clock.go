@@ -0,0 +1,31 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: clock.go
+//
+// This file contains the [Clock] abstraction used by time based metrics,
+// allowing time to be injected for deterministic tests.
+//--------------------------------------------------------------------------------
+
+import "time"
+
+// Clock provides the current time to time based metrics such as [Timer]
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+}
+
+// realClock implements [Clock] using [time.Now]
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// clockOrDefault returns c, or the real clock if c is nil
+func clockOrDefault(c Clock) Clock {
+	if c == nil {
+		return realClock{}
+	}
+	return c
+}
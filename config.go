@@ -10,6 +10,9 @@ import (
 type Config struct {
 	// Global settings
 	GlobalLevel Level `json:"global_level" yaml:"global_level"`
+	// Categories of metrics enabled in every group, see [Mask]. Zero leaves
+	// the mask of the registry as it is.
+	GlobalMask Mask `json:"global_mask,omitempty" yaml:"global_mask,omitempty"`
 
 	// Per-group settings
 	Groups map[string]GroupConfig `json:"groups" yaml:"groups"`
@@ -24,6 +27,9 @@ type GroupConfig struct {
 	Level Level `json:"level" yaml:"level"`
 	// Level options for this group
 	LevelOpts LevelOpts `json:"level_opts" yaml:"level_opts"`
+	// Categories of metrics enabled in this group, see [Mask]. Zero leaves
+	// the mask of the group as it is.
+	Mask Mask `json:"mask,omitempty" yaml:"mask,omitempty"`
 }
 
 // BackendConfig represents backend-specific configuration
@@ -45,16 +51,16 @@ func DefaultConfig() *Config {
 }
 
 // ProductionConfig returns a production-ready configuration
-func ProductionConfig(backend Backend) *Config {
+//
+// Each of the named groups is configured at [LevelImportant], so that when
+// the config is applied, debug metrics of those groups are disabled.
+func ProductionConfig(backend Backend, groups ...string) *Config {
 	config := DefaultConfig()
 	config.GlobalLevel = LevelImportant
 
 	// Disable debug metrics in production
-	for name, group := range config.Groups {
-		if group.Level > LevelImportant {
-			group.Level = LevelImportant
-		}
-		config.Groups[name] = group
+	for _, name := range groups {
+		config.Groups[name] = GroupConfig{Level: LevelImportant}
 	}
 
 	config.Backend.Name = backend.Name()
@@ -63,14 +69,19 @@ func ProductionConfig(backend Backend) *Config {
 }
 
 // DevelopmentConfig returns a development configuration with more verbose metrics
-func DevelopmentConfig(backend Backend) *Config {
+//
+// Each of the named groups is configured at [LevelVerbose], replacing noops,
+// so that when the config is applied, all metrics of those groups are enabled.
+func DevelopmentConfig(backend Backend, groups ...string) *Config {
 	config := DefaultConfig()
 	config.GlobalLevel = LevelVerbose
 
 	// Enable detailed metrics in development
-	for name, group := range config.Groups {
-		group.Level = LevelVerbose
-		config.Groups[name] = group
+	for _, name := range groups {
+		config.Groups[name] = GroupConfig{
+			Level:     LevelVerbose,
+			LevelOpts: LevelOpts{ReplaceNoops: true},
+		}
 	}
 
 	config.Backend.Name = backend.Name()
@@ -110,13 +121,18 @@ func LoadConfigFromEnv() *Config {
 		config.GlobalLevel = ParseLevel(levelStr)
 	}
 
+	// Global mask
+	if maskStr := os.Getenv(EnvMetricsMaskKey); maskStr != "" {
+		config.GlobalMask = ParseMask(maskStr)
+	}
+
 	// Backend type
 	if backendType := os.Getenv(EnvMetricsBackendKey); backendType != "" {
 		config.Backend.Name = backendType
 	}
 
 	// Group-specific overrides
-	// Format: METRICS_GROUP_<NAME>_LEVEL=<level>
+	// Format: METRICS_GROUP_<NAME>_LEVEL=<level> or METRICS_GROUP_<NAME>_MASK=<mask>
 	for _, env := range os.Environ() {
 		if strings.HasPrefix(env, EnvMetricsGroupPrefix) {
 			parts := strings.SplitN(env, "=", 2)
@@ -140,6 +156,8 @@ func LoadConfigFromEnv() *Config {
 			switch setting {
 			case "level":
 				groupConfig.Level = ParseLevel(value)
+			case "mask":
+				groupConfig.Mask = ParseMask(value)
 			}
 
 			config.Groups[groupName] = groupConfig
@@ -150,18 +168,26 @@ func LoadConfigFromEnv() *Config {
 }
 
 // ApplyConfig applies the configuration to a metrics [Registry]
+//
+// The global level is applied with the registry's default [LevelOpts]
+// (see [WithDefaultLevelOpts]).
 func ApplyConfig(manager Registry, config *Config) {
-	globalLevelOpts := LevelOpts{
-		ReplaceNoops: false,
-	}
-
 	// Apply global settings
-	manager.SetGlobalLevel(config.GlobalLevel, globalLevelOpts)
+	manager.SetGlobalLevel(config.GlobalLevel)
+	if config.GlobalMask != MaskNone {
+		manager.SetGlobalMask(config.GlobalMask)
+	}
 
-	// Apply group-specific settings
+	// Apply group-specific settings, skipping groups that don't exist (yet)
 	for name, groupConfig := range config.Groups {
 		group := manager.Group(name)
+		if group == nil {
+			continue
+		}
 		group.SetGroupLevel(groupConfig.Level, groupConfig.LevelOpts)
+		if groupConfig.Mask != MaskNone {
+			group.SetGroupMask(groupConfig.Mask)
+		}
 	}
 }
 
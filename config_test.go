@@ -7,9 +7,71 @@ func TestDefaultConfig(t *testing.T) {
 }
 
 func TestDevelopmentConfig(t *testing.T) {
-	// Test implementation
+	config := DevelopmentConfig(NewMockBackend(), "web", "db")
+
+	if config.GlobalLevel != LevelVerbose {
+		t.Errorf("Expected global level %v, got %v", LevelVerbose, config.GlobalLevel)
+	}
+	if len(config.Groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %d", len(config.Groups))
+	}
+	for _, name := range []string{"web", "db"} {
+		group := config.Groups[name]
+		if group.Level != LevelVerbose || !group.LevelOpts.ReplaceNoops {
+			t.Errorf("Expected group %q at %v replacing noops, got %+v", name, LevelVerbose, group)
+		}
+	}
 }
 
 func TestProductionConfig(t *testing.T) {
-	// Test implementation
+	config := ProductionConfig(NewMockBackend(), "web", "db")
+
+	if config.GlobalLevel != LevelImportant {
+		t.Errorf("Expected global level %v, got %v", LevelImportant, config.GlobalLevel)
+	}
+	if config.Backend.Name != "mock" {
+		t.Errorf("Expected backend %q, got %q", "mock", config.Backend.Name)
+	}
+	for _, name := range []string{"web", "db"} {
+		if got := config.Groups[name].Level; got != LevelImportant {
+			t.Errorf("Expected group %q at %v, got %v", name, LevelImportant, got)
+		}
+	}
+}
+
+func TestApplyProductionConfig(t *testing.T) {
+	reg := NewRegistry(LevelVerbose)
+	web := reg.NewGroup("web", NewMockBackend())
+
+	// "db" is configured but never created, and must be skipped
+	ApplyConfig(reg, ProductionConfig(NewMockBackend(), "web", "db"))
+
+	if !web.Context().Enabled(LevelImportant) || web.Context().Enabled(LevelDebug) {
+		t.Error("Expected web group to be clamped to LevelImportant")
+	}
+}
+
+func TestLoadConfigFromEnvMask(t *testing.T) {
+	t.Setenv(EnvMetricsMaskKey, "all,-histogram")
+	t.Setenv(EnvMetricsGroupPrefix+"WEB_MASK", "counter")
+
+	config := LoadConfigFromEnv()
+	if config.GlobalMask != MaskAll&^MaskHistogram {
+		t.Errorf("Expected global mask %b, got %b", MaskAll&^MaskHistogram, config.GlobalMask)
+	}
+	if got := config.Groups["web"].Mask; got != MaskCounter {
+		t.Errorf("Expected web mask %b, got %b", MaskCounter, got)
+	}
+
+	reg := NewRegistry(LevelVerbose)
+	web := reg.NewGroup("web", NewMockBackend())
+	db := reg.NewGroup("db", NewMockBackend())
+	ApplyConfig(reg, config)
+
+	if !web.Context().EnabledMask(MaskCounter) || web.Context().EnabledMask(MaskGauge) {
+		t.Error("Expected web to enable only counters")
+	}
+	if !db.Context().EnabledMask(MaskGauge) || db.Context().EnabledMask(MaskHistogram) {
+		t.Error("Expected db to enable everything but histograms")
+	}
 }
@@ -4,9 +4,15 @@ package umami
 // File: context.go
 //
 // This file contains the definition and implementation of the [Context] interface
-// for the umami metrics library.
+// for the umami metrics library, and a bridge to store it in a [context.Context].
 //--------------------------------------------------------------------------------
 
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
 //--------------------------------------------------------------------------------
 // Interfaces
 //--------------------------------------------------------------------------------
@@ -22,6 +28,59 @@ type Context interface {
 
 	// WithLevel returns a new context curried with the specified level
 	WithLevel(level Level) Context
+
+	// EnabledMask returns true if metrics of every category of mask should
+	// be processed, see [Mask]
+	EnabledMask(mask Mask) bool
+
+	// WithMask returns a new context enabling only the metrics whose
+	// categories are all in mask, whatever their level. Contexts enable every
+	// category ([MaskAll]) unless a mask is set.
+	//
+	// Like tags and predicates, the mask applies to forced contexts (see
+	// [Context.WithForce]).
+	WithMask(mask Mask) Context
+
+	// Forced returns true if metrics should record regardless of their level,
+	// see [Context.WithForce]
+	Forced() bool
+
+	// WithForce returns a new context that records metrics of any level.
+	//
+	// This is an escape hatch for events that must always be counted, such as
+	// security audit events, whatever the configured verbosity. Use it
+	// sparingly, as forced recordings can't be turned down by level changes.
+	//
+	// It only bypasses level checks: a [LevelDisabled] context is never
+	// forced, metrics of a paused group or registry (at [LevelDisabled]) don't
+	// record whatever the context, tags and predicates still apply, and noop
+	// metrics (created while their level was disabled) have
+	// no backend metric to record into.
+	WithForce() Context
+
+	// Sampled returns false if the context was sampled out, see
+	// [Context.WithSamplingDecision]. Metrics don't record with a sampled
+	// out context.
+	Sampled() bool
+
+	// WithSamplingDecision returns a new context that is sampled in with
+	// probability rate, and sampled out otherwise. The decision is made once,
+	// so that every metric recorded with the context, e.g. during a request,
+	// either records or skips, keeping correlated metrics consistent.
+	//
+	// A rate of 1 or more always samples in, and 0 or less always samples out.
+	// Recorded values are not scaled by the rate. Like tags and predicates,
+	// sampling applies to forced contexts (see [Context.WithForce]).
+	WithSamplingDecision(rate float64) Context
+
+	// Start returns the start time set by [Context.WithStart], and whether
+	// one was set
+	Start() (time.Time, bool)
+
+	// WithStart returns a new context carrying start, e.g. the time a request
+	// entered the process, so that its latency can be recorded on exit from
+	// anywhere the context reaches, see [Timer.RecordFromContextStart].
+	WithStart(start time.Time) Context
 }
 
 //--------------------------------------------------------------------------------
@@ -30,24 +89,138 @@ type Context interface {
 
 // metricsContext implements the [Context] interface
 type metricsContext struct {
-	level Level
+	level      Level
+	mask       Mask // See [metricsContext.WithMask]
+	forced     bool
+	sampledOut bool      // See [metricsContext.WithSamplingDecision]
+	start      time.Time // See [metricsContext.WithStart], zero if unset
 }
 
 // NewContext creates a new [metricsContext] with the given [Level]
+//
+// The level is normalized with [Level.Normalize], so out of range levels
+// (e.g. converted from raw ints) behave like the nearest defined level.
 func NewContext(level Level) Context {
 	return &metricsContext{
-		level: level,
+		level: level.Normalize(),
+		mask:  MaskAll,
 	}
 }
 
+// NewContextDisabled creates a new [Context] in which no metrics are enabled
+func NewContextDisabled() Context {
+	return NewContext(LevelDisabled)
+}
+
+// NewContextAll creates a new [Context] in which metrics of every level are enabled
+func NewContextAll() Context {
+	return NewContext(LevelVerbose)
+}
+
 // Enabled returns true if metrics at this level should be processed
 func (c *metricsContext) Enabled(level Level) bool {
 	return level.Enabled(c.level)
 }
 
-// WithLevel returns a new context with the specified level
+// WithLevel returns a new context with the specified level, normalized
+// like [NewContext]. The new context is masked, forced, sampled and started
+// like this one.
 func (c *metricsContext) WithLevel(level Level) Context {
 	return &metricsContext{
-		level: level,
+		level:      level.Normalize(),
+		mask:       c.mask,
+		forced:     c.forced,
+		sampledOut: c.sampledOut,
+		start:      c.start,
+	}
+}
+
+// EnabledMask returns true if every category of mask is enabled
+func (c *metricsContext) EnabledMask(mask Mask) bool {
+	return mask.Enabled(c.mask)
+}
+
+// WithMask returns a new context like this one, enabling the categories of mask
+func (c *metricsContext) WithMask(mask Mask) Context {
+	return &metricsContext{
+		level:      c.level,
+		mask:       mask,
+		forced:     c.forced,
+		sampledOut: c.sampledOut,
+		start:      c.start,
 	}
 }
+
+// Forced returns true if metrics should record regardless of their level.
+// A [LevelDisabled] context is never forced.
+func (c *metricsContext) Forced() bool {
+	return c.forced && c.level != LevelDisabled
+}
+
+// WithForce returns a new context with the same level that records metrics
+// of any level
+func (c *metricsContext) WithForce() Context {
+	return &metricsContext{
+		level:      c.level,
+		mask:       c.mask,
+		forced:     true,
+		sampledOut: c.sampledOut,
+		start:      c.start,
+	}
+}
+
+// Sampled returns false if the context was sampled out
+func (c *metricsContext) Sampled() bool {
+	return !c.sampledOut
+}
+
+// WithSamplingDecision returns a new context with the same level, sampled
+// in with probability rate
+func (c *metricsContext) WithSamplingDecision(rate float64) Context {
+	return &metricsContext{
+		level:      c.level,
+		mask:       c.mask,
+		forced:     c.forced,
+		sampledOut: rate < 1 && (rate <= 0 || rand.Float64() >= rate),
+		start:      c.start,
+	}
+}
+
+// Start returns the start time of the context, and whether one was set
+func (c *metricsContext) Start() (time.Time, bool) {
+	return c.start, !c.start.IsZero()
+}
+
+// WithStart returns a new context like this one, carrying start
+func (c *metricsContext) WithStart(start time.Time) Context {
+	return &metricsContext{
+		level:      c.level,
+		mask:       c.mask,
+		forced:     c.forced,
+		sampledOut: c.sampledOut,
+		start:      start,
+	}
+}
+
+//--------------------------------------------------------------------------------
+// Go Context Bridge
+//
+// Allows a [Context] to be carried in a [context.Context] through deep call
+// chains, instead of threading two context parameters.
+//--------------------------------------------------------------------------------
+
+// goContextKey is the private key type under which a [Context] is stored
+// in a [context.Context]
+type goContextKey struct{}
+
+// IntoGoContext returns a copy of parent carrying mc, retrievable with [FromGoContext]
+func IntoGoContext(parent context.Context, mc Context) context.Context {
+	return context.WithValue(parent, goContextKey{}, mc)
+}
+
+// FromGoContext returns the [Context] stored in ctx by [IntoGoContext], and
+// whether one was found
+func FromGoContext(ctx context.Context) (Context, bool) {
+	mc, ok := ctx.Value(goContextKey{}).(Context)
+	return mc, ok
+}
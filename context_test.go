@@ -0,0 +1,204 @@
+package umami
+
+import (
+	"context"
+	"testing"
+)
+
+type otherKey struct{}
+
+func TestGoContextRoundTrip(t *testing.T) {
+	mc := NewContext(LevelImportant)
+
+	ctx := IntoGoContext(context.Background(), mc)
+	ctx = context.WithValue(ctx, otherKey{}, "unrelated")
+
+	got, ok := FromGoContext(ctx)
+	if !ok {
+		t.Fatal("Expected a Context to be found")
+	}
+	if got != mc {
+		t.Error("Expected the stored Context to be returned")
+	}
+	if !got.Enabled(LevelImportant) || got.Enabled(LevelDebug) {
+		t.Error("Expected the stored Context to keep its level")
+	}
+}
+
+func TestGoContextMissing(t *testing.T) {
+	if mc, ok := FromGoContext(context.Background()); ok || mc != nil {
+		t.Errorf("Expected no Context, got %v", mc)
+	}
+}
+
+func TestGoContextOverride(t *testing.T) {
+	ctx := IntoGoContext(context.Background(), NewContext(LevelCritical))
+	ctx = IntoGoContext(ctx, NewContext(LevelVerbose))
+
+	got, _ := FromGoContext(ctx)
+	if !got.Enabled(LevelVerbose) {
+		t.Error("Expected the innermost Context to win")
+	}
+}
+
+func TestNewContextNormalizesLevel(t *testing.T) {
+	tests := []struct {
+		name  string
+		level Level
+		want  Level
+	}{
+		{"below disabled", Level(-5), LevelDisabled},
+		{"above verbose", Level(42), LevelVerbose},
+		{"in range", LevelImportant, LevelImportant},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.level.Normalize(); got != tt.want {
+				t.Errorf("Normalize() = %v, want %v", got, tt.want)
+			}
+
+			ctx := NewContext(tt.level)
+			for l := LevelCritical; l <= LevelVerbose; l++ {
+				if got, want := ctx.Enabled(l), l.Enabled(tt.want); got != want {
+					t.Errorf("Enabled(%v) = %v, want %v", l, got, want)
+				}
+			}
+		})
+	}
+
+	// A raw level below LevelDisabled must not enable anything
+	if NewContext(Level(-2)).Enabled(Level(-3)) {
+		t.Error("Expected an out of range context level to be disabled")
+	}
+}
+
+func TestContextConvenienceConstructors(t *testing.T) {
+	disabled, all := NewContextDisabled(), NewContextAll()
+
+	for l := LevelCritical; l <= LevelVerbose; l++ {
+		if disabled.Enabled(l) {
+			t.Errorf("Expected NewContextDisabled to disable %v", l)
+		}
+		if !all.Enabled(l) {
+			t.Errorf("Expected NewContextAll to enable %v", l)
+		}
+	}
+}
+
+func TestContextForcedRecordsBelowLevel(t *testing.T) {
+	group := NewRegistry(LevelVerbose).NewGroup("audit", NewMockBackend())
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "logins"}}, LevelDebug)
+	critical := NewContext(LevelCritical)
+
+	counter.Inc(critical)
+	if got := mockCounterOf(t, counter).GetCount(); got != 0 {
+		t.Fatalf("Expected a debug counter to be disabled by a critical context, got %v", got)
+	}
+
+	forced := critical.WithForce()
+	if !forced.Forced() || critical.Forced() {
+		t.Error("Expected only the context returned by WithForce to be forced")
+	}
+	counter.Inc(forced)
+	counter.Inc(forced.WithLevel(LevelImportant))
+	if got := mockCounterOf(t, counter).GetCount(); got != 2 {
+		t.Errorf("Expected forced contexts to record, got %v", got)
+	}
+}
+
+func TestContextForcedRespectsPause(t *testing.T) {
+	reg := NewRegistry(LevelVerbose)
+	group := reg.NewGroup("audit", NewMockBackend())
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "logins"}}, LevelCritical)
+
+	reg.SetGlobalLevel(LevelDisabled)
+	forced := group.Context().WithForce()
+	if forced.Forced() {
+		t.Error("Expected a disabled context to never be forced")
+	}
+
+	counter.Inc(forced)
+	if got := mockCounterOf(t, counter).GetCount(); got != 0 {
+		t.Errorf("Expected a paused registry to suppress forced recordings, got %v", got)
+	}
+}
+
+func TestContextForcedRespectsPauseOfOtherContexts(t *testing.T) {
+	reg := NewRegistry(LevelVerbose)
+	group := reg.NewGroup("audit", NewMockBackend())
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "logins"}}, LevelCritical)
+
+	// Taken before the pause, and not derived from the group at all
+	before := group.Context()
+	reg.SetGlobalLevel(LevelDisabled)
+
+	counter.Inc(before.WithForce())
+	counter.Inc(NewContext(LevelCritical).WithForce())
+	if got := mockCounterOf(t, counter).GetCount(); got != 0 {
+		t.Errorf("Expected a paused registry to suppress forced recordings, got %v", got)
+	}
+
+	reg.SetGlobalLevel(LevelCritical)
+	counter.Inc(before.WithForce())
+	if got := mockCounterOf(t, counter).GetCount(); got != 1 {
+		t.Errorf("Expected forced recordings once resumed, got %v", got)
+	}
+
+	group.SetGroupLevel(LevelDisabled, LevelOpts{})
+	counter.Inc(before.WithForce())
+	if got := mockCounterOf(t, counter).GetCount(); got != 1 {
+		t.Errorf("Expected a paused group to suppress forced recordings, got %v", got)
+	}
+}
+
+func TestContextSamplingDecisionIsShared(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("web", NewMockBackend())
+	requests := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total"}}, LevelCritical)
+	bytes := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "bytes_total"}}, LevelCritical)
+	cache := group.Cache(CacheOpts{
+		MetricInfo: MetricInfo{Name: "cache"},
+		HitOpts:    CounterOpts{MetricInfo: MetricInfo{Name: "cache_hits"}},
+	}, LevelCritical)
+	hits := cache.Components()[0].(Counter)
+
+	sampled := 0
+	for range 200 {
+		ctx := group.Context().WithSamplingDecision(0.5)
+		if ctx.Sampled() {
+			sampled++
+		}
+		_ = requests.Inc(ctx)
+		_ = bytes.Add(ctx.WithLevel(LevelImportant), 10)
+		_ = cache.Hit(ctx.WithForce())
+
+		// Correlated metrics of a request agree, whatever the decision
+		count := mockCounterOf(t, requests).GetCount()
+		if mockCounterOf(t, bytes).GetCount() != count*10 || mockCounterOf(t, hits).GetCount() != count {
+			t.Fatalf("Expected correlated metrics to agree after %v requests, got %v, %v",
+				count, mockCounterOf(t, bytes).GetCount(), mockCounterOf(t, hits).GetCount())
+		}
+	}
+
+	if got := mockCounterOf(t, requests).GetCount(); got != float64(sampled) {
+		t.Errorf("Expected the %d sampled requests to be recorded, got %v", sampled, got)
+	}
+	if sampled == 0 || sampled == 200 {
+		t.Errorf("Expected some requests to be sampled out, got %d of 200 sampled", sampled)
+	}
+}
+
+func TestContextSamplingDecisionRates(t *testing.T) {
+	ctx := NewContextAll()
+	if !ctx.Sampled() {
+		t.Error("Expected a new context to be sampled")
+	}
+	for range 10 {
+		if !ctx.WithSamplingDecision(1).Sampled() || ctx.WithSamplingDecision(0).Sampled() {
+			t.Fatal("Expected a rate of 1 to always sample, and 0 to never sample")
+		}
+	}
+	if ctx.WithSamplingDecision(0).WithForce().Sampled() {
+		t.Error("Expected forcing a context to keep its sampling decision")
+	}
+}
@@ -0,0 +1,175 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: created_backend.go
+//
+// This file contains the [createdBackend], an internal [Backend] that creates a
+// creation timestamp gauge alongside every metric of another backend, like the
+// OpenMetrics "_created" series. It is used by groups of registries created
+// with [WithCreationTimestamps], e.g. to debug metric churn.
+//--------------------------------------------------------------------------------
+
+import (
+	"sync"
+)
+
+// CreatedSuffix is appended to the name of a metric to name its creation
+// timestamp gauge, see [WithCreationTimestamps]
+const CreatedSuffix = "_created"
+
+// createdBackend implements the [Backend] interface by creating, along with
+// every metric of another backend, a gauge set to the unix time at which the
+// metric was created.
+//
+// Adapters are those of the wrapped backend. Each metric is stamped once, when
+// it is first created, until it is unregistered.
+type createdBackend struct {
+	backend Backend
+	clock   Clock
+
+	mu      sync.Mutex
+	stamped map[string]struct{} // Qualified names of the metrics with a timestamp gauge
+}
+
+// newCreatedBackend returns a backend stamping the metrics of backend with
+// their creation time, read from clock
+func newCreatedBackend(backend Backend, clock Clock) Backend {
+	return &createdBackend{
+		backend: backend,
+		clock:   clockOrDefault(clock),
+		stamped: make(map[string]struct{}),
+	}
+}
+
+// stamp creates the creation timestamp gauge of the metric of info, unless
+// the metric is already stamped. The gauge has the namespace, subsystem and
+// const labels of the metric, so that it is named after its qualified name.
+func (c *createdBackend) stamp(info MetricInfo) {
+	name := info.QualifiedName()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.stamped[name]; ok {
+		return
+	}
+	c.stamped[name] = struct{}{}
+
+	gauge := c.backend.Gauge(GaugeOpts{
+		MetricInfo: MetricInfo{
+			Name:        info.Name + CreatedSuffix,
+			Help:        "Unix time at which " + name + " was created",
+			Namespace:   info.Namespace,
+			Subsystem:   info.Subsystem,
+			ConstLabels: info.ConstLabels,
+			Unit:        "seconds",
+		},
+	})
+	created := c.clock.Now()
+	_ = gauge.Set(float64(created.UnixNano()) / 1e9)
+}
+
+// Name returns the name of the wrapped backend
+func (c *createdBackend) Name() string {
+	return c.backend.Name()
+}
+
+// Unregister unregisters the metric and its creation timestamp gauge if the
+// wrapped backend is an [UnregisterBackend], so that a metric created again
+// is stamped again
+func (c *createdBackend) Unregister(name string) bool {
+	unregisterer, ok := c.backend.(UnregisterBackend)
+	if !ok {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, stamped := c.stamped[name]; stamped {
+		delete(c.stamped, name)
+		unregisterer.Unregister(name + CreatedSuffix)
+	}
+	return unregisterer.Unregister(name)
+}
+
+// Gather gathers the metrics of the wrapped backend if it is a [GatherBackend]
+func (c *createdBackend) Gather() ([]MetricFamily, error) {
+	gatherer, ok := c.backend.(GatherBackend)
+	if !ok {
+		return nil, ErrGatherUnsupported
+	}
+	return gatherer.Gather()
+}
+
+// Supports returns true if the wrapped backend supports the kind, see [CapabilityBackend]
+func (c *createdBackend) Supports(kind MetricKind) bool {
+	return backendSupports(c.backend, kind)
+}
+
+func (c *createdBackend) wrapped() Backend {
+	return c.backend
+}
+
+// GaugeFunc stamps the gauge, and forwards it to the wrapped backend, see
+// [gaugeFuncBackend]
+func (c *createdBackend) GaugeFunc(opts GaugeOpts, fn func() float64) {
+	c.stamp(opts.MetricInfo)
+	c.backend.(GaugeFuncBackend).GaugeFunc(opts, fn)
+}
+
+// CounterFunc stamps the counter, and forwards it to the wrapped backend,
+// see [counterFuncBackend]
+func (c *createdBackend) CounterFunc(opts CounterOpts, fn func() float64) {
+	c.stamp(opts.MetricInfo)
+	c.backend.(CounterFuncBackend).CounterFunc(opts, fn)
+}
+
+func (c *createdBackend) Counter(opts CounterOpts) CounterAdapter {
+	c.stamp(opts.MetricInfo)
+	return c.backend.Counter(opts)
+}
+
+func (c *createdBackend) CounterVec(opts CounterVecOpts) CounterVecAdapter {
+	c.stamp(opts.MetricInfo)
+	return c.backend.CounterVec(opts)
+}
+
+func (c *createdBackend) Gauge(opts GaugeOpts) GaugeAdapter {
+	c.stamp(opts.MetricInfo)
+	return c.backend.Gauge(opts)
+}
+
+func (c *createdBackend) GaugeVec(opts GaugeVecOpts) GaugeVecAdapter {
+	c.stamp(opts.MetricInfo)
+	return c.backend.GaugeVec(opts)
+}
+
+func (c *createdBackend) Histogram(opts HistogramOpts) HistogramAdapter {
+	c.stamp(opts.MetricInfo)
+	return c.backend.Histogram(opts)
+}
+
+func (c *createdBackend) HistogramVec(opts HistogramVecOpts) HistogramVecAdapter {
+	c.stamp(opts.MetricInfo)
+	return c.backend.HistogramVec(opts)
+}
+
+func (c *createdBackend) Summary(opts SummaryOpts) SummaryAdapter {
+	c.stamp(opts.MetricInfo)
+	return c.backend.Summary(opts)
+}
+
+func (c *createdBackend) SummaryVec(opts SummaryVecOpts) SummaryVecAdapter {
+	c.stamp(opts.MetricInfo)
+	return c.backend.SummaryVec(opts)
+}
+
+var (
+	__ctc_createdBackend            Backend            = (*createdBackend)(nil)
+	__ctc_createdUnregisterBackend  UnregisterBackend  = (*createdBackend)(nil)
+	__ctc_createdGatherBackend      GatherBackend      = (*createdBackend)(nil)
+	__ctc_createdCapabilityBackend  CapabilityBackend  = (*createdBackend)(nil)
+	__ctc_createdGaugeFuncBackend   GaugeFuncBackend   = (*createdBackend)(nil)
+	__ctc_createdCounterFuncBackend CounterFuncBackend = (*createdBackend)(nil)
+)
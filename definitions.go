@@ -0,0 +1,318 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: definitions.go
+//
+// This file contains the export of the definitions of the backend metrics of a
+// [Registry], without their values, see [Registry.Definitions]. Definitions are
+// derived from the opts metrics were created with, so noop metrics, which have
+// no backend metric (yet), are included as well. They are meant for generating
+// documentation, e.g. a catalog of the metrics a service exposes.
+//--------------------------------------------------------------------------------
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+)
+
+// MetricDefinition describes a backend metric, see [Registry.Definitions]
+type MetricDefinition struct {
+	// Name is the backend name of the metric, e.g. "web_requests_total"
+	Name   string     `json:"name"`
+	Help   string     `json:"help,omitempty"`
+	Kind   MetricKind `json:"kind"`
+	Level  Level      `json:"level"`
+	Labels []string   `json:"labels,omitempty"` // Label names of label-vectorized metrics
+	Unit   string     `json:"unit,omitempty"`   // See [MetricInfo.Unit]
+	SLO    *SLOSpec   `json:"slo,omitempty"`    // See [MetricInfo.SLO]
+
+	// Group is the name of the [Group] that created the metric
+	Group string `json:"group"`
+
+	// Composite is the name of the composite metric the metric is a component
+	// of, or empty for a basic metric
+	Composite string `json:"composite,omitempty"`
+}
+
+// Definitions returns the definitions of the backend metrics of every group,
+// sorted by name
+func (m *registry) Definitions() []MetricDefinition {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var definitions []MetricDefinition
+	for _, group := range m.groups {
+		definitions = append(definitions, group.definitions()...)
+	}
+
+	slices.SortFunc(definitions, func(a, b MetricDefinition) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Group, b.Group))
+	})
+	return definitions
+}
+
+// definitions returns the definitions of the backend metrics of the group's
+// tracked metrics, and of the components of its composite metrics
+func (g *group) definitions() []MetricDefinition {
+	// The metrics are read unlocked, as their locks are never taken under g.mu
+	g.mu.RLock()
+	basics := slices.Collect(maps.Values(g.basics))
+	composites := slices.Collect(maps.Values(g.composites))
+	g.mu.RUnlock()
+
+	var definitions []MetricDefinition
+	for _, metric := range basics {
+		if definition, ok := basicDefinition(metric.switchOpts()); ok {
+			definition.Level = metric.Level()
+			definition.Group = g.name
+			definitions = append(definitions, definition)
+		}
+	}
+
+	for _, metric := range composites {
+		for _, opts := range componentOpts(metric.switchOpts()) {
+			definition, ok := basicDefinition(opts)
+			if !ok {
+				continue
+			}
+
+			// Component opts hold their backend names, see [group.initComponent]
+			definition.Level = metric.Level()
+			definition.Group = g.name
+			definition.Composite = metric.Name()
+			if definition.SLO == nil {
+				definition.SLO = compositeInfo(metric.switchOpts()).SLO
+			}
+			definitions = append(definitions, definition)
+		}
+	}
+
+	return definitions
+}
+
+// basicDefinition returns the definition of a basic metric created with opts,
+// named by its qualified name (see [MetricInfo.QualifiedName]), without its
+// level and group, or false if opts are not basic metric opts
+func basicDefinition(opts any) (MetricDefinition, bool) {
+	definition := func(info MetricInfo, kind MetricKind, labels []string) (MetricDefinition, bool) {
+		return MetricDefinition{Name: info.QualifiedName(), Help: info.Help, Kind: kind, Labels: labels, Unit: info.Unit, SLO: info.SLO}, true
+	}
+
+	switch opts := opts.(type) {
+	case CounterOpts:
+		return definition(opts.MetricInfo, MetricKindCounter, nil)
+	case counterGroupOpts:
+		return definition(opts.MetricInfo, MetricKindCounter, nil)
+	case counterFuncOpts:
+		return definition(opts.MetricInfo, MetricKindCounter, nil)
+	case CounterVecOpts:
+		return definition(opts.MetricInfo, MetricKindCounter, opts.Labels)
+	case GaugeOpts:
+		return definition(opts.MetricInfo, MetricKindGauge, nil)
+	case atomicGaugeOpts:
+		return definition(opts.MetricInfo, MetricKindGauge, nil)
+	case gaugeFuncOpts:
+		return definition(opts.MetricInfo, MetricKindGauge, nil)
+	case GaugeVecOpts:
+		return definition(opts.MetricInfo, MetricKindGauge, opts.Labels)
+	case HistogramOpts:
+		return definition(opts.MetricInfo, MetricKindHistogram, nil)
+	case HistogramVecOpts:
+		return definition(opts.MetricInfo, MetricKindHistogram, opts.Labels)
+	case SummaryOpts:
+		return definition(opts.MetricInfo, MetricKindSummary, nil)
+	case SummaryVecOpts:
+		return definition(opts.MetricInfo, MetricKindSummary, opts.Labels)
+	default:
+		return MetricDefinition{}, false
+	}
+}
+
+// basicOpts returns the [BasicMetricOpts] of basic metric opts
+func basicOpts(opts any) BasicMetricOpts {
+	switch opts := opts.(type) {
+	case CounterOpts:
+		return opts.BasicMetricOpts
+	case counterGroupOpts:
+		return opts.BasicMetricOpts
+	case counterFuncOpts:
+		return opts.BasicMetricOpts
+	case CounterVecOpts:
+		return opts.BasicMetricOpts
+	case GaugeOpts:
+		return opts.BasicMetricOpts
+	case atomicGaugeOpts:
+		return opts.BasicMetricOpts
+	case gaugeFuncOpts:
+		return opts.BasicMetricOpts
+	case GaugeVecOpts:
+		return opts.BasicMetricOpts
+	case HistogramOpts:
+		return opts.BasicMetricOpts
+	case HistogramVecOpts:
+		return opts.BasicMetricOpts
+	case SummaryOpts:
+		return opts.BasicMetricOpts
+	case SummaryVecOpts:
+		return opts.BasicMetricOpts
+	default:
+		return BasicMetricOpts{}
+	}
+}
+
+// basicInfo returns the [MetricInfo] of basic metric opts
+func basicInfo(opts any) MetricInfo {
+	switch opts := opts.(type) {
+	case CounterOpts:
+		return opts.MetricInfo
+	case counterGroupOpts:
+		return opts.MetricInfo
+	case counterFuncOpts:
+		return opts.MetricInfo
+	case CounterVecOpts:
+		return opts.MetricInfo
+	case GaugeOpts:
+		return opts.MetricInfo
+	case atomicGaugeOpts:
+		return opts.MetricInfo
+	case gaugeFuncOpts:
+		return opts.MetricInfo
+	case GaugeVecOpts:
+		return opts.MetricInfo
+	case HistogramOpts:
+		return opts.MetricInfo
+	case HistogramVecOpts:
+		return opts.MetricInfo
+	case SummaryOpts:
+		return opts.MetricInfo
+	case SummaryVecOpts:
+		return opts.MetricInfo
+	default:
+		return MetricInfo{}
+	}
+}
+
+// compositeInfo returns the [MetricInfo] of composite metric opts
+func compositeInfo(opts any) MetricInfo {
+	switch opts := opts.(type) {
+	case TimerOpts:
+		return opts.MetricInfo
+	case TimerVecOpts:
+		return opts.MetricInfo
+	case CacheOpts:
+		return opts.MetricInfo
+	case CacheVecOpts:
+		return opts.MetricInfo
+	case PoolOpts:
+		return opts.MetricInfo
+	case PoolVecOpts:
+		return opts.MetricInfo
+	case CircuitBreakerOpts:
+		return opts.MetricInfo
+	case CircuitBreakerVecOpts:
+		return opts.MetricInfo
+	case QueueOpts:
+		return opts.MetricInfo
+	case QueueVecOpts:
+		return opts.MetricInfo
+	case ThroughputOpts:
+		return opts.MetricInfo
+	case DistributionGaugeOpts:
+		return opts.MetricInfo
+	case MultiResHistogramOpts:
+		return opts.MetricInfo
+	case DependencyOpts:
+		return opts.MetricInfo
+	default:
+		return MetricInfo{}
+	}
+}
+
+// componentOpts returns the opts of the components of a composite metric
+// created with opts, in the order of [CompositeMetric.Components]
+func componentOpts(opts any) []any {
+	switch opts := opts.(type) {
+	case TimerOpts:
+		return []any{opts.HistogramOpts}
+	case TimerVecOpts:
+		return []any{opts.HistogramVecOpts}
+	case CacheOpts:
+		return []any{opts.HitOpts, opts.MissOpts, opts.SizeOpts}
+	case CacheVecOpts:
+		return []any{opts.HitVecOpts, opts.MissVecOpts, opts.SizeVecOpts}
+	case PoolOpts:
+		return []any{opts.ActiveOpts, opts.IdleOpts, opts.AcquiredOpts, opts.ReleasedOpts}
+	case PoolVecOpts:
+		return []any{opts.ActiveVecOpts, opts.IdleVecOpts, opts.AcquiredVecOpts, opts.ReleasedVecOpts}
+	case CircuitBreakerOpts:
+		return []any{opts.StateOpts, opts.SuccessOpts, opts.FailureOpts}
+	case CircuitBreakerVecOpts:
+		return []any{opts.StateVecOpts, opts.SuccessVecOpts, opts.FailureVecOpts}
+	case QueueOpts:
+		return []any{opts.DepthOpts, opts.EnqueuedOpts, opts.DequeuedOpts, opts.WaitTimeOpts}
+	case QueueVecOpts:
+		return []any{opts.DepthVecOpts, opts.EnqueuedVecOpts, opts.DequeuedVecOpts, opts.WaitTimeVecOpts}
+	case ThroughputOpts:
+		return []any{opts.CountOpts, opts.RateOpts}
+	case DistributionGaugeOpts:
+		return []any{opts.GaugeOpts, opts.HistogramOpts}
+	case MultiResHistogramOpts:
+		components := make([]any, len(opts.Resolutions))
+		for i, resolution := range opts.Resolutions {
+			components[i] = resolution
+		}
+		return components
+	case DependencyOpts:
+		return []any{opts.CallsVecOpts, opts.LatencyVecOpts, opts.InFlightVecOpts}
+	default:
+		return nil
+	}
+}
+
+// DiffDefinitions compares the metric definitions b against a, e.g. the current
+// [Registry.Definitions] against a checked-in baseline, so that a golden test
+// can catch metrics that are accidentally added, removed, or renamed.
+//
+// Definitions are matched by name. It returns the definitions of b whose name
+// is not in a (added), those of a whose name is not in b (removed), and those
+// of b that differ from the definition of the same name in a (changed), each
+// sorted by name. A renamed metric is both removed and added.
+func DiffDefinitions(a, b []MetricDefinition) (added, removed, changed []MetricDefinition) {
+	before := make(map[string]MetricDefinition, len(a))
+	for _, definition := range a {
+		before[definition.Name] = definition
+	}
+	after := make(map[string]MetricDefinition, len(b))
+	for _, definition := range b {
+		after[definition.Name] = definition
+	}
+
+	for _, definition := range b {
+		old, exists := before[definition.Name]
+		switch {
+		case !exists:
+			added = append(added, definition)
+		case !definitionsEqual(old, definition):
+			changed = append(changed, definition)
+		}
+	}
+	for _, definition := range a {
+		if _, exists := after[definition.Name]; !exists {
+			removed = append(removed, definition)
+		}
+	}
+
+	byName := func(x, y MetricDefinition) int { return cmp.Compare(x.Name, y.Name) }
+	slices.SortFunc(added, byName)
+	slices.SortFunc(removed, byName)
+	slices.SortFunc(changed, byName)
+	return added, removed, changed
+}
+
+// definitionsEqual returns true if a and b define the same metric
+func definitionsEqual(a, b MetricDefinition) bool {
+	return a.Name == b.Name && a.Help == b.Help && a.Kind == b.Kind && a.Level == b.Level &&
+		slices.Equal(a.Labels, b.Labels) && a.Unit == b.Unit && a.Group == b.Group && a.Composite == b.Composite &&
+		(a.SLO == b.SLO || a.SLO != nil && b.SLO != nil && *a.SLO == *b.SLO)
+}
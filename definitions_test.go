@@ -0,0 +1,142 @@
+package umami
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRegistryDefinitions(t *testing.T) {
+	reg := NewRegistry(LevelImportant)
+	web := reg.NewGroup("web", NewMockBackend())
+
+	// Created as a noop, below the group level
+	web.HistogramVec(HistogramVecOpts{
+		MetricInfo: MetricInfo{Name: "latency_seconds", Help: "Request latency", Unit: "seconds"},
+		Labels:     []string{"route", "method"},
+	}, LevelDebug)
+	web.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total", Help: "Requests"}}, LevelCritical)
+	web.Cache(CacheOpts{
+		MetricInfo: MetricInfo{Name: "cache", Help: "Cache"},
+		HitOpts:    CounterOpts{MetricInfo: MetricInfo{Name: "cache_hits"}},
+		MissOpts:   CounterOpts{MetricInfo: MetricInfo{Name: "cache_misses"}},
+		SizeOpts:   GaugeOpts{MetricInfo: MetricInfo{Name: "cache_size_bytes", Unit: "bytes"}},
+	}, LevelVerbose)
+
+	definitions := reg.Definitions()
+	names := make([]string, len(definitions))
+	for i, definition := range definitions {
+		names[i] = definition.Name
+	}
+	want := []string{"web_cache_hits", "web_cache_misses", "web_cache_size_bytes", "web_latency_seconds", "web_requests_total"}
+	if !slices.Equal(names, want) {
+		t.Fatalf("Expected definitions %v, got %v", want, names)
+	}
+
+	latency := definitions[3]
+	if latency.Kind != MetricKindHistogram || latency.Help != "Request latency" || latency.Unit != "seconds" {
+		t.Errorf("Expected the noop vec definition to keep its opts, got %+v", latency)
+	}
+	if !slices.Equal(latency.Labels, []string{"route", "method"}) || latency.Level != LevelDebug || latency.Group != "web" {
+		t.Errorf("Expected the labels, level and group of the noop vec, got %+v", latency)
+	}
+
+	size := definitions[2]
+	if size.Composite != "cache" || size.Kind != MetricKindGauge || size.Unit != "bytes" || size.Level != LevelVerbose {
+		t.Errorf("Expected the component definition of the noop composite, got %+v", size)
+	}
+	if size.Help != "Cache (size)" {
+		t.Errorf("Expected the derived component help, got %q", size.Help)
+	}
+
+	if requests := definitions[4]; requests.Kind != MetricKindCounter || requests.Labels != nil || requests.Composite != "" {
+		t.Errorf("Expected a basic counter definition, got %+v", requests)
+	}
+}
+
+func TestDiffDefinitions(t *testing.T) {
+	define := func(reg Registry, requestsHelp string) {
+		web := reg.NewGroup("web", NewMockBackend())
+		web.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total", Help: requestsHelp}}, LevelCritical)
+		web.Gauge(GaugeOpts{MetricInfo: MetricInfo{Name: "in_flight", Help: "In-flight requests"}}, LevelImportant)
+	}
+
+	baselineReg := NewRegistry(LevelImportant)
+	define(baselineReg, "Requests")
+	baseline := baselineReg.Definitions()
+
+	currentReg := NewRegistry(LevelImportant)
+	define(currentReg, "Served requests")
+	currentReg.Group("web").HistogramVec(HistogramVecOpts{
+		MetricInfo: MetricInfo{Name: "latency_seconds"},
+		Labels:     []string{"route"},
+	}, LevelDebug)
+	current := currentReg.Definitions()
+
+	added, removed, changed := DiffDefinitions(baseline, current)
+	if len(added) != 1 || added[0].Name != "web_latency_seconds" {
+		t.Errorf("Expected web_latency_seconds to be added, got %+v", added)
+	}
+	if len(removed) != 0 {
+		t.Errorf("Expected no removed definitions, got %+v", removed)
+	}
+	if len(changed) != 1 || changed[0].Name != "web_requests_total" || changed[0].Help != "Served requests" {
+		t.Errorf("Expected the changed help of web_requests_total, got %+v", changed)
+	}
+
+	// Diffing the other way around reports the addition as a removal
+	added, removed, _ = DiffDefinitions(current, baseline)
+	if len(added) != 0 || len(removed) != 1 || removed[0].Name != "web_latency_seconds" {
+		t.Errorf("Expected web_latency_seconds to be removed, got added %+v and removed %+v", added, removed)
+	}
+
+	if added, removed, changed := DiffDefinitions(baseline, baseline); added != nil || removed != nil || changed != nil {
+		t.Errorf("Expected no differences to a copy, got %+v, %+v, %+v", added, removed, changed)
+	}
+}
+
+func TestRegistryDefinitionsSLO(t *testing.T) {
+	reg := NewRegistry(LevelImportant)
+	web := reg.NewGroup("web", NewMockBackend())
+
+	latencySLO := &SLOSpec{Target: 0.99, Threshold: 0.3, Window: 30 * 24 * time.Hour}
+	web.Histogram(HistogramOpts{MetricInfo: MetricInfo{Name: "latency_seconds", SLO: latencySLO}}, LevelCritical)
+	web.Timer(TimerOpts{
+		MetricInfo:    MetricInfo{Name: "db", SLO: &SLOSpec{Target: 0.999, Threshold: 0.05}},
+		HistogramOpts: HistogramOpts{MetricInfo: MetricInfo{Name: "db_seconds"}},
+	}, LevelDebug)
+	web.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total"}}, LevelCritical)
+
+	definitions := reg.Definitions()
+	if slo := definitions[1].SLO; definitions[1].Name != "web_latency_seconds" || slo == nil || *slo != *latencySLO {
+		t.Errorf("Expected the SLO of web_latency_seconds, got %+v", definitions[1])
+	}
+	if slo := definitions[0].SLO; definitions[0].Name != "web_db_seconds" || slo == nil || slo.Target != 0.999 {
+		t.Errorf("Expected the composite's SLO to apply to its component, got %+v", definitions[0])
+	}
+	if definitions[2].SLO != nil {
+		t.Errorf("Expected no SLO for web_requests_total, got %+v", definitions[2].SLO)
+	}
+
+	base := web.Metric("web_latency_seconds").(*switchableHistogram).impl.(*baseHistogram)
+	if base.slo != latencySLO {
+		t.Error("Expected the SLO to be stored with the metric")
+	}
+}
+
+func TestRegistryDefinitionsQualifiedNames(t *testing.T) {
+	reg := NewRegistry(LevelImportant)
+	web := reg.NewGroup("web", NewMockBackend())
+
+	web.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total", Namespace: "shop"}}, LevelCritical)
+	web.Timer(TimerOpts{MetricInfo: MetricInfo{Name: "checkout", Namespace: "shop", Subsystem: "api"}}, LevelDebug)
+
+	var names []string
+	for _, definition := range reg.Definitions() {
+		names = append(names, definition.Name)
+	}
+	want := []string{"shop_api_web_checkout_histogram", "shop_web_requests_total"}
+	if !slices.Equal(names, want) {
+		t.Errorf("Expected definitions named by qualified names %v, got %v", want, names)
+	}
+}
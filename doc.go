@@ -3,6 +3,6 @@
 // git remote add origin https://github.com/SimonDaKappa/go-umami.git
 // Currently supported monitoring backends:
 // - Prometheus
-// - StatsD (PLANNED)
-// - OpenTelemetry (PLANNED)
+// - StatsD
+// - OpenTelemetry
 package umami
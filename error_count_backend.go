@@ -0,0 +1,364 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: error_count_backend.go
+//
+// This file contains the [errorCountBackend], an internal [Backend] that counts
+// the adapter operations of another backend that return an error. It is used by
+// groups of registries created with [WithErrorMetrics], so that failures of the
+// metric pipeline are visible even when callers drop the returned errors.
+//--------------------------------------------------------------------------------
+
+// ErrorMetricName is the name of the reserved counter vec of the metric
+// operations that returned an error, see [WithErrorMetrics]. It is labeled
+// by group and metric name.
+const ErrorMetricName = "umami_metric_errors_total"
+
+// errorCountBackend implements the [Backend] interface by wrapping the adapters
+// of another backend, counting their errors in a shared counter vec.
+type errorCountBackend struct {
+	backend Backend
+	group   string            // Name of the group using the backend
+	errors  CounterVecAdapter // The reserved [ErrorMetricName] counter vec
+}
+
+// newErrorCountBackend returns a backend counting the adapter errors of backend
+// in errors, labeled with group
+func newErrorCountBackend(backend Backend, group string, errors CounterVecAdapter) Backend {
+	return &errorCountBackend{
+		backend: backend,
+		group:   group,
+		errors:  errors,
+	}
+}
+
+// newErrorCounter creates the reserved [ErrorMetricName] counter vec on backend
+func newErrorCounter(backend Backend) CounterVecAdapter {
+	return backend.CounterVec(CounterVecOpts{
+		MetricInfo: MetricInfo{
+			Name: ErrorMetricName,
+			Help: "Number of metric operations that returned an error",
+		},
+		Labels: []string{"group", "metric"},
+	})
+}
+
+// count counts err against the named metric if it is not nil, and returns it
+func (r *errorCountBackend) count(name string, err error) error {
+	if err != nil {
+		_ = r.errors.Inc(VecLabels{"group": r.group, "metric": name})
+	}
+	return err
+}
+
+// Name returns the name of the wrapped backend
+func (r *errorCountBackend) Name() string {
+	return r.backend.Name()
+}
+
+// Unregister unregisters the metric if the wrapped backend is an [UnregisterBackend]
+func (r *errorCountBackend) Unregister(name string) bool {
+	unregisterer, ok := r.backend.(UnregisterBackend)
+	return ok && unregisterer.Unregister(name)
+}
+
+// Gather gathers the metrics of the wrapped backend if it is a [GatherBackend]
+func (r *errorCountBackend) Gather() ([]MetricFamily, error) {
+	gatherer, ok := r.backend.(GatherBackend)
+	if !ok {
+		return nil, ErrGatherUnsupported
+	}
+	return gatherer.Gather()
+}
+
+// Supports returns true if the wrapped backend supports the kind, see [CapabilityBackend]
+func (r *errorCountBackend) Supports(kind MetricKind) bool {
+	return backendSupports(r.backend, kind)
+}
+
+func (r *errorCountBackend) wrapped() Backend {
+	return r.backend
+}
+
+// GaugeFunc forwards the gauge func to the wrapped backend, see
+// [gaugeFuncBackend]
+func (r *errorCountBackend) GaugeFunc(opts GaugeOpts, fn func() float64) {
+	r.backend.(GaugeFuncBackend).GaugeFunc(opts, fn)
+}
+
+// CounterFunc forwards the counter func to the wrapped backend, see
+// [counterFuncBackend]
+func (r *errorCountBackend) CounterFunc(opts CounterOpts, fn func() float64) {
+	r.backend.(CounterFuncBackend).CounterFunc(opts, fn)
+}
+
+func (r *errorCountBackend) Counter(opts CounterOpts) CounterAdapter {
+	return &errorCountCounterAdapter{backend: r, name: opts.QualifiedName(), internal: r.backend.Counter(opts)}
+}
+
+func (r *errorCountBackend) CounterVec(opts CounterVecOpts) CounterVecAdapter {
+	return &errorCountCounterVecAdapter{backend: r, name: opts.QualifiedName(), internal: r.backend.CounterVec(opts)}
+}
+
+func (r *errorCountBackend) Gauge(opts GaugeOpts) GaugeAdapter {
+	return &errorCountGaugeAdapter{backend: r, name: opts.QualifiedName(), internal: r.backend.Gauge(opts)}
+}
+
+func (r *errorCountBackend) GaugeVec(opts GaugeVecOpts) GaugeVecAdapter {
+	return &errorCountGaugeVecAdapter{backend: r, name: opts.QualifiedName(), internal: r.backend.GaugeVec(opts)}
+}
+
+func (r *errorCountBackend) Histogram(opts HistogramOpts) HistogramAdapter {
+	return &errorCountHistogramAdapter{backend: r, name: opts.QualifiedName(), internal: r.backend.Histogram(opts)}
+}
+
+func (r *errorCountBackend) HistogramVec(opts HistogramVecOpts) HistogramVecAdapter {
+	return &errorCountHistogramVecAdapter{backend: r, name: opts.QualifiedName(), internal: r.backend.HistogramVec(opts)}
+}
+
+func (r *errorCountBackend) Summary(opts SummaryOpts) SummaryAdapter {
+	return &errorCountSummaryAdapter{backend: r, name: opts.QualifiedName(), internal: r.backend.Summary(opts)}
+}
+
+func (r *errorCountBackend) SummaryVec(opts SummaryVecOpts) SummaryVecAdapter {
+	return &errorCountSummaryVecAdapter{backend: r, name: opts.QualifiedName(), internal: r.backend.SummaryVec(opts)}
+}
+
+//--------------------------------------------------------------------------------
+// Error Count Adapters
+//--------------------------------------------------------------------------------
+
+type errorCountCounterAdapter struct {
+	backend  *errorCountBackend
+	name     string
+	internal CounterAdapter
+}
+
+func (a *errorCountCounterAdapter) Inc() error {
+	return a.backend.count(a.name, a.internal.Inc())
+}
+
+func (a *errorCountCounterAdapter) Add(value float64) error {
+	return a.backend.count(a.name, a.internal.Add(value))
+}
+
+func (a *errorCountCounterAdapter) AddWithExemplar(value float64, exemplar VecLabels) error {
+	return a.backend.count(a.name, addWithExemplar(a.internal, value, exemplar))
+}
+
+func (a *errorCountCounterAdapter) Value() (float64, error) {
+	value, err := adapterValue(a.internal)
+	return value, a.backend.count(a.name, err)
+}
+
+type errorCountCounterVecAdapter struct {
+	backend  *errorCountBackend
+	name     string
+	internal CounterVecAdapter
+}
+
+func (a *errorCountCounterVecAdapter) Inc(labels VecLabels) error {
+	return a.backend.count(a.name, a.internal.Inc(labels))
+}
+
+func (a *errorCountCounterVecAdapter) Add(value float64, labels VecLabels) error {
+	return a.backend.count(a.name, a.internal.Add(value, labels))
+}
+
+func (a *errorCountCounterVecAdapter) Series() ([]Sample, error) {
+	series, err := adapterSeries(a.internal)
+	return series, a.backend.count(a.name, err)
+}
+
+func (a *errorCountCounterVecAdapter) InitLabels(labels VecLabels) error {
+	return a.backend.count(a.name, initVecSeries(a.internal, labels, func(labels VecLabels) error { return a.internal.Add(0, labels) }))
+}
+
+func (a *errorCountCounterVecAdapter) WithLabelValues(values ...string) (CounterAdapter, error) {
+	internal, err := adapterLabelValues(a.internal, values)
+	if err != nil {
+		return nil, err
+	}
+	return &errorCountCounterAdapter{backend: a.backend, name: a.name, internal: internal}, nil
+}
+
+func (a *errorCountCounterVecAdapter) Delete(labels VecLabels) bool {
+	return adapterDelete(a.internal, labels)
+}
+
+func (a *errorCountCounterVecAdapter) Reset() {
+	adapterReset(a.internal)
+}
+
+type errorCountGaugeAdapter struct {
+	backend  *errorCountBackend
+	name     string
+	internal GaugeAdapter
+}
+
+func (a *errorCountGaugeAdapter) Set(value float64) error {
+	return a.backend.count(a.name, a.internal.Set(value))
+}
+
+func (a *errorCountGaugeAdapter) Inc() error {
+	return a.backend.count(a.name, a.internal.Inc())
+}
+
+func (a *errorCountGaugeAdapter) Dec() error {
+	return a.backend.count(a.name, a.internal.Dec())
+}
+
+func (a *errorCountGaugeAdapter) Add(value float64) error {
+	return a.backend.count(a.name, a.internal.Add(value))
+}
+
+func (a *errorCountGaugeAdapter) Value() (float64, error) {
+	value, err := adapterValue(a.internal)
+	return value, a.backend.count(a.name, err)
+}
+
+type errorCountGaugeVecAdapter struct {
+	backend  *errorCountBackend
+	name     string
+	internal GaugeVecAdapter
+}
+
+func (a *errorCountGaugeVecAdapter) Set(value float64, labels VecLabels) error {
+	return a.backend.count(a.name, a.internal.Set(value, labels))
+}
+
+func (a *errorCountGaugeVecAdapter) Inc(labels VecLabels) error {
+	return a.backend.count(a.name, a.internal.Inc(labels))
+}
+
+func (a *errorCountGaugeVecAdapter) Dec(labels VecLabels) error {
+	return a.backend.count(a.name, a.internal.Dec(labels))
+}
+
+func (a *errorCountGaugeVecAdapter) Add(value float64, labels VecLabels) error {
+	return a.backend.count(a.name, a.internal.Add(value, labels))
+}
+
+func (a *errorCountGaugeVecAdapter) InitLabels(labels VecLabels) error {
+	return a.backend.count(a.name, initVecSeries(a.internal, labels, func(labels VecLabels) error { return a.internal.Add(0, labels) }))
+}
+
+func (a *errorCountGaugeVecAdapter) Delete(labels VecLabels) bool {
+	return adapterDelete(a.internal, labels)
+}
+
+func (a *errorCountGaugeVecAdapter) Reset() {
+	adapterReset(a.internal)
+}
+
+type errorCountHistogramAdapter struct {
+	backend  *errorCountBackend
+	name     string
+	internal HistogramAdapter
+}
+
+func (a *errorCountHistogramAdapter) Observe(value float64) error {
+	return a.backend.count(a.name, a.internal.Observe(value))
+}
+
+func (a *errorCountHistogramAdapter) ObserveWithExemplar(value float64, exemplar VecLabels) error {
+	return a.backend.count(a.name, observeWithExemplar(a.internal, value, exemplar))
+}
+
+type errorCountHistogramVecAdapter struct {
+	backend  *errorCountBackend
+	name     string
+	internal HistogramVecAdapter
+}
+
+func (a *errorCountHistogramVecAdapter) Observe(value float64, labels VecLabels) error {
+	return a.backend.count(a.name, a.internal.Observe(value, labels))
+}
+
+func (a *errorCountHistogramVecAdapter) InitLabels(labels VecLabels) error {
+	return a.backend.count(a.name, initVecSeries(a.internal, labels, nil))
+}
+
+func (a *errorCountHistogramVecAdapter) Delete(labels VecLabels) bool {
+	return adapterDelete(a.internal, labels)
+}
+
+func (a *errorCountHistogramVecAdapter) Reset() {
+	adapterReset(a.internal)
+}
+
+type errorCountSummaryAdapter struct {
+	backend  *errorCountBackend
+	name     string
+	internal SummaryAdapter
+}
+
+func (a *errorCountSummaryAdapter) Observe(value float64) error {
+	return a.backend.count(a.name, a.internal.Observe(value))
+}
+
+func (a *errorCountSummaryAdapter) Quantile(q float64) (float64, error) {
+	value, err := a.internal.Quantile(q)
+	return value, a.backend.count(a.name, err)
+}
+
+type errorCountSummaryVecAdapter struct {
+	backend  *errorCountBackend
+	name     string
+	internal SummaryVecAdapter
+}
+
+func (a *errorCountSummaryVecAdapter) Observe(value float64, labels VecLabels) error {
+	return a.backend.count(a.name, a.internal.Observe(value, labels))
+}
+
+func (a *errorCountSummaryVecAdapter) Quantile(q float64, labels VecLabels) (float64, error) {
+	value, err := a.internal.Quantile(q, labels)
+	return value, a.backend.count(a.name, err)
+}
+
+func (a *errorCountSummaryVecAdapter) InitLabels(labels VecLabels) error {
+	return a.backend.count(a.name, initVecSeries(a.internal, labels, nil))
+}
+
+func (a *errorCountSummaryVecAdapter) Delete(labels VecLabels) bool {
+	return adapterDelete(a.internal, labels)
+}
+
+func (a *errorCountSummaryVecAdapter) Reset() {
+	adapterReset(a.internal)
+}
+
+var (
+	__ctc_errorCountBackend            Backend            = (*errorCountBackend)(nil)
+	__ctc_errorCountUnregisterBackend  UnregisterBackend  = (*errorCountBackend)(nil)
+	__ctc_errorCountGatherBackend      GatherBackend      = (*errorCountBackend)(nil)
+	__ctc_errorCountCapabilityBackend  CapabilityBackend  = (*errorCountBackend)(nil)
+	__ctc_errorCountGaugeFuncBackend   GaugeFuncBackend   = (*errorCountBackend)(nil)
+	__ctc_errorCountCounterFuncBackend CounterFuncBackend = (*errorCountBackend)(nil)
+
+	__ctc_errorCountCounterAdapter      CounterAdapter      = (*errorCountCounterAdapter)(nil)
+	__ctc_errorCountCounterVecAdapter   CounterVecAdapter   = (*errorCountCounterVecAdapter)(nil)
+	__ctc_errorCountGaugeAdapter        GaugeAdapter        = (*errorCountGaugeAdapter)(nil)
+	__ctc_errorCountGaugeVecAdapter     GaugeVecAdapter     = (*errorCountGaugeVecAdapter)(nil)
+	__ctc_errorCountHistogramAdapter    HistogramAdapter    = (*errorCountHistogramAdapter)(nil)
+	__ctc_errorCountHistogramVecAdapter HistogramVecAdapter = (*errorCountHistogramVecAdapter)(nil)
+	__ctc_errorCountSummaryAdapter      SummaryAdapter      = (*errorCountSummaryAdapter)(nil)
+	__ctc_errorCountSummaryVecAdapter   SummaryVecAdapter   = (*errorCountSummaryVecAdapter)(nil)
+
+	__ctc_errorCountCounterValueAdapter ValueAdapter             = (*errorCountCounterAdapter)(nil)
+	__ctc_errorCountGaugeValueAdapter   ValueAdapter             = (*errorCountGaugeAdapter)(nil)
+	__ctc_errorCountCounterVecSeries    SeriesAdapter            = (*errorCountCounterVecAdapter)(nil)
+	__ctc_errorCountCounterVecValues    LabelValuesAdapter       = (*errorCountCounterVecAdapter)(nil)
+	__ctc_errorCountCounterVecDelete    DeleteAdapter            = (*errorCountCounterVecAdapter)(nil)
+	__ctc_errorCountGaugeVecDelete      DeleteAdapter            = (*errorCountGaugeVecAdapter)(nil)
+	__ctc_errorCountHistogramVecDelete  DeleteAdapter            = (*errorCountHistogramVecAdapter)(nil)
+	__ctc_errorCountSummaryVecDelete    DeleteAdapter            = (*errorCountSummaryVecAdapter)(nil)
+	__ctc_errorCountCounterExemplar     ExemplarCounterAdapter   = (*errorCountCounterAdapter)(nil)
+	__ctc_errorCountHistogramExemplar   ExemplarHistogramAdapter = (*errorCountHistogramAdapter)(nil)
+
+	__ctc_errorCountCounterVecInitAdapter   VecInitAdapter = (*errorCountCounterVecAdapter)(nil)
+	__ctc_errorCountGaugeVecInitAdapter     VecInitAdapter = (*errorCountGaugeVecAdapter)(nil)
+	__ctc_errorCountHistogramVecInitAdapter VecInitAdapter = (*errorCountHistogramVecAdapter)(nil)
+	__ctc_errorCountSummaryVecInitAdapter   VecInitAdapter = (*errorCountSummaryVecAdapter)(nil)
+)
@@ -0,0 +1,124 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: errors.go
+//
+// This file contains the sentinel errors of the umami metrics library.
+//
+// Errors returned by the library wrap one of these with details, e.g. the name
+// of the metric, so they should be checked with [errors.Is]:
+//
+//	if errors.Is(err, umami.ErrLabelMismatch) {
+//		...
+//	}
+//--------------------------------------------------------------------------------
+
+import "errors"
+
+//--------------------------------------------------------------------------------
+// Metric Errors
+//--------------------------------------------------------------------------------
+
+var (
+	// ErrNegativeCounterAdd is returned when adding a negative value to a
+	// counter, which may only increase
+	ErrNegativeCounterAdd = errors.New("umami: counter cannot decrease")
+
+	// ErrInvalidValue is returned when recording a value a metric can't hold,
+	// e.g. NaN added to a counter
+	ErrInvalidValue = errors.New("umami: invalid metric value")
+
+	// ErrOutOfBounds is returned when setting a gauge with strict bounds to a
+	// value out of range, see [GaugeOpts.Bounds]
+	ErrOutOfBounds = errors.New("umami: value out of gauge bounds")
+
+	// ErrLabelMismatch is returned when the labels passed to a label-vectorized
+	// metric don't match its declared label names
+	ErrLabelMismatch = errors.New("umami: labels do not match the declared label names")
+
+	// ErrCardinalityExceeded is returned when recording into a new series of a
+	// label-vectorized metric that already has the maximum number of series
+	ErrCardinalityExceeded = errors.New("umami: label cardinality limit exceeded")
+
+	// ErrQuantileNotFound is returned by summary adapters reading a quantile that
+	// is not one of the summary's objectives, or of a series that does not exist
+	ErrQuantileNotFound = errors.New("umami: quantile not found")
+
+	// ErrConflictingObjectives is reported when a summary quantile is listed
+	// with different errors, see [MergeObjectives]
+	ErrConflictingObjectives = errors.New("umami: conflicting summary objectives")
+
+	// ErrNotReconfigurable is returned when reconfiguring a metric that was not
+	// created by a [Group]
+	ErrNotReconfigurable = errors.New("umami: metric was not created by a group and cannot be reconfigured")
+
+	// ErrNoStartTime is returned when recording the time elapsed since the
+	// start of a [Context] that has none, see [Context.WithStart]
+	ErrNoStartTime = errors.New("umami: context has no start time")
+)
+
+//--------------------------------------------------------------------------------
+// Lookup Errors
+//--------------------------------------------------------------------------------
+
+var (
+	// ErrMetricNotFound is returned when looking up the value of a metric that
+	// no backend has gathered, or a metric that a [Group] doesn't track
+	ErrMetricNotFound = errors.New("umami: metric not found")
+
+	// ErrMetricRedeclaredWithDifferentOpts is reported when a metric is
+	// requested again with a different level or help, see [RedeclarePolicy]
+	ErrMetricRedeclaredWithDifferentOpts = errors.New("umami: metric redeclared with different opts")
+
+	// ErrEmptyMetricName is the error panicked with when creating a metric, or a
+	// composite metric, with an empty name, which would otherwise be exported as
+	// the group prefix alone (e.g. "http_") or as its component suffixes
+	// (e.g. "_hit")
+	ErrEmptyMetricName = errors.New("umami: metric name is empty")
+
+	// ErrMetricNameTaken is the error panicked with when creating a metric whose
+	// backend name is already used by another group of the registry, or is
+	// reserved by the registry itself, see [BasicMetricOpts.NoPrefix]
+	ErrMetricNameTaken = errors.New("umami: metric name already used by another group")
+
+	// ErrRegistrySealed is the error panicked with when creating a new metric in
+	// a group of a sealed registry, see [Registry.Seal]
+	ErrRegistrySealed = errors.New("umami: registry is sealed")
+
+	// ErrMetricTypeMismatch is returned by [MetricAs] when the named metric is not
+	// of the requested type
+	ErrMetricTypeMismatch = errors.New("umami: metric type mismatch")
+
+	// ErrUnknownDumpFormat is returned by [Registry.Dump] for unknown formats
+	ErrUnknownDumpFormat = errors.New("umami: unknown dump format")
+)
+
+//--------------------------------------------------------------------------------
+// Backend Errors
+//--------------------------------------------------------------------------------
+
+var (
+	// ErrUnsupportedMetric is returned when a [Backend] can't provide a metric
+	// type or operation
+	ErrUnsupportedMetric = errors.New("umami: metric not supported by backend")
+
+	// ErrUnregisterUnsupported is returned by operations that require an
+	// [UnregisterBackend] when the backend does not implement it
+	ErrUnregisterUnsupported = errors.New("umami: backend does not support unregistering metrics")
+
+	// ErrGatherUnsupported is returned by operations that require a
+	// [GatherBackend] when no backend implements it
+	ErrGatherUnsupported = errors.New("umami: backend does not support gathering metrics")
+
+	// ErrBackendPanic is returned by metric operations whose backend adapter
+	// panicked, see [WithRecover]
+	ErrBackendPanic = errors.New("umami: backend panicked")
+
+	// ErrEmitRateLimited is returned by metric operations dropped by the emit rate
+	// limit of their registry, see [WithEmitRateLimit]
+	ErrEmitRateLimited = errors.New("umami: metric operation dropped by emit rate limit")
+
+	// ErrAsyncQueueFull is returned by operations of async metrics dropped as
+	// the queue of their registry stayed full, see [BasicMetricOpts.Async]
+	ErrAsyncQueueFull = errors.New("umami: metric operation dropped by full async queue")
+)
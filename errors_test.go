@@ -0,0 +1,55 @@
+package umami
+
+import (
+	"errors"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestErrorsIs(t *testing.T) {
+	reg := NewRegistry(LevelDebug)
+	group := reg.NewGroup("test", NewMockBackend())
+	ctx := group.Context()
+
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests"}}, LevelImportant)
+	counterVec := group.CounterVec(CounterVecOpts{MetricInfo: MetricInfo{Name: "hits"}, Labels: []string{"route"}}, LevelImportant)
+
+	validating := NewValidatingBackend()
+	validatingVec := validating.CounterVec(CounterVecOpts{MetricInfo: MetricInfo{Name: "hits"}, Labels: []string{"route"}})
+
+	tests := []struct {
+		name string
+		err  func() error
+		want error
+	}{
+		{"negative counter add", func() error { return counter.Add(ctx, -1) }, ErrNegativeCounterAdd},
+		{"negative counter vec add", func() error { return counterVec.Add(ctx, -1, testLabels) }, ErrNegativeCounterAdd},
+		{"NaN counter add", func() error { return counter.Add(ctx, math.NaN()) }, ErrInvalidValue},
+		{"NaN counter vec add", func() error { return counterVec.Add(ctx, math.NaN(), testLabels) }, ErrInvalidValue},
+		{"validating label mismatch", func() error {
+			validatingVec.Inc(VecLabels{"status": "200"})
+			return errors.Join(validating.Problems()...)
+		}, ErrLabelMismatch},
+		{"unconfigurable summary", func() error {
+			return (&baseSummary{}).Reconfigure(SummaryOpts{})
+		}, ErrNotReconfigurable},
+		{"missing metric", func() error { _, err := MetricAs[Counter](group, "missing"); return err }, ErrMetricNotFound},
+		{"metric type mismatch", func() error { _, err := MetricAs[Gauge](group, counter.Name()); return err }, ErrMetricTypeMismatch},
+		{"missing metric value", func() error { _, err := reg.MetricValue("missing"); return err }, ErrMetricNotFound},
+		{"unknown dump format", func() error { return reg.Dump(io.Discard, "xml") }, ErrUnknownDumpFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.err(); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// Rejected values never reach the backend
+	if got := mockCounterOf(t, counter).GetCount(); got != 0 {
+		t.Errorf("Expected rejected adds not to be recorded, got %v", got)
+	}
+}
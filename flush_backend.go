@@ -0,0 +1,119 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: flush_backend.go
+//
+// This file contains the [PeriodicFlushBackend], a [Backend] wrapping a
+// [FlushableBackend] (e.g. StatsD, Influx or push gateway backends) that flushes
+// it on an interval, so that callers don't have to flush buffered backends
+// manually.
+//--------------------------------------------------------------------------------
+
+import (
+	"sync"
+	"time"
+)
+
+// PeriodicFlushBackend is a [FlushableBackend] that flushes the wrapped backend
+// every interval until it is closed, see [NewPeriodicFlushBackend].
+//
+// Metrics are created by the wrapped backend.
+type PeriodicFlushBackend struct {
+	FlushableBackend
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewPeriodicFlushBackend returns a backend flushing backend every interval,
+// starting a goroutine that runs until [PeriodicFlushBackend.Close].
+//
+// Errors of periodic flushes are dropped, as there is no caller to return them
+// to. Panics if interval is not positive.
+func NewPeriodicFlushBackend(backend FlushableBackend, interval time.Duration) *PeriodicFlushBackend {
+	if interval <= 0 {
+		panic("umami: flush interval must be positive")
+	}
+
+	p := &PeriodicFlushBackend{
+		FlushableBackend: backend,
+		stop:             make(chan struct{}),
+		done:             make(chan struct{}),
+	}
+	go p.run(interval)
+	return p
+}
+
+func (p *PeriodicFlushBackend) run(interval time.Duration) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = p.Flush()
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Close stops the periodic flushes, waiting for a running flush to finish,
+// then flushes the values buffered since the last one and returns its error.
+//
+// Calling Close more than once returns the error of the first call.
+func (p *PeriodicFlushBackend) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.stop)
+		<-p.done
+		p.closeErr = p.Flush()
+	})
+	return p.closeErr
+}
+
+// Unregister unregisters the metric if the wrapped backend is an [UnregisterBackend]
+func (p *PeriodicFlushBackend) Unregister(name string) bool {
+	unregisterer, ok := p.FlushableBackend.(UnregisterBackend)
+	return ok && unregisterer.Unregister(name)
+}
+
+// Gather gathers the metrics of the wrapped backend if it is a [GatherBackend]
+func (p *PeriodicFlushBackend) Gather() ([]MetricFamily, error) {
+	gatherer, ok := p.FlushableBackend.(GatherBackend)
+	if !ok {
+		return nil, ErrGatherUnsupported
+	}
+	return gatherer.Gather()
+}
+
+// Supports returns true if the wrapped backend supports the kind, see [CapabilityBackend]
+func (p *PeriodicFlushBackend) Supports(kind MetricKind) bool {
+	return backendSupports(p.FlushableBackend, kind)
+}
+
+func (p *PeriodicFlushBackend) wrapped() Backend {
+	return p.FlushableBackend
+}
+
+// GaugeFunc forwards the gauge to the wrapped backend, see [gaugeFuncBackend]
+func (p *PeriodicFlushBackend) GaugeFunc(opts GaugeOpts, fn func() float64) {
+	p.FlushableBackend.(GaugeFuncBackend).GaugeFunc(opts, fn)
+}
+
+// CounterFunc forwards the counter to the wrapped backend, see [counterFuncBackend]
+func (p *PeriodicFlushBackend) CounterFunc(opts CounterOpts, fn func() float64) {
+	p.FlushableBackend.(CounterFuncBackend).CounterFunc(opts, fn)
+}
+
+var (
+	__ctc_periodicFlushBackend            FlushableBackend   = (*PeriodicFlushBackend)(nil)
+	__ctc_periodicFlushUnregisterBackend  UnregisterBackend  = (*PeriodicFlushBackend)(nil)
+	__ctc_periodicFlushGatherBackend      GatherBackend      = (*PeriodicFlushBackend)(nil)
+	__ctc_periodicFlushCapabilityBackend  CapabilityBackend  = (*PeriodicFlushBackend)(nil)
+	__ctc_periodicFlushGaugeFuncBackend   GaugeFuncBackend   = (*PeriodicFlushBackend)(nil)
+	__ctc_periodicFlushCounterFuncBackend CounterFuncBackend = (*PeriodicFlushBackend)(nil)
+)
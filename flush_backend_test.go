@@ -0,0 +1,88 @@
+package umami
+
+import (
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flushCountingBackend is a [FlushableBackend] counting its flushes
+type flushCountingBackend struct {
+	Backend
+	flushes  atomic.Int64
+	flushErr error
+}
+
+func (b *flushCountingBackend) Flush() error {
+	b.flushes.Add(1)
+	return b.flushErr
+}
+
+// flushableMockBackend is a [FlushableBackend] mock backend, which reads the
+// functions of gauge and counter funcs
+type flushableMockBackend struct {
+	*mockBackend
+}
+
+func (b flushableMockBackend) Flush() error {
+	return nil
+}
+
+func TestPeriodicFlushBackend(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	flushable := &flushCountingBackend{Backend: NewMockBackend(), flushErr: errors.New("flush failed")}
+	backend := NewPeriodicFlushBackend(flushable, time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for flushable.flushes.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected periodic flushes, got %d", flushable.flushes.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Metrics are created by the wrapped backend
+	group := NewRegistry(LevelDebug).NewGroup("test", backend)
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "sent"}}, LevelImportant)
+	counter.Inc(group.Context())
+	if got := mockCounterOf(t, counter).GetCount(); got != 1 {
+		t.Errorf("Expected the wrapped backend to record, got %v", got)
+	}
+
+	before := flushable.flushes.Load()
+	if err := backend.Close(); err != flushable.flushErr {
+		t.Errorf("Expected Close to return the final flush error, got %v", err)
+	}
+	closed := flushable.flushes.Load()
+	if closed <= before {
+		t.Error("Expected Close to flush")
+	}
+	assertNoGoroutineLeak(t, baseline)
+
+	time.Sleep(10 * time.Millisecond)
+	if got := flushable.flushes.Load(); got != closed {
+		t.Errorf("Expected no flushes after Close, got %d more", got-closed)
+	}
+	if err := backend.Close(); err != flushable.flushErr || flushable.flushes.Load() != closed {
+		t.Error("Expected a second Close to only return the first result")
+	}
+}
+
+func TestPeriodicFlushBackendFuncs(t *testing.T) {
+	mock := NewMockBackend().(*mockBackend)
+	backend := NewPeriodicFlushBackend(flushableMockBackend{mock}, time.Hour)
+	defer backend.Close()
+
+	group := NewRegistry(LevelDebug).NewGroup("test", backend)
+	group.GaugeFunc(GaugeOpts{MetricInfo: MetricInfo{Name: "queued"}}, func() float64 { return 4 }, LevelImportant)
+	group.CounterFunc(CounterOpts{MetricInfo: MetricInfo{Name: "sent_total"}}, func() float64 { return 2 }, LevelImportant)
+
+	if gauge, ok := mock.adapter("test_queued").(*mockGaugeFuncAdapter); !ok || gauge.GetValue() != 4 {
+		t.Errorf("Expected the wrapped backend to read the gauge func, got %v", mock.adapter("test_queued"))
+	}
+	if counter, ok := mock.adapter("test_sent_total").(*mockCounterFuncAdapter); !ok || counter.GetCount() != 2 {
+		t.Errorf("Expected the wrapped backend to read the counter func, got %v", mock.adapter("test_sent_total"))
+	}
+}
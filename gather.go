@@ -0,0 +1,114 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: gather.go
+//
+// This file contains the backend neutral representation of gathered metric
+// values, see [GatherBackend] and [Registry.MetricValue], and their dump
+// formats (see [Registry.Dump]). The types carry JSON tags so they can be
+// served as is, e.g. by a debug endpoint.
+//--------------------------------------------------------------------------------
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// MetricKind is the kind of the metrics of a [MetricFamily]
+type MetricKind string
+
+const (
+	MetricKindCounter   MetricKind = "counter"
+	MetricKindGauge     MetricKind = "gauge"
+	MetricKindHistogram MetricKind = "histogram"
+	MetricKindSummary   MetricKind = "summary"
+)
+
+// MetricFamily is the gathered value of a backend metric, with one [Sample]
+// per set of labels (a single one for metrics without labels).
+type MetricFamily struct {
+	Name    string     `json:"name"`
+	Help    string     `json:"help,omitempty"`
+	Kind    MetricKind `json:"kind"`
+	Samples []Sample   `json:"samples"`
+}
+
+// Sample is the value of a [MetricFamily] for one set of labels
+type Sample struct {
+	Labels VecLabels `json:"labels,omitempty"`
+
+	// Value is the value of a counter or gauge
+	Value float64 `json:"value"`
+
+	// Count and Sum are the number and sum of observations of a histogram or summary
+	Count uint64  `json:"count,omitempty"`
+	Sum   float64 `json:"sum,omitempty"`
+}
+
+const (
+	// DumpFormatText dumps one line per sample, e.g.
+	//
+	//	web_requests_total{route="/"} counter 3
+	//	web_latency_seconds{route="/"} histogram count=2 sum=0.3
+	DumpFormatText string = "text"
+
+	// DumpFormatJSON dumps an indented JSON array of [MetricFamily]
+	DumpFormatJSON string = "json"
+)
+
+// Dump writes the current value of every backend metric to w in the given format
+func (m *registry) Dump(w io.Writer, format string) error {
+	if format != DumpFormatText && format != DumpFormatJSON {
+		return fmt.Errorf("%w: %q", ErrUnknownDumpFormat, format)
+	}
+
+	families, err := m.gather()
+	if err != nil {
+		return err
+	}
+
+	if format == DumpFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(families)
+	}
+
+	for _, family := range families {
+		for _, sample := range family.Samples {
+			if _, err := fmt.Fprintln(w, formatSample(family, sample)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// formatSample formats a sample of family as a [DumpFormatText] line
+func formatSample(family MetricFamily, sample Sample) string {
+	var b strings.Builder
+	b.WriteString(family.Name)
+
+	if len(sample.Labels) > 0 {
+		b.WriteByte('{')
+		for i, name := range slices.Sorted(maps.Keys(sample.Labels)) {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, "%s=%q", name, sample.Labels[name])
+		}
+		b.WriteByte('}')
+	}
+
+	switch family.Kind {
+	case MetricKindHistogram, MetricKindSummary:
+		fmt.Fprintf(&b, " %s count=%d sum=%g", family.Kind, sample.Count, sample.Sum)
+	default:
+		fmt.Fprintf(&b, " %s %g", family.Kind, sample.Value)
+	}
+
+	return b.String()
+}
@@ -8,7 +8,15 @@ package umami
 //--------------------------------------------------------------------------------
 
 import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 //--------------------------------------------------------------------------------
@@ -29,12 +37,133 @@ type Group interface {
 	//
 	// Optionally, you can provide a flag to replace existing no-op metrics with
 	// real implementations if they are now enabled by the new level.
+	//
+	// The level is explicit: it is cascaded to the sub groups of this group
+	// that inherit their level (see [Registry.NewSubGroup]), and this group no
+	// longer inherits the level of its parent, if any.
 	SetGroupLevel(level Level, opts LevelOpts)
 
-	// Context returns a context for this group
+	// SetGroupMask sets the categories of metrics enabled in this group,
+	// whatever their level, see [Mask]. Metrics keep their implementation,
+	// and operations of those with a disabled category are a noop.
+	//
+	// Groups enable every category ([MaskAll]) unless a mask is set.
+	SetGroupMask(mask Mask)
+
+	// Context returns a context for this group, at the group's level and
+	// with its mask
 	Context() Context
 
+	// BackendSupports returns true if the group's backend records metrics of
+	// the kind, e.g. to fall back to histograms where summaries are noops:
+	//
+	//	if !group.BackendSupports(umami.MetricKindSummary) {
+	//		...
+	//	}
+	//
+	// See [CapabilityBackend]. With mirror backends, every backend must
+	// support the kind.
+	BackendSupports(kind MetricKind) bool
+
+	//--------------------------------------------------------------------------------
+	// Must
+	//
+	// Each Must method is like its [Factory] counterpart, but panics with an
+	// error wrapping [ErrUnsupportedMetric] if the group's backend doesn't
+	// support the kind of the metric (see [Group.BackendSupports]), e.g. to
+	// fail at startup rather than record summaries a backend can't compute.
+	// The Factory methods create the metric regardless.
+	//--------------------------------------------------------------------------------
+
+	MustCounter(opts CounterOpts, level Level) Counter
+	MustCounterVec(opts CounterVecOpts, level Level) CounterVec
+	MustGauge(opts GaugeOpts, level Level) Gauge
+	MustGaugeVec(opts GaugeVecOpts, level Level) GaugeVec
+	MustHistogram(opts HistogramOpts, level Level) Histogram
+	MustHistogramVec(opts HistogramVecOpts, level Level) HistogramVec
+	MustSummary(opts SummaryOpts, level Level) Summary
+	MustSummaryVec(opts SummaryVecOpts, level Level) SummaryVec
+
+	//--------------------------------------------------------------------------------
+	// Try
+	//
+	// Each Try method is like its [Factory] counterpart, but returns an error
+	// wrapping [ErrUnsupportedMetric], without creating the metric, if the
+	// group's backend doesn't support the kind of the metric (see
+	// [Group.BackendSupports]), e.g. to fall back to another kind. The Must
+	// methods panic with that error instead.
+	//--------------------------------------------------------------------------------
+
+	TryCounter(opts CounterOpts, level Level) (Counter, error)
+	TryCounterVec(opts CounterVecOpts, level Level) (CounterVec, error)
+	TryGauge(opts GaugeOpts, level Level) (Gauge, error)
+	TryGaugeVec(opts GaugeVecOpts, level Level) (GaugeVec, error)
+	TryHistogram(opts HistogramOpts, level Level) (Histogram, error)
+	TryHistogramVec(opts HistogramVecOpts, level Level) (HistogramVec, error)
+	TrySummary(opts SummaryOpts, level Level) (Summary, error)
+	TrySummaryVec(opts SummaryVecOpts, level Level) (SummaryVec, error)
+
+	// Metric returns the tracked metric with the given name, or nil if there
+	// is none. See [MetricAs] to look up a metric of a specific type.
 	Metric(name string) Metric
+
+	// Remove stops tracking the named metric (see [Group.Metric]), and stops it
+	// if it is [Stoppable]. Returns false if no such metric is tracked.
+	//
+	// The metric's backend metrics stay registered, so a metric of the same
+	// name created later records into them.
+	Remove(name string) bool
+
+	// EnableTags re-enables metrics tagged with any of the tags, see [MetricInfo.Tags].
+	//
+	// A metric is only enabled if none of its tags is disabled.
+	EnableTags(tags ...string)
+
+	// DisableTags disables every metric of this group tagged with any of the
+	// tags, see [MetricInfo.Tags]. Disabled metrics keep their implementation,
+	// and their operations are a noop.
+	DisableTags(tags ...string)
+
+	// InstrumentReader returns r, counting the bytes read and observing the
+	// throughput of each read into metrics of this group, see [IOOpts]. The
+	// returned reader is an [io.ReadCloser] if r is an [io.Closer].
+	InstrumentReader(r io.Reader, opts IOOpts, level Level) io.Reader
+
+	// InstrumentWriter returns w, counting the bytes written and observing
+	// the throughput of each write into metrics of this group, see [IOOpts].
+	// The returned writer is an [io.WriteCloser] if w is an [io.Closer].
+	InstrumentWriter(w io.Writer, opts IOOpts, level Level) io.Writer
+
+	//--------------------------------------------------------------------------------
+	// Get or Create
+	//
+	// Each GetOrCreate method is like its [Factory] counterpart, but also reports
+	// whether the metric was created by the call (true), or an already tracked
+	// metric with the same name was returned (false). This allows idempotent
+	// setup code to configure a metric exactly once.
+	//--------------------------------------------------------------------------------
+	GetOrCreateCounter(opts CounterOpts, level Level) (Counter, bool)
+	GetOrCreateCounterVec(opts CounterVecOpts, level Level) (CounterVec, bool)
+	GetOrCreateGauge(opts GaugeOpts, level Level) (Gauge, bool)
+	GetOrCreateGaugeVec(opts GaugeVecOpts, level Level) (GaugeVec, bool)
+	GetOrCreateHistogram(opts HistogramOpts, level Level) (Histogram, bool)
+	GetOrCreateHistogramVec(opts HistogramVecOpts, level Level) (HistogramVec, bool)
+	GetOrCreateSummary(opts SummaryOpts, level Level) (Summary, bool)
+	GetOrCreateSummaryVec(opts SummaryVecOpts, level Level) (SummaryVec, bool)
+	GetOrCreateTimer(opts TimerOpts, level Level) (Timer, bool)
+	GetOrCreateTimerVec(opts TimerVecOpts, level Level) (TimerVec, bool)
+	GetOrCreateCache(opts CacheOpts, level Level) (Cache, bool)
+	GetOrCreateCacheVec(opts CacheVecOpts, level Level) (CacheVec, bool)
+	GetOrCreatePool(opts PoolOpts, level Level) (Pool, bool)
+	GetOrCreatePoolVec(opts PoolVecOpts, level Level) (PoolVec, bool)
+	GetOrCreateCircuitBreaker(opts CircuitBreakerOpts, level Level) (CircuitBreaker, bool)
+	GetOrCreateCircuitBreakerVec(opts CircuitBreakerVecOpts, level Level) (CircuitBreakerVec, bool)
+	GetOrCreateQueue(opts QueueOpts, level Level) (Queue, bool)
+	GetOrCreateQueueVec(opts QueueVecOpts, level Level) (QueueVec, bool)
+	GetOrCreateThroughput(opts ThroughputOpts, level Level) (Throughput, bool)
+	GetOrCreateDistributionGauge(opts DistributionGaugeOpts, level Level) (DistributionGauge, bool)
+	GetOrCreateMultiResHistogram(opts MultiResHistogramOpts, level Level) (MultiResHistogram, bool)
+	GetOrCreateDependency(opts DependencyOpts, level Level) (Dependency, bool)
 }
 
 // Factory creates metrics with the appropriate [Level]
@@ -42,6 +171,15 @@ type Group interface {
 // A factory may create any type of metric implementation, but
 // will typically create [switchableMetric]s that can switch between
 // the corresponding base adapter wrapper and a noop implementation.
+//
+// Requesting a metric whose name already exists returns the existing metric.
+// If the requested level or help differ from those it was created with, the
+// registry's [RedeclarePolicy] applies, by default keeping the existing metric
+// as it is and reporting a warning (see [WithRedeclarePolicy]).
+//
+// Basic metrics and the components of composite metrics share the backend
+// names of a group: creating one named like another panics. Creating a metric
+// with an empty name panics with [ErrEmptyMetricName].
 type Factory interface {
 	// Counter creates a counter with the given level and mask
 	Counter(opts CounterOpts, level Level) Counter
@@ -49,9 +187,55 @@ type Factory interface {
 	// CounterVec creates a label-vectorized counter with the given level and mask
 	CounterVec(opts CounterVecOpts, level Level) CounterVec
 
+	// CounterGroup creates a counter that reports the sum of members as a
+	// single series.
+	//
+	// The backend reads the sum at collection time if it is a
+	// [CounterFuncBackend], otherwise the sum is sampled every
+	// [CounterGroupSampleInterval] until the counter is stopped, see
+	// [Stoppable], and is read as 0 while the counter is disabled, like a
+	// [Factory.CounterFunc]. Members are read regardless of their level, and
+	// are typically created with [BasicMetricOpts.FromComposite] set, so they
+	// aren't exported themselves.
+	CounterGroup(opts CounterOpts, level Level, members ...Counter) CounterGroup
+
+	// CounterFunc creates a counter whose value is returned by fn, which must
+	// not decrease, e.g. a count kept by a library.
+	//
+	// The backend calls fn at collection time if it is a [CounterFuncBackend].
+	// Push-based backends implementing it (e.g. StatsD) call fn on every flush
+	// instead, and fn is sampled every [FuncSampleInterval] on other backends,
+	// until the counter is stopped, see [Stoppable]. fn is only read by the
+	// backend once the level of the counter is enabled, and while the counter
+	// is disabled by the group (e.g. paused, or masked out) the backend reads
+	// 0 and samples are skipped.
+	CounterFunc(opts CounterOpts, fn func() float64, level Level) CounterFunc
+
 	// Gauge creates a gauge with the given level and mask
 	Gauge(opts GaugeOpts, level Level) Gauge
 
+	// GaugeFromAtomic creates a gauge that reflects a caller owned value.
+	//
+	// The backend reads value at collection time if it is a [GaugeFuncBackend],
+	// otherwise value is sampled every [AtomicGaugeSampleInterval] until the
+	// gauge is stopped, see [Stoppable], and is read as 0 while the gauge is
+	// disabled, like a [Factory.GaugeFunc]. Operations on the gauge write
+	// through to value.
+	GaugeFromAtomic(opts GaugeOpts, level Level, value *atomic.Int64) Gauge
+
+	// GaugeFunc creates a gauge whose value is returned by fn, e.g. the length
+	// of a queue or the number of goroutines, for values cheaper to read when
+	// collected than to set on every change.
+	//
+	// The backend calls fn at collection time if it is a [GaugeFuncBackend].
+	// Push-based backends implementing it (e.g. StatsD) call fn on every flush
+	// instead, and fn is sampled every [FuncSampleInterval] on other backends,
+	// until the gauge is stopped, see [Stoppable]. fn is only read by the
+	// backend once the level of the gauge is enabled, and while the gauge is
+	// disabled by the group (e.g. paused, or masked out) the backend reads 0
+	// and samples are skipped.
+	GaugeFunc(opts GaugeOpts, fn func() float64, level Level) GaugeFunc
+
 	// GaugeVec creates a label-vectorized gauge with the given level and mask
 	GaugeVec(opts GaugeVecOpts, level Level) GaugeVec
 
@@ -100,6 +284,18 @@ type Factory interface {
 
 	// QueueVec creates a label-vectorized queue with the given level and mask
 	QueueVec(opts QueueVecOpts, level Level) QueueVec
+
+	// Throughput creates throughput metrics with the given level and mask
+	Throughput(opts ThroughputOpts, level Level) Throughput
+
+	// DistributionGauge creates a distribution gauge with the given level and mask
+	DistributionGauge(opts DistributionGaugeOpts, level Level) DistributionGauge
+
+	// MultiResHistogram creates a multi-resolution histogram with the given level and mask
+	MultiResHistogram(opts MultiResHistogramOpts, level Level) MultiResHistogram
+
+	// Dependency creates outbound call metrics with the given level and mask
+	Dependency(opts DependencyOpts, level Level) Dependency
 }
 
 //--------------------------------------------------------------------------------
@@ -114,7 +310,27 @@ type group struct {
 	basics     map[string]SwitchableMetric
 	composites map[string]SwitchableMetric
 	noops      map[string]MetricType
+	components map[string]string // Map of component backend name to the name of its tracked composite
 	minLevel   Level
+	tags       tagFilter     // Disabled tags, see [group.DisableTags]
+	mask       maskFilter    // Enabled categories, see [group.SetGroupMask]
+	names      *nameClaims   // Backend names claimed by the groups of the registry, if any
+	onWarning  func(error)   // Called with non-fatal metric problems, if set
+	sealed     *atomic.Bool  // Set once the registry is sealed, if any
+	async      *asyncEmitter // Applies the writes of async metrics, if set
+
+	droppedOnce sync.Once
+	dropped     CounterVecAdapter // The [DroppedSeriesMetricName] counter vec, once created
+
+	redeclarePolicy RedeclarePolicy // Applied to metrics requested again with different opts
+
+	shareComponents bool                        // If set, components are shared, see [WithSharedComponents]
+	sharedMu        sync.Mutex                  // Guards shared, taken after mu when both are held
+	shared          map[string]SwitchableMetric // Map of backend name to the metric shared under it
+
+	parent        *group   // Parent of a sub group, see [Registry.NewSubGroup]
+	children      []*group // Sub groups of the group
+	inheritsLevel bool     // If set, the level follows that of the parent
 }
 
 func newGroup(backend Backend, name string, level Level) *group {
@@ -126,31 +342,246 @@ func newGroup(backend Backend, name string, level Level) *group {
 		basics:     make(map[string]SwitchableMetric),
 		composites: make(map[string]SwitchableMetric),
 		noops:      make(map[string]MetricType),
+		components: make(map[string]string),
+		shared:     make(map[string]SwitchableMetric),
 	}
 }
 
+// SetGroupLevel sets the level of the group explicitly, and cascades it to
+// the sub groups inheriting their level, see [Registry.NewSubGroup]
 func (g *group) SetGroupLevel(level Level, opts LevelOpts) {
+	g.mu.Lock()
+	g.inheritsLevel = false
+	g.mu.Unlock()
+
+	g.applyLevel(level, opts)
+	g.cascadeLevel(level, opts)
+}
+
+// cascadeLevel sets the level of the sub groups of the group that inherit
+// their level, and of their own inheriting sub groups
+func (g *group) cascadeLevel(level Level, opts LevelOpts) {
+	g.mu.RLock()
+	children := slices.Clone(g.children)
+	g.mu.RUnlock()
+
+	for _, child := range children {
+		child.mu.RLock()
+		inherits := child.inheritsLevel
+		child.mu.RUnlock()
+
+		if inherits {
+			child.applyLevel(level, opts)
+			child.cascadeLevel(level, opts)
+		}
+	}
+}
+
+// parentGroup returns the parent of a sub group, or nil
+func (g *group) parentGroup() *group {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.parent
+}
+
+// removeChild forgets the sub group child, e.g. once it is deleted
+func (g *group) removeChild(child *group) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.children = slices.DeleteFunc(g.children, func(c *group) bool { return c == child })
+}
+
+// applyLevel sets the level of the group and of its metrics, leaving whether
+// the level is inherited as it is
+func (g *group) applyLevel(level Level, opts LevelOpts) {
+	g.mu.Lock()
 	g.minLevel = level
+	g.mu.Unlock()
 
-	if opts.ReplaceNoops && level.Enabled(g.minLevel) {
+	if opts.ReclaimDisabled {
+		g.reclaimDisabled()
+	}
+	if opts.ReplaceNoops {
 		g.convertNoops()
 	} else {
-		for _, metric := range g.composites {
-			metric.SetLevel(level)
+		// Snapshot under the lock, as the level of a metric must not be set
+		// with it held, see [group.relevel]
+		g.mu.RLock()
+		metrics := slices.Collect(maps.Values(g.composites))
+		metrics = slices.AppendSeq(metrics, maps.Values(g.basics))
+		g.mu.RUnlock()
+
+		for _, metric := range metrics {
+			metric.setImplLevel(level)
 		}
+	}
 
-		for _, metric := range g.basics {
-			metric.SetLevel(level)
-		}
+	g.cacheEnabled()
+}
+
+// cacheEnabled eagerly recomputes the cached enabled state of every tracked
+// metric for the group's level, see [baseMetric.groupDisabled], so that the
+// first operations after a level change don't pay for it
+func (g *group) cacheEnabled() {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	for _, metric := range g.composites {
+		metric.cacheEnabled(g.minLevel)
+	}
+	for _, metric := range g.basics {
+		metric.cacheEnabled(g.minLevel)
 	}
 }
 
+// SetGroupMask sets the categories of metrics enabled in the group
+func (g *group) SetGroupMask(mask Mask) {
+	g.mask.set(mask)
+}
+
 // Context returns a context representation of this group
 func (g *group) Context() Context {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 
-	return NewContext(g.minLevel)
+	return NewContext(g.minLevel).WithMask(g.mask.get())
+}
+
+// BackendSupports returns true if the backend of the group records metrics of the kind
+func (g *group) BackendSupports(kind MetricKind) bool {
+	return backendSupports(g.backend, kind)
+}
+
+// supports returns an error wrapping [ErrUnsupportedMetric] if the backend of
+// the group doesn't support the kind of the metric name, see [Group.TryCounter]
+func (g *group) supports(kind MetricKind, name string) error {
+	if !g.BackendSupports(kind) {
+		return fmt.Errorf("%w: %s %q of group %q, backend %q", ErrUnsupportedMetric, kind, name, g.name, g.backend.Name())
+	}
+	return nil
+}
+
+// mustSupport panics with the error of [group.supports], see [Group.MustCounter]
+func (g *group) mustSupport(kind MetricKind, name string) {
+	if err := g.supports(kind, name); err != nil {
+		panic(err)
+	}
+}
+
+func (g *group) MustCounter(opts CounterOpts, level Level) Counter {
+	g.mustSupport(MetricKindCounter, opts.Name)
+	return g.Counter(opts, level)
+}
+
+func (g *group) MustCounterVec(opts CounterVecOpts, level Level) CounterVec {
+	g.mustSupport(MetricKindCounter, opts.Name)
+	return g.CounterVec(opts, level)
+}
+
+func (g *group) MustGauge(opts GaugeOpts, level Level) Gauge {
+	g.mustSupport(MetricKindGauge, opts.Name)
+	return g.Gauge(opts, level)
+}
+
+func (g *group) MustGaugeVec(opts GaugeVecOpts, level Level) GaugeVec {
+	g.mustSupport(MetricKindGauge, opts.Name)
+	return g.GaugeVec(opts, level)
+}
+
+func (g *group) MustHistogram(opts HistogramOpts, level Level) Histogram {
+	g.mustSupport(MetricKindHistogram, opts.Name)
+	return g.Histogram(opts, level)
+}
+
+func (g *group) MustHistogramVec(opts HistogramVecOpts, level Level) HistogramVec {
+	g.mustSupport(MetricKindHistogram, opts.Name)
+	return g.HistogramVec(opts, level)
+}
+
+func (g *group) MustSummary(opts SummaryOpts, level Level) Summary {
+	g.mustSupport(MetricKindSummary, opts.Name)
+	return g.Summary(opts, level)
+}
+
+func (g *group) MustSummaryVec(opts SummaryVecOpts, level Level) SummaryVec {
+	g.mustSupport(MetricKindSummary, opts.Name)
+	return g.SummaryVec(opts, level)
+}
+
+func (g *group) TryCounter(opts CounterOpts, level Level) (Counter, error) {
+	if err := g.supports(MetricKindCounter, opts.Name); err != nil {
+		return nil, err
+	}
+	return g.Counter(opts, level), nil
+}
+
+func (g *group) TryCounterVec(opts CounterVecOpts, level Level) (CounterVec, error) {
+	if err := g.supports(MetricKindCounter, opts.Name); err != nil {
+		return nil, err
+	}
+	return g.CounterVec(opts, level), nil
+}
+
+func (g *group) TryGauge(opts GaugeOpts, level Level) (Gauge, error) {
+	if err := g.supports(MetricKindGauge, opts.Name); err != nil {
+		return nil, err
+	}
+	return g.Gauge(opts, level), nil
+}
+
+func (g *group) TryGaugeVec(opts GaugeVecOpts, level Level) (GaugeVec, error) {
+	if err := g.supports(MetricKindGauge, opts.Name); err != nil {
+		return nil, err
+	}
+	return g.GaugeVec(opts, level), nil
+}
+
+func (g *group) TryHistogram(opts HistogramOpts, level Level) (Histogram, error) {
+	if err := g.supports(MetricKindHistogram, opts.Name); err != nil {
+		return nil, err
+	}
+	return g.Histogram(opts, level), nil
+}
+
+func (g *group) TryHistogramVec(opts HistogramVecOpts, level Level) (HistogramVec, error) {
+	if err := g.supports(MetricKindHistogram, opts.Name); err != nil {
+		return nil, err
+	}
+	return g.HistogramVec(opts, level), nil
+}
+
+func (g *group) TrySummary(opts SummaryOpts, level Level) (Summary, error) {
+	if err := g.supports(MetricKindSummary, opts.Name); err != nil {
+		return nil, err
+	}
+	return g.Summary(opts, level), nil
+}
+
+func (g *group) TrySummaryVec(opts SummaryVecOpts, level Level) (SummaryVec, error) {
+	if err := g.supports(MetricKindSummary, opts.Name); err != nil {
+		return nil, err
+	}
+	return g.SummaryVec(opts, level), nil
+}
+
+// EnableTags re-enables metrics tagged with any of the tags
+func (g *group) EnableTags(tags ...string) {
+	g.tags.enable(tags...)
+}
+
+// DisableTags disables metrics tagged with any of the tags
+func (g *group) DisableTags(tags ...string) {
+	g.tags.disable(tags...)
+}
+
+// level returns the group's current minimum level
+func (g *group) level() Level {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.minLevel
 }
 
 func (g *group) Metric(name string) Metric {
@@ -172,6 +603,103 @@ func (g *group) Metric(name string) Metric {
 	return nil
 }
 
+// MetricAs returns the metric of g with the given name as a T, like a type
+// assertion of [Group.Metric] that returns an error instead of panicking.
+// Like [Group.Metric], name is matched against [Metric.Name].
+//
+//	requests, err := umami.MetricAs[umami.Counter](group, "web_requests")
+//
+// Returns [ErrMetricNotFound] if g tracks no such metric, and
+// [ErrMetricTypeMismatch] if the metric is not a T.
+func MetricAs[T Metric](g Group, name string) (T, error) {
+	var zero T
+
+	metric := g.Metric(name)
+	if metric == nil {
+		return zero, fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+
+	typed, ok := metric.(T)
+	if !ok {
+		return zero, fmt.Errorf("%w: %s is a %T, not a %v", ErrMetricTypeMismatch, name, metric, reflect.TypeFor[T]())
+	}
+	return typed, nil
+}
+
+// Remove stops tracking the named metric, and stops it if it is [Stoppable]
+func (g *group) Remove(name string) bool {
+	g.mu.Lock()
+	metric := g.untrack(name)
+	g.mu.Unlock()
+
+	if metric == nil {
+		return false
+	}
+	stopMetric(metric)
+	return true
+}
+
+// untrack removes the named metric from the tracking maps, and releases its
+// backend names, or those of its components, so that other groups may use
+// them. Returns the metric, or nil if it is not tracked. Callers must hold the
+// write lock of [group.mu].
+func (g *group) untrack(name string) Metric {
+	for _, tracked := range []map[string]SwitchableMetric{g.basics, g.composites} {
+		for key, metric := range tracked {
+			if metric.Name() == name {
+				delete(tracked, key)
+				delete(g.noops, key)
+				g.releaseName(key)
+				if _, ok := metric.(CompositeMetric); ok {
+					maps.DeleteFunc(g.components, func(component, composite string) bool {
+						if composite != key {
+							return false
+						}
+						g.releaseName(component)
+						return true
+					})
+				}
+				return metric
+			}
+		}
+	}
+
+	return nil
+}
+
+// releaseName releases the claim of the group on the backend name of an
+// untracked metric, unless it is shared with other metrics of the group, see
+// [WithSharedComponents]
+func (g *group) releaseName(name string) {
+	if g.names != nil && !g.isShared(name) {
+		g.names.releaseName(name, g.name)
+	}
+}
+
+// close stops every [Stoppable] metric of the group, see [Registry.DeleteGroup]
+func (g *group) close() {
+	g.mu.RLock()
+	metrics := make([]Metric, 0, len(g.basics)+len(g.composites))
+	for _, metric := range g.basics {
+		metrics = append(metrics, metric)
+	}
+	for _, metric := range g.composites {
+		metrics = append(metrics, metric)
+	}
+	g.mu.RUnlock()
+
+	for _, metric := range metrics {
+		stopMetric(metric)
+	}
+}
+
+// stopMetric stops metric if it is [Stoppable]
+func stopMetric(metric Metric) {
+	if stoppable, ok := metric.(Stoppable); ok {
+		stoppable.Stop()
+	}
+}
+
 //--------------------------------------------------------------------------------
 // Basic Metric Factory Functions
 //
@@ -188,803 +716,1869 @@ func (g *group) Metric(name string) Metric {
 
 // Counter creates a counter with the given level
 func (g *group) Counter(opts CounterOpts, level Level) Counter {
-	opts.Name = g.name + "_" + opts.Name
-
-	if !opts.FromComposite {
-		if m := g.getBasic(opts.Name); m != nil {
-			return m.(Counter)
-		}
-	}
+	counter, _ := g.GetOrCreateCounter(opts, level)
+	return counter
+}
 
-	var impl Counter
-	var isTrackedNoop bool
+// GetOrCreateCounter is like [group.Counter], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreateCounter(opts CounterOpts, level Level) (Counter, bool) {
+	opts.Name = g.metricName(opts.Name, opts.NoPrefix)
 
-	if !level.Enabled(g.minLevel) {
-		impl = newNoopCounter(opts, level)
-		isTrackedNoop = !opts.FromComposite
-	} else {
-		impl = &baseCounter{
-			baseMetric: baseMetric{
-				name:  opts.Name,
-				help:  opts.Help,
-				level: level,
-			},
-			adapter: g.backend.Counter(opts),
+	return getOrCreate[Counter](g, opts, opts.Name, opts.Help, level, MetricTypeBasic, !opts.FromComposite, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableCounter(newNoopCounter(opts, level), opts), !opts.FromComposite
 		}
-	}
+		return newSwitchableCounter(g.newBaseCounter(opts, level), opts), false
+	})
+}
 
-	switchable := newSwitchableCounter(impl, opts)
+// CounterVec creates a counter vector with the given level
+func (g *group) CounterVec(opts CounterVecOpts, level Level) CounterVec {
+	counterVec, _ := g.GetOrCreateCounterVec(opts, level)
+	return counterVec
+}
 
-	if !opts.FromComposite {
-		g.track(switchable, isTrackedNoop)
-	}
+// GetOrCreateCounterVec is like [group.CounterVec], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreateCounterVec(opts CounterVecOpts, level Level) (CounterVec, bool) {
+	opts.Name = g.metricName(opts.Name, opts.NoPrefix)
+	g.reserveDroppedSeries(opts.MaxCardinality)
 
-	return switchable
+	return getOrCreate[CounterVec](g, opts, opts.Name, opts.Help, level, MetricTypeBasic, !opts.FromComposite, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableCounterVec(newNoopCounterVec(opts, level), opts), !opts.FromComposite
+		}
+		return newSwitchableCounterVec(g.newBaseCounterVec(opts, level), opts), false
+	})
 }
 
-func (g *group) CounterVec(opts CounterVecOpts, level Level) CounterVec {
-	opts.Name = g.name + "_" + opts.Name
+// CounterGroupSampleInterval is how often the sums of counters created by
+// [Factory.CounterGroup] are sampled on backends that are not
+// a [CounterFuncBackend]
+var CounterGroupSampleInterval = 10 * time.Second
 
-	if !opts.FromComposite {
-		if m := g.getBasic(opts.Name); m != nil {
-			return m.(CounterVec)
-		}
-	}
+// counterGroupOpts are the constructor opts of a counter created by
+// [group.CounterGroup]
+type counterGroupOpts struct {
+	CounterOpts
+	members []Counter
+}
 
-	var counterVec CounterVec
-	var isTrackedNoop bool
+// CounterGroup creates a counter summing members with the given level
+func (g *group) CounterGroup(opts CounterOpts, level Level, members ...Counter) CounterGroup {
+	opts.Name = g.metricName(opts.Name, opts.NoPrefix)
+	gopts := counterGroupOpts{CounterOpts: opts, members: slices.Clone(members)}
 
-	if !level.Enabled(g.minLevel) {
-		counterVec = newNoopCounterVec(opts, level)
-		isTrackedNoop = !opts.FromComposite
-	} else {
-		counterVec = &baseCounterVec{
-			baseMetric: baseMetric{
-				name:  opts.Name,
-				help:  opts.Help,
-				level: level,
-			},
-			adapter: g.backend.CounterVec(opts),
+	counterGroup, _ := getOrCreate[CounterGroup](g, opts, opts.Name, opts.Help, level, MetricTypeBasic, !opts.FromComposite, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableCounterGroup(newNoopCounterGroup(gopts, level), gopts), !opts.FromComposite
 		}
-	}
+		return newSwitchableCounterGroup(g.newBaseCounterGroup(gopts, level), gopts), false
+	})
+	return counterGroup
+}
 
-	switchable := newSwitchableCounterVec(counterVec, opts)
+// FuncSampleInterval is how often the functions of metrics created by
+// [Factory.GaugeFunc] and [Factory.CounterFunc] are sampled on backends that
+// are not a [GaugeFuncBackend] or [CounterFuncBackend]
+var FuncSampleInterval = 10 * time.Second
 
-	if !opts.FromComposite {
-		g.track(switchable, isTrackedNoop)
-	}
+// counterFuncOpts are the constructor opts of a counter created by
+// [group.CounterFunc]
+type counterFuncOpts struct {
+	CounterOpts
+	fn func() float64
+}
 
-	return switchable
+// CounterFunc creates a counter reading fn with the given level
+func (g *group) CounterFunc(opts CounterOpts, fn func() float64, level Level) CounterFunc {
+	opts.Name = g.metricName(opts.Name, opts.NoPrefix)
+	fopts := counterFuncOpts{CounterOpts: opts, fn: fn}
+
+	counterFunc, _ := getOrCreate[CounterFunc](g, opts, opts.Name, opts.Help, level, MetricTypeBasic, !opts.FromComposite, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableCounterFunc(newNoopCounterFunc(fopts, level), fopts), !opts.FromComposite
+		}
+		return newSwitchableCounterFunc(g.newBaseCounterFunc(fopts, level), fopts), false
+	})
+	return counterFunc
 }
 
 // Gauge creates a gauge with the given level
 func (g *group) Gauge(opts GaugeOpts, level Level) Gauge {
-	opts.Name = g.name + "_" + opts.Name
-
-	if !opts.FromComposite {
-		if m := g.getBasic(opts.Name); m != nil {
-			return m.(Gauge)
-		}
-	}
+	gauge, _ := g.GetOrCreateGauge(opts, level)
+	return gauge
+}
 
-	var gauge Gauge
-	var isTrackedNoop bool
+// GetOrCreateGauge is like [group.Gauge], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreateGauge(opts GaugeOpts, level Level) (Gauge, bool) {
+	opts.Name = g.metricName(opts.Name, opts.NoPrefix)
 
-	if !level.Enabled(g.minLevel) {
-		gauge = newNoopGauge(opts, level)
-		isTrackedNoop = !opts.FromComposite
-	} else {
-		gauge = &baseGauge{
-			baseMetric: baseMetric{
-				name:  opts.Name,
-				help:  opts.Help,
-				level: level,
-			},
-			adapter: g.backend.Gauge(opts),
+	return getOrCreate[Gauge](g, opts, opts.Name, opts.Help, level, MetricTypeBasic, !opts.FromComposite, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableGauge(newNoopGauge(opts, level), opts), !opts.FromComposite
 		}
-	}
-
-	switchable := newSwitchableGauge(gauge, opts)
+		return newSwitchableGauge(g.newBaseGauge(opts, level), opts), false
+	})
+}
 
-	if !opts.FromComposite {
-		g.track(switchable, isTrackedNoop)
-	}
+// AtomicGaugeSampleInterval is how often gauges created by
+// [Factory.GaugeFromAtomic] are sampled on backends that are not
+// a [GaugeFuncBackend]
+var AtomicGaugeSampleInterval = 10 * time.Second
 
-	return switchable
+// atomicGaugeOpts are the constructor opts of a gauge created by
+// [group.GaugeFromAtomic]
+type atomicGaugeOpts struct {
+	GaugeOpts
+	value *atomic.Int64
 }
 
-// GaugeVec creates a gauge vector with the given level
-func (g *group) GaugeVec(opts GaugeVecOpts, level Level) GaugeVec {
-	opts.Name = g.name + "_" + opts.Name
+// GaugeFromAtomic creates a gauge reflecting value with the given level
+func (g *group) GaugeFromAtomic(opts GaugeOpts, level Level, value *atomic.Int64) Gauge {
+	opts.Name = g.metricName(opts.Name, opts.NoPrefix)
+	aopts := atomicGaugeOpts{GaugeOpts: opts, value: value}
 
-	if !opts.FromComposite {
-		if m := g.getBasic(opts.Name); m != nil {
-			return m.(GaugeVec)
+	gauge, _ := getOrCreate[Gauge](g, opts, opts.Name, opts.Help, level, MetricTypeBasic, !opts.FromComposite, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableAtomicGauge(newNoopGauge(opts, level), aopts), !opts.FromComposite
 		}
-	}
+		return newSwitchableAtomicGauge(g.newBaseAtomicGauge(aopts, level), aopts), false
+	})
+	return gauge
+}
 
-	var gaugeVec GaugeVec
-	var isTrackedNoop bool
+// gaugeFuncOpts are the constructor opts of a gauge created by
+// [group.GaugeFunc]
+type gaugeFuncOpts struct {
+	GaugeOpts
+	fn func() float64
+}
 
-	if !level.Enabled(g.minLevel) {
-		gaugeVec = newNoopGaugeVec(opts, level)
-		isTrackedNoop = !opts.FromComposite
-	} else {
-		gaugeVec = &baseGaugeVec{
-			baseMetric: baseMetric{
-				name:  opts.Name,
-				help:  opts.Help,
-				level: level,
-			},
-			adapter: g.backend.GaugeVec(opts),
+// GaugeFunc creates a gauge reading fn with the given level
+func (g *group) GaugeFunc(opts GaugeOpts, fn func() float64, level Level) GaugeFunc {
+	opts.Name = g.metricName(opts.Name, opts.NoPrefix)
+	fopts := gaugeFuncOpts{GaugeOpts: opts, fn: fn}
+
+	gaugeFunc, _ := getOrCreate[GaugeFunc](g, opts, opts.Name, opts.Help, level, MetricTypeBasic, !opts.FromComposite, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableGaugeFunc(newNoopGaugeFunc(fopts, level), fopts), !opts.FromComposite
 		}
-	}
+		return newSwitchableGaugeFunc(g.newBaseGaugeFunc(fopts, level), fopts), false
+	})
+	return gaugeFunc
+}
 
-	switchable := newSwitchableGaugeVec(gaugeVec, opts)
+// GaugeVec creates a gauge vector with the given level
+func (g *group) GaugeVec(opts GaugeVecOpts, level Level) GaugeVec {
+	gaugeVec, _ := g.GetOrCreateGaugeVec(opts, level)
+	return gaugeVec
+}
 
-	if !opts.FromComposite {
-		g.track(switchable, isTrackedNoop)
-	}
+// GetOrCreateGaugeVec is like [group.GaugeVec], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreateGaugeVec(opts GaugeVecOpts, level Level) (GaugeVec, bool) {
+	opts.Name = g.metricName(opts.Name, opts.NoPrefix)
+	g.reserveDroppedSeries(opts.MaxCardinality)
 
-	return switchable
+	return getOrCreate[GaugeVec](g, opts, opts.Name, opts.Help, level, MetricTypeBasic, !opts.FromComposite, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableGaugeVec(newNoopGaugeVec(opts, level), opts), !opts.FromComposite
+		}
+		return newSwitchableGaugeVec(g.newBaseGaugeVec(opts, level), opts), false
+	})
 }
 
 // Histogram creates a histogram with the given level
 func (g *group) Histogram(opts HistogramOpts, level Level) Histogram {
-	opts.Name = g.name + "_" + opts.Name
-
-	if !opts.FromComposite {
-		if m := g.getBasic(opts.Name); m != nil {
-			return m.(Histogram)
-		}
-	}
+	histogram, _ := g.GetOrCreateHistogram(opts, level)
+	return histogram
+}
 
-	var histogram Histogram
-	var isTrackedNoop bool
+// GetOrCreateHistogram is like [group.Histogram], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreateHistogram(opts HistogramOpts, level Level) (Histogram, bool) {
+	opts.Name = g.metricName(opts.Name, opts.NoPrefix)
+	opts.Buckets = resolveBuckets(opts.Buckets, opts.BucketPreset)
 
-	if !level.Enabled(g.minLevel) {
-		histogram = newNoopHistogram(opts, level)
-		isTrackedNoop = !opts.FromComposite
-	} else {
-		histogram = &baseHistogram{
-			baseMetric: baseMetric{
-				name:  opts.Name,
-				help:  opts.Help,
-				level: level,
-			},
-			adapter: g.backend.Histogram(opts),
+	return getOrCreate[Histogram](g, opts, opts.Name, opts.Help, level, MetricTypeBasic, !opts.FromComposite, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableHistogram(newNoopHistogram(opts, level), opts), !opts.FromComposite
 		}
-	}
-
-	switchable := newSwitchableHistogram(histogram, opts)
-
-	if !opts.FromComposite {
-		g.track(switchable, isTrackedNoop)
-	}
-
-	return switchable
+		return newSwitchableHistogram(g.newBaseHistogram(opts, level), opts), false
+	})
 }
 
 // HistogramVec creates a histogram vector with the given level
 func (g *group) HistogramVec(opts HistogramVecOpts, level Level) HistogramVec {
-	opts.Name = g.name + "_" + opts.Name
+	histogramVec, _ := g.GetOrCreateHistogramVec(opts, level)
+	return histogramVec
+}
+
+// GetOrCreateHistogramVec is like [group.HistogramVec], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreateHistogramVec(opts HistogramVecOpts, level Level) (HistogramVec, bool) {
+	opts.Name = g.metricName(opts.Name, opts.NoPrefix)
+	g.reserveDroppedSeries(opts.MaxCardinality)
+	opts.Buckets = resolveBuckets(opts.Buckets, opts.BucketPreset)
 
-	if !opts.FromComposite {
-		if m := g.getBasic(opts.Name); m != nil {
-			return m.(HistogramVec)
+	return getOrCreate[HistogramVec](g, opts, opts.Name, opts.Help, level, MetricTypeBasic, !opts.FromComposite, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableHistogramVec(newNoopHistogramVec(opts, level), opts), !opts.FromComposite
 		}
-	}
+		return newSwitchableHistogramVec(g.newBaseHistogramVec(opts, level), opts), false
+	})
+}
 
-	var histogramVec HistogramVec
-	var isTrackedNoop bool
+// Summary creates a summary with the given level
+func (g *group) Summary(opts SummaryOpts, level Level) Summary {
+	summary, _ := g.GetOrCreateSummary(opts, level)
+	return summary
+}
 
-	if !level.Enabled(g.minLevel) {
-		histogramVec = newNoopHistogramVec(opts, level)
-		isTrackedNoop = !opts.FromComposite
-	} else {
-		histogramVec = &baseHistogramVec{
-			baseMetric: baseMetric{
-				name:  opts.Name,
-				help:  opts.Help,
-				level: level,
-			},
-			adapter: g.backend.HistogramVec(opts),
+// GetOrCreateSummary is like [group.Summary], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreateSummary(opts SummaryOpts, level Level) (Summary, bool) {
+	opts.Name = g.metricName(opts.Name, opts.NoPrefix)
+	opts.Objectives = g.mergeObjectives(opts.Name, opts.Objectives, opts.ObjectiveSets)
+	opts.ObjectiveSets = nil
+
+	return getOrCreate[Summary](g, opts, opts.Name, opts.Help, level, MetricTypeBasic, !opts.FromComposite, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableSummary(g, newNoopSummary(opts, level), opts), !opts.FromComposite
 		}
-	}
+		return newSwitchableSummary(g, g.newBaseSummary(opts, level), opts), false
+	})
+}
 
-	switchable := newSwitchableHistogramVec(histogramVec, opts)
+// SummaryVec creates a summary vector with the given level
+func (g *group) SummaryVec(opts SummaryVecOpts, level Level) SummaryVec {
+	summaryVec, _ := g.GetOrCreateSummaryVec(opts, level)
+	return summaryVec
+}
 
-	if !opts.FromComposite {
-		g.track(switchable, isTrackedNoop)
-	}
+// GetOrCreateSummaryVec is like [group.SummaryVec], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreateSummaryVec(opts SummaryVecOpts, level Level) (SummaryVec, bool) {
+	opts.Name = g.metricName(opts.Name, opts.NoPrefix)
+	g.reserveDroppedSeries(opts.MaxCardinality)
+	opts.Objectives = g.mergeObjectives(opts.Name, opts.Objectives, opts.ObjectiveSets)
+	opts.ObjectiveSets = nil
+
+	return getOrCreate[SummaryVec](g, opts, opts.Name, opts.Help, level, MetricTypeBasic, !opts.FromComposite, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableSummaryVec(newNoopSummaryVec(opts, level), opts), !opts.FromComposite
+		}
+		return newSwitchableSummaryVec(g.newBaseSummaryVec(opts, level), opts), false
+	})
+}
 
-	return switchable
+//--------------------------------------------------------------------------------
+// Composite Metric Factory Functions
+//
+// Composite metrics are built on top of prime metrics, and so are tracked, but,
+// their prime components are not tracked individually. So, any call to
+// [group.SetGroupLevel] on a composite metric will not duplicate the calls to setting the
+// prime components levels.
+//--------------------------------------------------------------------------------
+
+// Timer creates a timer with the given level
+func (g *group) Timer(opts TimerOpts, level Level) Timer {
+	timer, _ := g.GetOrCreateTimer(opts, level)
+	return timer
 }
 
-// Summary creates a summary with the given level
-func (g *group) Summary(opts SummaryOpts, level Level) Summary {
-	opts.Name = g.name + "_" + opts.Name
+// GetOrCreateTimer is like [group.Timer], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreateTimer(opts TimerOpts, level Level) (Timer, bool) {
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.HistogramOpts, componentSuffixHistogram, "duration")
 
-	if !opts.FromComposite {
-		if m := g.getBasic(opts.Name); m != nil {
-			return m.(Summary)
+	return getOrCreate[Timer](g, opts, opts.Name, opts.Help, level, MetricTypeComposite, true, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableTimer(newNoopTimer(opts, level), opts), true
 		}
-	}
+		return newSwitchableTimer(g.newBaseTimer(opts, level), opts), false
+	})
+}
 
-	var summary Summary
-	var isTrackedNoop bool
+// TimerVec creates a timer vector with the given level
+func (g *group) TimerVec(opts TimerVecOpts, level Level) TimerVec {
+	timerVec, _ := g.GetOrCreateTimerVec(opts, level)
+	return timerVec
+}
 
-	if !level.Enabled(g.minLevel) {
-		summary = newNoopSummary(opts, level)
-		isTrackedNoop = !opts.FromComposite
-	} else {
-		summary = &baseSummary{
-			baseMetric: baseMetric{
-				name:  opts.Name,
-				help:  opts.Help,
-				level: level,
-			},
-			adapter: g.backend.Summary(opts),
+// GetOrCreateTimerVec is like [group.TimerVec], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreateTimerVec(opts TimerVecOpts, level Level) (TimerVec, bool) {
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.HistogramVecOpts, componentSuffixHistogram, "duration")
+
+	return getOrCreate[TimerVec](g, opts, opts.Name, opts.Help, level, MetricTypeComposite, true, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableTimerVec(newNoopTimerVec(opts, level), opts), true
 		}
-	}
+		return newSwitchableTimerVec(g.newBaseTimerVec(opts, level), opts), false
+	})
+}
 
-	switchable := newSwitchableSummary(summary, opts)
+// Cache creates cache metrics with the given level
+func (g *group) Cache(opts CacheOpts, level Level) Cache {
+	cache, _ := g.GetOrCreateCache(opts, level)
+	return cache
+}
 
-	if !opts.FromComposite {
-		g.track(switchable, isTrackedNoop)
-	}
+// GetOrCreateCache is like [group.Cache], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreateCache(opts CacheOpts, level Level) (Cache, bool) {
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.HitOpts, componentSuffixHit, "hits")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.MissOpts, componentSuffixMiss, "misses")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.SizeOpts, componentSuffixSize, "size")
 
-	return switchable
+	return getOrCreate[Cache](g, opts, opts.Name, opts.Help, level, MetricTypeComposite, true, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableCache(newNoopCache(opts, level), opts), true
+		}
+		return newSwitchableCache(g.newBaseCache(opts, level), opts), false
+	})
 }
 
-// SummaryVec creates a summary vector with the given level
-func (g *group) SummaryVec(opts SummaryVecOpts, level Level) SummaryVec {
-	opts.Name = g.name + "_" + opts.Name
+// CacheVec creates a cache vector with the given level
+func (g *group) CacheVec(opts CacheVecOpts, level Level) CacheVec {
+	cacheVec, _ := g.GetOrCreateCacheVec(opts, level)
+	return cacheVec
+}
+
+// GetOrCreateCacheVec is like [group.CacheVec], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreateCacheVec(opts CacheVecOpts, level Level) (CacheVec, bool) {
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.HitVecOpts, componentSuffixHit, "hits")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.MissVecOpts, componentSuffixMiss, "misses")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.SizeVecOpts, componentSuffixSize, "size")
 
-	if !opts.FromComposite {
-		if m := g.getBasic(opts.Name); m != nil {
-			return m.(SummaryVec)
+	return getOrCreate[CacheVec](g, opts, opts.Name, opts.Help, level, MetricTypeComposite, true, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableCacheVec(newNoopCacheVec(opts, level), opts), true
 		}
-	}
+		return newSwitchableCacheVec(g.newBaseCacheVec(opts, level), opts), false
+	})
+}
 
-	var summaryVec SummaryVec
-	var isTrackedNoop bool
+// Pool creates pool metrics with the given level
+func (g *group) Pool(opts PoolOpts, level Level) Pool {
+	pool, _ := g.GetOrCreatePool(opts, level)
+	return pool
+}
 
-	if !level.Enabled(g.minLevel) {
-		summaryVec = newNoopSummaryVec(opts, level)
-		isTrackedNoop = !opts.FromComposite
-	} else {
-		summaryVec = &baseSummaryVec{
-			baseMetric: baseMetric{
-				name:  opts.Name,
-				help:  opts.Help,
-				level: level,
-			},
-			adapter: g.backend.SummaryVec(opts),
+// GetOrCreatePool is like [group.Pool], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreatePool(opts PoolOpts, level Level) (Pool, bool) {
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.ActiveOpts, componentSuffixActive, "active")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.IdleOpts, componentSuffixIdle, "idle")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.AcquiredOpts, componentSuffixAcquired, "acquired")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.ReleasedOpts, componentSuffixReleased, "released")
+
+	return getOrCreate[Pool](g, opts, opts.Name, opts.Help, level, MetricTypeComposite, true, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchablePool(newNoopPool(opts, level), opts), true
 		}
-	}
+		return newSwitchablePool(g.newBasePool(opts, level), opts), false
+	})
+}
 
-	switchable := newSwitchableSummaryVec(summaryVec, opts)
+// PoolVec creates a pool vector with the given level
+func (g *group) PoolVec(opts PoolVecOpts, level Level) PoolVec {
+	poolVec, _ := g.GetOrCreatePoolVec(opts, level)
+	return poolVec
+}
 
-	if !opts.FromComposite {
-		g.track(switchable, isTrackedNoop)
-	}
+// GetOrCreatePoolVec is like [group.PoolVec], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreatePoolVec(opts PoolVecOpts, level Level) (PoolVec, bool) {
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.ActiveVecOpts, componentSuffixActive, "active")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.IdleVecOpts, componentSuffixIdle, "idle")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.AcquiredVecOpts, componentSuffixAcquired, "acquired")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.ReleasedVecOpts, componentSuffixReleased, "released")
+
+	return getOrCreate[PoolVec](g, opts, opts.Name, opts.Help, level, MetricTypeComposite, true, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchablePoolVec(newNoopPoolVec(opts, level), opts), true
+		}
+		return newSwitchablePoolVec(g.newBasePoolVec(opts, level), opts), false
+	})
+}
 
-	return switchable
+// CircuitBreaker creates circuit breaker metrics with the given level
+func (g *group) CircuitBreaker(opts CircuitBreakerOpts, level Level) CircuitBreaker {
+	circuitBreaker, _ := g.GetOrCreateCircuitBreaker(opts, level)
+	return circuitBreaker
 }
 
-//--------------------------------------------------------------------------------
-// Composite Metric Factory Functions
-//
-// Composite metrics are built on top of prime metrics, and so are tracked, but,
-// their prime components are not tracked individually. So, any call to
-// [group.SetGroupLevel] on a composite metric will not duplicate the calls to setting the
-// prime components levels.
-//--------------------------------------------------------------------------------
+// GetOrCreateCircuitBreaker is like [group.CircuitBreaker], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreateCircuitBreaker(opts CircuitBreakerOpts, level Level) (CircuitBreaker, bool) {
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.StateOpts, componentSuffixState, "state")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.SuccessOpts, componentSuffixSuccess, "successes")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.FailureOpts, componentSuffixFailure, "failures")
 
-// Timer creates a timer with the given level
-func (g *group) Timer(opts TimerOpts, level Level) Timer {
-	if m := g.getComposite(opts.Name); m != nil {
-		return m.(Timer)
-	}
+	return getOrCreate[CircuitBreaker](g, opts, opts.Name, opts.Help, level, MetricTypeComposite, true, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableCircuitBreaker(newNoopCircuitBreaker(opts, level), opts), true
+		}
+		return newSwitchableCircuitBreaker(g.newBaseCircuitBreaker(opts, level), opts), false
+	})
+}
 
-	var timer Timer
-	var isTrackedNoop bool
-	opts.HistogramOpts.FromComposite = true
+// CircuitBreakerVec creates a circuit breaker vector with the given level
+func (g *group) CircuitBreakerVec(opts CircuitBreakerVecOpts, level Level) CircuitBreakerVec {
+	circuitBreakerVec, _ := g.GetOrCreateCircuitBreakerVec(opts, level)
+	return circuitBreakerVec
+}
 
-	if !level.Enabled(g.minLevel) {
-		timer = newNoopTimer(opts, level)
-		isTrackedNoop = true
-	} else {
-		timer = &baseTimer{
-			baseCompositeMetric: baseCompositeMetric{
-				baseMetric: baseMetric{
-					name:  opts.Name,
-					help:  opts.Help,
-					level: level,
-				},
-			},
-			histogram: g.Histogram(opts.HistogramOpts, level),
-		}
-	}
+// GetOrCreateCircuitBreakerVec is like [group.CircuitBreakerVec], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreateCircuitBreakerVec(opts CircuitBreakerVecOpts, level Level) (CircuitBreakerVec, bool) {
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.StateVecOpts, componentSuffixState, "state")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.SuccessVecOpts, componentSuffixSuccess, "successes")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.FailureVecOpts, componentSuffixFailure, "failures")
 
-	switchable := newSwitchableTimer(timer, opts)
-	switchable.SetLevel(level)
+	return getOrCreate[CircuitBreakerVec](g, opts, opts.Name, opts.Help, level, MetricTypeComposite, true, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableCircuitBreakerVec(newNoopCircuitBreakerVec(opts, level), opts), true
+		}
+		return newSwitchableCircuitBreakerVec(g.newBaseCircuitBreakerVec(opts, level), opts), false
+	})
+}
 
-	g.track(switchable, isTrackedNoop)
+// Queue creates queue metrics with the given level
+func (g *group) Queue(opts QueueOpts, level Level) Queue {
+	queue, _ := g.GetOrCreateQueue(opts, level)
+	return queue
+}
 
-	return switchable
+// GetOrCreateQueue is like [group.Queue], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreateQueue(opts QueueOpts, level Level) (Queue, bool) {
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.DepthOpts, componentSuffixDepth, "depth")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.EnqueuedOpts, componentSuffixEnqueued, "enqueued")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.DequeuedOpts, componentSuffixDequeued, "dequeued")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.WaitTimeOpts, componentSuffixWaitTime, "wait time")
+
+	return getOrCreate[Queue](g, opts, opts.Name, opts.Help, level, MetricTypeComposite, true, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableQueue(newNoopQueue(opts, level), opts), true
+		}
+		return newSwitchableQueue(g.newBaseQueue(opts, level), opts), false
+	})
 }
 
-// TimerVec creates a timer vector with the given level
-func (g *group) TimerVec(opts TimerVecOpts, level Level) TimerVec {
-	if m := g.getComposite(opts.Name); m != nil {
-		return m.(TimerVec)
-	}
+// QueueVec creates a queue vector with the given level
+func (g *group) QueueVec(opts QueueVecOpts, level Level) QueueVec {
+	queueVec, _ := g.GetOrCreateQueueVec(opts, level)
+	return queueVec
+}
 
-	var timerVec TimerVec
-	var isTrackedNoop bool
+// GetOrCreateQueueVec is like [group.QueueVec], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreateQueueVec(opts QueueVecOpts, level Level) (QueueVec, bool) {
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.DepthVecOpts, componentSuffixDepth, "depth")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.EnqueuedVecOpts, componentSuffixEnqueued, "enqueued")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.DequeuedVecOpts, componentSuffixDequeued, "dequeued")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.WaitTimeVecOpts, componentSuffixWaitTime, "wait time")
+
+	return getOrCreate[QueueVec](g, opts, opts.Name, opts.Help, level, MetricTypeComposite, true, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableQueueVec(newNoopQueueVec(opts, level), opts), true
+		}
+		return newSwitchableQueueVec(g.newBaseQueueVec(opts, level), opts), false
+	})
+}
 
-	opts.HistogramVecOpts.FromComposite = true
+// Throughput creates throughput metrics with the given level
+func (g *group) Throughput(opts ThroughputOpts, level Level) Throughput {
+	throughput, _ := g.GetOrCreateThroughput(opts, level)
+	return throughput
+}
 
-	if !level.Enabled(g.minLevel) {
-		timerVec = newNoopTimerVec(opts, level)
-		isTrackedNoop = true
-	} else {
-		timerVec = &baseTimerVec{
-			baseCompositeMetric: baseCompositeMetric{
-				baseMetric: baseMetric{
-					name:  opts.Name,
-					help:  opts.Help,
-					level: level,
-				},
-			},
-			histogramVec: g.HistogramVec(opts.HistogramVecOpts, level),
+// GetOrCreateThroughput is like [group.Throughput], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+//
+// If opts.AutoStart is set, the ticker is only started when the metric is created.
+func (g *group) GetOrCreateThroughput(opts ThroughputOpts, level Level) (Throughput, bool) {
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.CountOpts, componentSuffixTotal, "count")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.RateOpts, componentSuffixRate, "rate")
+
+	throughput, created := getOrCreate[Throughput](g, opts, opts.Name, opts.Help, level, MetricTypeComposite, true, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableThroughput(newNoopThroughput(opts, level), opts), true
 		}
+		return newSwitchableThroughput(g.newBaseThroughput(opts, level), opts), false
+	})
+	if created && opts.AutoStart {
+		throughput.Start()
 	}
+	return throughput, created
+}
+
+// DistributionGauge creates a distribution gauge with the given level
+func (g *group) DistributionGauge(opts DistributionGaugeOpts, level Level) DistributionGauge {
+	distributionGauge, _ := g.GetOrCreateDistributionGauge(opts, level)
+	return distributionGauge
+}
 
-	switchable := newSwitchableTimerVec(timerVec, opts)
-	switchable.SetLevel(level)
+// GetOrCreateDistributionGauge is like [group.DistributionGauge], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreateDistributionGauge(opts DistributionGaugeOpts, level Level) (DistributionGauge, bool) {
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.GaugeOpts, componentSuffixLatest, "latest")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.HistogramOpts, componentSuffixDistribution, "distribution")
 
-	g.track(switchable, isTrackedNoop)
+	return getOrCreate[DistributionGauge](g, opts, opts.Name, opts.Help, level, MetricTypeComposite, true, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableDistributionGauge(newNoopDistributionGauge(opts, level), opts), true
+		}
+		return newSwitchableDistributionGauge(g.newBaseDistributionGauge(opts, level), opts), false
+	})
+}
 
-	return switchable
+// MultiResHistogram creates a multi-resolution histogram with the given level
+func (g *group) MultiResHistogram(opts MultiResHistogramOpts, level Level) MultiResHistogram {
+	multiResHistogram, _ := g.GetOrCreateMultiResHistogram(opts, level)
+	return multiResHistogram
 }
 
-// Cache creates cache metrics with the given level
-func (g *group) Cache(opts CacheOpts, level Level) Cache {
-	if m := g.getComposite(opts.Name); m != nil {
-		return m.(Cache)
+// GetOrCreateMultiResHistogram is like [group.MultiResHistogram], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+//
+// Panics if two resolutions have the same name.
+func (g *group) GetOrCreateMultiResHistogram(opts MultiResHistogramOpts, level Level) (MultiResHistogram, bool) {
+	opts.Resolutions = slices.Clone(opts.Resolutions)
+	names := make(map[string]bool, len(opts.Resolutions))
+	for i := range opts.Resolutions {
+		resolution := &opts.Resolutions[i]
+		if resolution.Name == "" {
+			resolution.Name = resolutionName(opts.Name, i)
+		}
+		if names[resolution.Name] {
+			panic(fmt.Sprintf("umami: multi-resolution histogram %q has more than one resolution named %q", opts.Name, resolution.Name))
+		}
+		names[resolution.Name] = true
+
+		g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, resolution, "", resolutionRole(i))
 	}
 
-	var cache Cache
-	var isTrackedNoop bool
+	return getOrCreate[MultiResHistogram](g, opts, opts.Name, opts.Help, level, MetricTypeComposite, true, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableMultiResHistogram(newNoopMultiResHistogram(opts, level), opts), true
+		}
+		return newSwitchableMultiResHistogram(g.newBaseMultiResHistogram(opts, level), opts), false
+	})
+}
 
-	opts.HitOpts.FromComposite = true
-	opts.MissOpts.FromComposite = true
-	opts.SizeOpts.FromComposite = true
+// Dependency creates outbound call metrics with the given level
+func (g *group) Dependency(opts DependencyOpts, level Level) Dependency {
+	dependency, _ := g.GetOrCreateDependency(opts, level)
+	return dependency
+}
 
-	if !level.Enabled(g.minLevel) {
-		cache = newNoopCache(opts, level)
-		isTrackedNoop = true
-	} else {
-		cache = &baseCache{
-			baseCompositeMetric: baseCompositeMetric{
-				baseMetric: baseMetric{
-					name:  opts.Name,
-					help:  opts.Help,
-					level: level,
-				},
-			},
-			hits:   g.Counter(opts.HitOpts, level),
-			misses: g.Counter(opts.MissOpts, level),
-			size:   g.Gauge(opts.SizeOpts, level),
+// GetOrCreateDependency is like [group.Dependency], but also reports whether the metric
+// was created by this call (true), or was already tracked (false)
+func (g *group) GetOrCreateDependency(opts DependencyOpts, level Level) (Dependency, bool) {
+	opts.CallsVecOpts.Labels = []string{DependencyLabel, OperationLabel, OutcomeLabel}
+	opts.LatencyVecOpts.Labels = []string{DependencyLabel, OperationLabel}
+	opts.InFlightVecOpts.Labels = []string{DependencyLabel, OperationLabel}
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.CallsVecOpts, componentSuffixCallsTotal, "calls")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.LatencyVecOpts, componentSuffixLatencySeconds, "latency")
+	g.initComponent(opts.MetricInfo, opts.CompositeMetricOpts, &opts.InFlightVecOpts, componentSuffixInFlight, "in flight")
+
+	return getOrCreate[Dependency](g, opts, opts.Name, opts.Help, level, MetricTypeComposite, true, func() (SwitchableMetric, bool) {
+		if !level.Enabled(g.minLevel) {
+			return newSwitchableDependency(newNoopDependency(opts, level), opts), true
 		}
+		return newSwitchableDependency(g.newBaseDependency(opts, level), opts), false
+	})
+}
+
+//--------------------------------------------------------------------------------
+// Real Metric Constructors
+//
+// These construct the real (non-noop) implementation of each metric from its
+// constructor opts. They are used by the factory functions when the metric's
+// level is enabled, and by [group.convertNoops] to replace noop implementations.
+//
+// Note: basic metric opts must already have the group prefix applied to their name.
+//--------------------------------------------------------------------------------
+
+func (g *group) newBaseCounter(opts CounterOpts, level Level) *baseCounter {
+	return &baseCounter{
+		baseMetric: baseMetric{
+			name:       opts.Name,
+			help:       opts.Help,
+			level:      level,
+			predicate:  opts.Predicate,
+			tags:       opts.Tags,
+			tagFilter:  &g.tags,
+			mask:       opts.Mask | MaskCounter,
+			maskFilter: &g.mask,
+			slo:        opts.SLO,
+		},
+		adapter: g.asyncCounter(opts.BasicMetricOpts, g.backend.Counter(opts)),
 	}
+}
 
-	switchable := newSwitchableCache(cache, opts)
-	switchable.SetLevel(level)
+func (g *group) newBaseCounterGroup(opts counterGroupOpts, level Level) *baseCounterGroup {
+	counterGroup := &baseCounterGroup{
+		baseMetric: baseMetric{
+			name:       opts.Name,
+			help:       opts.Help,
+			level:      level,
+			predicate:  opts.Predicate,
+			tags:       opts.Tags,
+			tagFilter:  &g.tags,
+			mask:       opts.Mask | MaskCounter,
+			maskFilter: &g.mask,
+			slo:        opts.SLO,
+		},
+		members: opts.members,
+	}
+	counterGroup.sampler = g.readCounter(opts.CounterOpts, func() float64 {
+		sum, _ := counterGroup.sum()
+		return sum
+	}, counterGroup.collected, CounterGroupSampleInterval)
 
-	g.track(switchable, isTrackedNoop)
+	return counterGroup
+}
 
-	return switchable
+func (g *group) newBaseCounterFunc(opts counterFuncOpts, level Level) *baseCounterFunc {
+	counterFunc := &baseCounterFunc{
+		baseMetric: baseMetric{
+			name:       opts.Name,
+			help:       opts.Help,
+			level:      level,
+			predicate:  opts.Predicate,
+			tags:       opts.Tags,
+			tagFilter:  &g.tags,
+			mask:       opts.Mask | MaskCounter,
+			maskFilter: &g.mask,
+			slo:        opts.SLO,
+		},
+		fn: opts.fn,
+	}
+	counterFunc.sampler = g.readCounter(opts.CounterOpts, opts.fn, counterFunc.collected, FuncSampleInterval)
+
+	return counterFunc
 }
 
-// CacheVec creates a cache vector with the given level
-func (g *group) CacheVec(opts CacheVecOpts, level Level) CacheVec {
-	if m := g.getComposite(opts.Name); m != nil {
-		return m.(CacheVec)
+func (g *group) newBaseCounterVec(opts CounterVecOpts, level Level) *baseCounterVec {
+	adapter := g.limitCounterVec(opts, g.asyncCounterVec(opts.BasicMetricOpts, g.backend.CounterVec(opts)))
+	preInitVec(adapter, opts.PreInitLabels, func(labels VecLabels) error { return adapter.Add(0, labels) })
+
+	return &baseCounterVec{
+		baseMetric: baseMetric{
+			name:       opts.Name,
+			help:       opts.Help,
+			level:      level,
+			predicate:  opts.Predicate,
+			tags:       opts.Tags,
+			tagFilter:  &g.tags,
+			mask:       opts.Mask | MaskCounter,
+			maskFilter: &g.mask,
+			slo:        opts.SLO,
+		},
+		adapter: adapter,
+		labels:  opts.Labels,
 	}
+}
 
-	var cacheVec CacheVec
-	var isTrackedNoop bool
+func (g *group) newBaseGauge(opts GaugeOpts, level Level) *baseGauge {
+	return &baseGauge{
+		baseMetric: baseMetric{
+			name:       opts.Name,
+			help:       opts.Help,
+			level:      level,
+			predicate:  opts.Predicate,
+			tags:       opts.Tags,
+			tagFilter:  &g.tags,
+			mask:       opts.Mask | MaskGauge,
+			maskFilter: &g.mask,
+			slo:        opts.SLO,
+		},
+		adapter: g.asyncGauge(opts.BasicMetricOpts, g.backend.Gauge(opts)),
+		bounds:  opts.Bounds,
+	}
+}
 
-	opts.HitVecOpts.FromComposite = true
-	opts.MissVecOpts.FromComposite = true
-	opts.SizeVecOpts.FromComposite = true
+func (g *group) newBaseAtomicGauge(opts atomicGaugeOpts, level Level) *baseAtomicGauge {
+	value := opts.value
+	read := func() float64 { return float64(value.Load()) }
+
+	atomicGauge := &baseAtomicGauge{
+		baseMetric: baseMetric{
+			name:       opts.Name,
+			help:       opts.Help,
+			level:      level,
+			predicate:  opts.Predicate,
+			tags:       opts.Tags,
+			tagFilter:  &g.tags,
+			mask:       opts.Mask | MaskGauge,
+			maskFilter: &g.mask,
+			slo:        opts.SLO,
+		},
+		value:  value,
+		bounds: opts.Bounds,
+	}
+	atomicGauge.sampler = g.readGauge(opts.GaugeOpts, read, atomicGauge.collected, AtomicGaugeSampleInterval)
 
-	if !level.Enabled(g.minLevel) {
-		cacheVec = newNoopCacheVec(opts, level)
-		isTrackedNoop = true
-	} else {
-		cacheVec = &baseCacheVec{
-			baseCompositeMetric: baseCompositeMetric{
-				baseMetric: baseMetric{
-					name:  opts.Name,
-					help:  opts.Help,
-					level: level,
-				},
-			},
-			hits:   g.CounterVec(opts.HitVecOpts, level),
-			misses: g.CounterVec(opts.MissVecOpts, level),
-			size:   g.GaugeVec(opts.SizeVecOpts, level),
-		}
+	return atomicGauge
+}
+
+func (g *group) newBaseGaugeFunc(opts gaugeFuncOpts, level Level) *baseGaugeFunc {
+	gaugeFunc := &baseGaugeFunc{
+		baseMetric: baseMetric{
+			name:       opts.Name,
+			help:       opts.Help,
+			level:      level,
+			predicate:  opts.Predicate,
+			tags:       opts.Tags,
+			tagFilter:  &g.tags,
+			mask:       opts.Mask | MaskGauge,
+			maskFilter: &g.mask,
+			slo:        opts.SLO,
+		},
+		fn: opts.fn,
 	}
+	gaugeFunc.sampler = g.readGauge(opts.GaugeOpts, opts.fn, gaugeFunc.collected, FuncSampleInterval)
 
-	switchable := newSwitchableCacheVec(cacheVec, opts)
-	switchable.SetLevel(level)
+	return gaugeFunc
+}
 
-	g.track(switchable, isTrackedNoop)
+func (g *group) newBaseGaugeVec(opts GaugeVecOpts, level Level) *baseGaugeVec {
+	adapter := g.limitGaugeVec(opts, g.asyncGaugeVec(opts.BasicMetricOpts, g.backend.GaugeVec(opts)))
+	preInitVec(adapter, opts.PreInitLabels, func(labels VecLabels) error { return adapter.Add(0, labels) })
+
+	return &baseGaugeVec{
+		baseMetric: baseMetric{
+			name:       opts.Name,
+			help:       opts.Help,
+			level:      level,
+			predicate:  opts.Predicate,
+			tags:       opts.Tags,
+			tagFilter:  &g.tags,
+			mask:       opts.Mask | MaskGauge,
+			maskFilter: &g.mask,
+			slo:        opts.SLO,
+		},
+		adapter: adapter,
+	}
+}
 
-	return switchable
+func (g *group) newBaseHistogram(opts HistogramOpts, level Level) *baseHistogram {
+	return &baseHistogram{
+		baseMetric: baseMetric{
+			name:       opts.Name,
+			help:       opts.Help,
+			level:      level,
+			predicate:  opts.Predicate,
+			tags:       opts.Tags,
+			tagFilter:  &g.tags,
+			mask:       opts.Mask | MaskHistogram,
+			maskFilter: &g.mask,
+			slo:        opts.SLO,
+		},
+		adapter: g.asyncHistogram(opts.BasicMetricOpts, g.backend.Histogram(opts)),
+	}
 }
 
-// Pool creates pool metrics with the given level
-func (g *group) Pool(opts PoolOpts, level Level) Pool {
-	if m := g.getComposite(opts.Name); m != nil {
-		return m.(Pool)
+func (g *group) newBaseHistogramVec(opts HistogramVecOpts, level Level) *baseHistogramVec {
+	adapter := g.limitHistogramVec(opts, g.asyncHistogramVec(opts.BasicMetricOpts, g.backend.HistogramVec(opts)))
+	preInitVec(adapter, opts.PreInitLabels, nil)
+
+	return &baseHistogramVec{
+		baseMetric: baseMetric{
+			name:       opts.Name,
+			help:       opts.Help,
+			level:      level,
+			predicate:  opts.Predicate,
+			tags:       opts.Tags,
+			tagFilter:  &g.tags,
+			mask:       opts.Mask | MaskHistogram,
+			maskFilter: &g.mask,
+			slo:        opts.SLO,
+		},
+		adapter: adapter,
 	}
+}
 
-	var pool Pool
-	var isTrackedNoop bool
+func (g *group) newBaseSummary(opts SummaryOpts, level Level) *baseSummary {
+	return &baseSummary{
+		baseMetric: baseMetric{
+			name:       opts.Name,
+			help:       opts.Help,
+			level:      level,
+			predicate:  opts.Predicate,
+			tags:       opts.Tags,
+			tagFilter:  &g.tags,
+			mask:       opts.Mask | MaskSummary,
+			maskFilter: &g.mask,
+			slo:        opts.SLO,
+		},
+		adapter: g.asyncSummary(opts.BasicMetricOpts, g.backend.Summary(opts)),
+	}
+}
 
-	opts.ActiveOpts.FromComposite = true
-	opts.IdleOpts.FromComposite = true
-	opts.AcquiredOpts.FromComposite = true
-	opts.ReleasedOpts.FromComposite = true
+func (g *group) newBaseSummaryVec(opts SummaryVecOpts, level Level) *baseSummaryVec {
+	adapter := g.limitSummaryVec(opts, g.asyncSummaryVec(opts.BasicMetricOpts, g.backend.SummaryVec(opts)))
+	preInitVec(adapter, opts.PreInitLabels, nil)
+
+	return &baseSummaryVec{
+		baseMetric: baseMetric{
+			name:       opts.Name,
+			help:       opts.Help,
+			level:      level,
+			predicate:  opts.Predicate,
+			tags:       opts.Tags,
+			tagFilter:  &g.tags,
+			mask:       opts.Mask | MaskSummary,
+			maskFilter: &g.mask,
+			slo:        opts.SLO,
+		},
+		adapter: adapter,
+	}
+}
 
-	if !level.Enabled(g.minLevel) {
-		pool = newNoopPool(opts, level)
-		isTrackedNoop = true
-	} else {
-		pool = &basePool{
-			baseCompositeMetric: baseCompositeMetric{
-				baseMetric: baseMetric{
-					name:  opts.Name,
-					help:  opts.Help,
-					level: level,
-				},
+func (g *group) newBaseTimer(opts TimerOpts, level Level) *baseTimer {
+	return &baseTimer{
+		baseCompositeMetric: baseCompositeMetric{
+			baseMetric: baseMetric{
+				name:  opts.Name,
+				help:  opts.Help,
+				level: level,
 			},
-			active:   g.Gauge(opts.ActiveOpts, level),
-			idle:     g.Gauge(opts.IdleOpts, level),
-			acquired: g.Counter(opts.AcquiredOpts, level),
-			released: g.Counter(opts.ReleasedOpts, level),
-		}
+		},
+		histogram: g.Histogram(opts.HistogramOpts, level),
+		clock:     clockOrDefault(opts.Clock),
 	}
+}
 
-	switchable := newSwitchablePool(pool, opts)
-	switchable.SetLevel(level)
-
-	g.track(switchable, isTrackedNoop)
+func (g *group) newBaseTimerVec(opts TimerVecOpts, level Level) *baseTimerVec {
+	return &baseTimerVec{
+		baseCompositeMetric: baseCompositeMetric{
+			baseMetric: baseMetric{
+				name:  opts.Name,
+				help:  opts.Help,
+				level: level,
+			},
+		},
+		histogramVec: g.HistogramVec(opts.HistogramVecOpts, level),
+		clock:        clockOrDefault(opts.Clock),
+	}
+}
 
-	return switchable
+func (g *group) newBaseCache(opts CacheOpts, level Level) *baseCache {
+	return &baseCache{
+		baseCompositeMetric: baseCompositeMetric{
+			baseMetric: baseMetric{
+				name:  opts.Name,
+				help:  opts.Help,
+				level: level,
+			},
+		},
+		hits:   g.Counter(opts.HitOpts, level),
+		misses: g.Counter(opts.MissOpts, level),
+		size:   g.Gauge(opts.SizeOpts, level),
+	}
 }
 
-// PoolVec creates a pool vector with the given level
-func (g *group) PoolVec(opts PoolVecOpts, level Level) PoolVec {
-	if m := g.getComposite(opts.Name); m != nil {
-		return m.(PoolVec)
+func (g *group) newBaseCacheVec(opts CacheVecOpts, level Level) *baseCacheVec {
+	return &baseCacheVec{
+		baseCompositeMetric: baseCompositeMetric{
+			baseMetric: baseMetric{
+				name:  opts.Name,
+				help:  opts.Help,
+				level: level,
+			},
+		},
+		hits:   g.CounterVec(opts.HitVecOpts, level),
+		misses: g.CounterVec(opts.MissVecOpts, level),
+		size:   g.GaugeVec(opts.SizeVecOpts, level),
 	}
+}
 
-	var poolVec PoolVec
-	var isTrackedNoop bool
+func (g *group) newBasePool(opts PoolOpts, level Level) *basePool {
+	return &basePool{
+		baseCompositeMetric: baseCompositeMetric{
+			baseMetric: baseMetric{
+				name:  opts.Name,
+				help:  opts.Help,
+				level: level,
+			},
+		},
+		active:   g.Gauge(opts.ActiveOpts, level),
+		idle:     g.Gauge(opts.IdleOpts, level),
+		acquired: g.Counter(opts.AcquiredOpts, level),
+		released: g.Counter(opts.ReleasedOpts, level),
+	}
+}
 
-	opts.ActiveVecOpts.FromComposite = true
-	opts.IdleVecOpts.FromComposite = true
-	opts.AcquiredVecOpts.FromComposite = true
-	opts.ReleasedVecOpts.FromComposite = true
+func (g *group) newBasePoolVec(opts PoolVecOpts, level Level) *basePoolVec {
+	return &basePoolVec{
+		baseCompositeMetric: baseCompositeMetric{
+			baseMetric: baseMetric{
+				name:  opts.Name,
+				help:  opts.Help,
+				level: level,
+			},
+		},
+		active:   g.GaugeVec(opts.ActiveVecOpts, level),
+		idle:     g.GaugeVec(opts.IdleVecOpts, level),
+		acquired: g.CounterVec(opts.AcquiredVecOpts, level),
+		released: g.CounterVec(opts.ReleasedVecOpts, level),
+	}
+}
 
-	if !level.Enabled(g.minLevel) {
-		poolVec = newNoopPoolVec(opts, level)
-		isTrackedNoop = true
-	} else {
+func (g *group) newBaseCircuitBreaker(opts CircuitBreakerOpts, level Level) *baseCircuitBreaker {
+	return &baseCircuitBreaker{
+		baseCompositeMetric: baseCompositeMetric{
+			baseMetric: baseMetric{
+				name:  opts.Name,
+				help:  opts.Help,
+				level: level,
+			},
+		},
+		state:     g.Gauge(opts.StateOpts, level),
+		successes: g.Counter(opts.SuccessOpts, level),
+		failures:  g.Counter(opts.FailureOpts, level),
+	}
+}
 
-		poolVec = &basePoolVec{
-			baseCompositeMetric: baseCompositeMetric{
-				baseMetric: baseMetric{
-					name:  opts.Name,
-					help:  opts.Help,
-					level: level,
-				},
+func (g *group) newBaseCircuitBreakerVec(opts CircuitBreakerVecOpts, level Level) *baseCircuitBreakerVec {
+	return &baseCircuitBreakerVec{
+		baseCompositeMetric: baseCompositeMetric{
+			baseMetric: baseMetric{
+				name:  opts.Name,
+				help:  opts.Help,
+				level: level,
 			},
-			active:   g.GaugeVec(opts.ActiveVecOpts, level),
-			idle:     g.GaugeVec(opts.IdleVecOpts, level),
-			acquired: g.CounterVec(opts.AcquiredVecOpts, level),
-			released: g.CounterVec(opts.ReleasedVecOpts, level),
-		}
+		},
+		state:     g.GaugeVec(opts.StateVecOpts, level),
+		successes: g.CounterVec(opts.SuccessVecOpts, level),
+		failures:  g.CounterVec(opts.FailureVecOpts, level),
 	}
+}
 
-	switchable := newSwitchablePoolVec(poolVec, opts)
-	switchable.SetLevel(level)
+func (g *group) newBaseQueue(opts QueueOpts, level Level) *baseQueue {
+	return &baseQueue{
+		baseCompositeMetric: baseCompositeMetric{
+			baseMetric: baseMetric{
+				name:  opts.Name,
+				help:  opts.Help,
+				level: level,
+			},
+		},
+		depth:    g.Gauge(opts.DepthOpts, level),
+		enqueued: g.Counter(opts.EnqueuedOpts, level),
+		dequeued: g.Counter(opts.DequeuedOpts, level),
+		waitTime: g.Histogram(opts.WaitTimeOpts, level),
+	}
+}
 
-	g.track(switchable, isTrackedNoop)
+func (g *group) newBaseQueueVec(opts QueueVecOpts, level Level) *baseQueueVec {
+	return &baseQueueVec{
+		baseCompositeMetric: baseCompositeMetric{
+			baseMetric: baseMetric{
+				name:  opts.Name,
+				help:  opts.Help,
+				level: level,
+			},
+		},
+		depth:    g.GaugeVec(opts.DepthVecOpts, level),
+		enqueued: g.CounterVec(opts.EnqueuedVecOpts, level),
+		dequeued: g.CounterVec(opts.DequeuedVecOpts, level),
+		waitTime: g.HistogramVec(opts.WaitTimeVecOpts, level),
+	}
+}
 
-	return switchable
+// newBaseThroughput constructs a stopped throughput, see [group.GetOrCreateThroughput]
+func (g *group) newBaseThroughput(opts ThroughputOpts, level Level) *baseThroughput {
+	clock := clockOrDefault(opts.Clock)
+	return &baseThroughput{
+		baseCompositeMetric: baseCompositeMetric{
+			baseMetric: baseMetric{
+				name:       opts.Name,
+				help:       opts.Help,
+				level:      level,
+				tags:       opts.Tags,
+				tagFilter:  &g.tags,
+				mask:       opts.Mask,
+				maskFilter: &g.mask,
+			},
+		},
+		count:    g.Counter(opts.CountOpts, level),
+		rate:     g.Gauge(opts.RateOpts, level),
+		window:   throughputWindow(opts.Window),
+		clock:    clock,
+		lastTick: clock.Now(),
+	}
 }
 
-// CircuitBreaker creates circuit breaker metrics with the given level
-func (g *group) CircuitBreaker(opts CircuitBreakerOpts, level Level) CircuitBreaker {
-	if m := g.getComposite(opts.Name); m != nil {
-		return m.(CircuitBreaker)
+func (g *group) newBaseDistributionGauge(opts DistributionGaugeOpts, level Level) *baseDistributionGauge {
+	return &baseDistributionGauge{
+		baseCompositeMetric: baseCompositeMetric{
+			baseMetric: baseMetric{
+				name:  opts.Name,
+				help:  opts.Help,
+				level: level,
+			},
+		},
+		gauge:     g.Gauge(opts.GaugeOpts, level),
+		histogram: g.Histogram(opts.HistogramOpts, level),
 	}
+}
 
-	var circuitBreaker CircuitBreaker
-	var isTrackedNoop bool
+func (g *group) newBaseMultiResHistogram(opts MultiResHistogramOpts, level Level) *baseMultiResHistogram {
+	histograms := make([]Histogram, len(opts.Resolutions))
+	for i, resolution := range opts.Resolutions {
+		histograms[i] = g.Histogram(resolution, level)
+	}
 
-	opts.StateOpts.FromComposite = true
-	opts.SuccessOpts.FromComposite = true
-	opts.FailureOpts.FromComposite = true
+	return &baseMultiResHistogram{
+		baseCompositeMetric: baseCompositeMetric{
+			baseMetric: baseMetric{
+				name:  opts.Name,
+				help:  opts.Help,
+				level: level,
+			},
+		},
+		histograms: histograms,
+	}
+}
 
-	if !level.Enabled(g.minLevel) {
-		circuitBreaker = newNoopCircuitBreaker(opts, level)
-		isTrackedNoop = true
-	} else {
-		circuitBreaker = &baseCircuitBreaker{
-			baseCompositeMetric: baseCompositeMetric{
-				baseMetric: baseMetric{
-					name:  opts.Name,
-					help:  opts.Help,
-					level: level,
-				},
+func (g *group) newBaseDependency(opts DependencyOpts, level Level) *baseDependency {
+	return &baseDependency{
+		baseCompositeMetric: baseCompositeMetric{
+			baseMetric: baseMetric{
+				name:  opts.Name,
+				help:  opts.Help,
+				level: level,
 			},
-			state:     g.Gauge(opts.StateOpts, level),
-			successes: g.Counter(opts.SuccessOpts, level),
-			failures:  g.Counter(opts.FailureOpts, level),
-		}
+		},
+		calls:    g.CounterVec(opts.CallsVecOpts, level),
+		latency:  g.HistogramVec(opts.LatencyVecOpts, level),
+		inFlight: g.GaugeVec(opts.InFlightVecOpts, level),
+		clock:    clockOrDefault(opts.Clock),
 	}
+}
 
-	switchable := newSwitchableCircuitBreaker(circuitBreaker, opts)
-	switchable.SetLevel(level)
+// resolutionName returns the default name of the i-th resolution of a
+// [MultiResHistogram] named name
+func resolutionName(name string, i int) string {
+	return fmt.Sprintf("%s_res%d", name, i)
+}
 
-	g.track(switchable, isTrackedNoop)
+// resolutionRole returns the role of the i-th resolution of a [MultiResHistogram],
+// see [componentHelp]
+func resolutionRole(i int) string {
+	return fmt.Sprintf("resolution %d", i)
+}
 
-	return switchable
+// throughputWindow returns window, or [DefaultThroughputWindow] if it is unset
+func throughputWindow(window time.Duration) time.Duration {
+	if window <= 0 {
+		return DefaultThroughputWindow
+	}
+	return window
 }
 
-// CircuitBreakerVec creates a circuit breaker vector with the given level
-func (g *group) CircuitBreakerVec(opts CircuitBreakerVecOpts, level Level) CircuitBreakerVec {
-	if m := g.getComposite(opts.Name); m != nil {
-		return m.(CircuitBreakerVec)
+// rebuildSummary returns a replacement for the summary old built from opts,
+// at the same level. A real summary's backend metric is unregistered first,
+// so that a new one with the same name can be registered.
+func (g *group) rebuildSummary(old Summary, opts SummaryOpts) (Summary, error) {
+	level := old.Level()
+
+	if _, ok := old.(NoopMetric); ok {
+		return newNoopSummary(opts, level), nil
+	}
+
+	backend, ok := g.backend.(UnregisterBackend)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnregisterUnsupported, g.backend.Name())
 	}
+	backend.Unregister(opts.QualifiedName())
 
-	var circuitBreakerVec CircuitBreakerVec
-	var isTrackedNoop bool
+	return g.newBaseSummary(opts, level), nil
+}
 
-	opts.StateVecOpts.FromComposite = true
-	opts.SuccessVecOpts.FromComposite = true
-	opts.FailureVecOpts.FromComposite = true
+// redeclare applies the group's [RedeclarePolicy] to metric, an existing metric
+// requested again with the given help and level
+func (g *group) redeclare(metric Metric, name, help string, level Level) {
+	if metric.Level() == level && metric.Help() == help {
+		return
+	}
 
-	if !level.Enabled(g.minLevel) {
-		circuitBreakerVec = newNoopCircuitBreakerVec(opts, level)
-		isTrackedNoop = true
-	} else {
+	err := fmt.Errorf("%w: %q declared with level %v and help %q, requested with level %v and help %q",
+		ErrMetricRedeclaredWithDifferentOpts, name, metric.Level(), metric.Help(), level, help)
 
-		circuitBreakerVec = &baseCircuitBreakerVec{
-			baseCompositeMetric: baseCompositeMetric{
-				baseMetric: baseMetric{
-					name:  opts.Name,
-					help:  opts.Help,
-					level: level,
-				},
-			},
-			state:     g.GaugeVec(opts.StateVecOpts, level),
-			successes: g.CounterVec(opts.SuccessVecOpts, level),
-			failures:  g.CounterVec(opts.FailureVecOpts, level),
+	switch g.redeclarePolicy {
+	case RedeclarePanic:
+		panic(err)
+	case RedeclareUpdateLevel:
+		metric.SetLevel(level)
+		if metric.Help() != help {
+			g.warn(err)
 		}
+	default:
+		g.warn(err)
+	}
+}
+
+// warn reports a non-fatal problem to the group's warning handler, if any
+func (g *group) warn(err error) {
+	if g.onWarning != nil {
+		g.onWarning(err)
 	}
+}
 
-	switchable := newSwitchableCircuitBreakerVec(circuitBreakerVec, opts)
-	switchable.SetLevel(level)
+// sampler is the goroutine sampling a metric on a backend that can't read it
+// at collection time, e.g. a [Factory.GaugeFunc] on a backend that isn't a
+// [GaugeFuncBackend], or the registration of a metric read by its backend,
+// whose function is unregistered once stopped. A nil sampler is stopped.
+type sampler struct {
+	stop       chan struct{}
+	done       chan struct{} // Closed once the goroutine has exited
+	unregister func()        // Unregisters the function read by the backend, if any
+	stopOnce   sync.Once
+}
 
-	g.track(switchable, isTrackedNoop)
+// readGauge has read called by the backend at collection time if it is a
+// [GaugeFuncBackend], or sampled into a gauge every interval otherwise,
+// until the returned sampler is stopped.
+//
+// While enabled returns false, e.g. while the metric is disabled by the level
+// of its group (see [baseMetric.collected]), the backend reads 0 and no
+// sample is emitted.
+func (g *group) readGauge(opts GaugeOpts, read func() float64, enabled func() bool, interval time.Duration) *sampler {
+	if backend, ok := gaugeFuncBackend(g.backend); ok {
+		backend.GaugeFunc(opts, gateRead(read, enabled))
+		return g.registeredSampler(opts.QualifiedName())
+	}
 
-	return switchable
+	adapter := g.backend.Gauge(opts)
+	return startSampler(func(stop <-chan struct{}) {
+		sampleGauge(adapter, read, enabled, interval, stop)
+	})
 }
 
-// Queue creates queue metrics with the given level
-func (g *group) Queue(opts QueueOpts, level Level) Queue {
-	if m := g.getComposite(opts.Name); m != nil {
-		return m.(Queue)
+// readCounter is [group.readGauge] for counters, see [CounterFuncBackend]
+func (g *group) readCounter(opts CounterOpts, read func() float64, enabled func() bool, interval time.Duration) *sampler {
+	if backend, ok := counterFuncBackend(g.backend); ok {
+		backend.CounterFunc(opts, gateRead(read, enabled))
+		return g.registeredSampler(opts.QualifiedName())
 	}
 
-	var queue Queue
-	var isTrackedNoop bool
+	adapter := g.backend.Counter(opts)
+	return startSampler(func(stop <-chan struct{}) {
+		sampleCounter(adapter, read, enabled, interval, stop)
+	})
+}
 
-	opts.DepthOpts.FromComposite = true
-	opts.EnqueuedOpts.FromComposite = true
-	opts.DequeuedOpts.FromComposite = true
-	opts.WaitTimeOpts.FromComposite = true
+// gateRead returns read, returning 0 without calling it while enabled
+// returns false
+func gateRead(read func() float64, enabled func() bool) func() float64 {
+	return func() float64 {
+		if !enabled() {
+			return 0
+		}
+		return read()
+	}
+}
 
-	if !level.Enabled(g.minLevel) {
-		queue = newNoopQueue(opts, level)
-		isTrackedNoop = true
-	} else {
+// registeredSampler returns the sampler of the named metric read by the
+// backend, which unregisters it when stopped if the backend is an
+// [UnregisterBackend], so that the backend stops reading its function
+func (g *group) registeredSampler(name string) *sampler {
+	backend, ok := g.backend.(UnregisterBackend)
+	if !ok {
+		return nil
+	}
+	return &sampler{unregister: func() { backend.Unregister(name) }}
+}
 
-		queue = &baseQueue{
-			baseCompositeMetric: baseCompositeMetric{
-				baseMetric: baseMetric{
-					name:  opts.Name,
-					help:  opts.Help,
-					level: level,
-				},
-			},
-			depth:    g.Gauge(opts.DepthOpts, level),
-			enqueued: g.Counter(opts.EnqueuedOpts, level),
-			dequeued: g.Counter(opts.DequeuedOpts, level),
-			waitTime: g.Histogram(opts.WaitTimeOpts, level),
+// startSampler runs sample on a goroutine until the returned sampler is stopped
+func startSampler(sample func(stop <-chan struct{})) *sampler {
+	s := &sampler{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		sample(s.stop)
+	}()
+	return s
+}
+
+// Stop stops the goroutine, and waits for it to exit, or unregisters the
+// function read by the backend. Noop if stopped.
+func (s *sampler) Stop() {
+	if s == nil {
+		return
+	}
+	if s.unregister != nil {
+		s.stopOnce.Do(s.unregister)
+		return
+	}
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+// sampleGauge sets adapter to the value returned by read, immediately and
+// then every interval, until stop is closed. Samples are skipped while
+// enabled returns false.
+func sampleGauge(adapter GaugeAdapter, read func() float64, enabled func() bool, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if enabled() {
+			_ = adapter.Set(read())
+		}
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
 		}
 	}
+}
 
-	switchable := newSwitchableQueue(queue, opts)
-	switchable.SetLevel(level)
+// sampleCounter adds the increase of the value returned by read to adapter,
+// immediately and then every interval, until stop is closed. Decreases, e.g.
+// of a member counter that was reset, are skipped until the value is back
+// above its last sample. Samples are skipped while enabled returns false.
+func sampleCounter(adapter CounterAdapter, read func() float64, enabled func() bool, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := 0.0
+	for {
+		if enabled() {
+			if value := read(); value > last {
+				_ = adapter.Add(value - last)
+				last = value
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
 
-	g.track(switchable, isTrackedNoop)
+//--------------------------------------------------------------------------------
+// Composite Component Helpers
+//--------------------------------------------------------------------------------
 
-	return switchable
+// metricName returns the backend name of a basic metric of the group: name
+// prefixed with the group name, or name as given if noPrefix is set.
+//
+// Panics with [ErrEmptyMetricName] if name is empty, and if the name is
+// already used by another group of the registry.
+func (g *group) metricName(name string, noPrefix bool) string {
+	if name == "" {
+		panic(fmt.Errorf("%w: metric of group %q", ErrEmptyMetricName, g.name))
+	}
+	name = g.backendName(name, noPrefix)
+	if g.names != nil {
+		g.names.claim(name, g.name)
+	}
+	return name
 }
 
-// QueueVec creates a queue vector with the given level
-func (g *group) QueueVec(opts QueueVecOpts, level Level) QueueVec {
-	if m := g.getComposite(opts.Name); m != nil {
-		return m.(QueueVec)
+// backendName returns the backend name of a basic metric of the group, like
+// [group.metricName], without claiming it
+func (g *group) backendName(name string, noPrefix bool) string {
+	if noPrefix {
+		return name
 	}
+	return g.name + "_" + name
+}
 
-	var queueVec QueueVec
-	var isTrackedNoop bool
+// Default name suffixes of the components of composite metrics, appended to
+// the name of the composite for components whose opts leave their name unset
+const (
+	componentSuffixHistogram      = "_histogram"
+	componentSuffixHit            = "_hit"
+	componentSuffixMiss           = "_miss"
+	componentSuffixSize           = "_size"
+	componentSuffixActive         = "_active"
+	componentSuffixIdle           = "_idle"
+	componentSuffixAcquired       = "_acquired"
+	componentSuffixReleased       = "_released"
+	componentSuffixState          = "_state"
+	componentSuffixSuccess        = "_success"
+	componentSuffixFailure        = "_failure"
+	componentSuffixDepth          = "_depth"
+	componentSuffixEnqueued       = "_enqueued"
+	componentSuffixDequeued       = "_dequeued"
+	componentSuffixWaitTime       = "_wait_time"
+	componentSuffixTotal          = "_total"
+	componentSuffixRate           = "_rate"
+	componentSuffixLatest         = "_latest"
+	componentSuffixDistribution   = "_distribution"
+	componentSuffixCallsTotal     = "_calls_total"
+	componentSuffixLatencySeconds = "_latency_seconds"
+	componentSuffixInFlight       = "_in_flight"
+)
 
-	opts.DepthVecOpts.FromComposite = true
-	opts.EnqueuedVecOpts.FromComposite = true
-	opts.DequeuedVecOpts.FromComposite = true
-	opts.WaitTimeVecOpts.FromComposite = true
+// componentName returns the backend name of a component of the composite
+// metric named composite: name, or composite suffixed with suffix if name is
+// unset, prefixed like [group.backendName].
+//
+// The opts of named components are set NoPrefix, so that the real components,
+// created through the group's factories, and the noop components of a composite
+// have the same names.
+//
+// Panics with [ErrEmptyMetricName] if composite is empty, as its components
+// would be named by their suffixes alone.
+func (g *group) componentName(composite, name, suffix string, noPrefix bool) string {
+	if composite == "" {
+		panic(fmt.Errorf("%w: composite metric of group %q", ErrEmptyMetricName, g.name))
+	}
+	if name == "" {
+		name = composite + suffix
+	}
+	return g.backendName(name, noPrefix)
+}
 
-	if !level.Enabled(g.minLevel) {
-		queueVec = newNoopQueueVec(opts, level)
-		isTrackedNoop = true
-	} else {
+// component is implemented by the opts of the basic metrics that may be the
+// components of a composite metric, through their embedded [BasicMetricOpts]
+// and [MetricInfo], see [group.initComponent]
+type component interface {
+	basicMetricOpts() *BasicMetricOpts
+	metricInfo() *MetricInfo
+}
 
-		queueVec = &baseQueueVec{
-			baseCompositeMetric: baseCompositeMetric{
-				baseMetric: baseMetric{
-					name:  opts.Name,
-					help:  opts.Help,
-					level: level,
-				},
-			},
-			depth:    g.GaugeVec(opts.DepthVecOpts, level),
-			enqueued: g.CounterVec(opts.EnqueuedVecOpts, level),
-			dequeued: g.CounterVec(opts.DequeuedVecOpts, level),
-			waitTime: g.HistogramVec(opts.WaitTimeVecOpts, level),
-		}
+func (o *BasicMetricOpts) basicMetricOpts() *BasicMetricOpts {
+	return o
+}
+
+func (i *MetricInfo) metricInfo() *MetricInfo {
+	return i
+}
+
+// initComponent sets up the opts of a component of the composite of the given
+// info and opts: the component is created from the composite, see
+// [BasicMetricOpts.FromComposite], with the info returned by
+// [group.componentInfo].
+func (g *group) initComponent(parent MetricInfo, composite CompositeMetricOpts, c component, suffix, role string) {
+	basic, info := c.basicMetricOpts(), c.metricInfo()
+	*info = g.componentInfo(parent, *info, suffix, role, basic.NoPrefix || composite.NoPrefix)
+	basic.FromComposite = true
+	basic.NoPrefix = true
+}
+
+// componentInfo returns the info of a component of the composite of info
+// parent, in the given role, from the component's own info.
+//
+// The name is the backend name of the component (see [group.componentName]),
+// the help is derived from that of the composite (see [componentHelp]), and
+// the tags and the mask of the composite apply to the component too. So do its
+// namespace, subsystem and const labels, unless the component sets its own.
+func (g *group) componentInfo(parent, info MetricInfo, suffix, role string, noPrefix bool) MetricInfo {
+	info.Name = g.componentName(parent.Name, info.Name, suffix, noPrefix)
+	info.Help = componentHelp(parent.Help, info.Help, role)
+	info.Namespace = cmp.Or(info.Namespace, parent.Namespace)
+	info.Subsystem = cmp.Or(info.Subsystem, parent.Subsystem)
+	info.ConstLabels = componentConstLabels(parent.ConstLabels, info.ConstLabels)
+	info.Tags = componentTags(parent.Tags, info.Tags)
+	info.Mask |= parent.Mask
+	return info
+}
+
+// componentHelp returns the help text for a component of a composite metric.
+//
+// If the component's own help text is set, it is used verbatim, allowing it to
+// be overridden per component opts. Otherwise, the help is derived from the
+// composite's help and the component's role, e.g. "Cache metrics (hits)".
+func componentHelp(compositeHelp, help, role string) string {
+	if help != "" {
+		return help
 	}
 
-	switchable := newSwitchableQueueVec(queueVec, opts)
-	switchable.SetLevel(level)
+	if compositeHelp == "" {
+		return ""
+	}
 
-	g.track(switchable, isTrackedNoop)
+	return compositeHelp + " (" + role + ")"
+}
 
-	return switchable
+// componentConstLabels returns the const labels of a component of a composite
+// metric: those of the composite, and the component's own, which take
+// precedence over the composite's of the same name
+func componentConstLabels(compositeLabels, labels map[string]string) map[string]string {
+	if len(compositeLabels) == 0 {
+		return labels
+	}
+
+	merged := maps.Clone(compositeLabels)
+	maps.Copy(merged, labels)
+	return merged
 }
 
 //--------------------------------------------------------------------------------
 // Metric Tracking Helpers
 //--------------------------------------------------------------------------------
 
-// getBasic retrieves a basic metric by name from
-// the group's tracking map, or nil if not found.
+// getOrCreate returns the metric tracked under name in the tracking map for
+// class ([group.basics] or [group.composites]), or creates one with create
+// and tracks it. The bool result is true if the metric was created.
 //
-// It is safe for concurrent use and read locks [group.mu]
-func (g *group) getBasic(name string) Metric {
-	g.mu.RLock()
-	defer g.mu.RUnlock()
+// The lookup, creation, and tracking happen under a single write lock of
+// [group.mu], so concurrent calls for the same name create the metric exactly
+// once. create must therefore not call any method locking [group.mu].
+// It returns the new metric, and whether it is a noop to track in
+// [group.noops] for later replacement.
+//
+// If track is false (e.g. composite components), the metric is always
+// created, and neither looked up nor tracked.
+//
+// Basics and the components of composites share a single namespace of backend
+// names, so that they don't collide at the backend: creating a basic named like
+// a component of a tracked composite, or a composite (with opts) whose component
+// is named like a tracked basic or another composite's component, panics.
+func getOrCreate[M Metric](g *group, opts any, name, help string, level Level, class MetricType, track bool, create func() (SwitchableMetric, bool)) (M, bool) {
+	if !track {
+		if g.shareComponents {
+			return shareComponent[M](g, name, create), true
+		}
+		metric, _ := create()
+		return metric.(M), true
+	}
 
-	if m, exist := g.basics[name]; exist {
-		return m
+	g.mu.Lock()
+
+	tracked := g.basics
+	if class == MetricTypeComposite {
+		tracked = g.composites
 	}
 
-	return nil
+	if metric, exists := tracked[name]; exists {
+		// Unlocked first, as updating the level may relevel the metric
+		g.mu.Unlock()
+		g.redeclare(metric, name, help, level)
+		return metric.(M), false
+	}
+	defer g.mu.Unlock()
+
+	if g.sealed != nil && g.sealed.Load() {
+		panic(fmt.Errorf("%w: metric %q of group %q created after sealing", ErrRegistrySealed, name, g.name))
+	}
+
+	if class == MetricTypeComposite {
+		g.claimComponents(name, opts)
+	} else if composite, exists := g.components[name]; exists && !g.shareComponents {
+		panic(fmt.Sprintf("umami: metric %q of group %q is already a component of composite %q", name, g.name, composite))
+	} else if g.shareComponents && class == MetricTypeBasic {
+		return shareBasic[M](g, name, create), true
+	}
+
+	metric, isNoop := create()
+	metric.cacheEnabled(g.minLevel)
+	metric.bindRelevel(func(level Level) bool {
+		return g.relevel(metric, name, class, level)
+	})
+	tracked[name] = metric
+	if isNoop {
+		g.noops[name] = class
+	}
+
+	return metric.(M), true
 }
 
-// getComposite retrieves a composite metric by name from
-// the group's tracking map, or nil if not found.
-//
-// It is safe for concurrent use and read locks [group.mu]
-func (g *group) getComposite(name string) Metric {
-	g.mu.RLock()
-	defer g.mu.RUnlock()
+// claimComponents records the backend names of the components of the composite
+// created with opts, panicking if one is already used by a tracked basic or a
+// component of another composite. Callers must hold the write lock of [group.mu].
+func (g *group) claimComponents(composite string, opts any) {
+	names := g.componentNames(opts)
+
+	if g.shareComponents {
+		for _, name := range names {
+			if _, exists := g.components[name]; !exists {
+				g.components[name] = composite
+			}
+		}
+		return
+	}
+
+	for _, name := range names {
+		if _, exists := g.basics[name]; exists {
+			panic(fmt.Sprintf("umami: component %q of composite %q of group %q is already a basic metric", name, composite, g.name))
+		}
+		if owner, exists := g.components[name]; exists {
+			panic(fmt.Sprintf("umami: component %q of composite %q of group %q is already a component of composite %q", name, composite, g.name, owner))
+		}
+	}
 
-	if m, exist := g.composites[name]; exist {
-		return m
+	for _, name := range names {
+		g.components[name] = composite
 	}
+}
 
-	return nil
+// componentNames returns the backend names of the components of the
+// composite created with opts
+func (g *group) componentNames(opts any) []string {
+	var names []string
+	for _, info := range g.componentInfos(opts) {
+		names = append(names, info.Name)
+	}
+	return names
 }
 
-// track adds a metric (basic or composite) to the group's corresponding
-// tracking map ([group.basics] or [group.composites]).
-//
-// WARNING: this does not check for duplicates, so the caller must ensure
-// that the metric is not already tracked.
-//
-// Optionally, if isTrackedNoop is true, its name is added to the
-// [group.noop] map for potential later replacement.
-func (g *group) track(metric SwitchableMetric, isTrackedNoop bool) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
+// componentInfos returns the infos of the components of the composite created
+// with opts, named by their backend names
+func (g *group) componentInfos(opts any) []MetricInfo {
+	var infos []MetricInfo
+	for _, component := range componentOpts(opts) {
+		if info := basicInfo(component); info.Name != "" {
+			info.Name = g.backendName(info.Name, basicOpts(component).NoPrefix)
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// shareComponent returns the component shared under name, or creates it with
+// create and shares it, see [WithSharedComponents]. Noop components are not
+// shared, as they are replaced once their composite is enabled.
+func shareComponent[M Metric](g *group, name string, create func() (SwitchableMetric, bool)) M {
+	g.sharedMu.Lock()
+	defer g.sharedMu.Unlock()
 
-	switch metric.Type() {
-	case MetricTypeBasic:
-		g.basics[metric.Name()] = metric
-	case MetricTypeComposite:
-		g.composites[metric.Name()] = metric
+	if metric, exists := g.shared[name]; exists && !metric.IsNoop() {
+		return sharedAs[M](g, name, metric)
 	}
 
-	if isTrackedNoop {
-		g.noops[metric.Name()] = metric.Type()
+	metric, _ := create()
+	if !metric.IsNoop() {
+		g.shared[name] = metric
 	}
+	return metric.(M)
+}
+
+// shareBasic tracks the component shared under name as a basic metric, or
+// creates, tracks and shares the basic metric with create, see
+// [WithSharedComponents]. Callers must hold the write lock of [group.mu].
+func shareBasic[M Metric](g *group, name string, create func() (SwitchableMetric, bool)) M {
+	g.sharedMu.Lock()
+	defer g.sharedMu.Unlock()
+
+	metric, exists := g.shared[name]
+	if exists && !metric.IsNoop() {
+		sharedAs[M](g, name, metric)
+	} else {
+		var isNoop bool
+		metric, isNoop = create()
+		if isNoop {
+			g.noops[name] = MetricTypeBasic
+		}
+		// Tracked basics are releveled in place, so they are shared even if noop
+		g.shared[name] = metric
+	}
+
+	metric.cacheEnabled(g.minLevel)
+	metric.bindRelevel(func(level Level) bool {
+		return g.relevel(metric, name, MetricTypeBasic, level)
+	})
+	g.basics[name] = metric
+	return metric.(M)
+}
+
+// sharedAs returns the metric shared under name as an M, panicking if it
+// is of another type
+func sharedAs[M Metric](g *group, name string, metric SwitchableMetric) M {
+	typed, ok := metric.(M)
+	if !ok {
+		panic(fmt.Sprintf("umami: shared metric %q of group %q is a %T, not a %v", name, g.name, metric, reflect.TypeFor[M]()))
+	}
+	return typed
 }
 
 //--------------------------------------------------------------------------------
 // Noop Conversion and Group Management
 //--------------------------------------------------------------------------------
 
-func (g *group) convertNoopPrime(metric NoopMetric) Metric {
-
-	switch metric.(type) {
-	case *noopCounter:
-		return g.Counter(metric.constructorOpts().(CounterOpts), metric.Level())
-	case *noopCounterVec:
-		return g.CounterVec(metric.constructorOpts().(CounterVecOpts), metric.Level())
-	case *noopGauge:
-		return g.Gauge(metric.constructorOpts().(GaugeOpts), metric.Level())
-	case *noopGaugeVec:
-		return g.GaugeVec(metric.constructorOpts().(GaugeVecOpts), metric.Level())
-	case *noopHistogram:
-		return g.Histogram(metric.constructorOpts().(HistogramOpts), metric.Level())
-	case *noopHistogramVec:
-		return g.HistogramVec(metric.constructorOpts().(HistogramVecOpts), metric.Level())
-	case *noopSummary:
-		return g.Summary(metric.constructorOpts().(SummaryOpts), metric.Level())
-	case *noopSummaryVec:
-		return g.SummaryVec(metric.constructorOpts().(SummaryVecOpts), metric.Level())
+// newRealImpl constructs the real implementation of a metric from the
+// opts it was constructed with (see [Switchable.switchOpts]).
+func (g *group) newRealImpl(opts any, level Level) Metric {
+	switch opts := opts.(type) {
+	case CounterOpts:
+		return g.newBaseCounter(opts, level)
+	case counterGroupOpts:
+		return g.newBaseCounterGroup(opts, level)
+	case counterFuncOpts:
+		return g.newBaseCounterFunc(opts, level)
+	case CounterVecOpts:
+		return g.newBaseCounterVec(opts, level)
+	case GaugeOpts:
+		return g.newBaseGauge(opts, level)
+	case atomicGaugeOpts:
+		return g.newBaseAtomicGauge(opts, level)
+	case gaugeFuncOpts:
+		return g.newBaseGaugeFunc(opts, level)
+	case GaugeVecOpts:
+		return g.newBaseGaugeVec(opts, level)
+	case HistogramOpts:
+		return g.newBaseHistogram(opts, level)
+	case HistogramVecOpts:
+		return g.newBaseHistogramVec(opts, level)
+	case SummaryOpts:
+		return g.newBaseSummary(opts, level)
+	case SummaryVecOpts:
+		return g.newBaseSummaryVec(opts, level)
+	case TimerOpts:
+		return g.newBaseTimer(opts, level)
+	case TimerVecOpts:
+		return g.newBaseTimerVec(opts, level)
+	case CacheOpts:
+		return g.newBaseCache(opts, level)
+	case CacheVecOpts:
+		return g.newBaseCacheVec(opts, level)
+	case PoolOpts:
+		return g.newBasePool(opts, level)
+	case PoolVecOpts:
+		return g.newBasePoolVec(opts, level)
+	case CircuitBreakerOpts:
+		return g.newBaseCircuitBreaker(opts, level)
+	case CircuitBreakerVecOpts:
+		return g.newBaseCircuitBreakerVec(opts, level)
+	case QueueOpts:
+		return g.newBaseQueue(opts, level)
+	case QueueVecOpts:
+		return g.newBaseQueueVec(opts, level)
+	case ThroughputOpts:
+		return g.newBaseThroughput(opts, level)
+	case DistributionGaugeOpts:
+		return g.newBaseDistributionGauge(opts, level)
+	case MultiResHistogramOpts:
+		return g.newBaseMultiResHistogram(opts, level)
+	case DependencyOpts:
+		return g.newBaseDependency(opts, level)
 	default:
-		panic("can't convert unknown basic NoopMetric type")
+		panic("can't convert unknown NoopMetric opts type")
 	}
 }
 
-func (g *group) convertNoopComposite(metric CompositeMetric) CompositeMetric {
+// newNoopImpl constructs the noop implementation of a metric from the opts
+// it was constructed with, like [group.newRealImpl]
+func (g *group) newNoopImpl(opts any, level Level) Metric {
+	switch opts := opts.(type) {
+	case CounterOpts:
+		return newNoopCounter(opts, level)
+	case counterGroupOpts:
+		return newNoopCounterGroup(opts, level)
+	case counterFuncOpts:
+		return newNoopCounterFunc(opts, level)
+	case CounterVecOpts:
+		return newNoopCounterVec(opts, level)
+	case GaugeOpts:
+		return newNoopGauge(opts, level)
+	case atomicGaugeOpts:
+		return newNoopGauge(opts.GaugeOpts, level)
+	case gaugeFuncOpts:
+		return newNoopGaugeFunc(opts, level)
+	case GaugeVecOpts:
+		return newNoopGaugeVec(opts, level)
+	case HistogramOpts:
+		return newNoopHistogram(opts, level)
+	case HistogramVecOpts:
+		return newNoopHistogramVec(opts, level)
+	case SummaryOpts:
+		return newNoopSummary(opts, level)
+	case SummaryVecOpts:
+		return newNoopSummaryVec(opts, level)
+	case TimerOpts:
+		return newNoopTimer(opts, level)
+	case TimerVecOpts:
+		return newNoopTimerVec(opts, level)
+	case CacheOpts:
+		return newNoopCache(opts, level)
+	case CacheVecOpts:
+		return newNoopCacheVec(opts, level)
+	case PoolOpts:
+		return newNoopPool(opts, level)
+	case PoolVecOpts:
+		return newNoopPoolVec(opts, level)
+	case CircuitBreakerOpts:
+		return newNoopCircuitBreaker(opts, level)
+	case CircuitBreakerVecOpts:
+		return newNoopCircuitBreakerVec(opts, level)
+	case QueueOpts:
+		return newNoopQueue(opts, level)
+	case QueueVecOpts:
+		return newNoopQueueVec(opts, level)
+	case ThroughputOpts:
+		return newNoopThroughput(opts, level)
+	case DistributionGaugeOpts:
+		return newNoopDistributionGauge(opts, level)
+	case MultiResHistogramOpts:
+		return newNoopMultiResHistogram(opts, level)
+	case DependencyOpts:
+		return newNoopDependency(opts, level)
+	default:
+		panic("can't construct noop of unknown metric opts type")
+	}
+}
 
-	for _, component := range metric.Components() {
-		if noop, ok := component.(NoopMetric); ok {
+// relevel swaps the implementation of the tracked metric for a new level if
+// the level crosses the group's level, see [baseSwitchableMetric.SetLevel].
+// Returns false if the implementation is kept.
+//
+// It must not be called with the lock of [group.mu] or of the metric held.
+func (g *group) relevel(metric SwitchableMetric, name string, class MetricType, level Level) bool {
+	enabled := level.Enabled(g.level())
+	if enabled != metric.IsNoop() {
+		return false
+	}
+
+	if enabled {
+		metric.switchImpl(g.newRealImpl(metric.switchOpts(), level))
+	} else {
+		metric.switchImpl(g.newNoopImpl(metric.switchOpts(), level))
+	}
+
+	g.mu.Lock()
+	tracked := g.basics
+	if class == MetricTypeComposite {
+		tracked = g.composites
+	}
+	if tracked[name] != metric {
+		// Removed meanwhile, so the new implementation is stopped, like the
+		// removed one was by [group.Remove]
+		g.mu.Unlock()
+		stopMetric(metric)
+		return true
+	}
+	defer g.mu.Unlock()
+
+	metric.cacheEnabled(g.minLevel)
+	if enabled {
+		delete(g.noops, name)
+	} else {
+		g.noops[name] = class
+	}
+	return true
+}
 
-			// $$$SIMON can we guarantee that components always returns a slice of
-			// pointers to the actual components, so that this assignment works?
-			component = g.convertNoopPrime(noop)
+// reclaimDisabled replaces the implementation of every tracked real metric
+// whose level is disabled by the group's level with a noop, and unregisters
+// its backend metric, or those of its components, so that the backend stops
+// retaining and exporting them, see [LevelOpts.ReclaimDisabled].
+//
+// Backends that can't unregister metrics keep them. Components shared with
+// [WithSharedComponents] are kept too, as other metrics may still use them.
+func (g *group) reclaimDisabled() {
+	type tracked struct {
+		name   string
+		class  MetricType
+		metric SwitchableMetric
+	}
+
+	// Snapshot under the lock, as metrics are releveled without it
+	g.mu.RLock()
+	var metrics []tracked
+	for name, metric := range g.basics {
+		metrics = append(metrics, tracked{name, MetricTypeBasic, metric})
+	}
+	for name, metric := range g.composites {
+		metrics = append(metrics, tracked{name, MetricTypeComposite, metric})
+	}
+	g.mu.RUnlock()
+
+	backend, canUnregister := g.backend.(UnregisterBackend)
+	for _, m := range metrics {
+		// Switched to noop first, so that nothing records into the backend
+		// metrics once they are unregistered
+		if m.metric.IsNoop() || !g.relevel(m.metric, m.name, m.class, m.metric.Level()) || !canUnregister {
+			continue
+		}
+
+		infos := []MetricInfo{basicInfo(m.metric.switchOpts())}
+		if m.class == MetricTypeComposite {
+			infos = g.componentInfos(m.metric.switchOpts())
+		}
+		for _, info := range infos {
+			if !g.isShared(info.Name) {
+				backend.Unregister(info.QualifiedName())
+			}
 		}
 	}
+}
 
-	return metric
+// isShared returns true if name is the backend name of a shared metric, see
+// [WithSharedComponents]
+func (g *group) isShared(name string) bool {
+	g.sharedMu.Lock()
+	defer g.sharedMu.Unlock()
+	_, shared := g.shared[name]
+	return shared
 }
 
+// convertNoops replaces the implementation of every tracked noop metric
+// whose level is enabled by the group's level with a real implementation.
+//
+// The switchable wrappers are kept, so user references stay valid. Noops
+// that are still disabled remain tracked for a later conversion.
+//
+// The real implementations are built without holding [group.mu], as building
+// them calls the backend, and the factories for the components of composites.
+// The lock is only taken to snapshot the noops and to swap each of them, if
+// it was not releveled or removed meanwhile.
 func (g *group) convertNoops() {
+	type noop struct {
+		name   string
+		metric SwitchableMetric
+	}
+
+	g.mu.RLock()
+	var noops []noop
+	for name, class := range g.noops {
+		tracked := g.basics
+		if class == MetricTypeComposite {
+			tracked = g.composites
+		}
+		noops = append(noops, noop{name, tracked[name]})
+	}
+	minLevel := g.minLevel
+	g.mu.RUnlock()
+
+	for _, n := range noops {
+		if n.metric == nil || !n.metric.IsNoop() || !n.metric.Level().Enabled(minLevel) {
+			continue
+		}
+		impl := g.newRealImpl(n.metric.switchOpts(), n.metric.Level())
+
+		g.mu.Lock()
+		// Checked again, as the metric or the group may have been releveled
+		_, tracked := g.noops[n.name]
+		converted := tracked && n.metric.IsNoop() && n.metric.Level().Enabled(g.minLevel)
+		if converted {
+			n.metric.switchImpl(impl)
+			n.metric.cacheEnabled(g.minLevel)
+			delete(g.noops, n.name)
+		}
+		g.mu.Unlock()
+
+		// Stopped if discarded, so that its sampler, if any, doesn't leak
+		if !converted {
+			stopMetric(impl)
+		}
+	}
+
+	// Forget the noops that were releveled or removed meanwhile
 	g.mu.Lock()
 	defer g.mu.Unlock()
-
-	for _, class := range g.noops {
-		switch class {
-		case 1:
-			// do something
-		case 2:
-			// do something
+	for name, class := range g.noops {
+		tracked := g.basics
+		if class == MetricTypeComposite {
+			tracked = g.composites
+		}
+		if metric := tracked[name]; metric == nil || !metric.IsNoop() {
+			delete(g.noops, name)
 		}
 	}
 }
@@ -0,0 +1,1392 @@
+package umami
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testLabels = VecLabels{"route": "/"}
+
+// mockRecorded returns true if the mock adapter has recorded anything,
+// using [testLabels] for label-vectorized adapters
+func mockRecorded(adapter any) bool {
+	switch a := adapter.(type) {
+	case *mockCounterAdapter:
+		return a.GetCount() > 0
+	case *mockCounterVecAdapter:
+		return a.GetCount(testLabels) > 0
+	case *mockGaugeAdapter:
+		return a.GetValue() != 0
+	case *mockGaugeVecAdapter:
+		return a.GetValue(testLabels) != 0
+	case *mockHistogramAdapter:
+		return a.GetObservationCount() > 0
+	case *mockHistogramVecAdapter:
+		return a.GetObservationCount(testLabels) > 0
+	case *mockSummaryAdapter:
+		return len(a.GetObservations()) > 0
+	case *mockSummaryVecAdapter:
+		return len(a.GetObservations(testLabels)) > 0
+	default:
+		return false
+	}
+}
+
+// disabledGroupCase creates a metric on a group, records into it, and names
+// the backend adapter (or a component's adapter) that the recording lands in
+type disabledGroupCase struct {
+	name    string
+	adapter string
+	create  func(g Group) Metric
+	record  func(m Metric, ctx Context)
+}
+
+func disabledGroupCases() []disabledGroupCase {
+	labels := []string{"route"}
+	counter := func(name string) CounterOpts {
+		return CounterOpts{MetricInfo: MetricInfo{Name: name}}
+	}
+	counterVec := func(name string) CounterVecOpts {
+		return CounterVecOpts{MetricInfo: MetricInfo{Name: name}, Labels: labels}
+	}
+	gauge := func(name string) GaugeOpts {
+		return GaugeOpts{MetricInfo: MetricInfo{Name: name}}
+	}
+	gaugeVec := func(name string) GaugeVecOpts {
+		return GaugeVecOpts{MetricInfo: MetricInfo{Name: name}, Labels: labels}
+	}
+	histogram := func(name string) HistogramOpts {
+		return HistogramOpts{MetricInfo: MetricInfo{Name: name}}
+	}
+	histogramVec := func(name string) HistogramVecOpts {
+		return HistogramVecOpts{MetricInfo: MetricInfo{Name: name}, Labels: labels}
+	}
+
+	return []disabledGroupCase{
+		{
+			name: "Counter", adapter: "test_counter",
+			create: func(g Group) Metric { return g.Counter(counter("counter"), LevelCritical) },
+			record: func(m Metric, ctx Context) { _ = m.(Counter).Inc(ctx) },
+		},
+		{
+			name: "CounterVec", adapter: "test_counter_vec",
+			create: func(g Group) Metric { return g.CounterVec(counterVec("counter_vec"), LevelCritical) },
+			record: func(m Metric, ctx Context) { _ = m.(CounterVec).Inc(ctx, testLabels) },
+		},
+		{
+			name: "Gauge", adapter: "test_gauge",
+			create: func(g Group) Metric { return g.Gauge(gauge("gauge"), LevelCritical) },
+			record: func(m Metric, ctx Context) { _ = m.(Gauge).Set(ctx, 3) },
+		},
+		{
+			name: "GaugeVec", adapter: "test_gauge_vec",
+			create: func(g Group) Metric { return g.GaugeVec(gaugeVec("gauge_vec"), LevelCritical) },
+			record: func(m Metric, ctx Context) { _ = m.(GaugeVec).Set(ctx, 3, testLabels) },
+		},
+		{
+			name: "Histogram", adapter: "test_histogram",
+			create: func(g Group) Metric { return g.Histogram(histogram("histogram"), LevelCritical) },
+			record: func(m Metric, ctx Context) { _ = m.(Histogram).Observe(ctx, 1) },
+		},
+		{
+			name: "HistogramVec", adapter: "test_histogram_vec",
+			create: func(g Group) Metric { return g.HistogramVec(histogramVec("histogram_vec"), LevelCritical) },
+			record: func(m Metric, ctx Context) { _ = m.(HistogramVec).Observe(ctx, 1, testLabels) },
+		},
+		{
+			name: "Summary", adapter: "test_summary",
+			create: func(g Group) Metric {
+				return g.Summary(SummaryOpts{MetricInfo: MetricInfo{Name: "summary"}}, LevelCritical)
+			},
+			record: func(m Metric, ctx Context) { _ = m.(Summary).Observe(ctx, 1) },
+		},
+		{
+			name: "SummaryVec", adapter: "test_summary_vec",
+			create: func(g Group) Metric {
+				return g.SummaryVec(SummaryVecOpts{MetricInfo: MetricInfo{Name: "summary_vec"}, Labels: labels}, LevelCritical)
+			},
+			record: func(m Metric, ctx Context) { _ = m.(SummaryVec).Observe(ctx, 1, testLabels) },
+		},
+		{
+			name: "Timer", adapter: "test_timer_duration",
+			create: func(g Group) Metric {
+				return g.Timer(TimerOpts{MetricInfo: MetricInfo{Name: "timer"}, HistogramOpts: histogram("timer_duration")}, LevelCritical)
+			},
+			record: func(m Metric, ctx Context) { _ = m.(Timer).Record(ctx, time.Second) },
+		},
+		{
+			name: "TimerVec", adapter: "test_timer_vec_duration",
+			create: func(g Group) Metric {
+				return g.TimerVec(TimerVecOpts{MetricInfo: MetricInfo{Name: "timer_vec"}, HistogramVecOpts: histogramVec("timer_vec_duration")}, LevelCritical)
+			},
+			record: func(m Metric, ctx Context) { _ = m.(TimerVec).Record(ctx, time.Second, testLabels) },
+		},
+		{
+			name: "Cache", adapter: "test_cache_hits",
+			create: func(g Group) Metric {
+				return g.Cache(CacheOpts{
+					MetricInfo: MetricInfo{Name: "cache"},
+					HitOpts:    counter("cache_hits"),
+					MissOpts:   counter("cache_misses"),
+					SizeOpts:   gauge("cache_size"),
+				}, LevelCritical)
+			},
+			record: func(m Metric, ctx Context) { _ = m.(Cache).Hit(ctx) },
+		},
+		{
+			name: "CacheVec", adapter: "test_cache_vec_hits",
+			create: func(g Group) Metric {
+				return g.CacheVec(CacheVecOpts{
+					MetricInfo:  MetricInfo{Name: "cache_vec"},
+					HitVecOpts:  counterVec("cache_vec_hits"),
+					MissVecOpts: counterVec("cache_vec_misses"),
+					SizeVecOpts: gaugeVec("cache_vec_size"),
+				}, LevelCritical)
+			},
+			record: func(m Metric, ctx Context) { _ = m.(CacheVec).Hit(ctx, testLabels) },
+		},
+		{
+			name: "Pool", adapter: "test_pool_acquired",
+			create: func(g Group) Metric {
+				return g.Pool(PoolOpts{
+					MetricInfo:   MetricInfo{Name: "pool"},
+					ActiveOpts:   gauge("pool_active"),
+					IdleOpts:     gauge("pool_idle"),
+					AcquiredOpts: counter("pool_acquired"),
+					ReleasedOpts: counter("pool_released"),
+				}, LevelCritical)
+			},
+			record: func(m Metric, ctx Context) { _ = m.(Pool).Acquired(ctx) },
+		},
+		{
+			name: "PoolVec", adapter: "test_pool_vec_acquired",
+			create: func(g Group) Metric {
+				return g.PoolVec(PoolVecOpts{
+					MetricInfo:      MetricInfo{Name: "pool_vec"},
+					ActiveVecOpts:   gaugeVec("pool_vec_active"),
+					IdleVecOpts:     gaugeVec("pool_vec_idle"),
+					AcquiredVecOpts: counterVec("pool_vec_acquired"),
+					ReleasedVecOpts: counterVec("pool_vec_released"),
+				}, LevelCritical)
+			},
+			record: func(m Metric, ctx Context) { _ = m.(PoolVec).Acquired(ctx, testLabels) },
+		},
+		{
+			name: "CircuitBreaker", adapter: "test_breaker_failures",
+			create: func(g Group) Metric {
+				return g.CircuitBreaker(CircuitBreakerOpts{
+					MetricInfo:  MetricInfo{Name: "breaker"},
+					StateOpts:   gauge("breaker_state"),
+					SuccessOpts: counter("breaker_successes"),
+					FailureOpts: counter("breaker_failures"),
+				}, LevelCritical)
+			},
+			record: func(m Metric, ctx Context) { _ = m.(CircuitBreaker).Failure(ctx) },
+		},
+		{
+			name: "CircuitBreakerVec", adapter: "test_breaker_vec_failures",
+			create: func(g Group) Metric {
+				return g.CircuitBreakerVec(CircuitBreakerVecOpts{
+					MetricInfo:     MetricInfo{Name: "breaker_vec"},
+					StateVecOpts:   gaugeVec("breaker_vec_state"),
+					SuccessVecOpts: counterVec("breaker_vec_successes"),
+					FailureVecOpts: counterVec("breaker_vec_failures"),
+				}, LevelCritical)
+			},
+			record: func(m Metric, ctx Context) { _ = m.(CircuitBreakerVec).Failure(ctx, testLabels) },
+		},
+		{
+			name: "Queue", adapter: "test_queue_enqueued",
+			create: func(g Group) Metric {
+				return g.Queue(QueueOpts{
+					MetricInfo:   MetricInfo{Name: "queue"},
+					DepthOpts:    gauge("queue_depth"),
+					EnqueuedOpts: counter("queue_enqueued"),
+					DequeuedOpts: counter("queue_dequeued"),
+					WaitTimeOpts: histogram("queue_wait_time"),
+				}, LevelCritical)
+			},
+			record: func(m Metric, ctx Context) { _ = m.(Queue).Enqueued(ctx) },
+		},
+		{
+			name: "QueueVec", adapter: "test_queue_vec_enqueued",
+			create: func(g Group) Metric {
+				return g.QueueVec(QueueVecOpts{
+					MetricInfo:      MetricInfo{Name: "queue_vec"},
+					DepthVecOpts:    gaugeVec("queue_vec_depth"),
+					EnqueuedVecOpts: counterVec("queue_vec_enqueued"),
+					DequeuedVecOpts: counterVec("queue_vec_dequeued"),
+					WaitTimeVecOpts: histogramVec("queue_vec_wait_time"),
+				}, LevelCritical)
+			},
+			record: func(m Metric, ctx Context) { _ = m.(QueueVec).Enqueued(ctx, testLabels) },
+		},
+		{
+			name: "Throughput", adapter: "test_throughput_total",
+			create: func(g Group) Metric {
+				return g.Throughput(ThroughputOpts{
+					MetricInfo: MetricInfo{Name: "throughput"},
+					CountOpts:  counter("throughput_total"),
+					RateOpts:   gauge("throughput_rate"),
+				}, LevelCritical)
+			},
+			record: func(m Metric, ctx Context) { _ = m.(Throughput).Mark(ctx) },
+		},
+		{
+			name: "DistributionGauge", adapter: "test_distribution_latest",
+			create: func(g Group) Metric {
+				return g.DistributionGauge(DistributionGaugeOpts{
+					MetricInfo:    MetricInfo{Name: "distribution"},
+					GaugeOpts:     gauge("distribution_latest"),
+					HistogramOpts: histogram("distribution_observed"),
+				}, LevelCritical)
+			},
+			record: func(m Metric, ctx Context) { _ = m.(DistributionGauge).Observe(ctx, 1) },
+		},
+		{
+			name: "MultiResHistogram", adapter: "test_multi_res_res0",
+			create: func(g Group) Metric {
+				return g.MultiResHistogram(MultiResHistogramOpts{
+					MetricInfo:  MetricInfo{Name: "multi_res"},
+					Resolutions: []HistogramOpts{{}, {}},
+				}, LevelCritical)
+			},
+			record: func(m Metric, ctx Context) { _ = m.(MultiResHistogram).Observe(ctx, 1) },
+		},
+	}
+}
+
+func TestGroupSetLevelConcurrentCreation(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := range 100 {
+			group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: fmt.Sprintf("counter_%d", i)}}, LevelDebug)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := range 100 {
+			group.SetGroupLevel([]Level{LevelDebug, LevelImportant}[i%2], LevelOpts{})
+		}
+	}()
+	wg.Wait()
+}
+
+func TestGroupReplaceNoopsConcurrentRecording(t *testing.T) {
+	group := NewRegistry(LevelImportant).NewGroup("test", NewMockBackend())
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests"}}, LevelDebug)
+	queue := newTestQueue(group)
+	queue.SetLevel(LevelDebug)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		ctx := NewContextAll()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				_ = counter.Inc(ctx)
+				_ = queue.Enqueue(ctx)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i := range 100 {
+			level := []Level{LevelDebug, LevelImportant}[i%2]
+			group.SetGroupLevel(level, LevelOpts{ReplaceNoops: true, ReclaimDisabled: true})
+		}
+	}()
+	wg.Wait()
+
+	group.SetGroupLevel(LevelDebug, LevelOpts{ReplaceNoops: true})
+	if counter.(SwitchableMetric).IsNoop() || queue.(SwitchableMetric).IsNoop() {
+		t.Error("Expected the metrics to be real once the group enables them")
+	}
+}
+
+func TestGroupDisabledToEnabled(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDisabled).NewGroup("test", backend)
+
+	cases := disabledGroupCases()
+	metrics := make([]Metric, len(cases))
+	for i, tc := range cases {
+		metrics[i] = tc.create(group)
+
+		switchable, ok := metrics[i].(SwitchableMetric)
+		if !ok {
+			t.Fatalf("%s: expected a switchable metric, got %T", tc.name, metrics[i])
+		}
+		if !switchable.IsNoop() {
+			t.Errorf("%s: expected a noop on a disabled group", tc.name)
+		}
+		if backend.adapter(tc.adapter) != nil {
+			t.Errorf("%s: expected no backend adapter while disabled", tc.name)
+		}
+	}
+
+	group.SetGroupLevel(LevelDebug, LevelOpts{ReplaceNoops: true})
+	ctx := group.Context()
+
+	for i, tc := range cases {
+		// The original references must now record into the backend
+		if metrics[i].(SwitchableMetric).IsNoop() {
+			t.Errorf("%s: expected a real implementation after enabling", tc.name)
+			continue
+		}
+
+		tc.record(metrics[i], ctx)
+
+		adapter := backend.adapter(tc.adapter)
+		if adapter == nil {
+			t.Errorf("%s: expected backend adapter %q after enabling", tc.name, tc.adapter)
+			continue
+		}
+		if !mockRecorded(adapter) {
+			t.Errorf("%s: expected a recording in %q", tc.name, tc.adapter)
+		}
+
+		// Requesting the metric again returns the same reference
+		if again := tc.create(group); again != metrics[i] {
+			t.Errorf("%s: expected the tracked reference to be returned", tc.name)
+		}
+	}
+}
+
+func TestGroupReplaceNoopsKeepsDisabledLevels(t *testing.T) {
+	group := NewRegistry(LevelDisabled).NewGroup("test", NewMockBackend())
+
+	critical := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "critical"}}, LevelCritical)
+	verbose := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "verbose"}}, LevelVerbose)
+
+	group.SetGroupLevel(LevelImportant, LevelOpts{ReplaceNoops: true})
+
+	if critical.(SwitchableMetric).IsNoop() {
+		t.Error("Expected critical counter to be converted")
+	}
+	if !verbose.(SwitchableMetric).IsNoop() {
+		t.Error("Expected verbose counter to remain a noop")
+	}
+
+	group.SetGroupLevel(LevelVerbose, LevelOpts{ReplaceNoops: true})
+
+	if verbose.(SwitchableMetric).IsNoop() {
+		t.Error("Expected verbose counter to be converted once its level is enabled")
+	}
+}
+
+func TestGroupReclaimDisabled(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug).NewGroup("test", backend)
+
+	critical := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "critical"}}, LevelCritical)
+	debug := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "debug"}}, LevelDebug)
+	cache := group.Cache(CacheOpts{
+		MetricInfo: MetricInfo{Name: "cache"},
+		HitOpts:    CounterOpts{MetricInfo: MetricInfo{Name: "cache_hits"}},
+		MissOpts:   CounterOpts{MetricInfo: MetricInfo{Name: "cache_misses"}},
+		SizeOpts:   GaugeOpts{MetricInfo: MetricInfo{Name: "cache_size"}},
+	}, LevelDebug)
+
+	group.SetGroupLevel(LevelImportant, LevelOpts{ReclaimDisabled: true})
+	if critical.(SwitchableMetric).IsNoop() || backend.adapter("test_critical") == nil {
+		t.Error("Expected the enabled counter to be kept")
+	}
+	if !debug.(SwitchableMetric).IsNoop() || !cache.(SwitchableMetric).IsNoop() {
+		t.Error("Expected the disabled metrics to be switched to noops")
+	}
+	for _, name := range []string{"test_debug", "test_cache_hits", "test_cache_misses", "test_cache_size"} {
+		if backend.adapter(name) != nil {
+			t.Errorf("Expected %s to be unregistered", name)
+		}
+	}
+
+	// Enabling them again registers new backend metrics
+	group.SetGroupLevel(LevelDebug, LevelOpts{ReplaceNoops: true})
+	if debug.(SwitchableMetric).IsNoop() || backend.adapter("test_debug") == nil || backend.adapter("test_cache_hits") == nil {
+		t.Error("Expected the reclaimed metrics to be real again")
+	}
+}
+
+func TestGroupLevelCachesEnabled(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelVerbose).NewGroup("test", backend)
+	debug := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "debug"}}, LevelDebug)
+	cache := group.Cache(CacheOpts{
+		MetricInfo: MetricInfo{Name: "cache"},
+		HitOpts:    CounterOpts{MetricInfo: MetricInfo{Name: "cache_hits"}},
+	}, LevelDebug)
+
+	base := debug.(*switchableCounter).impl.(*baseCounter)
+	hits := cache.Components()[0].(*switchableCounter).impl.(*baseCounter)
+	if base.groupDisabled != 0 || hits.groupDisabled != 0 {
+		t.Fatal("Expected metrics enabled by the group level to be cached as enabled")
+	}
+
+	// The real debug metrics keep their level, and are disabled by the group
+	group.SetGroupLevel(LevelImportant, LevelOpts{ReplaceNoops: true})
+	if base.groupDisabled != 1 || hits.groupDisabled != 1 {
+		t.Error("Expected the cache to be recomputed by the level change")
+	}
+	debug.Inc(NewContextAll())
+	cache.Hit(NewContextAll())
+	if got := backend.adapter("test_debug").(*mockCounterAdapter).GetCount(); got != 0 {
+		t.Errorf("Expected metrics disabled by the group not to record, got %v", got)
+	}
+
+	group.SetGroupLevel(LevelDebug, LevelOpts{ReplaceNoops: true})
+	if base.groupDisabled != 0 || hits.groupDisabled != 0 {
+		t.Error("Expected the cache to be recomputed by the level change")
+	}
+	debug.Inc(NewContextAll())
+	cache.Hit(NewContextAll())
+	if got := backend.adapter("test_debug").(*mockCounterAdapter).GetCount(); got != 1 {
+		t.Errorf("Expected metrics enabled again to record, got %v", got)
+	}
+	if got := backend.adapter("test_cache_hits").(*mockCounterAdapter).GetCount(); got != 1 {
+		t.Errorf("Expected components enabled again to record, got %v", got)
+	}
+}
+
+func TestGroupGetOrCreate(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+	opts := CounterOpts{MetricInfo: MetricInfo{Name: "setup"}}
+
+	first, created := group.GetOrCreateCounter(opts, LevelCritical)
+	if !created {
+		t.Error("Expected first call to create the counter")
+	}
+
+	second, created := group.GetOrCreateCounter(opts, LevelCritical)
+	if created {
+		t.Error("Expected second call to return the existing counter")
+	}
+	if first != second {
+		t.Error("Expected the same counter reference")
+	}
+
+	if _, created := group.GetOrCreateCounter(opts, LevelDebug); created {
+		t.Error("Expected a different level to still return the existing counter")
+	}
+
+	cacheOpts := CacheOpts{
+		MetricInfo: MetricInfo{Name: "cache"},
+		HitOpts:    CounterOpts{MetricInfo: MetricInfo{Name: "cache_hits"}},
+		MissOpts:   CounterOpts{MetricInfo: MetricInfo{Name: "cache_misses"}},
+		SizeOpts:   GaugeOpts{MetricInfo: MetricInfo{Name: "cache_size"}},
+	}
+	if _, created := group.GetOrCreateCache(cacheOpts, LevelCritical); !created {
+		t.Error("Expected first call to create the cache")
+	}
+	if _, created := group.GetOrCreateCache(cacheOpts, LevelCritical); created {
+		t.Error("Expected second call to return the existing cache")
+	}
+}
+
+func TestMetricAs(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests"}}, LevelImportant)
+	timer := group.Timer(TimerOpts{MetricInfo: MetricInfo{Name: "latency"}}, LevelImportant)
+
+	got, err := MetricAs[Counter](group, counter.Name())
+	if err != nil || got != counter {
+		t.Errorf("Expected the counter, got %v, %v", got, err)
+	}
+	if got, err := MetricAs[Timer](group, "latency"); err != nil || got != timer {
+		t.Errorf("Expected the timer, got %v, %v", got, err)
+	}
+
+	if got, err := MetricAs[Gauge](group, counter.Name()); !errors.Is(err, ErrMetricTypeMismatch) || got != nil {
+		t.Errorf("Expected ErrMetricTypeMismatch, got %v, %v", got, err)
+	}
+	if _, err := MetricAs[Counter](group, "missing"); !errors.Is(err, ErrMetricNotFound) {
+		t.Errorf("Expected ErrMetricNotFound, got %v", err)
+	}
+}
+
+// countingBackend counts the counters created by its backend
+type countingBackend struct {
+	Backend
+	counters atomic.Int32
+}
+
+func (b *countingBackend) Counter(opts CounterOpts) CounterAdapter {
+	b.counters.Add(1)
+	return b.Backend.Counter(opts)
+}
+
+// TestGroupGetOrCreateConcurrent checks that concurrent requests of the same
+// metric create a single backend metric, as a second registration of the
+// same name panics with Prometheus
+func TestGroupGetOrCreateConcurrent(t *testing.T) {
+	backend := &countingBackend{Backend: NewMockBackend()}
+	group := NewRegistry(LevelDebug).NewGroup("test", backend)
+	opts := CounterOpts{MetricInfo: MetricInfo{Name: "racy"}}
+
+	const workers = 50
+	results := make(chan bool, workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, created := group.GetOrCreateCounter(opts, LevelCritical)
+			results <- created
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	creations := 0
+	for created := range results {
+		if created {
+			creations++
+		}
+	}
+	if creations != 1 {
+		t.Errorf("Expected exactly one creation, got %d", creations)
+	}
+	if counters := backend.counters.Load(); counters != 1 {
+		t.Errorf("Expected exactly one backend counter, got %d", counters)
+	}
+}
+
+func TestGroupTags(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+	ctx := group.Context()
+
+	slo := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "slo", Tags: []string{"slo", "latency"}}}, LevelCritical)
+	untagged := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "untagged"}}, LevelCritical)
+	sloAdapter, untaggedAdapter := mockCounterOf(t, slo), mockCounterOf(t, untagged)
+
+	group.DisableTags("latency")
+	_ = slo.Inc(ctx)
+	_ = untagged.Inc(ctx)
+	if got := sloAdapter.GetCount(); got != 0 {
+		t.Errorf("Expected no recording while any tag is disabled, got %v", got)
+	}
+	if got := untaggedAdapter.GetCount(); got != 1 {
+		t.Errorf("Expected untagged metrics to be unaffected, got %v", got)
+	}
+
+	group.EnableTags("latency")
+	_ = slo.Inc(ctx)
+	if got := sloAdapter.GetCount(); got != 1 {
+		t.Errorf("Expected recording after re-enabling the tag, got %v", got)
+	}
+
+	group.DisableTags("slo", "unused")
+	group.EnableTags("unused")
+	_ = slo.Inc(ctx)
+	if got := sloAdapter.GetCount(); got != 1 {
+		t.Errorf("Expected no recording while the slo tag is disabled, got %v", got)
+	}
+}
+
+func TestGroupTagsComposite(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+	ctx := group.Context()
+
+	cache := group.Cache(CacheOpts{
+		MetricInfo: MetricInfo{Name: "cache", Tags: []string{"cache"}},
+		HitOpts:    CounterOpts{MetricInfo: MetricInfo{Name: "cache_hits"}},
+		MissOpts:   CounterOpts{MetricInfo: MetricInfo{Name: "cache_misses", Tags: []string{"misses"}}},
+		SizeOpts:   GaugeOpts{MetricInfo: MetricInfo{Name: "cache_size"}},
+	}, LevelCritical)
+	base := cache.(*switchableCache).impl.(*baseCache)
+	hits, misses := mockCounterOf(t, base.hits), mockCounterOf(t, base.misses)
+
+	group.DisableTags("cache")
+	_ = cache.Hit(ctx)
+	if got := hits.GetCount(); got != 0 {
+		t.Errorf("Expected composite tags to apply to components, got %v", got)
+	}
+
+	group.EnableTags("cache")
+	group.DisableTags("misses")
+	_ = cache.Hit(ctx)
+	_ = cache.Miss(ctx)
+	if hits.GetCount() != 1 || misses.GetCount() != 0 {
+		t.Errorf("Expected only the misses component to be disabled, got hits=%v misses=%v", hits.GetCount(), misses.GetCount())
+	}
+}
+
+func TestGroupNamespaceComposite(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug).NewGroup("test", backend)
+	ctx := group.Context()
+
+	cache := group.Cache(CacheOpts{
+		MetricInfo: MetricInfo{
+			Name:        "cache",
+			Namespace:   "shop",
+			Subsystem:   "checkout",
+			ConstLabels: map[string]string{"service": "api", "tier": "edge"},
+		},
+		MissOpts: CounterOpts{MetricInfo: MetricInfo{Subsystem: "lookup", ConstLabels: map[string]string{"tier": "origin"}}},
+	}, LevelDebug)
+	_ = cache.Hit(ctx)
+	_ = cache.Miss(ctx)
+
+	// Components have the namespace, subsystem and const labels of the
+	// composite, unless they set their own
+	want := map[string]VecLabels{
+		"shop_checkout_test_cache_hit": {"service": "api", "tier": "edge"},
+		"shop_lookup_test_cache_miss":  {"service": "api", "tier": "origin"},
+	}
+	families, _ := backend.Gather()
+	for _, family := range families {
+		labels, ok := want[family.Name]
+		if !ok {
+			continue
+		}
+		delete(want, family.Name)
+		if len(family.Samples) != 1 || family.Samples[0].Value != 1 || !maps.Equal(family.Samples[0].Labels, labels) {
+			t.Errorf("Expected %s to be 1 with labels %v, got %+v", family.Name, labels, family.Samples)
+		}
+	}
+	if len(want) != 0 {
+		t.Errorf("Expected the components %v to be gathered", slices.Sorted(maps.Keys(want)))
+	}
+
+	// Components are unregistered by their qualified names
+	group.SetGroupLevel(LevelImportant, LevelOpts{ReclaimDisabled: true})
+	for _, name := range []string{"shop_checkout_test_cache_hit", "shop_lookup_test_cache_miss", "shop_checkout_test_cache_size"} {
+		if backend.adapter(name) != nil {
+			t.Errorf("Expected %s to be unregistered", name)
+		}
+	}
+}
+
+func TestCompositeUpdateAtomicWithLevelSwitch(t *testing.T) {
+	// Both levels are enabled by the group, so the queue is never swapped
+	group := NewRegistry(LevelVerbose).NewGroup("test", NewMockBackend())
+	queue := newTestQueue(group)
+	base := queue.(*switchableQueue).impl.(*baseQueue)
+
+	// Operations use a fixed context, so flipping the metric's level between
+	// LevelDebug and LevelVerbose toggles recording on and off
+	ctx := NewContext(LevelDebug)
+
+	const iterations = 5000
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := range iterations {
+			if i%2 == 0 {
+				queue.SetLevel(LevelVerbose)
+			} else {
+				queue.SetLevel(LevelDebug)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range iterations {
+			_ = queue.Enqueue(ctx)
+		}
+	}()
+	wg.Wait()
+
+	enqueued := mockCounterOf(t, base.enqueued).GetCount()
+	depth := base.depth.(*switchableGauge).impl.(*baseGauge).adapter.(*mockGaugeAdapter).GetValue()
+	if enqueued != depth {
+		t.Errorf("Expected every Enqueue to update all components or none, got enqueued=%v depth=%v", enqueued, depth)
+	}
+	if queue.Level() != LevelDebug || base.depth.Level() != LevelDebug {
+		t.Errorf("Expected the level to propagate to components, got %v and %v", queue.Level(), base.depth.Level())
+	}
+}
+
+// newTestThroughput creates an auto-started throughput named name on group
+func newTestThroughput(group Group, name string) Throughput {
+	return group.Throughput(ThroughputOpts{
+		MetricInfo: MetricInfo{Name: name},
+		CountOpts:  CounterOpts{MetricInfo: MetricInfo{Name: name + "_total"}},
+		RateOpts:   GaugeOpts{MetricInfo: MetricInfo{Name: name + "_rate"}},
+		Window:     time.Millisecond,
+		AutoStart:  true,
+	}, LevelImportant)
+}
+
+// assertNoGoroutineLeak fails the test if the number of goroutines doesn't
+// return to baseline within a second
+func assertNoGoroutineLeak(t *testing.T, baseline int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > baseline {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d goroutines, got %d", baseline, runtime.NumGoroutine())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestGroupRemove(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+	baseline := runtime.NumGoroutine()
+
+	throughput := newTestThroughput(group, "events")
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "count"}}, LevelImportant)
+	if runtime.NumGoroutine() <= baseline {
+		t.Fatal("Expected the throughput to start a ticker")
+	}
+
+	if !group.Remove("events") {
+		t.Fatal("Expected the throughput to be removed")
+	}
+	assertNoGoroutineLeak(t, baseline)
+	if group.Metric("events") != nil {
+		t.Error("Expected the throughput to no longer be tracked")
+	}
+	if throughput.(*switchableThroughput).impl.(*baseThroughput).running() {
+		t.Error("Expected the throughput to be stopped")
+	}
+
+	if !group.Remove(counter.Name()) {
+		t.Error("Expected the counter to be removed")
+	}
+	if group.Remove("events") {
+		t.Error("Expected removing an untracked metric to return false")
+	}
+
+	// A removed name can be created again
+	if _, created := group.GetOrCreateCounter(CounterOpts{MetricInfo: MetricInfo{Name: "count"}}, LevelImportant); !created {
+		t.Error("Expected a removed metric to be created again")
+	}
+}
+
+func TestGroupStopsSamplers(t *testing.T) {
+	reg := NewRegistry(LevelImportant)
+	baseline := runtime.NumGoroutine()
+
+	// Hides the func support of the mock, so that the metrics are sampled
+	group := reg.NewGroup("test", struct{ Backend }{NewMockBackend()})
+
+	var inFlight atomic.Int64
+	for i := range 20 {
+		gauge := group.GaugeFromAtomic(GaugeOpts{MetricInfo: MetricInfo{Name: fmt.Sprintf("in_flight_%d", i)}}, LevelImportant, &inFlight)
+		if !group.Remove(gauge.Name()) {
+			t.Fatalf("Expected %s to be removed", gauge.Name())
+		}
+	}
+	assertNoGoroutineLeak(t, baseline)
+
+	read := func() float64 { return 1 }
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "jobs_total"}}, LevelImportant)
+	metrics := []Metric{
+		group.GaugeFunc(GaugeOpts{MetricInfo: MetricInfo{Name: "queue_length"}}, read, LevelImportant),
+		group.CounterFunc(CounterOpts{MetricInfo: MetricInfo{Name: "processed_total"}}, read, LevelImportant),
+		group.CounterGroup(CounterOpts{MetricInfo: MetricInfo{Name: "all_jobs_total"}}, LevelImportant, counter),
+		group.GaugeFromAtomic(GaugeOpts{MetricInfo: MetricInfo{Name: "in_flight"}}, LevelImportant, &inFlight),
+	}
+	if runtime.NumGoroutine() < baseline+len(metrics) {
+		t.Fatal("Expected the metrics to be sampled")
+	}
+
+	// Releveling replaces the implementations, stopping the replaced samplers
+	for range 3 {
+		for _, metric := range metrics {
+			metric.SetLevel(LevelDebug)
+			metric.SetLevel(LevelImportant)
+		}
+	}
+	if got := runtime.NumGoroutine(); got > baseline+len(metrics) {
+		t.Errorf("Expected the replaced samplers to be stopped, got %d goroutines over %d", got-baseline, len(metrics))
+	}
+
+	if !reg.DeleteGroup("test") {
+		t.Fatal("Expected the group to be deleted")
+	}
+	assertNoGoroutineLeak(t, baseline)
+}
+
+func TestGroupRelevelStopsRemoved(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	// Hides the func support of the mock, so that the metrics are sampled
+	g := NewRegistry(LevelImportant).NewGroup("test", struct{ Backend }{NewMockBackend()})
+	gauge := g.GaugeFunc(GaugeOpts{MetricInfo: MetricInfo{Name: "queue_length"}}, func() float64 { return 1 }, LevelImportant)
+	g.Remove(gauge.Name())
+
+	// Releveling a stale reference doesn't restart the sampler
+	gauge.SetLevel(LevelDebug)
+	gauge.SetLevel(LevelImportant)
+	assertNoGoroutineLeak(t, baseline)
+	if noops := g.(*group).noops; len(noops) != 0 {
+		t.Errorf("Expected the removed gauge not to be tracked as a noop, got %v", noops)
+	}
+}
+
+func TestGroupConvertNoopsStopsDiscarded(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	backend := &hookGaugeBackend{Backend: NewMockBackend()}
+	group := NewRegistry(LevelImportant).NewGroup("test", backend)
+	group.GaugeFunc(GaugeOpts{MetricInfo: MetricInfo{Name: "queue_length"}}, func() float64 { return 1 }, LevelDebug)
+
+	// Removed while its real implementation is built, which is then discarded
+	backend.onGauge = func() { group.Remove("test_queue_length") }
+	group.SetGroupLevel(LevelDebug, LevelOpts{ReplaceNoops: true})
+
+	if group.Metric("test_queue_length") != nil {
+		t.Fatal("Expected the gauge func to be removed")
+	}
+	assertNoGoroutineLeak(t, baseline)
+}
+
+// hookGaugeBackend calls onGauge, if set, whenever a gauge is created. It
+// hides the func support of the wrapped backend, so that funcs are sampled.
+type hookGaugeBackend struct {
+	Backend
+	onGauge func()
+}
+
+func (h *hookGaugeBackend) Gauge(opts GaugeOpts) GaugeAdapter {
+	if h.onGauge != nil {
+		h.onGauge()
+	}
+	return h.Backend.Gauge(opts)
+}
+
+func TestGroupPreInitLabels(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug).NewGroup("test", backend)
+	routes := []VecLabels{{"route": "/a"}, {"route": "/b"}}
+
+	// Counter vecs of the mock backend fall back to adding 0
+	group.CounterVec(CounterVecOpts{MetricInfo: MetricInfo{Name: "requests"}, Labels: []string{"route"}, PreInitLabels: routes}, LevelImportant)
+	// Histogram vecs of the mock backend are a VecInitAdapter
+	group.HistogramVec(HistogramVecOpts{MetricInfo: MetricInfo{Name: "latency"}, Labels: []string{"route"}, PreInitLabels: routes}, LevelImportant)
+
+	counts := backend.adapter("test_requests").(*mockCounterVecAdapter).counts
+	observations := backend.adapter("test_latency").(*mockHistogramVecAdapter).observations
+	if len(counts) != 2 || len(observations) != 2 {
+		t.Errorf("Expected 2 initialized series each, got %v and %v", counts, observations)
+	}
+
+	// Disabled metrics are initialized when converted from a noop
+	group.SetGroupLevel(LevelCritical, LevelOpts{})
+	group.GaugeVec(GaugeVecOpts{MetricInfo: MetricInfo{Name: "in_flight"}, Labels: []string{"route"}, PreInitLabels: routes}, LevelImportant)
+	if backend.adapter("test_in_flight") != nil {
+		t.Fatal("Expected no adapter for a noop")
+	}
+	group.SetGroupLevel(LevelImportant, LevelOpts{ReplaceNoops: true})
+	if values := backend.adapter("test_in_flight").(*mockGaugeVecAdapter).values; len(values) != 2 {
+		t.Errorf("Expected 2 initialized series after conversion, got %v", values)
+	}
+}
+
+func TestGroupNoPrefix(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug).NewGroup("web", backend)
+
+	counter := group.Counter(CounterOpts{
+		BasicMetricOpts: BasicMetricOpts{NoPrefix: true},
+		MetricInfo:      MetricInfo{Name: "http_requests_total"},
+	}, LevelImportant)
+	if counter.Name() != "http_requests_total" || backend.adapter("http_requests_total") == nil {
+		t.Errorf("Expected the counter name as given, got %q", counter.Name())
+	}
+
+	group.Timer(TimerOpts{
+		CompositeMetricOpts: CompositeMetricOpts{NoPrefix: true},
+		MetricInfo:          MetricInfo{Name: "op"},
+		HistogramOpts:       HistogramOpts{MetricInfo: MetricInfo{Name: "op_duration_seconds"}},
+	}, LevelImportant)
+	if backend.adapter("op_duration_seconds") == nil {
+		t.Error("Expected the composite's components to be named as given")
+	}
+
+	// Prefixing stays the default
+	if prefixed := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "errors"}}, LevelImportant); prefixed.Name() != "web_errors" {
+		t.Errorf("Expected a prefixed name, got %q", prefixed.Name())
+	}
+}
+
+func TestGroupNameCollisionPanics(t *testing.T) {
+	reg := NewRegistry(LevelDebug)
+	backend := NewMockBackend()
+	opts := CounterOpts{BasicMetricOpts: BasicMetricOpts{NoPrefix: true}, MetricInfo: MetricInfo{Name: "up"}}
+
+	reg.NewGroup("web", backend).Counter(opts, LevelImportant)
+	reg.NewGroup("web", backend).Counter(opts, LevelImportant) // The same group may get it again
+
+	assertPanics := func(name string, create func()) {
+		t.Helper()
+		defer func() {
+			if err, _ := recover().(error); !errors.Is(err, ErrMetricNameTaken) {
+				t.Errorf("Expected %s to panic with ErrMetricNameTaken, got %v", name, err)
+			}
+		}()
+		create()
+	}
+	assertPanics("a name used by another group", func() {
+		reg.NewGroup("db", backend).Counter(opts, LevelImportant)
+	})
+	assertPanics("a prefixed name used by another group", func() {
+		reg.NewGroup("web_api", backend).Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests"}}, LevelImportant)
+		reg.NewGroup("web", backend).Counter(CounterOpts{MetricInfo: MetricInfo{Name: "api_requests"}}, LevelImportant)
+	})
+
+	// Deleting a group releases its names
+	reg.DeleteGroup("web")
+	reg.NewGroup("db", backend).Counter(opts, LevelImportant)
+}
+
+func TestGroupRemoveReleasesName(t *testing.T) {
+	reg := NewRegistry(LevelDebug)
+	backend := NewMockBackend()
+	opts := CounterOpts{BasicMetricOpts: BasicMetricOpts{NoPrefix: true}, MetricInfo: MetricInfo{Name: "shared_total"}}
+	cacheOpts := CacheOpts{
+		MetricInfo:          MetricInfo{Name: "cache"},
+		CompositeMetricOpts: CompositeMetricOpts{NoPrefix: true},
+		HitOpts:             CounterOpts{MetricInfo: MetricInfo{Name: "cache_hits"}},
+	}
+
+	a := reg.NewGroup("a", backend)
+	a.Counter(opts, LevelImportant)
+	a.Cache(cacheOpts, LevelImportant)
+	if !a.Remove("shared_total") || !a.Remove("cache") {
+		t.Fatal("Expected the metrics to be removed")
+	}
+
+	// Panics if the names of the removed metrics are still claimed by a
+	b := reg.NewGroup("b", backend)
+	b.Counter(opts, LevelImportant)
+	b.Cache(cacheOpts, LevelImportant)
+}
+
+func TestGroupComponentNameCollisionPanics(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("web", NewMockBackend())
+	cacheOpts := CacheOpts{
+		MetricInfo: MetricInfo{Name: "cache"},
+		HitOpts:    CounterOpts{MetricInfo: MetricInfo{Name: "cache_hits"}},
+		MissOpts:   CounterOpts{MetricInfo: MetricInfo{Name: "cache_misses"}},
+		SizeOpts:   GaugeOpts{MetricInfo: MetricInfo{Name: "cache_size"}},
+	}
+	group.Cache(cacheOpts, LevelImportant)
+	group.Cache(cacheOpts, LevelImportant) // The same composite may be requested again
+	group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests"}}, LevelImportant)
+
+	assertPanics := func(name string, create func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected %s to panic", name)
+			}
+		}()
+		create()
+	}
+	assertPanics("a basic named like a component", func() {
+		group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "cache_hits"}}, LevelImportant)
+	})
+	assertPanics("a component named like a basic", func() {
+		group.Timer(TimerOpts{
+			MetricInfo:    MetricInfo{Name: "request_timer"},
+			HistogramOpts: HistogramOpts{MetricInfo: MetricInfo{Name: "requests"}},
+		}, LevelImportant)
+	})
+	assertPanics("a component named like another composite's component", func() {
+		group.Throughput(ThroughputOpts{
+			MetricInfo: MetricInfo{Name: "hit_rate"},
+			CountOpts:  CounterOpts{MetricInfo: MetricInfo{Name: "cache_hits"}},
+			RateOpts:   GaugeOpts{MetricInfo: MetricInfo{Name: "hit_rate"}},
+		}, LevelImportant)
+	})
+	if group.Metric("request_timer") != nil || group.Metric("hit_rate") != nil {
+		t.Error("Expected colliding composites not to be tracked")
+	}
+
+	// Removing a composite releases the names of its components
+	group.Remove("cache")
+	group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "cache_hits"}}, LevelImportant)
+}
+
+func TestGroupSharedComponents(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug, WithSharedComponents(true)).NewGroup("web", backend)
+	cacheOpts := func(name string) CacheOpts {
+		return CacheOpts{
+			MetricInfo: MetricInfo{Name: name},
+			HitOpts:    CounterOpts{MetricInfo: MetricInfo{Name: name + "_hits"}},
+			MissOpts:   CounterOpts{MetricInfo: MetricInfo{Name: "backend_requests_total"}},
+			SizeOpts:   GaugeOpts{MetricInfo: MetricInfo{Name: name + "_size"}},
+		}
+	}
+	users := group.Cache(cacheOpts("users"), LevelImportant)
+	adapter := backend.adapter("web_backend_requests_total")
+	sessions := group.Cache(cacheOpts("sessions"), LevelImportant)
+	if backend.adapter("web_backend_requests_total") != adapter {
+		t.Fatal("Expected the second cache to reuse the shared miss counter")
+	}
+
+	ctx := NewContextAll()
+	_ = users.Miss(ctx)
+	_ = sessions.Miss(ctx)
+	_ = sessions.Miss(ctx)
+	if count := adapter.(*mockCounterAdapter).GetCount(); count != 3 {
+		t.Errorf("Expected 3 backend requests, got %v", count)
+	}
+
+	// Basics are shared with components, both ways
+	requests := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "backend_requests_total"}}, LevelImportant)
+	_ = requests.Inc(ctx)
+	if count := adapter.(*mockCounterAdapter).GetCount(); count != 4 {
+		t.Errorf("Expected the basic to share the miss counter, got %v requests", count)
+	}
+	group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "lookups_total"}}, LevelImportant)
+	lookups := backend.adapter("web_lookups_total")
+	_ = group.Cache(CacheOpts{
+		MetricInfo: MetricInfo{Name: "tokens"},
+		HitOpts:    CounterOpts{MetricInfo: MetricInfo{Name: "lookups_total"}},
+		MissOpts:   CounterOpts{MetricInfo: MetricInfo{Name: "backend_requests_total"}},
+		SizeOpts:   GaugeOpts{MetricInfo: MetricInfo{Name: "tokens_size"}},
+	}, LevelImportant).Hit(ctx)
+	if backend.adapter("web_lookups_total") != lookups || lookups.(*mockCounterAdapter).GetCount() != 1 {
+		t.Error("Expected the composite to share the basic counter")
+	}
+
+	// Components of different types still collide
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a component shared with a metric of another type to panic")
+		}
+	}()
+	group.Timer(TimerOpts{
+		MetricInfo:    MetricInfo{Name: "request_timer"},
+		HistogramOpts: HistogramOpts{MetricInfo: MetricInfo{Name: "backend_requests_total"}},
+	}, LevelImportant)
+}
+
+func TestGroupRedeclareIgnore(t *testing.T) {
+	var warnings []error
+	group := NewRegistry(LevelDebug, WithWarningHandler(func(err error) {
+		warnings = append(warnings, err)
+	})).NewGroup("test", NewMockBackend())
+
+	opts := CounterOpts{MetricInfo: MetricInfo{Name: "requests", Help: "Requests"}}
+	counter := group.Counter(opts, LevelImportant)
+	if group.Counter(opts, LevelImportant) != counter || len(warnings) != 0 {
+		t.Fatalf("Expected an identical redeclaration to return the metric silently, got %v", warnings)
+	}
+
+	redeclared := group.Counter(opts, LevelDebug)
+	if redeclared != counter || counter.Level() != LevelImportant {
+		t.Error("Expected the existing metric to be kept as it is")
+	}
+
+	group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests", Help: "All requests"}}, LevelImportant)
+	if len(warnings) != 2 {
+		t.Fatalf("Expected a warning per mismatching redeclaration, got %v", warnings)
+	}
+	for _, err := range warnings {
+		if !errors.Is(err, ErrMetricRedeclaredWithDifferentOpts) {
+			t.Errorf("Expected ErrMetricRedeclaredWithDifferentOpts, got %v", err)
+		}
+	}
+}
+
+func TestGroupRedeclareUpdateLevel(t *testing.T) {
+	group := NewRegistry(LevelDebug, WithRedeclarePolicy(RedeclareUpdateLevel)).NewGroup("test", NewMockBackend())
+
+	opts := CacheOpts{MetricInfo: MetricInfo{Name: "cache"}}
+	cache := group.Cache(opts, LevelImportant)
+	if redeclared := group.Cache(opts, LevelCritical); redeclared != cache {
+		t.Error("Expected the existing metric to be returned")
+	}
+	if cache.Level() != LevelCritical {
+		t.Errorf("Expected the level to be updated to %v, got %v", LevelCritical, cache.Level())
+	}
+	for _, component := range cache.Components() {
+		if component.Level() != LevelCritical {
+			t.Errorf("Expected component %s to be updated, got %v", component.Name(), component.Level())
+		}
+	}
+}
+
+func TestGroupRedeclarePanic(t *testing.T) {
+	group := NewRegistry(LevelDebug, WithRedeclarePolicy(RedeclarePanic)).NewGroup("test", NewMockBackend())
+
+	opts := GaugeOpts{MetricInfo: MetricInfo{Name: "in_flight", Help: "In-flight requests"}}
+	group.Gauge(opts, LevelImportant)
+	group.Gauge(opts, LevelImportant) // Identical redeclarations are fine
+
+	assertRedeclarePanics := func(name string, create func()) {
+		t.Helper()
+		defer func() {
+			err, _ := recover().(error)
+			if !errors.Is(err, ErrMetricRedeclaredWithDifferentOpts) {
+				t.Errorf("Expected %s to panic with ErrMetricRedeclaredWithDifferentOpts, got %v", name, err)
+			}
+		}()
+		create()
+	}
+	assertRedeclarePanics("a different level", func() { group.Gauge(opts, LevelDebug) })
+	assertRedeclarePanics("a different help", func() {
+		group.Gauge(GaugeOpts{MetricInfo: MetricInfo{Name: "in_flight", Help: "Requests"}}, LevelImportant)
+	})
+}
+
+func TestGroupEmptyMetricName(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+
+	for name, create := range map[string]func(){
+		"a counter":           func() { group.Counter(CounterOpts{}, LevelImportant) },
+		"an unprefixed gauge": func() { group.Gauge(GaugeOpts{BasicMetricOpts: BasicMetricOpts{NoPrefix: true}}, LevelImportant) },
+		"a disabled counter":  func() { group.Counter(CounterOpts{}, LevelDisabled) },
+		"a cache":             func() { group.Cache(CacheOpts{}, LevelImportant) },
+		"a cache with named components": func() {
+			group.Cache(CacheOpts{
+				HitOpts:  CounterOpts{MetricInfo: MetricInfo{Name: "hits"}},
+				MissOpts: CounterOpts{MetricInfo: MetricInfo{Name: "misses"}},
+				SizeOpts: GaugeOpts{MetricInfo: MetricInfo{Name: "size"}},
+			}, LevelImportant)
+		},
+		"a timer vec": func() {
+			group.TimerVec(TimerVecOpts{HistogramVecOpts: HistogramVecOpts{Labels: []string{"route"}}}, LevelImportant)
+		},
+	} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				if !errors.Is(err, ErrEmptyMetricName) {
+					t.Errorf("Expected %s with an empty name to panic with ErrEmptyMetricName, got %v", name, err)
+				}
+			}()
+			create()
+		}()
+	}
+
+	for _, name := range []string{"", "test_"} {
+		if group.Metric(name) != nil {
+			t.Errorf("Expected no metric to be created as %q", name)
+		}
+	}
+}
+
+// capabilityBackend is a [CapabilityBackend] supporting a fixed set of kinds
+type capabilityBackend struct {
+	Backend
+	kinds []MetricKind
+}
+
+func (c capabilityBackend) Supports(kind MetricKind) bool {
+	return slices.Contains(c.kinds, kind)
+}
+
+func TestGroupBackendSupports(t *testing.T) {
+	reg := NewRegistry(LevelDebug)
+	noSummaries := capabilityBackend{Backend: NewMockBackend(), kinds: []MetricKind{MetricKindCounter, MetricKindGauge, MetricKindHistogram}}
+	countersOnly := capabilityBackend{Backend: NewMockBackend(), kinds: []MetricKind{MetricKindCounter}}
+
+	tests := []struct {
+		name  string
+		group Group
+		want  map[MetricKind]bool
+	}{
+		{"every kind without capabilities", reg.NewGroup("plain", NewMockBackend()),
+			map[MetricKind]bool{MetricKindCounter: true, MetricKindGauge: true, MetricKindHistogram: true, MetricKindSummary: true, "exemplar": false}},
+		{"reported capabilities", reg.NewGroup("statsd", noSummaries),
+			map[MetricKind]bool{MetricKindCounter: true, MetricKindHistogram: true, MetricKindSummary: false}},
+		{"every mirror must support the kind", reg.NewMirroredGroup("mirrored", noSummaries, []Backend{countersOnly}),
+			map[MetricKind]bool{MetricKindCounter: true, MetricKindGauge: false, MetricKindSummary: false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for kind, want := range tt.want {
+				if got := tt.group.BackendSupports(kind); got != want {
+					t.Errorf("Expected BackendSupports(%q) to be %v, got %v", kind, want, got)
+				}
+			}
+		})
+	}
+
+	// Capabilities are forwarded through the backend wrappers of registry options
+	wrapped := NewRegistry(LevelDebug, WithRecover(true), WithEmitRateLimit(100)).NewGroup("wrapped", noSummaries)
+	if !wrapped.BackendSupports(MetricKindHistogram) || wrapped.BackendSupports(MetricKindSummary) {
+		t.Error("Expected the wrapped backend to report the capabilities of the backend")
+	}
+}
+
+func TestGroupMustUnsupported(t *testing.T) {
+	noSummaries := capabilityBackend{Backend: NewMockBackend(), kinds: []MetricKind{MetricKindCounter, MetricKindGauge, MetricKindHistogram}}
+	group := NewRegistry(LevelDebug).NewGroup("statsd", noSummaries)
+
+	if counter := group.MustCounter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total"}}, LevelImportant); counter == nil {
+		t.Error("Expected a supported counter to be created")
+	}
+
+	// The plain form creates the metric regardless
+	if summary := group.Summary(SummaryOpts{MetricInfo: MetricInfo{Name: "size"}}, LevelImportant); summary == nil {
+		t.Error("Expected Summary to create the metric")
+	}
+
+	for name, create := range map[string]func(){
+		"MustSummary": func() { group.MustSummary(SummaryOpts{MetricInfo: MetricInfo{Name: "latency"}}, LevelImportant) },
+		"MustSummaryVec": func() {
+			group.MustSummaryVec(SummaryVecOpts{MetricInfo: MetricInfo{Name: "latency_by_route"}, Labels: []string{"route"}}, LevelDebug)
+		},
+	} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				if !errors.Is(err, ErrUnsupportedMetric) {
+					t.Errorf("Expected %s to panic with ErrUnsupportedMetric, got %v", name, err)
+				}
+			}()
+			create()
+		}()
+	}
+	if group.Metric("statsd_latency") != nil {
+		t.Error("Expected the unsupported summary to not be created")
+	}
+}
+
+func TestGroupTryUnsupported(t *testing.T) {
+	noSummaries := capabilityBackend{Backend: NewMockBackend(), kinds: []MetricKind{MetricKindCounter, MetricKindGauge, MetricKindHistogram}}
+	group := NewRegistry(LevelDebug).NewGroup("statsd", noSummaries)
+
+	if counter, err := group.TryCounter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total"}}, LevelImportant); counter == nil || err != nil {
+		t.Errorf("Expected a supported counter to be created, got %v, %v", counter, err)
+	}
+
+	summary, err := group.TrySummary(SummaryOpts{MetricInfo: MetricInfo{Name: "latency"}}, LevelImportant)
+	if summary != nil || !errors.Is(err, ErrUnsupportedMetric) {
+		t.Errorf("Expected TrySummary to fail with ErrUnsupportedMetric, got %v, %v", summary, err)
+	}
+	vec, err := group.TrySummaryVec(SummaryVecOpts{MetricInfo: MetricInfo{Name: "latency_by_route"}, Labels: []string{"route"}}, LevelDebug)
+	if vec != nil || !errors.Is(err, ErrUnsupportedMetric) {
+		t.Errorf("Expected TrySummaryVec to fail with ErrUnsupportedMetric, got %v, %v", vec, err)
+	}
+	if group.Metric("statsd_latency") != nil || group.Metric("statsd_latency_by_route") != nil {
+		t.Error("Expected the unsupported summaries to not be created")
+	}
+
+	// Falling back to a supported kind
+	histogram, err := group.TryHistogram(HistogramOpts{MetricInfo: MetricInfo{Name: "latency"}}, LevelImportant)
+	if histogram == nil || err != nil {
+		t.Errorf("Expected the fallback histogram to be created, got %v, %v", histogram, err)
+	}
+}
+
+// componentSignature returns the name of each component of composite, and
+// whether its opts are flagged FromComposite
+func componentSignature(t *testing.T, composite CompositeMetric) []string {
+	t.Helper()
+	var signature []string
+	for _, component := range composite.Components() {
+		var opts any
+		switch component := component.(type) {
+		case SwitchableMetric:
+			opts = component.switchOpts()
+		case NoopMetric:
+			opts = component.constructorOpts()
+		default:
+			t.Fatalf("Unexpected component type %T", component)
+		}
+		signature = append(signature, fmt.Sprintf("%s/%v", component.Name(), basicOpts(opts).FromComposite))
+	}
+	return signature
+}
+
+func TestCompositeNoopComponentsMatchReal(t *testing.T) {
+	labels := []string{"route"}
+	tests := []struct {
+		name   string
+		create func(f Factory, level Level) CompositeMetric
+	}{
+		{"Timer", func(f Factory, level Level) CompositeMetric {
+			return f.Timer(TimerOpts{MetricInfo: MetricInfo{Name: "op"}}, level)
+		}},
+		{"TimerNamed", func(f Factory, level Level) CompositeMetric {
+			return f.Timer(TimerOpts{
+				MetricInfo:    MetricInfo{Name: "op"},
+				HistogramOpts: HistogramOpts{MetricInfo: MetricInfo{Name: "op_duration_seconds"}},
+			}, level)
+		}},
+		{"TimerVec", func(f Factory, level Level) CompositeMetric {
+			return f.TimerVec(TimerVecOpts{MetricInfo: MetricInfo{Name: "op"}, HistogramVecOpts: HistogramVecOpts{Labels: labels}}, level)
+		}},
+		{"Cache", func(f Factory, level Level) CompositeMetric {
+			return f.Cache(CacheOpts{MetricInfo: MetricInfo{Name: "cache"}, CompositeMetricOpts: CompositeMetricOpts{NoPrefix: true}}, level)
+		}},
+		{"CacheVec", func(f Factory, level Level) CompositeMetric {
+			return f.CacheVec(CacheVecOpts{MetricInfo: MetricInfo{Name: "cache"}}, level)
+		}},
+		{"Pool", func(f Factory, level Level) CompositeMetric {
+			return f.Pool(PoolOpts{MetricInfo: MetricInfo{Name: "pool"}}, level)
+		}},
+		{"PoolVec", func(f Factory, level Level) CompositeMetric {
+			return f.PoolVec(PoolVecOpts{MetricInfo: MetricInfo{Name: "pool"}}, level)
+		}},
+		{"CircuitBreaker", func(f Factory, level Level) CompositeMetric {
+			return f.CircuitBreaker(CircuitBreakerOpts{MetricInfo: MetricInfo{Name: "breaker"}}, level)
+		}},
+		{"CircuitBreakerVec", func(f Factory, level Level) CompositeMetric {
+			return f.CircuitBreakerVec(CircuitBreakerVecOpts{MetricInfo: MetricInfo{Name: "breaker"}}, level)
+		}},
+		{"Queue", func(f Factory, level Level) CompositeMetric {
+			return f.Queue(QueueOpts{MetricInfo: MetricInfo{Name: "queue"}}, level)
+		}},
+		{"QueueVec", func(f Factory, level Level) CompositeMetric {
+			return f.QueueVec(QueueVecOpts{MetricInfo: MetricInfo{Name: "queue"}}, level)
+		}},
+		{"Throughput", func(f Factory, level Level) CompositeMetric {
+			return f.Throughput(ThroughputOpts{MetricInfo: MetricInfo{Name: "events"}}, level)
+		}},
+		{"DistributionGauge", func(f Factory, level Level) CompositeMetric {
+			return f.DistributionGauge(DistributionGaugeOpts{MetricInfo: MetricInfo{Name: "temperature"}}, level)
+		}},
+		{"MultiResHistogram", func(f Factory, level Level) CompositeMetric {
+			return f.MultiResHistogram(MultiResHistogramOpts{
+				MetricInfo:  MetricInfo{Name: "latency"},
+				Resolutions: []HistogramOpts{{}, {MetricInfo: MetricInfo{Name: "latency_coarse"}}},
+			}, level)
+		}},
+		{"Dependency", func(f Factory, level Level) CompositeMetric {
+			return f.Dependency(DependencyOpts{MetricInfo: MetricInfo{Name: "db"}}, level)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			real := tt.create(NewRegistry(LevelDebug).NewGroup("test", NewMockBackend()), LevelDebug)
+			noop := tt.create(NewRegistry(LevelImportant).NewGroup("test", NewMockBackend()), LevelDebug)
+			if !noop.(SwitchableMetric).IsNoop() || real.(SwitchableMetric).IsNoop() {
+				t.Fatal("Expected a noop and a real composite")
+			}
+
+			want := componentSignature(t, real)
+			if got := componentSignature(t, noop); !slices.Equal(got, want) {
+				t.Errorf("Expected noop components %v, got %v", want, got)
+			}
+			for _, component := range want {
+				if strings.HasPrefix(component, "/") || !strings.HasSuffix(component, "/true") {
+					t.Errorf("Expected a named component from a composite, got %q", component)
+				}
+			}
+		})
+	}
+}
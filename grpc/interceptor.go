@@ -0,0 +1,121 @@
+package umami_grpc
+
+// Integration with gRPC servers, recording the calls served by their handlers
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/SimonDaKappa/go-umami"
+)
+
+// Labels of the call metrics
+const (
+	MethodLabel = "grpc_method"
+	CodeLabel   = "grpc_code"
+)
+
+// serverMetrics records the calls served by the handlers of a server
+type serverMetrics struct {
+	group    umami.Group
+	calls    umami.CounterVec   // By method and code
+	duration umami.HistogramVec // By method
+	inFlight umami.Gauge
+}
+
+// newServerMetrics creates the metrics of the interceptors in group at level.
+// The metrics are created in the group like any other, so every interceptor
+// of a group records into the same metrics.
+func newServerMetrics(group umami.Group, level umami.Level) *serverMetrics {
+	return &serverMetrics{
+		group: group,
+		calls: group.CounterVec(umami.CounterVecOpts{
+			MetricInfo: umami.MetricInfo{Name: "server_calls_total", Help: "Number of gRPC calls served"},
+			Labels:     []string{MethodLabel, CodeLabel},
+		}, level),
+		duration: group.HistogramVec(umami.HistogramVecOpts{
+			MetricInfo:   umami.MetricInfo{Name: "server_call_duration_seconds", Help: "Duration of the gRPC calls served", Unit: "seconds"},
+			Labels:       []string{MethodLabel},
+			BucketPreset: umami.BucketPresetLatency,
+		}, level),
+		inFlight: group.Gauge(umami.GaugeOpts{
+			MetricInfo: umami.MetricInfo{Name: "server_calls_in_flight", Help: "Number of gRPC calls being served"},
+		}, level),
+	}
+}
+
+// serve calls handler, recording the call to method under ctx, the context
+// of the call
+func (m *serverMetrics) serve(ctx context.Context, method string, handler func() error) error {
+	umamiCtx := m.group.Context()
+	start := time.Now()
+	_ = m.inFlight.Inc(umamiCtx)
+	defer func() { _ = m.inFlight.Dec(umamiCtx) }()
+
+	err := handler()
+
+	_ = m.calls.Inc(umamiCtx, umami.VecLabels{MethodLabel: method, CodeLabel: Code(ctx, err).String()})
+	_ = m.duration.Observe(umamiCtx, time.Since(start).Seconds(), umami.VecLabels{MethodLabel: method})
+	return err
+}
+
+// Code returns the status code of a call that returned err, under ctx, the
+// context of the call:
+//
+//   - the code of err if it is a gRPC status error
+//   - [codes.Canceled] or [codes.DeadlineExceeded] if err is, or was caused
+//     by (i.e. ctx is done), the cancellation or the deadline of the call
+//   - [codes.Unknown] for other errors
+func Code(ctx context.Context, err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	if s, ok := status.FromError(err); ok {
+		return s.Code()
+	}
+	if code := status.FromContextError(err).Code(); code != codes.Unknown {
+		return code
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return status.FromContextError(ctxErr).Code()
+	}
+	return codes.Unknown
+}
+
+// UnaryServerInterceptor returns an interceptor recording the unary calls
+// served by a server into metrics of group at level:
+//
+//   - server_calls_total, the number of calls served, by method and status code
+//   - server_call_duration_seconds, the duration of the calls, by method
+//   - server_calls_in_flight, the number of calls being served
+//
+// Methods are labeled by their full name, e.g. "/package.Service/Method", and
+// codes by their name, e.g. "NotFound", see [Code]. Calls whose handler
+// failed with a plain error because the client canceled them are labeled
+// "Canceled", rather than "Unknown".
+func UnaryServerInterceptor(group umami.Group, level umami.Level) grpc.UnaryServerInterceptor {
+	metrics := newServerMetrics(group, level)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		err = metrics.serve(ctx, info.FullMethod, func() (err error) {
+			resp, err = handler(ctx, req)
+			return err
+		})
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor recording the streaming calls
+// served by a server into the metrics of [UnaryServerInterceptor]. A call is
+// recorded once its stream ends, and its duration is that of the stream.
+func StreamServerInterceptor(group umami.Group, level umami.Level) grpc.StreamServerInterceptor {
+	metrics := newServerMetrics(group, level)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return metrics.serve(ss.Context(), info.FullMethod, func() error {
+			return handler(srv, ss)
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package umami_grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/SimonDaKappa/go-umami"
+	umami_prometheus "github.com/SimonDaKappa/go-umami/prometheus"
+	"github.com/SimonDaKappa/go-umami/prometheus/promtest"
+)
+
+const (
+	checkMethod = "/grpc.health.v1.Health/Check"
+	watchMethod = "/grpc.health.v1.Health/Watch"
+)
+
+// healthServer answers the health of the services "" (serving) and "blocking",
+// whose calls block until canceled by the client, failing with the plain
+// error of their context. Other services are not found.
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+	started chan struct{} // Receives the start of each blocking call
+}
+
+func (h *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	switch req.GetService() {
+	case "":
+		return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+	case "blocking":
+		h.started <- struct{}{}
+		<-ctx.Done()
+		return nil, fmt.Errorf("checking: %w", ctx.Err())
+	}
+	return nil, status.Errorf(codes.NotFound, "unknown service %q", req.GetService())
+}
+
+func (h *healthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	if err := stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}); err != nil {
+		return err
+	}
+	<-stream.Context().Done()
+	return stream.Context().Err()
+}
+
+// newTestServer starts a server of a [healthServer] with the interceptors of
+// group, returning it, a client, and the healthServer
+func newTestServer(t *testing.T, group umami.Group) (*grpc.Server, healthpb.HealthClient, *healthServer) {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(group, umami.LevelImportant)),
+		grpc.StreamInterceptor(StreamServerInterceptor(group, umami.LevelImportant)),
+	)
+	health := &healthServer{started: make(chan struct{}, 1)}
+	healthpb.RegisterHealthServer(server, health)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to create the client: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return server, healthpb.NewHealthClient(conn), health
+}
+
+func TestServerInterceptors(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	group := umami.NewRegistry(umami.LevelImportant).NewGroup("grpc", umami_prometheus.NewPrometheusBackend(reg))
+	server, client, health := newTestServer(t, group)
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+	}
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+
+	// A call canceled by the client fails with a plain context error
+	blockingCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		<-health.started
+		cancel()
+	}()
+	if _, err := client.Check(blockingCtx, &healthpb.HealthCheckRequest{Service: "blocking"}); status.Code(err) != codes.Canceled {
+		t.Errorf("expected the client to cancel the call, got %v", err)
+	}
+
+	watchCtx, cancelWatch := context.WithCancel(ctx)
+	stream, err := client.Watch(watchCtx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("failed to receive the health: %v", err)
+	}
+	cancelWatch()
+
+	// Waits for the handlers, and so the interceptors, to return
+	server.GracefulStop()
+
+	tests := []struct {
+		method, code string
+		want         float64
+	}{
+		{checkMethod, "OK", 2},
+		{checkMethod, "NotFound", 1},
+		{checkMethod, "Canceled", 1},
+		{watchMethod, "Canceled", 1},
+	}
+	for _, tt := range tests {
+		promtest.AssertCounterValue(t, reg, "grpc_server_calls_total", map[string]string{MethodLabel: tt.method, CodeLabel: tt.code}, tt.want)
+	}
+	promtest.AssertHistogramSampleCount(t, reg, "grpc_server_call_duration_seconds", map[string]string{MethodLabel: checkMethod}, 4)
+	promtest.AssertHistogramSampleCount(t, reg, "grpc_server_call_duration_seconds", map[string]string{MethodLabel: watchMethod}, 1)
+	promtest.AssertGaugeValue(t, reg, "grpc_server_calls_in_flight", nil, 0)
+}
+
+func TestCode(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want codes.Code
+	}{
+		{"success", context.Background(), nil, codes.OK},
+		{"status", canceled, status.Error(codes.NotFound, "missing"), codes.NotFound},
+		{"deadline", context.Background(), fmt.Errorf("query: %w", context.DeadlineExceeded), codes.DeadlineExceeded},
+		{"canceled call", canceled, errors.New("connection reset"), codes.Canceled},
+		{"plain error", context.Background(), errors.New("boom"), codes.Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Code(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
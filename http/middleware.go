@@ -0,0 +1,158 @@
+package umami_http
+
+// Integration with net/http, recording the requests served by handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SimonDaKappa/go-umami"
+)
+
+// UnmatchedRoute is the route label of requests that no pattern of a
+// [http.ServeMux] matched, e.g. those answered 404, by the default [RouteFunc]
+const UnmatchedRoute = "unmatched"
+
+// RouteFunc returns the route label of a served request. It must map requests
+// to a bounded set of routes (e.g. "/users/{id}", not "/users/42"), as each
+// route is a series of the request metrics.
+type RouteFunc func(r *http.Request) string
+
+// PatternRoute is the default [RouteFunc]. It returns the pattern of the
+// [http.ServeMux] that matched the request, or [UnmatchedRoute] if none did.
+//
+// The pattern is set by a mux handling the request inside the middleware, so
+// it is only known once the request is served.
+func PatternRoute(r *http.Request) string {
+	if r.Pattern == "" {
+		return UnmatchedRoute
+	}
+	return r.Pattern
+}
+
+// MiddlewareOption configures the middleware returned by [Middleware]
+type MiddlewareOption func(*middleware)
+
+// WithRoute sets the [RouteFunc] labeling requests, instead of [PatternRoute]
+func WithRoute(route RouteFunc) MiddlewareOption {
+	return func(m *middleware) {
+		m.route = route
+	}
+}
+
+// WithDurationBuckets sets the buckets of the request duration histogram,
+// instead of [umami.LatencyBuckets]
+func WithDurationBuckets(buckets ...float64) MiddlewareOption {
+	return func(m *middleware) {
+		m.buckets = buckets
+	}
+}
+
+// middleware records the requests served by the handlers it wraps
+type middleware struct {
+	group    umami.Group
+	route    RouteFunc
+	buckets  []float64
+	requests umami.CounterVec   // By method, status, and route
+	duration umami.HistogramVec // By method and route
+	inFlight umami.Gauge
+}
+
+// Middleware returns a middleware recording the requests served by the
+// handlers it wraps into metrics of group at level, with optional
+// [MiddlewareOption]s:
+//
+//   - requests_total, the number of requests served, by method, status, and route
+//   - request_duration_seconds, the duration of the requests, by method and route
+//   - requests_in_flight, the number of requests being served
+//
+// The metrics are created in the group like any other, so every middleware of
+// a group records into the same metrics. Routes are labeled by [PatternRoute],
+// unless set by [WithRoute].
+//
+// The status of a request is the one written by its handler, 200 if it wrote
+// none, or 500 if it panicked before writing one.
+func Middleware(group umami.Group, level umami.Level, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	m := &middleware{
+		group:   group,
+		route:   PatternRoute,
+		buckets: umami.LatencyBuckets(),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	m.requests = group.CounterVec(umami.CounterVecOpts{
+		MetricInfo: umami.MetricInfo{Name: "requests_total", Help: "Number of HTTP requests served"},
+		Labels:     []string{"method", "status", "route"},
+	}, level)
+	m.duration = group.HistogramVec(umami.HistogramVecOpts{
+		MetricInfo: umami.MetricInfo{Name: "request_duration_seconds", Help: "Duration of the HTTP requests served", Unit: "seconds"},
+		Labels:     []string{"method", "route"},
+		Buckets:    m.buckets,
+	}, level)
+	m.inFlight = group.Gauge(umami.GaugeOpts{
+		MetricInfo: umami.MetricInfo{Name: "requests_in_flight", Help: "Number of HTTP requests being served"},
+	}, level)
+
+	return m.wrap
+}
+
+func (m *middleware) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := m.group.Context()
+		start := time.Now()
+		_ = m.inFlight.Inc(ctx)
+
+		rw := &responseWriter{ResponseWriter: w}
+		served := false
+		defer func() {
+			_ = m.inFlight.Dec(ctx)
+
+			status := rw.status
+			if status == 0 {
+				status = http.StatusOK
+				if !served {
+					status = http.StatusInternalServerError
+				}
+			}
+
+			route := m.route(r)
+			_ = m.requests.Inc(ctx, umami.VecLabels{"method": r.Method, "status": strconv.Itoa(status), "route": route})
+			_ = m.duration.Observe(ctx, time.Since(start).Seconds(), umami.VecLabels{"method": r.Method, "route": route})
+		}()
+
+		next.ServeHTTP(rw, r)
+		served = true
+	})
+}
+
+// responseWriter captures the status written to a [http.ResponseWriter].
+//
+// Other interfaces of the wrapped writer, e.g. [http.Flusher], are reachable
+// through [http.ResponseController], which unwraps it.
+type responseWriter struct {
+	http.ResponseWriter
+	status int // 0 until a final header is written
+}
+
+// WriteHeader captures the first final (non 1xx) status written
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 && status >= 200 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped writer, see [http.ResponseController]
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
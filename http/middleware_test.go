@@ -0,0 +1,109 @@
+package umami_http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/SimonDaKappa/go-umami"
+	umami_prometheus "github.com/SimonDaKappa/go-umami/prometheus"
+	"github.com/SimonDaKappa/go-umami/prometheus/promtest"
+)
+
+func newTestGroup() (*prometheus.Registry, umami.Group) {
+	reg := prometheus.NewRegistry()
+	return reg, umami.NewRegistry(umami.LevelImportant).NewGroup("http", umami_prometheus.NewPrometheusBackend(reg))
+}
+
+// serve sends a request through handler, recovering its panic
+func serve(handler http.Handler, method, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	defer func() { _ = recover() }()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+	return recorder
+}
+
+func TestMiddleware(t *testing.T) {
+	reg, group := newTestGroup()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		promtest.AssertGaugeValue(t, reg, "http_requests_in_flight", nil, 1)
+		_, _ = w.Write([]byte(r.PathValue("id")))
+	})
+	mux.HandleFunc("POST /users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	handler := Middleware(group, umami.LevelImportant)(mux)
+
+	for _, id := range []string{"1", "2", "3"} {
+		if body := serve(handler, http.MethodGet, "/users/"+id).Body.String(); body != id {
+			t.Errorf("expected the handler to serve %q, got %q", id, body)
+		}
+	}
+	serve(handler, http.MethodPost, "/users")
+	serve(handler, http.MethodGet, "/panic")
+	serve(handler, http.MethodGet, "/missing")
+
+	tests := []struct {
+		method, status, route string
+		want                  float64
+	}{
+		{"GET", "200", "GET /users/{id}", 3},
+		{"POST", "201", "POST /users", 1},
+		{"GET", "500", "/panic", 1},
+		{"GET", "404", UnmatchedRoute, 1},
+	}
+	for _, tt := range tests {
+		promtest.AssertCounterValue(t, reg, "http_requests_total", map[string]string{"method": tt.method, "status": tt.status, "route": tt.route}, tt.want)
+	}
+	promtest.AssertHistogramSampleCount(t, reg, "http_request_duration_seconds", map[string]string{"method": "GET", "route": "GET /users/{id}"}, 3)
+	promtest.AssertGaugeValue(t, reg, "http_requests_in_flight", nil, 0)
+}
+
+func TestMiddlewareWithRoute(t *testing.T) {
+	reg, group := newTestGroup()
+
+	// Routes by the first path segment, whatever the handler
+	firstSegment := func(r *http.Request) string {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		return "/" + segment
+	}
+	handler := Middleware(group, umami.LevelImportant, WithRoute(firstSegment), WithDurationBuckets(0.1, 1))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	serve(handler, http.MethodGet, "/orders/1")
+	serve(handler, http.MethodGet, "/orders/2/items")
+
+	promtest.AssertCounterValue(t, reg, "http_requests_total", map[string]string{"method": "GET", "status": "200", "route": "/orders"}, 2)
+	if m := promtest.FindMetric(t, reg, "http_request_duration_seconds", nil); m != nil && len(m.GetHistogram().GetBucket()) != 2 {
+		t.Errorf("expected the duration buckets to be set, got %d buckets", len(m.GetHistogram().GetBucket()))
+	}
+}
+
+func TestMiddlewareDisabled(t *testing.T) {
+	reg, group := newTestGroup()
+
+	handler := Middleware(group, umami.LevelDebug)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	if status := serve(handler, http.MethodGet, "/").Code; status != http.StatusNoContent {
+		t.Errorf("expected a disabled middleware to serve the request, got %d", status)
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	if len(mfs) != 0 {
+		t.Errorf("expected no metric to be recorded below the group level, got %d families", len(mfs))
+	}
+}
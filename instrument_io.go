@@ -0,0 +1,125 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: instrument_io.go
+//
+// This file contains the [io.Reader] and [io.Writer] wrappers returned by
+// [Group.InstrumentReader] and [Group.InstrumentWriter], which record the
+// bytes flowing through them into metrics of the group.
+//--------------------------------------------------------------------------------
+
+import "io"
+
+// IOOpts are the metrics of an instrumented reader or writer, see
+// [Group.InstrumentReader] and [Group.InstrumentWriter].
+//
+// The metrics are created in the group like any other, so instrumenting
+// several readers or writers with the same opts records into the same metrics.
+type IOOpts struct {
+	// BytesOpts is the counter of the bytes read or written
+	BytesOpts CounterOpts
+
+	// ThroughputOpts is the histogram of the throughput, in bytes per second,
+	// of each read or write of at least one byte
+	ThroughputOpts HistogramOpts
+
+	// Clock is the time source used to measure throughput. Defaults to the real clock.
+	Clock Clock
+}
+
+// ioMetrics records the bytes transferred by the reads or writes of an
+// instrumented reader or writer
+type ioMetrics struct {
+	ctx        Context
+	bytes      Counter
+	throughput Histogram
+	clock      Clock
+}
+
+func (g *group) newIOMetrics(opts IOOpts, level Level) ioMetrics {
+	return ioMetrics{
+		ctx:        g.Context(),
+		bytes:      g.Counter(opts.BytesOpts, level),
+		throughput: g.Histogram(opts.ThroughputOpts, level),
+		clock:      clockOrDefault(opts.Clock),
+	}
+}
+
+// transfer calls op, recording the bytes it transferred and their throughput.
+// Errors of the metrics are dropped, so they never fail the I/O.
+func (m ioMetrics) transfer(op func() (int, error)) (int, error) {
+	start := m.clock.Now()
+	n, err := op()
+	if n <= 0 {
+		return n, err
+	}
+
+	_ = m.bytes.Add(m.ctx, float64(n))
+	if elapsed := m.clock.Now().Sub(start); elapsed > 0 {
+		_ = m.throughput.Observe(m.ctx, float64(n)/elapsed.Seconds())
+	}
+	return n, err
+}
+
+// InstrumentReader returns r, counting the bytes read and observing the
+// throughput of each read, see [Group.InstrumentReader]
+func (g *group) InstrumentReader(r io.Reader, opts IOOpts, level Level) io.Reader {
+	reader := &instrumentedReader{reader: r, metrics: g.newIOMetrics(opts, level)}
+	if closer, ok := r.(io.Closer); ok {
+		return &instrumentedReadCloser{instrumentedReader: reader, closer: closer}
+	}
+	return reader
+}
+
+// InstrumentWriter returns w, counting the bytes written and observing the
+// throughput of each write, see [Group.InstrumentWriter]
+func (g *group) InstrumentWriter(w io.Writer, opts IOOpts, level Level) io.Writer {
+	writer := &instrumentedWriter{writer: w, metrics: g.newIOMetrics(opts, level)}
+	if closer, ok := w.(io.Closer); ok {
+		return &instrumentedWriteCloser{instrumentedWriter: writer, closer: closer}
+	}
+	return writer
+}
+
+type instrumentedReader struct {
+	reader  io.Reader
+	metrics ioMetrics
+}
+
+func (r *instrumentedReader) Read(p []byte) (int, error) {
+	return r.metrics.transfer(func() (int, error) { return r.reader.Read(p) })
+}
+
+type instrumentedReadCloser struct {
+	*instrumentedReader
+	closer io.Closer
+}
+
+func (r *instrumentedReadCloser) Close() error {
+	return r.closer.Close()
+}
+
+type instrumentedWriter struct {
+	writer  io.Writer
+	metrics ioMetrics
+}
+
+func (w *instrumentedWriter) Write(p []byte) (int, error) {
+	return w.metrics.transfer(func() (int, error) { return w.writer.Write(p) })
+}
+
+type instrumentedWriteCloser struct {
+	*instrumentedWriter
+	closer io.Closer
+}
+
+func (w *instrumentedWriteCloser) Close() error {
+	return w.closer.Close()
+}
+
+var (
+	__ctc_instrumentedReader      io.Reader      = (*instrumentedReader)(nil)
+	__ctc_instrumentedReadCloser  io.ReadCloser  = (*instrumentedReadCloser)(nil)
+	__ctc_instrumentedWriter      io.Writer      = (*instrumentedWriter)(nil)
+	__ctc_instrumentedWriteCloser io.WriteCloser = (*instrumentedWriteCloser)(nil)
+)
@@ -0,0 +1,100 @@
+package umami
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowReader is a reader whose reads take a second of clock
+type slowReader struct {
+	io.Reader
+	clock *fakeClock
+}
+
+func (r slowReader) Read(p []byte) (int, error) {
+	r.clock.Advance(time.Second)
+	return r.Reader.Read(p)
+}
+
+// closeRecorder is a writer recording whether it was closed
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func testIOOpts(clock Clock) IOOpts {
+	return IOOpts{
+		BytesOpts:      CounterOpts{MetricInfo: MetricInfo{Name: "bytes_total"}},
+		ThroughputOpts: HistogramOpts{MetricInfo: MetricInfo{Name: "throughput"}},
+		Clock:          clock,
+	}
+}
+
+func TestInstrumentReader(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug).NewGroup("io", backend)
+	clock := &fakeClock{now: time.Unix(0, 0)}
+
+	r := group.InstrumentReader(slowReader{strings.NewReader("0123456789"), clock}, testIOOpts(clock), LevelImportant)
+	if _, ok := r.(io.Closer); ok {
+		t.Error("Expected a reader without Close to not be an io.Closer")
+	}
+
+	buf := make([]byte, 4)
+	for {
+		if _, err := r.Read(buf); err == io.EOF {
+			break
+		}
+	}
+
+	if got := backend.adapter("io_bytes_total").(*mockCounterAdapter).GetCount(); got != 10 {
+		t.Errorf("Expected 10 bytes read, got %v", got)
+	}
+	// The read at EOF transfers nothing, and isn't observed
+	observations := backend.adapter("io_throughput").(*mockHistogramAdapter).GetObservations()
+	if want := []float64{4, 4, 2}; !slices.Equal(observations, want) {
+		t.Errorf("Expected the throughputs %v, got %v", want, observations)
+	}
+}
+
+func TestInstrumentWriter(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug).NewGroup("io", backend)
+
+	dst := &closeRecorder{}
+	w := group.InstrumentWriter(dst, testIOOpts(nil), LevelImportant)
+	if _, err := io.Copy(w, strings.NewReader("hello, world")); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if dst.String() != "hello, world" {
+		t.Errorf("Expected the data to be written through, got %q", dst.String())
+	}
+	if got := backend.adapter("io_bytes_total").(*mockCounterAdapter).GetCount(); got != 12 {
+		t.Errorf("Expected 12 bytes written, got %v", got)
+	}
+
+	closer, ok := w.(io.WriteCloser)
+	if !ok {
+		t.Fatalf("Expected the Close of the writer to pass through")
+	}
+	if err := closer.Close(); err != nil || !dst.closed {
+		t.Errorf("Expected the writer to be closed, got %v", err)
+	}
+
+	// Metrics below the group level don't affect the I/O
+	group.SetGroupLevel(LevelCritical, LevelOpts{})
+	var out bytes.Buffer
+	quiet := group.InstrumentWriter(&out, testIOOpts(nil), LevelDebug)
+	if n, err := quiet.Write([]byte("data")); n != 4 || err != nil || out.String() != "data" {
+		t.Errorf("Expected the write to pass through, got %d (%v)", n, err)
+	}
+}
@@ -0,0 +1,91 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: labels.go
+//
+// This file contains helpers deriving the label names and [VecLabels] of
+// label-vectorized metrics from structs, so that labels can be declared once
+// as a typed struct instead of matching name slices and maps by hand:
+//
+//	type routeLabels struct {
+//		Method string `umami:"method"`
+//		Route  string `umami:"route"`
+//	}
+//
+//	requests := group.CounterVec(umami.CounterVecOpts{
+//		MetricInfo: umami.MetricInfo{Name: "requests_total"},
+//		Labels:     umami.LabelNames[routeLabels](),
+//	}, umami.LevelImportant)
+//
+//	_, labels := umami.LabelsFromStruct(routeLabels{Method: "GET", Route: "/"})
+//	requests.Inc(ctx, labels)
+//--------------------------------------------------------------------------------
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// LabelTag is the struct tag holding the label name of a field, see [LabelsFromStruct]
+const LabelTag = "umami"
+
+// LabelNames returns the label names of the struct type T, in field order,
+// like [LabelsFromStruct] without needing a value. Panics if T is not a struct.
+func LabelNames[T any]() []string {
+	return labelNames(structType(reflect.TypeFor[T]()))
+}
+
+// LabelsFromStruct returns the label names and labels of v, a struct or a
+// pointer to one. Panics if v is not.
+//
+// Every exported field tagged `umami:"label_name"` is a label, and its value
+// is formatted with [fmt.Sprint]. Untagged fields and fields tagged
+// `umami:"-"` are skipped. Names are returned in field order.
+func LabelsFromStruct(v any) (names []string, labels VecLabels) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+	typ := structType(value.Type())
+
+	names = labelNames(typ)
+	labels = make(VecLabels, len(names))
+	for i := range typ.NumField() {
+		if name, ok := labelName(typ.Field(i)); ok {
+			labels[name] = fmt.Sprint(value.Field(i).Interface())
+		}
+	}
+
+	return names, labels
+}
+
+// structType returns typ, dereferencing pointers, panicking if it is not a struct
+func structType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("umami: labels must be derived from a struct, got %v", typ))
+	}
+	return typ
+}
+
+// labelNames returns the label names of the fields of the struct type typ
+func labelNames(typ reflect.Type) []string {
+	var names []string
+	for i := range typ.NumField() {
+		if name, ok := labelName(typ.Field(i)); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// labelName returns the label name of field, and false if it is not a label
+func labelName(field reflect.StructField) (string, bool) {
+	name, ok := field.Tag.Lookup(LabelTag)
+	if !ok || name == "" || name == "-" || !field.IsExported() {
+		return "", false
+	}
+	return name, true
+}
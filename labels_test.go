@@ -0,0 +1,66 @@
+package umami
+
+import (
+	"slices"
+	"testing"
+)
+
+type testRouteLabels struct {
+	Method   string `umami:"method"`
+	Status   int    `umami:"status_code"`
+	Cached   bool   `umami:"cached"`
+	Route    string // Untagged fields are not labels
+	Skipped  string `umami:"-"`
+	internal string `umami:"internal"` // Unexported fields are not labels
+}
+
+func TestLabelsFromStruct(t *testing.T) {
+	wantNames := []string{"method", "status_code", "cached"}
+
+	names, labels := LabelsFromStruct(testRouteLabels{Method: "GET", Status: 404, Cached: true, Route: "/", internal: "x"})
+	if !slices.Equal(names, wantNames) {
+		t.Errorf("Expected names %v, got %v", wantNames, names)
+	}
+	want := VecLabels{"method": "GET", "status_code": "404", "cached": "true"}
+	if len(labels) != len(want) {
+		t.Errorf("Expected labels %v, got %v", want, labels)
+	}
+	for name, value := range want {
+		if labels[name] != value {
+			t.Errorf("Expected label %s=%q, got %q", name, value, labels[name])
+		}
+	}
+
+	if _, fromPointer := LabelsFromStruct(&testRouteLabels{Method: "POST"}); fromPointer["method"] != "POST" {
+		t.Errorf("Expected labels from a pointer, got %v", fromPointer)
+	}
+	if names := LabelNames[testRouteLabels](); !slices.Equal(names, wantNames) {
+		t.Errorf("Expected LabelNames %v, got %v", wantNames, names)
+	}
+}
+
+func TestLabelsFromStructVec(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("test", NewMockBackend())
+	counterVec := group.CounterVec(CounterVecOpts{
+		MetricInfo: MetricInfo{Name: "requests"},
+		Labels:     LabelNames[testRouteLabels](),
+	}, LevelImportant)
+
+	_, labels := LabelsFromStruct(testRouteLabels{Method: "GET", Status: 200})
+	if err := counterVec.Inc(group.Context(), labels); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	adapter := counterVec.(*switchableCounterVec).impl.(*baseCounterVec).adapter.(*mockCounterVecAdapter)
+	if got := adapter.GetCount(labels); got != 1 {
+		t.Errorf("Expected 1 increment, got %v", got)
+	}
+}
+
+func TestLabelsFromStructPanicsOnNonStruct(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for a non-struct value")
+		}
+	}()
+	LabelsFromStruct("GET")
+}
@@ -74,14 +74,44 @@ func ParseLevel(s string) Level {
 	}
 }
 
+// Normalize clamps the level into the range of defined levels.
+//
+// Levels below [LevelDisabled] normalize to [LevelDisabled], and levels
+// above [LevelVerbose] normalize to [LevelVerbose].
+func (l Level) Normalize() Level {
+	return min(max(l, LevelDisabled), LevelVerbose)
+}
+
+// AtLeast returns true if this level is at least as verbose as other,
+// e.g. [LevelDebug].AtLeast([LevelImportant]) is true.
+//
+// [LevelDisabled] is the least verbose level.
+func (l Level) AtLeast(other Level) bool {
+	return l >= other
+}
+
+// MoreVerboseThan returns true if this level is strictly more verbose than
+// other, e.g. [LevelVerbose].MoreVerboseThan([LevelDebug]) is true.
+//
+// [LevelDisabled] is the least verbose level.
+func (l Level) MoreVerboseThan(other Level) bool {
+	return l > other
+}
+
 // Enabled returns true if this level should be processed given the configured level
 //
-// A metric with level L should be processed if
-// L <= configuredLevel and configuredLevel != LevelDisabled
+// A metric with level L should be processed if the configured level is at
+// least as verbose as L, and is not [LevelDisabled].
 func (l Level) Enabled(configuredLevel Level) bool {
-	return l <= configuredLevel && configuredLevel != LevelDisabled
+	return configuredLevel.AtLeast(l) && configuredLevel.MoreVerboseThan(LevelDisabled)
 }
 
 type LevelOpts struct {
 	ReplaceNoops bool // If true, replace no-op metrics when changing level
+
+	// ReclaimDisabled, if true, replaces the real metrics disabled by the new
+	// level with no-ops, and unregisters their backend metrics, so that they
+	// are no longer retained nor exported. They are registered again, from
+	// zero, when a later level change with ReplaceNoops enables them.
+	ReclaimDisabled bool
 }
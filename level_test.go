@@ -107,3 +107,42 @@ func TestGroupLevelUpdates(t *testing.T) {
 
 	t.Log("Group level updates test passed")
 }
+
+func TestLevelComparisons(t *testing.T) {
+	tests := []struct {
+		l, other        Level
+		atLeast, strict bool
+	}{
+		{LevelDebug, LevelImportant, true, true},
+		{LevelImportant, LevelDebug, false, false},
+		{LevelDebug, LevelDebug, true, false},
+		{LevelCritical, LevelDisabled, true, true},
+		{LevelDisabled, LevelCritical, false, false},
+		{LevelDisabled, LevelDisabled, true, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.l.AtLeast(tt.other); got != tt.atLeast {
+			t.Errorf("%v.AtLeast(%v) = %v, want %v", tt.l, tt.other, got, tt.atLeast)
+		}
+		if got := tt.l.MoreVerboseThan(tt.other); got != tt.strict {
+			t.Errorf("%v.MoreVerboseThan(%v) = %v, want %v", tt.l, tt.other, got, tt.strict)
+		}
+	}
+}
+
+func TestLevelEnabledDisabledBoundary(t *testing.T) {
+	// Nothing is enabled by a disabled configuration, not even disabled metrics
+	for _, l := range []Level{LevelDisabled, LevelCritical, LevelVerbose} {
+		if l.Enabled(LevelDisabled) {
+			t.Errorf("Expected %v to be disabled by %v", l, LevelDisabled)
+		}
+	}
+
+	if !LevelCritical.Enabled(LevelCritical) {
+		t.Error("Expected LevelCritical to be enabled by LevelCritical")
+	}
+	if LevelImportant.Enabled(LevelCritical) {
+		t.Error("Expected LevelImportant to be disabled by LevelCritical")
+	}
+}
@@ -0,0 +1,129 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: mask.go
+//
+// This file contains the [Mask], which allows metrics to be toggled by
+// category independently of their [Level], e.g. to keep every counter while
+// dropping every histogram, see [Context.WithMask] and [Group.SetGroupMask].
+//--------------------------------------------------------------------------------
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Mask is a set of metric categories, one per bit.
+//
+// Every metric belongs to the category of its kind (e.g. [MaskCounter]), and
+// to those of [MetricInfo.Mask]. A metric is enabled only if all of its
+// categories are enabled, by both the context (see [Context.EnabledMask]) and
+// the creating group (see [Group.SetGroupMask]).
+type Mask uint64
+
+const (
+	// MaskCounter is the category of every counter
+	MaskCounter Mask = 1 << iota
+	// MaskGauge is the category of every gauge
+	MaskGauge
+	// MaskHistogram is the category of every histogram, including those of
+	// timers and other composites
+	MaskHistogram
+	// MaskSummary is the category of every summary
+	MaskSummary
+	// MaskLatency categorizes metrics of durations, see [MetricInfo.Mask]
+	MaskLatency
+	// MaskPerUser categorizes metrics broken down per user or per request,
+	// see [MetricInfo.Mask]
+	MaskPerUser
+)
+
+const (
+	// MaskNone is the empty set of categories, enabling no metrics
+	MaskNone Mask = 0
+
+	// MaskAll is the set of every category, enabling every metric
+	MaskAll Mask = ^Mask(0)
+
+	// MaskCustom is the first bit free for application categories, e.g.
+	//
+	//	const (
+	//		MaskBilling = umami.MaskCustom << iota
+	//		MaskAudit
+	//	)
+	MaskCustom Mask = 1 << 16
+)
+
+// maskNames maps the names accepted by [ParseMask] to their categories
+var maskNames = map[string]Mask{
+	"none":      MaskNone,
+	"all":       MaskAll,
+	"counter":   MaskCounter,
+	"gauge":     MaskGauge,
+	"histogram": MaskHistogram,
+	"summary":   MaskSummary,
+	"latency":   MaskLatency,
+	"per_user":  MaskPerUser,
+}
+
+// ParseMask parses a mask string into a Mask, e.g. from [EnvMetricsMaskKey].
+//
+// The string is a list of category names or numbers, separated by commas or
+// pipes, e.g. "counter|gauge". A name prefixed with "-" removes its categories,
+// so that "all,-histogram" enables every metric but histograms. Unknown names
+// are ignored, and an empty string is [MaskAll].
+func ParseMask(s string) Mask {
+	if strings.TrimSpace(s) == "" {
+		return MaskAll
+	}
+
+	var mask Mask
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '|' }) {
+		part = strings.ToLower(strings.TrimSpace(part))
+		name, remove := strings.CutPrefix(part, "-")
+
+		bits, ok := maskNames[name]
+		if !ok {
+			n, err := strconv.ParseUint(name, 0, 64)
+			if err != nil {
+				continue
+			}
+			bits = Mask(n)
+		}
+
+		if remove {
+			mask &^= bits
+		} else {
+			mask |= bits
+		}
+	}
+	return mask
+}
+
+// Enabled returns true if every category of mask is enabled by the
+// configured mask
+func (m Mask) Enabled(configuredMask Mask) bool {
+	return m&^configuredMask == 0
+}
+
+// maskFilter holds the mask of a [Group]. It stores the disabled categories,
+// so that its zero value enables every metric.
+type maskFilter struct {
+	disabled atomic.Uint64
+}
+
+// allows returns true if every category of mask is enabled
+func (f *maskFilter) allows(mask Mask) bool {
+	return mask&Mask(f.disabled.Load()) == 0
+}
+
+// set sets the enabled categories
+func (f *maskFilter) set(mask Mask) {
+	f.disabled.Store(uint64(^mask))
+}
+
+// get returns the enabled categories
+func (f *maskFilter) get() Mask {
+	return ^Mask(f.disabled.Load())
+}
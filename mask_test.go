@@ -0,0 +1,95 @@
+package umami
+
+import "testing"
+
+func TestParseMask(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Mask
+	}{
+		{"", MaskAll},
+		{"counter", MaskCounter},
+		{"counter|gauge", MaskCounter | MaskGauge},
+		{"Counter, Histogram", MaskCounter | MaskHistogram},
+		{"all,-histogram", MaskAll &^ MaskHistogram},
+		{"none", MaskNone},
+		{"latency,per_user", MaskLatency | MaskPerUser},
+		{"0x10000", MaskCustom},
+		{"counter,bogus", MaskCounter},
+	}
+
+	for _, tt := range tests {
+		if got := ParseMask(tt.in); got != tt.want {
+			t.Errorf("ParseMask(%q) = %b, expected %b", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMaskLevelMatrix(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug).NewGroup("test", backend)
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests"}}, LevelImportant)
+	histogram := group.Histogram(HistogramOpts{MetricInfo: MetricInfo{Name: "latency", Mask: MaskLatency}}, LevelImportant)
+
+	// Levels and masks are orthogonal: each combination enables a metric
+	// only if both its level and all of its categories are enabled
+	for _, level := range []Level{LevelCritical, LevelImportant, LevelVerbose} {
+		for _, mask := range []Mask{MaskAll, MaskAll &^ MaskHistogram, MaskCounter | MaskHistogram, MaskHistogram | MaskLatency} {
+			ctx := NewContext(level).WithMask(mask)
+			before := backend.adapter("test_requests").(*mockCounterAdapter).GetCount()
+			observed := len(backend.adapter("test_latency").(*mockHistogramAdapter).GetObservations())
+
+			_ = counter.Inc(ctx)
+			_ = histogram.Observe(ctx, 1)
+
+			levelEnabled := LevelImportant.Enabled(level)
+			wantCounter := levelEnabled && MaskCounter.Enabled(mask)
+			wantHistogram := levelEnabled && (MaskHistogram | MaskLatency).Enabled(mask)
+
+			if got := backend.adapter("test_requests").(*mockCounterAdapter).GetCount() > before; got != wantCounter {
+				t.Errorf("Level %v, mask %b: expected counter recorded %v, got %v", level, mask, wantCounter, got)
+			}
+			if got := len(backend.adapter("test_latency").(*mockHistogramAdapter).GetObservations()) > observed; got != wantHistogram {
+				t.Errorf("Level %v, mask %b: expected histogram recorded %v, got %v", level, mask, wantHistogram, got)
+			}
+		}
+	}
+}
+
+func TestGroupMask(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	registry := NewRegistry(LevelDebug)
+	group := registry.NewGroup("test", backend)
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests"}}, LevelImportant)
+	timer := group.Timer(TimerOpts{MetricInfo: MetricInfo{Name: "op"}}, LevelImportant)
+	ctx := NewContextAll()
+
+	// Disabling histograms disables the histograms of composites too, and the
+	// group's context carries its mask
+	group.SetGroupMask(MaskAll &^ MaskHistogram)
+	_ = counter.Inc(ctx)
+	_ = timer.Record(ctx, 1)
+	if group.Context().EnabledMask(MaskHistogram) {
+		t.Error("Expected the group context to disable histograms")
+	}
+	if got := backend.adapter("test_requests").(*mockCounterAdapter).GetCount(); got != 1 {
+		t.Errorf("Expected the counter to record, got %v", got)
+	}
+	if got := backend.adapter("test_op_histogram").(*mockHistogramAdapter).GetObservations(); len(got) != 0 {
+		t.Errorf("Expected the timer not to record, got %v", got)
+	}
+
+	// The global mask applies to existing and new groups
+	registry.SetGlobalMask(MaskHistogram)
+	_ = counter.Inc(ctx)
+	_ = timer.Record(ctx, 1)
+	if got := backend.adapter("test_requests").(*mockCounterAdapter).GetCount(); got != 1 {
+		t.Errorf("Expected the counter not to record, got %v", got)
+	}
+	if got := backend.adapter("test_op_histogram").(*mockHistogramAdapter).GetObservations(); len(got) != 1 {
+		t.Errorf("Expected the timer to record, got %v", got)
+	}
+	if registry.NewGroup("other", NewMockBackend()).Context().EnabledMask(MaskCounter) {
+		t.Error("Expected a new group to disable counters")
+	}
+}
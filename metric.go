@@ -1,6 +1,9 @@
 package umami
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Metric is the base interface for all metrics
 type Metric interface {
@@ -13,9 +16,26 @@ type Metric interface {
 
 type CompositeMetric interface {
 	Metric
+
+	// Components returns the basic metrics composing the metric.
+	//
+	// The order is fixed for each composite type, and documented on its
+	// interface, so that callers can map components to their role by index.
 	Components() []Metric
 }
 
+// Stoppable is implemented by metrics that own background resources, such as
+// the ticker of a [Throughput], or the sampler of a [GaugeFunc] on a backend
+// that can't read it at collection time (or its function, unregistered from
+// an [UnregisterBackend] that reads it). [Group.Remove] and
+// [Registry.DeleteGroup] stop removed metrics, so that they don't leak
+// goroutines, and metrics stop the implementations they replace on a level
+// change.
+type Stoppable interface {
+	// Stop releases the background resources of the metric. Noop if stopped.
+	Stop()
+}
+
 type NoopMetric interface {
 	Metric
 	constructorOpts() any
@@ -26,16 +46,119 @@ type VecLabels map[string]string
 
 type BasicMetricOpts struct {
 	FromComposite bool
+
+	// NoPrefix, if set, uses Name as the backend name of the metric as given,
+	// instead of prefixing it with the group name (e.g. for shared, already
+	// fully qualified metric names). Names must be unique across the groups
+	// of a registry, so creating a metric whose name is already used by
+	// another group panics.
+	NoPrefix bool
+
+	// Predicate, if set, is consulted on every operation after the level check
+	// passes. The operation is a noop if it returns false, allowing a metric to
+	// be toggled dynamically (e.g. by a feature flag) without level changes.
+	//
+	// It is called on the hot path, so it should be cheap.
+	Predicate func(ctx Context) bool
+
+	// Async, if set, applies the writes of the metric to its backend on a
+	// background goroutine, through a queue bounded in size and in how long
+	// a write waits for room (see [WithAsyncEmit]), so that costly emits
+	// don't block callers. Writes dropped by a full queue return
+	// [ErrAsyncQueueFull], and errors of applied writes are passed to the
+	// warning handler of the registry ([WithWarningHandler]).
+	//
+	// Reads (e.g. [Summary.Quantile]) wait for the writes queued before them
+	// to be applied. See [Registry.Flush] and [Registry.Close].
+	Async bool
+}
+
+// CompositeMetricOpts are the options common to all composite metrics
+type CompositeMetricOpts struct {
+	// NoPrefix, if set, applies [BasicMetricOpts.NoPrefix] to every component
+	NoPrefix bool
 }
 
 type MetricInfo struct {
 	Name string
 	Help string
+
+	// Namespace and Subsystem, if set, qualify the name of the backend metric,
+	// e.g. "shop_checkout_<name>", see [MetricInfo.QualifiedName]. Backends
+	// know their metrics by the qualified name, e.g. to unregister them. The
+	// namespace and subsystem of a composite metric apply to those of its
+	// components that don't have their own.
+	Namespace string
+	Subsystem string
+
+	// ConstLabels are static labels of every series of the metric, e.g. the
+	// service or version, for backends that support them (e.g. Prometheus, or
+	// OpenTelemetry as attributes). Their names must not clash with the labels
+	// of vec metrics. The const labels of a composite metric also apply to all
+	// of its components.
+	ConstLabels map[string]string
+
+	// Unit is the unit of the metric's values, e.g. "seconds" or "bytes". It
+	// is informational, see [Registry.Definitions], and not added to the name.
+	Unit string
+
+	// Tags categorize the metric (e.g. "slo", "latency"). Operations are a noop
+	// while any of the tags is disabled on the group (see [Group.DisableTags]).
+	// Tags of a composite metric also apply to all of its components.
+	Tags []string
+
+	// Mask adds categories to the metric, besides that of its kind, e.g.
+	// [MaskLatency]. Operations are a noop while any of its categories is
+	// disabled, see [Mask]. The mask of a composite metric also applies to all
+	// of its components.
+	Mask Mask
+
+	// SLO is the service level objective the metric tracks, if any, e.g. for
+	// dashboard generation. It is informational, see [Registry.Definitions].
+	// The SLO of a composite metric applies to those of its components that
+	// don't have their own.
+	SLO *SLOSpec
+}
+
+// QualifiedName returns the name prefixed with the namespace and subsystem
+// that are set, joined with underscores, e.g. "shop_checkout_orders_total"
+func (i MetricInfo) QualifiedName() string {
+	var parts []string
+	for _, part := range []string{i.Namespace, i.Subsystem, i.Name} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+// SLOSpec describes a service level objective, e.g. "99% of requests in less
+// than 300ms over 30 days", see [MetricInfo.SLO]
+type SLOSpec struct {
+	// Target is the fraction of events that must meet the objective, e.g. 0.99
+	Target float64 `json:"target"`
+
+	// Threshold is the value events must not exceed to meet the objective, in
+	// the unit of the metric, e.g. 0.3 for 300ms of a latency in seconds
+	Threshold float64 `json:"threshold,omitempty"`
+
+	// Window is the period over which the objective is evaluated, e.g. 30 days
+	Window time.Duration `json:"window,omitempty"`
 }
 
 type CounterOpts struct {
 	BasicMetricOpts
 	MetricInfo
+
+	// ResetOnScrape reports the counter as the delta since the previous
+	// collection, zeroing it on every collection, for StatsD-like consumers
+	// that expect per-flush deltas.
+	//
+	// This breaks the expectations of Prometheus' rate() and increase(), which
+	// see a counter reset on every scrape, and any gather of the backend, e.g.
+	// [Registry.MetricValue], counts as a collection. Backends that can't hook
+	// collection ignore it, and report the counter cumulatively.
+	ResetOnScrape bool
 }
 
 // Counter is a metric that counts occurrences. It only counts up.
@@ -47,12 +170,74 @@ type Counter interface {
 
 	// Add adds the given value to the counter. Noop if disabled.
 	Add(ctx Context, value float64) error
+
+	// IncWithExemplar increments the counter, attaching exemplar to the
+	// increment, e.g. {"trace_id": id} to correlate it with a trace. Noop if
+	// disabled.
+	//
+	// Backends that don't support exemplars ignore it, see
+	// [ExemplarCounterAdapter].
+	IncWithExemplar(ctx Context, exemplar VecLabels) error
+
+	// AddWithExemplar adds the given value to the counter, attaching exemplar
+	// to it like IncWithExemplar. Noop if disabled.
+	AddWithExemplar(ctx Context, value float64, exemplar VecLabels) error
+
+	// Value returns the current value of the counter. Returns 0 if disabled.
+	//
+	// Returns [ErrUnsupportedMetric] if the backend can't read values back,
+	// see [ValueAdapter].
+	Value(ctx Context) (float64, error)
+}
+
+// CounterGroup is a read-only counter reporting the sum of several member
+// counters as one series, e.g. per-shard error counts rolled up into the
+// total errors. See [Factory.CounterGroup].
+type CounterGroup interface {
+	Metric
+
+	// Value returns the sum of the values of the members. Returns 0 if disabled.
+	//
+	// Members whose value can't be read are skipped, and their errors joined.
+	Value(ctx Context) (float64, error)
+
+	// Members returns the counters summed by the group
+	Members() []Counter
+}
+
+// CounterFunc is a read-only counter whose value is returned by a function,
+// e.g. a count kept by a library, read when the backend collects it. See
+// [Factory.CounterFunc].
+type CounterFunc interface {
+	Metric
+
+	// Value returns the value of the function. Returns 0 if disabled.
+	Value(ctx Context) (float64, error)
 }
 
 type CounterVecOpts struct {
 	BasicMetricOpts
 	MetricInfo
 	Labels []string
+
+	// PreInitLabels are label sets whose series are created when the metric
+	// is created, so that they are exported (e.g. at 0) before anything is
+	// recorded, and queries like rate() don't see missing series.
+	//
+	// Each set creates a series for the lifetime of the backend metric, so
+	// only list the bounded, known set of expected combinations.
+	PreInitLabels []VecLabels
+
+	// MaxCardinality is the maximum number of series of the metric, or 0 for
+	// no limit. Once reached, operations that would create a new series are
+	// dropped, returning [ErrCardinalityExceeded], and counted in the
+	// [DroppedSeriesMetricName] counter vec of the group, while those of
+	// existing series still apply. Deleting a series frees its slot.
+	//
+	// Series are tracked per metric, including those of PreInitLabels, so
+	// the limit guards against an unbounded label (e.g. a user id) at the
+	// cost of a lookup per operation.
+	MaxCardinality int
 }
 
 // CounterVec is a metric that counts occurrences, partitioned by labels.
@@ -64,11 +249,72 @@ type CounterVec interface {
 
 	// Add adds the given value to the counter for the given labels. Noop if disabled.
 	Add(ctx Context, value float64, labels VecLabels) error
+
+	// Collect returns a snapshot of the labels and value of every series of
+	// the counter, e.g. to reconcile them with another source. Returns nil if
+	// disabled.
+	//
+	// Returns [ErrUnsupportedMetric] if the backend can't enumerate series,
+	// see [SeriesAdapter].
+	Collect(ctx Context) ([]Sample, error)
+
+	// WithLabelValues returns the counter of the series of values, given in
+	// the order of the declared Labels, e.g. to bind a hot series once and
+	// skip building a [VecLabels] on every operation.
+	//
+	// The series is resolved when the counter is returned if the vec is
+	// enabled under ctx, and on its first enabled operation otherwise. If
+	// the number of values doesn't match the declared labels, operations on
+	// the counter return an error wrapping [ErrLabelMismatch].
+	WithLabelValues(ctx Context, values ...string) Counter
+
+	// Delete removes the series of the labels, e.g. of a client that
+	// disconnected, so that the backend stops exporting it and frees it.
+	// Returns true if the series existed. Noop returning false if disabled.
+	//
+	// Returns false if the backend can't remove series, see [DeleteAdapter].
+	// Counters bound to the series by WithLabelValues no longer record into
+	// the vec once it is deleted.
+	Delete(ctx Context, labels VecLabels) bool
+
+	// Reset removes every series, like Delete. Noop if disabled.
+	Reset(ctx Context)
 }
 
 type GaugeOpts struct {
 	BasicMetricOpts
 	MetricInfo
+
+	// Bounds, if set, is the range of values the gauge may be set to, e.g.
+	// [RatioBounds] for a ratio. It only applies to Set, as Inc, Dec, and Add
+	// are relative to a value the gauge may not know.
+	Bounds *GaugeBounds
+}
+
+// GaugeBounds is the range of values a gauge may be set to, see [GaugeOpts.Bounds]
+type GaugeBounds struct {
+	Min float64
+	Max float64
+
+	// Strict rejects values out of range with [ErrOutOfBounds], instead of
+	// clamping them into the range
+	Strict bool
+}
+
+// RatioBounds returns the bounds of a ratio gauge, e.g. a cache hit ratio,
+// whose values must be in [0, 1]
+func RatioBounds(strict bool) *GaugeBounds {
+	return &GaugeBounds{Min: 0, Max: 1, Strict: strict}
+}
+
+// GaugeFunc is a read-only gauge whose value is returned by a function, e.g.
+// the length of a queue, read when the backend collects it. See
+// [Factory.GaugeFunc].
+type GaugeFunc interface {
+	Metric
+
+	// Value returns the value of the function. Returns 0 if disabled.
+	Value(ctx Context) (float64, error)
 }
 
 // Gauge is a metric that represents a single numerical value that can arbitrarily go up and down.
@@ -86,12 +332,37 @@ type Gauge interface {
 
 	// Add adds the given value to the gauge. Noop if disabled.
 	Add(ctx Context, value float64) error
+
+	// Value returns the current value of the gauge. Returns 0 if disabled.
+	//
+	// Returns [ErrUnsupportedMetric] if the backend can't read values back,
+	// see [ValueAdapter].
+	Value(ctx Context) (float64, error)
 }
 
 type GaugeVecOpts struct {
 	BasicMetricOpts
 	MetricInfo
 	Labels []string
+
+	// PreInitLabels are label sets whose series are created when the metric
+	// is created, so that they are exported (e.g. at 0) before anything is
+	// recorded, and queries like rate() don't see missing series.
+	//
+	// Each set creates a series for the lifetime of the backend metric, so
+	// only list the bounded, known set of expected combinations.
+	PreInitLabels []VecLabels
+
+	// MaxCardinality is the maximum number of series of the metric, or 0 for
+	// no limit. Once reached, operations that would create a new series are
+	// dropped, returning [ErrCardinalityExceeded], and counted in the
+	// [DroppedSeriesMetricName] counter vec of the group, while those of
+	// existing series still apply. Deleting a series frees its slot.
+	//
+	// Series are tracked per metric, including those of PreInitLabels, so
+	// the limit guards against an unbounded label (e.g. a user id) at the
+	// cost of a lookup per operation.
+	MaxCardinality int
 }
 
 // GaugeVec is a metric that represents a collection of gauge values, partitioned by labels.
@@ -109,12 +380,34 @@ type GaugeVec interface {
 
 	// Add adds the given value to the gauge for the given labels. Noop if disabled.
 	Add(ctx Context, value float64, labels VecLabels) error
+
+	// Delete removes the series of the labels, like [CounterVec.Delete]
+	Delete(ctx Context, labels VecLabels) bool
+
+	// Reset removes every series, like [CounterVec.Reset]
+	Reset(ctx Context)
 }
 
 type HistogramOpts struct {
 	BasicMetricOpts
 	MetricInfo
 	Buckets []float64
+
+	// BucketPreset names a default bucket set (see [BucketPresetLatency],
+	// [BucketPresetSize], and [BucketPresetRatio]) used when Buckets is nil.
+	BucketPreset string
+
+	// NativeHistogramBucketFactor, if greater than 1, makes the histogram a
+	// native (sparse) histogram on backends supporting them, such as
+	// Prometheus, whose buckets grow exponentially by at most this factor
+	// (e.g. 1.1 for buckets 10% wide). Buckets are then ignored. Backends
+	// that don't support native histograms use Buckets instead.
+	NativeHistogramBucketFactor float64
+
+	// NativeHistogramMaxBucketNumber, if set, is the maximum number of
+	// buckets of a native histogram, beyond which its resolution is reduced.
+	// Zero means no limit.
+	NativeHistogramMaxBucketNumber uint32
 }
 
 // Histogram is a metric that represents a distribution of values.
@@ -123,6 +416,25 @@ type Histogram interface {
 
 	// Observe adds an observation to the histogram. Noop if disabled.
 	Observe(ctx Context, value float64) error
+
+	// ObserveWithExemplar adds an observation to the histogram, attaching
+	// exemplar to it, e.g. {"trace_id": id} to correlate the bucket of the
+	// observation with a trace. Noop if disabled.
+	//
+	// Backends that don't support exemplars ignore it, see
+	// [ExemplarHistogramAdapter].
+	ObserveWithExemplar(ctx Context, value float64, exemplar VecLabels) error
+
+	// ObserveBytes adds an observation of n bytes to the histogram. Noop if disabled.
+	//
+	// It is equivalent to Observe(ctx, float64(n)), and is best paired with
+	// [ByteBuckets].
+	ObserveBytes(ctx Context, n int64) error
+
+	// Time calls fn, and observes its duration in seconds whether it returns
+	// an error or panics. The error of fn is returned. If disabled, fn is
+	// called without being timed.
+	Time(ctx Context, fn func() error) error
 }
 
 type HistogramVecOpts struct {
@@ -130,6 +442,34 @@ type HistogramVecOpts struct {
 	MetricInfo
 	Labels  []string
 	Buckets []float64
+
+	// BucketPreset names a default bucket set (see [BucketPresetLatency],
+	// [BucketPresetSize], and [BucketPresetRatio]) used when Buckets is nil.
+	BucketPreset string
+
+	// NativeHistogramBucketFactor and NativeHistogramMaxBucketNumber make
+	// the histogram a native histogram, see [HistogramOpts]
+	NativeHistogramBucketFactor    float64
+	NativeHistogramMaxBucketNumber uint32
+
+	// PreInitLabels are label sets whose series are created when the metric
+	// is created, so that they are exported (e.g. with a count of 0) before
+	// anything is recorded, and queries like rate() don't see missing series.
+	//
+	// Each set creates a series for the lifetime of the backend metric, so
+	// only list the bounded, known set of expected combinations.
+	PreInitLabels []VecLabels
+
+	// MaxCardinality is the maximum number of series of the metric, or 0 for
+	// no limit. Once reached, operations that would create a new series are
+	// dropped, returning [ErrCardinalityExceeded], and counted in the
+	// [DroppedSeriesMetricName] counter vec of the group, while those of
+	// existing series still apply. Deleting a series frees its slot.
+	//
+	// Series are tracked per metric, including those of PreInitLabels, so
+	// the limit guards against an unbounded label (e.g. a user id) at the
+	// cost of a lookup per operation.
+	MaxCardinality int
 }
 
 // HistogramVec is a metric that represents a distribution of values, partitioned by labels.
@@ -138,12 +478,27 @@ type HistogramVec interface {
 
 	// Observe adds an observation to the histogram for the given labels. Noop if disabled.
 	Observe(ctx Context, value float64, labels VecLabels) error
+
+	// Time calls fn, and observes its duration in seconds for the given
+	// labels, like [Histogram.Time]
+	Time(ctx Context, fn func() error, labels VecLabels) error
+
+	// Delete removes the series of the labels, like [CounterVec.Delete]
+	Delete(ctx Context, labels VecLabels) bool
+
+	// Reset removes every series, like [CounterVec.Reset]
+	Reset(ctx Context)
 }
 
 type SummaryOpts struct {
 	BasicMetricOpts
 	MetricInfo
 	Objectives map[float64]float64
+
+	// ObjectiveSets are additional objectives merged into Objectives when the
+	// summary is created, see [MergeObjectives]. Conflicting errors for the
+	// same quantile are reported to the registry's [WithWarningHandler].
+	ObjectiveSets []map[float64]float64
 }
 
 // Summary is a metric that provides quantiles of a distribution.
@@ -155,6 +510,14 @@ type Summary interface {
 
 	// Quantile returns the value at the given quantile. Returns 0 if metric is disabled.
 	Quantile(ctx Context, q float64) (float64, error)
+
+	// Reconfigure replaces the summary's objectives and help with those in opts.
+	// The name of the summary is kept, and opts.Name is ignored.
+	//
+	// Since backends like Prometheus cannot change objectives of a live summary,
+	// the backend summary is unregistered and a new one registered in its place.
+	// All data recorded so far is reset. The backend must be an [UnregisterBackend].
+	Reconfigure(opts SummaryOpts) error
 }
 
 type SummaryVecOpts struct {
@@ -162,6 +525,30 @@ type SummaryVecOpts struct {
 	MetricInfo
 	Labels     []string
 	Objectives map[float64]float64
+
+	// ObjectiveSets are additional objectives merged into Objectives when the
+	// summary is created, see [MergeObjectives]. Conflicting errors for the
+	// same quantile are reported to the registry's [WithWarningHandler].
+	ObjectiveSets []map[float64]float64
+
+	// PreInitLabels are label sets whose series are created when the metric
+	// is created, so that they are exported (e.g. with a count of 0) before
+	// anything is recorded, and queries like rate() don't see missing series.
+	//
+	// Each set creates a series for the lifetime of the backend metric, so
+	// only list the bounded, known set of expected combinations.
+	PreInitLabels []VecLabels
+
+	// MaxCardinality is the maximum number of series of the metric, or 0 for
+	// no limit. Once reached, operations that would create a new series are
+	// dropped, returning [ErrCardinalityExceeded], and counted in the
+	// [DroppedSeriesMetricName] counter vec of the group, while those of
+	// existing series still apply. Deleting a series frees its slot.
+	//
+	// Series are tracked per metric, including those of PreInitLabels, so
+	// the limit guards against an unbounded label (e.g. a user id) at the
+	// cost of a lookup per operation.
+	MaxCardinality int
 }
 
 // SummaryVec is a metric that provides quantiles of a distribution, partitioned by labels.
@@ -173,31 +560,78 @@ type SummaryVec interface {
 
 	// Quantile returns the value at the given quantile for the given labels. Returns 0 if metric is disabled.
 	Quantile(ctx Context, q float64, labels VecLabels) (float64, error)
+
+	// Delete removes the series of the labels, like [CounterVec.Delete]
+	Delete(ctx Context, labels VecLabels) bool
+
+	// Reset removes every series, like [CounterVec.Reset]
+	Reset(ctx Context)
 }
 
 type TimerOpts struct {
+	CompositeMetricOpts
 	MetricInfo
 	HistogramOpts HistogramOpts
+
+	// Clock is the time source used to measure durations. Defaults to the real clock.
+	Clock Clock
 }
 
 // Timer is a metric that measures durations.
+//
+// Its [CompositeMetric.Components] are, in order: its duration histogram.
 type Timer interface {
 	CompositeMetric
 
 	// Start returns a function that should be called when the operation completes
 	// Returns a no-op function if metric is disabled
+	//
+	// Called with defer, as in defer timer.Start(ctx)(), the duration is also
+	// recorded if the surrounding function panics. A function stored and called
+	// manually is skipped by a panic, see [Timer.StartFunc].
 	Start(ctx Context) func()
 
+	// StartFunc calls fn, and records its duration whether it returns an error
+	// or panics. The error of fn is returned, and its panic is propagated once
+	// the duration is recorded. Noop if disabled, other than calling fn.
+	StartFunc(ctx Context, fn func() error) error
+
 	// Record records a duration. Noop if disabled.
 	Record(ctx Context, duration time.Duration) error
+
+	// RecordFromContextStart records the duration elapsed since the start
+	// time of ctx, see [Context.WithStart]. Returns an error wrapping
+	// [ErrNoStartTime] if ctx has none, even if disabled. Noop if disabled.
+	RecordFromContextStart(ctx Context) error
+
+	// AsObserver returns a [DurationObserver] recording durations under ctx,
+	// to plug the timer into middleware. Errors of [Timer.Record] are dropped.
+	AsObserver(ctx Context) DurationObserver
+}
+
+// DurationObserver is a sink of durations, such as the callbacks expected by
+// HTTP and gRPC middleware frameworks, see [Timer.AsObserver]
+type DurationObserver func(duration time.Duration)
+
+// DurationObserverVec is a label-vectorized [DurationObserver], see
+// [TimerVec.AsObserverVec]
+type DurationObserverVec interface {
+	// With returns a [DurationObserver] recording durations with the labels
+	With(labels VecLabels) DurationObserver
 }
 
 type TimerVecOpts struct {
+	CompositeMetricOpts
 	MetricInfo
 	HistogramVecOpts HistogramVecOpts
+
+	// Clock is the time source used to measure durations. Defaults to the real clock.
+	Clock Clock
 }
 
 // TimerVec is a metric that measures durations, partitioned by labels.
+//
+// Its [CompositeMetric.Components] are, in order: its duration histogram vector.
 type TimerVec interface {
 	CompositeMetric
 
@@ -207,9 +641,22 @@ type TimerVec interface {
 
 	// Record records a duration. Noop if disabled.
 	Record(ctx Context, duration time.Duration, labels VecLabels) error
+
+	// RecordFromContextStart records the duration elapsed since the start
+	// time of ctx for the given labels, like [Timer.RecordFromContextStart]
+	RecordFromContextStart(ctx Context, labels VecLabels) error
+
+	// AsObserverVec returns a [DurationObserverVec] recording durations under
+	// ctx, like [Timer.AsObserver]
+	AsObserverVec(ctx Context) DurationObserverVec
+
+	// Partition returns a [Timer] recording into the timer for the given labels,
+	// for code tracking a single partition. Its level is that of the vec.
+	Partition(labels VecLabels) Timer
 }
 
 type CacheOpts struct {
+	CompositeMetricOpts
 	MetricInfo
 	HitOpts  CounterOpts
 	MissOpts CounterOpts
@@ -217,6 +664,8 @@ type CacheOpts struct {
 }
 
 // Cache is a metric that represents cache performance.
+//
+// Its [CompositeMetric.Components] are, in order: the hits counter, the misses counter and the size gauge.
 type Cache interface {
 	CompositeMetric
 
@@ -231,6 +680,7 @@ type Cache interface {
 }
 
 type CacheVecOpts struct {
+	CompositeMetricOpts
 	MetricInfo
 	HitVecOpts  CounterVecOpts
 	MissVecOpts CounterVecOpts
@@ -238,6 +688,9 @@ type CacheVecOpts struct {
 }
 
 // CacheVec is a metric that represents cache performance, partitioned by labels.
+//
+// Its [CompositeMetric.Components] are, in order: the hits counter vector, the misses counter vector and the size gauge
+// vector.
 type CacheVec interface {
 	CompositeMetric
 
@@ -249,9 +702,14 @@ type CacheVec interface {
 
 	// SetSize sets the current cache size for the given labels. Noop if disabled.
 	SetSize(ctx Context, bytes int64, labels VecLabels) error
+
+	// Partition returns a [Cache] recording into the cache for the given labels,
+	// for code tracking a single partition. Its level is that of the vec.
+	Partition(labels VecLabels) Cache
 }
 
 type PoolOpts struct {
+	CompositeMetricOpts
 	MetricInfo
 	ActiveOpts   GaugeOpts
 	IdleOpts     GaugeOpts
@@ -260,6 +718,9 @@ type PoolOpts struct {
 }
 
 // Pool is a metric that represents item pool utilization.
+//
+// Its [CompositeMetric.Components] are, in order: the active gauge, the idle gauge, the acquired counter and the
+// released counter.
 type Pool interface {
 	CompositeMetric
 
@@ -277,6 +738,7 @@ type Pool interface {
 }
 
 type PoolVecOpts struct {
+	CompositeMetricOpts
 	MetricInfo
 	ActiveVecOpts   GaugeVecOpts
 	IdleVecOpts     GaugeVecOpts
@@ -285,6 +747,9 @@ type PoolVecOpts struct {
 }
 
 // PoolVec is a metric that represents item pool utilization, partitioned by labels.
+//
+// Its [CompositeMetric.Components] are, in order: the active gauge vector, the idle gauge vector, the acquired
+// counter vector and the released counter vector.
 type PoolVec interface {
 	CompositeMetric
 
@@ -299,9 +764,14 @@ type PoolVec interface {
 
 	// Released records an item release for the given labels. Noop if disabled.
 	Released(ctx Context, labels VecLabels) error
+
+	// Partition returns a [Pool] recording into the pool for the given labels,
+	// for code tracking a single partition. Its level is that of the vec.
+	Partition(labels VecLabels) Pool
 }
 
 type CircuitBreakerOpts struct {
+	CompositeMetricOpts
 	MetricInfo
 	StateOpts   GaugeOpts
 	SuccessOpts CounterOpts
@@ -309,6 +779,9 @@ type CircuitBreakerOpts struct {
 }
 
 // CircuitBreaker is a metric that represents the circuit breaker state
+//
+// Its [CompositeMetric.Components] are, in order: the state gauge, the successes counter and the failures
+// counter.
 type CircuitBreaker interface {
 	CompositeMetric
 
@@ -323,6 +796,7 @@ type CircuitBreaker interface {
 }
 
 type CircuitBreakerVecOpts struct {
+	CompositeMetricOpts
 	MetricInfo
 	StateVecOpts   GaugeVecOpts
 	SuccessVecOpts CounterVecOpts
@@ -330,6 +804,9 @@ type CircuitBreakerVecOpts struct {
 }
 
 // CircuitBreakerVec is a metric that represents the circuit breaker state, partitioned by labels.
+//
+// Its [CompositeMetric.Components] are, in order: the state gauge vector, the successes counter vector and
+// the failures counter vector.
 type CircuitBreakerVec interface {
 	CompositeMetric
 
@@ -341,9 +818,14 @@ type CircuitBreakerVec interface {
 
 	// Failure records a failed operation for the given labels. Noop if disabled.
 	Failure(ctx Context, labels VecLabels) error
+
+	// Partition returns a [CircuitBreaker] recording into the circuit breaker for the given labels,
+	// for code tracking a single partition. Its level is that of the vec.
+	Partition(labels VecLabels) CircuitBreaker
 }
 
 type QueueOpts struct {
+	CompositeMetricOpts
 	MetricInfo
 	DepthOpts    GaugeOpts
 	EnqueuedOpts CounterOpts
@@ -352,6 +834,9 @@ type QueueOpts struct {
 }
 
 // Queue is a metric that represents queue statistics.
+//
+// Its [CompositeMetric.Components] are, in order: the depth gauge, the enqueued counter, the dequeued counter and
+// the wait time histogram.
 type Queue interface {
 	CompositeMetric
 
@@ -364,11 +849,29 @@ type Queue interface {
 	// Dequeued records an item being dequeued. Noop if disabled.
 	Dequeued(ctx Context) error
 
+	// ObserveWait observes how long an item waited in the queue. Noop if disabled.
+	ObserveWait(ctx Context, duration time.Duration) error
+
+	// DequeuedWithWait records an item being dequeued and observes how long it
+	// waited in the queue. Noop if disabled.
+	DequeuedWithWait(ctx Context, waited time.Duration) error
+
+	// Enqueue records an item being enqueued and increments the depth,
+	// keeping both consistent. Noop if disabled.
+	Enqueue(ctx Context) error
+
+	// Dequeue records an item being dequeued, decrements the depth, and
+	// observes how long it waited in the queue. Noop if disabled.
+	Dequeue(ctx Context, waited time.Duration) error
+
 	// SetWaitTime records how long items wait in the queue. Noop if disabled.
+	//
+	// Deprecated: SetWaitTime observes rather than sets, use [Queue.ObserveWait].
 	SetWaitTime(ctx Context, duration time.Duration) error
 }
 
 type QueueVecOpts struct {
+	CompositeMetricOpts
 	MetricInfo
 	DepthVecOpts    GaugeVecOpts
 	EnqueuedVecOpts CounterVecOpts
@@ -377,6 +880,9 @@ type QueueVecOpts struct {
 }
 
 // QueueVec is a metric that represents queue statistics, partitioned by labels.
+//
+// Its [CompositeMetric.Components] are, in order: the depth gauge vector, the enqueued counter vector, the
+// dequeued counter vector and the wait time histogram vector.
 type QueueVec interface {
 	CompositeMetric
 
@@ -389,6 +895,170 @@ type QueueVec interface {
 	// Dequeued records an item being dequeued for the given labels. Noop if disabled.
 	Dequeued(ctx Context, labels VecLabels) error
 
+	// ObserveWait observes how long an item waited in the queue for the given labels. Noop if disabled.
+	ObserveWait(ctx Context, duration time.Duration, labels VecLabels) error
+
+	// DequeuedWithWait records an item being dequeued for the given labels and
+	// observes how long it waited in the queue. Noop if disabled.
+	DequeuedWithWait(ctx Context, waited time.Duration, labels VecLabels) error
+
+	// Enqueue records an item being enqueued and increments the depth,
+	// keeping both consistent. Noop if disabled.
+	Enqueue(ctx Context, labels VecLabels) error
+
+	// Dequeue records an item being dequeued, decrements the depth, and
+	// observes how long it waited in the queue. Noop if disabled.
+	Dequeue(ctx Context, waited time.Duration, labels VecLabels) error
+
 	// SetWaitTime records how long items wait in the queue for the given labels. Noop if disabled.
+	//
+	// Deprecated: SetWaitTime observes rather than sets, use [QueueVec.ObserveWait].
 	SetWaitTime(ctx Context, duration time.Duration, labels VecLabels) error
+
+	// Partition returns a [Queue] recording into the queue for the given labels,
+	// for code tracking a single partition. Its level is that of the vec.
+	Partition(labels VecLabels) Queue
+}
+
+type DistributionGaugeOpts struct {
+	CompositeMetricOpts
+	MetricInfo
+	GaugeOpts     GaugeOpts
+	HistogramOpts HistogramOpts
+}
+
+// DistributionGauge is a metric that tracks the latest value of a quantity
+// (e.g. a temperature) along with the distribution of its observed values.
+//
+// Its [CompositeMetric.Components] are, in order: the gauge and the histogram.
+type DistributionGauge interface {
+	CompositeMetric
+
+	// Observe sets the gauge to value, and observes it in the histogram. Noop if disabled.
+	Observe(ctx Context, value float64) error
+}
+
+type MultiResHistogramOpts struct {
+	CompositeMetricOpts
+	MetricInfo
+
+	// Resolutions are the histograms every observation is recorded into,
+	// typically with different bucket sets, e.g. coarse buckets for long-term
+	// storage and fine buckets for recent dashboards.
+	//
+	// Their names must be distinct. An unset name defaults to the name of the
+	// metric suffixed with the index of the resolution, e.g. "latency_res0".
+	Resolutions []HistogramOpts
+}
+
+// MultiResHistogram is a metric that records every observation into several
+// histograms with different bucket resolutions.
+//
+// Its [CompositeMetric.Components] are, in order: the histograms, in the order of
+// [MultiResHistogramOpts.Resolutions].
+type MultiResHistogram interface {
+	CompositeMetric
+
+	// Observe adds an observation to every histogram. Noop if disabled.
+	Observe(ctx Context, value float64) error
+}
+
+// Labels of the components of a [Dependency], and the values of its outcome label
+const (
+	DependencyLabel = "dependency"
+	OperationLabel  = "operation"
+	OutcomeLabel    = "outcome"
+
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+type DependencyOpts struct {
+	CompositeMetricOpts
+	MetricInfo
+
+	// CallsVecOpts is the counter of completed calls, labeled by
+	// [DependencyLabel], [OperationLabel] and [OutcomeLabel]. An unset name
+	// defaults to the name of the metric suffixed with "_calls_total".
+	CallsVecOpts CounterVecOpts
+
+	// LatencyVecOpts is the histogram of the latencies of calls, in seconds,
+	// labeled by [DependencyLabel] and [OperationLabel]. An unset name
+	// defaults to the name of the metric suffixed with "_latency_seconds".
+	LatencyVecOpts HistogramVecOpts
+
+	// InFlightVecOpts is the gauge of the calls in flight, labeled by
+	// [DependencyLabel] and [OperationLabel]. An unset name defaults to the
+	// name of the metric suffixed with "_in_flight".
+	InFlightVecOpts GaugeVecOpts
+
+	// Clock is the time source used to measure latencies. Defaults to the real clock.
+	Clock Clock
+}
+
+// Dependency is a metric that instruments the outbound calls to dependencies,
+// e.g. databases, HTTP APIs or caches, identically: the calls by outcome,
+// their latency, and the calls in flight. The labels of the components are
+// set by the metric, and those of their opts are ignored.
+//
+// Its [CompositeMetric.Components] are, in order: the calls counter vector,
+// the latency histogram vector and the in-flight gauge vector.
+type Dependency interface {
+	CompositeMetric
+
+	// Call records the start of a call to the operation of the dependency,
+	// and returns a function to call with the error of the call once it
+	// completes, which records its outcome and latency:
+	//
+	//	done := dependency.Call(ctx, "postgres", "select_user")
+	//	user, err := selectUser(id)
+	//	done(err)
+	//
+	// A nil error is a success. Returns a no-op function if the metric is disabled.
+	Call(ctx Context, dependency, operation string) func(err error)
+}
+
+// DefaultThroughputWindow is the window of a [Throughput] whose opts don't set one
+const DefaultThroughputWindow = 10 * time.Second
+
+type ThroughputOpts struct {
+	CompositeMetricOpts
+	MetricInfo
+	CountOpts CounterOpts
+	RateOpts  GaugeOpts
+
+	// Window is the interval at which the rate is computed from the events
+	// marked since the previous computation. Defaults to [DefaultThroughputWindow].
+	Window time.Duration
+
+	// AutoStart starts computing the rate when the metric is created.
+	// Otherwise, the computation only runs between [Throughput.Start] and
+	// [Throughput.Stop].
+	AutoStart bool
+
+	// Clock is the time source used to compute the rate. Defaults to the real clock.
+	Clock Clock
+}
+
+// Throughput is a metric that counts events and tracks their current rate.
+//
+// The rate, in events per second, is computed by a background ticker every
+// window. Call Stop, or remove the metric from its [Group], when the metric
+// is no longer used to release the ticker.
+//
+// Its [CompositeMetric.Components] are, in order: the count counter and the rate gauge.
+type Throughput interface {
+	CompositeMetric
+	Stoppable
+
+	// Mark records a single event. Noop if disabled.
+	Mark(ctx Context) error
+
+	// MarkN records n events. Noop if disabled.
+	MarkN(ctx Context, n int64) error
+
+	// Start starts computing the rate. Noop if already started.
+	//
+	// [Stoppable.Stop] stops computing the rate, and waits for the ticker to exit.
+	Start()
 }
@@ -0,0 +1,491 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: mirror_backend.go
+//
+// This file contains the [mirrorBackend], a [Backend] that fans out every
+// adapter operation to a primary backend and zero or more mirror backends. It
+// is used by groups created with mirror backends (see
+// [Registry.NewMirroredGroup]) so that a single group can, for example, record
+// into Prometheus while also mirroring into an audit backend, and is exposed
+// as is by [NewMultiBackend].
+//--------------------------------------------------------------------------------
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// mirrorBackend implements the [Backend] interface by fanning out to
+// a primary backend and its mirrors.
+//
+// Every adapter operation is applied to every backend, even if one of them
+// fails, and all errors are joined, each prefixed with the name of the
+// backend that returned it. Reads (e.g. [SummaryAdapter.Quantile]) are served
+// by the primary backend only.
+type mirrorBackend struct {
+	name    string // If empty, the name of the primary backend
+	primary Backend
+	mirrors []Backend
+	names   []string // Names of the backends, primary first
+}
+
+// newMirrorBackend returns a backend fanning out to primary and mirrors.
+// If there are no mirrors, primary is returned as is.
+func newMirrorBackend(primary Backend, mirrors ...Backend) Backend {
+	if len(mirrors) == 0 {
+		return primary
+	}
+
+	names := []string{primary.Name()}
+	for _, mirror := range mirrors {
+		names = append(names, mirror.Name())
+	}
+
+	return &mirrorBackend{
+		primary: primary,
+		mirrors: mirrors,
+		names:   names,
+	}
+}
+
+// NewMultiBackend returns a backend recording every operation into each of
+// backends, e.g. into Prometheus for local scraping while pushing to StatsD.
+//
+// A failing backend doesn't prevent the others from recording: the errors of
+// all backends are joined, each prefixed with the name of the backend that
+// returned it. Reads, e.g. of [Counter.Value] or [Registry.MetricValue], are
+// served by the first backend. The name of the backend lists the names of
+// backends, e.g. "multi[prometheus,statsd]".
+//
+// Panics if backends is empty.
+func NewMultiBackend(backends ...Backend) Backend {
+	if len(backends) == 0 {
+		panic("umami: multi backend requires at least one backend")
+	}
+
+	multi := &mirrorBackend{
+		primary: backends[0],
+		mirrors: backends[1:],
+	}
+	for _, backend := range backends {
+		multi.names = append(multi.names, backend.Name())
+	}
+	multi.name = "multi[" + strings.Join(multi.names, ",") + "]"
+	return multi
+}
+
+// Name returns the name of the primary backend, or of the multi backend, see
+// [NewMultiBackend]
+func (m *mirrorBackend) Name() string {
+	if m.name != "" {
+		return m.name
+	}
+	return m.primary.Name()
+}
+
+// backends returns the primary backend and its mirrors
+func (m *mirrorBackend) backends() []Backend {
+	return append([]Backend{m.primary}, m.mirrors...)
+}
+
+// GaugeFunc forwards the gauge to the primary backend and every mirror, see
+// [gaugeFuncBackend]
+func (m *mirrorBackend) GaugeFunc(opts GaugeOpts, fn func() float64) {
+	for _, backend := range m.backends() {
+		backend.(GaugeFuncBackend).GaugeFunc(opts, fn)
+	}
+}
+
+// CounterFunc forwards the counter to the primary backend and every mirror,
+// see [counterFuncBackend]
+func (m *mirrorBackend) CounterFunc(opts CounterOpts, fn func() float64) {
+	for _, backend := range m.backends() {
+		backend.(CounterFuncBackend).CounterFunc(opts, fn)
+	}
+}
+
+// Unregister unregisters the metric from every backend that is an
+// [UnregisterBackend], returning true if any of them had it
+func (m *mirrorBackend) Unregister(name string) bool {
+	removed := false
+	for _, backend := range m.backends() {
+		if unregisterer, ok := backend.(UnregisterBackend); ok {
+			removed = unregisterer.Unregister(name) || removed
+		}
+	}
+	return removed
+}
+
+// Gather gathers the metrics of the primary backend, which serves reads
+func (m *mirrorBackend) Gather() ([]MetricFamily, error) {
+	gatherer, ok := m.primary.(GatherBackend)
+	if !ok {
+		return nil, ErrGatherUnsupported
+	}
+	return gatherer.Gather()
+}
+
+// Supports returns true if the primary backend and every mirror support the
+// kind, see [CapabilityBackend]
+func (m *mirrorBackend) Supports(kind MetricKind) bool {
+	for _, backend := range m.backends() {
+		if !backendSupports(backend, kind) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *mirrorBackend) Counter(opts CounterOpts) CounterAdapter {
+	adapter := &mirrorCounterAdapter{primary: m.primary.Counter(opts), names: m.names}
+	for _, mirror := range m.mirrors {
+		adapter.mirrors = append(adapter.mirrors, mirror.Counter(opts))
+	}
+	return adapter
+}
+
+func (m *mirrorBackend) CounterVec(opts CounterVecOpts) CounterVecAdapter {
+	adapter := &mirrorCounterVecAdapter{primary: m.primary.CounterVec(opts), names: m.names}
+	for _, mirror := range m.mirrors {
+		adapter.mirrors = append(adapter.mirrors, mirror.CounterVec(opts))
+	}
+	return adapter
+}
+
+func (m *mirrorBackend) Gauge(opts GaugeOpts) GaugeAdapter {
+	adapter := &mirrorGaugeAdapter{primary: m.primary.Gauge(opts), names: m.names}
+	for _, mirror := range m.mirrors {
+		adapter.mirrors = append(adapter.mirrors, mirror.Gauge(opts))
+	}
+	return adapter
+}
+
+func (m *mirrorBackend) GaugeVec(opts GaugeVecOpts) GaugeVecAdapter {
+	adapter := &mirrorGaugeVecAdapter{primary: m.primary.GaugeVec(opts), names: m.names}
+	for _, mirror := range m.mirrors {
+		adapter.mirrors = append(adapter.mirrors, mirror.GaugeVec(opts))
+	}
+	return adapter
+}
+
+func (m *mirrorBackend) Histogram(opts HistogramOpts) HistogramAdapter {
+	adapter := &mirrorHistogramAdapter{primary: m.primary.Histogram(opts), names: m.names}
+	for _, mirror := range m.mirrors {
+		adapter.mirrors = append(adapter.mirrors, mirror.Histogram(opts))
+	}
+	return adapter
+}
+
+func (m *mirrorBackend) HistogramVec(opts HistogramVecOpts) HistogramVecAdapter {
+	adapter := &mirrorHistogramVecAdapter{primary: m.primary.HistogramVec(opts), names: m.names}
+	for _, mirror := range m.mirrors {
+		adapter.mirrors = append(adapter.mirrors, mirror.HistogramVec(opts))
+	}
+	return adapter
+}
+
+func (m *mirrorBackend) Summary(opts SummaryOpts) SummaryAdapter {
+	adapter := &mirrorSummaryAdapter{primary: m.primary.Summary(opts), names: m.names}
+	for _, mirror := range m.mirrors {
+		adapter.mirrors = append(adapter.mirrors, mirror.Summary(opts))
+	}
+	return adapter
+}
+
+func (m *mirrorBackend) SummaryVec(opts SummaryVecOpts) SummaryVecAdapter {
+	adapter := &mirrorSummaryVecAdapter{primary: m.primary.SummaryVec(opts), names: m.names}
+	for _, mirror := range m.mirrors {
+		adapter.mirrors = append(adapter.mirrors, mirror.SummaryVec(opts))
+	}
+	return adapter
+}
+
+//--------------------------------------------------------------------------------
+// Mirror Adapters
+//--------------------------------------------------------------------------------
+
+// fanOut applies op to primary and every mirror, joining all errors prefixed
+// with names, the names of the backends of the adapters
+func fanOut[A any](names []string, primary A, mirrors []A, op func(A) error) error {
+	var errs []error
+	for i, adapter := range append([]A{primary}, mirrors...) {
+		if err := op(adapter); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", names[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type mirrorCounterAdapter struct {
+	primary CounterAdapter
+	mirrors []CounterAdapter
+	names   []string
+}
+
+func (m *mirrorCounterAdapter) Inc() error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a CounterAdapter) error { return a.Inc() })
+}
+
+func (m *mirrorCounterAdapter) Add(value float64) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a CounterAdapter) error { return a.Add(value) })
+}
+
+func (m *mirrorCounterAdapter) AddWithExemplar(value float64, exemplar VecLabels) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a CounterAdapter) error { return addWithExemplar(a, value, exemplar) })
+}
+
+func (m *mirrorCounterAdapter) Value() (float64, error) {
+	return adapterValue(m.primary)
+}
+
+type mirrorCounterVecAdapter struct {
+	primary CounterVecAdapter
+	mirrors []CounterVecAdapter
+	names   []string
+}
+
+func (m *mirrorCounterVecAdapter) Inc(labels VecLabels) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a CounterVecAdapter) error { return a.Inc(labels) })
+}
+
+func (m *mirrorCounterVecAdapter) Add(value float64, labels VecLabels) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a CounterVecAdapter) error { return a.Add(value, labels) })
+}
+
+func (m *mirrorCounterVecAdapter) Series() ([]Sample, error) {
+	return adapterSeries(m.primary)
+}
+
+func (m *mirrorCounterVecAdapter) InitLabels(labels VecLabels) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a CounterVecAdapter) error {
+		return initVecSeries(a, labels, func(labels VecLabels) error { return a.Add(0, labels) })
+	})
+}
+
+// WithLabelValues binds the series on the primary and on every mirror, and
+// fails if any of them can't, so that values recorded through the bound
+// adapter are mirrored like those recorded through labels
+func (m *mirrorCounterVecAdapter) WithLabelValues(values ...string) (CounterAdapter, error) {
+	primary, err := adapterLabelValues(m.primary, values)
+	if err != nil {
+		return nil, err
+	}
+
+	adapter := &mirrorCounterAdapter{primary: primary, names: m.names}
+	for _, mirror := range m.mirrors {
+		bound, err := adapterLabelValues(mirror, values)
+		if err != nil {
+			return nil, err
+		}
+		adapter.mirrors = append(adapter.mirrors, bound)
+	}
+	return adapter, nil
+}
+
+// Delete removes the series from the primary and from every mirror, returning
+// true if it existed on the primary
+func (m *mirrorCounterVecAdapter) Delete(labels VecLabels) bool {
+	for _, mirror := range m.mirrors {
+		adapterDelete(mirror, labels)
+	}
+	return adapterDelete(m.primary, labels)
+}
+
+func (m *mirrorCounterVecAdapter) Reset() {
+	for _, mirror := range m.mirrors {
+		adapterReset(mirror)
+	}
+	adapterReset(m.primary)
+}
+
+type mirrorGaugeAdapter struct {
+	primary GaugeAdapter
+	mirrors []GaugeAdapter
+	names   []string
+}
+
+func (m *mirrorGaugeAdapter) Set(value float64) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a GaugeAdapter) error { return a.Set(value) })
+}
+
+func (m *mirrorGaugeAdapter) Inc() error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a GaugeAdapter) error { return a.Inc() })
+}
+
+func (m *mirrorGaugeAdapter) Dec() error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a GaugeAdapter) error { return a.Dec() })
+}
+
+func (m *mirrorGaugeAdapter) Add(value float64) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a GaugeAdapter) error { return a.Add(value) })
+}
+
+func (m *mirrorGaugeAdapter) Value() (float64, error) {
+	return adapterValue(m.primary)
+}
+
+type mirrorGaugeVecAdapter struct {
+	primary GaugeVecAdapter
+	mirrors []GaugeVecAdapter
+	names   []string
+}
+
+func (m *mirrorGaugeVecAdapter) Set(value float64, labels VecLabels) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a GaugeVecAdapter) error { return a.Set(value, labels) })
+}
+
+func (m *mirrorGaugeVecAdapter) Inc(labels VecLabels) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a GaugeVecAdapter) error { return a.Inc(labels) })
+}
+
+func (m *mirrorGaugeVecAdapter) Dec(labels VecLabels) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a GaugeVecAdapter) error { return a.Dec(labels) })
+}
+
+func (m *mirrorGaugeVecAdapter) Add(value float64, labels VecLabels) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a GaugeVecAdapter) error { return a.Add(value, labels) })
+}
+
+func (m *mirrorGaugeVecAdapter) InitLabels(labels VecLabels) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a GaugeVecAdapter) error {
+		return initVecSeries(a, labels, func(labels VecLabels) error { return a.Add(0, labels) })
+	})
+}
+
+func (m *mirrorGaugeVecAdapter) Delete(labels VecLabels) bool {
+	for _, mirror := range m.mirrors {
+		adapterDelete(mirror, labels)
+	}
+	return adapterDelete(m.primary, labels)
+}
+
+func (m *mirrorGaugeVecAdapter) Reset() {
+	for _, mirror := range m.mirrors {
+		adapterReset(mirror)
+	}
+	adapterReset(m.primary)
+}
+
+type mirrorHistogramAdapter struct {
+	primary HistogramAdapter
+	mirrors []HistogramAdapter
+	names   []string
+}
+
+func (m *mirrorHistogramAdapter) Observe(value float64) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a HistogramAdapter) error { return a.Observe(value) })
+}
+
+func (m *mirrorHistogramAdapter) ObserveWithExemplar(value float64, exemplar VecLabels) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a HistogramAdapter) error { return observeWithExemplar(a, value, exemplar) })
+}
+
+type mirrorHistogramVecAdapter struct {
+	primary HistogramVecAdapter
+	mirrors []HistogramVecAdapter
+	names   []string
+}
+
+func (m *mirrorHistogramVecAdapter) Observe(value float64, labels VecLabels) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a HistogramVecAdapter) error { return a.Observe(value, labels) })
+}
+
+func (m *mirrorHistogramVecAdapter) InitLabels(labels VecLabels) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a HistogramVecAdapter) error { return initVecSeries(a, labels, nil) })
+}
+
+func (m *mirrorHistogramVecAdapter) Delete(labels VecLabels) bool {
+	for _, mirror := range m.mirrors {
+		adapterDelete(mirror, labels)
+	}
+	return adapterDelete(m.primary, labels)
+}
+
+func (m *mirrorHistogramVecAdapter) Reset() {
+	for _, mirror := range m.mirrors {
+		adapterReset(mirror)
+	}
+	adapterReset(m.primary)
+}
+
+type mirrorSummaryAdapter struct {
+	primary SummaryAdapter
+	mirrors []SummaryAdapter
+	names   []string
+}
+
+func (m *mirrorSummaryAdapter) Observe(value float64) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a SummaryAdapter) error { return a.Observe(value) })
+}
+
+func (m *mirrorSummaryAdapter) Quantile(q float64) (float64, error) {
+	return m.primary.Quantile(q)
+}
+
+type mirrorSummaryVecAdapter struct {
+	primary SummaryVecAdapter
+	mirrors []SummaryVecAdapter
+	names   []string
+}
+
+func (m *mirrorSummaryVecAdapter) Observe(value float64, labels VecLabels) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a SummaryVecAdapter) error { return a.Observe(value, labels) })
+}
+
+func (m *mirrorSummaryVecAdapter) Quantile(q float64, labels VecLabels) (float64, error) {
+	return m.primary.Quantile(q, labels)
+}
+
+func (m *mirrorSummaryVecAdapter) InitLabels(labels VecLabels) error {
+	return fanOut(m.names, m.primary, m.mirrors, func(a SummaryVecAdapter) error { return initVecSeries(a, labels, nil) })
+}
+
+func (m *mirrorSummaryVecAdapter) Delete(labels VecLabels) bool {
+	for _, mirror := range m.mirrors {
+		adapterDelete(mirror, labels)
+	}
+	return adapterDelete(m.primary, labels)
+}
+
+func (m *mirrorSummaryVecAdapter) Reset() {
+	for _, mirror := range m.mirrors {
+		adapterReset(mirror)
+	}
+	adapterReset(m.primary)
+}
+
+var (
+	__ctc_mirrorBackend            Backend            = (*mirrorBackend)(nil)
+	__ctc_mirrorUnregisterBackend  UnregisterBackend  = (*mirrorBackend)(nil)
+	__ctc_mirrorGatherBackend      GatherBackend      = (*mirrorBackend)(nil)
+	__ctc_mirrorCapabilityBackend  CapabilityBackend  = (*mirrorBackend)(nil)
+	__ctc_mirrorGaugeFuncBackend   GaugeFuncBackend   = (*mirrorBackend)(nil)
+	__ctc_mirrorCounterFuncBackend CounterFuncBackend = (*mirrorBackend)(nil)
+
+	__ctc_mirrorCounterAdapter      CounterAdapter      = (*mirrorCounterAdapter)(nil)
+	__ctc_mirrorCounterVecAdapter   CounterVecAdapter   = (*mirrorCounterVecAdapter)(nil)
+	__ctc_mirrorGaugeAdapter        GaugeAdapter        = (*mirrorGaugeAdapter)(nil)
+	__ctc_mirrorGaugeVecAdapter     GaugeVecAdapter     = (*mirrorGaugeVecAdapter)(nil)
+	__ctc_mirrorHistogramAdapter    HistogramAdapter    = (*mirrorHistogramAdapter)(nil)
+	__ctc_mirrorHistogramVecAdapter HistogramVecAdapter = (*mirrorHistogramVecAdapter)(nil)
+	__ctc_mirrorSummaryAdapter      SummaryAdapter      = (*mirrorSummaryAdapter)(nil)
+	__ctc_mirrorSummaryVecAdapter   SummaryVecAdapter   = (*mirrorSummaryVecAdapter)(nil)
+
+	__ctc_mirrorCounterVecInitAdapter   VecInitAdapter = (*mirrorCounterVecAdapter)(nil)
+	__ctc_mirrorGaugeVecInitAdapter     VecInitAdapter = (*mirrorGaugeVecAdapter)(nil)
+	__ctc_mirrorHistogramVecInitAdapter VecInitAdapter = (*mirrorHistogramVecAdapter)(nil)
+	__ctc_mirrorSummaryVecInitAdapter   VecInitAdapter = (*mirrorSummaryVecAdapter)(nil)
+
+	__ctc_mirrorCounterValueAdapter ValueAdapter             = (*mirrorCounterAdapter)(nil)
+	__ctc_mirrorGaugeValueAdapter   ValueAdapter             = (*mirrorGaugeAdapter)(nil)
+	__ctc_mirrorCounterVecSeries    SeriesAdapter            = (*mirrorCounterVecAdapter)(nil)
+	__ctc_mirrorCounterVecValues    LabelValuesAdapter       = (*mirrorCounterVecAdapter)(nil)
+	__ctc_mirrorCounterVecDelete    DeleteAdapter            = (*mirrorCounterVecAdapter)(nil)
+	__ctc_mirrorGaugeVecDelete      DeleteAdapter            = (*mirrorGaugeVecAdapter)(nil)
+	__ctc_mirrorHistogramVecDelete  DeleteAdapter            = (*mirrorHistogramVecAdapter)(nil)
+	__ctc_mirrorSummaryVecDelete    DeleteAdapter            = (*mirrorSummaryVecAdapter)(nil)
+	__ctc_mirrorCounterExemplar     ExemplarCounterAdapter   = (*mirrorCounterAdapter)(nil)
+	__ctc_mirrorHistogramExemplar   ExemplarHistogramAdapter = (*mirrorHistogramAdapter)(nil)
+)
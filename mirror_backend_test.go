@@ -0,0 +1,107 @@
+package umami
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// namedBackend is a [Backend] with a custom name
+type namedBackend struct {
+	Backend
+	name string
+}
+
+func (n namedBackend) Name() string {
+	return n.name
+}
+
+func TestMultiBackend(t *testing.T) {
+	prom := NewMockBackend().(*mockBackend)
+	statsd := NewMockBackend().(*mockBackend)
+	multi := NewMultiBackend(namedBackend{prom, "prometheus"}, namedBackend{statsd, "statsd"})
+
+	if got, want := multi.Name(), "multi[prometheus,statsd]"; got != want {
+		t.Errorf("Expected the name %q, got %q", want, got)
+	}
+
+	group := NewRegistry(LevelDebug).NewGroup("web", multi)
+	ctx := group.Context()
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total"}}, LevelImportant)
+	vec := group.GaugeVec(GaugeVecOpts{MetricInfo: MetricInfo{Name: "in_flight"}, Labels: []string{"route"}}, LevelImportant)
+	for range 2 {
+		_ = counter.Inc(ctx)
+	}
+	_ = vec.Set(ctx, 3, VecLabels{"route": "/a"})
+	_ = vec.Dec(ctx, VecLabels{"route": "/a"})
+
+	for _, backend := range []*mockBackend{prom, statsd} {
+		if got := backend.adapter("web_requests_total").(*mockCounterAdapter).GetCount(); got != 2 {
+			t.Errorf("Expected every backend to count 2, got %v", got)
+		}
+		if got := backend.adapter("web_in_flight").(*mockGaugeVecAdapter).GetValue(VecLabels{"route": "/a"}); got != 2 {
+			t.Errorf("Expected every backend to set 2, got %v", got)
+		}
+	}
+
+	// Reads are served by the first backend
+	if value, err := counter.Value(ctx); err != nil || value != 2 {
+		t.Errorf("Expected to read 2, got %v (%v)", value, err)
+	}
+}
+
+func TestMirroredGroupFuncs(t *testing.T) {
+	primary := NewMockBackend().(*mockBackend)
+	mirror := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug).NewMirroredGroup("web", primary, []Backend{mirror})
+
+	group.GaugeFunc(GaugeOpts{MetricInfo: MetricInfo{Name: "sessions"}}, func() float64 { return 3 }, LevelImportant)
+	group.CounterFunc(CounterOpts{MetricInfo: MetricInfo{Name: "ops_total"}}, func() float64 { return 5 }, LevelImportant)
+
+	// The funcs are read by every backend
+	for _, backend := range []*mockBackend{primary, mirror} {
+		gauge, ok := backend.adapter("web_sessions").(*mockGaugeFuncAdapter)
+		if !ok || gauge.GetValue() != 3 {
+			t.Errorf("Expected every backend to read the gauge func, got %v", backend.adapter("web_sessions"))
+		}
+		counter, ok := backend.adapter("web_ops_total").(*mockCounterFuncAdapter)
+		if !ok || counter.GetCount() != 5 {
+			t.Errorf("Expected every backend to read the counter func, got %v", backend.adapter("web_ops_total"))
+		}
+	}
+
+	// Funcs are sampled unless every backend reads them
+	multi := NewMultiBackend(primary, struct{ Backend }{mirror})
+	if _, ok := gaugeFuncBackend(multi); ok {
+		t.Error("Expected a multi backend with a backend without gauge funcs not to read them")
+	}
+	if _, ok := counterFuncBackend(multi); ok {
+		t.Error("Expected a multi backend with a backend without counter funcs not to read them")
+	}
+}
+
+func TestMultiBackendFailure(t *testing.T) {
+	healthy := NewMockBackend().(*mockBackend)
+	multi := NewMultiBackend(namedBackend{erroringBackend{NewMockBackend()}, "statsd"}, namedBackend{healthy, "prometheus"})
+
+	counter := multi.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total"}})
+	err := counter.Inc()
+	if !errors.Is(err, errBackendDown) {
+		t.Fatalf("Expected the backend error, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "statsd: ") {
+		t.Errorf("Expected the error to identify the failing backend, got %q", err)
+	}
+
+	// The failure doesn't prevent the other backends from recording
+	if got := healthy.adapter("requests_total").(*mockCounterAdapter).GetCount(); got != 1 {
+		t.Errorf("Expected the healthy backend to count 1, got %v", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a multi backend without backends to panic")
+		}
+	}()
+	NewMultiBackend()
+}
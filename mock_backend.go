@@ -2,18 +2,26 @@ package umami
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
+	"sync"
 )
 
 // mockBackend implements Backend interface for testing
 type mockBackend struct {
 	name string
+
+	mu          sync.Mutex
+	adapters    map[string]any               // Map of qualified metric name to the last adapter created for it
+	constLabels map[string]map[string]string // Map of qualified metric name to its const labels, if any
 }
 
 // NewMockBackend creates a new mock backend for testing
 func NewMockBackend() Backend {
 	return &mockBackend{
-		name: "mock",
+		name:     "mock",
+		adapters: make(map[string]any),
 	}
 }
 
@@ -22,66 +30,230 @@ func (m *mockBackend) AsMock() *mockBackend {
 	return m
 }
 
+// adapter returns the last adapter created for the given qualified metric
+// name, or nil
+func (m *mockBackend) adapter(name string) any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.adapters[name]
+}
+
+// remember records the adapter created for the metric of info, by its
+// qualified name, along with its const labels
+func (m *mockBackend) remember(info MetricInfo, adapter any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.adapters == nil {
+		m.adapters = make(map[string]any)
+	}
+	if m.constLabels == nil {
+		m.constLabels = make(map[string]map[string]string)
+	}
+	name := info.QualifiedName()
+	m.adapters[name] = adapter
+	m.constLabels[name] = info.ConstLabels
+}
+
+// Unregister forgets the adapter created for the given qualified metric name
+func (m *mockBackend) Unregister(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, exists := m.adapters[name]
+	delete(m.adapters, name)
+	delete(m.constLabels, name)
+	return exists
+}
+
+// Gather returns the values recorded by the last adapter created for each
+// metric, labeled with its const labels
+func (m *mockBackend) Gather() ([]MetricFamily, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	families := make([]MetricFamily, 0, len(m.adapters))
+	for name, adapter := range m.adapters {
+		family := MetricFamily{Name: name}
+		switch a := adapter.(type) {
+		case *mockCounterAdapter:
+			family.Kind = MetricKindCounter
+			family.Samples = []Sample{{Value: a.count}}
+			if a.resetOnScrape {
+				a.count = 0
+			}
+		case *mockCounterFuncAdapter:
+			family.Kind = MetricKindCounter
+			family.Samples = []Sample{{Value: a.GetCount()}}
+		case *mockCounterVecAdapter:
+			family.Kind = MetricKindCounter
+			for key, count := range a.counts {
+				family.Samples = append(family.Samples, Sample{Labels: mockKeyToLabels(key), Value: count})
+			}
+		case *mockGaugeAdapter:
+			family.Kind = MetricKindGauge
+			family.Samples = []Sample{{Value: a.value}}
+		case *mockGaugeFuncAdapter:
+			family.Kind = MetricKindGauge
+			family.Samples = []Sample{{Value: a.GetValue()}}
+		case *mockGaugeVecAdapter:
+			family.Kind = MetricKindGauge
+			for key, value := range a.values {
+				family.Samples = append(family.Samples, Sample{Labels: mockKeyToLabels(key), Value: value})
+			}
+		case *mockHistogramAdapter:
+			family.Kind = MetricKindHistogram
+			family.Samples = []Sample{mockObservationsSample("", a.observations)}
+		case *mockHistogramVecAdapter:
+			family.Kind = MetricKindHistogram
+			for key, observations := range a.observations {
+				family.Samples = append(family.Samples, mockObservationsSample(key, observations))
+			}
+		case *mockSummaryAdapter:
+			family.Kind = MetricKindSummary
+			family.Samples = []Sample{mockObservationsSample("", a.observations)}
+		case *mockSummaryVecAdapter:
+			family.Kind = MetricKindSummary
+			for key, observations := range a.observations {
+				family.Samples = append(family.Samples, mockObservationsSample(key, observations))
+			}
+		}
+		family.Samples = mockConstLabels(family.Samples, m.constLabels[name])
+		families = append(families, family)
+	}
+
+	slices.SortFunc(families, func(a, b MetricFamily) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return families, nil
+}
+
+// mockConstLabels adds the const labels of a metric to the labels of its samples
+func mockConstLabels(samples []Sample, constLabels map[string]string) []Sample {
+	if len(constLabels) == 0 {
+		return samples
+	}
+	for i := range samples {
+		labels := maps.Clone(constLabels)
+		maps.Copy(labels, samples[i].Labels)
+		samples[i].Labels = labels
+	}
+	return samples
+}
+
+// mockKeyToLabels parses the labels of a mock vec adapter key
+func mockKeyToLabels(key string) VecLabels {
+	labels := make(VecLabels)
+	for _, part := range strings.Split(key, ",") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+// mockObservationsSample returns the sample of observations recorded for a key
+func mockObservationsSample(key string, observations []float64) Sample {
+	sample := Sample{Count: uint64(len(observations))}
+	if key != "" {
+		sample.Labels = mockKeyToLabels(key)
+	}
+	for _, observation := range observations {
+		sample.Sum += observation
+	}
+	return sample
+}
+
 func (m *mockBackend) Name() string {
 	return m.name
 }
 
 func (m *mockBackend) Counter(opts CounterOpts) CounterAdapter {
-	return &mockCounterAdapter{
-		name: opts.Name,
+	adapter := &mockCounterAdapter{
+		name:          opts.QualifiedName(),
+		resetOnScrape: opts.ResetOnScrape,
 	}
+	m.remember(opts.MetricInfo, adapter)
+	return adapter
 }
 
 func (m *mockBackend) CounterVec(opts CounterVecOpts) CounterVecAdapter {
-	return &mockCounterVecAdapter{
-		name:   opts.Name,
+	adapter := &mockCounterVecAdapter{
+		name:   opts.QualifiedName(),
 		counts: make(map[string]float64),
 	}
+	m.remember(opts.MetricInfo, adapter)
+	return adapter
 }
 
 func (m *mockBackend) Gauge(opts GaugeOpts) GaugeAdapter {
-	return &mockGaugeAdapter{
-		name: opts.Name,
+	adapter := &mockGaugeAdapter{
+		name: opts.QualifiedName(),
 	}
+	m.remember(opts.MetricInfo, adapter)
+	return adapter
 }
 
 func (m *mockBackend) GaugeVec(opts GaugeVecOpts) GaugeVecAdapter {
-	return &mockGaugeVecAdapter{
-		name:   opts.Name,
+	adapter := &mockGaugeVecAdapter{
+		name:   opts.QualifiedName(),
 		values: make(map[string]float64),
 	}
+	m.remember(opts.MetricInfo, adapter)
+	return adapter
+}
+
+func (m *mockBackend) CounterFunc(opts CounterOpts, fn func() float64) {
+	m.remember(opts.MetricInfo, &mockCounterFuncAdapter{
+		name: opts.QualifiedName(),
+		fn:   fn,
+	})
+}
+
+func (m *mockBackend) GaugeFunc(opts GaugeOpts, fn func() float64) {
+	m.remember(opts.MetricInfo, &mockGaugeFuncAdapter{
+		name: opts.QualifiedName(),
+		fn:   fn,
+	})
 }
 
 func (m *mockBackend) Histogram(opts HistogramOpts) HistogramAdapter {
-	return &mockHistogramAdapter{
-		name: opts.Name,
+	adapter := &mockHistogramAdapter{
+		name: opts.QualifiedName(),
 	}
+	m.remember(opts.MetricInfo, adapter)
+	return adapter
 }
 
 func (m *mockBackend) HistogramVec(opts HistogramVecOpts) HistogramVecAdapter {
-	return &mockHistogramVecAdapter{
-		name:         opts.Name,
+	adapter := &mockHistogramVecAdapter{
+		name:         opts.QualifiedName(),
 		observations: make(map[string][]float64),
 	}
+	m.remember(opts.MetricInfo, adapter)
+	return adapter
 }
 
 func (m *mockBackend) Summary(opts SummaryOpts) SummaryAdapter {
-	return &mockSummaryAdapter{
-		name: opts.Name,
+	adapter := &mockSummaryAdapter{
+		name: opts.QualifiedName(),
 	}
+	m.remember(opts.MetricInfo, adapter)
+	return adapter
 }
 
-func (m *mockBackend) SummaryVec(opts SummaryVecOpts) SummaryVecAdapater {
-	return &mockSummaryVecAdapter{
-		name:         opts.Name,
+func (m *mockBackend) SummaryVec(opts SummaryVecOpts) SummaryVecAdapter {
+	adapter := &mockSummaryVecAdapter{
+		name:         opts.QualifiedName(),
 		observations: make(map[string][]float64),
 	}
+	m.remember(opts.MetricInfo, adapter)
+	return adapter
 }
 
 // Counter adapter
 type mockCounterAdapter struct {
-	name  string
-	count float64
+	name          string
+	count         float64
+	resetOnScrape bool // If set, Gather zeros the count
 }
 
 func (m *mockCounterAdapter) Inc() error {
@@ -98,6 +270,10 @@ func (m *mockCounterAdapter) GetCount() float64 {
 	return m.count
 }
 
+func (m *mockCounterAdapter) Value() (float64, error) {
+	return m.count, nil
+}
+
 // CounterVec adapter
 type mockCounterVecAdapter struct {
 	name   string
@@ -121,14 +297,36 @@ func (m *mockCounterVecAdapter) GetCount(labels VecLabels) float64 {
 	return m.counts[key]
 }
 
+// Series returns the count of every series, see [SeriesAdapter]
+func (m *mockCounterVecAdapter) Series() ([]Sample, error) {
+	series := make([]Sample, 0, len(m.counts))
+	for _, key := range slices.Sorted(maps.Keys(m.counts)) {
+		series = append(series, Sample{Labels: mockKeyToLabels(key), Value: m.counts[key]})
+	}
+	return series, nil
+}
+
 func (m *mockCounterVecAdapter) labelsToKey(labels VecLabels) string {
 	var parts []string
 	for k, v := range labels {
 		parts = append(parts, fmt.Sprintf("%s=%s", k, v))
 	}
+	slices.Sort(parts)
 	return strings.Join(parts, ",")
 }
 
+// Delete removes the series of the labels, see [DeleteAdapter]
+func (m *mockCounterVecAdapter) Delete(labels VecLabels) bool {
+	key := m.labelsToKey(labels)
+	_, exists := m.counts[key]
+	delete(m.counts, key)
+	return exists
+}
+
+func (m *mockCounterVecAdapter) Reset() {
+	clear(m.counts)
+}
+
 // Gauge adapter
 type mockGaugeAdapter struct {
 	name  string
@@ -159,6 +357,30 @@ func (m *mockGaugeAdapter) GetValue() float64 {
 	return m.value
 }
 
+func (m *mockGaugeAdapter) Value() (float64, error) {
+	return m.value, nil
+}
+
+// CounterFunc adapter, read at collection time
+type mockCounterFuncAdapter struct {
+	name string
+	fn   func() float64
+}
+
+func (m *mockCounterFuncAdapter) GetCount() float64 {
+	return m.fn()
+}
+
+// GaugeFunc adapter, read at collection time
+type mockGaugeFuncAdapter struct {
+	name string
+	fn   func() float64
+}
+
+func (m *mockGaugeFuncAdapter) GetValue() float64 {
+	return m.fn()
+}
+
 // GaugeVec adapter
 type mockGaugeVecAdapter struct {
 	name   string
@@ -199,9 +421,21 @@ func (m *mockGaugeVecAdapter) labelsToKey(labels VecLabels) string {
 	for k, v := range labels {
 		parts = append(parts, fmt.Sprintf("%s=%s", k, v))
 	}
+	slices.Sort(parts)
 	return strings.Join(parts, ",")
 }
 
+func (m *mockGaugeVecAdapter) Delete(labels VecLabels) bool {
+	key := m.labelsToKey(labels)
+	_, exists := m.values[key]
+	delete(m.values, key)
+	return exists
+}
+
+func (m *mockGaugeVecAdapter) Reset() {
+	clear(m.values)
+}
+
 // Histogram adapter
 type mockHistogramAdapter struct {
 	name         string
@@ -236,6 +470,15 @@ func (m *mockHistogramVecAdapter) Observe(value float64, labels VecLabels) error
 	return nil
 }
 
+// InitLabels creates an empty series of observations for the labels
+func (m *mockHistogramVecAdapter) InitLabels(labels VecLabels) error {
+	key := m.labelsToKey(labels)
+	if m.observations[key] == nil {
+		m.observations[key] = make([]float64, 0)
+	}
+	return nil
+}
+
 func (m *mockHistogramVecAdapter) GetObservations(labels VecLabels) []float64 {
 	key := m.labelsToKey(labels)
 	return m.observations[key]
@@ -251,9 +494,21 @@ func (m *mockHistogramVecAdapter) labelsToKey(labels VecLabels) string {
 	for k, v := range labels {
 		parts = append(parts, fmt.Sprintf("%s=%s", k, v))
 	}
+	slices.Sort(parts)
 	return strings.Join(parts, ",")
 }
 
+func (m *mockHistogramVecAdapter) Delete(labels VecLabels) bool {
+	key := m.labelsToKey(labels)
+	_, exists := m.observations[key]
+	delete(m.observations, key)
+	return exists
+}
+
+func (m *mockHistogramVecAdapter) Reset() {
+	clear(m.observations)
+}
+
 // Summary adapter
 type mockSummaryAdapter struct {
 	name         string
@@ -310,6 +565,15 @@ func (m *mockSummaryVecAdapter) Quantile(q float64, labels VecLabels) (float64,
 	return obs[index], nil
 }
 
+// InitLabels creates an empty series of observations for the labels
+func (m *mockSummaryVecAdapter) InitLabels(labels VecLabels) error {
+	key := m.labelsToKey(labels)
+	if m.observations[key] == nil {
+		m.observations[key] = make([]float64, 0)
+	}
+	return nil
+}
+
 func (m *mockSummaryVecAdapter) GetObservations(labels VecLabels) []float64 {
 	key := m.labelsToKey(labels)
 	return m.observations[key]
@@ -320,5 +584,17 @@ func (m *mockSummaryVecAdapter) labelsToKey(labels VecLabels) string {
 	for k, v := range labels {
 		parts = append(parts, fmt.Sprintf("%s=%s", k, v))
 	}
+	slices.Sort(parts)
 	return strings.Join(parts, ",")
 }
+
+func (m *mockSummaryVecAdapter) Delete(labels VecLabels) bool {
+	key := m.labelsToKey(labels)
+	_, exists := m.observations[key]
+	delete(m.observations, key)
+	return exists
+}
+
+func (m *mockSummaryVecAdapter) Reset() {
+	clear(m.observations)
+}
@@ -19,6 +19,11 @@ package umami
 //    CompositeMetric, which has a Components() method to return any underlying prime
 //    metrics. This is used to construct the composite metric's actual
 //    implementation.
+//
+// Noop composites are constructed from the opts normalized by the group's
+// factory, e.g. [group.GetOrCreateTimer], which names their components and
+// flags them FromComposite. They take the component opts as given, so that a
+// noop composite and its real counterpart have the same components.
 //--------------------------------------------------------------------------------
 
 // noopFunc is an empty function that is used by metric operations that return closures,
@@ -50,6 +55,18 @@ func (n *noopCounter) Add(ctx Context, value float64) error {
 	return nil
 }
 
+func (n *noopCounter) IncWithExemplar(ctx Context, exemplar VecLabels) error {
+	return nil
+}
+
+func (n *noopCounter) AddWithExemplar(ctx Context, value float64, exemplar VecLabels) error {
+	return nil
+}
+
+func (n *noopCounter) Value(ctx Context) (float64, error) {
+	return 0, nil
+}
+
 func (n *noopCounter) constructorOpts() any {
 	return n.copts
 }
@@ -79,10 +96,78 @@ func (n *noopCounterVec) Add(ctx Context, value float64, labels VecLabels) error
 	return nil
 }
 
+func (n *noopCounterVec) Collect(ctx Context) ([]Sample, error) {
+	return nil, nil
+}
+
+func (n *noopCounterVec) WithLabelValues(ctx Context, values ...string) Counter {
+	return newNoopCounter(CounterOpts{BasicMetricOpts: n.copts.BasicMetricOpts, MetricInfo: n.copts.MetricInfo}, n.level)
+}
+
 func (n *noopCounterVec) constructorOpts() any {
 	return n.copts
 }
 
+func (n *noopCounterVec) Delete(ctx Context, labels VecLabels) bool {
+	return false
+}
+
+func (n *noopCounterVec) Reset(ctx Context) {}
+
+// noopCounterGroup implements [CounterGroup] interface with no-op operations
+type noopCounterGroup struct {
+	baseMetric
+	copts counterGroupOpts
+}
+
+func newNoopCounterGroup(opts counterGroupOpts, level Level) *noopCounterGroup {
+	return &noopCounterGroup{
+		baseMetric: baseMetric{
+			name:  opts.Name,
+			help:  opts.Help,
+			level: level,
+		},
+		copts: opts,
+	}
+}
+
+func (n *noopCounterGroup) Value(ctx Context) (float64, error) {
+	return 0, nil
+}
+
+func (n *noopCounterGroup) Members() []Counter {
+	return n.copts.members
+}
+
+func (n *noopCounterGroup) constructorOpts() any {
+	return n.copts
+}
+
+// noopCounterFunc implements [CounterFunc] interface with no-op operations
+type noopCounterFunc struct {
+	baseMetric
+	copts counterFuncOpts
+}
+
+func newNoopCounterFunc(opts counterFuncOpts, level Level) *noopCounterFunc {
+	return &noopCounterFunc{
+		baseMetric: baseMetric{
+			name:  opts.Name,
+			help:  opts.Help,
+			level: level,
+		},
+		copts: opts,
+	}
+}
+
+func (n *noopCounterFunc) Value(ctx Context) (float64, error) {
+	return 0, nil
+}
+
+func (n *noopCounterFunc) constructorOpts() any {
+	return n.copts
+}
+
 // noopGauge implements [Gauge] interface with no-op operations
 type noopGauge struct {
 	baseMetric
@@ -116,10 +201,39 @@ func (n *noopGauge) Add(ctx Context, value float64) error {
 	return nil
 }
 
+func (n *noopGauge) Value(ctx Context) (float64, error) {
+	return 0, nil
+}
+
 func (n *noopGauge) constructorOpts() any {
 	return n.copts
 }
 
+// noopGaugeFunc implements [GaugeFunc] interface with no-op operations
+type noopGaugeFunc struct {
+	baseMetric
+	copts gaugeFuncOpts
+}
+
+func newNoopGaugeFunc(opts gaugeFuncOpts, level Level) *noopGaugeFunc {
+	return &noopGaugeFunc{
+		baseMetric: baseMetric{
+			name:  opts.Name,
+			help:  opts.Help,
+			level: level,
+		},
+		copts: opts,
+	}
+}
+
+func (n *noopGaugeFunc) Value(ctx Context) (float64, error) {
+	return 0, nil
+}
+
+func (n *noopGaugeFunc) constructorOpts() any {
+	return n.copts
+}
+
 // noopGaugeVec implements [GaugeVec] interface with no-op operations
 type noopGaugeVec struct {
 	baseMetric
@@ -157,6 +271,12 @@ func (n *noopGaugeVec) constructorOpts() any {
 	return n.copts
 }
 
+func (n *noopGaugeVec) Delete(ctx Context, labels VecLabels) bool {
+	return false
+}
+
+func (n *noopGaugeVec) Reset(ctx Context) {}
+
 // noopHistogram implements [Histogram] interface with no-op operations
 type noopHistogram struct {
 	baseMetric
@@ -178,6 +298,18 @@ func (n *noopHistogram) Observe(ctx Context, value float64) error {
 	return nil
 }
 
+func (n *noopHistogram) ObserveWithExemplar(ctx Context, value float64, exemplar VecLabels) error {
+	return nil
+}
+
+func (n *noopHistogram) ObserveBytes(ctx Context, bytes int64) error {
+	return nil
+}
+
+func (n *noopHistogram) Time(ctx Context, fn func() error) error {
+	return fn()
+}
+
 func (n *noopHistogram) constructorOpts() any {
 	return n.copts
 }
@@ -203,10 +335,20 @@ func (n *noopHistogramVec) Observe(ctx Context, value float64, labels VecLabels)
 	return nil
 }
 
+func (n *noopHistogramVec) Time(ctx Context, fn func() error, labels VecLabels) error {
+	return fn()
+}
+
 func (n *noopHistogramVec) constructorOpts() any {
 	return n.copts
 }
 
+func (n *noopHistogramVec) Delete(ctx Context, labels VecLabels) bool {
+	return false
+}
+
+func (n *noopHistogramVec) Reset(ctx Context) {}
+
 // noopSummary implements [Summary] interface with no-op operations
 type noopSummary struct {
 	baseMetric
@@ -232,6 +374,11 @@ func (n *noopSummary) Quantile(ctx Context, q float64) (float64, error) {
 	return 0, nil
 }
 
+// Reconfigure is only supported through the switchable wrapper created by a [Group]
+func (n *noopSummary) Reconfigure(opts SummaryOpts) error {
+	return ErrNotReconfigurable
+}
+
 func (n *noopSummary) constructorOpts() any {
 	return n.copts
 }
@@ -273,9 +420,6 @@ func (n *noopSummaryVec) constructorOpts() any {
 // }
 
 func newNoopTimer(opts TimerOpts, level Level) Timer {
-	opts.HistogramOpts.FromComposite = true
-	opts.HistogramOpts.Name = opts.Name + "_histogram"
-
 	base := baseMetric{
 		name:  opts.Name,
 		help:  opts.Help,
@@ -285,6 +429,7 @@ func newNoopTimer(opts TimerOpts, level Level) Timer {
 	return &baseTimer{
 		baseCompositeMetric: baseCompositeMetric{base},
 		histogram:           newNoopHistogram(opts.HistogramOpts, level),
+		clock:               clockOrDefault(opts.Clock),
 	}
 }
 
@@ -319,9 +464,6 @@ func newNoopTimer(opts TimerOpts, level Level) Timer {
 // }
 
 func newNoopTimerVec(opts TimerVecOpts, level Level) TimerVec {
-	opts.HistogramVecOpts.FromComposite = true
-	opts.HistogramVecOpts.Name = opts.Name + "_histogram"
-
 	base := baseMetric{
 		name:  opts.Name,
 		help:  opts.Help,
@@ -331,6 +473,7 @@ func newNoopTimerVec(opts TimerVecOpts, level Level) TimerVec {
 	return &baseTimerVec{
 		baseCompositeMetric: baseCompositeMetric{base},
 		histogramVec:        newNoopHistogramVec(opts.HistogramVecOpts, level),
+		clock:               clockOrDefault(opts.Clock),
 	}
 }
 
@@ -367,13 +510,6 @@ func newNoopTimerVec(opts TimerVecOpts, level Level) TimerVec {
 // }
 
 func newNoopCache(opts CacheOpts, level Level) Cache {
-	opts.HitOpts.FromComposite = true
-	opts.HitOpts.Name = opts.Name + "_hit"
-	opts.MissOpts.FromComposite = true
-	opts.MissOpts.Name = opts.Name + "_miss"
-	opts.SizeOpts.FromComposite = true
-	opts.SizeOpts.Name = opts.Name + "_size"
-
 	base := baseMetric{
 		name:  opts.Name,
 		help:  opts.Help,
@@ -427,13 +563,6 @@ func newNoopCache(opts CacheOpts, level Level) Cache {
 // }
 
 func newNoopCacheVec(opts CacheVecOpts, level Level) CacheVec {
-	opts.HitVecOpts.FromComposite = true
-	opts.HitVecOpts.Name = opts.Name + "_hit"
-	opts.MissVecOpts.FromComposite = true
-	opts.MissVecOpts.Name = opts.Name + "_miss"
-	opts.SizeVecOpts.FromComposite = true
-	opts.SizeVecOpts.Name = opts.Name + "_size"
-
 	base := baseMetric{
 		name:  opts.Name,
 		help:  opts.Help,
@@ -489,15 +618,6 @@ func newNoopCacheVec(opts CacheVecOpts, level Level) CacheVec {
 // }
 
 func newNoopPool(opts PoolOpts, level Level) Pool {
-	opts.ActiveOpts.FromComposite = true
-	opts.ActiveOpts.Name = opts.Name + "_active"
-	opts.IdleOpts.FromComposite = true
-	opts.IdleOpts.Name = opts.Name + "_idle"
-	opts.AcquiredOpts.FromComposite = true
-	opts.AcquiredOpts.Name = opts.Name + "_acquired"
-	opts.ReleasedOpts.FromComposite = true
-	opts.ReleasedOpts.Name = opts.Name + "_released"
-
 	base := baseMetric{
 		name:  opts.Name,
 		help:  opts.Help,
@@ -562,15 +682,6 @@ func newNoopPool(opts PoolOpts, level Level) Pool {
 // }
 
 func newNoopPoolVec(opts PoolVecOpts, level Level) PoolVec {
-	opts.ActiveVecOpts.FromComposite = true
-	opts.ActiveVecOpts.Name = opts.Name + "_active"
-	opts.IdleVecOpts.FromComposite = true
-	opts.IdleVecOpts.Name = opts.Name + "_idle"
-	opts.AcquiredVecOpts.FromComposite = true
-	opts.AcquiredVecOpts.Name = opts.Name + "_acquired"
-	opts.ReleasedVecOpts.FromComposite = true
-	opts.ReleasedVecOpts.Name = opts.Name + "_released"
-
 	base := baseMetric{
 		name:  opts.Name,
 		help:  opts.Help,
@@ -635,13 +746,6 @@ func newNoopPoolVec(opts PoolVecOpts, level Level) PoolVec {
 // }
 
 func newNoopCircuitBreaker(opts CircuitBreakerOpts, level Level) CircuitBreaker {
-	opts.StateOpts.FromComposite = true
-	opts.StateOpts.Name = opts.Name + "_state"
-	opts.SuccessOpts.FromComposite = true
-	opts.SuccessOpts.Name = opts.Name + "_success"
-	opts.FailureOpts.FromComposite = true
-	opts.FailureOpts.Name = opts.Name + "_failure"
-
 	base := baseMetric{
 		name:  opts.Name,
 		help:  opts.Help,
@@ -700,13 +804,6 @@ func newNoopCircuitBreaker(opts CircuitBreakerOpts, level Level) CircuitBreaker
 // }
 
 func newNoopCircuitBreakerVec(opts CircuitBreakerVecOpts, level Level) CircuitBreakerVec {
-	opts.StateVecOpts.FromComposite = true
-	opts.StateVecOpts.Name = opts.Name + "_state"
-	opts.SuccessVecOpts.FromComposite = true
-	opts.SuccessVecOpts.Name = opts.Name + "_success"
-	opts.FailureVecOpts.FromComposite = true
-	opts.FailureVecOpts.Name = opts.Name + "_failure"
-
 	base := baseMetric{
 		name:  opts.Name,
 		help:  opts.Help,
@@ -766,15 +863,6 @@ func newNoopCircuitBreakerVec(opts CircuitBreakerVecOpts, level Level) CircuitBr
 // }
 
 func newNoopQueue(opts QueueOpts, level Level) Queue {
-	opts.DepthOpts.FromComposite = true
-	opts.DepthOpts.Name = opts.Name + "_depth"
-	opts.EnqueuedOpts.FromComposite = true
-	opts.EnqueuedOpts.Name = opts.Name + "_enqueued"
-	opts.DequeuedOpts.FromComposite = true
-	opts.DequeuedOpts.Name = opts.Name + "_dequeued"
-	opts.WaitTimeOpts.FromComposite = true
-	opts.WaitTimeOpts.Name = opts.Name + "_wait_time"
-
 	base := baseMetric{
 		name:  opts.Name,
 		help:  opts.Help,
@@ -840,15 +928,6 @@ func newNoopQueue(opts QueueOpts, level Level) Queue {
 // }
 
 func newNoopQueueVec(opts QueueVecOpts, level Level) QueueVec {
-	opts.DepthVecOpts.FromComposite = true
-	opts.DepthVecOpts.Name = opts.Name + "_depth"
-	opts.EnqueuedVecOpts.FromComposite = true
-	opts.EnqueuedVecOpts.Name = opts.Name + "_enqueued"
-	opts.DequeuedVecOpts.FromComposite = true
-	opts.DequeuedVecOpts.Name = opts.Name + "_dequeued"
-	opts.WaitTimeVecOpts.FromComposite = true
-	opts.WaitTimeVecOpts.Name = opts.Name + "_wait_time"
-
 	base := baseMetric{
 		name:  opts.Name,
 		help:  opts.Help,
@@ -902,12 +981,87 @@ func newNoopQueueVec(opts QueueVecOpts, level Level) QueueVec {
 // 	}
 // }
 
+func newNoopThroughput(opts ThroughputOpts, level Level) Throughput {
+	base := baseMetric{
+		name:  opts.Name,
+		help:  opts.Help,
+		level: level,
+	}
+
+	clock := clockOrDefault(opts.Clock)
+	return &baseThroughput{
+		baseCompositeMetric: baseCompositeMetric{base},
+		count:               newNoopCounter(opts.CountOpts, level),
+		rate:                newNoopGauge(opts.RateOpts, level),
+		window:              throughputWindow(opts.Window),
+		clock:               clock,
+		lastTick:            clock.Now(),
+	}
+}
+
+func newNoopDistributionGauge(opts DistributionGaugeOpts, level Level) DistributionGauge {
+	base := baseMetric{
+		name:  opts.Name,
+		help:  opts.Help,
+		level: level,
+	}
+
+	return &baseDistributionGauge{
+		baseCompositeMetric: baseCompositeMetric{base},
+		gauge:               newNoopGauge(opts.GaugeOpts, level),
+		histogram:           newNoopHistogram(opts.HistogramOpts, level),
+	}
+}
+
+func newNoopMultiResHistogram(opts MultiResHistogramOpts, level Level) MultiResHistogram {
+	base := baseMetric{
+		name:  opts.Name,
+		help:  opts.Help,
+		level: level,
+	}
+
+	histograms := make([]Histogram, len(opts.Resolutions))
+	for i, resolution := range opts.Resolutions {
+		histograms[i] = newNoopHistogram(resolution, level)
+	}
+
+	return &baseMultiResHistogram{
+		baseCompositeMetric: baseCompositeMetric{base},
+		histograms:          histograms,
+	}
+}
+
+func newNoopDependency(opts DependencyOpts, level Level) Dependency {
+	base := baseMetric{
+		name:  opts.Name,
+		help:  opts.Help,
+		level: level,
+	}
+
+	return &baseDependency{
+		baseCompositeMetric: baseCompositeMetric{base},
+		calls:               newNoopCounterVec(opts.CallsVecOpts, level),
+		latency:             newNoopHistogramVec(opts.LatencyVecOpts, level),
+		inFlight:            newNoopGaugeVec(opts.InFlightVecOpts, level),
+		clock:               clockOrDefault(opts.Clock),
+	}
+}
+
+func (n *noopSummaryVec) Delete(ctx Context, labels VecLabels) bool {
+	return false
+}
+
+func (n *noopSummaryVec) Reset(ctx Context) {}
+
 // Sanity checks for interfaces
 var (
 	// Metric interface checks
 	__ctc_noopCounterIntf           Counter           = (*noopCounter)(nil)
+	__ctc_noopCounterGroupIntf      CounterGroup      = (*noopCounterGroup)(nil)
+	__ctc_noopCounterFuncIntf       CounterFunc       = (*noopCounterFunc)(nil)
 	__ctc_noopCounterVecIntf        CounterVec        = (*noopCounterVec)(nil)
 	__ctc_noopGaugeIntf             Gauge             = (*noopGauge)(nil)
+	__ctc_noopGaugeFuncIntf         GaugeFunc         = (*noopGaugeFunc)(nil)
 	__ctc_noopGaugeVecIntf          GaugeVec          = (*noopGaugeVec)(nil)
 	__ctc_noopHistogramIntf         Histogram         = (*noopHistogram)(nil)
 	__ctc_noopHistogramVecIntf      HistogramVec      = (*noopHistogramVec)(nil)
@@ -923,11 +1077,18 @@ var (
 	__ctc_noopCircuitBreakerVecIntf CircuitBreakerVec = newNoopCircuitBreakerVec(CircuitBreakerVecOpts{}, LevelDisabled)
 	__ctc_noopQueueIntf             Queue             = newNoopQueue(QueueOpts{}, LevelDisabled)
 	__ctc_noopQueueVecIntf          QueueVec          = newNoopQueueVec(QueueVecOpts{}, LevelDisabled)
+	__ctc_noopThroughputIntf        Throughput        = newNoopThroughput(ThroughputOpts{}, LevelDisabled)
+	__ctc_noopDistributionGaugeIntf DistributionGauge = newNoopDistributionGauge(DistributionGaugeOpts{}, LevelDisabled)
+	__ctc_noopMultiResHistogramIntf MultiResHistogram = newNoopMultiResHistogram(MultiResHistogramOpts{}, LevelDisabled)
+	__ctc_noopDependencyIntf        Dependency        = newNoopDependency(DependencyOpts{}, LevelDisabled)
 
 	// Basic NoopMetric interface checks
 	__ctc_noopCounterNoopBasic      NoopMetric = (*noopCounter)(nil)
+	__ctc_noopCounterGroupNoopBasic NoopMetric = (*noopCounterGroup)(nil)
+	__ctc_noopCounterFuncNoopBasic  NoopMetric = (*noopCounterFunc)(nil)
 	__ctc_noopCounterVecNoopBasic   NoopMetric = (*noopCounterVec)(nil)
 	__ctc_noopGaugeNoopBasic        NoopMetric = (*noopGauge)(nil)
+	__ctc_noopGaugeFuncNoopBasic    NoopMetric = (*noopGaugeFunc)(nil)
 	__ctc_noopGaugeVecNoopBasic     NoopMetric = (*noopGaugeVec)(nil)
 	__ctc_noopHistogramNoopBasic    NoopMetric = (*noopHistogram)(nil)
 	__ctc_noopHistogramVecNoopBasic NoopMetric = (*noopHistogramVec)(nil)
@@ -945,4 +1106,8 @@ var (
 	__ctc_noopCircuitBreakerVecNoopComposite CompositeMetric = newNoopCircuitBreakerVec(CircuitBreakerVecOpts{}, LevelDisabled)
 	__ctc_noopQueueNoopComposite             CompositeMetric = newNoopQueue(QueueOpts{}, LevelDisabled)
 	__ctc_noopQueueVecNoopComposite          CompositeMetric = newNoopQueueVec(QueueVecOpts{}, LevelDisabled)
+	__ctc_noopThroughputNoopComposite        CompositeMetric = newNoopThroughput(ThroughputOpts{}, LevelDisabled)
+	__ctc_noopDistributionGaugeNoopComposite CompositeMetric = newNoopDistributionGauge(DistributionGaugeOpts{}, LevelDisabled)
+	__ctc_noopMultiResHistogramNoopComposite CompositeMetric = newNoopMultiResHistogram(MultiResHistogramOpts{}, LevelDisabled)
+	__ctc_noopDependencyNoopComposite        CompositeMetric = newNoopDependency(DependencyOpts{}, LevelDisabled)
 )
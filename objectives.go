@@ -0,0 +1,86 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: objectives.go
+//
+// This file contains the normalization of summary objectives, merging the
+// [SummaryOpts.Objectives] and [SummaryOpts.ObjectiveSets] of a summary into a
+// single map, so that e.g. tight tail quantiles and loose median quantiles can
+// be declared separately and still share one summary:
+//
+//	latency := group.Summary(umami.SummaryOpts{
+//		MetricInfo:    umami.MetricInfo{Name: "latency_seconds"},
+//		ObjectiveSets: []map[float64]float64{
+//			{0.99: 0.001, 0.999: 0.0001}, // Tail
+//			{0.5: 0.05},                  // Median
+//		},
+//	}, umami.LevelImportant)
+//--------------------------------------------------------------------------------
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// MergeObjectives merges summary objective maps, mapping quantiles to their
+// allowed error, into one. Quantiles listed more than once are deduplicated.
+//
+// If a quantile is listed with different errors, the tightest (smallest) error
+// is kept, so that no objective is less precise than requested, and an error
+// wrapping [ErrConflictingObjectives] is returned for every such quantile.
+// The merged objectives are returned either way.
+//
+// Returns nil if no set has objectives.
+func MergeObjectives(sets ...map[float64]float64) (map[float64]float64, error) {
+	var merged map[float64]float64
+	conflicts := make(map[float64][]float64)
+
+	for _, set := range sets {
+		for q, e := range set {
+			if merged == nil {
+				merged = make(map[float64]float64)
+			}
+
+			current, exists := merged[q]
+			if !exists {
+				merged[q] = e
+				continue
+			}
+			if current == e {
+				continue
+			}
+
+			if !slices.Contains(conflicts[q], current) {
+				conflicts[q] = append(conflicts[q], current)
+			}
+			if !slices.Contains(conflicts[q], e) {
+				conflicts[q] = append(conflicts[q], e)
+			}
+			merged[q] = min(current, e)
+		}
+	}
+
+	var errs []error
+	for _, q := range slices.Sorted(maps.Keys(conflicts)) {
+		errs = append(errs, fmt.Errorf("%w: quantile %v has errors %v, keeping %v",
+			ErrConflictingObjectives, q, conflicts[q], merged[q]))
+	}
+
+	return merged, errors.Join(errs...)
+}
+
+// mergeObjectives merges objectives and sets like [MergeObjectives], reporting
+// conflicts of the named summary to the group's warning handler
+func (g *group) mergeObjectives(name string, objectives map[float64]float64, sets []map[float64]float64) map[float64]float64 {
+	if len(sets) == 0 {
+		return objectives
+	}
+
+	merged, err := MergeObjectives(append([]map[float64]float64{objectives}, sets...)...)
+	if err != nil {
+		g.warn(fmt.Errorf("summary %q: %w", name, err))
+	}
+	return merged
+}
@@ -0,0 +1,88 @@
+package umami
+
+import (
+	"errors"
+	"maps"
+	"testing"
+)
+
+func TestMergeObjectivesDuplicates(t *testing.T) {
+	merged, err := MergeObjectives(
+		map[float64]float64{0.5: 0.05, 0.99: 0.001},
+		map[float64]float64{0.99: 0.001, 0.999: 0.0001},
+		nil,
+	)
+	if err != nil {
+		t.Errorf("Expected no error for identical duplicates, got %v", err)
+	}
+
+	want := map[float64]float64{0.5: 0.05, 0.99: 0.001, 0.999: 0.0001}
+	if !maps.Equal(merged, want) {
+		t.Errorf("Expected %v, got %v", want, merged)
+	}
+
+	if merged, err := MergeObjectives(nil, map[float64]float64{}); merged != nil || err != nil {
+		t.Errorf("Expected nil objectives without error, got %v (%v)", merged, err)
+	}
+}
+
+func TestMergeObjectivesConflicts(t *testing.T) {
+	merged, err := MergeObjectives(
+		map[float64]float64{0.5: 0.05, 0.99: 0.01},
+		map[float64]float64{0.5: 0.01, 0.99: 0.001},
+	)
+	if !errors.Is(err, ErrConflictingObjectives) {
+		t.Fatalf("Expected ErrConflictingObjectives, got %v", err)
+	}
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != 2 {
+		t.Errorf("Expected one error per conflicting quantile, got %d: %v", n, err)
+	}
+
+	// The tightest error is kept, and no objective is dropped
+	want := map[float64]float64{0.5: 0.01, 0.99: 0.001}
+	if !maps.Equal(merged, want) {
+		t.Errorf("Expected %v, got %v", want, merged)
+	}
+}
+
+func TestGroupSummaryObjectiveSets(t *testing.T) {
+	var warnings []error
+	reg := NewRegistry(LevelDebug, WithWarningHandler(func(err error) {
+		warnings = append(warnings, err)
+	}))
+	group := reg.NewGroup("test", NewMockBackend())
+
+	summary := group.Summary(SummaryOpts{
+		MetricInfo:    MetricInfo{Name: "latency"},
+		Objectives:    map[float64]float64{0.5: 0.05},
+		ObjectiveSets: []map[float64]float64{{0.99: 0.001}, {0.5: 0.05}},
+	}, LevelImportant)
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings for duplicate objectives, got %v", warnings)
+	}
+
+	opts := summary.(*switchableSummary).opts.(SummaryOpts)
+	want := map[float64]float64{0.5: 0.05, 0.99: 0.001}
+	if !maps.Equal(opts.Objectives, want) || opts.ObjectiveSets != nil {
+		t.Errorf("Expected merged objectives %v, got %v and sets %v", want, opts.Objectives, opts.ObjectiveSets)
+	}
+
+	group.SummaryVec(SummaryVecOpts{
+		MetricInfo:    MetricInfo{Name: "latency_by_route"},
+		Labels:        []string{"route"},
+		ObjectiveSets: []map[float64]float64{{0.99: 0.001}, {0.99: 0.01}},
+	}, LevelImportant)
+	if len(warnings) != 1 || !errors.Is(warnings[0], ErrConflictingObjectives) {
+		t.Errorf("Expected one ErrConflictingObjectives warning, got %v", warnings)
+	}
+
+	if err := summary.Reconfigure(SummaryOpts{ObjectiveSets: []map[float64]float64{{0.9: 0.01}, {0.9: 0.05}}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(warnings) != 2 {
+		t.Errorf("Expected Reconfigure to warn about conflicting objectives, got %v", warnings)
+	}
+	if got := summary.(*switchableSummary).opts.(SummaryOpts).Objectives; !maps.Equal(got, map[float64]float64{0.9: 0.01}) {
+		t.Errorf("Expected reconfigured objectives to be merged, got %v", got)
+	}
+}
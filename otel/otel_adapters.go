@@ -0,0 +1,221 @@
+package umami_otel
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/SimonDaKappa/go-umami"
+)
+
+// constAttributes returns the attributes of the const labels of a metric, see
+// [umami.MetricInfo.ConstLabels]
+func constAttributes(constLabels map[string]string) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(constLabels))
+	for name, value := range constLabels {
+		kvs = append(kvs, attribute.String(name, value))
+	}
+	return kvs
+}
+
+// series are the declared label names and the const attributes of a vec metric
+type series struct {
+	labels []string
+	consts []attribute.KeyValue
+}
+
+// attributes translates the labels of a vec metric to the attribute set
+// recorded with each value, along with its const attributes. Returns an error
+// wrapping [umami.ErrLabelMismatch] if labels don't match the declared label
+// names.
+func (s series) attributes(labels umami.VecLabels) (attribute.Set, error) {
+	if len(labels) != len(s.labels) {
+		return attribute.Set{}, fmt.Errorf("%w: got %d labels, declared %v", umami.ErrLabelMismatch, len(labels), s.labels)
+	}
+
+	kvs := make([]attribute.KeyValue, len(s.labels), len(s.labels)+len(s.consts))
+	for i, name := range s.labels {
+		value, ok := labels[name]
+		if !ok {
+			return attribute.Set{}, fmt.Errorf("%w: missing label %q", umami.ErrLabelMismatch, name)
+		}
+		kvs[i] = attribute.String(name, value)
+	}
+	return attribute.NewSet(append(kvs, s.consts...)...), nil
+}
+
+type otCounterAdapter struct {
+	internal metric.Float64Counter
+	consts   attribute.Set // Const attributes, recorded with each value
+}
+
+func (a *otCounterAdapter) Inc() error {
+	return a.Add(1)
+}
+
+func (a *otCounterAdapter) Add(value float64) error {
+	a.internal.Add(context.Background(), value, metric.WithAttributeSet(a.consts))
+	return nil
+}
+
+type otCounterVecAdapter struct {
+	internal metric.Float64Counter
+	series
+}
+
+func (a *otCounterVecAdapter) Inc(labels umami.VecLabels) error {
+	return a.Add(1, labels)
+}
+
+func (a *otCounterVecAdapter) Add(value float64, labels umami.VecLabels) error {
+	set, err := a.attributes(labels)
+	if err != nil {
+		return err
+	}
+	a.internal.Add(context.Background(), value, metric.WithAttributeSet(set))
+	return nil
+}
+
+// gaugeValues records the values of the series of a synchronous gauge, which
+// can only be set, tracking them so that they can also be incremented
+type gaugeValues struct {
+	internal metric.Float64Gauge
+
+	mu     sync.Mutex
+	values map[attribute.Distinct]float64 // Map of attribute set to current value
+}
+
+func newGaugeValues(internal metric.Float64Gauge) gaugeValues {
+	return gaugeValues{internal: internal, values: make(map[attribute.Distinct]float64)}
+}
+
+func (g *gaugeValues) set(value float64, set attribute.Set) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.values[set.Equivalent()] = value
+	g.internal.Record(context.Background(), value, metric.WithAttributeSet(set))
+}
+
+func (g *gaugeValues) add(delta float64, set attribute.Set) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	value := g.values[set.Equivalent()] + delta
+	g.values[set.Equivalent()] = value
+	g.internal.Record(context.Background(), value, metric.WithAttributeSet(set))
+}
+
+type otGaugeAdapter struct {
+	gaugeValues
+	consts attribute.Set // Const attributes, recorded with each value
+}
+
+func (a *otGaugeAdapter) Set(value float64) error {
+	a.set(value, a.consts)
+	return nil
+}
+
+func (a *otGaugeAdapter) Inc() error {
+	return a.Add(1)
+}
+
+func (a *otGaugeAdapter) Dec() error {
+	return a.Add(-1)
+}
+
+func (a *otGaugeAdapter) Add(value float64) error {
+	a.add(value, a.consts)
+	return nil
+}
+
+type otGaugeVecAdapter struct {
+	gaugeValues
+	series
+}
+
+func (a *otGaugeVecAdapter) Set(value float64, labels umami.VecLabels) error {
+	set, err := a.attributes(labels)
+	if err != nil {
+		return err
+	}
+	a.set(value, set)
+	return nil
+}
+
+func (a *otGaugeVecAdapter) Inc(labels umami.VecLabels) error {
+	return a.Add(1, labels)
+}
+
+func (a *otGaugeVecAdapter) Dec(labels umami.VecLabels) error {
+	return a.Add(-1, labels)
+}
+
+func (a *otGaugeVecAdapter) Add(value float64, labels umami.VecLabels) error {
+	set, err := a.attributes(labels)
+	if err != nil {
+		return err
+	}
+	a.add(value, set)
+	return nil
+}
+
+type otHistogramAdapter struct {
+	internal metric.Float64Histogram
+	consts   attribute.Set // Const attributes, recorded with each value
+}
+
+func (a *otHistogramAdapter) Observe(value float64) error {
+	a.internal.Record(context.Background(), value, metric.WithAttributeSet(a.consts))
+	return nil
+}
+
+type otHistogramVecAdapter struct {
+	internal metric.Float64Histogram
+	series
+}
+
+func (a *otHistogramVecAdapter) Observe(value float64, labels umami.VecLabels) error {
+	set, err := a.attributes(labels)
+	if err != nil {
+		return err
+	}
+	a.internal.Record(context.Background(), value, metric.WithAttributeSet(set))
+	return nil
+}
+
+// otSummaryAdapter records observations into a histogram, as OpenTelemetry
+// has no summaries
+type otSummaryAdapter struct {
+	otHistogramAdapter
+}
+
+// Quantile is unsupported, as histograms don't track quantiles
+func (a *otSummaryAdapter) Quantile(q float64) (float64, error) {
+	return 0, umami.ErrUnsupportedMetric
+}
+
+// otSummaryVecAdapter records observations into a histogram, as
+// OpenTelemetry has no summaries
+type otSummaryVecAdapter struct {
+	otHistogramVecAdapter
+}
+
+// Quantile is unsupported, as histograms don't track quantiles
+func (a *otSummaryVecAdapter) Quantile(q float64, labels umami.VecLabels) (float64, error) {
+	return 0, umami.ErrUnsupportedMetric
+}
+
+var (
+	__ctc_otCounterAdapter      umami.CounterAdapter      = (*otCounterAdapter)(nil)
+	__ctc_otCounterVecAdapter   umami.CounterVecAdapter   = (*otCounterVecAdapter)(nil)
+	__ctc_otGaugeAdapter        umami.GaugeAdapter        = (*otGaugeAdapter)(nil)
+	__ctc_otGaugeVecAdapter     umami.GaugeVecAdapter     = (*otGaugeVecAdapter)(nil)
+	__ctc_otHistogramAdapter    umami.HistogramAdapter    = (*otHistogramAdapter)(nil)
+	__ctc_otHistogramVecAdapter umami.HistogramVecAdapter = (*otHistogramVecAdapter)(nil)
+	__ctc_otSummaryAdapter      umami.SummaryAdapter      = (*otSummaryAdapter)(nil)
+	__ctc_otSummaryVecAdapter   umami.SummaryVecAdapter   = (*otSummaryVecAdapter)(nil)
+)
@@ -0,0 +1,136 @@
+package umami_otel
+
+// Integration with the OpenTelemetry metrics API, recording through the
+// instruments of a metric.MeterProvider, e.g. of an existing SDK pipeline.
+//
+// This is a module of its own, so that the core module doesn't depend on
+// OpenTelemetry.
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/SimonDaKappa/go-umami"
+)
+
+const (
+	OTelBackendName string = "otel"
+
+	// ScopeName is the instrumentation scope of the meter of the backend
+	ScopeName = "github.com/SimonDaKappa/go-umami"
+)
+
+// otelBackend implements the [umami.Backend] interface with the instruments
+// of a meter
+type otelBackend struct {
+	meter metric.Meter
+}
+
+// NewOTelBackend creates a backend recording through the instruments of a
+// meter of provider, scoped [ScopeName].
+//
+// Counters map to Float64Counters, gauges to synchronous Float64Gauges, and
+// histograms to Float64Histograms with the buckets of [umami.HistogramOpts]
+// as explicit bucket boundaries. Labels of vec metrics are recorded as
+// attributes, and so are the const labels of every metric, see
+// [umami.MetricInfo.ConstLabels]. Instruments are named by the qualified names
+// of the metrics, see [umami.MetricInfo.QualifiedName].
+//
+// OpenTelemetry has no summaries: they fall back to histograms with the
+// default boundaries of the SDK, and their quantiles can't be read, see
+// [umami.CapabilityBackend]. Metric values can't be read back either.
+//
+// Creating an instrument the meter rejects, e.g. because of an invalid
+// name, panics.
+func NewOTelBackend(provider metric.MeterProvider) umami.Backend {
+	return &otelBackend{meter: provider.Meter(ScopeName)}
+}
+
+func (o *otelBackend) Name() string {
+	return OTelBackendName
+}
+
+// Supports returns false for summaries, which are recorded as histograms
+func (o *otelBackend) Supports(kind umami.MetricKind) bool {
+	return kind != umami.MetricKindSummary
+}
+
+// must returns instrument, panicking if the meter failed to create it
+func must[I any](name string, instrument I, err error) I {
+	if err != nil {
+		panic(fmt.Sprintf("umami_otel: creating instrument %q: %v", name, err))
+	}
+	return instrument
+}
+
+func (o *otelBackend) counter(info umami.MetricInfo) metric.Float64Counter {
+	counter, err := o.meter.Float64Counter(info.QualifiedName(), metric.WithDescription(info.Help))
+	return must(info.QualifiedName(), counter, err)
+}
+
+func (o *otelBackend) gauge(info umami.MetricInfo) metric.Float64Gauge {
+	gauge, err := o.meter.Float64Gauge(info.QualifiedName(), metric.WithDescription(info.Help))
+	return must(info.QualifiedName(), gauge, err)
+}
+
+func (o *otelBackend) histogram(info umami.MetricInfo, buckets []float64) metric.Float64Histogram {
+	opts := []metric.Float64HistogramOption{metric.WithDescription(info.Help)}
+	if len(buckets) > 0 {
+		opts = append(opts, metric.WithExplicitBucketBoundaries(buckets...))
+	}
+	histogram, err := o.meter.Float64Histogram(info.QualifiedName(), opts...)
+	return must(info.QualifiedName(), histogram, err)
+}
+
+// consts returns the const attributes of the metric of info as a set
+func consts(info umami.MetricInfo) attribute.Set {
+	return attribute.NewSet(constAttributes(info.ConstLabels)...)
+}
+
+// vecSeries returns the series of the vec metric of info declaring labels
+func vecSeries(info umami.MetricInfo, labels []string) series {
+	return series{labels: labels, consts: constAttributes(info.ConstLabels)}
+}
+
+func (o *otelBackend) Counter(opts umami.CounterOpts) umami.CounterAdapter {
+	return &otCounterAdapter{internal: o.counter(opts.MetricInfo), consts: consts(opts.MetricInfo)}
+}
+
+func (o *otelBackend) CounterVec(opts umami.CounterVecOpts) umami.CounterVecAdapter {
+	return &otCounterVecAdapter{internal: o.counter(opts.MetricInfo), series: vecSeries(opts.MetricInfo, opts.Labels)}
+}
+
+func (o *otelBackend) Gauge(opts umami.GaugeOpts) umami.GaugeAdapter {
+	return &otGaugeAdapter{gaugeValues: newGaugeValues(o.gauge(opts.MetricInfo)), consts: consts(opts.MetricInfo)}
+}
+
+func (o *otelBackend) GaugeVec(opts umami.GaugeVecOpts) umami.GaugeVecAdapter {
+	return &otGaugeVecAdapter{gaugeValues: newGaugeValues(o.gauge(opts.MetricInfo)), series: vecSeries(opts.MetricInfo, opts.Labels)}
+}
+
+func (o *otelBackend) Histogram(opts umami.HistogramOpts) umami.HistogramAdapter {
+	return &otHistogramAdapter{internal: o.histogram(opts.MetricInfo, opts.Buckets), consts: consts(opts.MetricInfo)}
+}
+
+func (o *otelBackend) HistogramVec(opts umami.HistogramVecOpts) umami.HistogramVecAdapter {
+	return &otHistogramVecAdapter{internal: o.histogram(opts.MetricInfo, opts.Buckets), series: vecSeries(opts.MetricInfo, opts.Labels)}
+}
+
+// Summary records into a histogram with the default boundaries, as
+// OpenTelemetry has no summaries
+func (o *otelBackend) Summary(opts umami.SummaryOpts) umami.SummaryAdapter {
+	return &otSummaryAdapter{otHistogramAdapter{internal: o.histogram(opts.MetricInfo, nil), consts: consts(opts.MetricInfo)}}
+}
+
+// SummaryVec records into a histogram with the default boundaries, as
+// OpenTelemetry has no summaries
+func (o *otelBackend) SummaryVec(opts umami.SummaryVecOpts) umami.SummaryVecAdapter {
+	return &otSummaryVecAdapter{otHistogramVecAdapter{internal: o.histogram(opts.MetricInfo, nil), series: vecSeries(opts.MetricInfo, opts.Labels)}}
+}
+
+var (
+	__ctc_otelBackend           umami.Backend           = (*otelBackend)(nil)
+	__ctc_otelCapabilityBackend umami.CapabilityBackend = (*otelBackend)(nil)
+)
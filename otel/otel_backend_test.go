@@ -0,0 +1,191 @@
+package umami_otel
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/SimonDaKappa/go-umami"
+)
+
+// newTestBackend returns a backend recording to an in-memory reader
+func newTestBackend(t *testing.T) (umami.Backend, *sdkmetric.ManualReader) {
+	t.Helper()
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return NewOTelBackend(provider), reader
+}
+
+// collect returns the data of the instrument name collected by reader
+func collect(t *testing.T, reader *sdkmetric.ManualReader, name string) metricdata.Aggregation {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	for _, scope := range rm.ScopeMetrics {
+		if scope.Scope.Name != ScopeName {
+			continue
+		}
+		for _, m := range scope.Metrics {
+			if m.Name == name {
+				return m.Data
+			}
+		}
+	}
+	t.Fatalf("Instrument %q not collected", name)
+	return nil
+}
+
+func TestOTelCounter(t *testing.T) {
+	backend, reader := newTestBackend(t)
+
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("web", backend)
+	counter := group.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "requests_total"}}, umami.LevelImportant)
+	_ = counter.Inc(group.Context())
+	_ = counter.Add(group.Context(), 2)
+
+	sum, ok := collect(t, reader, "web_requests_total").(metricdata.Sum[float64])
+	if !ok {
+		t.Fatalf("Expected a float sum")
+	}
+	if !sum.IsMonotonic || len(sum.DataPoints) != 1 || sum.DataPoints[0].Value != 3 {
+		t.Errorf("Expected one monotonic data point of 3, got %+v", sum)
+	}
+}
+
+func TestOTelCounterVecAttributes(t *testing.T) {
+	backend, reader := newTestBackend(t)
+
+	vec := backend.CounterVec(umami.CounterVecOpts{MetricInfo: umami.MetricInfo{Name: "requests_total"}, Labels: []string{"method"}})
+	_ = vec.Inc(umami.VecLabels{"method": "GET"})
+	_ = vec.Add(4, umami.VecLabels{"method": "POST"})
+	if err := vec.Inc(umami.VecLabels{"route": "/a"}); !errors.Is(err, umami.ErrLabelMismatch) {
+		t.Errorf("Expected ErrLabelMismatch, got %v", err)
+	}
+
+	sum := collect(t, reader, "requests_total").(metricdata.Sum[float64])
+	get := attribute.NewSet(attribute.String("method", "GET"))
+	post := attribute.NewSet(attribute.String("method", "POST"))
+	want := map[attribute.Distinct]float64{
+		get.Equivalent():  1,
+		post.Equivalent(): 4,
+	}
+	if len(sum.DataPoints) != len(want) {
+		t.Fatalf("Expected %d data points, got %d", len(want), len(sum.DataPoints))
+	}
+	for _, dp := range sum.DataPoints {
+		if value, ok := want[dp.Attributes.Equivalent()]; !ok || dp.Value != value {
+			t.Errorf("Unexpected data point %v = %v", dp.Attributes.ToSlice(), dp.Value)
+		}
+	}
+}
+
+func TestOTelGauge(t *testing.T) {
+	backend, reader := newTestBackend(t)
+
+	gauge := backend.Gauge(umami.GaugeOpts{MetricInfo: umami.MetricInfo{Name: "in_flight"}})
+	_ = gauge.Set(5)
+	_ = gauge.Inc()
+	_ = gauge.Add(0.5)
+	_ = gauge.Dec()
+
+	data := collect(t, reader, "in_flight").(metricdata.Gauge[float64])
+	if len(data.DataPoints) != 1 || data.DataPoints[0].Value != 5.5 {
+		t.Errorf("Expected one data point of 5.5, got %+v", data.DataPoints)
+	}
+
+	vec := backend.GaugeVec(umami.GaugeVecOpts{MetricInfo: umami.MetricInfo{Name: "queue_depth"}, Labels: []string{"queue"}})
+	_ = vec.Inc(umami.VecLabels{"queue": "a"})
+	_ = vec.Inc(umami.VecLabels{"queue": "a"})
+	_ = vec.Set(7, umami.VecLabels{"queue": "b"})
+
+	data = collect(t, reader, "queue_depth").(metricdata.Gauge[float64])
+	for _, dp := range data.DataPoints {
+		queue, _ := dp.Attributes.Value("queue")
+		if want := map[string]float64{"a": 2, "b": 7}[queue.AsString()]; dp.Value != want {
+			t.Errorf("Expected queue %q at %v, got %v", queue.AsString(), want, dp.Value)
+		}
+	}
+}
+
+func TestOTelHistogramBuckets(t *testing.T) {
+	backend, reader := newTestBackend(t)
+
+	buckets := []float64{0.1, 1, 10}
+	histogram := backend.HistogramVec(umami.HistogramVecOpts{MetricInfo: umami.MetricInfo{Name: "latency"}, Labels: []string{"route"}, Buckets: buckets})
+	for _, value := range []float64{0.05, 0.5, 5, 50} {
+		_ = histogram.Observe(value, umami.VecLabels{"route": "/a"})
+	}
+
+	data := collect(t, reader, "latency").(metricdata.Histogram[float64])
+	if len(data.DataPoints) != 1 {
+		t.Fatalf("Expected one data point, got %d", len(data.DataPoints))
+	}
+	dp := data.DataPoints[0]
+	if !slices.Equal(dp.Bounds, buckets) {
+		t.Errorf("Expected the bounds %v, got %v", buckets, dp.Bounds)
+	}
+	if want := []uint64{1, 1, 1, 1}; dp.Count != 4 || !slices.Equal(dp.BucketCounts, want) {
+		t.Errorf("Expected the bucket counts %v, got %v", want, dp.BucketCounts)
+	}
+	if route, _ := dp.Attributes.Value("route"); route.AsString() != "/a" {
+		t.Errorf("Expected the route attribute, got %v", dp.Attributes.ToSlice())
+	}
+}
+
+func TestOTelSummaryFallback(t *testing.T) {
+	backend, reader := newTestBackend(t)
+
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("web", backend)
+	if group.BackendSupports(umami.MetricKindSummary) {
+		t.Errorf("Expected summaries to be reported unsupported")
+	}
+
+	summary := backend.Summary(umami.SummaryOpts{MetricInfo: umami.MetricInfo{Name: "size"}})
+	_ = summary.Observe(512)
+	if _, err := summary.Quantile(0.5); !errors.Is(err, umami.ErrUnsupportedMetric) {
+		t.Errorf("Expected ErrUnsupportedMetric, got %v", err)
+	}
+
+	data := collect(t, reader, "size").(metricdata.Histogram[float64])
+	if len(data.DataPoints) != 1 || data.DataPoints[0].Sum != 512 {
+		t.Errorf("Expected the observation in a histogram, got %+v", data.DataPoints)
+	}
+}
+
+func TestOTelQualifiedNamesConstLabels(t *testing.T) {
+	backend, reader := newTestBackend(t)
+
+	counter := backend.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{
+		Name:        "orders_total",
+		Namespace:   "shop",
+		Subsystem:   "checkout",
+		ConstLabels: map[string]string{"service": "api"},
+	}})
+	_ = counter.Inc()
+
+	sum := collect(t, reader, "shop_checkout_orders_total").(metricdata.Sum[float64])
+	service := attribute.NewSet(attribute.String("service", "api"))
+	if len(sum.DataPoints) != 1 || !sum.DataPoints[0].Attributes.Equals(&service) {
+		t.Errorf("Expected one data point with the const labels as attributes, got %+v", sum.DataPoints)
+	}
+
+	vec := backend.GaugeVec(umami.GaugeVecOpts{
+		MetricInfo: umami.MetricInfo{Name: "queue_depth", Namespace: "shop", ConstLabels: map[string]string{"service": "api"}},
+		Labels:     []string{"queue"},
+	})
+	_ = vec.Set(3, umami.VecLabels{"queue": "a"})
+
+	data := collect(t, reader, "shop_queue_depth").(metricdata.Gauge[float64])
+	want := attribute.NewSet(attribute.String("queue", "a"), attribute.String("service", "api"))
+	if len(data.DataPoints) != 1 || !data.DataPoints[0].Attributes.Equals(&want) || data.DataPoints[0].Value != 3 {
+		t.Errorf("Expected one data point of 3 with the labels and const labels, got %+v", data.DataPoints)
+	}
+}
@@ -0,0 +1,150 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: partition.go
+//
+// This file contains the partitions of label-vectorized composite metrics,
+// returned by e.g. [QueueVec.Partition]. A partition binds a vec to a fixed set
+// of labels, and implements the non-vec interface by passing them to every
+// operation, so that code tracking a single partition doesn't need the labels:
+//
+//	jobs := queues.Partition(umami.VecLabels{"queue": "jobs"})
+//	jobs.Enqueue(ctx)
+//
+// Partitions record through the vec they were created from, so they follow its
+// level changes. Their [Metric] methods, e.g. SetLevel, are those of the vec.
+//--------------------------------------------------------------------------------
+
+import "time"
+
+// timerPartition implements [Timer] by recording into a [TimerVec] with fixed labels
+type timerPartition struct {
+	TimerVec
+	labels VecLabels
+}
+
+func (p *timerPartition) Start(ctx Context) func() {
+	return p.TimerVec.Start(ctx, p.labels)
+}
+
+func (p *timerPartition) StartFunc(ctx Context, fn func() error) error {
+	defer p.Start(ctx)()
+	return fn()
+}
+
+func (p *timerPartition) Record(ctx Context, duration time.Duration) error {
+	return p.TimerVec.Record(ctx, duration, p.labels)
+}
+
+func (p *timerPartition) RecordFromContextStart(ctx Context) error {
+	return p.TimerVec.RecordFromContextStart(ctx, p.labels)
+}
+
+func (p *timerPartition) AsObserver(ctx Context) DurationObserver {
+	return p.TimerVec.AsObserverVec(ctx).With(p.labels)
+}
+
+// cachePartition implements [Cache] by recording into a [CacheVec] with fixed labels
+type cachePartition struct {
+	CacheVec
+	labels VecLabels
+}
+
+func (p *cachePartition) Hit(ctx Context) error {
+	return p.CacheVec.Hit(ctx, p.labels)
+}
+
+func (p *cachePartition) Miss(ctx Context) error {
+	return p.CacheVec.Miss(ctx, p.labels)
+}
+
+func (p *cachePartition) SetSize(ctx Context, bytes int64) error {
+	return p.CacheVec.SetSize(ctx, bytes, p.labels)
+}
+
+// poolPartition implements [Pool] by recording into a [PoolVec] with fixed labels
+type poolPartition struct {
+	PoolVec
+	labels VecLabels
+}
+
+func (p *poolPartition) SetActive(ctx Context, count int) error {
+	return p.PoolVec.SetActive(ctx, count, p.labels)
+}
+
+func (p *poolPartition) SetIdle(ctx Context, count int) error {
+	return p.PoolVec.SetIdle(ctx, count, p.labels)
+}
+
+func (p *poolPartition) Acquired(ctx Context) error {
+	return p.PoolVec.Acquired(ctx, p.labels)
+}
+
+func (p *poolPartition) Released(ctx Context) error {
+	return p.PoolVec.Released(ctx, p.labels)
+}
+
+// circuitBreakerPartition implements [CircuitBreaker] by recording into a
+// [CircuitBreakerVec] with fixed labels
+type circuitBreakerPartition struct {
+	CircuitBreakerVec
+	labels VecLabels
+}
+
+func (p *circuitBreakerPartition) SetState(ctx Context, state CircuitBreakerState) error {
+	return p.CircuitBreakerVec.SetState(ctx, state, p.labels)
+}
+
+func (p *circuitBreakerPartition) Success(ctx Context) error {
+	return p.CircuitBreakerVec.Success(ctx, p.labels)
+}
+
+func (p *circuitBreakerPartition) Failure(ctx Context) error {
+	return p.CircuitBreakerVec.Failure(ctx, p.labels)
+}
+
+// queuePartition implements [Queue] by recording into a [QueueVec] with fixed labels
+type queuePartition struct {
+	QueueVec
+	labels VecLabels
+}
+
+func (p *queuePartition) SetDepth(ctx Context, depth int) error {
+	return p.QueueVec.SetDepth(ctx, depth, p.labels)
+}
+
+func (p *queuePartition) Enqueued(ctx Context) error {
+	return p.QueueVec.Enqueued(ctx, p.labels)
+}
+
+func (p *queuePartition) Dequeued(ctx Context) error {
+	return p.QueueVec.Dequeued(ctx, p.labels)
+}
+
+func (p *queuePartition) ObserveWait(ctx Context, duration time.Duration) error {
+	return p.QueueVec.ObserveWait(ctx, duration, p.labels)
+}
+
+func (p *queuePartition) DequeuedWithWait(ctx Context, waited time.Duration) error {
+	return p.QueueVec.DequeuedWithWait(ctx, waited, p.labels)
+}
+
+func (p *queuePartition) Enqueue(ctx Context) error {
+	return p.QueueVec.Enqueue(ctx, p.labels)
+}
+
+func (p *queuePartition) Dequeue(ctx Context, waited time.Duration) error {
+	return p.QueueVec.Dequeue(ctx, waited, p.labels)
+}
+
+func (p *queuePartition) SetWaitTime(ctx Context, duration time.Duration) error {
+	return p.ObserveWait(ctx, duration)
+}
+
+var (
+	__ctc_timerPartition          Timer          = (*timerPartition)(nil)
+	__ctc_cachePartition          Cache          = (*cachePartition)(nil)
+	__ctc_poolPartition           Pool           = (*poolPartition)(nil)
+	__ctc_circuitBreakerPartition CircuitBreaker = (*circuitBreakerPartition)(nil)
+	__ctc_queuePartition          Queue          = (*queuePartition)(nil)
+)
@@ -0,0 +1,81 @@
+package umami
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueueVecPartition(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug).NewGroup("test", backend)
+	ctx := group.Context()
+
+	labels := []string{"queue"}
+	queueVec := group.QueueVec(QueueVecOpts{
+		MetricInfo:      MetricInfo{Name: "queue"},
+		DepthVecOpts:    GaugeVecOpts{MetricInfo: MetricInfo{Name: "queue_depth"}, Labels: labels},
+		EnqueuedVecOpts: CounterVecOpts{MetricInfo: MetricInfo{Name: "queue_enqueued"}, Labels: labels},
+		DequeuedVecOpts: CounterVecOpts{MetricInfo: MetricInfo{Name: "queue_dequeued"}, Labels: labels},
+		WaitTimeVecOpts: HistogramVecOpts{MetricInfo: MetricInfo{Name: "queue_wait"}, Labels: labels},
+	}, LevelImportant)
+
+	jobs := queueVec.Partition(VecLabels{"queue": "jobs"})
+	_ = jobs.Enqueue(ctx)
+	_ = jobs.Enqueue(ctx)
+	_ = jobs.Dequeue(ctx, time.Second)
+	_ = queueVec.Partition(VecLabels{"queue": "mail"}).Enqueue(ctx)
+
+	jobLabels, mailLabels := VecLabels{"queue": "jobs"}, VecLabels{"queue": "mail"}
+	depth := backend.adapter("test_queue_depth").(*mockGaugeVecAdapter)
+	enqueued := backend.adapter("test_queue_enqueued").(*mockCounterVecAdapter)
+	wait := backend.adapter("test_queue_wait").(*mockHistogramVecAdapter)
+	if got := depth.GetValue(jobLabels); got != 1 {
+		t.Errorf("Expected jobs depth 1, got %v", got)
+	}
+	if got := enqueued.GetCount(jobLabels); got != 2 {
+		t.Errorf("Expected 2 jobs enqueued, got %v", got)
+	}
+	if got := wait.GetObservationCount(jobLabels); got != 1 {
+		t.Errorf("Expected 1 jobs wait observation, got %v", got)
+	}
+	if got := enqueued.GetCount(mailLabels); got != 1 || depth.GetValue(mailLabels) != 1 {
+		t.Errorf("Expected partitions to record into their own series, got %v mail enqueued", got)
+	}
+
+	if jobs.Name() != queueVec.Name() || jobs.Level() != queueVec.Level() {
+		t.Error("Expected a partition to have the name and level of its vec")
+	}
+}
+
+func TestPartitionFollowsLevelSwitch(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelImportant).NewGroup("test", backend)
+	ctx := group.Context()
+
+	labels := []string{"pool"}
+	poolVec := group.PoolVec(PoolVecOpts{
+		MetricInfo:      MetricInfo{Name: "pool"},
+		ActiveVecOpts:   GaugeVecOpts{MetricInfo: MetricInfo{Name: "pool_active"}, Labels: labels},
+		IdleVecOpts:     GaugeVecOpts{MetricInfo: MetricInfo{Name: "pool_idle"}, Labels: labels},
+		AcquiredVecOpts: CounterVecOpts{MetricInfo: MetricInfo{Name: "pool_acquired"}, Labels: labels},
+		ReleasedVecOpts: CounterVecOpts{MetricInfo: MetricInfo{Name: "pool_released"}, Labels: labels},
+	}, LevelDebug)
+
+	// Created while the vec is a noop
+	db := poolVec.Partition(VecLabels{"pool": "db"})
+	_ = db.Acquired(ctx)
+	if backend.adapter("test_pool_acquired") != nil {
+		t.Fatal("Expected no adapter for a noop")
+	}
+
+	poolVec.SetLevel(LevelImportant)
+	_ = db.Acquired(ctx)
+	_ = db.SetActive(ctx, 3)
+
+	if got := backend.adapter("test_pool_acquired").(*mockCounterVecAdapter).GetCount(VecLabels{"pool": "db"}); got != 1 {
+		t.Errorf("Expected the partition to record after the switch, got %v", got)
+	}
+	if got := backend.adapter("test_pool_active").(*mockGaugeVecAdapter).GetValue(VecLabels{"pool": "db"}); got != 3 {
+		t.Errorf("Expected active 3, got %v", got)
+	}
+}
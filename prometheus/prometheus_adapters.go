@@ -2,6 +2,8 @@ package umami_prometheus
 
 import (
 	"fmt"
+	"slices"
+	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
@@ -23,20 +25,140 @@ func (pca *prCounterAdapter) Add(value float64) error {
 	return nil
 }
 
+// AddWithExemplar adds value with an exemplar, see [umami.ExemplarCounterAdapter]
+func (pca *prCounterAdapter) AddWithExemplar(value float64, exemplar umami.VecLabels) error {
+	adder, ok := pca.internal.(prometheus.ExemplarAdder)
+	if !ok {
+		return pca.Add(value)
+	}
+	return withExemplar(func() { adder.AddWithExemplar(value, prometheus.Labels(exemplar)) })
+}
+
+// Value reads the current value of the counter back from the collector
+func (pca *prCounterAdapter) Value() (float64, error) {
+	series := &dto.Metric{}
+	if err := pca.internal.Write(series); err != nil {
+		return 0, err
+	}
+	return series.GetCounter().GetValue(), nil
+}
+
+// resetOnScrapeCounter is a collector and [umami.CounterAdapter] reporting the
+// increase of a counter since the previous collection, see
+// [umami.CounterOpts.ResetOnScrape]
+type resetOnScrapeCounter struct {
+	desc *prometheus.Desc
+
+	mu    sync.Mutex
+	value float64
+}
+
+func newResetOnScrapeCounter(desc *prometheus.Desc) *resetOnScrapeCounter {
+	return &resetOnScrapeCounter{desc: desc}
+}
+
+func (c *resetOnScrapeCounter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect reports the current value and zeros it
+func (c *resetOnScrapeCounter) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	value := c.value
+	c.value = 0
+	c.mu.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.CounterValue, value)
+}
+
+func (c *resetOnScrapeCounter) Inc() error {
+	return c.Add(1)
+}
+
+func (c *resetOnScrapeCounter) Add(value float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.value += value
+	return nil
+}
+
+// Value returns the increase since the previous collection, without zeroing it
+func (c *resetOnScrapeCounter) Value() (float64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.value, nil
+}
+
 type prCounterVecAdapter struct {
 	internal *prometheus.CounterVec
 }
 
 func (pcva *prCounterVecAdapter) Inc(labels umami.VecLabels) error {
-	pcva.internal.With(prometheus.Labels(labels)).Inc()
+	metric, err := pcva.internal.GetMetricWith(prometheus.Labels(labels))
+	if err != nil {
+		return labelError(err)
+	}
+	metric.Inc()
 	return nil
 }
 
+// Series collects the series of the vec, see [umami.SeriesAdapter]
+func (pcva *prCounterVecAdapter) Series() ([]umami.Sample, error) {
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		pcva.internal.Collect(metrics)
+		close(metrics)
+	}()
+
+	var series []umami.Sample
+	var err error
+	for metric := range metrics {
+		m := &dto.Metric{}
+		if writeErr := metric.Write(m); writeErr != nil {
+			err = writeErr
+			continue
+		}
+		series = append(series, gatherSample(m))
+	}
+	return series, err
+}
+
+// InitLabels creates the series of the labels at 0
+func (pcva *prCounterVecAdapter) InitLabels(labels umami.VecLabels) error {
+	_, err := pcva.internal.GetMetricWith(prometheus.Labels(labels))
+	return labelError(err)
+}
+
+// WithLabelValues binds the series of the positional label values, see
+// [umami.LabelValuesAdapter]
+func (pcva *prCounterVecAdapter) WithLabelValues(values ...string) (umami.CounterAdapter, error) {
+	counter, err := pcva.internal.GetMetricWithLabelValues(values...)
+	if err != nil {
+		return nil, labelError(err)
+	}
+	return &prCounterAdapter{internal: counter}, nil
+}
+
 func (pcva *prCounterVecAdapter) Add(value float64, labels umami.VecLabels) error {
-	pcva.internal.With(prometheus.Labels(labels)).Add(value)
+	metric, err := pcva.internal.GetMetricWith(prometheus.Labels(labels))
+	if err != nil {
+		return labelError(err)
+	}
+	metric.Add(value)
 	return nil
 }
 
+// Delete removes the series of the labels, see [umami.DeleteAdapter]
+func (pcva *prCounterVecAdapter) Delete(labels umami.VecLabels) bool {
+	return pcva.internal.Delete(prometheus.Labels(labels))
+}
+
+func (pcva *prCounterVecAdapter) Reset() {
+	pcva.internal.Reset()
+}
+
 type prGaugeAdapter struct {
 	internal prometheus.Gauge
 }
@@ -61,30 +183,69 @@ func (pga *prGaugeAdapter) Dec() error {
 	return nil
 }
 
+// Value reads the current value of the gauge back from the collector
+func (pga *prGaugeAdapter) Value() (float64, error) {
+	series := &dto.Metric{}
+	if err := pga.internal.Write(series); err != nil {
+		return 0, err
+	}
+	return series.GetGauge().GetValue(), nil
+}
+
 type prGaugeVecAdapter struct {
 	internal *prometheus.GaugeVec
 }
 
 func (pgva *prGaugeVecAdapter) Set(value float64, labels umami.VecLabels) error {
-	pgva.internal.With(prometheus.Labels(labels)).Set(value)
+	metric, err := pgva.internal.GetMetricWith(prometheus.Labels(labels))
+	if err != nil {
+		return labelError(err)
+	}
+	metric.Set(value)
 	return nil
 }
 
+// InitLabels creates the series of the labels at 0
+func (pgva *prGaugeVecAdapter) InitLabels(labels umami.VecLabels) error {
+	_, err := pgva.internal.GetMetricWith(prometheus.Labels(labels))
+	return labelError(err)
+}
+
 func (pgva *prGaugeVecAdapter) Add(value float64, labels umami.VecLabels) error {
-	pgva.internal.With(prometheus.Labels(labels)).Add(value)
+	metric, err := pgva.internal.GetMetricWith(prometheus.Labels(labels))
+	if err != nil {
+		return labelError(err)
+	}
+	metric.Add(value)
 	return nil
 }
 
 func (pgva *prGaugeVecAdapter) Inc(labels umami.VecLabels) error {
-	pgva.internal.With(prometheus.Labels(labels)).Inc()
+	metric, err := pgva.internal.GetMetricWith(prometheus.Labels(labels))
+	if err != nil {
+		return labelError(err)
+	}
+	metric.Inc()
 	return nil
 }
 
 func (pgva *prGaugeVecAdapter) Dec(labels umami.VecLabels) error {
-	pgva.internal.With(prometheus.Labels(labels)).Dec()
+	metric, err := pgva.internal.GetMetricWith(prometheus.Labels(labels))
+	if err != nil {
+		return labelError(err)
+	}
+	metric.Dec()
 	return nil
 }
 
+func (pgva *prGaugeVecAdapter) Delete(labels umami.VecLabels) bool {
+	return pgva.internal.Delete(prometheus.Labels(labels))
+}
+
+func (pgva *prGaugeVecAdapter) Reset() {
+	pgva.internal.Reset()
+}
+
 type prHistogramAdapter struct {
 	internal prometheus.Histogram
 }
@@ -94,15 +255,43 @@ func (pha *prHistogramAdapter) Observe(value float64) error {
 	return nil
 }
 
+// ObserveWithExemplar observes value with an exemplar, see
+// [umami.ExemplarHistogramAdapter]
+func (pha *prHistogramAdapter) ObserveWithExemplar(value float64, exemplar umami.VecLabels) error {
+	observer, ok := pha.internal.(prometheus.ExemplarObserver)
+	if !ok {
+		return pha.Observe(value)
+	}
+	return withExemplar(func() { observer.ObserveWithExemplar(value, prometheus.Labels(exemplar)) })
+}
+
 type prHistogramVecAdapter struct {
 	internal *prometheus.HistogramVec
 }
 
 func (phva *prHistogramVecAdapter) Observe(value float64, labels umami.VecLabels) error {
-	phva.internal.With(prometheus.Labels(labels)).Observe(value)
+	metric, err := phva.internal.GetMetricWith(prometheus.Labels(labels))
+	if err != nil {
+		return labelError(err)
+	}
+	metric.Observe(value)
 	return nil
 }
 
+// InitLabels creates the series of the labels without observations
+func (phva *prHistogramVecAdapter) InitLabels(labels umami.VecLabels) error {
+	_, err := phva.internal.GetMetricWith(prometheus.Labels(labels))
+	return labelError(err)
+}
+
+func (phva *prHistogramVecAdapter) Delete(labels umami.VecLabels) bool {
+	return phva.internal.Delete(prometheus.Labels(labels))
+}
+
+func (phva *prHistogramVecAdapter) Reset() {
+	phva.internal.Reset()
+}
+
 type prSummaryAdapter struct {
 	internal prometheus.Summary
 }
@@ -113,21 +302,7 @@ func (psa *prSummaryAdapter) Observe(value float64) error {
 }
 
 func (psa *prSummaryAdapter) Quantile(q float64) (float64, error) {
-	mfs := make(chan prometheus.Metric, 1)
-	psa.internal.Collect(mfs)
-	close(mfs)
-	for metric := range mfs {
-		dto := &dto.Metric{}
-		if err := metric.Write(dto); err != nil {
-			return 0, err
-		}
-		for _, quantile := range dto.Summary.Quantile {
-			if quantile.GetQuantile() == q {
-				return quantile.GetValue(), nil
-			}
-		}
-	}
-	return 0, fmt.Errorf("Quantile %f not found", q)
+	return summaryQuantile(psa.internal, q, nil)
 }
 
 type prSummaryVecAdapter struct {
@@ -135,32 +310,118 @@ type prSummaryVecAdapter struct {
 }
 
 func (m *prSummaryVecAdapter) Observe(value float64, labels umami.VecLabels) error {
-	m.internal.With(prometheus.Labels(labels)).Observe(value)
+	summary, err := m.internal.GetMetricWith(prometheus.Labels(labels))
+	if err != nil {
+		return labelError(err)
+	}
+	summary.Observe(value)
 	return nil
 }
 
+// InitLabels creates the series of the labels without observations
+func (m *prSummaryVecAdapter) InitLabels(labels umami.VecLabels) error {
+	_, err := m.internal.GetMetricWith(prometheus.Labels(labels))
+	return labelError(err)
+}
+
+// Quantile returns the quantile q of the series of the labels, without
+// creating the series if it doesn't exist
 func (m *prSummaryVecAdapter) Quantile(q float64, labels umami.VecLabels) (float64, error) {
-	curried, err := m.internal.CurryWith(prometheus.Labels(labels))
-	if err != nil {
-		return 0, err
+	// Currying validates the label names, but a curried vec still collects
+	// every series of the vec, so the series is selected by its labels.
+	if _, err := m.internal.CurryWith(prometheus.Labels(labels)); err != nil {
+		return 0, labelError(err)
+	}
+
+	return summaryQuantile(m.internal, q, labels)
+}
+
+// withExemplar calls record, which records a value with an exemplar, and
+// returns an error wrapping [ErrInvalidExemplar] if the Prometheus client
+// panics because the exemplar is invalid, e.g. its labels are too long
+func withExemplar(record func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrInvalidExemplar, r)
+		}
+	}()
+	record()
+	return nil
+}
+
+// labelError wraps an error of GetMetricWith or CurryWith of a vec, which is
+// caused by labels not matching the label names of the vec, with
+// [umami.ErrLabelMismatch]. Returns nil for a nil err.
+func labelError(err error) error {
+	if err == nil {
+		return nil
 	}
+	return fmt.Errorf("%w: %w", umami.ErrLabelMismatch, err)
+}
 
-	mfs := make(chan prometheus.Metric, 1)
-	curried.Collect(mfs)
-	close(mfs)
+// summaryQuantile returns the quantile q of the first summary series collected
+// from collector that has all of the labels.
+//
+// Returns an error wrapping [umami.ErrQuantileNotFound] if no series has the
+// labels, or q is not one of its objectives.
+func summaryQuantile(collector prometheus.Collector, q float64, labels umami.VecLabels) (float64, error) {
+	// Collect blocks until every series is sent, so it must run concurrently
+	// with the receiving loop, which drains the channel even once found.
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		collector.Collect(metrics)
+		close(metrics)
+	}()
+
+	var (
+		value float64
+		err   = fmt.Errorf("%w: %v of %v", umami.ErrQuantileNotFound, q, labels)
+		found bool
+	)
+	for metric := range metrics {
+		if found {
+			continue
+		}
 
-	for metric := range mfs {
-		dto := &dto.Metric{}
-		if err := metric.Write(dto); err != nil {
-			return 0, err
+		series := &dto.Metric{}
+		if writeErr := metric.Write(series); writeErr != nil {
+			value, err, found = 0, writeErr, true
+			continue
+		}
+		if !hasLabels(series, labels) {
+			continue
 		}
-		for _, quantile := range dto.Summary.Quantile {
+
+		found = true
+		for _, quantile := range series.GetSummary().GetQuantile() {
 			if quantile.GetQuantile() == q {
-				return quantile.GetValue(), nil
+				value, err = quantile.GetValue(), nil
+				break
 			}
 		}
 	}
-	return 0, fmt.Errorf("Quantile %f not found", q)
+
+	return value, err
+}
+
+// hasLabels returns true if series has every one of the labels
+func hasLabels(series *dto.Metric, labels umami.VecLabels) bool {
+	for name, value := range labels {
+		if !slices.ContainsFunc(series.GetLabel(), func(pair *dto.LabelPair) bool {
+			return pair.GetName() == name && pair.GetValue() == value
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *prSummaryVecAdapter) Delete(labels umami.VecLabels) bool {
+	return m.internal.Delete(prometheus.Labels(labels))
+}
+
+func (m *prSummaryVecAdapter) Reset() {
+	m.internal.Reset()
 }
 
 // Sanity checks for interface implementation
@@ -171,4 +432,21 @@ var (
 	_pGaugeVecBackend     umami.GaugeVecAdapter     = (*prGaugeVecAdapter)(nil)
 	_pHistogramBackend    umami.HistogramAdapter    = (*prHistogramAdapter)(nil)
 	_pHistogramVecBackend umami.HistogramVecAdapter = (*prHistogramVecAdapter)(nil)
+	_pSummaryBackend      umami.SummaryAdapter      = (*prSummaryAdapter)(nil)
+	_pSummaryVecBackend   umami.SummaryVecAdapter   = (*prSummaryVecAdapter)(nil)
+
+	_pCounterVecInit   umami.VecInitAdapter = (*prCounterVecAdapter)(nil)
+	_pGaugeVecInit     umami.VecInitAdapter = (*prGaugeVecAdapter)(nil)
+	_pHistogramVecInit umami.VecInitAdapter = (*prHistogramVecAdapter)(nil)
+	_pSummaryVecInit   umami.VecInitAdapter = (*prSummaryVecAdapter)(nil)
+
+	_pCounterVecSeries umami.SeriesAdapter = (*prCounterVecAdapter)(nil)
+
+	_pCounterVecDelete   umami.DeleteAdapter = (*prCounterVecAdapter)(nil)
+	_pGaugeVecDelete     umami.DeleteAdapter = (*prGaugeVecAdapter)(nil)
+	_pHistogramVecDelete umami.DeleteAdapter = (*prHistogramVecAdapter)(nil)
+	_pSummaryVecDelete   umami.DeleteAdapter = (*prSummaryVecAdapter)(nil)
+
+	_pCounterValue umami.ValueAdapter = (*prCounterAdapter)(nil)
+	_pGaugeValue   umami.ValueAdapter = (*prGaugeAdapter)(nil)
 )
@@ -4,7 +4,15 @@ package umami_prometheus
 // No support for V1 exists for now
 
 import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"regexp"
+	"sync"
+
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 
 	"github.com/SimonDaKappa/go-umami"
 )
@@ -13,110 +21,404 @@ const (
 	PrometheusBackendName string = "prometheus"
 )
 
+// strictMetricNameRe matches metric names accepted by the backend by default.
+// Colons are excluded, as Prometheus reserves them for recording rules.
+var strictMetricNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ErrNameTruncated is the warning reported when a metric name longer than the
+// limit set by [WithMaxNameLength] is truncated
+var ErrNameTruncated = errors.New("umami_prometheus: metric name truncated")
+
+// ErrInvalidExemplar is returned when an exemplar is rejected by the
+// Prometheus client, e.g. because its labels are longer than 128 runes, see
+// [umami.Counter.AddWithExemplar]. The value is recorded without it.
+var ErrInvalidExemplar = errors.New("umami_prometheus: invalid exemplar")
+
+// nameHashLength is the length of the suffix of truncated names, an
+// underscore followed by the 8 hex digits of the hash of the full name
+const nameHashLength = 9
+
+// Option configures optional behavior of the Prometheus backend
+type Option func(*prometheusBackend)
+
+// WithVerbatimNames disables the backend's metric name validation, passing
+// names through to Prometheus verbatim, e.g. names with colons that legacy
+// dashboards expect from recording rules.
+//
+// This is meant for advanced users. Names are still subject to the
+// validation of the Prometheus client itself.
+func WithVerbatimNames() Option {
+	return func(p *prometheusBackend) {
+		p.verbatimNames = true
+	}
+}
+
+// WithMaxNameLength truncates the metric names longer than length runes to length
+// runes, replacing their end with a hash of the full name, so that names
+// sharing a long prefix stay distinct, e.g. the components of a composite of
+// a group with a long name. Truncation is deterministic, so a metric keeps
+// its name across restarts. Each truncation is reported to the handler set
+// by [WithWarningHandler] with an error wrapping [ErrNameTruncated].
+//
+// Names are not limited by default. It panics if length is too short to hold
+// the hash, i.e. shorter than 16.
+func WithMaxNameLength(length int) Option {
+	if length < 16 {
+		panic(fmt.Sprintf("umami_prometheus: max name length %d is shorter than 16", length))
+	}
+	return func(p *prometheusBackend) {
+		p.maxNameLength = length
+	}
+}
+
+// WithWarningHandler sets a function called with the non-fatal problems of
+// the backend, e.g. [ErrNameTruncated], to log them. Warnings are dropped by
+// default.
+func WithWarningHandler(fn func(err error)) Option {
+	return func(p *prometheusBackend) {
+		p.onWarning = fn
+	}
+}
+
+// ScopedBackend is the Prometheus [umami.Backend], which can create scoped
+// views of itself, see [ScopedBackend.WithLabels]
+type ScopedBackend interface {
+	umami.Backend
+
+	// WithLabels returns a view of the backend whose metrics carry labels as
+	// constant labels, e.g. a "tenant" label per tenant of a multi-tenant
+	// process. Metrics are registered with the same registry, wrapped with
+	// [prometheus.WrapRegistererWith], so views of different label values
+	// create series of the same metrics differing only by those labels.
+	//
+	// Each view creates its own metrics, so the metric names of views are
+	// usually shared by creating a [umami.Registry] and [umami.Group] per view.
+	// Label names must not clash with the variable labels of vec metrics, or
+	// creating them panics, like registering with Prometheus.
+	//
+	// Views gather the whole registry, including the metrics of other views.
+	WithLabels(labels umami.VecLabels) ScopedBackend
+}
+
 // Mock backend for demonstration
 type prometheusBackend struct {
-	registry *prometheus.Registry
+	registry   *prometheus.Registry
+	registerer prometheus.Registerer // The registry, wrapped with the labels of a view
+
+	verbatimNames bool        // If true, metric names are not validated
+	maxNameLength int         // Length beyond which names are truncated, if set
+	onWarning     func(error) // Called with non-fatal problems, if set
+
+	mu         sync.Mutex
+	collectors map[string]prometheus.Collector // Map of qualified metric name to registered collector
 }
 
-func NewPrometheusBackend(reg *prometheus.Registry) umami.Backend {
+// NewPrometheusBackend creates a Prometheus backend registering its metrics
+// with reg, and optional [Option]s
+//
+// By default, metric names must consist of ASCII letters, digits and
+// underscores, and not start with a digit. Constructing a metric with any
+// other name panics. See [WithVerbatimNames].
+func NewPrometheusBackend(reg *prometheus.Registry, opts ...Option) ScopedBackend {
+	p := &prometheusBackend{
+		registry:   reg,
+		registerer: reg,
+		collectors: make(map[string]prometheus.Collector),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// WithLabels returns a view of the backend registering its metrics with labels
+func (p *prometheusBackend) WithLabels(labels umami.VecLabels) ScopedBackend {
 	return &prometheusBackend{
-		registry: reg,
+		registry:      p.registry,
+		registerer:    prometheus.WrapRegistererWith(prometheus.Labels(labels), p.registerer),
+		verbatimNames: p.verbatimNames,
+		maxNameLength: p.maxNameLength,
+		onWarning:     p.onWarning,
+		collectors:    make(map[string]prometheus.Collector),
+	}
+}
+
+// getOrRegister returns the collector already registered by this backend for
+// the metric of the qualified name if it is of the same type as collector. Otherwise, collector
+// is registered, panicking like [prometheus.Registry.MustRegister] on failure.
+// Invalid metric names panic as well, unless [WithVerbatimNames] is set.
+//
+// This makes repeated construction of the same metric idempotent, e.g. when a
+// group is recreated on the same backend. Opts of the repeated construction,
+// such as help or buckets, are ignored in that case.
+func getOrRegister[C prometheus.Collector](p *prometheusBackend, name string, collector C) C {
+	if !p.verbatimNames && !strictMetricNameRe.MatchString(name) {
+		panic(fmt.Sprintf("umami_prometheus: invalid metric name %q (see WithVerbatimNames)", name))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if existing, ok := p.collectors[name].(C); ok && reflect.TypeOf(existing) == reflect.TypeOf(collector) {
+		return existing
+	}
+
+	p.registerer.MustRegister(collector)
+	p.collectors[name] = collector
+	if truncated := p.metricName(name); truncated != name && p.onWarning != nil {
+		p.onWarning(fmt.Errorf("%w: %q to %q", ErrNameTruncated, name, truncated))
+	}
+	return collector
+}
+
+// metricName returns the name of the Prometheus metric of the umami metric of
+// the qualified name (see [umami.MetricInfo.QualifiedName]), truncated if it is
+// longer than [WithMaxNameLength] allows. The namespace and subsystem are part
+// of the truncated name, rather than passed to Prometheus, so that they count
+// towards the limit.
+//
+// Collectors are still tracked by the full qualified name, which umami knows
+// them by, e.g. to unregister them.
+func (p *prometheusBackend) metricName(name string) string {
+	runes := []rune(name)
+	if p.maxNameLength == 0 || len(runes) <= p.maxNameLength {
+		return name
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	return fmt.Sprintf("%s_%08x", string(runes[:p.maxNameLength-nameHashLength]), hash.Sum32())
+}
+
+// Unregister unregisters the collector of the metric of the qualified name from
+// the registry
+func (p *prometheusBackend) Unregister(name string) bool {
+	p.mu.Lock()
+	collector, exists := p.collectors[name]
+	delete(p.collectors, name)
+	p.mu.Unlock()
+
+	if !exists {
+		return false
 	}
+	return p.registerer.Unregister(collector)
+}
+
+// Gather gathers the registry, converting the families of the metric types
+// supported by umami to [umami.MetricFamily]s. Other families are skipped.
+func (p *prometheusBackend) Gather() ([]umami.MetricFamily, error) {
+	mfs, err := p.registry.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	families := make([]umami.MetricFamily, 0, len(mfs))
+	for _, mf := range mfs {
+		family := umami.MetricFamily{
+			Name: mf.GetName(),
+			Help: mf.GetHelp(),
+		}
+
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			family.Kind = umami.MetricKindCounter
+		case dto.MetricType_GAUGE:
+			family.Kind = umami.MetricKindGauge
+		case dto.MetricType_HISTOGRAM:
+			family.Kind = umami.MetricKindHistogram
+		case dto.MetricType_SUMMARY:
+			family.Kind = umami.MetricKindSummary
+		default:
+			continue
+		}
+
+		for _, m := range mf.GetMetric() {
+			family.Samples = append(family.Samples, gatherSample(m))
+		}
+		families = append(families, family)
+	}
+
+	return families, nil
+}
+
+// gatherSample converts a gathered metric to a [umami.Sample]
+func gatherSample(m *dto.Metric) umami.Sample {
+	var sample umami.Sample
+
+	if pairs := m.GetLabel(); len(pairs) > 0 {
+		sample.Labels = make(umami.VecLabels, len(pairs))
+		for _, pair := range pairs {
+			sample.Labels[pair.GetName()] = pair.GetValue()
+		}
+	}
+
+	switch {
+	case m.Counter != nil:
+		sample.Value = m.GetCounter().GetValue()
+	case m.Gauge != nil:
+		sample.Value = m.GetGauge().GetValue()
+	case m.Histogram != nil:
+		sample.Count = m.GetHistogram().GetSampleCount()
+		sample.Sum = m.GetHistogram().GetSampleSum()
+	case m.Summary != nil:
+		sample.Count = m.GetSummary().GetSampleCount()
+		sample.Sum = m.GetSummary().GetSampleSum()
+	}
+
+	return sample
 }
 
 func (p *prometheusBackend) Counter(opts umami.CounterOpts) umami.CounterAdapter {
-	counter := prometheus.NewCounter(
+	name := opts.QualifiedName()
+	if opts.ResetOnScrape {
+		return getOrRegister(p, name, newResetOnScrapeCounter(
+			prometheus.NewDesc(p.metricName(name), opts.Help, nil, opts.ConstLabels),
+		))
+	}
+
+	counter := getOrRegister(p, name, prometheus.NewCounter(
 		prometheus.CounterOpts{
-			Name: opts.Name,
-			Help: opts.Help,
+			Name:        p.metricName(name),
+			ConstLabels: opts.ConstLabels,
+			Help:        opts.Help,
 		},
-	)
-	p.registry.MustRegister(counter)
+	))
 	return &prCounterAdapter{internal: counter}
 }
 
 func (p *prometheusBackend) CounterVec(opts umami.CounterVecOpts) umami.CounterVecAdapter {
-	counterVec := prometheus.NewCounterVec(
+	name := opts.QualifiedName()
+	counterVec := getOrRegister(p, name, prometheus.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: opts.Name,
-			Help: opts.Help,
+			Name:        p.metricName(name),
+			ConstLabels: opts.ConstLabels,
+			Help:        opts.Help,
 		},
 		opts.Labels,
-	)
-	p.registry.MustRegister(counterVec)
+	))
 	return &prCounterVecAdapter{internal: counterVec}
 }
 
 func (p *prometheusBackend) Gauge(opts umami.GaugeOpts) umami.GaugeAdapter {
-	gauge := prometheus.NewGauge(
+	name := opts.QualifiedName()
+	gauge := getOrRegister(p, name, prometheus.NewGauge(
 		prometheus.GaugeOpts{
-			Name: opts.Name,
-			Help: opts.Help,
+			Name:        p.metricName(name),
+			ConstLabels: opts.ConstLabels,
+			Help:        opts.Help,
 		},
-	)
-	p.registry.MustRegister(gauge)
+	))
 	return &prGaugeAdapter{internal: gauge}
 }
 
+// GaugeFunc registers a gauge whose value is read from fn at scrape time
+func (p *prometheusBackend) GaugeFunc(opts umami.GaugeOpts, fn func() float64) {
+	name := opts.QualifiedName()
+	getOrRegister(p, name, prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name:        p.metricName(name),
+			ConstLabels: opts.ConstLabels,
+			Help:        opts.Help,
+		},
+		fn,
+	))
+}
+
+// CounterFunc registers a counter whose value is read from fn at scrape time
+func (p *prometheusBackend) CounterFunc(opts umami.CounterOpts, fn func() float64) {
+	name := opts.QualifiedName()
+	getOrRegister(p, name, prometheus.NewCounterFunc(
+		prometheus.CounterOpts{
+			Name:        p.metricName(name),
+			ConstLabels: opts.ConstLabels,
+			Help:        opts.Help,
+		},
+		fn,
+	))
+}
+
 func (p *prometheusBackend) GaugeVec(opts umami.GaugeVecOpts) umami.GaugeVecAdapter {
-	gaugeVec := prometheus.NewGaugeVec(
+	name := opts.QualifiedName()
+	gaugeVec := getOrRegister(p, name, prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
-			Name: opts.Name,
-			Help: opts.Help,
+			Name:        p.metricName(name),
+			ConstLabels: opts.ConstLabels,
+			Help:        opts.Help,
 		},
 		opts.Labels,
-	)
-	p.registry.MustRegister(gaugeVec)
+	))
 	return &prGaugeVecAdapter{internal: gaugeVec}
 }
 
 func (p *prometheusBackend) Histogram(opts umami.HistogramOpts) umami.HistogramAdapter {
-	histogram := prometheus.NewHistogram(
+	name := opts.QualifiedName()
+	histogram := getOrRegister(p, name, prometheus.NewHistogram(
 		prometheus.HistogramOpts{
-			Name:    opts.Name,
-			Help:    opts.Help,
-			Buckets: opts.Buckets,
+			Name:        p.metricName(name),
+			ConstLabels: opts.ConstLabels,
+			Help:        opts.Help,
+			Buckets:     histogramBuckets(opts.Buckets, opts.NativeHistogramBucketFactor),
+
+			NativeHistogramBucketFactor:    opts.NativeHistogramBucketFactor,
+			NativeHistogramMaxBucketNumber: opts.NativeHistogramMaxBucketNumber,
 		},
-	)
-	p.registry.MustRegister(histogram)
+	))
 	return &prHistogramAdapter{internal: histogram}
 }
 
 func (p *prometheusBackend) HistogramVec(opts umami.HistogramVecOpts) umami.HistogramVecAdapter {
-	histogramVec := prometheus.NewHistogramVec(
+	name := opts.QualifiedName()
+	histogramVec := getOrRegister(p, name, prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name:    opts.Name,
-			Help:    opts.Help,
-			Buckets: opts.Buckets,
+			Name:        p.metricName(name),
+			ConstLabels: opts.ConstLabels,
+			Help:        opts.Help,
+			Buckets:     histogramBuckets(opts.Buckets, opts.NativeHistogramBucketFactor),
+
+			NativeHistogramBucketFactor:    opts.NativeHistogramBucketFactor,
+			NativeHistogramMaxBucketNumber: opts.NativeHistogramMaxBucketNumber,
 		},
 		opts.Labels,
-	)
-	p.registry.MustRegister(histogramVec)
+	))
 	return &prHistogramVecAdapter{internal: histogramVec}
 }
 
+// histogramBuckets returns the classic buckets of a histogram, which are
+// dropped for a native histogram so that it is exported without them, see
+// [umami.HistogramOpts.NativeHistogramBucketFactor]
+func histogramBuckets(buckets []float64, nativeFactor float64) []float64 {
+	if nativeFactor > 1 {
+		return nil
+	}
+	return buckets
+}
+
 func (p *prometheusBackend) Summary(opts umami.SummaryOpts) umami.SummaryAdapter {
-	summary := prometheus.NewSummary(
+	name := opts.QualifiedName()
+	summary := getOrRegister(p, name, prometheus.NewSummary(
 		prometheus.SummaryOpts{
-			Name:       opts.Name,
-			Help:       opts.Help,
-			Objectives: opts.Objectives,
+			Name:        p.metricName(name),
+			ConstLabels: opts.ConstLabels,
+			Help:        opts.Help,
+			Objectives:  opts.Objectives,
 		},
-	)
-	p.registry.MustRegister(summary)
+	))
 	return &prSummaryAdapter{internal: summary}
 }
 
-func (p *prometheusBackend) SummaryVec(opts umami.SummaryVecOpts) umami.SummaryVecAdapater {
-	summaryVec := prometheus.NewSummaryVec(
+func (p *prometheusBackend) SummaryVec(opts umami.SummaryVecOpts) umami.SummaryVecAdapter {
+	name := opts.QualifiedName()
+	summaryVec := getOrRegister(p, name, prometheus.NewSummaryVec(
 		prometheus.SummaryOpts{
-			Name:       opts.Name,
-			Help:       opts.Help,
-			Objectives: opts.Objectives,
+			Name:        p.metricName(name),
+			ConstLabels: opts.ConstLabels,
+			Help:        opts.Help,
+			Objectives:  opts.Objectives,
 		},
 		opts.Labels,
-	)
-	p.registry.MustRegister(summaryVec)
+	))
 	return &prSummaryVecAdapter{internal: summaryVec}
 }
 
@@ -124,4 +426,11 @@ func (p *prometheusBackend) Name() string {
 	return PrometheusBackendName
 }
 
-var __ctc_prometheusBackend umami.Backend = (*prometheusBackend)(nil)
+var (
+	__ctc_prometheusBackend                   umami.Backend            = (*prometheusBackend)(nil)
+	__ctc_prometheusScopedBackend             ScopedBackend            = (*prometheusBackend)(nil)
+	__ctc_prometheusBackendGaugeFuncBackend   umami.GaugeFuncBackend   = (*prometheusBackend)(nil)
+	__ctc_prometheusBackendCounterFuncBackend umami.CounterFuncBackend = (*prometheusBackend)(nil)
+	__ctc_prometheusUnregisterBackend         umami.UnregisterBackend  = (*prometheusBackend)(nil)
+	__ctc_prometheusGatherBackend             umami.GatherBackend      = (*prometheusBackend)(nil)
+)
@@ -1,22 +1,29 @@
 package umami_prometheus
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 
 	"github.com/SimonDaKappa/go-umami"
+	"github.com/SimonDaKappa/go-umami/prometheus/promtest"
 )
 
 func TestManagerIntegrationWithPrometheusBackend(t *testing.T) {
 	reg := prometheus.NewRegistry()
 	backend := NewPrometheusBackend(reg)
-	manager := umami.NewRegistry(backend)
-	group := manager.Group("web")
-	factory := group.Factory()
+	manager := umami.NewRegistry(umami.LevelDebug)
+	factory := manager.NewGroup("web", backend)
 
-	counter := factory.Counter(umami.CounterOpts{Name: "integration_counter", Help: "integration counter"}, 0)
+	counter := factory.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "integration_counter", Help: "integration counter"}}, 0)
 	ctx := umami.NewContext(0)
 	if err := counter.Inc(ctx); err != nil {
 		t.Errorf("Manager integration Counter Inc failed: %v", err)
@@ -25,7 +32,7 @@ func TestManagerIntegrationWithPrometheusBackend(t *testing.T) {
 		t.Errorf("Manager integration Counter Add failed: %v", err)
 	}
 
-	gauge := factory.Gauge(umami.GaugeOpts{Name: "integration_gauge", Help: "integration gauge"}, 0)
+	gauge := factory.Gauge(umami.GaugeOpts{MetricInfo: umami.MetricInfo{Name: "integration_gauge", Help: "integration gauge"}}, 0)
 	if err := gauge.Set(ctx, 10); err != nil {
 		t.Errorf("Manager integration Gauge Set failed: %v", err)
 	}
@@ -39,12 +46,12 @@ func TestManagerIntegrationWithPrometheusBackend(t *testing.T) {
 		t.Errorf("Manager integration Gauge Add failed: %v", err)
 	}
 
-	hist := factory.Histogram(umami.HistogramOpts{Name: "integration_histogram", Help: "integration histogram", Buckets: []float64{0.1, 1, 10}}, 0)
+	hist := factory.Histogram(umami.HistogramOpts{MetricInfo: umami.MetricInfo{Name: "integration_histogram", Help: "integration histogram"}, Buckets: []float64{0.1, 1, 10}}, 0)
 	if err := hist.Observe(ctx, 0.7); err != nil {
 		t.Errorf("Manager integration Histogram Observe failed: %v", err)
 	}
 
-	summary := factory.Summary(umami.SummaryOpts{Name: "integration_summary", Help: "integration summary", Objectives: map[float64]float64{0.5: 0.05}}, 0)
+	summary := factory.Summary(umami.SummaryOpts{MetricInfo: umami.MetricInfo{Name: "integration_summary", Help: "integration summary"}, Objectives: map[float64]float64{0.5: 0.05}}, 0)
 	if err := summary.Observe(ctx, 1.5); err != nil {
 		t.Errorf("Manager integration Summary Observe failed: %v", err)
 	}
@@ -54,7 +61,7 @@ func TestManagerIntegrationWithPrometheusBackend(t *testing.T) {
 func TestPrometheusCounterBackend(t *testing.T) {
 	reg := prometheus.NewRegistry()
 	backend := NewPrometheusBackend(reg)
-	counter := backend.Counter(umami.CounterOpts{Name: "test_counter", Help: "test counter"})
+	counter := backend.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "test_counter", Help: "test counter"}})
 	if err := counter.Inc(); err != nil {
 		t.Errorf("Counter Inc failed: %v", err)
 	}
@@ -66,7 +73,7 @@ func TestPrometheusCounterBackend(t *testing.T) {
 func TestPrometheusGaugeBackend(t *testing.T) {
 	reg := prometheus.NewRegistry()
 	backend := NewPrometheusBackend(reg)
-	gauge := backend.Gauge(umami.GaugeOpts{Name: "test_gauge", Help: "test gauge"})
+	gauge := backend.Gauge(umami.GaugeOpts{MetricInfo: umami.MetricInfo{Name: "test_gauge", Help: "test gauge"}})
 	if err := gauge.Set(42); err != nil {
 		t.Errorf("Gauge Set failed: %v", err)
 	}
@@ -84,7 +91,7 @@ func TestPrometheusGaugeBackend(t *testing.T) {
 func TestPrometheusHistogramBackend(t *testing.T) {
 	reg := prometheus.NewRegistry()
 	backend := NewPrometheusBackend(reg)
-	hist := backend.Histogram(umami.HistogramOpts{Name: "test_histogram", Help: "test histogram", Buckets: []float64{0.1, 1, 10}})
+	hist := backend.Histogram(umami.HistogramOpts{MetricInfo: umami.MetricInfo{Name: "test_histogram", Help: "test histogram"}, Buckets: []float64{0.1, 1, 10}})
 	if err := hist.Observe(0.5); err != nil {
 		t.Errorf("Histogram Observe failed: %v", err)
 	}
@@ -93,7 +100,7 @@ func TestPrometheusHistogramBackend(t *testing.T) {
 func TestPrometheusSummaryBackend(t *testing.T) {
 	reg := prometheus.NewRegistry()
 	backend := NewPrometheusBackend(reg)
-	summary := backend.Summary(umami.SummaryOpts{Name: "test_summary", Help: "test summary", Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01}})
+	summary := backend.Summary(umami.SummaryOpts{MetricInfo: umami.MetricInfo{Name: "test_summary", Help: "test summary"}, Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01}})
 	if err := summary.Observe(1.0); err != nil {
 		t.Errorf("Summary Observe failed: %v", err)
 	}
@@ -146,7 +153,7 @@ func getMetricValue(t *testing.T, reg *prometheus.Registry, name string, labelPa
 func TestPrometheusCounterVecBackend(t *testing.T) {
 	reg := prometheus.NewRegistry()
 	backend := NewPrometheusBackend(reg)
-	counterVec := backend.CounterVec(umami.CounterVecOpts{Name: "test_counter_vec", Help: "test counter vec", Labels: []string{"foo", "bar"}})
+	counterVec := backend.CounterVec(umami.CounterVecOpts{MetricInfo: umami.MetricInfo{Name: "test_counter_vec", Help: "test counter vec"}, Labels: []string{"foo", "bar"}})
 	labels := umami.VecLabels{"foo": "a", "bar": "b"}
 	if err := counterVec.Inc(labels); err != nil {
 		t.Errorf("CounterVec Inc failed: %v", err)
@@ -160,10 +167,35 @@ func TestPrometheusCounterVecBackend(t *testing.T) {
 	}
 }
 
+func TestPrometheusCounterVecCollect(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("web", NewPrometheusBackend(reg))
+	ctx := group.Context()
+
+	requests := group.CounterVec(umami.CounterVecOpts{
+		MetricInfo: umami.MetricInfo{Name: "requests_total", Help: "Requests"},
+		Labels:     []string{"route"},
+	}, umami.LevelDebug)
+	_ = requests.Inc(ctx, umami.VecLabels{"route": "/"})
+	_ = requests.Add(ctx, 4, umami.VecLabels{"route": "/orders"})
+
+	series, err := requests.Collect(ctx)
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	values := make(map[string]float64)
+	for _, sample := range series {
+		values[sample.Labels["route"]] = sample.Value
+	}
+	if len(values) != 2 || values["/"] != 1 || values["/orders"] != 4 {
+		t.Errorf("expected the series / = 1 and /orders = 4, got %v", series)
+	}
+}
+
 func TestPrometheusGaugeVecBackend(t *testing.T) {
 	reg := prometheus.NewRegistry()
 	backend := NewPrometheusBackend(reg)
-	gaugeVec := backend.GaugeVec(umami.GaugeVecOpts{Name: "test_gauge_vec", Help: "test gauge vec", Labels: []string{"foo"}})
+	gaugeVec := backend.GaugeVec(umami.GaugeVecOpts{MetricInfo: umami.MetricInfo{Name: "test_gauge_vec", Help: "test gauge vec"}, Labels: []string{"foo"}})
 	labels := umami.VecLabels{"foo": "bar"}
 	if err := gaugeVec.Set(10, labels); err != nil {
 		t.Errorf("GaugeVec Set failed: %v", err)
@@ -186,7 +218,7 @@ func TestPrometheusGaugeVecBackend(t *testing.T) {
 func TestPrometheusHistogramVecBackend(t *testing.T) {
 	reg := prometheus.NewRegistry()
 	backend := NewPrometheusBackend(reg)
-	histVec := backend.HistogramVec(umami.HistogramVecOpts{Name: "test_histogram_vec", Help: "test histogram vec", Buckets: []float64{0.1, 1, 10}, Labels: []string{"foo"}})
+	histVec := backend.HistogramVec(umami.HistogramVecOpts{MetricInfo: umami.MetricInfo{Name: "test_histogram_vec", Help: "test histogram vec"}, Buckets: []float64{0.1, 1, 10}, Labels: []string{"foo"}})
 	labels := umami.VecLabels{"foo": "bar"}
 	if err := histVec.Observe(0.5, labels); err != nil {
 		t.Errorf("HistogramVec Observe failed: %v", err)
@@ -200,7 +232,7 @@ func TestPrometheusHistogramVecBackend(t *testing.T) {
 func TestPrometheusSummaryVecBackend(t *testing.T) {
 	reg := prometheus.NewRegistry()
 	backend := NewPrometheusBackend(reg)
-	summaryVec := backend.SummaryVec(umami.SummaryVecOpts{Name: "test_summary_vec", Help: "test summary vec", Objectives: map[float64]float64{0.5: 0.05}, Labels: []string{"foo"}})
+	summaryVec := backend.SummaryVec(umami.SummaryVecOpts{MetricInfo: umami.MetricInfo{Name: "test_summary_vec", Help: "test summary vec"}, Objectives: map[float64]float64{0.5: 0.05}, Labels: []string{"foo"}})
 	labels := umami.VecLabels{"foo": "bar"}
 	if err := summaryVec.Observe(2.0, labels); err != nil {
 		t.Errorf("SummaryVec Observe failed: %v", err)
@@ -212,3 +244,941 @@ func TestPrometheusSummaryVecBackend(t *testing.T) {
 		t.Errorf("SummaryVec value = %v, want 2.0", val)
 	}
 }
+
+func newTestSummaryVec(reg *prometheus.Registry) umami.SummaryVecAdapter {
+	return NewPrometheusBackend(reg).SummaryVec(umami.SummaryVecOpts{
+		MetricInfo: umami.MetricInfo{Name: "test_summary_vec", Help: "test summary vec"},
+		Objectives: map[float64]float64{0.5: 0.001, 0.99: 0.001},
+		Labels:     []string{"route", "method"},
+	})
+}
+
+func TestPrometheusSummaryVecQuantiles(t *testing.T) {
+	summaryVec := newTestSummaryVec(prometheus.NewRegistry())
+
+	// Many series, so that collecting the vec sends more than one metric
+	for i := range 10 {
+		labels := umami.VecLabels{"route": fmt.Sprintf("/%d", i), "method": "GET"}
+		for value := 1; value <= 100; value++ {
+			if err := summaryVec.Observe(float64(i*1000+value), labels); err != nil {
+				t.Fatalf("SummaryVec Observe failed: %v", err)
+			}
+		}
+	}
+
+	for i := range 10 {
+		labels := umami.VecLabels{"route": fmt.Sprintf("/%d", i), "method": "GET"}
+		median, err := summaryVec.Quantile(0.5, labels)
+		if err != nil {
+			t.Fatalf("SummaryVec Quantile failed for %v: %v", labels, err)
+		}
+		if want := float64(i*1000 + 50); median != want {
+			t.Errorf("SummaryVec median of %v = %v, want %v", labels, median, want)
+		}
+		if p99, _ := summaryVec.Quantile(0.99, labels); p99 != float64(i*1000+99) {
+			t.Errorf("SummaryVec p99 of %v = %v, want %v", labels, p99, i*1000+99)
+		}
+	}
+}
+
+func TestPrometheusSummaryVecErrors(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	summaryVec := newTestSummaryVec(reg)
+	labels := umami.VecLabels{"route": "/", "method": "GET"}
+	if err := summaryVec.Observe(1, labels); err != nil {
+		t.Fatalf("SummaryVec Observe failed: %v", err)
+	}
+
+	unknown := umami.VecLabels{"route": "/", "status": "200"}
+	if err := summaryVec.Observe(1, unknown); err == nil {
+		t.Error("Expected Observe with an unknown label to fail")
+	}
+	if _, err := summaryVec.Quantile(0.5, unknown); err == nil || errors.Is(err, umami.ErrQuantileNotFound) {
+		t.Errorf("Expected Quantile with an unknown label to fail currying, got %v", err)
+	}
+
+	if _, err := summaryVec.Quantile(0.9, labels); !errors.Is(err, umami.ErrQuantileNotFound) {
+		t.Errorf("Expected ErrQuantileNotFound for a quantile that is not an objective, got %v", err)
+	}
+
+	missing := umami.VecLabels{"route": "/missing", "method": "GET"}
+	if _, err := summaryVec.Quantile(0.5, missing); !errors.Is(err, umami.ErrQuantileNotFound) {
+		t.Errorf("Expected ErrQuantileNotFound for a missing series, got %v", err)
+	}
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	if series := mfs[0].GetMetric(); len(series) != 1 {
+		t.Errorf("Expected reading a quantile not to create a series, got %d series", len(series))
+	}
+}
+
+func TestPrometheusVecLabelMismatch(t *testing.T) {
+	backend := NewPrometheusBackend(prometheus.NewRegistry())
+	labels := []string{"route"}
+	wrong := umami.VecLabels{"status": "200"}
+
+	counterVec := backend.CounterVec(umami.CounterVecOpts{MetricInfo: umami.MetricInfo{Name: "counter_vec"}, Labels: labels})
+	gaugeVec := backend.GaugeVec(umami.GaugeVecOpts{MetricInfo: umami.MetricInfo{Name: "gauge_vec"}, Labels: labels})
+	histogramVec := backend.HistogramVec(umami.HistogramVecOpts{MetricInfo: umami.MetricInfo{Name: "histogram_vec"}, Labels: labels})
+	summaryVec := backend.SummaryVec(umami.SummaryVecOpts{MetricInfo: umami.MetricInfo{Name: "summary_vec"}, Labels: labels})
+
+	errs := map[string]error{
+		"counter vec inc":       counterVec.Inc(wrong),
+		"counter vec add":       counterVec.Add(1, wrong),
+		"gauge vec set":         gaugeVec.Set(1, wrong),
+		"gauge vec dec":         gaugeVec.Dec(wrong),
+		"histogram vec observe": histogramVec.Observe(1, wrong),
+		"summary vec observe":   summaryVec.Observe(1, wrong),
+		"counter vec init":      counterVec.(umami.VecInitAdapter).InitLabels(wrong),
+	}
+	_, errs["summary vec quantile"] = summaryVec.Quantile(0.5, wrong)
+
+	for op, err := range errs {
+		if !errors.Is(err, umami.ErrLabelMismatch) {
+			t.Errorf("Expected ErrLabelMismatch from %s, got %v", op, err)
+		}
+	}
+}
+
+func TestPrometheusSummaryVecConcurrent(t *testing.T) {
+	summaryVec := newTestSummaryVec(prometheus.NewRegistry())
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			labels := umami.VecLabels{"route": fmt.Sprintf("/%d", i%4), "method": "GET"}
+			for value := range 200 {
+				if err := summaryVec.Observe(float64(value), labels); err != nil {
+					t.Errorf("SummaryVec Observe failed: %v", err)
+					return
+				}
+				if _, err := summaryVec.Quantile(0.5, labels); err != nil {
+					t.Errorf("SummaryVec Quantile failed: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func getMetricHelp(t *testing.T, reg *prometheus.Registry, name string) string {
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() == name {
+			return mf.GetHelp()
+		}
+	}
+	t.Fatalf("metric %s not found", name)
+	return ""
+}
+
+func TestPrometheusCompositeComponentHelp(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	backend := NewPrometheusBackend(reg)
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("web", backend)
+
+	cache := group.Cache(
+		umami.CacheOpts{
+			MetricInfo: umami.MetricInfo{Name: "cache", Help: "Page cache"},
+			HitOpts:    umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "cache_hit"}},
+			MissOpts:   umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "cache_miss", Help: "Custom miss help"}},
+			SizeOpts:   umami.GaugeOpts{MetricInfo: umami.MetricInfo{Name: "cache_size"}},
+		},
+		umami.LevelCritical,
+	)
+
+	// Touch every component so the collectors are exposed
+	ctx := group.Context()
+	_ = cache.Hit(ctx)
+	_ = cache.Miss(ctx)
+	_ = cache.SetSize(ctx, 1)
+
+	tests := map[string]string{
+		"web_cache_hit":  "Page cache (hits)",
+		"web_cache_miss": "Custom miss help",
+		"web_cache_size": "Page cache (size)",
+	}
+	for name, want := range tests {
+		if got := getMetricHelp(t, reg, name); got != want {
+			t.Errorf("help for %s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestPrometheusHistogramObserveBytes(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	backend := NewPrometheusBackend(reg)
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("web", backend)
+
+	hist := group.Histogram(
+		umami.HistogramOpts{
+			MetricInfo: umami.MetricInfo{Name: "payload_bytes", Help: "Payload size"},
+			Buckets:    umami.ByteBuckets(),
+		},
+		umami.LevelCritical,
+	)
+	if err := hist.ObserveBytes(group.Context(), 1<<20); err != nil {
+		t.Fatalf("Histogram ObserveBytes failed: %v", err)
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != "web_payload_bytes" {
+			continue
+		}
+		for _, bucket := range mf.GetMetric()[0].GetHistogram().GetBucket() {
+			want := uint64(0)
+			if bucket.GetUpperBound() >= 1<<20 {
+				want = 1
+			}
+			if bucket.GetCumulativeCount() != want {
+				t.Errorf("bucket le=%v count = %v, want %v", bucket.GetUpperBound(), bucket.GetCumulativeCount(), want)
+			}
+		}
+		return
+	}
+	t.Fatal("metric web_payload_bytes not found")
+}
+
+func TestPrometheusGaugeFromAtomic(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	backend := NewPrometheusBackend(reg)
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("web", backend)
+
+	var inFlight atomic.Int64
+	gauge := group.GaugeFromAtomic(
+		umami.GaugeOpts{MetricInfo: umami.MetricInfo{Name: "in_flight", Help: "In-flight requests"}},
+		umami.LevelCritical,
+		&inFlight,
+	)
+
+	if v := getMetricValue(t, reg, "web_in_flight", nil); v != 0 {
+		t.Errorf("expected 0, got %v", v)
+	}
+
+	inFlight.Add(5)
+	if v := getMetricValue(t, reg, "web_in_flight", nil); v != 5 {
+		t.Errorf("expected scraped gauge to follow the atomic, got %v", v)
+	}
+
+	_ = gauge.Dec(group.Context())
+	if got := inFlight.Load(); got != 4 {
+		t.Errorf("expected gauge operations to write through, got %v", got)
+	}
+	if v := getMetricValue(t, reg, "web_in_flight", nil); v != 4 {
+		t.Errorf("expected 4, got %v", v)
+	}
+}
+
+func TestPrometheusCounterGaugeValue(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("web", NewPrometheusBackend(reg))
+	ctx := group.Context()
+
+	counter := group.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "requests_total", Help: "Requests"}}, umami.LevelCritical)
+	_ = counter.Add(ctx, 4)
+	if got, err := counter.Value(ctx); err != nil || got != 4 {
+		t.Errorf("expected counter value 4, got %v (%v)", got, err)
+	}
+
+	gauge := group.Gauge(umami.GaugeOpts{MetricInfo: umami.MetricInfo{Name: "in_flight", Help: "In-flight requests"}}, umami.LevelCritical)
+	_ = gauge.Set(ctx, 2)
+	_ = gauge.Inc(ctx)
+	if got, err := gauge.Value(ctx); err != nil || got != 3 {
+		t.Errorf("expected gauge value 3, got %v (%v)", got, err)
+	}
+}
+
+func TestPrometheusSummaryReconfigure(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	backend := NewPrometheusBackend(reg)
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("web", backend)
+	ctx := group.Context()
+
+	summary := group.Summary(
+		umami.SummaryOpts{
+			MetricInfo: umami.MetricInfo{Name: "latency", Help: "Latency"},
+			Objectives: map[float64]float64{0.5: 0.05},
+		},
+		umami.LevelCritical,
+	)
+	_ = summary.Observe(ctx, 1)
+
+	err := summary.Reconfigure(umami.SummaryOpts{
+		MetricInfo: umami.MetricInfo{Help: "Latency"},
+		Objectives: map[float64]float64{0.9: 0.01, 0.99: 0.001},
+	})
+	if err != nil {
+		t.Fatalf("Summary Reconfigure failed: %v", err)
+	}
+	_ = summary.Observe(ctx, 2)
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != "web_latency" {
+			continue
+		}
+		s := mf.GetMetric()[0].GetSummary()
+		if s.GetSampleCount() != 1 {
+			t.Errorf("expected data to reset on reconfigure, got %d samples", s.GetSampleCount())
+		}
+		var quantiles []float64
+		for _, q := range s.GetQuantile() {
+			quantiles = append(quantiles, q.GetQuantile())
+		}
+		if len(quantiles) != 2 || quantiles[0] != 0.9 || quantiles[1] != 0.99 {
+			t.Errorf("expected quantiles [0.9 0.99], got %v", quantiles)
+		}
+		return
+	}
+	t.Fatal("metric web_latency not found")
+}
+
+func TestPrometheusReclaimDisabled(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("web", NewPrometheusBackend(reg))
+	ctx := group.Context()
+
+	requests := group.CounterVec(umami.CounterVecOpts{
+		MetricInfo: umami.MetricInfo{Name: "requests_total", Help: "Requests"},
+		Labels:     []string{"route"},
+	}, umami.LevelDebug)
+	_ = requests.Inc(ctx, umami.VecLabels{"route": "/"})
+
+	group.SetGroupLevel(umami.LevelImportant, umami.LevelOpts{ReclaimDisabled: true})
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() == "web_requests_total" {
+			t.Fatal("expected the reclaimed series not to be gathered")
+		}
+	}
+
+	// Enabled again, the counter is registered again from zero
+	group.SetGroupLevel(umami.LevelDebug, umami.LevelOpts{ReplaceNoops: true})
+	_ = requests.Inc(group.Context(), umami.VecLabels{"route": "/"})
+	promtest.AssertCounterValue(t, reg, "web_requests_total", map[string]string{"route": "/"}, 1)
+}
+
+func TestPrometheusIdempotentRegistration(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	backend := NewPrometheusBackend(reg)
+	opts := umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "requests_total", Help: "Requests"}}
+
+	first := backend.Counter(opts)
+	second := backend.Counter(opts)
+
+	_ = first.Inc()
+	_ = second.Add(2)
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	if len(mfs) != 1 || len(mfs[0].GetMetric()) != 1 {
+		t.Fatalf("expected a single collector, got %d families", len(mfs))
+	}
+	if v := getMetricValue(t, reg, "requests_total", nil); v != 3 {
+		t.Errorf("expected accumulated value 3, got %v", v)
+	}
+}
+
+func TestPrometheusRegistrationTypeMismatchPanics(t *testing.T) {
+	backend := NewPrometheusBackend(prometheus.NewRegistry())
+	backend.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "thing", Help: "Thing"}})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a gauge under a counter's name to panic")
+		}
+	}()
+	backend.Gauge(umami.GaugeOpts{MetricInfo: umami.MetricInfo{Name: "thing", Help: "Thing"}})
+}
+
+func TestPrometheusVerbatimNames(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	backend := NewPrometheusBackend(reg, WithVerbatimNames())
+
+	counter := backend.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "job:requests:rate5m", Help: "Recorded"}})
+	if err := counter.Inc(); err != nil {
+		t.Fatalf("Counter Inc failed: %v", err)
+	}
+	if v := getMetricValue(t, reg, "job:requests:rate5m", nil); v != 1 {
+		t.Errorf("expected verbatim name to be registered with value 1, got %v", v)
+	}
+}
+
+func TestPrometheusMaxNameLength(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	var warnings []error
+	backend := NewPrometheusBackend(reg, WithMaxNameLength(24), WithWarningHandler(func(err error) {
+		warnings = append(warnings, err)
+	}))
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("checkout_service", backend)
+	ctx := group.Context()
+
+	// Both names exceed the limit and share their first 24 runes
+	hits := group.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "cache_hits_total", Help: "Hits"}}, umami.LevelDebug)
+	misses := group.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "cache_misses_total", Help: "Misses"}}, umami.LevelDebug)
+	short := group.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "orders", Help: "Orders"}}, umami.LevelDebug)
+	_ = hits.Inc(ctx)
+	_ = misses.Add(ctx, 2)
+	_ = short.Inc(ctx)
+
+	p := backend.(*prometheusBackend)
+	hitsName, missesName := p.metricName("checkout_service_cache_hits_total"), p.metricName("checkout_service_cache_misses_total")
+	if len(hitsName) != 24 || hitsName == missesName {
+		t.Fatalf("expected distinct names of 24 runes, got %q and %q", hitsName, missesName)
+	}
+	if again := p.metricName("checkout_service_cache_hits_total"); again != hitsName {
+		t.Errorf("expected truncation to be stable, got %q then %q", hitsName, again)
+	}
+	if v := getMetricValue(t, reg, hitsName, nil); v != 1 {
+		t.Errorf("expected %s to be 1, got %v", hitsName, v)
+	}
+	if v := getMetricValue(t, reg, missesName, nil); v != 2 {
+		t.Errorf("expected %s to be 2, got %v", missesName, v)
+	}
+	if v := getMetricValue(t, reg, "checkout_service_orders", nil); v != 1 {
+		t.Errorf("expected the short name to be kept, got %v", v)
+	}
+
+	if len(warnings) != 2 || !errors.Is(warnings[0], ErrNameTruncated) {
+		t.Errorf("expected a truncation warning per truncated name, got %v", warnings)
+	}
+
+	// Collectors are unregistered by their full name
+	if !p.Unregister("checkout_service_cache_hits_total") {
+		t.Error("expected the truncated metric to be unregistered by its full name")
+	}
+}
+
+func TestPrometheusStrictNamesPanics(t *testing.T) {
+	backend := NewPrometheusBackend(prometheus.NewRegistry())
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a colon-containing name to panic without WithVerbatimNames")
+		}
+	}()
+	backend.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "job:requests:rate5m", Help: "Recorded"}})
+}
+
+func TestPrometheusMetricValue(t *testing.T) {
+	reg := umami.NewRegistry(umami.LevelDebug)
+	group := reg.NewGroup("web", NewPrometheusBackend(prometheus.NewRegistry()))
+	ctx := group.Context()
+
+	counter := group.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "requests_total", Help: "Requests"}}, umami.LevelImportant)
+	counter.Add(ctx, 4)
+	histogramVec := group.HistogramVec(umami.HistogramVecOpts{
+		MetricInfo: umami.MetricInfo{Name: "latency_seconds", Help: "Latency"},
+		Labels:     []string{"route"},
+	}, umami.LevelImportant)
+	histogramVec.Observe(ctx, 0.5, umami.VecLabels{"route": "/a"})
+	histogramVec.Observe(ctx, 1.5, umami.VecLabels{"route": "/a"})
+
+	family, err := reg.MetricValue("web_requests_total")
+	if err != nil {
+		t.Fatalf("MetricValue() failed: %v", err)
+	}
+	if family.Kind != umami.MetricKindCounter || family.Help != "Requests" || family.Samples[0].Value != 4 {
+		t.Errorf("Expected counter with value 4, got %+v", family)
+	}
+
+	family, err = reg.MetricValue("web_latency_seconds")
+	if err != nil {
+		t.Fatalf("MetricValue() failed: %v", err)
+	}
+	if len(family.Samples) != 1 {
+		t.Fatalf("Expected a single sample, got %+v", family)
+	}
+	sample := family.Samples[0]
+	if family.Kind != umami.MetricKindHistogram || sample.Labels["route"] != "/a" || sample.Count != 2 || sample.Sum != 2 {
+		t.Errorf("Expected route=/a histogram with 2 observations summing to 2, got %+v", family)
+	}
+}
+
+func TestPrometheusPreInitLabels(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("web", NewPrometheusBackend(reg))
+	routes := []umami.VecLabels{{"route": "/a"}, {"route": "/b"}}
+
+	group.HistogramVec(umami.HistogramVecOpts{
+		MetricInfo:    umami.MetricInfo{Name: "latency_seconds", Help: "Latency"},
+		Labels:        []string{"route"},
+		PreInitLabels: routes,
+	}, umami.LevelImportant)
+	group.CounterVec(umami.CounterVecOpts{
+		MetricInfo:    umami.MetricInfo{Name: "requests_total", Help: "Requests"},
+		Labels:        []string{"route"},
+		PreInitLabels: routes,
+	}, umami.LevelImportant)
+
+	// The series exist before anything is observed
+	for _, labels := range routes {
+		promtest.AssertHistogramSampleCount(t, reg, "web_latency_seconds", labels, 0)
+		promtest.AssertCounterValue(t, reg, "web_requests_total", labels, 0)
+	}
+}
+
+func TestPrometheusCounterGroup(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	group := umami.NewRegistry(umami.LevelImportant).NewGroup("web", NewPrometheusBackend(reg))
+	ctx := group.Context()
+
+	var shards []umami.Counter
+	for _, shard := range []string{"a", "b"} {
+		shards = append(shards, group.Counter(umami.CounterOpts{
+			BasicMetricOpts: umami.BasicMetricOpts{FromComposite: true},
+			MetricInfo:      umami.MetricInfo{Name: "errors_" + shard + "_total", Help: "Shard errors"},
+		}, umami.LevelImportant))
+	}
+	group.CounterGroup(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "errors_total", Help: "Errors"}}, umami.LevelImportant, shards...)
+
+	_ = shards[0].Add(ctx, 2)
+	_ = shards[1].Add(ctx, 3)
+	promtest.AssertCounterValue(t, reg, "web_errors_total", nil, 5)
+
+	_ = shards[1].Inc(ctx)
+	promtest.AssertCounterValue(t, reg, "web_errors_total", nil, 6)
+}
+
+func TestPrometheusGaugeFuncAndCounterFunc(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	group := umami.NewRegistry(umami.LevelImportant).NewGroup("web", NewPrometheusBackend(reg))
+
+	var sessions, logins float64
+	group.GaugeFunc(umami.GaugeOpts{MetricInfo: umami.MetricInfo{Name: "sessions", Help: "Open sessions"}}, func() float64 { return sessions }, umami.LevelImportant)
+	group.CounterFunc(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "logins_total", Help: "Logins"}}, func() float64 { return logins }, umami.LevelImportant)
+
+	sessions, logins = 3, 7
+	if v := getMetricValue(t, reg, "web_sessions", nil); v != 3 {
+		t.Errorf("expected the scraped gauge to follow the function, got %v", v)
+	}
+	promtest.AssertCounterValue(t, reg, "web_logins_total", nil, 7)
+
+	sessions, logins = 1, 9
+	if v := getMetricValue(t, reg, "web_sessions", nil); v != 1 {
+		t.Errorf("expected the scraped gauge to follow the function, got %v", v)
+	}
+	promtest.AssertCounterValue(t, reg, "web_logins_total", nil, 9)
+}
+
+func TestPrometheusCreationTimestamps(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	group := umami.NewRegistry(umami.LevelImportant, umami.WithCreationTimestamps(true)).NewGroup("web", NewPrometheusBackend(reg))
+
+	before := time.Now().Unix()
+	group.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "requests_total", Help: "Requests"}}, umami.LevelImportant)
+	group.HistogramVec(umami.HistogramVecOpts{
+		MetricInfo: umami.MetricInfo{Name: "latency_seconds", Help: "Latency"},
+		Labels:     []string{"route"},
+	}, umami.LevelImportant)
+	after := time.Now().Unix()
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	created := make(map[string]float64)
+	for _, family := range families {
+		if family.GetType() == dto.MetricType_GAUGE && len(family.GetMetric()) == 1 {
+			created[family.GetName()] = family.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+
+	for _, name := range []string{"web_requests_total_created", "web_latency_seconds_created"} {
+		value, ok := created[name]
+		if !ok {
+			t.Errorf("Expected a %s series, got %v", name, created)
+			continue
+		}
+		if value < float64(before) || value > float64(after+1) {
+			t.Errorf("Expected %s to be a unix time between %d and %d, got %v", name, before, after, value)
+		}
+	}
+}
+
+func TestPrometheusCounterResetOnScrape(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	group := umami.NewRegistry(umami.LevelImportant).NewGroup("web", NewPrometheusBackend(reg))
+	ctx := group.Context()
+
+	deltas := group.Counter(umami.CounterOpts{
+		MetricInfo:    umami.MetricInfo{Name: "flushed_total", Help: "Flushed"},
+		ResetOnScrape: true,
+	}, umami.LevelImportant)
+	cumulative := group.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "requests_total", Help: "Requests"}}, umami.LevelImportant)
+
+	_ = deltas.Add(ctx, 3)
+	_ = cumulative.Add(ctx, 3)
+	if value, err := deltas.Value(ctx); err != nil || value != 3 {
+		t.Errorf("Expected Value to read 3 without resetting, got %v, %v", value, err)
+	}
+	promtest.AssertCounterValue(t, reg, "web_flushed_total", nil, 3)
+
+	// The scrape reset the counter
+	promtest.AssertCounterValue(t, reg, "web_flushed_total", nil, 0)
+
+	_ = deltas.Add(ctx, 2)
+	_ = cumulative.Add(ctx, 2)
+	promtest.AssertCounterValue(t, reg, "web_flushed_total", nil, 2)
+	promtest.AssertCounterValue(t, reg, "web_requests_total", nil, 5)
+}
+
+func TestPrometheusWithLabels(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	backend := NewPrometheusBackend(reg)
+
+	// A umami registry per tenant, so that both share the metric names
+	for tenant, requests := range map[string]int{"a": 2, "b": 5} {
+		group := umami.NewRegistry(umami.LevelDebug).NewGroup("web", backend.WithLabels(umami.VecLabels{"tenant": tenant}))
+		counter := group.CounterVec(umami.CounterVecOpts{
+			MetricInfo: umami.MetricInfo{Name: "requests_total", Help: "Requests"},
+			Labels:     []string{"route"},
+		}, umami.LevelCritical)
+		for range requests {
+			_ = counter.Inc(group.Context(), umami.VecLabels{"route": "/"})
+		}
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	if len(mfs) != 1 || len(mfs[0].GetMetric()) != 2 {
+		t.Fatalf("expected one family with a series per tenant, got %v", mfs)
+	}
+	for _, m := range mfs[0].GetMetric() {
+		if len(m.GetLabel()) != 2 {
+			t.Errorf("expected series to differ only by the tenant label, got %v", m.GetLabel())
+		}
+	}
+	if v := getMetricValue(t, reg, "web_requests_total", map[string]string{"tenant": "a", "route": "/"}); v != 2 {
+		t.Errorf("expected 2 requests of tenant a, got %v", v)
+	}
+	if v := getMetricValue(t, reg, "web_requests_total", map[string]string{"tenant": "b", "route": "/"}); v != 5 {
+		t.Errorf("expected 5 requests of tenant b, got %v", v)
+	}
+
+	// Views unregister their own metrics only
+	scoped := backend.WithLabels(umami.VecLabels{"tenant": "c"})
+	scoped.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "up", Help: "Up"}})
+	if !scoped.(umami.UnregisterBackend).Unregister("up") {
+		t.Error("expected the view to unregister its metric")
+	}
+	if backend.(umami.UnregisterBackend).Unregister("up") {
+		t.Error("expected the backend not to know the metric of its view")
+	}
+}
+
+func TestPrometheusNamespaceConstLabels(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("payments", NewPrometheusBackend(reg))
+	ctx := group.Context()
+
+	orders := group.CounterVec(umami.CounterVecOpts{
+		MetricInfo: umami.MetricInfo{
+			Name:        "orders_total",
+			Help:        "Orders",
+			Namespace:   "shop",
+			Subsystem:   "checkout",
+			ConstLabels: map[string]string{"service": "api", "version": "1.2"},
+		},
+		Labels: []string{"status"},
+	}, umami.LevelDebug)
+	_ = orders.Add(ctx, 3, umami.VecLabels{"status": "ok"})
+
+	// Namespace and Subsystem come before the group prefix
+	labels := map[string]string{"service": "api", "version": "1.2", "status": "ok"}
+	if v := getMetricValue(t, reg, "shop_checkout_payments_orders_total", labels); v != 3 {
+		t.Errorf("expected shop_checkout_payments_orders_total to be 3, got %v", v)
+	}
+}
+
+func TestPrometheusCounterVecWithLabelValues(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("test", NewPrometheusBackend(reg))
+	ctx := group.Context()
+
+	requests := group.CounterVec(umami.CounterVecOpts{
+		MetricInfo: umami.MetricInfo{Name: "requests_total", Help: "Requests"},
+		Labels:     []string{"route", "method"},
+	}, umami.LevelDebug)
+
+	users := requests.WithLabelValues(ctx, "/users", "GET")
+	_ = users.Inc(ctx)
+	_ = users.Add(ctx, 2)
+	if v := getMetricValue(t, reg, "test_requests_total", map[string]string{"route": "/users", "method": "GET"}); v != 3 {
+		t.Errorf("expected the bound series to be 3, got %v", v)
+	}
+	if v, err := users.Value(ctx); err != nil || v != 3 {
+		t.Errorf("expected the bound counter to read 3, got %v, %v", v, err)
+	}
+
+	if err := requests.WithLabelValues(ctx, "/users").Inc(ctx); !errors.Is(err, umami.ErrLabelMismatch) {
+		t.Errorf("expected ErrLabelMismatch, got %v", err)
+	}
+}
+
+func TestPrometheusNativeHistogram(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("test", NewPrometheusBackend(reg))
+	ctx := group.Context()
+
+	latency := group.Histogram(umami.HistogramOpts{
+		MetricInfo:                     umami.MetricInfo{Name: "latency_seconds", Help: "Latency"},
+		Buckets:                        []float64{0.1, 1},
+		NativeHistogramBucketFactor:    1.1,
+		NativeHistogramMaxBucketNumber: 100,
+	}, umami.LevelDebug)
+	for _, v := range []float64{0.001, 0.01, 0.05, 0.2, 0.7, 3, 12} {
+		_ = latency.Observe(ctx, v)
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	if len(mfs) != 1 || len(mfs[0].GetMetric()) != 1 {
+		t.Fatalf("expected a single histogram series, got %v", mfs)
+	}
+	h := mfs[0].GetMetric()[0].GetHistogram()
+	if h.Schema == nil {
+		t.Fatal("expected the exponential schema of a native histogram")
+	}
+	if len(h.GetPositiveSpan()) == 0 || len(h.GetPositiveDelta()) == 0 {
+		t.Errorf("expected populated native buckets, got spans %v and deltas %v", h.GetPositiveSpan(), h.GetPositiveDelta())
+	}
+	if len(h.GetBucket()) != 0 {
+		t.Errorf("expected the classic buckets to be ignored, got %v", h.GetBucket())
+	}
+	if h.GetSampleCount() != 7 {
+		t.Errorf("expected 7 observations, got %d", h.GetSampleCount())
+	}
+}
+
+func TestPrometheusExemplars(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("test", NewPrometheusBackend(reg))
+	ctx := group.Context()
+
+	requests := group.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "requests_total", Help: "Requests"}}, umami.LevelDebug)
+	latency := group.Histogram(umami.HistogramOpts{MetricInfo: umami.MetricInfo{Name: "latency_seconds", Help: "Latency"}, Buckets: []float64{0.1, 1, 10}}, umami.LevelDebug)
+
+	_ = requests.IncWithExemplar(ctx, umami.VecLabels{"trace_id": "abc"})
+	_ = requests.AddWithExemplar(ctx, 2, umami.VecLabels{"trace_id": "def"})
+	_ = latency.Observe(ctx, 0.05)
+	_ = latency.ObserveWithExemplar(ctx, 0.5, umami.VecLabels{"trace_id": "ghi"})
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	metrics := map[string]*dto.Metric{}
+	for _, mf := range mfs {
+		metrics[mf.GetName()] = mf.GetMetric()[0]
+	}
+
+	counter := metrics["test_requests_total"].GetCounter()
+	if counter.GetValue() != 3 {
+		t.Errorf("expected the counter to be 3, got %v", counter.GetValue())
+	}
+	if e := counter.GetExemplar(); e.GetValue() != 2 || len(e.GetLabel()) != 1 || e.GetLabel()[0].GetValue() != "def" {
+		t.Errorf("expected the latest exemplar on the counter, got %v", e)
+	}
+
+	for _, bucket := range metrics["test_latency_seconds"].GetHistogram().GetBucket() {
+		e := bucket.GetExemplar()
+		switch bucket.GetUpperBound() {
+		case 1:
+			if e.GetValue() != 0.5 || len(e.GetLabel()) != 1 || e.GetLabel()[0].GetValue() != "ghi" {
+				t.Errorf("expected the exemplar on the bucket of 0.5, got %v", e)
+			}
+		default:
+			if e != nil {
+				t.Errorf("expected no exemplar on the bucket of %v, got %v", bucket.GetUpperBound(), e)
+			}
+		}
+	}
+
+	if err := requests.IncWithExemplar(ctx, umami.VecLabels{"trace_id": strings.Repeat("x", 200)}); !errors.Is(err, ErrInvalidExemplar) {
+		t.Errorf("expected ErrInvalidExemplar, got %v", err)
+	}
+}
+
+func TestPrometheusMaxCardinalityWithLabelValues(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("test", NewPrometheusBackend(reg))
+	ctx := group.Context()
+
+	requests := group.CounterVec(umami.CounterVecOpts{
+		MetricInfo:     umami.MetricInfo{Name: "requests_total", Help: "Requests"},
+		Labels:         []string{"route"},
+		MaxCardinality: 10,
+	}, umami.LevelDebug)
+
+	users := requests.WithLabelValues(ctx, "/users")
+	_ = users.Inc(ctx)
+	if err := users.AddWithExemplar(ctx, 2, umami.VecLabels{"trace_id": "abc"}); err != nil {
+		t.Errorf("expected the exemplar to be recorded, got %v", err)
+	}
+	if v, err := users.Value(ctx); err != nil || v != 3 {
+		t.Errorf("expected the bound counter to read 3, got %v, %v", v, err)
+	}
+
+	m := promtest.FindMetric(t, reg, "test_requests_total", map[string]string{"route": "/users"})
+	if e := m.GetCounter().GetExemplar(); e.GetValue() != 2 || len(e.GetLabel()) != 1 || e.GetLabel()[0].GetValue() != "abc" {
+		t.Errorf("expected the exemplar on the limited vec, got %v", e)
+	}
+}
+
+func TestPrometheusVecDelete(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	group := umami.NewRegistry(umami.LevelImportant).NewGroup("web", NewPrometheusBackend(reg))
+	ctx := group.Context()
+
+	counterVec := group.CounterVec(umami.CounterVecOpts{MetricInfo: umami.MetricInfo{Name: "messages_total", Help: "Messages"}, Labels: []string{"client"}}, umami.LevelImportant)
+	gaugeVec := group.GaugeVec(umami.GaugeVecOpts{MetricInfo: umami.MetricInfo{Name: "queued", Help: "Queued messages"}, Labels: []string{"client"}}, umami.LevelImportant)
+	for i, client := range []string{"a", "b", "c"} {
+		_ = counterVec.Add(ctx, float64(i+1), umami.VecLabels{"client": client})
+		_ = gaugeVec.Set(ctx, float64(i+1), umami.VecLabels{"client": client})
+	}
+
+	// clients returns the client label of every gathered series of the family
+	clients := func(name string) []string {
+		t.Helper()
+		mfs, err := reg.Gather()
+		if err != nil {
+			t.Fatalf("failed to gather metrics: %v", err)
+		}
+		var clients []string
+		for _, mf := range mfs {
+			if mf.GetName() != name {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				clients = append(clients, m.GetLabel()[0].GetValue())
+			}
+		}
+		return clients
+	}
+
+	if !counterVec.Delete(ctx, umami.VecLabels{"client": "b"}) {
+		t.Error("expected deleting an existing series to return true")
+	}
+	if counterVec.Delete(ctx, umami.VecLabels{"client": "b"}) {
+		t.Error("expected deleting a deleted series to return false")
+	}
+	if got := clients("web_messages_total"); !slices.Equal(got, []string{"a", "c"}) {
+		t.Errorf("expected only the deleted series to disappear, got %v", got)
+	}
+	promtest.AssertCounterValue(t, reg, "web_messages_total", map[string]string{"client": "c"}, 3)
+
+	gaugeVec.Reset(ctx)
+	if got := clients("web_queued"); len(got) != 0 {
+		t.Errorf("expected Reset to remove every series, got %v", got)
+	}
+	if got := clients("web_messages_total"); len(got) != 2 {
+		t.Errorf("expected other vecs to keep their series, got %v", got)
+	}
+}
+
+func TestPrometheusNamespaceMaxNameLength(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	var warnings []error
+	backend := NewPrometheusBackend(reg, WithMaxNameLength(24), WithWarningHandler(func(err error) {
+		warnings = append(warnings, err)
+	}))
+
+	// The qualified name exceeds the limit, the bare name doesn't
+	info := umami.MetricInfo{Name: "orders_total", Help: "Orders", Namespace: "shop", Subsystem: "checkout"}
+	_ = backend.Counter(umami.CounterOpts{MetricInfo: info}).Inc()
+
+	p := backend.(*prometheusBackend)
+	name := p.metricName("shop_checkout_orders_total")
+	if len(name) != 24 {
+		t.Fatalf("expected the qualified name to be truncated to 24 runes, got %q", name)
+	}
+	if v := getMetricValue(t, reg, name, nil); v != 1 {
+		t.Errorf("expected %s to be 1, got %v", name, v)
+	}
+	if len(warnings) != 1 || !errors.Is(warnings[0], ErrNameTruncated) {
+		t.Errorf("expected a truncation warning, got %v", warnings)
+	}
+	if !p.Unregister("shop_checkout_orders_total") {
+		t.Error("expected the metric to be unregistered by its qualified name")
+	}
+}
+
+func TestPrometheusNamespacesDontCollide(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	backend := NewPrometheusBackend(reg)
+
+	shop := backend.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "orders_total", Help: "Orders", Namespace: "shop"}})
+	store := backend.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "orders_total", Help: "Orders", Namespace: "store"}})
+	_ = shop.Inc()
+	_ = store.Add(2)
+
+	if v := getMetricValue(t, reg, "shop_orders_total", nil); v != 1 {
+		t.Errorf("expected shop_orders_total to be 1, got %v", v)
+	}
+	if v := getMetricValue(t, reg, "store_orders_total", nil); v != 2 {
+		t.Errorf("expected store_orders_total to be 2, got %v", v)
+	}
+	if backend.(umami.UnregisterBackend).Unregister("orders_total") {
+		t.Error("expected no metric to be known by its bare name")
+	}
+}
+
+func TestPrometheusNamespaceComposite(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	group := umami.NewRegistry(umami.LevelDebug, umami.WithCreationTimestamps(true)).NewGroup("web", NewPrometheusBackend(reg))
+	ctx := group.Context()
+
+	cache := group.Cache(umami.CacheOpts{
+		MetricInfo: umami.MetricInfo{
+			Name:        "cache",
+			Help:        "Cache",
+			Namespace:   "shop",
+			ConstLabels: map[string]string{"service": "api"},
+		},
+	}, umami.LevelDebug)
+	_ = cache.Hit(ctx)
+
+	// The components have the namespace and const labels of the composite
+	labels := map[string]string{"service": "api"}
+	if v := getMetricValue(t, reg, "shop_web_cache_hit", labels); v != 1 {
+		t.Errorf("expected shop_web_cache_hit to be 1, got %v", v)
+	}
+	if v := getMetricValue(t, reg, "shop_web_cache_hit_created", labels); v == 0 {
+		t.Error("expected a creation timestamp of shop_web_cache_hit")
+	}
+
+	// Components are unregistered by their qualified names once reclaimed
+	group.SetGroupLevel(umami.LevelImportant, umami.LevelOpts{ReclaimDisabled: true})
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	for _, mf := range mfs {
+		t.Errorf("expected %s to be unregistered once reclaimed", mf.GetName())
+	}
+}
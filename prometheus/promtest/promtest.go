@@ -0,0 +1,142 @@
+// Package promtest provides test assertions for metrics recorded through the
+// umami Prometheus backend, so tests don't need to walk Gather() output.
+//
+// All helpers take a [testing.TB] and a [prometheus.Gatherer] (typically the
+// *prometheus.Registry passed to the backend), and report mismatches with
+// [testing.TB.Errorf].
+package promtest
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// FindMetric returns the metric of the named family whose labels include all
+// of the given labels, or nil after reporting an error if there is none.
+func FindMetric(tb testing.TB, gatherer prometheus.Gatherer, name string, labels map[string]string) *dto.Metric {
+	tb.Helper()
+
+	mfs, err := gatherer.Gather()
+	if err != nil {
+		tb.Errorf("failed to gather metrics: %v", err)
+		return nil
+	}
+
+	for _, mf := range mfs {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			if hasLabels(m, labels) {
+				return m
+			}
+		}
+	}
+
+	tb.Errorf("metric %s with labels %v not found", name, labels)
+	return nil
+}
+
+// hasLabels returns true if every label is present on the metric
+func hasLabels(m *dto.Metric, labels map[string]string) bool {
+	for name, value := range labels {
+		found := false
+		for _, lp := range m.GetLabel() {
+			if lp.GetName() == name && lp.GetValue() == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// AssertCounterValue asserts the value of a counter
+func AssertCounterValue(tb testing.TB, gatherer prometheus.Gatherer, name string, labels map[string]string, want float64) {
+	tb.Helper()
+
+	m := FindMetric(tb, gatherer, name, labels)
+	if m == nil {
+		return
+	}
+	if m.GetCounter() == nil {
+		tb.Errorf("metric %s is not a counter", name)
+		return
+	}
+	if got := m.GetCounter().GetValue(); got != want {
+		tb.Errorf("counter %s%v = %v, want %v", name, labels, got, want)
+	}
+}
+
+// AssertGaugeValue asserts the value of a gauge
+func AssertGaugeValue(tb testing.TB, gatherer prometheus.Gatherer, name string, labels map[string]string, want float64) {
+	tb.Helper()
+
+	m := FindMetric(tb, gatherer, name, labels)
+	if m == nil {
+		return
+	}
+	if m.GetGauge() == nil {
+		tb.Errorf("metric %s is not a gauge", name)
+		return
+	}
+	if got := m.GetGauge().GetValue(); got != want {
+		tb.Errorf("gauge %s%v = %v, want %v", name, labels, got, want)
+	}
+}
+
+// AssertHistogramSampleCount asserts the number of observations of a histogram
+func AssertHistogramSampleCount(tb testing.TB, gatherer prometheus.Gatherer, name string, labels map[string]string, want uint64) {
+	tb.Helper()
+
+	m := FindMetric(tb, gatherer, name, labels)
+	if m == nil {
+		return
+	}
+	if m.GetHistogram() == nil {
+		tb.Errorf("metric %s is not a histogram", name)
+		return
+	}
+	if got := m.GetHistogram().GetSampleCount(); got != want {
+		tb.Errorf("histogram %s%v sample count = %v, want %v", name, labels, got, want)
+	}
+}
+
+// AssertHistogramSampleSum asserts the sum of observations of a histogram
+func AssertHistogramSampleSum(tb testing.TB, gatherer prometheus.Gatherer, name string, labels map[string]string, want float64) {
+	tb.Helper()
+
+	m := FindMetric(tb, gatherer, name, labels)
+	if m == nil {
+		return
+	}
+	if m.GetHistogram() == nil {
+		tb.Errorf("metric %s is not a histogram", name)
+		return
+	}
+	if got := m.GetHistogram().GetSampleSum(); got != want {
+		tb.Errorf("histogram %s%v sample sum = %v, want %v", name, labels, got, want)
+	}
+}
+
+// AssertSummarySampleCount asserts the number of observations of a summary
+func AssertSummarySampleCount(tb testing.TB, gatherer prometheus.Gatherer, name string, labels map[string]string, want uint64) {
+	tb.Helper()
+
+	m := FindMetric(tb, gatherer, name, labels)
+	if m == nil {
+		return
+	}
+	if m.GetSummary() == nil {
+		tb.Errorf("metric %s is not a summary", name)
+		return
+	}
+	if got := m.GetSummary().GetSampleCount(); got != want {
+		tb.Errorf("summary %s%v sample count = %v, want %v", name, labels, got, want)
+	}
+}
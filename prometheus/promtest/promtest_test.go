@@ -0,0 +1,83 @@
+package promtest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// recordingTB captures errors reported by the helpers under test
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func newTestRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests_total", Help: "Requests"}, []string{"route"})
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "in_flight", Help: "In flight"})
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "latency_seconds", Help: "Latency"})
+	summary := prometheus.NewSummary(prometheus.SummaryOpts{Name: "size_bytes", Help: "Size"})
+	reg.MustRegister(counter, gauge, histogram, summary)
+
+	counter.WithLabelValues("/").Add(3)
+	gauge.Set(2)
+	histogram.Observe(0.5)
+	histogram.Observe(1.5)
+	summary.Observe(10)
+
+	return reg
+}
+
+func TestAssertionsPass(t *testing.T) {
+	reg := newTestRegistry()
+	tb := &recordingTB{TB: t}
+
+	AssertCounterValue(tb, reg, "requests_total", map[string]string{"route": "/"}, 3)
+	AssertGaugeValue(tb, reg, "in_flight", nil, 2)
+	AssertHistogramSampleCount(tb, reg, "latency_seconds", nil, 2)
+	AssertHistogramSampleSum(tb, reg, "latency_seconds", nil, 2)
+	AssertSummarySampleCount(tb, reg, "size_bytes", nil, 1)
+
+	if len(tb.errors) != 0 {
+		t.Errorf("expected no errors, got %v", tb.errors)
+	}
+}
+
+func TestAssertionsFail(t *testing.T) {
+	reg := newTestRegistry()
+
+	tests := []struct {
+		name   string
+		assert func(tb testing.TB)
+	}{
+		{"wrong counter value", func(tb testing.TB) { AssertCounterValue(tb, reg, "requests_total", nil, 4) }},
+		{"missing labels", func(tb testing.TB) {
+			AssertCounterValue(tb, reg, "requests_total", map[string]string{"route": "/x"}, 3)
+		}},
+		{"missing metric", func(tb testing.TB) { AssertGaugeValue(tb, reg, "missing", nil, 0) }},
+		{"wrong type", func(tb testing.TB) { AssertGaugeValue(tb, reg, "requests_total", nil, 3) }},
+		{"wrong gauge value", func(tb testing.TB) { AssertGaugeValue(tb, reg, "in_flight", nil, 1) }},
+		{"wrong histogram count", func(tb testing.TB) { AssertHistogramSampleCount(tb, reg, "latency_seconds", nil, 1) }},
+		{"wrong histogram sum", func(tb testing.TB) { AssertHistogramSampleSum(tb, reg, "latency_seconds", nil, 1) }},
+		{"wrong summary count", func(tb testing.TB) { AssertSummarySampleCount(tb, reg, "size_bytes", nil, 2) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := &recordingTB{TB: t}
+			tt.assert(tb)
+			if len(tb.errors) != 1 {
+				t.Errorf("expected exactly one error, got %v", tb.errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package umami_prometheus
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/SimonDaKappa/go-umami"
+)
+
+// serveReadHeaderTimeout bounds how long the server of [Serve] waits for the
+// headers of a request
+const serveReadHeaderTimeout = 10 * time.Second
+
+// Serve is the simplest setup of umami with Prometheus. It creates a
+// [umami.Registry] at level, whose default group (see [umami.Registry.Default])
+// registers its metrics with a fresh [prometheus.Registry], and serves the
+// exposition of that registry at path on addr, e.g. ":9090" and "/metrics".
+//
+// The address is listened on before Serve returns, so that errors binding it
+// are returned, and requests are served in the background. The returned
+// server's Addr is the listened address (e.g. with the port chosen for ":0"),
+// and shutting it down stops serving.
+func Serve(addr, path string, level umami.Level) (umami.Registry, umami.Group, *http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	reg := prometheus.NewRegistry()
+	registry := umami.NewRegistry(level, umami.WithDefaultBackend(NewPrometheusBackend(reg)))
+
+	mux := http.NewServeMux()
+	mux.Handle(path, promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	server := &http.Server{
+		Addr:              listener.Addr().String(),
+		Handler:           mux,
+		ReadHeaderTimeout: serveReadHeaderTimeout,
+	}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	return registry, registry.Default(), server, nil
+}
@@ -0,0 +1,41 @@
+package umami_prometheus
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/SimonDaKappa/go-umami"
+)
+
+func TestServe(t *testing.T) {
+	registry, group, server, err := Serve("127.0.0.1:0", "/metrics", umami.LevelImportant)
+	if err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+	defer server.Close()
+
+	if registry.Default() != group {
+		t.Error("expected the returned group to be the default group of the registry")
+	}
+	requests := group.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "requests_total", Help: "Requests"}}, umami.LevelImportant)
+	_ = requests.Add(group.Context(), 3)
+
+	resp, err := http.Get("http://" + server.Addr + "/metrics")
+	if err != nil {
+		t.Fatalf("failed to scrape: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read the exposition: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "default_requests_total 3") {
+		t.Errorf("expected default_requests_total 3 to be served, got %d:\n%s", resp.StatusCode, body)
+	}
+
+	if _, _, _, err := Serve(server.Addr, "/metrics", umami.LevelImportant); err == nil {
+		t.Error("expected serving on a used address to fail")
+	}
+}
@@ -0,0 +1,383 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: rate_limit_backend.go
+//
+// This file contains the [rateLimitBackend], an internal [Backend] that limits
+// the rate of the write operations of another backend's adapters with an
+// [emitLimiter] token bucket, dropping the excess. It is used by groups of
+// registries created with [WithEmitRateLimit], so that a runaway loop can't
+// overwhelm a (e.g. network) backend.
+//--------------------------------------------------------------------------------
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// emitLimiter is a token bucket shared by every group of a registry, refilled
+// at perSecond tokens per second up to a burst of perSecond tokens
+type emitLimiter struct {
+	mu        sync.Mutex
+	clock     Clock
+	perSecond float64
+	tokens    float64
+	last      time.Time // Time of the last refill
+
+	dropped atomic.Uint64 // Number of operations dropped
+}
+
+// newEmitLimiter returns a full limiter allowing perSecond operations per second
+func newEmitLimiter(perSecond int, clock Clock) *emitLimiter {
+	return &emitLimiter{
+		clock:     clock,
+		perSecond: float64(perSecond),
+		tokens:    float64(perSecond),
+		last:      clock.Now(),
+	}
+}
+
+// allow takes a token for one operation, returning false and counting the
+// operation as dropped if the bucket is empty
+func (l *emitLimiter) allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens = min(l.perSecond, l.tokens+elapsed.Seconds()*l.perSecond)
+		l.last = now
+	}
+
+	if l.tokens < 1 {
+		l.dropped.Add(1)
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// rateLimitBackend implements the [Backend] interface by wrapping the adapters
+// of another backend, limiting their write operations with an [emitLimiter].
+//
+// Reads (e.g. quantiles) and series initialization are not limited.
+type rateLimitBackend struct {
+	backend Backend
+	limiter *emitLimiter
+}
+
+// newRateLimitBackend returns a backend limiting the adapter writes of backend
+func newRateLimitBackend(backend Backend, limiter *emitLimiter) Backend {
+	return &rateLimitBackend{
+		backend: backend,
+		limiter: limiter,
+	}
+}
+
+// limit calls op if the limiter allows it, otherwise returns [ErrEmitRateLimited]
+func (r *rateLimitBackend) limit(op func() error) error {
+	if !r.limiter.allow() {
+		return ErrEmitRateLimited
+	}
+	return op()
+}
+
+// Name returns the name of the wrapped backend
+func (r *rateLimitBackend) Name() string {
+	return r.backend.Name()
+}
+
+// Unregister unregisters the metric if the wrapped backend is an [UnregisterBackend]
+func (r *rateLimitBackend) Unregister(name string) bool {
+	unregisterer, ok := r.backend.(UnregisterBackend)
+	return ok && unregisterer.Unregister(name)
+}
+
+// Gather gathers the metrics of the wrapped backend if it is a [GatherBackend]
+func (r *rateLimitBackend) Gather() ([]MetricFamily, error) {
+	gatherer, ok := r.backend.(GatherBackend)
+	if !ok {
+		return nil, ErrGatherUnsupported
+	}
+	return gatherer.Gather()
+}
+
+// Supports returns true if the wrapped backend supports the kind, see [CapabilityBackend]
+func (r *rateLimitBackend) Supports(kind MetricKind) bool {
+	return backendSupports(r.backend, kind)
+}
+
+func (r *rateLimitBackend) wrapped() Backend {
+	return r.backend
+}
+
+// GaugeFunc forwards the gauge func to the wrapped backend, see
+// [gaugeFuncBackend]. It is read, not written, so it is not limited.
+func (r *rateLimitBackend) GaugeFunc(opts GaugeOpts, fn func() float64) {
+	r.backend.(GaugeFuncBackend).GaugeFunc(opts, fn)
+}
+
+// CounterFunc forwards the counter func to the wrapped backend, see
+// [counterFuncBackend]. It is read, not written, so it is not limited.
+func (r *rateLimitBackend) CounterFunc(opts CounterOpts, fn func() float64) {
+	r.backend.(CounterFuncBackend).CounterFunc(opts, fn)
+}
+
+func (r *rateLimitBackend) Counter(opts CounterOpts) CounterAdapter {
+	return &rateLimitCounterAdapter{backend: r, internal: r.backend.Counter(opts)}
+}
+
+func (r *rateLimitBackend) CounterVec(opts CounterVecOpts) CounterVecAdapter {
+	return &rateLimitCounterVecAdapter{backend: r, internal: r.backend.CounterVec(opts)}
+}
+
+func (r *rateLimitBackend) Gauge(opts GaugeOpts) GaugeAdapter {
+	return &rateLimitGaugeAdapter{backend: r, internal: r.backend.Gauge(opts)}
+}
+
+func (r *rateLimitBackend) GaugeVec(opts GaugeVecOpts) GaugeVecAdapter {
+	return &rateLimitGaugeVecAdapter{backend: r, internal: r.backend.GaugeVec(opts)}
+}
+
+func (r *rateLimitBackend) Histogram(opts HistogramOpts) HistogramAdapter {
+	return &rateLimitHistogramAdapter{backend: r, internal: r.backend.Histogram(opts)}
+}
+
+func (r *rateLimitBackend) HistogramVec(opts HistogramVecOpts) HistogramVecAdapter {
+	return &rateLimitHistogramVecAdapter{backend: r, internal: r.backend.HistogramVec(opts)}
+}
+
+func (r *rateLimitBackend) Summary(opts SummaryOpts) SummaryAdapter {
+	return &rateLimitSummaryAdapter{backend: r, internal: r.backend.Summary(opts)}
+}
+
+func (r *rateLimitBackend) SummaryVec(opts SummaryVecOpts) SummaryVecAdapter {
+	return &rateLimitSummaryVecAdapter{backend: r, internal: r.backend.SummaryVec(opts)}
+}
+
+//--------------------------------------------------------------------------------
+// Rate Limit Adapters
+//--------------------------------------------------------------------------------
+
+type rateLimitCounterAdapter struct {
+	backend  *rateLimitBackend
+	internal CounterAdapter
+}
+
+func (a *rateLimitCounterAdapter) Inc() error {
+	return a.backend.limit(a.internal.Inc)
+}
+
+func (a *rateLimitCounterAdapter) Add(value float64) error {
+	return a.backend.limit(func() error { return a.internal.Add(value) })
+}
+
+func (a *rateLimitCounterAdapter) AddWithExemplar(value float64, exemplar VecLabels) error {
+	return a.backend.limit(func() error { return addWithExemplar(a.internal, value, exemplar) })
+}
+
+func (a *rateLimitCounterAdapter) Value() (float64, error) {
+	return adapterValue(a.internal)
+}
+
+type rateLimitCounterVecAdapter struct {
+	backend  *rateLimitBackend
+	internal CounterVecAdapter
+}
+
+func (a *rateLimitCounterVecAdapter) Inc(labels VecLabels) error {
+	return a.backend.limit(func() error { return a.internal.Inc(labels) })
+}
+
+func (a *rateLimitCounterVecAdapter) Add(value float64, labels VecLabels) error {
+	return a.backend.limit(func() error { return a.internal.Add(value, labels) })
+}
+
+func (a *rateLimitCounterVecAdapter) Series() ([]Sample, error) {
+	return adapterSeries(a.internal)
+}
+
+func (a *rateLimitCounterVecAdapter) InitLabels(labels VecLabels) error {
+	return initVecSeries(a.internal, labels, func(labels VecLabels) error { return a.internal.Add(0, labels) })
+}
+
+func (a *rateLimitCounterVecAdapter) WithLabelValues(values ...string) (CounterAdapter, error) {
+	internal, err := adapterLabelValues(a.internal, values)
+	if err != nil {
+		return nil, err
+	}
+	return &rateLimitCounterAdapter{backend: a.backend, internal: internal}, nil
+}
+
+func (a *rateLimitCounterVecAdapter) Delete(labels VecLabels) bool {
+	return adapterDelete(a.internal, labels)
+}
+
+func (a *rateLimitCounterVecAdapter) Reset() {
+	adapterReset(a.internal)
+}
+
+type rateLimitGaugeAdapter struct {
+	backend  *rateLimitBackend
+	internal GaugeAdapter
+}
+
+func (a *rateLimitGaugeAdapter) Set(value float64) error {
+	return a.backend.limit(func() error { return a.internal.Set(value) })
+}
+
+func (a *rateLimitGaugeAdapter) Inc() error {
+	return a.backend.limit(a.internal.Inc)
+}
+
+func (a *rateLimitGaugeAdapter) Dec() error {
+	return a.backend.limit(a.internal.Dec)
+}
+
+func (a *rateLimitGaugeAdapter) Add(value float64) error {
+	return a.backend.limit(func() error { return a.internal.Add(value) })
+}
+
+func (a *rateLimitGaugeAdapter) Value() (float64, error) {
+	return adapterValue(a.internal)
+}
+
+type rateLimitGaugeVecAdapter struct {
+	backend  *rateLimitBackend
+	internal GaugeVecAdapter
+}
+
+func (a *rateLimitGaugeVecAdapter) Set(value float64, labels VecLabels) error {
+	return a.backend.limit(func() error { return a.internal.Set(value, labels) })
+}
+
+func (a *rateLimitGaugeVecAdapter) Inc(labels VecLabels) error {
+	return a.backend.limit(func() error { return a.internal.Inc(labels) })
+}
+
+func (a *rateLimitGaugeVecAdapter) Dec(labels VecLabels) error {
+	return a.backend.limit(func() error { return a.internal.Dec(labels) })
+}
+
+func (a *rateLimitGaugeVecAdapter) Add(value float64, labels VecLabels) error {
+	return a.backend.limit(func() error { return a.internal.Add(value, labels) })
+}
+
+func (a *rateLimitGaugeVecAdapter) InitLabels(labels VecLabels) error {
+	return initVecSeries(a.internal, labels, func(labels VecLabels) error { return a.internal.Add(0, labels) })
+}
+
+func (a *rateLimitGaugeVecAdapter) Delete(labels VecLabels) bool {
+	return adapterDelete(a.internal, labels)
+}
+
+func (a *rateLimitGaugeVecAdapter) Reset() {
+	adapterReset(a.internal)
+}
+
+type rateLimitHistogramAdapter struct {
+	backend  *rateLimitBackend
+	internal HistogramAdapter
+}
+
+func (a *rateLimitHistogramAdapter) Observe(value float64) error {
+	return a.backend.limit(func() error { return a.internal.Observe(value) })
+}
+
+func (a *rateLimitHistogramAdapter) ObserveWithExemplar(value float64, exemplar VecLabels) error {
+	return a.backend.limit(func() error { return observeWithExemplar(a.internal, value, exemplar) })
+}
+
+type rateLimitHistogramVecAdapter struct {
+	backend  *rateLimitBackend
+	internal HistogramVecAdapter
+}
+
+func (a *rateLimitHistogramVecAdapter) Observe(value float64, labels VecLabels) error {
+	return a.backend.limit(func() error { return a.internal.Observe(value, labels) })
+}
+
+func (a *rateLimitHistogramVecAdapter) InitLabels(labels VecLabels) error {
+	return initVecSeries(a.internal, labels, nil)
+}
+
+func (a *rateLimitHistogramVecAdapter) Delete(labels VecLabels) bool {
+	return adapterDelete(a.internal, labels)
+}
+
+func (a *rateLimitHistogramVecAdapter) Reset() {
+	adapterReset(a.internal)
+}
+
+type rateLimitSummaryAdapter struct {
+	backend  *rateLimitBackend
+	internal SummaryAdapter
+}
+
+func (a *rateLimitSummaryAdapter) Observe(value float64) error {
+	return a.backend.limit(func() error { return a.internal.Observe(value) })
+}
+
+func (a *rateLimitSummaryAdapter) Quantile(q float64) (float64, error) {
+	return a.internal.Quantile(q)
+}
+
+type rateLimitSummaryVecAdapter struct {
+	backend  *rateLimitBackend
+	internal SummaryVecAdapter
+}
+
+func (a *rateLimitSummaryVecAdapter) Observe(value float64, labels VecLabels) error {
+	return a.backend.limit(func() error { return a.internal.Observe(value, labels) })
+}
+
+func (a *rateLimitSummaryVecAdapter) Quantile(q float64, labels VecLabels) (float64, error) {
+	return a.internal.Quantile(q, labels)
+}
+
+func (a *rateLimitSummaryVecAdapter) InitLabels(labels VecLabels) error {
+	return initVecSeries(a.internal, labels, nil)
+}
+
+func (a *rateLimitSummaryVecAdapter) Delete(labels VecLabels) bool {
+	return adapterDelete(a.internal, labels)
+}
+
+func (a *rateLimitSummaryVecAdapter) Reset() {
+	adapterReset(a.internal)
+}
+
+var (
+	__ctc_rateLimitBackend            Backend            = (*rateLimitBackend)(nil)
+	__ctc_rateLimitUnregisterBackend  UnregisterBackend  = (*rateLimitBackend)(nil)
+	__ctc_rateLimitGatherBackend      GatherBackend      = (*rateLimitBackend)(nil)
+	__ctc_rateLimitCapabilityBackend  CapabilityBackend  = (*rateLimitBackend)(nil)
+	__ctc_rateLimitGaugeFuncBackend   GaugeFuncBackend   = (*rateLimitBackend)(nil)
+	__ctc_rateLimitCounterFuncBackend CounterFuncBackend = (*rateLimitBackend)(nil)
+
+	__ctc_rateLimitCounterAdapter      CounterAdapter      = (*rateLimitCounterAdapter)(nil)
+	__ctc_rateLimitCounterVecAdapter   CounterVecAdapter   = (*rateLimitCounterVecAdapter)(nil)
+	__ctc_rateLimitGaugeAdapter        GaugeAdapter        = (*rateLimitGaugeAdapter)(nil)
+	__ctc_rateLimitGaugeVecAdapter     GaugeVecAdapter     = (*rateLimitGaugeVecAdapter)(nil)
+	__ctc_rateLimitHistogramAdapter    HistogramAdapter    = (*rateLimitHistogramAdapter)(nil)
+	__ctc_rateLimitHistogramVecAdapter HistogramVecAdapter = (*rateLimitHistogramVecAdapter)(nil)
+	__ctc_rateLimitSummaryAdapter      SummaryAdapter      = (*rateLimitSummaryAdapter)(nil)
+	__ctc_rateLimitSummaryVecAdapter   SummaryVecAdapter   = (*rateLimitSummaryVecAdapter)(nil)
+
+	__ctc_rateLimitCounterValueAdapter ValueAdapter             = (*rateLimitCounterAdapter)(nil)
+	__ctc_rateLimitGaugeValueAdapter   ValueAdapter             = (*rateLimitGaugeAdapter)(nil)
+	__ctc_rateLimitCounterVecSeries    SeriesAdapter            = (*rateLimitCounterVecAdapter)(nil)
+	__ctc_rateLimitCounterVecValues    LabelValuesAdapter       = (*rateLimitCounterVecAdapter)(nil)
+	__ctc_rateLimitCounterVecDelete    DeleteAdapter            = (*rateLimitCounterVecAdapter)(nil)
+	__ctc_rateLimitGaugeVecDelete      DeleteAdapter            = (*rateLimitGaugeVecAdapter)(nil)
+	__ctc_rateLimitHistogramVecDelete  DeleteAdapter            = (*rateLimitHistogramVecAdapter)(nil)
+	__ctc_rateLimitSummaryVecDelete    DeleteAdapter            = (*rateLimitSummaryVecAdapter)(nil)
+	__ctc_rateLimitCounterExemplar     ExemplarCounterAdapter   = (*rateLimitCounterAdapter)(nil)
+	__ctc_rateLimitHistogramExemplar   ExemplarHistogramAdapter = (*rateLimitHistogramAdapter)(nil)
+
+	__ctc_rateLimitCounterVecInitAdapter   VecInitAdapter = (*rateLimitCounterVecAdapter)(nil)
+	__ctc_rateLimitGaugeVecInitAdapter     VecInitAdapter = (*rateLimitGaugeVecAdapter)(nil)
+	__ctc_rateLimitHistogramVecInitAdapter VecInitAdapter = (*rateLimitHistogramVecAdapter)(nil)
+	__ctc_rateLimitSummaryVecInitAdapter   VecInitAdapter = (*rateLimitSummaryVecAdapter)(nil)
+)
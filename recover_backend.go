@@ -0,0 +1,423 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: recover_backend.go
+//
+// This file contains the [recoverBackend], an internal [Backend] that recovers
+// panics of the adapter operations of another backend, converting them to
+// [ErrBackendPanic] errors. It is used by groups of registries created with
+// [WithRecover], so that a misbehaving backend (e.g. a Prometheus label
+// cardinality panic) doesn't crash the goroutine recording a metric.
+//--------------------------------------------------------------------------------
+
+import (
+	"fmt"
+)
+
+// recoverBackend implements the [Backend] interface by wrapping the adapters
+// of another backend, recovering their panics.
+//
+// Only adapter operations, and the functions of gauge and counter funcs read
+// by the backend, are recovered. Constructing adapters still panics, as this
+// happens once at metric creation rather than on the hot path.
+type recoverBackend struct {
+	backend Backend
+	onPanic func(err error) // Called with every recovered panic, if set
+}
+
+// newRecoverBackend returns a backend recovering the adapter panics of backend
+func newRecoverBackend(backend Backend, onPanic func(err error)) Backend {
+	return &recoverBackend{
+		backend: backend,
+		onPanic: onPanic,
+	}
+}
+
+// guard calls op, converting a panic of op into an [ErrBackendPanic] error
+// naming the metric
+func (r *recoverBackend) guard(name string, op func() error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("%w: %s: %v", ErrBackendPanic, name, recovered)
+			if r.onPanic != nil {
+				r.onPanic(err)
+			}
+		}
+	}()
+
+	return op()
+}
+
+// Name returns the name of the wrapped backend
+func (r *recoverBackend) Name() string {
+	return r.backend.Name()
+}
+
+// Unregister unregisters the metric if the wrapped backend is an [UnregisterBackend]
+func (r *recoverBackend) Unregister(name string) bool {
+	unregisterer, ok := r.backend.(UnregisterBackend)
+	return ok && unregisterer.Unregister(name)
+}
+
+// Gather gathers the metrics of the wrapped backend if it is a [GatherBackend]
+func (r *recoverBackend) Gather() ([]MetricFamily, error) {
+	gatherer, ok := r.backend.(GatherBackend)
+	if !ok {
+		return nil, ErrGatherUnsupported
+	}
+	return gatherer.Gather()
+}
+
+// Supports returns true if the wrapped backend supports the kind, see [CapabilityBackend]
+func (r *recoverBackend) Supports(kind MetricKind) bool {
+	return backendSupports(r.backend, kind)
+}
+
+func (r *recoverBackend) wrapped() Backend {
+	return r.backend
+}
+
+// GaugeFunc forwards the gauge func to the wrapped backend, see
+// [gaugeFuncBackend], recovering the panics of fn
+func (r *recoverBackend) GaugeFunc(opts GaugeOpts, fn func() float64) {
+	r.backend.(GaugeFuncBackend).GaugeFunc(opts, r.guardFunc(opts.QualifiedName(), fn))
+}
+
+// CounterFunc forwards the counter func to the wrapped backend, see
+// [counterFuncBackend], recovering the panics of fn
+func (r *recoverBackend) CounterFunc(opts CounterOpts, fn func() float64) {
+	r.backend.(CounterFuncBackend).CounterFunc(opts, r.guardFunc(opts.QualifiedName(), fn))
+}
+
+// guardFunc returns fn, returning 0 instead of panicking, see [recoverBackend.guard]
+func (r *recoverBackend) guardFunc(name string, fn func() float64) func() float64 {
+	return func() (value float64) {
+		_ = r.guard(name, func() error {
+			value = fn()
+			return nil
+		})
+		return value
+	}
+}
+
+func (r *recoverBackend) Counter(opts CounterOpts) CounterAdapter {
+	return &recoverCounterAdapter{backend: r, name: opts.QualifiedName(), internal: r.backend.Counter(opts)}
+}
+
+func (r *recoverBackend) CounterVec(opts CounterVecOpts) CounterVecAdapter {
+	return &recoverCounterVecAdapter{backend: r, name: opts.QualifiedName(), internal: r.backend.CounterVec(opts)}
+}
+
+func (r *recoverBackend) Gauge(opts GaugeOpts) GaugeAdapter {
+	return &recoverGaugeAdapter{backend: r, name: opts.QualifiedName(), internal: r.backend.Gauge(opts)}
+}
+
+func (r *recoverBackend) GaugeVec(opts GaugeVecOpts) GaugeVecAdapter {
+	return &recoverGaugeVecAdapter{backend: r, name: opts.QualifiedName(), internal: r.backend.GaugeVec(opts)}
+}
+
+func (r *recoverBackend) Histogram(opts HistogramOpts) HistogramAdapter {
+	return &recoverHistogramAdapter{backend: r, name: opts.QualifiedName(), internal: r.backend.Histogram(opts)}
+}
+
+func (r *recoverBackend) HistogramVec(opts HistogramVecOpts) HistogramVecAdapter {
+	return &recoverHistogramVecAdapter{backend: r, name: opts.QualifiedName(), internal: r.backend.HistogramVec(opts)}
+}
+
+func (r *recoverBackend) Summary(opts SummaryOpts) SummaryAdapter {
+	return &recoverSummaryAdapter{backend: r, name: opts.QualifiedName(), internal: r.backend.Summary(opts)}
+}
+
+func (r *recoverBackend) SummaryVec(opts SummaryVecOpts) SummaryVecAdapter {
+	return &recoverSummaryVecAdapter{backend: r, name: opts.QualifiedName(), internal: r.backend.SummaryVec(opts)}
+}
+
+//--------------------------------------------------------------------------------
+// Recover Adapters
+//--------------------------------------------------------------------------------
+
+type recoverCounterAdapter struct {
+	backend  *recoverBackend
+	name     string
+	internal CounterAdapter
+}
+
+func (a *recoverCounterAdapter) Inc() error {
+	return a.backend.guard(a.name, a.internal.Inc)
+}
+
+func (a *recoverCounterAdapter) Add(value float64) error {
+	return a.backend.guard(a.name, func() error { return a.internal.Add(value) })
+}
+
+func (a *recoverCounterAdapter) AddWithExemplar(value float64, exemplar VecLabels) error {
+	return a.backend.guard(a.name, func() error { return addWithExemplar(a.internal, value, exemplar) })
+}
+
+func (a *recoverCounterAdapter) Value() (value float64, err error) {
+	err = a.backend.guard(a.name, func() (err error) {
+		value, err = adapterValue(a.internal)
+		return err
+	})
+	return value, err
+}
+
+type recoverCounterVecAdapter struct {
+	backend  *recoverBackend
+	name     string
+	internal CounterVecAdapter
+}
+
+func (a *recoverCounterVecAdapter) Inc(labels VecLabels) error {
+	return a.backend.guard(a.name, func() error { return a.internal.Inc(labels) })
+}
+
+func (a *recoverCounterVecAdapter) Add(value float64, labels VecLabels) error {
+	return a.backend.guard(a.name, func() error { return a.internal.Add(value, labels) })
+}
+
+func (a *recoverCounterVecAdapter) Series() (series []Sample, err error) {
+	err = a.backend.guard(a.name, func() (err error) {
+		series, err = adapterSeries(a.internal)
+		return err
+	})
+	return series, err
+}
+
+func (a *recoverCounterVecAdapter) InitLabels(labels VecLabels) error {
+	return a.backend.guard(a.name, func() error {
+		return initVecSeries(a.internal, labels, func(labels VecLabels) error { return a.internal.Add(0, labels) })
+	})
+}
+
+func (a *recoverCounterVecAdapter) WithLabelValues(values ...string) (CounterAdapter, error) {
+	var internal CounterAdapter
+	err := a.backend.guard(a.name, func() (err error) {
+		internal, err = adapterLabelValues(a.internal, values)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &recoverCounterAdapter{backend: a.backend, name: a.name, internal: internal}, nil
+}
+
+func (a *recoverCounterVecAdapter) Delete(labels VecLabels) (deleted bool) {
+	_ = a.backend.guard(a.name, func() error {
+		deleted = adapterDelete(a.internal, labels)
+		return nil
+	})
+	return deleted
+}
+
+func (a *recoverCounterVecAdapter) Reset() {
+	_ = a.backend.guard(a.name, func() error {
+		adapterReset(a.internal)
+		return nil
+	})
+}
+
+type recoverGaugeAdapter struct {
+	backend  *recoverBackend
+	name     string
+	internal GaugeAdapter
+}
+
+func (a *recoverGaugeAdapter) Set(value float64) error {
+	return a.backend.guard(a.name, func() error { return a.internal.Set(value) })
+}
+
+func (a *recoverGaugeAdapter) Inc() error {
+	return a.backend.guard(a.name, a.internal.Inc)
+}
+
+func (a *recoverGaugeAdapter) Dec() error {
+	return a.backend.guard(a.name, a.internal.Dec)
+}
+
+func (a *recoverGaugeAdapter) Add(value float64) error {
+	return a.backend.guard(a.name, func() error { return a.internal.Add(value) })
+}
+
+func (a *recoverGaugeAdapter) Value() (value float64, err error) {
+	err = a.backend.guard(a.name, func() (err error) {
+		value, err = adapterValue(a.internal)
+		return err
+	})
+	return value, err
+}
+
+type recoverGaugeVecAdapter struct {
+	backend  *recoverBackend
+	name     string
+	internal GaugeVecAdapter
+}
+
+func (a *recoverGaugeVecAdapter) Set(value float64, labels VecLabels) error {
+	return a.backend.guard(a.name, func() error { return a.internal.Set(value, labels) })
+}
+
+func (a *recoverGaugeVecAdapter) Inc(labels VecLabels) error {
+	return a.backend.guard(a.name, func() error { return a.internal.Inc(labels) })
+}
+
+func (a *recoverGaugeVecAdapter) Dec(labels VecLabels) error {
+	return a.backend.guard(a.name, func() error { return a.internal.Dec(labels) })
+}
+
+func (a *recoverGaugeVecAdapter) Add(value float64, labels VecLabels) error {
+	return a.backend.guard(a.name, func() error { return a.internal.Add(value, labels) })
+}
+
+func (a *recoverGaugeVecAdapter) InitLabels(labels VecLabels) error {
+	return a.backend.guard(a.name, func() error {
+		return initVecSeries(a.internal, labels, func(labels VecLabels) error { return a.internal.Add(0, labels) })
+	})
+}
+
+func (a *recoverGaugeVecAdapter) Delete(labels VecLabels) (deleted bool) {
+	_ = a.backend.guard(a.name, func() error {
+		deleted = adapterDelete(a.internal, labels)
+		return nil
+	})
+	return deleted
+}
+
+func (a *recoverGaugeVecAdapter) Reset() {
+	_ = a.backend.guard(a.name, func() error {
+		adapterReset(a.internal)
+		return nil
+	})
+}
+
+type recoverHistogramAdapter struct {
+	backend  *recoverBackend
+	name     string
+	internal HistogramAdapter
+}
+
+func (a *recoverHistogramAdapter) Observe(value float64) error {
+	return a.backend.guard(a.name, func() error { return a.internal.Observe(value) })
+}
+
+func (a *recoverHistogramAdapter) ObserveWithExemplar(value float64, exemplar VecLabels) error {
+	return a.backend.guard(a.name, func() error { return observeWithExemplar(a.internal, value, exemplar) })
+}
+
+type recoverHistogramVecAdapter struct {
+	backend  *recoverBackend
+	name     string
+	internal HistogramVecAdapter
+}
+
+func (a *recoverHistogramVecAdapter) Observe(value float64, labels VecLabels) error {
+	return a.backend.guard(a.name, func() error { return a.internal.Observe(value, labels) })
+}
+
+func (a *recoverHistogramVecAdapter) InitLabels(labels VecLabels) error {
+	return a.backend.guard(a.name, func() error { return initVecSeries(a.internal, labels, nil) })
+}
+
+func (a *recoverHistogramVecAdapter) Delete(labels VecLabels) (deleted bool) {
+	_ = a.backend.guard(a.name, func() error {
+		deleted = adapterDelete(a.internal, labels)
+		return nil
+	})
+	return deleted
+}
+
+func (a *recoverHistogramVecAdapter) Reset() {
+	_ = a.backend.guard(a.name, func() error {
+		adapterReset(a.internal)
+		return nil
+	})
+}
+
+type recoverSummaryAdapter struct {
+	backend  *recoverBackend
+	name     string
+	internal SummaryAdapter
+}
+
+func (a *recoverSummaryAdapter) Observe(value float64) error {
+	return a.backend.guard(a.name, func() error { return a.internal.Observe(value) })
+}
+
+func (a *recoverSummaryAdapter) Quantile(q float64) (value float64, err error) {
+	err = a.backend.guard(a.name, func() (err error) {
+		value, err = a.internal.Quantile(q)
+		return err
+	})
+	return value, err
+}
+
+type recoverSummaryVecAdapter struct {
+	backend  *recoverBackend
+	name     string
+	internal SummaryVecAdapter
+}
+
+func (a *recoverSummaryVecAdapter) Observe(value float64, labels VecLabels) error {
+	return a.backend.guard(a.name, func() error { return a.internal.Observe(value, labels) })
+}
+
+func (a *recoverSummaryVecAdapter) Quantile(q float64, labels VecLabels) (value float64, err error) {
+	err = a.backend.guard(a.name, func() (err error) {
+		value, err = a.internal.Quantile(q, labels)
+		return err
+	})
+	return value, err
+}
+
+func (a *recoverSummaryVecAdapter) InitLabels(labels VecLabels) error {
+	return a.backend.guard(a.name, func() error { return initVecSeries(a.internal, labels, nil) })
+}
+
+func (a *recoverSummaryVecAdapter) Delete(labels VecLabels) (deleted bool) {
+	_ = a.backend.guard(a.name, func() error {
+		deleted = adapterDelete(a.internal, labels)
+		return nil
+	})
+	return deleted
+}
+
+func (a *recoverSummaryVecAdapter) Reset() {
+	_ = a.backend.guard(a.name, func() error {
+		adapterReset(a.internal)
+		return nil
+	})
+}
+
+var (
+	__ctc_recoverBackend            Backend            = (*recoverBackend)(nil)
+	__ctc_recoverUnregisterBackend  UnregisterBackend  = (*recoverBackend)(nil)
+	__ctc_recoverGatherBackend      GatherBackend      = (*recoverBackend)(nil)
+	__ctc_recoverCapabilityBackend  CapabilityBackend  = (*recoverBackend)(nil)
+	__ctc_recoverGaugeFuncBackend   GaugeFuncBackend   = (*recoverBackend)(nil)
+	__ctc_recoverCounterFuncBackend CounterFuncBackend = (*recoverBackend)(nil)
+
+	__ctc_recoverCounterAdapter      CounterAdapter      = (*recoverCounterAdapter)(nil)
+	__ctc_recoverCounterVecAdapter   CounterVecAdapter   = (*recoverCounterVecAdapter)(nil)
+	__ctc_recoverGaugeAdapter        GaugeAdapter        = (*recoverGaugeAdapter)(nil)
+	__ctc_recoverGaugeVecAdapter     GaugeVecAdapter     = (*recoverGaugeVecAdapter)(nil)
+	__ctc_recoverHistogramAdapter    HistogramAdapter    = (*recoverHistogramAdapter)(nil)
+	__ctc_recoverHistogramVecAdapter HistogramVecAdapter = (*recoverHistogramVecAdapter)(nil)
+	__ctc_recoverSummaryAdapter      SummaryAdapter      = (*recoverSummaryAdapter)(nil)
+	__ctc_recoverSummaryVecAdapter   SummaryVecAdapter   = (*recoverSummaryVecAdapter)(nil)
+
+	__ctc_recoverCounterValueAdapter ValueAdapter             = (*recoverCounterAdapter)(nil)
+	__ctc_recoverGaugeValueAdapter   ValueAdapter             = (*recoverGaugeAdapter)(nil)
+	__ctc_recoverCounterVecSeries    SeriesAdapter            = (*recoverCounterVecAdapter)(nil)
+	__ctc_recoverCounterVecValues    LabelValuesAdapter       = (*recoverCounterVecAdapter)(nil)
+	__ctc_recoverCounterVecDelete    DeleteAdapter            = (*recoverCounterVecAdapter)(nil)
+	__ctc_recoverGaugeVecDelete      DeleteAdapter            = (*recoverGaugeVecAdapter)(nil)
+	__ctc_recoverHistogramVecDelete  DeleteAdapter            = (*recoverHistogramVecAdapter)(nil)
+	__ctc_recoverSummaryVecDelete    DeleteAdapter            = (*recoverSummaryVecAdapter)(nil)
+	__ctc_recoverCounterExemplar     ExemplarCounterAdapter   = (*recoverCounterAdapter)(nil)
+	__ctc_recoverHistogramExemplar   ExemplarHistogramAdapter = (*recoverHistogramAdapter)(nil)
+
+	__ctc_recoverCounterVecInitAdapter   VecInitAdapter = (*recoverCounterVecAdapter)(nil)
+	__ctc_recoverGaugeVecInitAdapter     VecInitAdapter = (*recoverGaugeVecAdapter)(nil)
+	__ctc_recoverHistogramVecInitAdapter VecInitAdapter = (*recoverHistogramVecAdapter)(nil)
+	__ctc_recoverSummaryVecInitAdapter   VecInitAdapter = (*recoverSummaryVecAdapter)(nil)
+)
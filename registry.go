@@ -17,8 +17,14 @@ package umami
 //--------------------------------------------------------------------------------
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
 	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Registry is a global level management interface for metrics
@@ -29,31 +35,339 @@ type Registry interface {
 	// Group returns a [Group] if it exists, or nil if it does not
 	Group(name string) Group
 
+	// Default returns the group named [DefaultGroupName], creating it on first
+	// use with the backend set by [WithDefaultBackend] and the global level,
+	// for applications that don't need to organize their metrics in groups:
+	//
+	//	requests := registry.Default().Counter(opts, umami.LevelImportant)
+	//
+	// Panics if the group doesn't exist and no default backend is set.
+	Default() Group
+
 	// NewGroup creates a new metric [Group] with the given name, [Backend], and [Level].
 	//
 	// If a group with the same name already exists, it is returned instead.
 	NewGroup(name string, backend Backend, level ...Level) Group
 
+	// NewMirroredGroup creates a new metric [Group] like [Registry.NewGroup], but
+	// every metric created by the group also records into each of the mirror
+	// [Backend]s, e.g. for auditing.
+	//
+	// If a group with the same name already exists, it is returned instead.
+	NewMirroredGroup(name string, backend Backend, mirrors []Backend, level ...Level) Group
+
+	// NewSubGroup creates a new metric [Group] like [Registry.NewGroup], as a
+	// sub group of the group named parent, e.g. "web_api" of "web". The name
+	// of the sub group is used as is.
+	//
+	// The sub group inherits the level of its parent: it starts at it, and
+	// follows the levels set on the parent with [Group.SetGroupLevel], until
+	// its own level is set explicitly. Levels set by the registry, e.g. by
+	// [Registry.SetGlobalLevel], apply to every group and don't change whether
+	// a group inherits its level.
+	//
+	// If a group with the same name already exists, it is returned instead.
+	// Panics if the parent group doesn't exist.
+	NewSubGroup(parent, name string, backend Backend) Group
+
+	// DeleteGroup removes the named [Group] from the registry, and stops each of
+	// its [Stoppable] metrics. Returns false if no such group exists.
+	//
+	// A group of the same name created later starts without any metrics.
+	DeleteGroup(name string) bool
+
 	// SetGlobalLevel sets the global metrics level
-	SetGlobalLevel(level Level, opts LevelOpts)
+	//
+	// If opts are omitted, the registry's default [LevelOpts] are used
+	// (see [WithDefaultLevelOpts]).
+	SetGlobalLevel(level Level, opts ...LevelOpts)
+
+	// SetGlobalMask sets the categories of metrics enabled in every [Group],
+	// including those created later, see [Group.SetGroupMask]
+	SetGlobalMask(mask Mask)
+
+	// ClampAllGroupsTo lowers the level of every [Group] above level to level,
+	// e.g. to drop to production verbosity everywhere during an incident.
+	// Unlike [Registry.SetGlobalLevel], groups already at or below level are
+	// left untouched, and so is the global level.
+	//
+	//	state := registry.SaveLevels()
+	//	registry.ClampAllGroupsTo(umami.LevelImportant, umami.LevelOpts{})
+	//	...
+	//	registry.RestoreLevels(state)
+	ClampAllGroupsTo(level Level, opts LevelOpts)
+
+	// SaveLevels captures the global level and the level of every [Group],
+	// to be restored later with [Registry.RestoreLevels].
+	//
+	//	defer registry.RestoreLevels(registry.SaveLevels())
+	SaveLevels() LevelState
+
+	// RestoreLevels restores the global and per-group levels captured by
+	// [Registry.SaveLevels], using the registry's default [LevelOpts].
+	//
+	// Groups created after the state was saved are left untouched.
+	RestoreLevels(state LevelState)
+
+	// MetricValue returns the current value of the named backend metric, e.g.
+	// "web_requests_total", gathered from the backends of the registry's groups.
+	//
+	// Returns [ErrGatherUnsupported] if no backend is a [GatherBackend], and
+	// [ErrMetricNotFound] if no backend has the metric.
+	MetricValue(name string) (MetricFamily, error)
+
+	// Dump writes the current value of every backend metric to w, gathered
+	// like [Registry.MetricValue], in the given format: [DumpFormatText] for
+	// humans, or [DumpFormatJSON] for machines (a JSON array of [MetricFamily]).
+	//
+	//	if *dumpMetrics {
+	//		return registry.Dump(os.Stdout, umami.DumpFormatText)
+	//	}
+	Dump(w io.Writer, format string) error
+
+	// Definitions returns the definitions of the backend metrics created by the
+	// registry's groups, including noop metrics and the components of composite
+	// metrics, sorted by name. Values are not included, see [Registry.Dump].
+	//
+	// This is meant for generating documentation, e.g. a catalog of metrics.
+	Definitions() []MetricDefinition
+
+	// DroppedEmits returns the number of metric operations dropped by the
+	// registry's emit rate limit (see [WithEmitRateLimit]), or by its full
+	// async queue (see [WithAsyncEmit])
+	DroppedEmits() uint64
+
+	// Flush blocks until the writes of async metrics (see [BasicMetricOpts.Async])
+	// queued by the registry's groups before the call are applied, e.g. before
+	// reading their backends directly
+	Flush()
+
+	// Close applies the queued writes of async metrics like [Registry.Flush],
+	// and stops the goroutine applying them, e.g. at shutdown. Async metrics
+	// keep working afterwards, their writes applied synchronously. Closing a
+	// closed registry does nothing.
+	Close()
 
 	// GlobalContext returns the global metrics context
 	GlobalContext() Context
+
+	// Seal forbids the creation of new metrics by the registry's groups, e.g.
+	// once the application has defined its metrics at startup, to catch metrics
+	// accidentally created per request. Creating a new metric afterwards panics
+	// with an error wrapping [ErrRegistrySealed].
+	//
+	// Existing metrics keep working, including noops converted by level
+	// changes, and requesting an existing metric again returns it.
+	Seal()
+
+	// CombinedContext returns a context whose level is the most verbose level
+	// of the named groups, e.g. for an operation that records into several
+	// groups. Names of groups that don't exist are skipped, and if none
+	// exists the context is disabled.
+	CombinedContext(groupNames ...string) Context
 }
 
+// DefaultGroupName is the name of the group returned by [Registry.Default],
+// which prefixes the names of its metrics like that of any group, e.g.
+// "default_requests_total"
+const DefaultGroupName = "default"
+
+// RegistryOption configures optional behavior of a [Registry]
+type RegistryOption func(*registry)
+
+// WithDefaultBackend sets the [Backend] of the group created by [Registry.Default]
+func WithDefaultBackend(backend Backend) RegistryOption {
+	return func(r *registry) {
+		r.defaultBackend = backend
+	}
+}
+
+// WithDefaultLevelOpts sets the [LevelOpts] used by level changes when the
+// caller does not provide any, e.g. [Registry.SetGlobalLevel] and [ApplyConfig].
+func WithDefaultLevelOpts(opts LevelOpts) RegistryOption {
+	return func(r *registry) {
+		r.defaultLevelOpts = opts
+	}
+}
+
+// WithRecover sets whether panics of backend adapter operations are recovered
+// and returned as [ErrBackendPanic] errors by the metric operations of groups
+// created afterwards. Disabled by default, so that broken metrics fail fast.
+func WithRecover(enabled bool) RegistryOption {
+	return func(r *registry) {
+		r.recoverPanics = enabled
+	}
+}
+
+// WithPanicHandler sets a function called with every panic recovered by
+// [WithRecover], e.g. to log it
+func WithPanicHandler(fn func(err error)) RegistryOption {
+	return func(r *registry) {
+		r.onPanic = fn
+	}
+}
+
+// WithWarningHandler sets a function called with the non-fatal problems of
+// metrics created by the registry's groups, e.g. [ErrConflictingObjectives],
+// to log them. Warnings are dropped by default.
+func WithWarningHandler(fn func(err error)) RegistryOption {
+	return func(r *registry) {
+		r.onWarning = fn
+	}
+}
+
+// WithSharedComponents sets whether the composite metrics of groups created
+// afterwards share their components with the group's basic metrics and with
+// other composites of the group. When enabled, a component is reused instead
+// of created when a metric with the same backend name and type already
+// exists, e.g. two caches counting their misses into the same
+// backend_requests_total counter. Disabled by default, so that such collisions
+// panic.
+//
+// A shared component keeps the level and help of the metric that created it.
+func WithSharedComponents(enabled bool) RegistryOption {
+	return func(r *registry) {
+		r.shareComponents = enabled
+	}
+}
+
+// RedeclarePolicy is what a [Group] does when a metric is requested again, by
+// name, with a different level or help than it was created with. Either way,
+// the existing metric is returned.
+type RedeclarePolicy int
+
+const (
+	// RedeclareIgnore keeps the existing metric as it is, and reports an error
+	// wrapping [ErrMetricRedeclaredWithDifferentOpts] to the registry's
+	// [WithWarningHandler]. This is the default.
+	RedeclareIgnore RedeclarePolicy = iota
+
+	// RedeclareUpdateLevel sets the existing metric to the requested level, so
+	// that the latest declaration wins. A different help is reported like
+	// [RedeclareIgnore], as it can't be changed.
+	RedeclareUpdateLevel
+
+	// RedeclarePanic panics with an error wrapping
+	// [ErrMetricRedeclaredWithDifferentOpts], like registering a conflicting
+	// collector with Prometheus' MustRegister.
+	RedeclarePanic
+)
+
+// WithRedeclarePolicy sets the [RedeclarePolicy] of groups created afterwards.
+// Defaults to [RedeclareIgnore].
+func WithRedeclarePolicy(policy RedeclarePolicy) RegistryOption {
+	return func(r *registry) {
+		r.redeclarePolicy = policy
+	}
+}
+
+// WithEmitRateLimit limits the total rate of backend write operations of the
+// metrics of groups created afterwards to perSecond, with bursts of up to
+// perSecond operations. Operations beyond the limit are dropped, return
+// [ErrEmitRateLimited], and are counted by [Registry.DroppedEmits].
+//
+// This is a safety valve protecting backends from runaway loops, not a way to
+// sample metrics: the limit is shared by every metric of the registry.
+// A perSecond of 0 or less disables the limit, which is the default.
+func WithEmitRateLimit(perSecond int) RegistryOption {
+	return func(r *registry) {
+		r.emitLimiter = nil
+		if perSecond > 0 {
+			r.emitLimiter = newEmitLimiter(perSecond, realClock{})
+		}
+	}
+}
+
+// WithAsyncEmit sets the queue applying the writes of async metrics (see
+// [BasicMetricOpts.Async]) of the registry's groups: up to queueSize writes
+// are queued, and a write waits up to deadline for room in a full queue
+// before it is dropped. A deadline of 0 or less drops writes as soon as the
+// queue is full. Defaults to [DefaultAsyncQueueSize] and [DefaultAsyncDeadline].
+//
+// The queue is applied by a single goroutine, started by the first write and
+// stopped by [Registry.Close].
+func WithAsyncEmit(queueSize int, deadline time.Duration) RegistryOption {
+	return func(r *registry) {
+		r.asyncEmitter = newAsyncEmitter(queueSize, deadline)
+	}
+}
+
+// WithErrorMetrics counts the backend adapter operations of the metrics of
+// groups created afterwards that return an error, including operations
+// dropped by [WithEmitRateLimit] and panics recovered by [WithRecover], so
+// that failures are visible even when callers drop the returned errors.
+//
+// The errors are counted in the reserved counter vec [ErrorMetricName],
+// labeled by group and metric name, created on backend. The name is reserved
+// for the registry, so groups can't create a metric of the same name.
+// A nil backend disables the counting, which is the default.
+func WithErrorMetrics(backend Backend) RegistryOption {
+	return func(r *registry) {
+		r.errorCounter = nil
+		if backend != nil {
+			r.names.claim(ErrorMetricName, reservedOwner)
+			r.errorCounter = newErrorCounter(backend)
+		}
+	}
+}
+
+// WithCreationTimestamps sets whether groups created afterwards create, along
+// with every backend metric, a gauge named like the metric suffixed with
+// [CreatedSuffix], set to the unix time at which the metric was created. This
+// mirrors the OpenMetrics "_created" series, e.g. to debug metric churn.
+//
+// Noop metrics are stamped when they are converted to real metrics, which is
+// when their backend metrics are created. Disabled by default.
+func WithCreationTimestamps(enabled bool) RegistryOption {
+	return func(r *registry) {
+		r.creationTimestamps = enabled
+	}
+}
+
+// reservedOwner is the owner of the backend names reserved by the registry
+// itself, see [nameClaims]. It is not a valid group name.
+const reservedOwner = ""
+
 // registry implements the [Registry] interface
 type registry struct {
-	mu          sync.RWMutex
-	groups      map[string]*group // Map of group name to group
-	globalLevel Level
+	mu                 sync.RWMutex
+	groups             map[string]*group // Map of group name to group
+	globalLevel        Level
+	globalMask         Mask
+	defaultLevelOpts   LevelOpts
+	defaultBackend     Backend           // Backend of the default group, see [registry.Default]
+	recoverPanics      bool              // If true, groups recover backend panics
+	creationTimestamps bool              // If true, groups stamp their metrics with their creation time
+	onPanic            func(err error)   // Called with recovered backend panics
+	onWarning          func(err error)   // Called with non-fatal metric problems
+	redeclarePolicy    RedeclarePolicy   // Applied by the groups to redeclared metrics
+	shareComponents    bool              // If true, groups share composite components, see [WithSharedComponents]
+	emitLimiter        *emitLimiter      // Shared by the groups' backends, if set
+	asyncEmitter       *asyncEmitter     // Applies the writes of the groups' async metrics
+	errorCounter       CounterVecAdapter // Counts the groups' backend errors, if set
+	names              *nameClaims       // Backend names of the groups' metrics
+	sealed             *atomic.Bool      // Set by [registry.Seal], shared with the groups
 }
 
-// NewRegistry creates a new metrics registry with the specified [Backend]
-func NewRegistry(level Level) Registry {
-	return &registry{
+// NewRegistry creates a new metrics registry with the specified global [Level]
+// and optional [RegistryOption]s
+func NewRegistry(level Level, opts ...RegistryOption) Registry {
+	r := &registry{
 		groups:      make(map[string]*group),
 		globalLevel: level,
+		globalMask:  MaskAll,
+		names:       &nameClaims{owners: make(map[string]string)},
+		sealed:      &atomic.Bool{},
+
+		asyncEmitter: newAsyncEmitter(DefaultAsyncQueueSize, DefaultAsyncDeadline),
+	}
+
+	for _, opt := range opts {
+		opt(r)
 	}
+	r.asyncEmitter.onError = r.onWarning
+
+	return r
 }
 
 // NewGroup creates a new metric [Group] with the given name, [Backend], and [Level].
@@ -67,6 +381,19 @@ func NewRegistry(level Level) Registry {
 // backend or level is requested, the existing group is returned and the new
 // parameters are ignored.
 func (m *registry) NewGroup(name string, backend Backend, level ...Level) Group {
+	return m.NewMirroredGroup(name, backend, nil, level...)
+}
+
+// NewMirroredGroup creates a new metric [Group] with the given name, primary
+// [Backend], mirror [Backend]s, and [Level]. If a group with the same name
+// already exists, it is returned instead.
+//
+// Every adapter created by the group fans out to the primary backend and each
+// mirror. Write operations are applied to all backends even if one of them
+// fails, and the errors are joined. Reads are served by the primary backend.
+//
+// Level handling is the same as [registry.NewGroup].
+func (m *registry) NewMirroredGroup(name string, backend Backend, mirrors []Backend, level ...Level) Group {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -79,11 +406,83 @@ func (m *registry) NewGroup(name string, backend Backend, level ...Level) Group
 	}
 	minLevel := slices.Min(level)
 
+	backend = newMirrorBackend(backend, mirrors...)
+	if m.creationTimestamps {
+		backend = newCreatedBackend(backend, realClock{})
+	}
+	if m.emitLimiter != nil {
+		backend = newRateLimitBackend(backend, m.emitLimiter)
+	}
+	if m.recoverPanics {
+		backend = newRecoverBackend(backend, m.onPanic)
+	}
+	if m.errorCounter != nil {
+		backend = newErrorCountBackend(backend, name, m.errorCounter)
+	}
+
 	group := newGroup(backend, name, minLevel)
+	group.mask.set(m.globalMask)
+	group.names = m.names
+	group.onWarning = m.onWarning
+	group.redeclarePolicy = m.redeclarePolicy
+	group.shareComponents = m.shareComponents
+	group.sealed = m.sealed
+	group.async = m.asyncEmitter
 	m.groups[name] = group
 	return group
 }
 
+// NewSubGroup creates a new metric [Group] inheriting the level of the group
+// named parent. If a group with the same name already exists, it is returned
+// instead.
+func (m *registry) NewSubGroup(parent, name string, backend Backend) Group {
+	m.mu.RLock()
+	existing, exists := m.groups[name]
+	parentGroup, parentExists := m.groups[parent]
+	m.mu.RUnlock()
+
+	if exists {
+		return existing
+	}
+	if !parentExists {
+		panic(fmt.Sprintf("umami: parent group %q of group %q doesn't exist", parent, name))
+	}
+
+	group := m.NewGroup(name, backend, parentGroup.level()).(*group)
+
+	// The parent holds its lock while linking, so that a level cascaded
+	// concurrently is either cascaded to the sub group or caught up below
+	parentGroup.mu.Lock()
+	defer parentGroup.mu.Unlock()
+
+	group.mu.Lock()
+	linked := group.parent != nil
+	if !linked {
+		group.parent = parentGroup
+		group.inheritsLevel = true
+	}
+	group.mu.Unlock()
+
+	if !linked {
+		parentGroup.children = append(parentGroup.children, group)
+		if group.level() != parentGroup.minLevel {
+			group.applyLevel(parentGroup.minLevel, m.defaultLevelOpts)
+		}
+	}
+	return group
+}
+
+// Default returns the default group, creating it if needed
+func (m *registry) Default() Group {
+	if group := m.Group(DefaultGroupName); group != nil {
+		return group
+	}
+	if m.defaultBackend == nil {
+		panic("umami: no default group backend (see WithDefaultBackend)")
+	}
+	return m.NewGroup(DefaultGroupName, m.defaultBackend)
+}
+
 // Group returns a metric [Group] if it exists, or nil if it does not
 func (m *registry) Group(name string) Group {
 	m.mu.Lock()
@@ -96,18 +495,222 @@ func (m *registry) Group(name string) Group {
 	return nil
 }
 
+// DeleteGroup removes the named [Group], and stops its [Stoppable] metrics
+func (m *registry) DeleteGroup(name string) bool {
+	m.mu.Lock()
+	group, exists := m.groups[name]
+	delete(m.groups, name)
+	m.mu.Unlock()
+
+	if !exists {
+		return false
+	}
+	if parent := group.parentGroup(); parent != nil {
+		parent.removeChild(group)
+	}
+	m.names.release(name)
+	group.close()
+	return true
+}
+
 // SetGlobalLevel sets the global metrics level
-func (m *registry) SetGlobalLevel(level Level, opts LevelOpts) {
+//
+// If opts are omitted, the registry's default [LevelOpts] are used.
+// Of those provided, only the first is used.
+func (m *registry) SetGlobalLevel(level Level, opts ...LevelOpts) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	levelOpts := m.levelOpts(opts)
+
 	m.globalLevel = level
 	// Update all existing groups
 	for _, group := range m.groups {
-		group.SetGroupLevel(level, opts)
+		group.applyLevel(level, levelOpts)
+	}
+}
+
+// SetGlobalMask sets the mask of every group, and of the groups created later
+func (m *registry) SetGlobalMask(mask Mask) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.globalMask = mask
+	for _, group := range m.groups {
+		group.SetGroupMask(mask)
 	}
 }
 
+// ClampAllGroupsTo lowers every group above level to level
+func (m *registry) ClampAllGroupsTo(level Level, opts LevelOpts) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, group := range m.groups {
+		if group.level() > level {
+			group.applyLevel(level, opts)
+		}
+	}
+}
+
+// LevelState is a snapshot of a [Registry]'s global and per-group levels,
+// see [Registry.SaveLevels]
+type LevelState struct {
+	Global Level
+	Groups map[string]Level // Map of group name to group level
+}
+
+// SaveLevels captures the global level and the level of every group
+func (m *registry) SaveLevels() LevelState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state := LevelState{
+		Global: m.globalLevel,
+		Groups: make(map[string]Level, len(m.groups)),
+	}
+	for name, group := range m.groups {
+		state.Groups[name] = group.level()
+	}
+
+	return state
+}
+
+// RestoreLevels restores the global and per-group levels from state
+func (m *registry) RestoreLevels(state LevelState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.globalLevel = state.Global
+	for name, level := range state.Groups {
+		if group, exists := m.groups[name]; exists {
+			group.applyLevel(level, m.defaultLevelOpts)
+		}
+	}
+}
+
+// levelOpts returns the first of the given [LevelOpts], or the registry's
+// default [LevelOpts] if none are given.
+func (m *registry) levelOpts(opts []LevelOpts) LevelOpts {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+
+	return m.defaultLevelOpts
+}
+
+// MetricValue returns the current value of the named backend metric
+//
+// The first gathered family with the given name is returned, see [registry.gather].
+func (m *registry) MetricValue(name string) (MetricFamily, error) {
+	families, err := m.gather()
+	if err != nil {
+		return MetricFamily{}, err
+	}
+
+	for _, family := range families {
+		if family.Name == name {
+			return family, nil
+		}
+	}
+
+	return MetricFamily{}, fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+}
+
+// gather gathers the backends of all groups, in group name order. A backend
+// shared by several groups is gathered once.
+//
+// Returns [ErrGatherUnsupported] if no backend is a [GatherBackend].
+func (m *registry) gather() ([]MetricFamily, error) {
+	m.mu.RLock()
+	names := make([]string, 0, len(m.groups))
+	for groupName := range m.groups {
+		names = append(names, groupName)
+	}
+	slices.Sort(names)
+	backends := make([]Backend, 0, len(names))
+	for _, groupName := range names {
+		if backend := m.groups[groupName].backend; !slices.Contains(backends, backend) {
+			backends = append(backends, backend)
+		}
+	}
+	m.mu.RUnlock()
+
+	var families []MetricFamily
+	gathered := false
+	for _, backend := range backends {
+		gatherer, ok := backend.(GatherBackend)
+		if !ok {
+			continue
+		}
+
+		backendFamilies, err := gatherer.Gather()
+		if errors.Is(err, ErrGatherUnsupported) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		gathered = true
+		families = append(families, backendFamilies...)
+	}
+
+	if !gathered {
+		return nil, ErrGatherUnsupported
+	}
+	return families, nil
+}
+
+// DroppedEmits returns the number of metric operations dropped by the emit
+// rate limit and the async queue
+func (m *registry) DroppedEmits() uint64 {
+	dropped := m.asyncEmitter.dropped.Load()
+	if m.emitLimiter != nil {
+		dropped += m.emitLimiter.dropped.Load()
+	}
+	return dropped
+}
+
+// nameClaims tracks which group uses each backend metric name, so that groups
+// of a registry don't silently share a backend metric, see [BasicMetricOpts.NoPrefix]
+type nameClaims struct {
+	mu     sync.Mutex
+	owners map[string]string // Map of backend metric name to group name
+}
+
+// claim records that the group uses name, panicking with [ErrMetricNameTaken]
+// if another group does
+func (c *nameClaims) claim(name, group string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if owner, exists := c.owners[name]; exists && owner != group {
+		panic(fmt.Errorf("%w: metric %q of group %q is already used by group %q", ErrMetricNameTaken, name, group, owner))
+	}
+	c.owners[name] = group
+}
+
+// releaseName forgets that the group uses name, e.g. once its metric is
+// removed, see [Group.Remove]
+func (c *nameClaims) releaseName(name, group string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.owners[name] == group {
+		delete(c.owners, name)
+	}
+}
+
+// release forgets every name used by the group
+func (c *nameClaims) release(group string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	maps.DeleteFunc(c.owners, func(_, owner string) bool {
+		return owner == group
+	})
+}
+
 // GlobalContext returns the global metrics context
 func (m *registry) GlobalContext() Context {
 	m.mu.RLock()
@@ -115,3 +718,33 @@ func (m *registry) GlobalContext() Context {
 
 	return NewContext(m.globalLevel)
 }
+
+// Flush blocks until the queued writes of async metrics are applied
+func (m *registry) Flush() {
+	m.asyncEmitter.wait()
+}
+
+// Close applies the queued writes of async metrics, and stops their goroutine
+func (m *registry) Close() {
+	m.asyncEmitter.close()
+}
+
+// Seal forbids the creation of new metrics by the groups of the registry
+func (m *registry) Seal() {
+	m.sealed.Store(true)
+}
+
+// CombinedContext returns a context at the most verbose level of the named groups
+func (m *registry) CombinedContext(groupNames ...string) Context {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	level := LevelDisabled
+	for _, name := range groupNames {
+		if group, exists := m.groups[name]; exists {
+			level = max(level, group.level())
+		}
+	}
+
+	return NewContext(level)
+}
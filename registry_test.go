@@ -0,0 +1,856 @@
+package umami
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRegistryDefaultLevelOpts(t *testing.T) {
+	reg := NewRegistry(LevelDebug).(*registry)
+	if got := reg.levelOpts(nil); got.ReplaceNoops {
+		t.Error("Expected default LevelOpts to not replace noops")
+	}
+
+	reg = NewRegistry(LevelDebug, WithDefaultLevelOpts(LevelOpts{ReplaceNoops: true})).(*registry)
+	if got := reg.levelOpts(nil); !got.ReplaceNoops {
+		t.Error("Expected omitted LevelOpts to use the registry default")
+	}
+
+	explicit := []LevelOpts{{ReplaceNoops: false}}
+	if got := reg.levelOpts(explicit); got.ReplaceNoops {
+		t.Error("Expected explicit LevelOpts to override the registry default")
+	}
+}
+
+func TestRegistryDefaultGroup(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	registry := NewRegistry(LevelImportant, WithDefaultBackend(backend))
+
+	requests := registry.Default().Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total"}}, LevelImportant)
+	_ = requests.Inc(registry.GlobalContext())
+	if got := backend.adapter("default_requests_total").(*mockCounterAdapter).GetCount(); got != 1 {
+		t.Errorf("Expected default_requests_total to be 1, got %v", got)
+	}
+
+	if registry.Default() != registry.Group(DefaultGroupName) {
+		t.Error("Expected the default group to be created once")
+	}
+	if level := registry.Default().Context(); !level.Enabled(LevelImportant) || level.Enabled(LevelDebug) {
+		t.Error("Expected the default group to be at the global level")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected Default to panic without a default backend")
+		}
+	}()
+	NewRegistry(LevelImportant).Default()
+}
+
+func TestRegistrySetGlobalLevelOmittedOpts(t *testing.T) {
+	reg := NewRegistry(LevelCritical, WithDefaultLevelOpts(LevelOpts{ReplaceNoops: true}))
+	backend := NewMockBackend().(*mockBackend)
+	group := reg.NewGroup("test_group", backend)
+
+	requests := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total"}}, LevelImportant)
+	if !requests.(SwitchableMetric).IsNoop() {
+		t.Fatal("Expected a noop counter below the global level")
+	}
+
+	// Raising the level without opts applies the registry default, replacing noops
+	reg.SetGlobalLevel(LevelDebug)
+
+	if requests.(SwitchableMetric).IsNoop() {
+		t.Fatal("Expected the default LevelOpts to replace the noop counter")
+	}
+	_ = requests.Inc(group.Context())
+	adapter, ok := backend.adapter("test_group_requests_total").(*mockCounterAdapter)
+	if !ok || adapter.GetCount() != 1 {
+		t.Errorf("Expected the replaced counter to write to the backend, got %v", backend.adapter("test_group_requests_total"))
+	}
+
+	reg.SetGlobalLevel(LevelCritical)
+
+	if !reg.GlobalContext().Enabled(LevelCritical) || reg.GlobalContext().Enabled(LevelImportant) {
+		t.Error("Expected global context to be at LevelCritical")
+	}
+	if !group.Context().Enabled(LevelCritical) || group.Context().Enabled(LevelImportant) {
+		t.Error("Expected group context to follow the global level")
+	}
+}
+
+func TestRegistryMirroredGroup(t *testing.T) {
+	primary := NewMockBackend().(*mockBackend)
+	audit := NewMockBackend().(*mockBackend)
+	reg := NewRegistry(LevelDebug)
+
+	mirrored := reg.NewMirroredGroup("web", primary, []Backend{audit})
+	plain := reg.NewGroup("db", primary)
+
+	webCounter := mirrored.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests", Help: "Requests"}}, LevelCritical)
+	dbCounter := plain.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "queries", Help: "Queries"}}, LevelCritical)
+
+	for range 2 {
+		if err := webCounter.Inc(mirrored.Context()); err != nil {
+			t.Fatalf("Counter.Inc() failed: %v", err)
+		}
+	}
+	if err := dbCounter.Inc(plain.Context()); err != nil {
+		t.Fatalf("Counter.Inc() failed: %v", err)
+	}
+
+	for _, backend := range []*mockBackend{primary, audit} {
+		adapter, ok := backend.adapter("web_requests").(*mockCounterAdapter)
+		if !ok {
+			t.Fatalf("Expected web_requests adapter on every backend")
+		}
+		if got := adapter.GetCount(); got != 2 {
+			t.Errorf("Expected web_requests count 2, got %v", got)
+		}
+	}
+
+	if primary.adapter("db_queries") == nil {
+		t.Error("Expected db_queries on the primary backend")
+	}
+	if audit.adapter("db_queries") != nil {
+		t.Error("Expected db_queries to not be mirrored to the audit backend")
+	}
+}
+
+func TestRegistrySaveRestoreLevels(t *testing.T) {
+	reg := NewRegistry(LevelImportant)
+	web := reg.NewGroup("web", NewMockBackend())
+	db := reg.NewGroup("db", NewMockBackend(), LevelCritical)
+
+	state := reg.SaveLevels()
+
+	reg.SetGlobalLevel(LevelVerbose)
+	db.SetGroupLevel(LevelDebug, LevelOpts{})
+	if !web.Context().Enabled(LevelVerbose) || !db.Context().Enabled(LevelDebug) {
+		t.Fatal("Expected levels to be raised before restore")
+	}
+
+	reg.RestoreLevels(state)
+
+	if !reg.GlobalContext().Enabled(LevelImportant) || reg.GlobalContext().Enabled(LevelDebug) {
+		t.Error("Expected global level to revert to LevelImportant")
+	}
+	if !web.Context().Enabled(LevelImportant) || web.Context().Enabled(LevelDebug) {
+		t.Error("Expected web group level to revert to LevelImportant")
+	}
+	if !db.Context().Enabled(LevelCritical) || db.Context().Enabled(LevelImportant) {
+		t.Error("Expected db group level to revert to LevelCritical")
+	}
+}
+
+func TestRegistryCombinedContext(t *testing.T) {
+	reg := NewRegistry(LevelImportant)
+	reg.NewGroup("web", NewMockBackend(), LevelCritical)
+	reg.NewGroup("db", NewMockBackend(), LevelDebug)
+	reg.NewGroup("cache", NewMockBackend(), LevelImportant)
+
+	ctx := reg.CombinedContext("web", "db", "cache")
+	if !ctx.Enabled(LevelDebug) || ctx.Enabled(LevelVerbose) {
+		t.Error("Expected the combined context to be at the most verbose level, LevelDebug")
+	}
+
+	ctx = reg.CombinedContext("web", "cache")
+	if !ctx.Enabled(LevelImportant) || ctx.Enabled(LevelDebug) {
+		t.Error("Expected the combined context of web and cache to be at LevelImportant")
+	}
+
+	ctx = reg.CombinedContext("web", "missing")
+	if !ctx.Enabled(LevelCritical) || ctx.Enabled(LevelImportant) {
+		t.Error("Expected missing groups to be skipped")
+	}
+
+	if reg.CombinedContext("missing").Enabled(LevelCritical) || reg.CombinedContext().Enabled(LevelCritical) {
+		t.Error("Expected a disabled context when no named group exists")
+	}
+}
+
+func TestRegistrySeal(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	reg := NewRegistry(LevelImportant)
+	group := reg.NewGroup("web", backend)
+
+	requests := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total"}}, LevelCritical)
+	cache := group.Cache(CacheOpts{MetricInfo: MetricInfo{Name: "cache"}}, LevelDebug)
+
+	reg.Seal()
+
+	// Existing metrics keep working, and can be requested again
+	if err := requests.Inc(group.Context()); err != nil {
+		t.Errorf("Expected an existing metric to record after sealing, got %v", err)
+	}
+	if again := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total"}}, LevelCritical); again != requests {
+		t.Error("Expected the existing metric to be returned after sealing")
+	}
+	group.SetGroupLevel(LevelDebug, LevelOpts{ReplaceNoops: true})
+	if cache.(SwitchableMetric).IsNoop() {
+		t.Error("Expected noops to be converted after sealing")
+	}
+
+	assertSealedPanics := func(name string, create func()) {
+		t.Helper()
+		defer func() {
+			err, _ := recover().(error)
+			if !errors.Is(err, ErrRegistrySealed) {
+				t.Errorf("Expected %s to panic with ErrRegistrySealed, got %v", name, err)
+			}
+		}()
+		create()
+	}
+	assertSealedPanics("a new basic", func() {
+		group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "errors_total"}}, LevelCritical)
+	})
+	assertSealedPanics("a new composite", func() {
+		group.Timer(TimerOpts{MetricInfo: MetricInfo{Name: "op"}}, LevelCritical)
+	})
+	assertSealedPanics("a metric of a new group", func() {
+		reg.NewGroup("db", NewMockBackend()).Gauge(GaugeOpts{MetricInfo: MetricInfo{Name: "connections"}}, LevelCritical)
+	})
+}
+
+func TestRegistrySubGroupInheritsLevel(t *testing.T) {
+	reg := NewRegistry(LevelImportant)
+	web := reg.NewGroup("web", NewMockBackend(), LevelCritical)
+	api := reg.NewSubGroup("web", "web_api", NewMockBackend())
+	admin := reg.NewSubGroup("web", "web_admin", NewMockBackend())
+	v1 := reg.NewSubGroup("web_api", "web_api_v1", NewMockBackend())
+
+	if levels := reg.SaveLevels(); levels.Groups["web_api"] != LevelCritical || levels.Groups["web_api_v1"] != LevelCritical {
+		t.Fatalf("Expected sub groups to start at the level of their parent, got %v", levels.Groups)
+	}
+
+	counter := v1.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests"}}, LevelDebug)
+	admin.SetGroupLevel(LevelImportant, LevelOpts{})
+	web.SetGroupLevel(LevelDebug, LevelOpts{ReplaceNoops: true})
+
+	want := map[string]Level{
+		"web":        LevelDebug,
+		"web_api":    LevelDebug,
+		"web_api_v1": LevelDebug,     // Cascaded through web_api
+		"web_admin":  LevelImportant, // Explicit levels are kept
+	}
+	levels := reg.SaveLevels()
+	for name, level := range want {
+		if levels.Groups[name] != level {
+			t.Errorf("Expected group %s at %v, got %v", name, level, levels.Groups[name])
+		}
+	}
+	if counter.(SwitchableMetric).IsNoop() {
+		t.Error("Expected the cascaded level to replace noops")
+	}
+
+	// Once set explicitly, a sub group stops inheriting
+	api.SetGroupLevel(LevelCritical, LevelOpts{})
+	web.SetGroupLevel(LevelVerbose, LevelOpts{})
+	if levels := reg.SaveLevels(); levels.Groups["web_api"] != LevelCritical || levels.Groups["web_api_v1"] != LevelCritical {
+		t.Errorf("Expected web_api and its sub group to keep the explicit level, got %v", levels.Groups)
+	}
+
+	// Registry-wide levels don't make levels explicit
+	reg.SetGlobalLevel(LevelImportant)
+	web.SetGroupLevel(LevelDebug, LevelOpts{})
+	if level := reg.SaveLevels().Groups["web_admin"]; level != LevelImportant {
+		t.Errorf("Expected web_admin to stay explicit, got %v", level)
+	}
+	if level := reg.SaveLevels().Groups["web_api_v1"]; level != LevelImportant {
+		t.Errorf("Expected web_api_v1 to follow web_api, got %v", level)
+	}
+
+	if reg.NewSubGroup("web", "web_api", NewMockBackend()) != api {
+		t.Error("Expected an existing group to be returned")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a sub group of a missing parent to panic")
+		}
+	}()
+	reg.NewSubGroup("db", "db_reads", NewMockBackend())
+}
+
+func TestRegistryClampAllGroupsTo(t *testing.T) {
+	reg := NewRegistry(LevelDebug)
+	verbose := reg.NewGroup("verbose", NewMockBackend(), LevelVerbose)
+	debug := reg.NewGroup("debug", NewMockBackend())
+	important := reg.NewGroup("important", NewMockBackend(), LevelImportant)
+	critical := reg.NewGroup("critical", NewMockBackend(), LevelCritical)
+
+	counter := verbose.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests"}}, LevelVerbose)
+
+	reg.ClampAllGroupsTo(LevelImportant, LevelOpts{})
+
+	levels := reg.SaveLevels()
+	want := map[string]Level{
+		"verbose":   LevelImportant,
+		"debug":     LevelImportant,
+		"important": LevelImportant,
+		"critical":  LevelCritical, // Lower groups are not raised
+	}
+	for name, level := range want {
+		if levels.Groups[name] != level {
+			t.Errorf("Expected group %s at %v, got %v", name, level, levels.Groups[name])
+		}
+	}
+	if levels.Global != LevelDebug {
+		t.Errorf("Expected the global level to be kept, got %v", levels.Global)
+	}
+
+	if counter.Level() != LevelImportant {
+		t.Errorf("Expected the metrics of clamped groups to be lowered, got %v", counter.Level())
+	}
+	if verbose.Context().Enabled(LevelDebug) || debug.Context().Enabled(LevelDebug) {
+		t.Error("Expected clamped groups to disable debug metrics")
+	}
+	if !important.Context().Enabled(LevelImportant) || critical.Context().Enabled(LevelImportant) {
+		t.Error("Expected unclamped groups to keep their level")
+	}
+}
+
+func TestRegistryDeleteGroupStopsMetrics(t *testing.T) {
+	reg := NewRegistry(LevelDebug)
+	baseline := runtime.NumGoroutine()
+
+	group := reg.NewGroup("jobs", NewMockBackend())
+	newTestThroughput(group, "started")
+	newTestThroughput(group, "stopped").Stop()
+
+	// A started noop owns a ticker as well
+	disabled := reg.NewGroup("disabled", NewMockBackend(), LevelDisabled)
+	newTestThroughput(disabled, "noop")
+
+	if !reg.DeleteGroup("jobs") || !reg.DeleteGroup("disabled") {
+		t.Fatal("Expected the groups to be deleted")
+	}
+	assertNoGoroutineLeak(t, baseline)
+
+	if reg.Group("jobs") != nil {
+		t.Error("Expected the group to no longer exist")
+	}
+	if reg.DeleteGroup("jobs") {
+		t.Error("Expected deleting a missing group to return false")
+	}
+}
+
+func TestRegistryMetricValue(t *testing.T) {
+	reg := NewRegistry(LevelDebug)
+	group := reg.NewGroup("web", NewMockBackend())
+	ctx := group.Context()
+
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total"}}, LevelImportant)
+	counter.Add(ctx, 3)
+	counterVec := group.CounterVec(CounterVecOpts{MetricInfo: MetricInfo{Name: "errors_total"}, Labels: []string{"code"}}, LevelImportant)
+	counterVec.Inc(ctx, VecLabels{"code": "500"})
+	counterVec.Inc(ctx, VecLabels{"code": "500"})
+
+	family, err := reg.MetricValue("web_requests_total")
+	if err != nil {
+		t.Fatalf("MetricValue() failed: %v", err)
+	}
+	if family.Kind != MetricKindCounter || len(family.Samples) != 1 || family.Samples[0].Value != 3 {
+		t.Errorf("Expected a counter with value 3, got %+v", family)
+	}
+
+	family, err = reg.MetricValue("web_errors_total")
+	if err != nil {
+		t.Fatalf("MetricValue() failed: %v", err)
+	}
+	if len(family.Samples) != 1 || family.Samples[0].Labels["code"] != "500" || family.Samples[0].Value != 2 {
+		t.Errorf("Expected a single code=500 sample with value 2, got %+v", family)
+	}
+
+	if _, err := reg.MetricValue("web_missing"); !errors.Is(err, ErrMetricNotFound) {
+		t.Errorf("Expected ErrMetricNotFound, got %v", err)
+	}
+}
+
+func TestRegistryMetricValueUnsupported(t *testing.T) {
+	reg := NewRegistry(LevelDebug)
+	reg.NewGroup("web", struct{ Backend }{NewMockBackend()})
+
+	if _, err := reg.MetricValue("web_requests_total"); !errors.Is(err, ErrGatherUnsupported) {
+		t.Errorf("Expected ErrGatherUnsupported, got %v", err)
+	}
+}
+
+// panickingBackend is a [Backend] whose counter adapters panic
+type panickingBackend struct {
+	Backend
+}
+
+func (p panickingBackend) Counter(opts CounterOpts) CounterAdapter {
+	return panickingCounterAdapter{}
+}
+
+type panickingCounterAdapter struct{}
+
+func (panickingCounterAdapter) Inc() error { panic("inconsistent label cardinality") }
+
+func (panickingCounterAdapter) Add(float64) error { panic("inconsistent label cardinality") }
+
+func TestRegistryWithRecover(t *testing.T) {
+	var handled []error
+	reg := NewRegistry(LevelDebug, WithRecover(true), WithPanicHandler(func(err error) {
+		handled = append(handled, err)
+	}))
+	group := reg.NewGroup("web", panickingBackend{NewMockBackend()})
+
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total"}}, LevelImportant)
+	err := counter.Inc(group.Context())
+	if !errors.Is(err, ErrBackendPanic) {
+		t.Fatalf("Expected ErrBackendPanic, got %v", err)
+	}
+	if len(handled) != 1 || handled[0] != err {
+		t.Errorf("Expected the panic handler to be called with %v, got %v", err, handled)
+	}
+
+	// Other adapters are unaffected
+	gauge := group.Gauge(GaugeOpts{MetricInfo: MetricInfo{Name: "in_flight"}}, LevelImportant)
+	if err := gauge.Set(group.Context(), 1); err != nil {
+		t.Errorf("Expected no error from a well-behaved adapter, got %v", err)
+	}
+}
+
+// TestRegistryWrappersForwardFuncs checks that the backend wrappers of the
+// registry options keep gauges from atomics read at gather time, rather than
+// sampled
+func TestRegistryWrappersForwardFuncs(t *testing.T) {
+	tests := []struct {
+		name   string
+		option RegistryOption
+	}{
+		{"recover", WithRecover(true)},
+		{"creation timestamps", WithCreationTimestamps(true)},
+		{"emit rate limit", WithEmitRateLimit(100)},
+		{"error metrics", WithErrorMetrics(NewMockBackend())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := NewMockBackend().(*mockBackend)
+			group := NewRegistry(LevelDebug, tt.option).NewGroup("web", backend)
+
+			var inFlight atomic.Int64
+			group.GaugeFromAtomic(GaugeOpts{MetricInfo: MetricInfo{Name: "in_flight"}}, LevelImportant, &inFlight)
+			inFlight.Store(7)
+
+			families, err := backend.Gather()
+			if err != nil {
+				t.Fatalf("Expected the backend to gather, got %v", err)
+			}
+			for _, family := range families {
+				if family.Name == "web_in_flight" {
+					if len(family.Samples) != 1 || family.Samples[0].Value != 7 {
+						t.Errorf("Expected the gauge to reflect the atomic at gather time, got %v", family.Samples)
+					}
+					return
+				}
+			}
+			t.Error("Expected the gauge to be gathered")
+		})
+	}
+}
+
+func TestRegistryWithRecoverGaugeFunc(t *testing.T) {
+	var handled []error
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelDebug, WithRecover(true), WithPanicHandler(func(err error) {
+		handled = append(handled, err)
+	})).NewGroup("web", backend)
+
+	group.GaugeFunc(GaugeOpts{MetricInfo: MetricInfo{Name: "queue_length"}}, func() float64 { panic("closed queue") }, LevelImportant)
+	adapter, ok := backend.adapter("web_queue_length").(*mockGaugeFuncAdapter)
+	if !ok {
+		t.Fatalf("Expected a gauge func adapter, got %T", backend.adapter("web_queue_length"))
+	}
+	if got := adapter.GetValue(); got != 0 {
+		t.Errorf("Expected a panicking function to read 0, got %v", got)
+	}
+	if len(handled) != 1 || !errors.Is(handled[0], ErrBackendPanic) {
+		t.Errorf("Expected the panic handler to be called with ErrBackendPanic, got %v", handled)
+	}
+}
+
+func TestRegistryWithoutRecoverPanics(t *testing.T) {
+	group := NewRegistry(LevelDebug).NewGroup("web", panickingBackend{NewMockBackend()})
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total"}}, LevelImportant)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected the backend panic to propagate by default")
+		}
+	}()
+	counter.Inc(group.Context())
+}
+
+func TestRegistryWithEmitRateLimit(t *testing.T) {
+	reg := NewRegistry(LevelDebug, WithEmitRateLimit(3))
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	reg.(*registry).emitLimiter = newEmitLimiter(3, clock)
+
+	// The limit is shared by the groups of the registry
+	backend := NewMockBackend().(*mockBackend)
+	web := reg.NewGroup("web", backend)
+	db := reg.NewGroup("db", NewMockBackend())
+	requests := web.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total"}}, LevelImportant)
+	queries := db.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "queries_total"}}, LevelImportant)
+
+	for range 2 {
+		if err := requests.Inc(web.Context()); err != nil {
+			t.Fatalf("Expected operations within the limit to succeed, got %v", err)
+		}
+	}
+	if err := queries.Inc(db.Context()); err != nil {
+		t.Fatalf("Expected operations within the limit to succeed, got %v", err)
+	}
+	if err := queries.Inc(db.Context()); !errors.Is(err, ErrEmitRateLimited) {
+		t.Errorf("Expected ErrEmitRateLimited, got %v", err)
+	}
+	requests.Inc(web.Context())
+	if got := reg.DroppedEmits(); got != 2 {
+		t.Errorf("Expected 2 dropped operations, got %v", got)
+	}
+	if got := backend.adapter("web_requests_total").(*mockCounterAdapter).GetCount(); got != 2 {
+		t.Errorf("Expected dropped operations not to be recorded, got %v", got)
+	}
+
+	// The bucket refills over time
+	clock.Advance(400 * time.Millisecond)
+	if err := queries.Inc(db.Context()); err != nil {
+		t.Errorf("Expected a refilled token to allow an operation, got %v", err)
+	}
+	if err := queries.Inc(db.Context()); !errors.Is(err, ErrEmitRateLimited) {
+		t.Errorf("Expected ErrEmitRateLimited once the refill is spent, got %v", err)
+	}
+	if got := reg.DroppedEmits(); got != 3 {
+		t.Errorf("Expected 3 dropped operations, got %v", got)
+	}
+}
+
+func TestRegistryWithoutEmitRateLimit(t *testing.T) {
+	reg := NewRegistry(LevelDebug)
+	group := reg.NewGroup("web", NewMockBackend())
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total"}}, LevelImportant)
+
+	for range 1000 {
+		if err := counter.Inc(group.Context()); err != nil {
+			t.Fatalf("Expected no limit by default, got %v", err)
+		}
+	}
+	if got := reg.DroppedEmits(); got != 0 {
+		t.Errorf("Expected no dropped operations, got %v", got)
+	}
+}
+
+// erroringBackend is a [Backend] whose counter adapters fail every operation
+type erroringBackend struct {
+	Backend
+}
+
+func (e erroringBackend) Counter(opts CounterOpts) CounterAdapter {
+	return erroringCounterAdapter{}
+}
+
+var errBackendDown = errors.New("backend down")
+
+type erroringCounterAdapter struct{}
+
+func (erroringCounterAdapter) Inc() error { return errBackendDown }
+
+func (erroringCounterAdapter) Add(float64) error { return errBackendDown }
+
+func TestRegistryWithErrorMetrics(t *testing.T) {
+	errorsBackend := NewMockBackend().(*mockBackend)
+	reg := NewRegistry(LevelDebug, WithErrorMetrics(errorsBackend))
+	group := reg.NewGroup("web", erroringBackend{NewMockBackend()})
+	ctx := group.Context()
+
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total"}}, LevelImportant)
+	for range 3 {
+		if err := counter.Inc(ctx); !errors.Is(err, errBackendDown) {
+			t.Fatalf("Expected the backend error to be returned, got %v", err)
+		}
+	}
+
+	// Successful operations are not counted
+	gauge := group.Gauge(GaugeOpts{MetricInfo: MetricInfo{Name: "in_flight"}}, LevelImportant)
+	if err := gauge.Set(ctx, 1); err != nil {
+		t.Fatalf("Expected no error from a well-behaved adapter, got %v", err)
+	}
+
+	errorCounts := errorsBackend.adapter(ErrorMetricName).(*mockCounterVecAdapter)
+	if got := errorCounts.GetCount(VecLabels{"group": "web", "metric": "web_requests_total"}); got != 3 {
+		t.Errorf("Expected 3 errors of web_requests_total, got %v", got)
+	}
+	if got := errorCounts.GetCount(VecLabels{"group": "web", "metric": "web_in_flight"}); got != 0 {
+		t.Errorf("Expected no errors of web_in_flight, got %v", got)
+	}
+
+	// The name of the error metric is reserved
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected creating a metric with the reserved name to panic")
+		}
+	}()
+	group.Counter(CounterOpts{BasicMetricOpts: BasicMetricOpts{NoPrefix: true}, MetricInfo: MetricInfo{Name: ErrorMetricName}}, LevelImportant)
+}
+
+func TestRegistryWithCreationTimestamps(t *testing.T) {
+	backend := NewMockBackend().(*mockBackend)
+	group := NewRegistry(LevelImportant, WithCreationTimestamps(true)).NewGroup("web", backend)
+
+	before := time.Now()
+	group.Cache(CacheOpts{
+		MetricInfo: MetricInfo{Name: "cache"},
+		HitOpts:    CounterOpts{MetricInfo: MetricInfo{Name: "cache_hits"}},
+		MissOpts:   CounterOpts{MetricInfo: MetricInfo{Name: "cache_misses"}},
+		SizeOpts:   GaugeOpts{MetricInfo: MetricInfo{Name: "cache_size"}},
+	}, LevelImportant)
+	group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "debug_total"}}, LevelDebug)
+
+	for _, name := range []string{"web_cache_hits", "web_cache_misses", "web_cache_size"} {
+		adapter, ok := backend.adapter(name + CreatedSuffix).(*mockGaugeAdapter)
+		if !ok {
+			t.Fatalf("Expected a creation timestamp gauge for %s", name)
+		}
+		if created := adapter.GetValue(); created < float64(before.Unix()) || created > float64(time.Now().Unix()+1) {
+			t.Errorf("Expected the creation time of %s, got %v", name, created)
+		}
+	}
+	if backend.adapter("web_debug_total"+CreatedSuffix) != nil {
+		t.Error("Expected no creation timestamp for a noop metric")
+	}
+
+	// Noop metrics are stamped when converted
+	group.SetGroupLevel(LevelDebug, LevelOpts{ReplaceNoops: true})
+	if backend.adapter("web_debug_total"+CreatedSuffix) == nil {
+		t.Error("Expected a creation timestamp once the noop is converted")
+	}
+}
+
+func TestRegistryDump(t *testing.T) {
+	reg := NewRegistry(LevelDebug)
+	group := reg.NewGroup("cli", NewMockBackend())
+	ctx := group.Context()
+
+	group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "files_total"}}, LevelImportant).Add(ctx, 7)
+	group.CounterVec(CounterVecOpts{MetricInfo: MetricInfo{Name: "errors_total"}, Labels: []string{"kind"}}, LevelImportant).
+		Inc(ctx, VecLabels{"kind": "io"})
+
+	var text strings.Builder
+	if err := reg.Dump(&text, DumpFormatText); err != nil {
+		t.Fatalf("Dump(text) failed: %v", err)
+	}
+	for _, line := range []string{"cli_files_total counter 7", `cli_errors_total{kind="io"} counter 1`} {
+		if !strings.Contains(text.String(), line) {
+			t.Errorf("Expected text dump to contain %q, got:\n%s", line, text.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := reg.Dump(&buf, DumpFormatJSON); err != nil {
+		t.Fatalf("Dump(json) failed: %v", err)
+	}
+	var families []MetricFamily
+	if err := json.Unmarshal(buf.Bytes(), &families); err != nil {
+		t.Fatalf("Expected a JSON array of families, got %v:\n%s", err, buf.String())
+	}
+	found := false
+	for _, family := range families {
+		if family.Name == "cli_files_total" && family.Kind == MetricKindCounter && family.Samples[0].Value == 7 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected JSON dump to contain cli_files_total, got:\n%s", buf.String())
+	}
+
+	if err := reg.Dump(&buf, "xml"); !errors.Is(err, ErrUnknownDumpFormat) {
+		t.Errorf("Expected ErrUnknownDumpFormat, got %v", err)
+	}
+}
+
+// blockingBackend is a [Backend] whose counter adapters block every operation
+// until release is closed, signaling entered as they start blocking
+type blockingBackend struct {
+	Backend
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newBlockingBackend() *blockingBackend {
+	return &blockingBackend{
+		Backend: NewMockBackend(),
+		entered: make(chan struct{}, 16),
+		release: make(chan struct{}),
+	}
+}
+
+func (b *blockingBackend) Counter(opts CounterOpts) CounterAdapter {
+	return blockingCounterAdapter{backend: b, internal: b.Backend.Counter(opts)}
+}
+
+type blockingCounterAdapter struct {
+	backend  *blockingBackend
+	internal CounterAdapter
+}
+
+func (a blockingCounterAdapter) Inc() error {
+	a.backend.entered <- struct{}{}
+	<-a.backend.release
+	return a.internal.Inc()
+}
+
+func (a blockingCounterAdapter) Add(value float64) error {
+	a.backend.entered <- struct{}{}
+	<-a.backend.release
+	return a.internal.Add(value)
+}
+
+func TestRegistryAsyncMetric(t *testing.T) {
+	reg := NewRegistry(LevelDebug)
+	backend := newBlockingBackend()
+	group := reg.NewGroup("web", backend)
+	asyncCounter := group.Counter(CounterOpts{BasicMetricOpts: BasicMetricOpts{Async: true}, MetricInfo: MetricInfo{Name: "emits_total"}}, LevelImportant)
+	syncCounter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total"}}, LevelImportant)
+	ctx := group.Context()
+
+	// The async metric returns while its backend blocks
+	if err := asyncCounter.Inc(ctx); err != nil {
+		t.Fatalf("Expected the async operation to be queued, got %v", err)
+	}
+
+	// The sync metric blocks until its backend returns
+	done := make(chan error)
+	go func() { done <- syncCounter.Inc(ctx) }()
+	<-backend.entered
+	<-backend.entered
+	select {
+	case <-done:
+		t.Fatal("Expected the sync operation to block on its backend")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(backend.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Sync operation failed: %v", err)
+	}
+	reg.(*registry).asyncEmitter.wait()
+
+	mock := backend.Backend.(*mockBackend)
+	for _, name := range []string{"web_emits_total", "web_requests_total"} {
+		if got := mock.adapter(name).(*mockCounterAdapter).GetCount(); got != 1 {
+			t.Errorf("Expected %s to be recorded once, got %v", name, got)
+		}
+	}
+}
+
+func TestRegistryAsyncQueueFull(t *testing.T) {
+	reg := NewRegistry(LevelDebug, WithAsyncEmit(1, 0))
+	backend := newBlockingBackend()
+	group := reg.NewGroup("web", backend)
+	counter := group.Counter(CounterOpts{BasicMetricOpts: BasicMetricOpts{Async: true}, MetricInfo: MetricInfo{Name: "emits_total"}}, LevelImportant)
+	ctx := group.Context()
+
+	// The first operation blocks the emitter, and the second fills the queue
+	_ = counter.Inc(ctx)
+	<-backend.entered
+	if err := counter.Inc(ctx); err != nil {
+		t.Fatalf("Expected the operation to be queued, got %v", err)
+	}
+	if err := counter.Inc(ctx); !errors.Is(err, ErrAsyncQueueFull) {
+		t.Errorf("Expected ErrAsyncQueueFull, got %v", err)
+	}
+	if got := reg.DroppedEmits(); got != 1 {
+		t.Errorf("Expected 1 dropped operation, got %v", got)
+	}
+
+	close(backend.release)
+	reg.(*registry).asyncEmitter.wait()
+	if got := backend.Backend.(*mockBackend).adapter("web_emits_total").(*mockCounterAdapter).GetCount(); got != 2 {
+		t.Errorf("Expected the queued operations to be recorded, got %v", got)
+	}
+}
+
+func TestRegistryAsyncReadsOwnWrites(t *testing.T) {
+	reg := NewRegistry(LevelDebug)
+	defer reg.Close()
+	group := reg.NewGroup("web", NewMockBackend())
+	gauge := group.Gauge(GaugeOpts{BasicMetricOpts: BasicMetricOpts{Async: true}, MetricInfo: MetricInfo{Name: "inflight"}}, LevelImportant)
+	ctx := group.Context()
+
+	for i := range 100 {
+		if err := gauge.Set(ctx, float64(i)); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		value, err := gauge.Value(ctx)
+		if err != nil {
+			t.Fatalf("Value failed: %v", err)
+		}
+		if value != float64(i) {
+			t.Fatalf("Expected the read to see the queued write %v, got %v", i, value)
+		}
+	}
+}
+
+func TestRegistryAsyncClose(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	reg := NewRegistry(LevelDebug, WithAsyncEmit(16, time.Second))
+	backend := newBlockingBackend()
+	group := reg.NewGroup("web", backend)
+	counter := group.Counter(CounterOpts{BasicMetricOpts: BasicMetricOpts{Async: true}, MetricInfo: MetricInfo{Name: "emits_total"}}, LevelImportant)
+	ctx := group.Context()
+
+	for range 3 {
+		if err := counter.Inc(ctx); err != nil {
+			t.Fatalf("Expected the operation to be queued, got %v", err)
+		}
+	}
+	<-backend.entered
+
+	closed := make(chan struct{})
+	go func() {
+		reg.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Expected Close to wait for the queued operations")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(backend.release)
+	<-closed
+	mock := backend.Backend.(*mockBackend)
+	if got := mock.adapter("web_emits_total").(*mockCounterAdapter).GetCount(); got != 3 {
+		t.Errorf("Expected the queued operations to be applied, got %v", got)
+	}
+	assertNoGoroutineLeak(t, baseline)
+
+	// Operations are applied synchronously once closed
+	if err := counter.Inc(ctx); err != nil {
+		t.Fatalf("Operation failed after Close: %v", err)
+	}
+	if got := mock.adapter("web_emits_total").(*mockCounterAdapter).GetCount(); got != 4 {
+		t.Errorf("Expected the operation to be applied synchronously, got %v", got)
+	}
+	reg.Flush()
+	reg.Close()
+}
+
+func TestRegistryCloseUnstarted(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	reg := NewRegistry(LevelDebug)
+	reg.Flush()
+	reg.Close()
+	assertNoGoroutineLeak(t, baseline)
+}
@@ -0,0 +1,197 @@
+package umami_statsd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SimonDaKappa/go-umami"
+)
+
+// series names the series of a vec metric from their labels, either as name
+// segments or as DogStatsD tags, see [WithTags]
+type series struct {
+	backend *statsdBackend
+	name    string
+	labels  []string // Declared label names, in order
+}
+
+func (s *statsdBackend) newSeries(name string, labels []string) series {
+	return series{backend: s, name: sanitize(name, ""), labels: labels}
+}
+
+// of returns the name and tags of the series of labels, in the declared order
+// of the label names. Returns an error wrapping [umami.ErrLabelMismatch] if
+// labels don't match the declared label names.
+func (s series) of(labels umami.VecLabels) (name, tags string, err error) {
+	if len(labels) != len(s.labels) {
+		return "", "", fmt.Errorf("%w: got %d labels, declared %v", umami.ErrLabelMismatch, len(labels), s.labels)
+	}
+
+	parts := make([]string, len(s.labels))
+	for i, label := range s.labels {
+		value, ok := labels[label]
+		if !ok {
+			return "", "", fmt.Errorf("%w: missing label %q", umami.ErrLabelMismatch, label)
+		}
+		if s.backend.tags {
+			parts[i] = sanitize(label, "") + ":" + sanitize(value, "")
+		} else {
+			parts[i] = sanitize(value, ".")
+		}
+	}
+
+	if s.backend.tags {
+		return s.name, strings.Join(parts, ","), nil
+	}
+	if len(parts) == 0 {
+		return s.name, "", nil
+	}
+	return s.name + "." + strings.Join(parts, "."), "", nil
+}
+
+type sdCounterAdapter struct {
+	backend *statsdBackend
+	name    string
+}
+
+func (a *sdCounterAdapter) Inc() error {
+	return a.Add(1)
+}
+
+func (a *sdCounterAdapter) Add(value float64) error {
+	if !a.backend.sampled() {
+		return nil
+	}
+	return a.backend.send(line(a.name, formatFloat(value), "c", a.backend.sampleRate, ""))
+}
+
+type sdCounterVecAdapter struct {
+	series series
+}
+
+func (a *sdCounterVecAdapter) Inc(labels umami.VecLabels) error {
+	return a.Add(1, labels)
+}
+
+func (a *sdCounterVecAdapter) Add(value float64, labels umami.VecLabels) error {
+	name, tags, err := a.series.of(labels)
+	if err != nil {
+		return err
+	}
+	if !a.series.backend.sampled() {
+		return nil
+	}
+	return a.series.backend.send(line(name, formatFloat(value), "c", a.series.backend.sampleRate, tags))
+}
+
+// setGauge sends the lines setting a gauge to value. Negative values are
+// preceded by a reset to 0, as StatsD reads a signed value as a delta.
+func setGauge(backend *statsdBackend, name string, value float64, tags string) error {
+	if value < 0 {
+		return backend.send(line(name, "0", "g", 1, tags), line(name, formatFloat(value), "g", 1, tags))
+	}
+	return backend.send(line(name, formatFloat(value), "g", 1, tags))
+}
+
+type sdGaugeAdapter struct {
+	backend *statsdBackend
+	name    string
+}
+
+func (a *sdGaugeAdapter) Set(value float64) error {
+	return setGauge(a.backend, a.name, value, "")
+}
+
+func (a *sdGaugeAdapter) Inc() error {
+	return a.Add(1)
+}
+
+func (a *sdGaugeAdapter) Dec() error {
+	return a.Add(-1)
+}
+
+func (a *sdGaugeAdapter) Add(value float64) error {
+	return a.backend.send(line(a.name, formatDelta(value), "g", 1, ""))
+}
+
+type sdGaugeVecAdapter struct {
+	series series
+}
+
+func (a *sdGaugeVecAdapter) Set(value float64, labels umami.VecLabels) error {
+	name, tags, err := a.series.of(labels)
+	if err != nil {
+		return err
+	}
+	return setGauge(a.series.backend, name, value, tags)
+}
+
+func (a *sdGaugeVecAdapter) Inc(labels umami.VecLabels) error {
+	return a.Add(1, labels)
+}
+
+func (a *sdGaugeVecAdapter) Dec(labels umami.VecLabels) error {
+	return a.Add(-1, labels)
+}
+
+func (a *sdGaugeVecAdapter) Add(value float64, labels umami.VecLabels) error {
+	name, tags, err := a.series.of(labels)
+	if err != nil {
+		return err
+	}
+	return a.series.backend.send(line(name, formatDelta(value), "g", 1, tags))
+}
+
+type sdHistogramAdapter struct {
+	backend *statsdBackend
+	name    string
+}
+
+func (a *sdHistogramAdapter) Observe(value float64) error {
+	return a.backend.send(line(a.name, formatFloat(value), string(a.backend.histogramType), 1, ""))
+}
+
+type sdHistogramVecAdapter struct {
+	series series
+}
+
+func (a *sdHistogramVecAdapter) Observe(value float64, labels umami.VecLabels) error {
+	name, tags, err := a.series.of(labels)
+	if err != nil {
+		return err
+	}
+	return a.series.backend.send(line(name, formatFloat(value), string(a.series.backend.histogramType), 1, tags))
+}
+
+// sdSummaryAdapter sends observations like a histogram, as quantiles are
+// computed by the StatsD server
+type sdSummaryAdapter struct {
+	sdHistogramAdapter
+}
+
+// Quantile is unsupported, as StatsD is write only
+func (a *sdSummaryAdapter) Quantile(q float64) (float64, error) {
+	return 0, umami.ErrUnsupportedMetric
+}
+
+// sdSummaryVecAdapter sends observations like a histogram vec, as quantiles
+// are computed by the StatsD server
+type sdSummaryVecAdapter struct {
+	sdHistogramVecAdapter
+}
+
+// Quantile is unsupported, as StatsD is write only
+func (a *sdSummaryVecAdapter) Quantile(q float64, labels umami.VecLabels) (float64, error) {
+	return 0, umami.ErrUnsupportedMetric
+}
+
+var (
+	__ctc_sdCounterAdapter      umami.CounterAdapter      = (*sdCounterAdapter)(nil)
+	__ctc_sdCounterVecAdapter   umami.CounterVecAdapter   = (*sdCounterVecAdapter)(nil)
+	__ctc_sdGaugeAdapter        umami.GaugeAdapter        = (*sdGaugeAdapter)(nil)
+	__ctc_sdGaugeVecAdapter     umami.GaugeVecAdapter     = (*sdGaugeVecAdapter)(nil)
+	__ctc_sdHistogramAdapter    umami.HistogramAdapter    = (*sdHistogramAdapter)(nil)
+	__ctc_sdHistogramVecAdapter umami.HistogramVecAdapter = (*sdHistogramVecAdapter)(nil)
+	__ctc_sdSummaryAdapter      umami.SummaryAdapter      = (*sdSummaryAdapter)(nil)
+	__ctc_sdSummaryVecAdapter   umami.SummaryVecAdapter   = (*sdSummaryVecAdapter)(nil)
+)
@@ -0,0 +1,410 @@
+package umami_statsd
+
+// Integration with StatsD over UDP, optionally with DogStatsD tags
+
+import (
+	"errors"
+	"maps"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SimonDaKappa/go-umami"
+)
+
+const (
+	StatsDBackendName string = "statsd"
+)
+
+const (
+	// DefaultFlushInterval is how often the backend sends its buffered
+	// values, unless set by [WithFlushInterval]
+	DefaultFlushInterval = time.Second
+
+	// DefaultMaxPacketSize is the maximum size of the UDP packets sent by the
+	// backend, unless set by [WithMaxPacketSize]. It fits the payload of an
+	// Ethernet frame.
+	DefaultMaxPacketSize = 1432
+)
+
+// HistogramType is the StatsD metric type histograms and summaries are sent
+// as, see [WithHistogramType]
+type HistogramType string
+
+const (
+	// HistogramTiming sends observations as timings ("ms"), aggregated into
+	// percentiles by the StatsD server. This is the default.
+	HistogramTiming HistogramType = "ms"
+
+	// HistogramDistribution sends observations as DogStatsD distributions
+	// ("d"), aggregated globally by the server.
+	HistogramDistribution HistogramType = "d"
+)
+
+// StatsDOption configures optional behavior of the StatsD backend
+type StatsDOption func(*statsdBackend)
+
+// WithTags sends the labels of vec metrics as DogStatsD tags, e.g.
+// "requests:1|c|#route:/a". By default, label values are appended to the
+// metric name as segments in the declared order, e.g. "requests./a:1|c".
+func WithTags() StatsDOption {
+	return func(s *statsdBackend) {
+		s.tags = true
+	}
+}
+
+// WithSampleRate sends counter increments with probability rate, annotated
+// with the rate so that the server scales them back up. A rate outside of
+// (0, 1) sends every increment, which is the default.
+func WithSampleRate(rate float64) StatsDOption {
+	return func(s *statsdBackend) {
+		s.sampleRate = 1
+		if rate > 0 && rate < 1 {
+			s.sampleRate = rate
+		}
+	}
+}
+
+// WithHistogramType sets the StatsD type histograms and summaries are sent as.
+// Defaults to [HistogramTiming].
+//
+// Observations are sent as observed, e.g. in seconds, without converting them
+// to the milliseconds StatsD timings are conventionally in.
+func WithHistogramType(typ HistogramType) StatsDOption {
+	return func(s *statsdBackend) {
+		s.histogramType = typ
+	}
+}
+
+// WithFlushInterval sets how often the buffered values are sent. An interval
+// of 0 or less disables the periodic flushes, so that values are only sent
+// when the buffer is full, or by [umami.FlushableBackend.Flush] and Close.
+// Defaults to [DefaultFlushInterval].
+func WithFlushInterval(interval time.Duration) StatsDOption {
+	return func(s *statsdBackend) {
+		s.flushInterval = interval
+	}
+}
+
+// WithMaxPacketSize sets the maximum size of the sent packets. Values are
+// buffered until the next one doesn't fit, and a single value larger than the
+// size is sent in a packet of its own. Defaults to [DefaultMaxPacketSize].
+func WithMaxPacketSize(size int) StatsDOption {
+	return func(s *statsdBackend) {
+		s.maxPacketSize = size
+	}
+}
+
+// StatsDBackend is the StatsD [umami.Backend]. It buffers values, and sends
+// them in newline separated batches, see [NewStatsDBackend].
+//
+// StatsD is write only: metric values can't be read back, and summary
+// quantiles return [umami.ErrUnsupportedMetric].
+type StatsDBackend interface {
+	umami.FlushableBackend
+
+	// Close stops the periodic flushes, sends the buffered values and closes
+	// the connection, returning the error of the final flush or of closing
+	Close() error
+}
+
+// statsdBackend implements the [StatsDBackend] interface
+type statsdBackend struct {
+	conn net.Conn
+
+	tags          bool          // If true, labels are sent as DogStatsD tags
+	sampleRate    float64       // Probability of sending a counter increment
+	histogramType HistogramType // StatsD type of histograms and summaries
+	flushInterval time.Duration
+	maxPacketSize int
+	random        func() float64 // Draws the counter samples
+
+	mu  sync.Mutex
+	buf []byte // Buffered newline separated values
+
+	funcsMu sync.Mutex
+	funcs   map[string]*polledFunc // Polled on every flush by qualified metric name, see [statsdBackend.GaugeFunc]
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewStatsDBackend creates a StatsD backend sending to the UDP address addr,
+// e.g. "127.0.0.1:8125", with optional [StatsDOption]s.
+//
+// Values are buffered and flushed every [DefaultFlushInterval], or when the
+// buffer is full. Call Close to send the remaining values when done.
+func NewStatsDBackend(addr string, opts ...StatsDOption) (StatsDBackend, error) {
+	s := &statsdBackend{
+		sampleRate:    1,
+		histogramType: HistogramTiming,
+		flushInterval: DefaultFlushInterval,
+		maxPacketSize: DefaultMaxPacketSize,
+		random:        rand.Float64,
+		funcs:         make(map[string]*polledFunc),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s.conn = conn
+	s.buf = make([]byte, 0, s.maxPacketSize)
+
+	if s.flushInterval > 0 {
+		go s.run()
+	} else {
+		close(s.done)
+	}
+	return s, nil
+}
+
+func (s *statsdBackend) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = s.Flush()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Flush polls the functions of the gauge and counter funcs, and sends the
+// buffered values
+func (s *statsdBackend) Flush() error {
+	pollErr := s.poll()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return errors.Join(pollErr, s.flushLocked())
+}
+
+// polledFunc is a gauge or counter whose value is read from a function on
+// every flush
+type polledFunc struct {
+	name    string
+	fn      func() float64
+	counter bool    // If true, the increase since the last poll is sent as a counter
+	last    float64 // Value of the last poll of a counter
+}
+
+// poll buffers the values of the polled functions, in the order of their
+// metric names
+func (s *statsdBackend) poll() error {
+	s.funcsMu.Lock()
+	defer s.funcsMu.Unlock()
+
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(s.funcs)) {
+		f := s.funcs[name]
+		value := f.fn()
+		if !f.counter {
+			errs = append(errs, setGauge(s, f.name, value, ""))
+			continue
+		}
+
+		if value > f.last {
+			errs = append(errs, s.send(line(f.name, formatFloat(value-f.last), "c", 1, "")))
+		}
+		f.last = value
+	}
+	return errors.Join(errs...)
+}
+
+// flushLocked sends the buffered values. Callers must hold [statsdBackend.mu].
+func (s *statsdBackend) flushLocked() error {
+	if len(s.buf) == 0 {
+		return nil
+	}
+
+	_, err := s.conn.Write(s.buf)
+	s.buf = s.buf[:0]
+	return err
+}
+
+// Close stops the periodic flushes, flushes and closes the connection
+func (s *statsdBackend) Close() error {
+	s.closeOnce.Do(func() {
+		if s.flushInterval > 0 {
+			close(s.stop)
+		}
+		<-s.done
+		s.closeErr = errors.Join(s.Flush(), s.conn.Close())
+	})
+	return s.closeErr
+}
+
+func (s *statsdBackend) Name() string {
+	return StatsDBackendName
+}
+
+// send buffers the value lines, first flushing the buffer if they don't fit
+func (s *statsdBackend) send(lines ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for _, line := range lines {
+		size := len(line)
+		if len(s.buf) > 0 {
+			size++ // Newline separator
+		}
+		if len(s.buf) > 0 && len(s.buf)+size > s.maxPacketSize {
+			errs = append(errs, s.flushLocked())
+		}
+
+		if len(s.buf) > 0 {
+			s.buf = append(s.buf, '\n')
+		}
+		s.buf = append(s.buf, line...)
+	}
+	return errors.Join(errs...)
+}
+
+// line formats a value of a metric in the StatsD wire format:
+//
+//	<name>:<value>|<type>[|@<rate>][|#<tags>]
+func line(name, value, typ string, rate float64, tags string) string {
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte(':')
+	b.WriteString(value)
+	b.WriteByte('|')
+	b.WriteString(typ)
+	if rate < 1 {
+		b.WriteString("|@")
+		b.WriteString(formatFloat(rate))
+	}
+	if tags != "" {
+		b.WriteString("|#")
+		b.WriteString(tags)
+	}
+	return b.String()
+}
+
+// sampled returns true if a counter increment is sent, see [WithSampleRate]
+func (s *statsdBackend) sampled() bool {
+	return s.sampleRate >= 1 || s.random() < s.sampleRate
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// formatDelta formats a gauge delta with an explicit sign, as StatsD sets
+// gauges to unsigned values
+func formatDelta(value float64) string {
+	if value < 0 {
+		return formatFloat(value)
+	}
+	return "+" + formatFloat(value)
+}
+
+// sanitize replaces the characters of the StatsD wire format, and any of
+// extra, in a name segment or tag with underscores
+func sanitize(s, extra string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(":|@#,\n "+extra, r) {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+func (s *statsdBackend) Counter(opts umami.CounterOpts) umami.CounterAdapter {
+	return &sdCounterAdapter{backend: s, name: sanitize(opts.QualifiedName(), "")}
+}
+
+// CounterFunc polls fn on every flush, sending the increase since the
+// previous poll as a counter. Decreases (e.g. resets) are not sent.
+//
+// fn replaces the function of a counter func of the same name, if any, whose
+// last polled value is kept, so that the increase isn't sent twice.
+func (s *statsdBackend) CounterFunc(opts umami.CounterOpts, fn func() float64) {
+	s.funcsMu.Lock()
+	defer s.funcsMu.Unlock()
+
+	name := opts.QualifiedName()
+	f := &polledFunc{name: sanitize(name, ""), fn: fn, counter: true}
+	if old, exists := s.funcs[name]; exists && old.counter {
+		f.last = old.last
+	}
+	s.funcs[name] = f
+}
+
+func (s *statsdBackend) CounterVec(opts umami.CounterVecOpts) umami.CounterVecAdapter {
+	return &sdCounterVecAdapter{series: s.newSeries(opts.QualifiedName(), opts.Labels)}
+}
+
+func (s *statsdBackend) Gauge(opts umami.GaugeOpts) umami.GaugeAdapter {
+	return &sdGaugeAdapter{backend: s, name: sanitize(opts.QualifiedName(), "")}
+}
+
+// GaugeFunc polls fn on every flush, sending its value as a gauge. fn
+// replaces the function of a gauge func of the same name, if any.
+func (s *statsdBackend) GaugeFunc(opts umami.GaugeOpts, fn func() float64) {
+	s.funcsMu.Lock()
+	defer s.funcsMu.Unlock()
+
+	name := opts.QualifiedName()
+	s.funcs[name] = &polledFunc{name: sanitize(name, ""), fn: fn}
+}
+
+// Unregister stops polling the function of the gauge or counter func of the
+// qualified name, see [umami.MetricInfo.QualifiedName].
+// Other metrics have no state on the backend, and return false.
+func (s *statsdBackend) Unregister(name string) bool {
+	s.funcsMu.Lock()
+	defer s.funcsMu.Unlock()
+
+	_, exists := s.funcs[name]
+	delete(s.funcs, name)
+	return exists
+}
+
+func (s *statsdBackend) GaugeVec(opts umami.GaugeVecOpts) umami.GaugeVecAdapter {
+	return &sdGaugeVecAdapter{series: s.newSeries(opts.QualifiedName(), opts.Labels)}
+}
+
+func (s *statsdBackend) Histogram(opts umami.HistogramOpts) umami.HistogramAdapter {
+	return &sdHistogramAdapter{backend: s, name: sanitize(opts.QualifiedName(), "")}
+}
+
+func (s *statsdBackend) HistogramVec(opts umami.HistogramVecOpts) umami.HistogramVecAdapter {
+	return &sdHistogramVecAdapter{series: s.newSeries(opts.QualifiedName(), opts.Labels)}
+}
+
+func (s *statsdBackend) Summary(opts umami.SummaryOpts) umami.SummaryAdapter {
+	return &sdSummaryAdapter{sdHistogramAdapter{backend: s, name: sanitize(opts.QualifiedName(), "")}}
+}
+
+func (s *statsdBackend) SummaryVec(opts umami.SummaryVecOpts) umami.SummaryVecAdapter {
+	return &sdSummaryVecAdapter{sdHistogramVecAdapter{series: s.newSeries(opts.QualifiedName(), opts.Labels)}}
+}
+
+var (
+	__ctc_statsdBackend            StatsDBackend            = (*statsdBackend)(nil)
+	__ctc_statsdFlushableBackend   umami.FlushableBackend   = (*statsdBackend)(nil)
+	__ctc_statsdGaugeFuncBackend   umami.GaugeFuncBackend   = (*statsdBackend)(nil)
+	__ctc_statsdCounterFuncBackend umami.CounterFuncBackend = (*statsdBackend)(nil)
+	__ctc_statsdUnregisterBackend  umami.UnregisterBackend  = (*statsdBackend)(nil)
+)
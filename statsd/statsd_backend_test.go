@@ -0,0 +1,300 @@
+package umami_statsd
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/SimonDaKappa/go-umami"
+)
+
+// listen starts a fake StatsD server on a local UDP port
+func listen(t *testing.T) net.PacketConn {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readPacket reads the next packet received by server
+func readPacket(t *testing.T, server net.PacketConn) string {
+	t.Helper()
+	buf := make([]byte, 65536)
+	_ = server.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := server.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Expected a packet: %v", err)
+	}
+	return string(buf[:n])
+}
+
+// newTestBackend returns a backend sending to server, flushed manually
+func newTestBackend(t *testing.T, server net.PacketConn, opts ...StatsDOption) *statsdBackend {
+	t.Helper()
+	backend, err := NewStatsDBackend(server.LocalAddr().String(), append([]StatsDOption{WithFlushInterval(0)}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewStatsDBackend failed: %v", err)
+	}
+	t.Cleanup(func() { backend.Close() })
+	return backend.(*statsdBackend)
+}
+
+func TestStatsDWireFormat(t *testing.T) {
+	labels := umami.VecLabels{"method": "GET", "route": "/a"}
+
+	tests := []struct {
+		name   string
+		opts   []StatsDOption
+		record func(b *statsdBackend) error
+		want   string
+	}{
+		{"counter inc", nil, func(b *statsdBackend) error {
+			return b.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "requests"}}).Inc()
+		}, "requests:1|c"},
+		{"counter add", nil, func(b *statsdBackend) error {
+			return b.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "bytes"}}).Add(2.5)
+		}, "bytes:2.5|c"},
+		{"counter vec segments", nil, func(b *statsdBackend) error {
+			vec := b.CounterVec(umami.CounterVecOpts{MetricInfo: umami.MetricInfo{Name: "requests"}, Labels: []string{"route", "method"}})
+			return vec.Add(3, labels)
+		}, "requests./a.GET:3|c"},
+		{"counter vec tags", []StatsDOption{WithTags()}, func(b *statsdBackend) error {
+			vec := b.CounterVec(umami.CounterVecOpts{MetricInfo: umami.MetricInfo{Name: "requests"}, Labels: []string{"route", "method"}})
+			return vec.Inc(labels)
+		}, "requests:1|c|#route:/a,method:GET"},
+		{"gauge set", nil, func(b *statsdBackend) error {
+			return b.Gauge(umami.GaugeOpts{MetricInfo: umami.MetricInfo{Name: "in_flight"}}).Set(7)
+		}, "in_flight:7|g"},
+		{"gauge set negative", nil, func(b *statsdBackend) error {
+			return b.Gauge(umami.GaugeOpts{MetricInfo: umami.MetricInfo{Name: "balance"}}).Set(-4)
+		}, "balance:0|g\nbalance:-4|g"},
+		{"gauge inc", nil, func(b *statsdBackend) error {
+			return b.Gauge(umami.GaugeOpts{MetricInfo: umami.MetricInfo{Name: "in_flight"}}).Inc()
+		}, "in_flight:+1|g"},
+		{"gauge dec", nil, func(b *statsdBackend) error {
+			return b.Gauge(umami.GaugeOpts{MetricInfo: umami.MetricInfo{Name: "in_flight"}}).Dec()
+		}, "in_flight:-1|g"},
+		{"gauge add", nil, func(b *statsdBackend) error {
+			return b.Gauge(umami.GaugeOpts{MetricInfo: umami.MetricInfo{Name: "in_flight"}}).Add(0.5)
+		}, "in_flight:+0.5|g"},
+		{"gauge vec set tags", []StatsDOption{WithTags()}, func(b *statsdBackend) error {
+			vec := b.GaugeVec(umami.GaugeVecOpts{MetricInfo: umami.MetricInfo{Name: "in_flight"}, Labels: []string{"route", "method"}})
+			return vec.Set(2, labels)
+		}, "in_flight:2|g|#route:/a,method:GET"},
+		{"gauge vec dec segments", nil, func(b *statsdBackend) error {
+			vec := b.GaugeVec(umami.GaugeVecOpts{MetricInfo: umami.MetricInfo{Name: "in_flight"}, Labels: []string{"route", "method"}})
+			return vec.Dec(labels)
+		}, "in_flight./a.GET:-1|g"},
+		{"histogram timing", nil, func(b *statsdBackend) error {
+			return b.Histogram(umami.HistogramOpts{MetricInfo: umami.MetricInfo{Name: "latency"}}).Observe(0.25)
+		}, "latency:0.25|ms"},
+		{"histogram distribution", []StatsDOption{WithHistogramType(HistogramDistribution)}, func(b *statsdBackend) error {
+			return b.Histogram(umami.HistogramOpts{MetricInfo: umami.MetricInfo{Name: "latency"}}).Observe(0.25)
+		}, "latency:0.25|d"},
+		{"histogram vec tags", []StatsDOption{WithTags()}, func(b *statsdBackend) error {
+			vec := b.HistogramVec(umami.HistogramVecOpts{MetricInfo: umami.MetricInfo{Name: "latency"}, Labels: []string{"route", "method"}})
+			return vec.Observe(1.5, labels)
+		}, "latency:1.5|ms|#route:/a,method:GET"},
+		{"summary", nil, func(b *statsdBackend) error {
+			return b.Summary(umami.SummaryOpts{MetricInfo: umami.MetricInfo{Name: "size"}}).Observe(512)
+		}, "size:512|ms"},
+		{"summary vec segments", nil, func(b *statsdBackend) error {
+			vec := b.SummaryVec(umami.SummaryVecOpts{MetricInfo: umami.MetricInfo{Name: "size"}, Labels: []string{"route", "method"}})
+			return vec.Observe(64, labels)
+		}, "size./a.GET:64|ms"},
+		{"sanitized label values", nil, func(b *statsdBackend) error {
+			vec := b.CounterVec(umami.CounterVecOpts{MetricInfo: umami.MetricInfo{Name: "requests"}, Labels: []string{"host"}})
+			return vec.Inc(umami.VecLabels{"host": "api.example.com:443"})
+		}, "requests.api_example_com_443:1|c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := listen(t)
+			backend := newTestBackend(t, server, tt.opts...)
+
+			if err := tt.record(backend); err != nil {
+				t.Fatalf("Recording failed: %v", err)
+			}
+			if err := backend.Flush(); err != nil {
+				t.Fatalf("Flush failed: %v", err)
+			}
+			if got := readPacket(t, server); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStatsDSampleRate(t *testing.T) {
+	server := listen(t)
+	backend := newTestBackend(t, server, WithSampleRate(0.5))
+	draws := []float64{0.2, 0.7, 0.4}
+	backend.random = func() float64 {
+		draw := draws[0]
+		draws = draws[1:]
+		return draw
+	}
+
+	counter := backend.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "requests"}})
+	for range 3 {
+		_ = counter.Inc()
+	}
+	_ = backend.Flush()
+
+	// The second increment is dropped
+	if got, want := readPacket(t, server), "requests:1|c|@0.5\nrequests:1|c|@0.5"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestStatsDFlushesFullBuffer(t *testing.T) {
+	server := listen(t)
+	backend := newTestBackend(t, server, WithMaxPacketSize(30))
+	counter := backend.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "requests"}})
+
+	// Each value is 12 bytes, so the third doesn't fit with the newlines
+	for range 3 {
+		_ = counter.Inc()
+	}
+	if got, want := readPacket(t, server), "requests:1|c\nrequests:1|c"; got != want {
+		t.Errorf("Expected the full buffer to be sent, got %q", got)
+	}
+
+	_ = backend.Flush()
+	if got, want := readPacket(t, server), "requests:1|c"; got != want {
+		t.Errorf("Expected the rest to be sent by Flush, got %q", got)
+	}
+}
+
+func TestStatsDFlushInterval(t *testing.T) {
+	server := listen(t)
+	backend, err := NewStatsDBackend(server.LocalAddr().String(), WithFlushInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewStatsDBackend failed: %v", err)
+	}
+
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("web", backend)
+	counter := group.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "requests"}}, umami.LevelImportant)
+	_ = counter.Inc(group.Context())
+
+	if got, want := readPacket(t, server), "web_requests:1|c"; got != want {
+		t.Errorf("Expected a periodic flush of %q, got %q", want, got)
+	}
+
+	_ = counter.Add(group.Context(), 2)
+	if err := backend.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if got, want := readPacket(t, server), "web_requests:2|c"; got != want {
+		t.Errorf("Expected Close to flush %q, got %q", want, got)
+	}
+	if err := backend.Close(); err != nil {
+		t.Errorf("Expected a second Close to return the first result, got %v", err)
+	}
+}
+
+func TestStatsDPollsFuncsOnFlush(t *testing.T) {
+	server := listen(t)
+	backend := newTestBackend(t, server)
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("web", backend)
+
+	sessions, logins := 3.0, 5.0
+	group.GaugeFunc(umami.GaugeOpts{MetricInfo: umami.MetricInfo{Name: "sessions"}}, func() float64 { return sessions }, umami.LevelImportant)
+	group.CounterFunc(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "logins"}}, func() float64 { return logins }, umami.LevelImportant)
+
+	_ = backend.Flush()
+	if got, want := readPacket(t, server), "web_logins:5|c\nweb_sessions:3|g"; got != want {
+		t.Errorf("Expected the functions to be polled by Flush, got %q", got)
+	}
+
+	// Counters send the increase since the previous flush
+	sessions, logins = 1, 8
+	_ = backend.Flush()
+	if got, want := readPacket(t, server), "web_logins:3|c\nweb_sessions:1|g"; got != want {
+		t.Errorf("Expected the polled values to follow the functions, got %q", got)
+	}
+}
+
+func TestStatsDUnregistersFuncs(t *testing.T) {
+	server := listen(t)
+	backend := newTestBackend(t, server)
+	reg := umami.NewRegistry(umami.LevelDebug)
+	group := reg.NewGroup("app", backend)
+
+	ops := 5.0
+	group.CounterFunc(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "ops"}}, func() float64 { return ops }, umami.LevelImportant)
+	group.GaugeFunc(umami.GaugeOpts{MetricInfo: umami.MetricInfo{Name: "sessions"}}, func() float64 { return 2 }, umami.LevelCritical)
+
+	group.SetGroupLevel(umami.LevelCritical, umami.LevelOpts{ReclaimDisabled: true})
+	group.SetGroupLevel(umami.LevelDebug, umami.LevelOpts{ReplaceNoops: true})
+	_ = backend.Flush()
+	if got, want := readPacket(t, server), "app_ops:5|c\napp_sessions:2|g"; got != want {
+		t.Errorf("Expected a reclaimed then enabled func to be polled once, got %q, want %q", got, want)
+	}
+
+	ops = 7
+	_ = backend.Flush()
+	if got, want := readPacket(t, server), "app_ops:2|c\napp_sessions:2|g"; got != want {
+		t.Errorf("Expected the increase since the last poll, got %q, want %q", got, want)
+	}
+
+	group.Remove("app_ops")
+	if _, polled := backend.funcs["app_ops"]; polled {
+		t.Error("Expected a removed func to be unregistered")
+	}
+	reg.DeleteGroup("app")
+	if len(backend.funcs) != 0 {
+		t.Errorf("Expected the funcs of a deleted group to be unregistered, got %v", backend.funcs)
+	}
+}
+
+func TestStatsDQualifiedNames(t *testing.T) {
+	server := listen(t)
+	backend := newTestBackend(t, server)
+	reg := umami.NewRegistry(umami.LevelDebug)
+	group := reg.NewGroup("app", backend)
+	ctx := group.Context()
+
+	cache := group.Cache(umami.CacheOpts{MetricInfo: umami.MetricInfo{Name: "cache", Namespace: "shop"}}, umami.LevelDebug)
+	_ = cache.Hit(ctx)
+	group.GaugeFunc(umami.GaugeOpts{MetricInfo: umami.MetricInfo{Name: "sessions", Namespace: "shop", Subsystem: "web"}}, func() float64 { return 2 }, umami.LevelDebug)
+	_ = backend.Flush()
+	if got, want := readPacket(t, server), "shop_app_cache_hit:1|c\nshop_web_app_sessions:2|g"; got != want {
+		t.Errorf("Expected metrics named by their qualified names, got %q, want %q", got, want)
+	}
+
+	// Funcs are unregistered by their qualified names
+	group.SetGroupLevel(umami.LevelCritical, umami.LevelOpts{ReclaimDisabled: true})
+	if len(backend.funcs) != 0 {
+		t.Errorf("Expected the reclaimed func to be unregistered, got %v", backend.funcs)
+	}
+}
+
+func TestStatsDUnsupportedReads(t *testing.T) {
+	server := listen(t)
+	backend := newTestBackend(t, server, WithTags())
+
+	vec := backend.CounterVec(umami.CounterVecOpts{MetricInfo: umami.MetricInfo{Name: "requests"}, Labels: []string{"route"}})
+	if err := vec.Inc(umami.VecLabels{"method": "GET"}); !errors.Is(err, umami.ErrLabelMismatch) {
+		t.Errorf("Expected ErrLabelMismatch, got %v", err)
+	}
+
+	summary := backend.Summary(umami.SummaryOpts{MetricInfo: umami.MetricInfo{Name: "size"}})
+	if _, err := summary.Quantile(0.5); !errors.Is(err, umami.ErrUnsupportedMetric) {
+		t.Errorf("Expected ErrUnsupportedMetric, got %v", err)
+	}
+
+	group := umami.NewRegistry(umami.LevelDebug).NewGroup("web", backend)
+	counter := group.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "requests_total"}}, umami.LevelImportant)
+	if _, err := counter.Value(group.Context()); !errors.Is(err, umami.ErrUnsupportedMetric) {
+		t.Errorf("Expected values to be unreadable, got %v", err)
+	}
+	if backend.Name() != StatsDBackendName {
+		t.Errorf("Expected the backend name %q, got %q", StatsDBackendName, backend.Name())
+	}
+}
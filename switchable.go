@@ -13,7 +13,9 @@ package umami
 //--------------------------------------------------------------------------------
 
 import (
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -29,6 +31,27 @@ type Switchable interface {
 	// This allows converting from noop to real or real to noop without
 	// breaking user references to the wrapper.
 	switchImpl(newImpl any)
+
+	// switchOpts returns the opts the metric was constructed with, which
+	// are used to construct a replacement implementation.
+	switchOpts() any
+
+	// IsNoop returns true if the current implementation is a noop
+	IsNoop() bool
+
+	// setImplLevel sets the level on the internal implementation, without
+	// swapping it like [Metric.SetLevel] may.
+	setImplLevel(level Level)
+
+	// cacheEnabled recomputes the cached enabled state of the implementation,
+	// and of its components, for the level of the tracking group, see
+	// [baseMetric.groupDisabled]
+	cacheEnabled(groupLevel Level)
+
+	// bindRelevel sets the function called by [Metric.SetLevel] to swap the
+	// implementation if the new level crosses the level of the tracking group.
+	// It returns true if it swapped the implementation.
+	bindRelevel(relevel func(level Level) bool)
 }
 
 type SwitchableMetric interface {
@@ -38,40 +61,145 @@ type SwitchableMetric interface {
 
 // baseSwitchableMetric provides common functionality for all switchable metrics.
 //
-// It holds a mutex and the current implementation of the metric.
+// It holds a mutex, the current implementation of the metric, and the opts
+// the metric was constructed with.
 // The following methods are provided to allow safe access to the internal metric.
 // - switchImpl(newImpl any) to replace the internal implementation
 // - IsNoop() bool to check if the current implementation is a noop
-// - SetLevel(level Level) to set the level on the internal implementation
+// - SetLevel(level Level) to set the level, swapping the implementation if needed
 type baseSwitchableMetric[M Metric] struct {
-	mu     sync.RWMutex
-	impl   M
-	isNoop bool
+	mu      sync.RWMutex
+	impl    M
+	opts    any
+	isNoop  bool
+	relevel func(level Level) bool // Bound by the tracking group, if any
 }
 
-func newBaseSwitchableMetric[M Metric](impl M) *baseSwitchableMetric[M] {
+func newBaseSwitchableMetric[M Metric](impl M, opts any) *baseSwitchableMetric[M] {
 	return &baseSwitchableMetric[M]{
-		mu:   sync.RWMutex{},
-		impl: impl,
+		mu:     sync.RWMutex{},
+		impl:   impl,
+		opts:   opts,
+		isNoop: isNoopImpl(impl),
 	}
 }
 
-// switchImpl replaces the internal metric implementation.
+// isNoopImpl returns true if impl is a noop implementation.
+//
+// Basic noops implement [NoopMetric]. Noop composites are base composites
+// whose components are all basic noops.
+func isNoopImpl(impl any) bool {
+	switch m := impl.(type) {
+	case NoopMetric:
+		return true
+	case CompositeMetric:
+		components := m.Components()
+		if len(components) == 0 {
+			return false
+		}
+
+		for _, component := range components {
+			if _, ok := component.(NoopMetric); !ok {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// switchImpl replaces the internal metric implementation, and stops the
+// replaced one if it is [Stoppable], e.g. the sampler of a [GaugeFunc].
 //
 // This is for internal use only, as it can break type safety if misused,
 // (intentionally no type assertion check on newImpl)
 func (b *baseSwitchableMetric[M]) switchImpl(newImpl any) {
 	b.mu.Lock()
-	defer b.mu.Unlock()
+	old := b.impl
 	b.impl = newImpl.(M)
+	b.isNoop = isNoopImpl(b.impl)
+	b.mu.Unlock()
+
+	// Stopped unlocked, as stopping waits for goroutines that may read the metric
+	stopMetric(old)
 }
 
-func (b *baseSwitchableMetric[M]) SetLevel(level Level) {
+// stopImpl stops the implementation if it is [Stoppable]
+func (b *baseSwitchableMetric[M]) stopImpl() {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	stopMetric(b.impl)
+}
+
+func (b *baseSwitchableMetric[M]) switchOpts() any {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
+	return b.opts
+}
+
+func (b *baseSwitchableMetric[M]) IsNoop() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.isNoop
+}
+
+// SetLevel sets the level of the metric.
+//
+// If the metric is tracked by a group and the new level crosses the group's
+// level, the implementation is swapped: a noop becomes real when its level is
+// enabled by the group, and a real implementation becomes a noop when it is
+// not. Otherwise the level is set on the internal implementation.
+func (b *baseSwitchableMetric[M]) SetLevel(level Level) {
+	if b.relevel != nil && b.relevel(level) {
+		return
+	}
+	b.setImplLevel(level)
+}
+
+// setImplLevel sets the level on the internal implementation.
+//
+// It takes the write lock, as it mutates the implementation. Since every
+// operation holds the read lock for its whole duration, an operation of a
+// composite metric updating several components is never interleaved with
+// a level change: it observes either the old or the new level on all of them.
+func (b *baseSwitchableMetric[M]) setImplLevel(level Level) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	b.impl.SetLevel(level)
 }
 
+// enabledCacher is implemented by base metrics caching their enabled state,
+// see [baseMetric.cacheEnabled]
+type enabledCacher interface {
+	cacheEnabled(groupLevel Level)
+}
+
+// cacheEnabled recomputes the cached enabled state of the implementation, or,
+// for a composite, of each of its components
+func (b *baseSwitchableMetric[M]) cacheEnabled(groupLevel Level) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if composite, ok := any(b.impl).(CompositeMetric); ok {
+		for _, component := range composite.Components() {
+			if cacher, ok := component.(enabledCacher); ok {
+				cacher.cacheEnabled(groupLevel)
+			}
+		}
+		return
+	}
+	if cacher, ok := any(b.impl).(enabledCacher); ok {
+		cacher.cacheEnabled(groupLevel)
+	}
+}
+
+// bindRelevel sets the function swapping the implementation on level changes.
+// It must be called before the metric is shared.
+func (b *baseSwitchableMetric[M]) bindRelevel(relevel func(level Level) bool) {
+	b.relevel = relevel
+}
+
 func (b *baseSwitchableMetric[M]) Name() string {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
@@ -107,7 +235,7 @@ type switchableCounter struct {
 
 func newSwitchableCounter(impl Counter, opts CounterOpts) *switchableCounter {
 	return &switchableCounter{
-		baseSwitchableMetric: newBaseSwitchableMetric(impl),
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
 	}
 }
 
@@ -123,6 +251,74 @@ func (s *switchableCounter) Add(ctx Context, value float64) error {
 	return s.impl.Add(ctx, value)
 }
 
+func (s *switchableCounter) IncWithExemplar(ctx Context, exemplar VecLabels) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.IncWithExemplar(ctx, exemplar)
+}
+
+func (s *switchableCounter) AddWithExemplar(ctx Context, value float64, exemplar VecLabels) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.AddWithExemplar(ctx, value, exemplar)
+}
+
+func (s *switchableCounter) Value(ctx Context) (float64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Value(ctx)
+}
+
+// switchableCounterGroup wraps a [CounterGroup] implementation that can be switched
+type switchableCounterGroup struct {
+	*baseSwitchableMetric[CounterGroup]
+}
+
+func newSwitchableCounterGroup(impl CounterGroup, opts counterGroupOpts) *switchableCounterGroup {
+	return &switchableCounterGroup{
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
+	}
+}
+
+// Stop stops the sampler of the implementation, if any
+func (s *switchableCounterGroup) Stop() {
+	s.stopImpl()
+}
+
+func (s *switchableCounterGroup) Value(ctx Context) (float64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Value(ctx)
+}
+
+func (s *switchableCounterGroup) Members() []Counter {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Members()
+}
+
+// switchableCounterFunc wraps a [CounterFunc] implementation that can be switched
+type switchableCounterFunc struct {
+	*baseSwitchableMetric[CounterFunc]
+}
+
+func newSwitchableCounterFunc(impl CounterFunc, opts counterFuncOpts) *switchableCounterFunc {
+	return &switchableCounterFunc{
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
+	}
+}
+
+// Stop stops the sampler of the implementation, if any
+func (s *switchableCounterFunc) Stop() {
+	s.stopImpl()
+}
+
+func (s *switchableCounterFunc) Value(ctx Context) (float64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Value(ctx)
+}
+
 // switchableCounterVec wraps a [CounterVec] implementation that can be switched
 type switchableCounterVec struct {
 	*baseSwitchableMetric[CounterVec]
@@ -130,7 +326,7 @@ type switchableCounterVec struct {
 
 func newSwitchableCounterVec(impl CounterVec, opts CounterVecOpts) *switchableCounterVec {
 	return &switchableCounterVec{
-		baseSwitchableMetric: newBaseSwitchableMetric(impl),
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
 	}
 }
 
@@ -146,6 +342,110 @@ func (s *switchableCounterVec) Add(ctx Context, value float64, labels VecLabels)
 	return s.impl.Add(ctx, value, labels)
 }
 
+func (s *switchableCounterVec) Collect(ctx Context) ([]Sample, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Collect(ctx)
+}
+
+func (s *switchableCounterVec) WithLabelValues(ctx Context, values ...string) Counter {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c := &switchableLabelValuesCounter{vec: s, values: slices.Clone(values)}
+	c.bound.Store(&labelValuesBinding{impl: s.impl, counter: s.impl.WithLabelValues(ctx, values...)})
+	return c
+}
+
+func (s *switchableCounterVec) Delete(ctx Context, labels VecLabels) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Delete(ctx, labels)
+}
+
+func (s *switchableCounterVec) Reset(ctx Context) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.impl.Reset(ctx)
+}
+
+// switchableLabelValuesCounter is the [Counter] of a series of a
+// [switchableCounterVec], see [CounterVec.WithLabelValues]. Its series is
+// bound again on the current implementation after the vec is switched, and
+// its level is the level of the vec.
+type switchableLabelValuesCounter struct {
+	vec    *switchableCounterVec
+	values []string
+	bound  atomic.Pointer[labelValuesBinding]
+}
+
+// labelValuesBinding is the counter of a series bound on an implementation
+type labelValuesBinding struct {
+	impl    CounterVec
+	counter Counter
+}
+
+// current returns the counter of the series on the current implementation of
+// the vec. The caller must hold the read lock of the vec.
+func (c *switchableLabelValuesCounter) current(ctx Context) Counter {
+	bound := c.bound.Load()
+	if bound.impl != c.vec.impl {
+		bound = &labelValuesBinding{impl: c.vec.impl, counter: c.vec.impl.WithLabelValues(ctx, c.values...)}
+		c.bound.Store(bound)
+	}
+	return bound.counter
+}
+
+func (c *switchableLabelValuesCounter) Inc(ctx Context) error {
+	c.vec.mu.RLock()
+	defer c.vec.mu.RUnlock()
+	return c.current(ctx).Inc(ctx)
+}
+
+func (c *switchableLabelValuesCounter) Add(ctx Context, value float64) error {
+	c.vec.mu.RLock()
+	defer c.vec.mu.RUnlock()
+	return c.current(ctx).Add(ctx, value)
+}
+
+func (c *switchableLabelValuesCounter) IncWithExemplar(ctx Context, exemplar VecLabels) error {
+	c.vec.mu.RLock()
+	defer c.vec.mu.RUnlock()
+	return c.current(ctx).IncWithExemplar(ctx, exemplar)
+}
+
+func (c *switchableLabelValuesCounter) AddWithExemplar(ctx Context, value float64, exemplar VecLabels) error {
+	c.vec.mu.RLock()
+	defer c.vec.mu.RUnlock()
+	return c.current(ctx).AddWithExemplar(ctx, value, exemplar)
+}
+
+func (c *switchableLabelValuesCounter) Value(ctx Context) (float64, error) {
+	c.vec.mu.RLock()
+	defer c.vec.mu.RUnlock()
+	return c.current(ctx).Value(ctx)
+}
+
+func (c *switchableLabelValuesCounter) SetLevel(level Level) {
+	c.vec.SetLevel(level)
+}
+
+func (c *switchableLabelValuesCounter) Name() string {
+	return c.vec.Name()
+}
+
+func (c *switchableLabelValuesCounter) Help() string {
+	return c.vec.Help()
+}
+
+func (c *switchableLabelValuesCounter) Type() MetricType {
+	return c.vec.Type()
+}
+
+func (c *switchableLabelValuesCounter) Level() Level {
+	return c.vec.Level()
+}
+
 // switchableGauge wraps a [Gauge] implementation that can be switched
 type switchableGauge struct {
 	*baseSwitchableMetric[Gauge]
@@ -153,10 +453,29 @@ type switchableGauge struct {
 
 func newSwitchableGauge(impl Gauge, opts GaugeOpts) *switchableGauge {
 	return &switchableGauge{
-		baseSwitchableMetric: newBaseSwitchableMetric(impl),
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
+	}
+}
+
+// switchableAtomicGauge wraps a [Gauge] implementation of
+// [Factory.GaugeFromAtomic] that can be switched
+type switchableAtomicGauge struct {
+	*switchableGauge
+}
+
+func newSwitchableAtomicGauge(impl Gauge, opts atomicGaugeOpts) *switchableAtomicGauge {
+	return &switchableAtomicGauge{
+		switchableGauge: &switchableGauge{
+			baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
+		},
 	}
 }
 
+// Stop stops the sampler of the implementation, if any
+func (s *switchableAtomicGauge) Stop() {
+	s.stopImpl()
+}
+
 func (s *switchableGauge) Set(ctx Context, value float64) error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -181,6 +500,34 @@ func (s *switchableGauge) Add(ctx Context, value float64) error {
 	return s.impl.Add(ctx, value)
 }
 
+func (s *switchableGauge) Value(ctx Context) (float64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Value(ctx)
+}
+
+// switchableGaugeFunc wraps a [GaugeFunc] implementation that can be switched
+type switchableGaugeFunc struct {
+	*baseSwitchableMetric[GaugeFunc]
+}
+
+func newSwitchableGaugeFunc(impl GaugeFunc, opts gaugeFuncOpts) *switchableGaugeFunc {
+	return &switchableGaugeFunc{
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
+	}
+}
+
+// Stop stops the sampler of the implementation, if any
+func (s *switchableGaugeFunc) Stop() {
+	s.stopImpl()
+}
+
+func (s *switchableGaugeFunc) Value(ctx Context) (float64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Value(ctx)
+}
+
 // switchableGaugeVec wraps a [GaugeVec] implementation that can be switched
 type switchableGaugeVec struct {
 	*baseSwitchableMetric[GaugeVec]
@@ -188,7 +535,7 @@ type switchableGaugeVec struct {
 
 func newSwitchableGaugeVec(impl GaugeVec, opts GaugeVecOpts) *switchableGaugeVec {
 	return &switchableGaugeVec{
-		baseSwitchableMetric: newBaseSwitchableMetric(impl),
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
 	}
 }
 
@@ -216,13 +563,25 @@ func (s *switchableGaugeVec) Add(ctx Context, value float64, labels VecLabels) e
 	return s.impl.Add(ctx, value, labels)
 }
 
+func (s *switchableGaugeVec) Delete(ctx Context, labels VecLabels) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Delete(ctx, labels)
+}
+
+func (s *switchableGaugeVec) Reset(ctx Context) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.impl.Reset(ctx)
+}
+
 type switchableHistogram struct {
 	*baseSwitchableMetric[Histogram]
 }
 
 func newSwitchableHistogram(impl Histogram, opts HistogramOpts) *switchableHistogram {
 	return &switchableHistogram{
-		baseSwitchableMetric: newBaseSwitchableMetric(impl),
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
 	}
 }
 
@@ -232,13 +591,33 @@ func (s *switchableHistogram) Observe(ctx Context, value float64) error {
 	return s.impl.Observe(ctx, value)
 }
 
+func (s *switchableHistogram) ObserveWithExemplar(ctx Context, value float64, exemplar VecLabels) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.ObserveWithExemplar(ctx, value, exemplar)
+}
+
+func (s *switchableHistogram) ObserveBytes(ctx Context, n int64) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.ObserveBytes(ctx, n)
+}
+
+// Time times fn through the wrapper, so that the lock isn't held while fn
+// runs. The duration is observed by the implementation current once fn returns.
+func (s *switchableHistogram) Time(ctx Context, fn func() error) error {
+	start := time.Now()
+	defer func() { _ = s.Observe(ctx, time.Since(start).Seconds()) }()
+	return fn()
+}
+
 type switchableHistogramVec struct {
 	*baseSwitchableMetric[HistogramVec]
 }
 
 func newSwitchableHistogramVec(impl HistogramVec, opts HistogramVecOpts) *switchableHistogramVec {
 	return &switchableHistogramVec{
-		baseSwitchableMetric: newBaseSwitchableMetric(impl),
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
 	}
 }
 
@@ -248,13 +627,34 @@ func (s *switchableHistogramVec) Observe(ctx Context, value float64, labels VecL
 	return s.impl.Observe(ctx, value, labels)
 }
 
+// Time times fn through the wrapper, like [switchableHistogram.Time]
+func (s *switchableHistogramVec) Time(ctx Context, fn func() error, labels VecLabels) error {
+	start := time.Now()
+	defer func() { _ = s.Observe(ctx, time.Since(start).Seconds(), labels) }()
+	return fn()
+}
+
+func (s *switchableHistogramVec) Delete(ctx Context, labels VecLabels) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Delete(ctx, labels)
+}
+
+func (s *switchableHistogramVec) Reset(ctx Context) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.impl.Reset(ctx)
+}
+
 type switchableSummary struct {
 	*baseSwitchableMetric[Summary]
+	group *group // group that created the summary, used to rebuild it
 }
 
-func newSwitchableSummary(impl Summary, opts SummaryOpts) *switchableSummary {
+func newSwitchableSummary(g *group, impl Summary, opts SummaryOpts) *switchableSummary {
 	return &switchableSummary{
-		baseSwitchableMetric: newBaseSwitchableMetric(impl),
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
+		group:                g,
 	}
 }
 
@@ -270,13 +670,52 @@ func (s *switchableSummary) Quantile(ctx Context, q float64) (float64, error) {
 	return s.impl.Quantile(ctx, q)
 }
 
+// Reconfigure rebuilds the internal implementation from opts, keeping the
+// summary's name, and swaps it in. Recorded data is reset.
+func (s *switchableSummary) Reconfigure(opts SummaryOpts) error {
+	if s.group == nil {
+		return ErrNotReconfigurable
+	}
+	if err := s.reconfigure(opts); err != nil {
+		return err
+	}
+
+	// Cached once swapped in, and outside of the lock of the summary, as the
+	// group takes it under its own lock, see [group.cacheEnabled]
+	s.cacheEnabled(s.group.level())
+	return nil
+}
+
+// reconfigure swaps in the implementation rebuilt from opts
+func (s *switchableSummary) reconfigure(opts SummaryOpts) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.opts.(SummaryOpts)
+	opts.Name = current.Name
+	opts.FromComposite = current.FromComposite
+	opts.NoPrefix = current.NoPrefix
+	opts.Objectives = s.group.mergeObjectives(opts.Name, opts.Objectives, opts.ObjectiveSets)
+	opts.ObjectiveSets = nil
+
+	impl, err := s.group.rebuildSummary(s.impl, opts)
+	if err != nil {
+		return err
+	}
+
+	s.impl = impl
+	s.opts = opts
+	s.isNoop = isNoopImpl(impl)
+	return nil
+}
+
 type switchableSummaryVec struct {
 	*baseSwitchableMetric[SummaryVec]
 }
 
 func newSwitchableSummaryVec(impl SummaryVec, opts SummaryVecOpts) *switchableSummaryVec {
 	return &switchableSummaryVec{
-		baseSwitchableMetric: newBaseSwitchableMetric(impl),
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
 	}
 }
 
@@ -292,6 +731,18 @@ func (s *switchableSummaryVec) Quantile(ctx Context, q float64, labels VecLabels
 	return s.impl.Quantile(ctx, q, labels)
 }
 
+func (s *switchableSummaryVec) Delete(ctx Context, labels VecLabels) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Delete(ctx, labels)
+}
+
+func (s *switchableSummaryVec) Reset(ctx Context) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.impl.Reset(ctx)
+}
+
 //--------------------------------------------------------------------------------
 // Switchable Composite Metrics
 //--------------------------------------------------------------------------------
@@ -303,7 +754,7 @@ type switchableTimer struct {
 
 func newSwitchableTimer(impl Timer, opts TimerOpts) *switchableTimer {
 	return &switchableTimer{
-		baseSwitchableMetric: newBaseSwitchableMetric(impl),
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
 	}
 }
 
@@ -319,19 +770,40 @@ func (s *switchableTimer) Start(ctx Context) func() {
 	return s.impl.Start(ctx)
 }
 
+// StartFunc times fn through the wrapper, so that the lock isn't held while
+// fn runs
+func (s *switchableTimer) StartFunc(ctx Context, fn func() error) error {
+	defer s.Start(ctx)()
+	return fn()
+}
+
 func (s *switchableTimer) Record(ctx Context, duration time.Duration) error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return s.impl.Record(ctx, duration)
 }
 
+func (s *switchableTimer) RecordFromContextStart(ctx Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.RecordFromContextStart(ctx)
+}
+
+// AsObserver returns an observer recording through the wrapper, so that it
+// follows implementation switches
+func (s *switchableTimer) AsObserver(ctx Context) DurationObserver {
+	return func(duration time.Duration) {
+		_ = s.Record(ctx, duration)
+	}
+}
+
 type switchableTimerVec struct {
 	*baseSwitchableMetric[TimerVec]
 }
 
 func newSwitchableTimerVec(impl TimerVec, opts TimerVecOpts) *switchableTimerVec {
 	return &switchableTimerVec{
-		baseSwitchableMetric: newBaseSwitchableMetric(impl),
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
 	}
 }
 
@@ -353,6 +825,24 @@ func (s *switchableTimerVec) Record(ctx Context, duration time.Duration, labels
 	return s.impl.Record(ctx, duration, labels)
 }
 
+func (s *switchableTimerVec) RecordFromContextStart(ctx Context, labels VecLabels) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.RecordFromContextStart(ctx, labels)
+}
+
+// AsObserverVec returns an observer vec recording through the wrapper, so
+// that it follows implementation switches
+func (s *switchableTimerVec) AsObserverVec(ctx Context) DurationObserverVec {
+	return &timerObserverVec{timerVec: s, ctx: ctx}
+}
+
+// Partition returns a partition recording through the wrapper, so that it
+// follows implementation switches
+func (s *switchableTimerVec) Partition(labels VecLabels) Timer {
+	return &timerPartition{TimerVec: s, labels: labels}
+}
+
 // switchableCache wraps a [Cache] implementation that can be switched
 type switchableCache struct {
 	*baseSwitchableMetric[Cache]
@@ -360,7 +850,7 @@ type switchableCache struct {
 
 func newSwitchableCache(impl Cache, opts CacheOpts) *switchableCache {
 	return &switchableCache{
-		baseSwitchableMetric: newBaseSwitchableMetric(impl),
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
 	}
 }
 
@@ -394,7 +884,7 @@ type switchableCacheVec struct {
 
 func newSwitchableCacheVec(impl CacheVec, opts CacheVecOpts) *switchableCacheVec {
 	return &switchableCacheVec{
-		baseSwitchableMetric: newBaseSwitchableMetric(impl),
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
 	}
 }
 
@@ -422,13 +912,19 @@ func (s *switchableCacheVec) SetSize(ctx Context, bytes int64, labels VecLabels)
 	return s.impl.SetSize(ctx, bytes, labels)
 }
 
+// Partition returns a partition recording through the wrapper, so that it
+// follows implementation switches
+func (s *switchableCacheVec) Partition(labels VecLabels) Cache {
+	return &cachePartition{CacheVec: s, labels: labels}
+}
+
 type switchablePool struct {
 	*baseSwitchableMetric[Pool]
 }
 
 func newSwitchablePool(impl Pool, opts PoolOpts) *switchablePool {
 	return &switchablePool{
-		baseSwitchableMetric: newBaseSwitchableMetric(impl),
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
 	}
 }
 
@@ -468,7 +964,7 @@ type switchablePoolVec struct {
 
 func newSwitchablePoolVec(impl PoolVec, opts PoolVecOpts) *switchablePoolVec {
 	return &switchablePoolVec{
-		baseSwitchableMetric: newBaseSwitchableMetric(impl),
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
 	}
 }
 
@@ -502,13 +998,19 @@ func (s *switchablePoolVec) Released(ctx Context, labels VecLabels) error {
 	return s.impl.Released(ctx, labels)
 }
 
+// Partition returns a partition recording through the wrapper, so that it
+// follows implementation switches
+func (s *switchablePoolVec) Partition(labels VecLabels) Pool {
+	return &poolPartition{PoolVec: s, labels: labels}
+}
+
 type switchableCircuitBreaker struct {
 	*baseSwitchableMetric[CircuitBreaker]
 }
 
 func newSwitchableCircuitBreaker(impl CircuitBreaker, opts CircuitBreakerOpts) *switchableCircuitBreaker {
 	return &switchableCircuitBreaker{
-		baseSwitchableMetric: newBaseSwitchableMetric(impl),
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
 	}
 }
 
@@ -542,7 +1044,7 @@ type switchableCircuitBreakerVec struct {
 
 func newSwitchableCircuitBreakerVec(impl CircuitBreakerVec, opts CircuitBreakerVecOpts) *switchableCircuitBreakerVec {
 	return &switchableCircuitBreakerVec{
-		baseSwitchableMetric: newBaseSwitchableMetric(impl),
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
 	}
 }
 
@@ -570,13 +1072,19 @@ func (s *switchableCircuitBreakerVec) Failure(ctx Context, labels VecLabels) err
 	return s.impl.Failure(ctx, labels)
 }
 
+// Partition returns a partition recording through the wrapper, so that it
+// follows implementation switches
+func (s *switchableCircuitBreakerVec) Partition(labels VecLabels) CircuitBreaker {
+	return &circuitBreakerPartition{CircuitBreakerVec: s, labels: labels}
+}
+
 type switchableQueue struct {
 	*baseSwitchableMetric[Queue]
 }
 
 func newSwitchableQueue(impl Queue, opts QueueOpts) *switchableQueue {
 	return &switchableQueue{
-		baseSwitchableMetric: newBaseSwitchableMetric(impl),
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
 	}
 }
 
@@ -604,6 +1112,30 @@ func (s *switchableQueue) Dequeued(ctx Context) error {
 	return s.impl.Dequeued(ctx)
 }
 
+func (s *switchableQueue) ObserveWait(ctx Context, duration time.Duration) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.ObserveWait(ctx, duration)
+}
+
+func (s *switchableQueue) DequeuedWithWait(ctx Context, waited time.Duration) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.DequeuedWithWait(ctx, waited)
+}
+
+func (s *switchableQueue) Enqueue(ctx Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Enqueue(ctx)
+}
+
+func (s *switchableQueue) Dequeue(ctx Context, waited time.Duration) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Dequeue(ctx, waited)
+}
+
 func (s *switchableQueue) SetWaitTime(ctx Context, duration time.Duration) error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -616,7 +1148,7 @@ type switchableQueueVec struct {
 
 func newSwitchableQueueVec(impl QueueVec, opts QueueVecOpts) *switchableQueueVec {
 	return &switchableQueueVec{
-		baseSwitchableMetric: newBaseSwitchableMetric(impl),
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
 	}
 }
 
@@ -644,19 +1176,180 @@ func (s *switchableQueueVec) Dequeued(ctx Context, labels VecLabels) error {
 	return s.impl.Dequeued(ctx, labels)
 }
 
+func (s *switchableQueueVec) ObserveWait(ctx Context, duration time.Duration, labels VecLabels) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.ObserveWait(ctx, duration, labels)
+}
+
+func (s *switchableQueueVec) DequeuedWithWait(ctx Context, waited time.Duration, labels VecLabels) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.DequeuedWithWait(ctx, waited, labels)
+}
+
+func (s *switchableQueueVec) Enqueue(ctx Context, labels VecLabels) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Enqueue(ctx, labels)
+}
+
+func (s *switchableQueueVec) Dequeue(ctx Context, waited time.Duration, labels VecLabels) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Dequeue(ctx, waited, labels)
+}
+
 func (s *switchableQueueVec) SetWaitTime(ctx Context, duration time.Duration, labels VecLabels) error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return s.impl.SetWaitTime(ctx, duration, labels)
 }
 
+// Partition returns a partition recording through the wrapper, so that it
+// follows implementation switches
+func (s *switchableQueueVec) Partition(labels VecLabels) Queue {
+	return &queuePartition{QueueVec: s, labels: labels}
+}
+
+// switchableThroughput wraps a [Throughput] implementation that can be switched
+type switchableThroughput struct {
+	*baseSwitchableMetric[Throughput]
+}
+
+func newSwitchableThroughput(impl Throughput, opts ThroughputOpts) *switchableThroughput {
+	return &switchableThroughput{
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
+	}
+}
+
+// switchImpl replaces the internal implementation, handing the ticker over
+// to the new implementation if the replaced one was started.
+func (s *switchableThroughput) switchImpl(newImpl any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if old, ok := s.impl.(*baseThroughput); ok && old.running() {
+		old.Stop()
+		newImpl.(Throughput).Start()
+	}
+	s.impl = newImpl.(Throughput)
+	s.isNoop = isNoopImpl(s.impl)
+}
+
+func (s *switchableThroughput) Components() []Metric {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Components()
+}
+
+func (s *switchableThroughput) Mark(ctx Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Mark(ctx)
+}
+
+func (s *switchableThroughput) MarkN(ctx Context, n int64) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.MarkN(ctx, n)
+}
+
+func (s *switchableThroughput) Start() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.impl.Start()
+}
+
+func (s *switchableThroughput) Stop() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.impl.Stop()
+}
+
+// switchableDistributionGauge wraps a [DistributionGauge] implementation that can be switched
+type switchableDistributionGauge struct {
+	*baseSwitchableMetric[DistributionGauge]
+}
+
+func newSwitchableDistributionGauge(impl DistributionGauge, opts DistributionGaugeOpts) *switchableDistributionGauge {
+	return &switchableDistributionGauge{
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
+	}
+}
+
+func (s *switchableDistributionGauge) Components() []Metric {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Components()
+}
+
+func (s *switchableDistributionGauge) Observe(ctx Context, value float64) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Observe(ctx, value)
+}
+
+// switchableMultiResHistogram wraps a [MultiResHistogram] implementation that can be switched
+type switchableMultiResHistogram struct {
+	*baseSwitchableMetric[MultiResHistogram]
+}
+
+func newSwitchableMultiResHistogram(impl MultiResHistogram, opts MultiResHistogramOpts) *switchableMultiResHistogram {
+	return &switchableMultiResHistogram{
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
+	}
+}
+
+func (s *switchableMultiResHistogram) Components() []Metric {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Components()
+}
+
+func (s *switchableMultiResHistogram) Observe(ctx Context, value float64) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Observe(ctx, value)
+}
+
+// switchableDependency wraps a [Dependency] implementation that can be switched
+type switchableDependency struct {
+	*baseSwitchableMetric[Dependency]
+}
+
+func newSwitchableDependency(impl Dependency, opts DependencyOpts) *switchableDependency {
+	return &switchableDependency{
+		baseSwitchableMetric: newBaseSwitchableMetric(impl, opts),
+	}
+}
+
+func (s *switchableDependency) Components() []Metric {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Components()
+}
+
+func (s *switchableDependency) Call(ctx Context, dependency, operation string) func(err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impl.Call(ctx, dependency, operation)
+}
+
 var (
 	__ctc_switchableCounter              Metric          = switchableCounter{}
 	__ctc_switchableCounterPtr           Metric          = &switchableCounter{}
+	__ctc_switchableCounterGroup         Metric          = switchableCounterGroup{}
+	__ctc_switchableCounterGroupPtr      Metric          = &switchableCounterGroup{}
+	__ctc_switchableCounterFunc          Metric          = switchableCounterFunc{}
+	__ctc_switchableCounterFuncPtr       Metric          = &switchableCounterFunc{}
 	__ctc_switchableCounterVec           Metric          = switchableCounterVec{}
 	__ctc_switchableCounterVecPtr        Metric          = &switchableCounterVec{}
+	__ctc_switchableLabelValuesCounter   Counter         = &switchableLabelValuesCounter{}
 	__ctc_switchableGauge                Metric          = switchableGauge{}
 	__ctc_switchableGaugePtr             Metric          = &switchableGauge{}
+	__ctc_switchableGaugeFunc            Metric          = switchableGaugeFunc{}
+	__ctc_switchableGaugeFuncPtr         Metric          = &switchableGaugeFunc{}
 	__ctc_switchableGaugeVec             Metric          = switchableGaugeVec{}
 	__ctc_switchableGaugeVecPtr          Metric          = &switchableGaugeVec{}
 	__ctc_switchableHistogram            Metric          = switchableHistogram{}
@@ -677,4 +1370,8 @@ var (
 	__ctc_switchableCircuitBreakerVecPtr CompositeMetric = &switchableCircuitBreakerVec{}
 	__ctc_switchableQueuePtr             CompositeMetric = &switchableQueue{}
 	__ctc_switchableQueueVecPtr          CompositeMetric = &switchableQueueVec{}
+	__ctc_switchableThroughputPtr        CompositeMetric = &switchableThroughput{}
+	__ctc_switchableDistributionGaugePtr CompositeMetric = &switchableDistributionGauge{}
+	__ctc_switchableMultiResHistogramPtr CompositeMetric = &switchableMultiResHistogram{}
+	__ctc_switchableDependencyPtr        CompositeMetric = &switchableDependency{}
 )
@@ -51,3 +51,73 @@ func TestSwitchableMetrics(t *testing.T) {
 		t.Errorf("Counter.Inc() failed: %v", err)
 	}
 }
+
+func TestSwitchableSetLevelSwapsImpl(t *testing.T) {
+	group := NewRegistry(LevelImportant).NewGroup("test", NewMockBackend())
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests"}}, LevelImportant)
+	queue := newTestQueue(group)
+
+	// Raising the level above the group's level de-materializes the metrics
+	counter.SetLevel(LevelDebug)
+	queue.SetLevel(LevelVerbose)
+	if _, ok := counter.(*switchableCounter).impl.(*noopCounter); !ok {
+		t.Errorf("Expected a noop counter, got %T", counter.(*switchableCounter).impl)
+	}
+	if !queue.(Switchable).IsNoop() {
+		t.Errorf("Expected a noop queue, got %T", queue.(*switchableQueue).impl)
+	}
+	if counter.Level() != LevelDebug || queue.Level() != LevelVerbose {
+		t.Errorf("Expected the new levels, got %v and %v", counter.Level(), queue.Level())
+	}
+
+	// Lowering it back to the group's level materializes them again
+	counter.SetLevel(LevelImportant)
+	queue.SetLevel(LevelCritical)
+	if _, ok := counter.(*switchableCounter).impl.(*baseCounter); !ok {
+		t.Errorf("Expected a real counter, got %T", counter.(*switchableCounter).impl)
+	}
+	if _, ok := queue.(*switchableQueue).impl.(*baseQueue); !ok {
+		t.Errorf("Expected a real queue, got %T", queue.(*switchableQueue).impl)
+	}
+	if err := counter.Inc(group.Context()); err != nil || mockCounterOf(t, counter).GetCount() != 1 {
+		t.Errorf("Expected the materialized counter to record, got %v", err)
+	}
+
+	// A level change within the group's level keeps the implementation
+	impl := counter.(*switchableCounter).impl
+	counter.SetLevel(LevelCritical)
+	if counter.(*switchableCounter).impl != impl || counter.Level() != LevelCritical {
+		t.Error("Expected the implementation to be kept with the new level")
+	}
+}
+
+func TestSwitchableSetLevelNoopConvertedByGroup(t *testing.T) {
+	group := NewRegistry(LevelImportant).NewGroup("test", NewMockBackend())
+	counter := group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests"}}, LevelImportant)
+
+	// A metric de-materialized by its own level is converted by the group
+	// once the group's level enables it
+	counter.SetLevel(LevelDebug)
+	group.SetGroupLevel(LevelDebug, LevelOpts{ReplaceNoops: true})
+	if _, ok := counter.(*switchableCounter).impl.(*baseCounter); !ok {
+		t.Errorf("Expected a real counter, got %T", counter.(*switchableCounter).impl)
+	}
+}
+
+func TestSwitchableIsNoopFollowsImpl(t *testing.T) {
+	group := newGroup(NewMockBackend(), "test", LevelDebug)
+	opts := CounterOpts{MetricInfo: MetricInfo{Name: "requests"}}
+	counter := newSwitchableCounter(newNoopCounter(opts, LevelDebug), opts)
+
+	if !counter.IsNoop() {
+		t.Fatal("Expected a counter constructed with a noop to be noop")
+	}
+	counter.switchImpl(group.newBaseCounter(opts, LevelDebug))
+	if counter.IsNoop() {
+		t.Error("Expected the counter not to be noop after switching to a real impl")
+	}
+	counter.switchImpl(newNoopCounter(opts, LevelDebug))
+	if !counter.IsNoop() {
+		t.Error("Expected the counter to be noop after switching back to a noop impl")
+	}
+}
@@ -0,0 +1,82 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: tags.go
+//
+// This file contains the [tagFilter], which allows metrics to be toggled by
+// category strings ([MetricInfo.Tags]) as a lighter alternative to levels,
+// see [Group.EnableTags] and [Group.DisableTags].
+//--------------------------------------------------------------------------------
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// tagFilter holds the set of disabled tags of a [Group].
+//
+// Reads are lock free, as they happen on every metric operation. Writes
+// replace the set under a mutex (copy on write).
+type tagFilter struct {
+	mu       sync.Mutex
+	disabled atomic.Pointer[map[string]struct{}]
+}
+
+// anyDisabled returns true if any of the tags is disabled
+func (f *tagFilter) anyDisabled(tags []string) bool {
+	disabled := f.disabled.Load()
+	if disabled == nil {
+		return false
+	}
+
+	for _, tag := range tags {
+		if _, ok := (*disabled)[tag]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// enable removes the tags from the disabled set
+func (f *tagFilter) enable(tags ...string) {
+	f.update(func(disabled map[string]struct{}) {
+		for _, tag := range tags {
+			delete(disabled, tag)
+		}
+	})
+}
+
+// disable adds the tags to the disabled set
+func (f *tagFilter) disable(tags ...string) {
+	f.update(func(disabled map[string]struct{}) {
+		for _, tag := range tags {
+			disabled[tag] = struct{}{}
+		}
+	})
+}
+
+// update applies fn to a copy of the disabled set, and stores the copy
+func (f *tagFilter) update(fn func(disabled map[string]struct{})) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	next := make(map[string]struct{})
+	if current := f.disabled.Load(); current != nil {
+		for tag := range *current {
+			next[tag] = struct{}{}
+		}
+	}
+
+	fn(next)
+	f.disabled.Store(&next)
+}
+
+// componentTags returns the tags of a component of a composite metric,
+// which are its own tags plus the composite's tags
+func componentTags(compositeTags, tags []string) []string {
+	if len(compositeTags) == 0 {
+		return tags
+	}
+
+	return append(append([]string(nil), tags...), compositeTags...)
+}
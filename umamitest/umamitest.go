@@ -0,0 +1,46 @@
+// Package umamitest provides test assertions for metrics created through a
+// umami [umami.Group], e.g. that a metric does nothing at the level of its
+// group, so tests don't need to know about switchable implementations.
+//
+// All helpers take a [testing.TB], and report mismatches with [testing.TB.Errorf].
+package umamitest
+
+import (
+	"testing"
+
+	"github.com/SimonDaKappa/go-umami"
+)
+
+// IsNoop returns true if the current implementation of m is a noop, i.e. if
+// operations on m are not recorded whatever the context. Metrics created by a
+// group report their current implementation, see [umami.Switchable.IsNoop].
+func IsNoop(m umami.Metric) bool {
+	switch m := m.(type) {
+	case umami.Switchable:
+		return m.IsNoop()
+	case umami.NoopMetric:
+		return true
+	default:
+		return false
+	}
+}
+
+// AssertNoop asserts that m is a noop, e.g. because its level is below the
+// level of its group
+func AssertNoop(tb testing.TB, m umami.Metric) {
+	tb.Helper()
+
+	if !IsNoop(m) {
+		tb.Errorf("metric %s at level %v is enabled, want noop", m.Name(), m.Level())
+	}
+}
+
+// AssertEnabled asserts that m is not a noop, so that its operations are
+// recorded by the backend under a context enabling its level
+func AssertEnabled(tb testing.TB, m umami.Metric) {
+	tb.Helper()
+
+	if IsNoop(m) {
+		tb.Errorf("metric %s at level %v is a noop, want enabled", m.Name(), m.Level())
+	}
+}
@@ -0,0 +1,65 @@
+package umamitest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/SimonDaKappa/go-umami"
+)
+
+// recordingTB captures errors reported by the helpers under test
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertNoopAndEnabled(t *testing.T) {
+	group := umami.NewRegistry(umami.LevelImportant).NewGroup("test", umami.NewMockBackend())
+	enabled := group.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "requests"}}, umami.LevelImportant)
+	noop := group.Counter(umami.CounterOpts{MetricInfo: umami.MetricInfo{Name: "debug_requests"}}, umami.LevelDebug)
+	noopComposite := group.Cache(umami.CacheOpts{MetricInfo: umami.MetricInfo{Name: "cache"}}, umami.LevelVerbose)
+
+	tests := []struct {
+		name       string
+		metric     umami.Metric
+		wantNoop   bool
+		assertName string
+		assert     func(tb testing.TB, m umami.Metric)
+	}{
+		{"enabled", enabled, false, "AssertEnabled", AssertEnabled},
+		{"enabled", enabled, false, "AssertNoop", AssertNoop},
+		{"noop", noop, true, "AssertNoop", AssertNoop},
+		{"noop", noop, true, "AssertEnabled", AssertEnabled},
+		{"noop composite", noopComposite, true, "AssertNoop", AssertNoop},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.assertName, func(t *testing.T) {
+			tb := &recordingTB{TB: t}
+			tt.assert(tb, tt.metric)
+
+			wantPass := tt.wantNoop == (tt.assertName == "AssertNoop")
+			if wantPass && len(tb.errors) != 0 {
+				t.Errorf("expected no errors, got %v", tb.errors)
+			}
+			if !wantPass && len(tb.errors) != 1 {
+				t.Errorf("expected 1 error, got %v", tb.errors)
+			}
+		})
+	}
+}
+
+func TestAssertEnabledAfterLevelChange(t *testing.T) {
+	group := umami.NewRegistry(umami.LevelImportant).NewGroup("test", umami.NewMockBackend())
+	gauge := group.Gauge(umami.GaugeOpts{MetricInfo: umami.MetricInfo{Name: "in_flight"}}, umami.LevelDebug)
+	AssertNoop(t, gauge)
+
+	gauge.SetLevel(umami.LevelImportant)
+	AssertEnabled(t, gauge)
+}
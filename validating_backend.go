@@ -0,0 +1,279 @@
+package umami
+
+//--------------------------------------------------------------------------------
+// File: validating_backend.go
+//
+// This file contains the [ValidatingBackend], a dry-run [Backend] that checks
+// metric definitions against common backend naming rules instead of recording
+// values. It is intended for CI, where an application's instrumentation can be
+// initialized against it to catch bad metric definitions early.
+//--------------------------------------------------------------------------------
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	BackendValidatingName string = "validating"
+)
+
+var (
+	// validMetricNameRe matches metric names accepted by Prometheus-style backends
+	validMetricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+	// validLabelNameRe matches label names accepted by Prometheus-style backends
+	validLabelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// ValidatingBackend is a [Backend] that validates metric definitions without
+// registering or recording anything.
+//
+// Every metric requested from it is checked for a valid qualified name (see
+// [MetricInfo.QualifiedName]), valid and unique label and const label names,
+// and sane type specific options (sorted buckets, quantiles in range). Any
+// problems found are recorded and can be retrieved via
+// [ValidatingBackend.Problems]. All returned adapters are no-ops, and label
+// sets passed to Vec adapters are checked against the declared labels.
+type ValidatingBackend struct {
+	mu       sync.Mutex
+	names    map[string]struct{}
+	problems []error
+}
+
+// NewValidatingBackend creates a new [ValidatingBackend]
+func NewValidatingBackend() *ValidatingBackend {
+	return &ValidatingBackend{
+		names: make(map[string]struct{}),
+	}
+}
+
+// Problems returns all problems found so far, in the order they were found
+func (v *ValidatingBackend) Problems() []error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return slices.Clone(v.problems)
+}
+
+func (v *ValidatingBackend) Name() string {
+	return BackendValidatingName
+}
+
+// Unregister forgets the qualified metric name, so it may be registered again
+func (v *ValidatingBackend) Unregister(name string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	_, exists := v.names[name]
+	delete(v.names, name)
+	return exists
+}
+
+func (v *ValidatingBackend) Counter(opts CounterOpts) CounterAdapter {
+	v.validate(opts.MetricInfo, nil)
+	return &validatingAdapter{}
+}
+
+func (v *ValidatingBackend) CounterVec(opts CounterVecOpts) CounterVecAdapter {
+	v.validate(opts.MetricInfo, opts.Labels)
+	return &validatingVecAdapter{backend: v, name: opts.QualifiedName(), labels: opts.Labels}
+}
+
+func (v *ValidatingBackend) Gauge(opts GaugeOpts) GaugeAdapter {
+	v.validate(opts.MetricInfo, nil)
+	return &validatingAdapter{}
+}
+
+func (v *ValidatingBackend) GaugeVec(opts GaugeVecOpts) GaugeVecAdapter {
+	v.validate(opts.MetricInfo, opts.Labels)
+	return &validatingVecAdapter{backend: v, name: opts.QualifiedName(), labels: opts.Labels}
+}
+
+func (v *ValidatingBackend) Histogram(opts HistogramOpts) HistogramAdapter {
+	v.validate(opts.MetricInfo, nil)
+	v.validateBuckets(opts.QualifiedName(), opts.Buckets)
+	return &validatingAdapter{}
+}
+
+func (v *ValidatingBackend) HistogramVec(opts HistogramVecOpts) HistogramVecAdapter {
+	v.validate(opts.MetricInfo, opts.Labels)
+	v.validateBuckets(opts.QualifiedName(), opts.Buckets)
+	return &validatingVecAdapter{backend: v, name: opts.QualifiedName(), labels: opts.Labels}
+}
+
+func (v *ValidatingBackend) Summary(opts SummaryOpts) SummaryAdapter {
+	v.validate(opts.MetricInfo, nil)
+	v.validateObjectives(opts.QualifiedName(), opts.Objectives)
+	return &validatingAdapter{}
+}
+
+func (v *ValidatingBackend) SummaryVec(opts SummaryVecOpts) SummaryVecAdapter {
+	v.validate(opts.MetricInfo, opts.Labels)
+	v.validateObjectives(opts.QualifiedName(), opts.Objectives)
+	return &validatingVecAdapter{backend: v, name: opts.QualifiedName(), labels: opts.Labels}
+}
+
+//--------------------------------------------------------------------------------
+// Validation Helpers
+//--------------------------------------------------------------------------------
+
+// report records a problem found during validation
+func (v *ValidatingBackend) report(format string, args ...any) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.problems = append(v.problems, fmt.Errorf(format, args...))
+}
+
+// validate checks the qualified metric name of info (see
+// [MetricInfo.QualifiedName]), the declared label names and the names of its
+// const labels, and that the name has not already been registered with this
+// backend.
+func (v *ValidatingBackend) validate(info MetricInfo, labels []string) {
+	name := info.QualifiedName()
+	if !validMetricNameRe.MatchString(name) {
+		v.report("metric %q: invalid metric name", name)
+	}
+
+	v.mu.Lock()
+	_, duplicate := v.names[name]
+	v.names[name] = struct{}{}
+	v.mu.Unlock()
+
+	if duplicate {
+		v.report("metric %q: registered more than once", name)
+	}
+
+	seen := make(map[string]struct{}, len(labels))
+	for _, label := range labels {
+		v.validateLabelName(name, label)
+		if _, exists := seen[label]; exists {
+			v.report("metric %q: duplicate label name %q", name, label)
+		}
+		seen[label] = struct{}{}
+	}
+
+	for _, label := range slices.Sorted(maps.Keys(info.ConstLabels)) {
+		v.validateLabelName(name, label)
+		if _, exists := seen[label]; exists {
+			v.report("metric %q: const label %q clashes with a label", name, label)
+		}
+	}
+}
+
+// validateLabelName checks that a label name is valid and not reserved
+func (v *ValidatingBackend) validateLabelName(name, label string) {
+	switch {
+	case !validLabelNameRe.MatchString(label):
+		v.report("metric %q: invalid label name %q", name, label)
+	case strings.HasPrefix(label, "__"):
+		v.report("metric %q: label name %q is reserved", name, label)
+	}
+}
+
+// validateBuckets checks that histogram buckets are strictly increasing
+func (v *ValidatingBackend) validateBuckets(name string, buckets []float64) {
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			v.report("metric %q: buckets must be strictly increasing", name)
+			return
+		}
+	}
+}
+
+// validateObjectives checks that summary quantiles and errors are within [0, 1]
+func (v *ValidatingBackend) validateObjectives(name string, objectives map[float64]float64) {
+	for q, e := range objectives {
+		if q < 0 || q > 1 {
+			v.report("metric %q: quantile %v out of range [0, 1]", name, q)
+		}
+		if e < 0 || e > 1 {
+			v.report("metric %q: quantile %v has error %v out of range [0, 1]", name, q, e)
+		}
+	}
+}
+
+// validateLabels checks that a label set matches the declared label names
+func (v *ValidatingBackend) validateLabels(name string, declared []string, labels VecLabels) {
+	if len(labels) != len(declared) {
+		v.report("metric %q: %w: expected %d labels, got %d", name, ErrLabelMismatch, len(declared), len(labels))
+		return
+	}
+
+	for _, label := range declared {
+		if _, ok := labels[label]; !ok {
+			v.report("metric %q: %w: missing label %q", name, ErrLabelMismatch, label)
+		}
+	}
+}
+
+//--------------------------------------------------------------------------------
+// Validating Adapters
+//--------------------------------------------------------------------------------
+
+// validatingAdapter is a no-op adapter for all basic (non-Vec) metric types
+type validatingAdapter struct{}
+
+func (a *validatingAdapter) Inc() error                          { return nil }
+func (a *validatingAdapter) Dec() error                          { return nil }
+func (a *validatingAdapter) Add(value float64) error             { return nil }
+func (a *validatingAdapter) Set(value float64) error             { return nil }
+func (a *validatingAdapter) Observe(value float64) error         { return nil }
+func (a *validatingAdapter) Quantile(q float64) (float64, error) { return 0, nil }
+
+// validatingVecAdapter is a no-op adapter for all Vec metric types that
+// checks passed label sets against the declared label names
+type validatingVecAdapter struct {
+	backend *ValidatingBackend
+	name    string
+	labels  []string
+}
+
+func (a *validatingVecAdapter) Inc(labels VecLabels) error {
+	a.backend.validateLabels(a.name, a.labels, labels)
+	return nil
+}
+
+func (a *validatingVecAdapter) Dec(labels VecLabels) error {
+	a.backend.validateLabels(a.name, a.labels, labels)
+	return nil
+}
+
+func (a *validatingVecAdapter) Add(value float64, labels VecLabels) error {
+	a.backend.validateLabels(a.name, a.labels, labels)
+	return nil
+}
+
+func (a *validatingVecAdapter) Set(value float64, labels VecLabels) error {
+	a.backend.validateLabels(a.name, a.labels, labels)
+	return nil
+}
+
+func (a *validatingVecAdapter) Observe(value float64, labels VecLabels) error {
+	a.backend.validateLabels(a.name, a.labels, labels)
+	return nil
+}
+
+func (a *validatingVecAdapter) Quantile(q float64, labels VecLabels) (float64, error) {
+	a.backend.validateLabels(a.name, a.labels, labels)
+	return 0, nil
+}
+
+var (
+	__ctc_validatingBackend           Backend           = (*ValidatingBackend)(nil)
+	__ctc_validatingUnregisterBackend UnregisterBackend = (*ValidatingBackend)(nil)
+
+	__ctc_validatingCounterAdapter      CounterAdapter      = (*validatingAdapter)(nil)
+	__ctc_validatingGaugeAdapter        GaugeAdapter        = (*validatingAdapter)(nil)
+	__ctc_validatingHistogramAdapter    HistogramAdapter    = (*validatingAdapter)(nil)
+	__ctc_validatingSummaryAdapter      SummaryAdapter      = (*validatingAdapter)(nil)
+	__ctc_validatingCounterVecAdapter   CounterVecAdapter   = (*validatingVecAdapter)(nil)
+	__ctc_validatingGaugeVecAdapter     GaugeVecAdapter     = (*validatingVecAdapter)(nil)
+	__ctc_validatingHistogramVecAdapter HistogramVecAdapter = (*validatingVecAdapter)(nil)
+	__ctc_validatingSummaryVecAdapter   SummaryVecAdapter   = (*validatingVecAdapter)(nil)
+)
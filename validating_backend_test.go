@@ -0,0 +1,112 @@
+package umami
+
+import (
+	"testing"
+)
+
+func TestValidatingBackendValidMetrics(t *testing.T) {
+	backend := NewValidatingBackend()
+	group := NewRegistry(LevelDebug).NewGroup("web", backend)
+
+	group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total", Help: "Requests"}}, LevelCritical)
+	group.HistogramVec(
+		HistogramVecOpts{
+			MetricInfo: MetricInfo{Name: "latency_seconds", Help: "Latency"},
+			Labels:     []string{"method", "route"},
+			Buckets:    []float64{0.1, 1, 10},
+		},
+		LevelImportant,
+	)
+
+	if problems := backend.Problems(); len(problems) != 0 {
+		t.Errorf("Expected no problems, got %v", problems)
+	}
+}
+
+func TestValidatingBackendReportsProblems(t *testing.T) {
+	backend := NewValidatingBackend()
+	group := NewRegistry(LevelDebug).NewGroup("web", backend)
+
+	group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "bad-name", Help: "Bad"}}, LevelCritical)
+	if problems := backend.Problems(); len(problems) != 1 {
+		t.Fatalf("Expected 1 problem for invalid name, got %v", problems)
+	}
+
+	group.CounterVec(
+		CounterVecOpts{
+			MetricInfo: MetricInfo{Name: "by_label", Help: "Labels"},
+			Labels:     []string{"__reserved", "bad-label"},
+		},
+		LevelCritical,
+	)
+	if problems := backend.Problems(); len(problems) != 3 {
+		t.Fatalf("Expected 3 problems after invalid labels, got %v", problems)
+	}
+
+	group.Histogram(
+		HistogramOpts{
+			MetricInfo: MetricInfo{Name: "unsorted", Help: "Unsorted"},
+			Buckets:    []float64{1, 0.5},
+		},
+		LevelCritical,
+	)
+	if problems := backend.Problems(); len(problems) != 4 {
+		t.Fatalf("Expected 4 problems after unsorted buckets, got %v", problems)
+	}
+}
+
+func TestValidatingBackendLabelMismatch(t *testing.T) {
+	backend := NewValidatingBackend()
+	group := NewRegistry(LevelDebug).NewGroup("web", backend)
+
+	counterVec := group.CounterVec(
+		CounterVecOpts{
+			MetricInfo: MetricInfo{Name: "by_method", Help: "By method"},
+			Labels:     []string{"method"},
+		},
+		LevelCritical,
+	)
+
+	ctx := group.Context()
+	if err := counterVec.Inc(ctx, VecLabels{"method": "GET"}); err != nil {
+		t.Errorf("CounterVec.Inc() failed: %v", err)
+	}
+	if problems := backend.Problems(); len(problems) != 0 {
+		t.Fatalf("Expected no problems for matching labels, got %v", problems)
+	}
+
+	if err := counterVec.Inc(ctx, VecLabels{"verb": "GET"}); err != nil {
+		t.Errorf("CounterVec.Inc() failed: %v", err)
+	}
+	if problems := backend.Problems(); len(problems) != 1 {
+		t.Errorf("Expected 1 problem for mismatched labels, got %v", problems)
+	}
+}
+
+func TestValidatingBackendQualifiedNames(t *testing.T) {
+	backend := NewValidatingBackend()
+	group := NewRegistry(LevelDebug).NewGroup("web", backend)
+
+	// The same name in different namespaces is no duplicate
+	group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total", Namespace: "shop"}}, LevelCritical)
+	NewRegistry(LevelDebug).NewGroup("web", backend).Counter(CounterOpts{MetricInfo: MetricInfo{Name: "requests_total", Namespace: "store"}}, LevelCritical)
+	if problems := backend.Problems(); len(problems) != 0 {
+		t.Fatalf("Expected no problems, got %v", problems)
+	}
+
+	group.Counter(CounterOpts{MetricInfo: MetricInfo{Name: "errors_total", Namespace: "bad-namespace"}}, LevelCritical)
+	if problems := backend.Problems(); len(problems) != 1 {
+		t.Fatalf("Expected 1 problem for the invalid namespace, got %v", problems)
+	}
+
+	group.CounterVec(
+		CounterVecOpts{
+			MetricInfo: MetricInfo{Name: "by_route", ConstLabels: map[string]string{"__reserved": "x", "route": "/"}},
+			Labels:     []string{"route"},
+		},
+		LevelCritical,
+	)
+	if problems := backend.Problems(); len(problems) != 3 {
+		t.Errorf("Expected 3 problems after a reserved and a clashing const label, got %v", problems)
+	}
+}